@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/alert/models"
+	"github.com/rasadov/EcommerceAPI/alert/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type Client struct {
+	conn    *grpc.ClientConn
+	service pb.AlertServiceClient
+}
+
+func NewClient(url string) (*Client, error) {
+	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	c := pb.NewAlertServiceClient(conn)
+	return &Client{conn, c}, nil
+}
+
+func (client *Client) Close() {
+	err := client.conn.Close()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func decodeSubscription(subscriptionProto *pb.AlertSubscription) (*models.Subscription, error) {
+	subscription := &models.Subscription{
+		ID:        uint(subscriptionProto.Id),
+		AccountID: subscriptionProto.AccountId,
+		ProductID: subscriptionProto.ProductId,
+		Kind:      subscriptionProto.Kind,
+		Channel:   subscriptionProto.Channel,
+		Target:    subscriptionProto.Target,
+	}
+	if subscriptionProto.Threshold != nil {
+		subscription.Threshold = money.FromProto(subscriptionProto.Threshold)
+	}
+	subscription.CreatedAt = time.Time{}
+	if err := subscription.CreatedAt.UnmarshalBinary(subscriptionProto.CreatedAt); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Subscribe creates an alert. threshold is only used for price_below alerts.
+func (client *Client) Subscribe(ctx context.Context, accountId uint64, productId, kind string, threshold *money.Money, channel, target string) (*models.Subscription, error) {
+	request := &pb.SubscribeRequest{
+		AccountId: accountId,
+		ProductId: productId,
+		Kind:      kind,
+		Channel:   channel,
+		Target:    target,
+	}
+	if threshold != nil {
+		request.Threshold = threshold.ToProto()
+	}
+
+	r, err := client.service.Subscribe(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSubscription(r.Subscription)
+}
+
+func (client *Client) Unsubscribe(ctx context.Context, accountId, subscriptionId uint64) error {
+	_, err := client.service.Unsubscribe(ctx, &pb.UnsubscribeRequest{
+		AccountId:      accountId,
+		SubscriptionId: subscriptionId,
+	})
+	return err
+}
+
+func (client *Client) GetSubscriptions(ctx context.Context, accountId uint64) ([]*models.Subscription, error) {
+	r, err := client.service.GetSubscriptions(ctx, &wrapperspb.UInt64Value{Value: accountId})
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptions []*models.Subscription
+	for _, subscriptionProto := range r.Subscriptions {
+		subscription, err := decodeSubscription(subscriptionProto)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
+
+// SetCheckoutReminders turns abandoned checkout reminders on or off for the account.
+func (client *Client) SetCheckoutReminders(ctx context.Context, accountId uint64, enabled bool) error {
+	_, err := client.service.SetCheckoutReminders(ctx, &pb.SetCheckoutRemindersRequest{
+		AccountId: accountId,
+		Enabled:   enabled,
+	})
+	return err
+}
+
+func (client *Client) GetCheckoutReminders(ctx context.Context, accountId uint64) (bool, error) {
+	r, err := client.service.GetCheckoutReminders(ctx, &wrapperspb.UInt64Value{Value: accountId})
+	if err != nil {
+		return false, err
+	}
+	return r.Value, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/rasadov/EcommerceAPI/alert/config"
+	"github.com/rasadov/EcommerceAPI/alert/internal"
+	"github.com/rasadov/EcommerceAPI/alert/models"
+	payment "github.com/rasadov/EcommerceAPI/payment/client"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/tinrab/retry"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	var repository internal.Repository
+
+	retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
+		db, err := gorm.Open(postgres.Open(config.DatabaseUrl), &gorm.Config{})
+		if err != nil {
+			log.Println(err)
+		}
+		repository, err = internal.NewPostgresRepository(db)
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	})
+	defer repository.Close()
+
+	// Setup Kafka consumer
+	var consumer sarama.Consumer
+	if config.KafkaBrokers != "" {
+		retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
+			kafkaConfig := sarama.NewConfig()
+			kafkaConfig.Consumer.Return.Errors = true
+
+			consumer, err = sarama.NewConsumer([]string{config.KafkaBrokers}, kafkaConfig)
+			if err != nil {
+				log.Printf("Failed to create Kafka consumer: %v", err)
+			}
+			return
+		})
+	}
+
+	var mailer internal.Mailer = internal.LogMailer{}
+	if config.SMTPAddr != "" {
+		mailer = &internal.SMTPMailer{
+			Addr:     config.SMTPAddr,
+			From:     config.MailFrom,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+		}
+	}
+	notifier := internal.ChannelNotifier{
+		models.ChannelEmail:   internal.NewEmailNotifier(mailer),
+		models.ChannelWebhook: internal.NewWebhookNotifier(config.WebhookSecret),
+	}
+
+	converter := money.NewConverter(money.NewRateSource(config.CurrencyRatesURL, config.CurrencyRatesFile))
+	converter.StartRefresh(context.Background(), time.Hour)
+
+	paymentClient, err := payment.NewClient(config.PaymentUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer paymentClient.Close()
+
+	service := internal.NewAlertService(repository, notifier, converter, mailer, paymentClient)
+	log.Println("Listening on port 8080...")
+	log.Fatal(internal.StartServers(service, consumer, config.ProductEventsTopic, config.PaymentEventsTopic, config.ProductUrl, config.GrpcPort))
+}
@@ -0,0 +1,45 @@
+package config
+
+import "os"
+
+var (
+	DatabaseUrl        string
+	ProductUrl         string
+	PaymentUrl         string
+	KafkaBrokers       string
+	ProductEventsTopic string
+	PaymentEventsTopic string
+	CurrencyRatesURL   string
+	CurrencyRatesFile  string
+	SMTPAddr           string
+	SMTPUsername       string
+	SMTPPassword       string
+	MailFrom           string
+	WebhookSecret      string
+)
+
+const (
+	GrpcPort int = 8080
+)
+
+func init() {
+	DatabaseUrl = os.Getenv("DATABASE_URL")
+	ProductUrl = os.Getenv("PRODUCT_SERVICE_URL")
+	KafkaBrokers = os.Getenv("KAFKA_BOOTSTRAP_SERVERS")
+	ProductEventsTopic = os.Getenv("PRODUCT_EVENTS_TOPIC")
+	if ProductEventsTopic == "" {
+		ProductEventsTopic = "product_events"
+	}
+	PaymentUrl = os.Getenv("PAYMENT_SERVICE_URL")
+	PaymentEventsTopic = os.Getenv("PAYMENT_EVENTS_TOPIC")
+	if PaymentEventsTopic == "" {
+		PaymentEventsTopic = "payment_events"
+	}
+	CurrencyRatesURL = os.Getenv("CURRENCY_RATES_URL")
+	CurrencyRatesFile = os.Getenv("CURRENCY_RATES_FILE")
+	SMTPAddr = os.Getenv("SMTP_ADDR")
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	MailFrom = os.Getenv("MAIL_FROM")
+	WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/IBM/sarama"
+	"github.com/rasadov/EcommerceAPI/alert/models"
+	"github.com/rasadov/EcommerceAPI/pkg/kafka"
+)
+
+type EventConsumer struct {
+	consumer sarama.Consumer
+	service  Service
+}
+
+func NewEventConsumer(consumer sarama.Consumer, service Service) *EventConsumer {
+	return &EventConsumer{
+		consumer: consumer,
+		service:  service,
+	}
+}
+
+func (ec *EventConsumer) GetConsumer() sarama.Consumer {
+	return ec.consumer
+}
+
+func (ec *EventConsumer) StartProductEventsConsumer(ctx context.Context, topic string) error {
+	return kafka.StartEventsConsumer(ctx, ec, topic, ec.handleProductEvent)
+}
+
+func (ec *EventConsumer) StartPaymentEventsConsumer(ctx context.Context, topic string) error {
+	return kafka.StartEventsConsumer(ctx, ec, topic, ec.handlePaymentEvent)
+}
+
+func (ec *EventConsumer) handleProductEvent(partition int32, pc sarama.PartitionConsumer) {
+	for {
+		select {
+		case message := <-pc.Messages():
+			if message == nil {
+				continue
+			}
+
+			var event models.ProductEvent
+			if err := json.Unmarshal(message.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal product event: %v", err)
+				continue
+			}
+			ec.HandleEvent(context.Background(), event)
+
+		case err := <-pc.Errors():
+			if err != nil {
+				log.Printf("Kafka consumer error: %v", err)
+			}
+		}
+	}
+}
+
+// HandleEvent evaluates alerts for a single product event.
+func (ec *EventConsumer) HandleEvent(ctx context.Context, event models.ProductEvent) {
+	if event.Data.ProductID == nil {
+		log.Printf("Invalid %s event: missing product ID", event.Type)
+		return
+	}
+
+	var err error
+	switch event.Type {
+	case "product_updated", "product_stock_changed":
+		err = ec.service.HandleProductUpdated(ctx, event.State())
+	case "product_deleted":
+		err = ec.service.HandleProductDeleted(ctx, *event.Data.ProductID)
+	default:
+		// New products cannot have subscriptions yet
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to process %s event for product %s: %v", event.Type, *event.Data.ProductID, err)
+	}
+}
+
+func (ec *EventConsumer) handlePaymentEvent(partition int32, pc sarama.PartitionConsumer) {
+	for {
+		select {
+		case message := <-pc.Messages():
+			if message == nil {
+				continue
+			}
+
+			var event models.CheckoutEvent
+			if err := json.Unmarshal(message.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal payment event: %v", err)
+				continue
+			}
+			ec.HandleCheckoutEvent(context.Background(), event)
+
+		case err := <-pc.Errors():
+			if err != nil {
+				log.Printf("Kafka consumer error: %v", err)
+			}
+		}
+	}
+}
+
+// HandleCheckoutEvent sends the reminder for an abandoned checkout.
+func (ec *EventConsumer) HandleCheckoutEvent(ctx context.Context, event models.CheckoutEvent) {
+	if event.Type != "checkout_abandoned" {
+		return
+	}
+	if err := ec.service.HandleCheckoutAbandoned(ctx, event.Data); err != nil {
+		log.Printf("Failed to remind about abandoned checkout for order %d: %v", event.Data.OrderId, err)
+	}
+}
@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/alert/models"
+)
+
+var (
+	ErrUnsupportedChannel = errors.New("unsupported notification channel")
+)
+
+// Notification is a triggered alert ready to be delivered.
+type Notification struct {
+	Subscription models.Subscription
+	Product      models.ProductState
+	Subject      string
+	Message      string
+}
+
+// Notifier delivers notifications to subscribers.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// ChannelNotifier routes each notification to the notifier registered for its subscription's channel.
+type ChannelNotifier map[string]Notifier
+
+func (n ChannelNotifier) Notify(ctx context.Context, notification Notification) error {
+	notifier, ok := n[notification.Subscription.Channel]
+	if !ok {
+		return ErrUnsupportedChannel
+	}
+	return notifier.Notify(ctx, notification)
+}
+
+// Mailer sends a plain text email.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// SMTPMailer sends email through an SMTP relay.
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", m.From, to, subject, body)
+	return smtp.SendMail(m.Addr, auth, m.From, []string{to}, []byte(message))
+}
+
+// LogMailer only logs the email. It is used when no SMTP relay is configured.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("Email to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// EmailNotifier emails the subscription target.
+type EmailNotifier struct {
+	mailer Mailer
+}
+
+func NewEmailNotifier(mailer Mailer) *EmailNotifier {
+	return &EmailNotifier{mailer}
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, notification Notification) error {
+	return n.mailer.Send(ctx, notification.Subscription.Target, notification.Subject, notification.Message)
+}
+
+// WebhookPayload is the JSON body posted to webhook subscribers.
+type WebhookPayload struct {
+	SubscriptionID uint   `json:"subscriptionId"`
+	Kind           string `json:"kind"`
+	ProductID      string `json:"productId"`
+	ProductName    string `json:"productName"`
+	Price          int64  `json:"price"`
+	Currency       string `json:"currency"`
+	Stock          *int   `json:"stock"`
+	Message        string `json:"message"`
+}
+
+// WebhookNotifier posts the alert to the subscription target. When a secret is configured
+// the body is signed with HMAC-SHA256 in the X-Alert-Signature header.
+type WebhookNotifier struct {
+	client *http.Client
+	secret string
+}
+
+func NewWebhookNotifier(secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		client: &http.Client{Timeout: 10 * time.Second},
+		secret: secret,
+	}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(WebhookPayload{
+		SubscriptionID: notification.Subscription.ID,
+		Kind:           notification.Subscription.Kind,
+		ProductID:      notification.Product.ID,
+		ProductName:    notification.Product.Name,
+		Price:          notification.Product.Price.Amount,
+		Currency:       notification.Product.Price.Currency,
+		Stock:          notification.Product.Stock,
+		Message:        notification.Message,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notification.Subscription.Target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		req.Header.Set("X-Alert-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// MemoryNotifier keeps notifications in memory. It is meant for tests.
+type MemoryNotifier struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (n *MemoryNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func (n *MemoryNotifier) Notifications() []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Notification(nil), n.notifications...)
+}
+
+// validTarget checks that the delivery target matches the channel.
+func validTarget(channel, target string) bool {
+	switch channel {
+	case models.ChannelEmail:
+		at := strings.LastIndex(target, "@")
+		return at > 0 && at < len(target)-1
+	case models.ChannelWebhook:
+		u, err := url.Parse(target)
+		return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+	}
+	return false
+}
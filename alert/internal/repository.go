@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/alert/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+type Repository interface {
+	Close()
+	PutSubscription(ctx context.Context, subscription *models.Subscription) error
+	GetSubscription(ctx context.Context, id uint64) (*models.Subscription, error)
+	GetActiveSubscription(ctx context.Context, accountId uint64, productId, kind string) (*models.Subscription, error)
+	ListActiveSubscriptionsForAccount(ctx context.Context, accountId uint64) ([]*models.Subscription, error)
+	ListActiveSubscriptionsForProduct(ctx context.Context, productId string) ([]*models.Subscription, error)
+	MarkNotified(ctx context.Context, id uint64, notifiedAt time.Time) error
+	DeleteSubscription(ctx context.Context, id uint64) error
+	DeleteSubscriptionsForProduct(ctx context.Context, productId string) error
+	SetCheckoutRemindersOptOut(ctx context.Context, accountId uint64, optOut bool) error
+	CheckoutRemindersOptedOut(ctx context.Context, accountId uint64) (bool, error)
+	ClaimCheckoutReminder(ctx context.Context, reminder *models.CheckoutReminder) (bool, error)
+	DeleteCheckoutReminder(ctx context.Context, orderId uint64) error
+}
+
+type postgresRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresRepository(db *gorm.DB) (Repository, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	err = sqlDB.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&models.Subscription{}, &models.CheckoutReminderOptOut{}, &models.CheckoutReminder{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresRepository{db}, nil
+}
+
+func (repository *postgresRepository) Close() {
+	sqlDB, err := repository.db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+		if err != nil {
+			log.Println("Error closing postgres repository")
+			log.Println(err)
+		}
+	}
+}
+
+// PutSubscription creates the subscription, or saves it when it already has an ID.
+func (repository *postgresRepository) PutSubscription(ctx context.Context, subscription *models.Subscription) error {
+	return repository.db.WithContext(ctx).Save(subscription).Error
+}
+
+func (repository *postgresRepository) GetSubscription(ctx context.Context, id uint64) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := repository.db.WithContext(ctx).First(&subscription, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (repository *postgresRepository) GetActiveSubscription(ctx context.Context, accountId uint64, productId, kind string) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := repository.db.WithContext(ctx).
+		Where("account_id = ? AND product_id = ? AND kind = ? AND notified_at IS NULL", accountId, productId, kind).
+		First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (repository *postgresRepository) ListActiveSubscriptionsForAccount(ctx context.Context, accountId uint64) ([]*models.Subscription, error) {
+	var subscriptions []*models.Subscription
+	err := repository.db.WithContext(ctx).
+		Where("account_id = ? AND notified_at IS NULL", accountId).
+		Order("created_at, id").
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (repository *postgresRepository) ListActiveSubscriptionsForProduct(ctx context.Context, productId string) ([]*models.Subscription, error) {
+	var subscriptions []*models.Subscription
+	err := repository.db.WithContext(ctx).
+		Where("product_id = ? AND notified_at IS NULL", productId).
+		Order("id").
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (repository *postgresRepository) MarkNotified(ctx context.Context, id uint64, notifiedAt time.Time) error {
+	return repository.db.WithContext(ctx).
+		Model(&models.Subscription{}).
+		Where("id = ?", id).
+		Update("notified_at", notifiedAt).Error
+}
+
+func (repository *postgresRepository) DeleteSubscription(ctx context.Context, id uint64) error {
+	return repository.db.WithContext(ctx).Delete(&models.Subscription{}, id).Error
+}
+
+func (repository *postgresRepository) DeleteSubscriptionsForProduct(ctx context.Context, productId string) error {
+	return repository.db.WithContext(ctx).
+		Where("product_id = ?", productId).
+		Delete(&models.Subscription{}).Error
+}
+
+func (repository *postgresRepository) SetCheckoutRemindersOptOut(ctx context.Context, accountId uint64, optOut bool) error {
+	if !optOut {
+		return repository.db.WithContext(ctx).Delete(&models.CheckoutReminderOptOut{}, "account_id = ?", accountId).Error
+	}
+	return repository.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.CheckoutReminderOptOut{AccountID: accountId, CreatedAt: time.Now().UTC()}).Error
+}
+
+func (repository *postgresRepository) CheckoutRemindersOptedOut(ctx context.Context, accountId uint64) (bool, error) {
+	var count int64
+	err := repository.db.WithContext(ctx).
+		Model(&models.CheckoutReminderOptOut{}).
+		Where("account_id = ?", accountId).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ClaimCheckoutReminder records the reminder and reports whether it was recorded, which is
+// false when the order was already reminded about.
+func (repository *postgresRepository) ClaimCheckoutReminder(ctx context.Context, reminder *models.CheckoutReminder) (bool, error) {
+	result := repository.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(reminder)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (repository *postgresRepository) DeleteCheckoutReminder(ctx context.Context, orderId uint64) error {
+	return repository.db.WithContext(ctx).Delete(&models.CheckoutReminder{}, "order_id = ?", orderId).Error
+}
@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/rasadov/EcommerceAPI/alert/models"
+	"github.com/rasadov/EcommerceAPI/alert/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	product "github.com/rasadov/EcommerceAPI/product/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type grpcServer struct {
+	pb.UnimplementedAlertServiceServer
+	service       Service
+	productClient *product.Client
+}
+
+// StartServers runs the gRPC server and, when a consumer is given, the product and payment
+// events consumers.
+func StartServers(service Service, consumer sarama.Consumer, productEventsTopic, paymentEventsTopic, productURL string, grpcPort int) error {
+	var wg sync.WaitGroup
+	errCh := make(chan error, 3)
+
+	if consumer != nil {
+		eventConsumer := NewEventConsumer(consumer, service)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := eventConsumer.StartProductEventsConsumer(context.Background(), productEventsTopic); err != nil {
+				errCh <- fmt.Errorf("kafka consumer error: %w", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := eventConsumer.StartPaymentEventsConsumer(context.Background(), paymentEventsTopic); err != nil {
+				errCh <- fmt.Errorf("kafka consumer error: %w", err)
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := ListenGRPC(service, productURL, grpcPort); err != nil {
+			errCh <- fmt.Errorf("gRPC server error: %w", err)
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(errCh)
+	}()
+
+	return <-errCh
+}
+
+func ListenGRPC(service Service, productURL string, port int) error {
+	productClient, err := product.NewClient(productURL)
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		productClient.Close()
+		return err
+	}
+
+	serv := grpc.NewServer()
+	pb.RegisterAlertServiceServer(serv, &grpcServer{
+		pb.UnimplementedAlertServiceServer{},
+		service,
+		productClient,
+	})
+	reflection.Register(serv)
+
+	return serv.Serve(lis)
+}
+
+func encodeSubscription(subscription *models.Subscription) *pb.AlertSubscription {
+	encoded := &pb.AlertSubscription{
+		Id:        uint64(subscription.ID),
+		AccountId: subscription.AccountID,
+		ProductId: subscription.ProductID,
+		Kind:      subscription.Kind,
+		Channel:   subscription.Channel,
+		Target:    subscription.Target,
+	}
+	if subscription.Kind == models.KindPriceBelow {
+		encoded.Threshold = subscription.Threshold.ToProto()
+	}
+	encoded.CreatedAt, _ = subscription.CreatedAt.MarshalBinary()
+	return encoded
+}
+
+func (server *grpcServer) Subscribe(ctx context.Context, request *pb.SubscribeRequest) (*pb.AlertSubscriptionResponse, error) {
+	p, err := server.productClient.GetProduct(ctx, request.ProductId)
+	if err != nil {
+		log.Println("Error getting product for alert", err)
+		return nil, err
+	}
+
+	subscription := models.Subscription{
+		AccountID: request.AccountId,
+		ProductID: request.ProductId,
+		Kind:      request.Kind,
+		Channel:   request.Channel,
+		Target:    request.Target,
+	}
+	if request.Threshold != nil {
+		subscription.Threshold = money.FromProto(request.Threshold)
+	}
+
+	created, err := server.service.Subscribe(ctx, subscription, models.ProductState{
+		ID:    p.ID,
+		Name:  p.Name,
+		Price: p.Price,
+		Stock: p.Stock,
+	})
+	if err != nil {
+		log.Println("Error subscribing to alert", err)
+		return nil, err
+	}
+	return &pb.AlertSubscriptionResponse{Subscription: encodeSubscription(created)}, nil
+}
+
+func (server *grpcServer) Unsubscribe(ctx context.Context, request *pb.UnsubscribeRequest) (*emptypb.Empty, error) {
+	err := server.service.Unsubscribe(ctx, request.AccountId, request.SubscriptionId)
+	if err != nil {
+		log.Println("Error unsubscribing from alert", err)
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (server *grpcServer) GetSubscriptions(ctx context.Context, request *wrapperspb.UInt64Value) (*pb.GetAlertSubscriptionsResponse, error) {
+	subscriptions, err := server.service.GetSubscriptions(ctx, request.Value)
+	if err != nil {
+		log.Println("Error getting alert subscriptions", err)
+		return nil, err
+	}
+
+	response := &pb.GetAlertSubscriptionsResponse{}
+	for _, subscription := range subscriptions {
+		response.Subscriptions = append(response.Subscriptions, encodeSubscription(subscription))
+	}
+	return response, nil
+}
+
+func (server *grpcServer) SetCheckoutReminders(ctx context.Context, request *pb.SetCheckoutRemindersRequest) (*emptypb.Empty, error) {
+	err := server.service.SetCheckoutReminders(ctx, request.AccountId, request.Enabled)
+	if err != nil {
+		log.Println("Error setting checkout reminders", err)
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (server *grpcServer) GetCheckoutReminders(ctx context.Context, request *wrapperspb.UInt64Value) (*wrapperspb.BoolValue, error) {
+	enabled, err := server.service.CheckoutRemindersEnabled(ctx, request.Value)
+	if err != nil {
+		log.Println("Error getting checkout reminders", err)
+		return nil, err
+	}
+	return wrapperspb.Bool(enabled), nil
+}
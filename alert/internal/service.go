@@ -0,0 +1,239 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/alert/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+var (
+	ErrInvalidKind      = errors.New("invalid alert kind")
+	ErrInvalidThreshold = errors.New("price threshold must be positive")
+	ErrInvalidTarget    = errors.New("invalid notification target")
+	ErrAlreadySatisfied = errors.New("alert condition is already met")
+)
+
+type Service interface {
+	Subscribe(ctx context.Context, subscription models.Subscription, current models.ProductState) (*models.Subscription, error)
+	Unsubscribe(ctx context.Context, accountId, subscriptionId uint64) error
+	GetSubscriptions(ctx context.Context, accountId uint64) ([]*models.Subscription, error)
+	HandleProductUpdated(ctx context.Context, product models.ProductState) error
+	HandleProductDeleted(ctx context.Context, productId string) error
+	SetCheckoutReminders(ctx context.Context, accountId uint64, enabled bool) error
+	CheckoutRemindersEnabled(ctx context.Context, accountId uint64) (bool, error)
+	HandleCheckoutAbandoned(ctx context.Context, checkout models.CheckoutEventData) error
+}
+
+// CheckoutRenewer opens a fresh checkout session for an order whose checkout was abandoned
+// and returns its URL.
+type CheckoutRenewer interface {
+	RenewCheckoutSession(ctx context.Context, orderId uint64) (string, error)
+}
+
+type alertService struct {
+	repository Repository
+	notifier   Notifier
+	converter  *money.Converter
+	mailer     Mailer
+	checkouts  CheckoutRenewer
+}
+
+func NewAlertService(repository Repository, notifier Notifier, converter *money.Converter, mailer Mailer, checkouts CheckoutRenewer) Service {
+	return &alertService{repository, notifier, converter, mailer, checkouts}
+}
+
+// Subscribe validates and stores an alert for the product in its current state. Subscribing
+// again to the same kind of alert on a product replaces the previous subscription.
+func (service alertService) Subscribe(ctx context.Context, subscription models.Subscription, current models.ProductState) (*models.Subscription, error) {
+	switch subscription.Kind {
+	case models.KindBackInStock:
+		subscription.Threshold = money.Money{}
+	case models.KindPriceBelow:
+		currency, err := money.Validate(subscription.Threshold.Currency)
+		if err != nil {
+			return nil, err
+		}
+		subscription.Threshold.Currency = currency
+		if subscription.Threshold.Amount <= 0 {
+			return nil, ErrInvalidThreshold
+		}
+	default:
+		return nil, ErrInvalidKind
+	}
+	if !validTarget(subscription.Channel, subscription.Target) {
+		return nil, ErrInvalidTarget
+	}
+
+	triggered, err := service.triggered(subscription, current)
+	if err != nil {
+		return nil, err
+	}
+	if triggered {
+		return nil, ErrAlreadySatisfied
+	}
+
+	existing, err := service.repository.GetActiveSubscription(ctx, subscription.AccountID, subscription.ProductID, subscription.Kind)
+	if err == nil {
+		subscription.ID = existing.ID
+		subscription.CreatedAt = existing.CreatedAt
+	} else if !errors.Is(err, ErrSubscriptionNotFound) {
+		return nil, err
+	} else {
+		subscription.CreatedAt = time.Now().UTC()
+	}
+
+	subscription.NotifiedAt = nil
+	if err = service.repository.PutSubscription(ctx, &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (service alertService) Unsubscribe(ctx context.Context, accountId, subscriptionId uint64) error {
+	subscription, err := service.repository.GetSubscription(ctx, subscriptionId)
+	if err != nil {
+		return err
+	}
+	if subscription.AccountID != accountId {
+		return ErrSubscriptionNotFound
+	}
+	return service.repository.DeleteSubscription(ctx, subscriptionId)
+}
+
+func (service alertService) GetSubscriptions(ctx context.Context, accountId uint64) ([]*models.Subscription, error) {
+	return service.repository.ListActiveSubscriptionsForAccount(ctx, accountId)
+}
+
+// HandleProductUpdated notifies every subscription whose condition the product now meets.
+// Subscriptions are only marked as notified once delivery succeeds, so a failed delivery is
+// retried on the next update.
+func (service alertService) HandleProductUpdated(ctx context.Context, product models.ProductState) error {
+	subscriptions, err := service.repository.ListActiveSubscriptionsForProduct(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		triggered, err := service.triggered(*subscription, product)
+		if err != nil {
+			log.Printf("Failed to evaluate alert %d: %v", subscription.ID, err)
+			continue
+		}
+		if !triggered {
+			continue
+		}
+
+		err = service.notifier.Notify(ctx, notificationFor(*subscription, product))
+		if err != nil {
+			log.Printf("Failed to deliver alert %d: %v", subscription.ID, err)
+			continue
+		}
+		if err = service.repository.MarkNotified(ctx, uint64(subscription.ID), time.Now().UTC()); err != nil {
+			log.Printf("Failed to mark alert %d as notified: %v", subscription.ID, err)
+		}
+	}
+	return nil
+}
+
+func (service alertService) HandleProductDeleted(ctx context.Context, productId string) error {
+	return service.repository.DeleteSubscriptionsForProduct(ctx, productId)
+}
+
+// SetCheckoutReminders turns abandoned checkout reminders on or off for the account.
+func (service alertService) SetCheckoutReminders(ctx context.Context, accountId uint64, enabled bool) error {
+	return service.repository.SetCheckoutRemindersOptOut(ctx, accountId, !enabled)
+}
+
+func (service alertService) CheckoutRemindersEnabled(ctx context.Context, accountId uint64) (bool, error) {
+	optedOut, err := service.repository.CheckoutRemindersOptedOut(ctx, accountId)
+	if err != nil {
+		return false, err
+	}
+	return !optedOut, nil
+}
+
+// HandleCheckoutAbandoned emails the customer a fresh checkout link for the abandoned order,
+// unless they opted out of reminders or were already reminded about the order. The reminder
+// is released again when no link could be opened, so that a redelivered event retries it.
+func (service alertService) HandleCheckoutAbandoned(ctx context.Context, checkout models.CheckoutEventData) error {
+	if !validTarget(models.ChannelEmail, checkout.Email) {
+		return ErrInvalidTarget
+	}
+	enabled, err := service.CheckoutRemindersEnabled(ctx, checkout.UserId)
+	if err != nil || !enabled {
+		return err
+	}
+
+	claimed, err := service.repository.ClaimCheckoutReminder(ctx, &models.CheckoutReminder{
+		OrderID:   checkout.OrderId,
+		AccountID: checkout.UserId,
+		SentAt:    time.Now().UTC(),
+	})
+	if err != nil || !claimed {
+		return err
+	}
+
+	checkoutURL, err := service.checkouts.RenewCheckoutSession(ctx, checkout.OrderId)
+	if err != nil {
+		if releaseErr := service.repository.DeleteCheckoutReminder(ctx, checkout.OrderId); releaseErr != nil {
+			log.Printf("Failed to release checkout reminder for order %d: %v", checkout.OrderId, releaseErr)
+		}
+		return err
+	}
+
+	subject, body := checkoutReminderEmail(checkout, checkoutURL)
+	return service.mailer.Send(ctx, checkout.Email, subject, body)
+}
+
+// triggered reports whether the product meets the subscription's condition. Prices are
+// compared in the threshold currency.
+func (service alertService) triggered(subscription models.Subscription, product models.ProductState) (bool, error) {
+	switch subscription.Kind {
+	case models.KindBackInStock:
+		return product.InStock(), nil
+	case models.KindPriceBelow:
+		price, err := service.converter.Convert(product.Price, subscription.Threshold.Currency)
+		if err != nil {
+			return false, err
+		}
+		cmp, err := price.Cmp(subscription.Threshold)
+		if err != nil {
+			return false, err
+		}
+		return cmp < 0, nil
+	}
+	return false, ErrInvalidKind
+}
+
+func notificationFor(subscription models.Subscription, product models.ProductState) Notification {
+	notification := Notification{
+		Subscription: subscription,
+		Product:      product,
+	}
+	switch subscription.Kind {
+	case models.KindBackInStock:
+		notification.Subject = fmt.Sprintf("%s is back in stock", product.Name)
+		notification.Message = fmt.Sprintf("%s is available again for %s.", product.Name, product.Price)
+	case models.KindPriceBelow:
+		notification.Subject = fmt.Sprintf("Price drop on %s", product.Name)
+		notification.Message = fmt.Sprintf("%s now costs %s, below your alert price of %s.", product.Name, product.Price, subscription.Threshold)
+	}
+	return notification
+}
+
+func checkoutReminderEmail(checkout models.CheckoutEventData, checkoutURL string) (string, string) {
+	greeting := "Hi,"
+	if checkout.Name != "" {
+		greeting = fmt.Sprintf("Hi %s,", checkout.Name)
+	}
+	subject := fmt.Sprintf("Your order #%d is waiting for you", checkout.OrderId)
+	body := fmt.Sprintf("%s\n\nYou left order #%d before paying for it. You can complete your purchase here:\n\n%s\n\n"+
+		"If you would rather not receive these reminders, you can turn them off in your account settings.",
+		greeting, checkout.OrderId, checkoutURL)
+	return subject, body
+}
@@ -0,0 +1,58 @@
+package models
+
+import "github.com/rasadov/EcommerceAPI/pkg/money"
+
+// ProductEventData mirrors product/models.EventData
+type ProductEventData struct {
+	ProductID   *string  `json:"product_id"`
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	Price       *float64 `json:"price"`
+	Amount      *int64   `json:"amount"`
+	Currency    *string  `json:"currency"`
+	AccountID   *int     `json:"accountID"`
+	Stock       *int     `json:"stock"`
+}
+
+type ProductEvent struct {
+	Type string           `json:"type"`
+	Data ProductEventData `json:"data"`
+}
+
+// State returns the product state carried by the event.
+func (event ProductEvent) State() ProductState {
+	state := ProductState{Stock: event.Data.Stock}
+	if event.Data.ProductID != nil {
+		state.ID = *event.Data.ProductID
+	}
+	if event.Data.Name != nil {
+		state.Name = *event.Data.Name
+	}
+
+	currency := money.DefaultCurrency
+	if event.Data.Currency != nil {
+		currency = *event.Data.Currency
+	}
+	if event.Data.Amount != nil {
+		state.Price = money.New(*event.Data.Amount, currency)
+	} else if event.Data.Price != nil {
+		state.Price = money.FromMajor(*event.Data.Price, currency)
+	}
+	return state
+}
+
+// CheckoutEventData mirrors payment/models.CheckoutEventData
+type CheckoutEventData struct {
+	OrderId     uint64 `json:"order_id"`
+	UserId      uint64 `json:"user_id"`
+	Email       string `json:"email"`
+	Name        string `json:"name"`
+	Currency    string `json:"currency"`
+	CheckoutURL string `json:"checkout_url"`
+	OpenedAt    string `json:"opened_at"`
+}
+
+type CheckoutEvent struct {
+	Type string            `json:"type"`
+	Data CheckoutEventData `json:"data"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// CheckoutReminderOptOut records an account that does not want abandoned checkout reminders.
+type CheckoutReminderOptOut struct {
+	AccountID uint64 `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time
+}
+
+// CheckoutReminder records the reminder sent for an abandoned order checkout, so that each
+// order is only reminded about once.
+type CheckoutReminder struct {
+	OrderID   uint64 `gorm:"primaryKey;autoIncrement:false"`
+	AccountID uint64 `gorm:"index"`
+	SentAt    time.Time
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+const (
+	KindBackInStock = "back_in_stock"
+	KindPriceBelow  = "price_below"
+
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Subscription asks to be notified once when a product comes back in stock or its price
+// drops below Threshold. It stops being active after the notification is delivered.
+type Subscription struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	AccountID  uint64 `gorm:"index"`
+	ProductID  string `gorm:"index"`
+	Kind       string
+	Threshold  money.Money `gorm:"embedded;embeddedPrefix:threshold_"`
+	Channel    string
+	Target     string
+	CreatedAt  time.Time
+	NotifiedAt *time.Time
+}
+
+// ProductState is the part of a product that alerts are evaluated against.
+type ProductState struct {
+	ID    string
+	Name  string
+	Price money.Money
+	// Stock is nil when the seller does not track inventory
+	Stock *int
+}
+
+func (p ProductState) InStock() bool {
+	return p.Stock == nil || *p.Stock > 0
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/alert/internal"
+	"github.com/rasadov/EcommerceAPI/alert/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMailer struct {
+	to, subject, body string
+}
+
+func (m *recordingMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.to, m.subject, m.body = to, subject, body
+	return nil
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	var payload internal.WebhookPayload
+	var signature string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Alert-Signature")
+		_ = json.Unmarshal(body, &payload)
+	}))
+	defer server.Close()
+
+	notifier := internal.NewWebhookNotifier("secret")
+	err := notifier.Notify(context.Background(), internal.Notification{
+		Subscription: models.Subscription{ID: 7, Kind: models.KindPriceBelow, Channel: models.ChannelWebhook, Target: server.URL},
+		Product:      models.ProductState{ID: "p1", Name: "Lamp", Price: money.New(999, "USD")},
+		Message:      "cheaper",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, uint(7), payload.SubscriptionID)
+	assert.Equal(t, int64(999), payload.Price)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), signature)
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := internal.NewWebhookNotifier("").Notify(context.Background(), internal.Notification{
+		Subscription: models.Subscription{Channel: models.ChannelWebhook, Target: server.URL},
+	})
+	assert.Error(t, err)
+}
+
+func TestChannelNotifier_Notify(t *testing.T) {
+	mailer := &recordingMailer{}
+	notifier := internal.ChannelNotifier{
+		models.ChannelEmail: internal.NewEmailNotifier(mailer),
+	}
+
+	err := notifier.Notify(context.Background(), internal.Notification{
+		Subscription: models.Subscription{Channel: models.ChannelEmail, Target: "buyer@example.com"},
+		Subject:      "Lamp is back in stock",
+		Message:      "Lamp is available again.",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "buyer@example.com", mailer.to)
+	assert.Equal(t, "Lamp is back in stock", mailer.subject)
+
+	err = notifier.Notify(context.Background(), internal.Notification{
+		Subscription: models.Subscription{Channel: models.ChannelWebhook},
+	})
+	assert.ErrorIs(t, err, internal.ErrUnsupportedChannel)
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/alert/internal"
+	"github.com/rasadov/EcommerceAPI/alert/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRenewer hands out a fresh checkout link per renewal, or fails with err.
+type fakeRenewer struct {
+	renewed []uint64
+	err     error
+}
+
+func (r *fakeRenewer) RenewCheckoutSession(ctx context.Context, orderId uint64) (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	r.renewed = append(r.renewed, orderId)
+	return "https://checkout.test/renewed", nil
+}
+
+func abandonedCheckout(orderId uint64) models.CheckoutEvent {
+	return models.CheckoutEvent{
+		Type: "checkout_abandoned",
+		Data: models.CheckoutEventData{
+			OrderId:     orderId,
+			UserId:      1,
+			Email:       "buyer@example.com",
+			Name:        "Ann",
+			Currency:    "USD",
+			CheckoutURL: "https://checkout.test/abandoned",
+		},
+	}
+}
+
+func TestAlertService_CheckoutReminders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reminds once with a fresh link", func(t *testing.T) {
+		mailer, renewer := &recordingMailer{}, &fakeRenewer{}
+		service, _ := setupTestServiceWith(t, mailer, renewer)
+		consumer := internal.NewEventConsumer(nil, service)
+
+		consumer.HandleCheckoutEvent(ctx, abandonedCheckout(7))
+		assert.Equal(t, []uint64{7}, renewer.renewed)
+		assert.Equal(t, "buyer@example.com", mailer.to)
+		assert.Contains(t, mailer.subject, "#7")
+		assert.Contains(t, mailer.body, "Hi Ann,")
+		assert.Contains(t, mailer.body, "https://checkout.test/renewed")
+		assert.NotContains(t, mailer.body, "https://checkout.test/abandoned")
+
+		// A redelivered event is not reminded about again
+		*mailer = recordingMailer{}
+		consumer.HandleCheckoutEvent(ctx, abandonedCheckout(7))
+		assert.Equal(t, []uint64{7}, renewer.renewed)
+		assert.Empty(t, mailer.to)
+	})
+
+	t.Run("Opted out accounts are not reminded", func(t *testing.T) {
+		mailer, renewer := &recordingMailer{}, &fakeRenewer{}
+		service, _ := setupTestServiceWith(t, mailer, renewer)
+
+		enabled, err := service.CheckoutRemindersEnabled(ctx, 1)
+		require.NoError(t, err)
+		assert.True(t, enabled)
+
+		require.NoError(t, service.SetCheckoutReminders(ctx, 1, false))
+		require.NoError(t, service.SetCheckoutReminders(ctx, 1, false))
+		enabled, err = service.CheckoutRemindersEnabled(ctx, 1)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+
+		require.NoError(t, service.HandleCheckoutAbandoned(ctx, abandonedCheckout(7).Data))
+		assert.Empty(t, renewer.renewed)
+		assert.Empty(t, mailer.to)
+
+		require.NoError(t, service.SetCheckoutReminders(ctx, 1, true))
+		require.NoError(t, service.HandleCheckoutAbandoned(ctx, abandonedCheckout(7).Data))
+		assert.Equal(t, []uint64{7}, renewer.renewed)
+		assert.Equal(t, "buyer@example.com", mailer.to)
+	})
+
+	t.Run("Failed renewals are retried", func(t *testing.T) {
+		mailer, renewer := &recordingMailer{}, &fakeRenewer{err: errors.New("payment unavailable")}
+		service, _ := setupTestServiceWith(t, mailer, renewer)
+
+		assert.Error(t, service.HandleCheckoutAbandoned(ctx, abandonedCheckout(7).Data))
+		assert.Empty(t, mailer.to)
+
+		renewer.err = nil
+		require.NoError(t, service.HandleCheckoutAbandoned(ctx, abandonedCheckout(7).Data))
+		assert.Equal(t, []uint64{7}, renewer.renewed)
+		assert.Equal(t, "buyer@example.com", mailer.to)
+	})
+
+	t.Run("Checkouts without an email are skipped", func(t *testing.T) {
+		mailer, renewer := &recordingMailer{}, &fakeRenewer{}
+		service, _ := setupTestServiceWith(t, mailer, renewer)
+
+		checkout := abandonedCheckout(7).Data
+		checkout.Email = ""
+		assert.ErrorIs(t, service.HandleCheckoutAbandoned(ctx, checkout), internal.ErrInvalidTarget)
+		assert.Empty(t, renewer.renewed)
+	})
+}
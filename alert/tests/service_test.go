@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/alert/internal"
+	"github.com/rasadov/EcommerceAPI/alert/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Test helper to create a service backed by an in-memory SQLite database and notifier
+func setupTestService(t *testing.T) (internal.Service, *internal.MemoryNotifier) {
+	return setupTestServiceWith(t, internal.LogMailer{}, nil)
+}
+
+func setupTestServiceWith(t *testing.T, mailer internal.Mailer, checkouts internal.CheckoutRenewer) (internal.Service, *internal.MemoryNotifier) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	repo, err := internal.NewPostgresRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(repo.Close)
+
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"base": "USD", "rates": {"EUR": 0.5}}`), 0o644))
+	converter := money.NewConverter(&money.FileRateSource{Path: path})
+	require.NoError(t, converter.Refresh(context.Background()))
+
+	notifier := &internal.MemoryNotifier{}
+	return internal.NewAlertService(repo, notifier, converter, mailer, checkouts), notifier
+}
+
+func stock(n int) *int {
+	return &n
+}
+
+func TestAlertService_BackInStock(t *testing.T) {
+	ctx := context.Background()
+	service, notifier := setupTestService(t)
+	product := models.ProductState{ID: "p1", Name: "Lamp", Price: money.New(2000, "USD"), Stock: stock(0)}
+
+	_, err := service.Subscribe(ctx, models.Subscription{
+		AccountID: 1,
+		ProductID: "p1",
+		Kind:      models.KindBackInStock,
+		Channel:   models.ChannelEmail,
+		Target:    "buyer@example.com",
+	}, product)
+	require.NoError(t, err)
+
+	// Still out of stock
+	require.NoError(t, service.HandleProductUpdated(ctx, product))
+	assert.Empty(t, notifier.Notifications())
+
+	product.Stock = stock(3)
+	require.NoError(t, service.HandleProductUpdated(ctx, product))
+	require.Len(t, notifier.Notifications(), 1)
+	assert.Equal(t, "Lamp is back in stock", notifier.Notifications()[0].Subject)
+
+	// Alerts fire only once
+	require.NoError(t, service.HandleProductUpdated(ctx, product))
+	assert.Len(t, notifier.Notifications(), 1)
+
+	subscriptions, err := service.GetSubscriptions(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, subscriptions)
+}
+
+func TestAlertService_PriceBelow(t *testing.T) {
+	ctx := context.Background()
+	service, notifier := setupTestService(t)
+	product := models.ProductState{ID: "p1", Name: "Lamp", Price: money.New(2000, "USD")}
+
+	_, err := service.Subscribe(ctx, models.Subscription{
+		AccountID: 1,
+		ProductID: "p1",
+		Kind:      models.KindPriceBelow,
+		Threshold: money.New(800, "EUR"),
+		Channel:   models.ChannelWebhook,
+		Target:    "https://example.com/hook",
+	}, product)
+	require.NoError(t, err)
+
+	// 16.00 USD is exactly 8.00 EUR, which is not below the threshold
+	product.Price = money.New(1600, "USD")
+	require.NoError(t, service.HandleProductUpdated(ctx, product))
+	assert.Empty(t, notifier.Notifications())
+
+	product.Price = money.New(1598, "USD")
+	require.NoError(t, service.HandleProductUpdated(ctx, product))
+	require.Len(t, notifier.Notifications(), 1)
+	assert.Equal(t, models.ChannelWebhook, notifier.Notifications()[0].Subscription.Channel)
+}
+
+func TestAlertService_Subscribe(t *testing.T) {
+	ctx := context.Background()
+	service, _ := setupTestService(t)
+	inStock := models.ProductState{ID: "p1", Name: "Lamp", Price: money.New(2000, "USD")}
+
+	t.Run("Condition already met", func(t *testing.T) {
+		_, err := service.Subscribe(ctx, models.Subscription{
+			AccountID: 1, ProductID: "p1", Kind: models.KindBackInStock,
+			Channel: models.ChannelEmail, Target: "buyer@example.com",
+		}, inStock)
+		assert.ErrorIs(t, err, internal.ErrAlreadySatisfied)
+	})
+
+	t.Run("Invalid target", func(t *testing.T) {
+		_, err := service.Subscribe(ctx, models.Subscription{
+			AccountID: 1, ProductID: "p1", Kind: models.KindPriceBelow, Threshold: money.New(100, "USD"),
+			Channel: models.ChannelWebhook, Target: "ftp://example.com",
+		}, inStock)
+		assert.ErrorIs(t, err, internal.ErrInvalidTarget)
+	})
+
+	t.Run("Invalid threshold", func(t *testing.T) {
+		_, err := service.Subscribe(ctx, models.Subscription{
+			AccountID: 1, ProductID: "p1", Kind: models.KindPriceBelow,
+			Channel: models.ChannelEmail, Target: "buyer@example.com",
+		}, inStock)
+		assert.ErrorIs(t, err, internal.ErrInvalidThreshold)
+	})
+
+	t.Run("Subscribing again replaces the alert", func(t *testing.T) {
+		first, err := service.Subscribe(ctx, models.Subscription{
+			AccountID: 1, ProductID: "p1", Kind: models.KindPriceBelow, Threshold: money.New(1500, "USD"),
+			Channel: models.ChannelEmail, Target: "buyer@example.com",
+		}, inStock)
+		require.NoError(t, err)
+		second, err := service.Subscribe(ctx, models.Subscription{
+			AccountID: 1, ProductID: "p1", Kind: models.KindPriceBelow, Threshold: money.New(1000, "USD"),
+			Channel: models.ChannelEmail, Target: "buyer@example.com",
+		}, inStock)
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+
+		subscriptions, err := service.GetSubscriptions(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, subscriptions, 1)
+		assert.Equal(t, money.New(1000, "USD"), subscriptions[0].Threshold)
+	})
+
+	t.Run("Only the owner can unsubscribe", func(t *testing.T) {
+		subscriptions, err := service.GetSubscriptions(ctx, 1)
+		require.NoError(t, err)
+		require.NotEmpty(t, subscriptions)
+
+		err = service.Unsubscribe(ctx, 2, uint64(subscriptions[0].ID))
+		assert.ErrorIs(t, err, internal.ErrSubscriptionNotFound)
+		assert.NoError(t, service.Unsubscribe(ctx, 1, uint64(subscriptions[0].ID)))
+	})
+}
+
+func TestEventConsumer_HandleEvent(t *testing.T) {
+	ctx := context.Background()
+	service, notifier := setupTestService(t)
+	consumer := internal.NewEventConsumer(nil, service)
+
+	_, err := service.Subscribe(ctx, models.Subscription{
+		AccountID: 1, ProductID: "p1", Kind: models.KindBackInStock,
+		Channel: models.ChannelEmail, Target: "buyer@example.com",
+	}, models.ProductState{ID: "p1", Stock: stock(0)})
+	require.NoError(t, err)
+
+	id, name, amount, currency := "p1", "Lamp", int64(1999), "USD"
+	consumer.HandleEvent(ctx, models.ProductEvent{
+		Type: "product_updated",
+		Data: models.ProductEventData{ProductID: &id, Name: &name, Amount: &amount, Currency: &currency, Stock: stock(1)},
+	})
+
+	require.Len(t, notifier.Notifications(), 1)
+	assert.Equal(t, money.New(1999, "USD"), notifier.Notifications()[0].Product.Price)
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"log"
+
+	"github.com/rasadov/EcommerceAPI/cart/models"
+	"github.com/rasadov/EcommerceAPI/cart/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type Client struct {
+	conn    *grpc.ClientConn
+	service pb.CartServiceClient
+}
+
+func NewClient(url string) (*Client, error) {
+	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	c := pb.NewCartServiceClient(conn)
+	return &Client{conn, c}, nil
+}
+
+func (client *Client) Close() {
+	err := client.conn.Close()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// GetCart returns the owner's cart, revalidated against the products' current prices and stock.
+func (client *Client) GetCart(ctx context.Context, owner models.Owner) (*models.Cart, error) {
+	r, err := client.service.GetCart(ctx, &pb.CartRequest{Owner: encodeOwner(owner)})
+	if err != nil {
+		return nil, err
+	}
+	return decodeCart(r.Cart)
+}
+
+func (client *Client) AddItem(ctx context.Context, owner models.Owner, productId string, quantity int) (*models.Cart, error) {
+	r, err := client.service.AddCartProduct(ctx, &pb.CartProductRequest{
+		Owner:     encodeOwner(owner),
+		ProductId: productId,
+		Quantity:  uint32(quantity),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeCart(r.Cart)
+}
+
+// UpdateItem sets the quantity of a product in the cart. A quantity of 0 removes it.
+func (client *Client) UpdateItem(ctx context.Context, owner models.Owner, productId string, quantity int) (*models.Cart, error) {
+	r, err := client.service.UpdateCartProduct(ctx, &pb.CartProductRequest{
+		Owner:     encodeOwner(owner),
+		ProductId: productId,
+		Quantity:  uint32(quantity),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeCart(r.Cart)
+}
+
+func (client *Client) RemoveItem(ctx context.Context, owner models.Owner, productId string) (*models.Cart, error) {
+	r, err := client.service.RemoveCartProduct(ctx, &pb.CartProductRequest{
+		Owner:     encodeOwner(owner),
+		ProductId: productId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeCart(r.Cart)
+}
+
+func (client *Client) ClearCart(ctx context.Context, owner models.Owner) error {
+	_, err := client.service.ClearCart(ctx, &pb.CartRequest{Owner: encodeOwner(owner)})
+	return err
+}
+
+// MergeCarts moves a session's cart into the account's cart after the session signs in.
+func (client *Client) MergeCarts(ctx context.Context, sessionId string, accountId uint64) (*models.Cart, error) {
+	r, err := client.service.MergeCarts(ctx, &pb.MergeCartsRequest{
+		SessionId: sessionId,
+		AccountId: accountId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeCart(r.Cart)
+}
+
+// SetCoupon applies a promotion code to the owner's cart; an empty code removes it.
+func (client *Client) SetCoupon(ctx context.Context, owner models.Owner, code string) (*models.Cart, error) {
+	r, err := client.service.SetCartCoupon(ctx, &pb.CartCouponRequest{
+		Owner: encodeOwner(owner),
+		Code:  code,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeCart(r.Cart)
+}
+
+func encodeOwner(owner models.Owner) *pb.CartOwner {
+	return &pb.CartOwner{
+		AccountId: owner.AccountID,
+		SessionId: owner.SessionID,
+	}
+}
+
+func decodeCart(cartProto *pb.Cart) (*models.Cart, error) {
+	cart := &models.Cart{Items: []models.CartItem{}, CouponCode: cartProto.CouponCode}
+	if accountId := cartProto.GetOwner().GetAccountId(); accountId != 0 {
+		cart.AccountID = &accountId
+	}
+	if sessionId := cartProto.GetOwner().GetSessionId(); sessionId != "" {
+		cart.SessionID = &sessionId
+	}
+	if len(cartProto.ExpiresAt) > 0 {
+		if err := cart.ExpiresAt.UnmarshalBinary(cartProto.ExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range cartProto.Products {
+		item := models.CartItem{
+			ProductID: p.ProductId,
+			Name:      p.Name,
+			Quantity:  int(p.Quantity),
+			UnitPrice: money.FromProto(p.GetUnitPrice()),
+			Available: p.Available,
+		}
+		if p.PreviousUnitPrice != nil {
+			previous := money.FromProto(p.PreviousUnitPrice)
+			item.PreviousUnitPrice = &previous
+		}
+		if err := item.AddedAt.UnmarshalBinary(p.AddedAt); err != nil {
+			return nil, err
+		}
+		cart.Items = append(cart.Items, item)
+	}
+	return cart, nil
+}
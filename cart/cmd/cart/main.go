@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/cart/config"
+	"github.com/rasadov/EcommerceAPI/cart/internal"
+	"github.com/tinrab/retry"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	var repository internal.Repository
+
+	retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
+		db, err := gorm.Open(postgres.Open(config.DatabaseUrl), &gorm.Config{})
+		if err != nil {
+			log.Println(err)
+		}
+		repository, err = internal.NewPostgresRepository(db)
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	})
+	defer repository.Close()
+	log.Println("Listening on port 8080...")
+
+	service := internal.NewCartService(repository, config.CartTTL)
+	log.Fatal(internal.ListenGRPC(service, config.ProductUrl, 8080))
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+var (
+	DatabaseUrl string
+	ProductUrl  string
+	CartTTL     time.Duration
+)
+
+func init() {
+	DatabaseUrl = os.Getenv("DATABASE_URL")
+	ProductUrl = os.Getenv("PRODUCT_SERVICE_URL")
+	CartTTL = durationOrDefault(os.Getenv("CART_TTL"), 30*24*time.Hour)
+}
+
+func durationOrDefault(value string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
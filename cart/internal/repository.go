@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/cart/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCartNotFound     = errors.New("cart not found")
+	ErrProductNotInCart = errors.New("product not in cart")
+)
+
+type Repository interface {
+	Close()
+	GetCart(ctx context.Context, owner models.Owner) (*models.Cart, error)
+	CreateCart(ctx context.Context, cart *models.Cart) error
+	TouchCart(ctx context.Context, cartId uint, expiresAt time.Time) error
+	SetCoupon(ctx context.Context, cartId uint, code string) error
+	SaveItems(ctx context.Context, items []*models.CartItem) error
+	RemoveItem(ctx context.Context, cartId uint, productId string) error
+	DeleteCart(ctx context.Context, cartId uint) error
+	MergeCarts(ctx context.Context, fromCartId uint, to *models.Cart, items []*models.CartItem) error
+	DeleteExpiredCarts(ctx context.Context, now time.Time) (int64, error)
+}
+
+type postgresRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresRepository(db *gorm.DB) (Repository, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	err = sqlDB.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&models.Cart{}, &models.CartItem{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresRepository{db}, nil
+}
+
+func (repository *postgresRepository) Close() {
+	sqlDB, err := repository.db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+		if err != nil {
+			log.Println("Error closing postgres repository")
+			log.Println(err)
+		}
+	}
+}
+
+func (repository *postgresRepository) GetCart(ctx context.Context, owner models.Owner) (*models.Cart, error) {
+	query := repository.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("added_at, id")
+		})
+	if owner.AccountID != 0 {
+		query = query.Where("account_id = ?", owner.AccountID)
+	} else {
+		query = query.Where("session_id = ?", owner.SessionID)
+	}
+
+	var cart models.Cart
+	err := query.First(&cart).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCartNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func (repository *postgresRepository) CreateCart(ctx context.Context, cart *models.Cart) error {
+	return repository.db.WithContext(ctx).Create(cart).Error
+}
+
+func (repository *postgresRepository) TouchCart(ctx context.Context, cartId uint, expiresAt time.Time) error {
+	return repository.db.WithContext(ctx).
+		Model(&models.Cart{ID: cartId}).
+		Update("expires_at", expiresAt).Error
+}
+
+func (repository *postgresRepository) SetCoupon(ctx context.Context, cartId uint, code string) error {
+	return repository.db.WithContext(ctx).
+		Model(&models.Cart{ID: cartId}).
+		Update("coupon_code", code).Error
+}
+
+// SaveItems inserts the items, or updates the quantity and product details of items
+// whose product is already in the cart.
+func (repository *postgresRepository) SaveItems(ctx context.Context, items []*models.CartItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return repository.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cart_id"}, {Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quantity", "name", "unit_price_amount", "unit_price_currency"}),
+	}).Create(items).Error
+}
+
+func (repository *postgresRepository) RemoveItem(ctx context.Context, cartId uint, productId string) error {
+	result := repository.db.WithContext(ctx).
+		Where("cart_id = ? AND product_id = ?", cartId, productId).
+		Delete(&models.CartItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrProductNotInCart
+	}
+	return nil
+}
+
+func (repository *postgresRepository) DeleteCart(ctx context.Context, cartId uint) error {
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteCarts(tx, []uint{cartId})
+	})
+}
+
+// MergeCarts saves items into the cart to and deletes the cart fromCartId, in one transaction.
+func (repository *postgresRepository) MergeCarts(ctx context.Context, fromCartId uint, to *models.Cart, items []*models.CartItem) error {
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if to.ID == 0 {
+			if err := tx.Create(to).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Model(to).Updates(map[string]any{
+			"expires_at":  to.ExpiresAt,
+			"coupon_code": to.CouponCode,
+		}).Error; err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			item.CartID = to.ID
+		}
+		if err := deleteCarts(tx, []uint{fromCartId}); err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "cart_id"}, {Name: "product_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"quantity", "name", "unit_price_amount", "unit_price_currency"}),
+		}).Create(items).Error
+	})
+}
+
+// DeleteExpiredCarts deletes the carts that expired before now, returning how many were deleted.
+func (repository *postgresRepository) DeleteExpiredCarts(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	err := repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cartIds []uint
+		err := tx.Model(&models.Cart{}).Where("expires_at < ?", now).Pluck("id", &cartIds).Error
+		if err != nil || len(cartIds) == 0 {
+			return err
+		}
+		deleted = int64(len(cartIds))
+		return deleteCarts(tx, cartIds)
+	})
+	return deleted, err
+}
+
+func deleteCarts(tx *gorm.DB, cartIds []uint) error {
+	// Items are deleted explicitly as well, for databases without foreign key enforcement
+	err := tx.Where("cart_id IN ?", cartIds).Delete(&models.CartItem{}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", cartIds).Delete(&models.Cart{}).Error
+}
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/cart/models"
+	"github.com/rasadov/EcommerceAPI/cart/proto/pb"
+	product "github.com/rasadov/EcommerceAPI/product/client"
+	productmodels "github.com/rasadov/EcommerceAPI/product/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type grpcServer struct {
+	pb.UnimplementedCartServiceServer
+	service       Service
+	productClient *product.Client
+}
+
+func ListenGRPC(service Service, productURL string, port int) error {
+	productClient, err := product.NewClient(productURL)
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		productClient.Close()
+		return err
+	}
+
+	serv := grpc.NewServer()
+	pb.RegisterCartServiceServer(serv, &grpcServer{
+		pb.UnimplementedCartServiceServer{},
+		service,
+		productClient,
+	})
+	reflection.Register(serv)
+
+	go deleteExpiredCarts(context.Background(), service, time.Hour)
+
+	return serv.Serve(lis)
+}
+
+func (server *grpcServer) GetCart(ctx context.Context, request *pb.CartRequest) (*pb.CartResponse, error) {
+	cart, err := server.service.GetCart(ctx, decodeOwner(request.Owner))
+	if err != nil {
+		log.Println("Error getting cart", err)
+		return nil, err
+	}
+	return server.validatedResponse(ctx, cart)
+}
+
+func (server *grpcServer) AddCartProduct(ctx context.Context, request *pb.CartProductRequest) (*pb.CartResponse, error) {
+	p, err := server.productClient.GetProduct(ctx, request.ProductId)
+	if err != nil {
+		log.Println("Error getting cart product", err)
+		return nil, err
+	}
+
+	cart, err := server.service.AddItem(ctx, decodeOwner(request.Owner), productState(p), int(request.Quantity))
+	if err != nil {
+		log.Println("Error adding cart product", err)
+		return nil, err
+	}
+	return server.validatedResponse(ctx, cart)
+}
+
+func (server *grpcServer) UpdateCartProduct(ctx context.Context, request *pb.CartProductRequest) (*pb.CartResponse, error) {
+	if request.Quantity == 0 {
+		return server.RemoveCartProduct(ctx, request)
+	}
+
+	p, err := server.productClient.GetProduct(ctx, request.ProductId)
+	if err != nil {
+		log.Println("Error getting cart product", err)
+		return nil, err
+	}
+
+	cart, err := server.service.UpdateItem(ctx, decodeOwner(request.Owner), productState(p), int(request.Quantity))
+	if err != nil {
+		log.Println("Error updating cart product", err)
+		return nil, err
+	}
+	return server.validatedResponse(ctx, cart)
+}
+
+func (server *grpcServer) RemoveCartProduct(ctx context.Context, request *pb.CartProductRequest) (*pb.CartResponse, error) {
+	cart, err := server.service.RemoveItem(ctx, decodeOwner(request.Owner), request.ProductId)
+	if err != nil {
+		log.Println("Error removing cart product", err)
+		return nil, err
+	}
+	return server.validatedResponse(ctx, cart)
+}
+
+func (server *grpcServer) ClearCart(ctx context.Context, request *pb.CartRequest) (*emptypb.Empty, error) {
+	err := server.service.ClearCart(ctx, decodeOwner(request.Owner))
+	if err != nil {
+		log.Println("Error clearing cart", err)
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (server *grpcServer) MergeCarts(ctx context.Context, request *pb.MergeCartsRequest) (*pb.CartResponse, error) {
+	cart, err := server.service.MergeCarts(ctx, request.SessionId, request.AccountId)
+	if err != nil {
+		log.Println("Error merging carts", err)
+		return nil, err
+	}
+	return server.validatedResponse(ctx, cart)
+}
+
+func (server *grpcServer) SetCartCoupon(ctx context.Context, request *pb.CartCouponRequest) (*pb.CartResponse, error) {
+	cart, err := server.service.SetCoupon(ctx, decodeOwner(request.Owner), strings.ToUpper(strings.TrimSpace(request.Code)))
+	if err != nil {
+		log.Println("Error setting cart coupon", err)
+		return nil, err
+	}
+	return server.validatedResponse(ctx, cart)
+}
+
+// validatedResponse revalidates the cart against the product service before returning it.
+func (server *grpcServer) validatedResponse(ctx context.Context, cart *models.Cart) (*pb.CartResponse, error) {
+	if len(cart.Items) > 0 {
+		productIds := make([]string, 0, len(cart.Items))
+		for _, item := range cart.Items {
+			productIds = append(productIds, item.ProductID)
+		}
+		products, err := server.productClient.GetProducts(ctx, 0, 0, productIds, "")
+		if err != nil {
+			log.Println("Error getting cart products", err)
+			return nil, err
+		}
+
+		states := make([]models.ProductState, 0, len(products))
+		for i := range products {
+			states = append(states, productState(&products[i]))
+		}
+		cart, err = server.service.Revalidate(ctx, cart, states)
+		if err != nil {
+			log.Println("Error revalidating cart", err)
+			return nil, err
+		}
+	}
+	return &pb.CartResponse{Cart: encodeCart(cart)}, nil
+}
+
+// deleteExpiredCarts deletes expired carts every interval until ctx is done.
+func deleteExpiredCarts(ctx context.Context, service Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		deleted, err := service.DeleteExpiredCarts(ctx)
+		if err != nil {
+			log.Println("Error deleting expired carts", err)
+		} else if deleted > 0 {
+			log.Printf("Deleted %d expired carts", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func productState(p *productmodels.Product) models.ProductState {
+	return models.ProductState{
+		ID:    p.ID,
+		Name:  p.Name,
+		Price: p.Price,
+		Stock: p.Stock,
+	}
+}
+
+func decodeOwner(owner *pb.CartOwner) models.Owner {
+	return models.Owner{
+		AccountID: owner.GetAccountId(),
+		SessionID: owner.GetSessionId(),
+	}
+}
+
+func encodeCart(cart *models.Cart) *pb.Cart {
+	encoded := &pb.Cart{
+		Owner:      &pb.CartOwner{},
+		Products:   []*pb.CartProduct{},
+		CouponCode: cart.CouponCode,
+	}
+	if cart.AccountID != nil {
+		encoded.Owner.AccountId = *cart.AccountID
+	}
+	if cart.SessionID != nil {
+		encoded.Owner.SessionId = *cart.SessionID
+	}
+	if !cart.ExpiresAt.IsZero() {
+		encoded.ExpiresAt, _ = cart.ExpiresAt.MarshalBinary()
+	}
+
+	for _, item := range cart.Items {
+		encodedItem := &pb.CartProduct{
+			ProductId: item.ProductID,
+			Name:      item.Name,
+			Quantity:  uint32(item.Quantity),
+			UnitPrice: item.UnitPrice.ToProto(),
+			Available: item.Available,
+		}
+		if item.PreviousUnitPrice != nil {
+			encodedItem.PreviousUnitPrice = item.PreviousUnitPrice.ToProto()
+		}
+		encodedItem.AddedAt, _ = item.AddedAt.MarshalBinary()
+		encoded.Products = append(encoded.Products, encodedItem)
+	}
+	return encoded
+}
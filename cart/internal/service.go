@@ -0,0 +1,267 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/cart/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrInvalidOwner      = status.Error(codes.InvalidArgument, "a cart belongs to either an account or a session")
+	ErrInvalidQuantity   = status.Error(codes.InvalidArgument, "quantity must be positive")
+	ErrInsufficientStock = status.Error(codes.FailedPrecondition, "not enough stock for the requested quantity")
+	ErrEmptyCart         = status.Error(codes.FailedPrecondition, "cart is empty")
+)
+
+type Service interface {
+	GetCart(ctx context.Context, owner models.Owner) (*models.Cart, error)
+	Revalidate(ctx context.Context, cart *models.Cart, products []models.ProductState) (*models.Cart, error)
+	AddItem(ctx context.Context, owner models.Owner, product models.ProductState, quantity int) (*models.Cart, error)
+	UpdateItem(ctx context.Context, owner models.Owner, product models.ProductState, quantity int) (*models.Cart, error)
+	RemoveItem(ctx context.Context, owner models.Owner, productId string) (*models.Cart, error)
+	ClearCart(ctx context.Context, owner models.Owner) error
+	SetCoupon(ctx context.Context, owner models.Owner, code string) (*models.Cart, error)
+	MergeCarts(ctx context.Context, sessionId string, accountId uint64) (*models.Cart, error)
+	DeleteExpiredCarts(ctx context.Context) (int64, error)
+}
+
+type cartService struct {
+	repository Repository
+	ttl        time.Duration
+}
+
+// NewCartService returns a cart service whose carts expire ttl after they were last changed.
+func NewCartService(repository Repository, ttl time.Duration) Service {
+	return &cartService{repository, ttl}
+}
+
+// GetCart returns the owner's cart. Owners without a cart, or whose cart expired, get an
+// empty cart that is only stored once an item is added.
+func (service cartService) GetCart(ctx context.Context, owner models.Owner) (*models.Cart, error) {
+	if (owner.AccountID == 0) == (owner.SessionID == "") {
+		return nil, ErrInvalidOwner
+	}
+
+	cart, err := service.repository.GetCart(ctx, owner)
+	if err == nil && cart.ExpiresAt.Before(time.Now()) {
+		err = service.repository.DeleteCart(ctx, cart.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = ErrCartNotFound
+	}
+	if errors.Is(err, ErrCartNotFound) {
+		cart = &models.Cart{Items: []models.CartItem{}}
+		if owner.AccountID != 0 {
+			cart.AccountID = &owner.AccountID
+		} else {
+			cart.SessionID = &owner.SessionID
+		}
+		return cart, nil
+	}
+	return cart, err
+}
+
+// Revalidate checks the cart against the products' current state. Items whose product is
+// gone or short of stock are marked unavailable, and changed names and prices are stored,
+// with the old price kept in PreviousUnitPrice so the change can be shown.
+func (service cartService) Revalidate(ctx context.Context, cart *models.Cart, products []models.ProductState) (*models.Cart, error) {
+	current := make(map[string]models.ProductState, len(products))
+	for _, product := range products {
+		current[product.ID] = product
+	}
+
+	var changed []*models.CartItem
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		product, ok := current[item.ProductID]
+		item.Available = ok && product.CanSupply(item.Quantity)
+		if !ok || (item.Name == product.Name && item.UnitPrice == product.Price) {
+			continue
+		}
+
+		if item.UnitPrice != product.Price {
+			previous := item.UnitPrice
+			item.PreviousUnitPrice = &previous
+		}
+		item.Name = product.Name
+		item.UnitPrice = product.Price
+		changed = append(changed, item)
+	}
+
+	err := service.repository.SaveItems(ctx, changed)
+	if err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// AddItem adds quantity units of the product to the owner's cart, on top of any already in it.
+func (service cartService) AddItem(ctx context.Context, owner models.Owner, product models.ProductState, quantity int) (*models.Cart, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	cart, err := service.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if existing := cart.Item(product.ID); existing != nil {
+		quantity += existing.Quantity
+	}
+	return service.setItem(ctx, cart, product, quantity)
+}
+
+// UpdateItem sets the quantity of a product in the owner's cart. A quantity of 0 removes it.
+func (service cartService) UpdateItem(ctx context.Context, owner models.Owner, product models.ProductState, quantity int) (*models.Cart, error) {
+	if quantity == 0 {
+		return service.RemoveItem(ctx, owner, product.ID)
+	}
+	if quantity < 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	cart, err := service.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Item(product.ID) == nil {
+		return nil, status.Error(codes.NotFound, ErrProductNotInCart.Error())
+	}
+	return service.setItem(ctx, cart, product, quantity)
+}
+
+func (service cartService) setItem(ctx context.Context, cart *models.Cart, product models.ProductState, quantity int) (*models.Cart, error) {
+	if !product.CanSupply(quantity) {
+		return nil, ErrInsufficientStock
+	}
+
+	cart.ExpiresAt = time.Now().UTC().Add(service.ttl)
+	var err error
+	if cart.ID == 0 {
+		err = service.repository.CreateCart(ctx, cart)
+	} else {
+		err = service.repository.TouchCart(ctx, cart.ID, cart.ExpiresAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	item := &models.CartItem{
+		CartID:    cart.ID,
+		ProductID: product.ID,
+		Quantity:  quantity,
+		Name:      product.Name,
+		UnitPrice: product.Price,
+		AddedAt:   time.Now().UTC(),
+	}
+	err = service.repository.SaveItems(ctx, []*models.CartItem{item})
+	if err != nil {
+		return nil, err
+	}
+	return service.GetCart(ctx, ownerOf(cart))
+}
+
+func (service cartService) RemoveItem(ctx context.Context, owner models.Owner, productId string) (*models.Cart, error) {
+	cart, err := service.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if cart.ID == 0 || cart.Item(productId) == nil {
+		return nil, status.Error(codes.NotFound, ErrProductNotInCart.Error())
+	}
+
+	err = service.repository.RemoveItem(ctx, cart.ID, productId)
+	if err != nil {
+		return nil, err
+	}
+	return service.GetCart(ctx, owner)
+}
+
+func (service cartService) ClearCart(ctx context.Context, owner models.Owner) error {
+	cart, err := service.GetCart(ctx, owner)
+	if err != nil || cart.ID == 0 {
+		return err
+	}
+	return service.repository.DeleteCart(ctx, cart.ID)
+}
+
+// SetCoupon applies the promotion code to the owner's cart, replacing any applied before.
+// An empty code removes the coupon. Whether the code is valid is up to the order service.
+func (service cartService) SetCoupon(ctx context.Context, owner models.Owner, code string) (*models.Cart, error) {
+	cart, err := service.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if cart.ID == 0 {
+		if code == "" {
+			return cart, nil
+		}
+		return nil, ErrEmptyCart
+	}
+
+	err = service.repository.SetCoupon(ctx, cart.ID, code)
+	if err != nil {
+		return nil, err
+	}
+	cart.CouponCode = code
+	return cart, nil
+}
+
+// MergeCarts moves the items of a session's cart into the account's cart when the
+// session signs in. Quantities of products in both carts are added up, and the session's
+// coupon carries over unless the account's cart has one.
+func (service cartService) MergeCarts(ctx context.Context, sessionId string, accountId uint64) (*models.Cart, error) {
+	if sessionId == "" || accountId == 0 {
+		return nil, ErrInvalidOwner
+	}
+
+	accountOwner := models.Owner{AccountID: accountId}
+	from, err := service.GetCart(ctx, models.Owner{SessionID: sessionId})
+	if err != nil {
+		return nil, err
+	}
+	to, err := service.GetCart(ctx, accountOwner)
+	if err != nil {
+		return nil, err
+	}
+	if from.ID == 0 {
+		return to, nil
+	}
+
+	items := make([]*models.CartItem, 0, len(from.Items))
+	for _, item := range from.Items {
+		merged := item
+		merged.ID = 0
+		if existing := to.Item(item.ProductID); existing != nil {
+			merged.Quantity += existing.Quantity
+			merged.AddedAt = existing.AddedAt
+		}
+		items = append(items, &merged)
+	}
+
+	to.ExpiresAt = time.Now().UTC().Add(service.ttl)
+	if to.CouponCode == "" {
+		to.CouponCode = from.CouponCode
+	}
+	err = service.repository.MergeCarts(ctx, from.ID, to, items)
+	if err != nil {
+		return nil, err
+	}
+	return service.GetCart(ctx, accountOwner)
+}
+
+func (service cartService) DeleteExpiredCarts(ctx context.Context) (int64, error) {
+	return service.repository.DeleteExpiredCarts(ctx, time.Now().UTC())
+}
+
+func ownerOf(cart *models.Cart) models.Owner {
+	if cart.AccountID != nil {
+		return models.Owner{AccountID: *cart.AccountID}
+	}
+	return models.Owner{SessionID: *cart.SessionID}
+}
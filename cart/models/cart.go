@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// Owner identifies a cart: an account's, or before sign-in, an anonymous session's.
+type Owner struct {
+	AccountID uint64
+	SessionID string
+}
+
+type Cart struct {
+	ID        uint    `gorm:"primaryKey;autoIncrement"`
+	AccountID *uint64 `gorm:"uniqueIndex"`
+	SessionID *string `gorm:"uniqueIndex"`
+	UpdatedAt time.Time
+	// ExpiresAt moves forward whenever the cart changes
+	ExpiresAt time.Time `gorm:"index"`
+	// CouponCode is the promotion applied to the cart, checked again at checkout
+	CouponCode string
+	Items      []CartItem `gorm:"foreignKey:CartID;constraint:OnDelete:CASCADE"`
+}
+
+// CartItem is a product in a cart. Name and UnitPrice are the product's details when the
+// cart was last validated against the product service.
+type CartItem struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	CartID    uint   `gorm:"uniqueIndex:idx_cart_product"`
+	ProductID string `gorm:"uniqueIndex:idx_cart_product"`
+	Quantity  int
+	Name      string
+	UnitPrice money.Money `gorm:"embedded;embeddedPrefix:unit_price_"`
+	AddedAt   time.Time
+	// Available reports whether the product still exists with enough stock for Quantity
+	Available bool `gorm:"-"`
+	// PreviousUnitPrice is set when the price changed since the cart was last validated
+	PreviousUnitPrice *money.Money `gorm:"-"`
+}
+
+// LineTotal is the unit price multiplied by the quantity.
+func (i CartItem) LineTotal() money.Money {
+	return i.UnitPrice.Mul(int64(i.Quantity))
+}
+
+// Item returns the cart's item for the product, or nil if the product is not in the cart.
+func (c *Cart) Item(productId string) *CartItem {
+	for i := range c.Items {
+		if c.Items[i].ProductID == productId {
+			return &c.Items[i]
+		}
+	}
+	return nil
+}
+
+// ProductState is a product's current details as reported by the product service.
+type ProductState struct {
+	ID    string
+	Name  string
+	Price money.Money
+	// Stock is nil when the seller does not track inventory for the product
+	Stock *int
+}
+
+// CanSupply reports whether quantity units of the product can be bought.
+func (p ProductState) CanSupply(quantity int) bool {
+	return p.Stock == nil || *p.Stock >= quantity
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/cart/internal"
+	"github.com/rasadov/EcommerceAPI/cart/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Test helper to create a repository backed by an in-memory SQLite database
+func setupTestRepository(t *testing.T) internal.Repository {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	r, err := internal.NewPostgresRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestCartRepository_SaveItems(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	accountId := uint64(1)
+	cart := &models.Cart{AccountID: &accountId, ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, repo.CreateCart(ctx, cart))
+
+	item := &models.CartItem{CartID: cart.ID, ProductID: "p1", Quantity: 1, UnitPrice: money.New(100, "USD"), AddedAt: time.Now()}
+	require.NoError(t, repo.SaveItems(ctx, []*models.CartItem{item}))
+
+	// Saving the product again updates the existing item
+	item = &models.CartItem{CartID: cart.ID, ProductID: "p1", Quantity: 3, UnitPrice: money.New(90, "USD"), AddedAt: time.Now()}
+	require.NoError(t, repo.SaveItems(ctx, []*models.CartItem{item}))
+
+	stored, err := repo.GetCart(ctx, models.Owner{AccountID: accountId})
+	require.NoError(t, err)
+	require.Len(t, stored.Items, 1)
+	assert.Equal(t, 3, stored.Items[0].Quantity)
+	assert.Equal(t, money.New(90, "USD"), stored.Items[0].UnitPrice)
+
+	require.NoError(t, repo.RemoveItem(ctx, cart.ID, "p1"))
+	assert.ErrorIs(t, repo.RemoveItem(ctx, cart.ID, "p1"), internal.ErrProductNotInCart)
+}
+
+func TestCartRepository_DeleteExpiredCarts(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	expired, current := "expired", "current"
+	for _, cart := range []*models.Cart{
+		{SessionID: &expired, ExpiresAt: now.Add(-time.Minute)},
+		{SessionID: &current, ExpiresAt: now.Add(time.Hour)},
+	} {
+		require.NoError(t, repo.CreateCart(ctx, cart))
+		require.NoError(t, repo.SaveItems(ctx, []*models.CartItem{{CartID: cart.ID, ProductID: "p1", Quantity: 1}}))
+	}
+
+	deleted, err := repo.DeleteExpiredCarts(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	_, err = repo.GetCart(ctx, models.Owner{SessionID: expired})
+	assert.ErrorIs(t, err, internal.ErrCartNotFound)
+	_, err = repo.GetCart(ctx, models.Owner{SessionID: current})
+	assert.NoError(t, err)
+}
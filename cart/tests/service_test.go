@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/cart/internal"
+	"github.com/rasadov/EcommerceAPI/cart/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func product(id string, price int64, stock *int) models.ProductState {
+	return models.ProductState{ID: id, Name: "Product " + id, Price: money.New(price, "USD"), Stock: stock}
+}
+
+func TestCartService_AddItem(t *testing.T) {
+	ctx := context.Background()
+	owner := models.Owner{SessionID: "session"}
+
+	t.Run("Quantities add up", func(t *testing.T) {
+		service := internal.NewCartService(setupTestRepository(t), time.Hour)
+
+		_, err := service.AddItem(ctx, owner, product("p1", 100, nil), 1)
+		require.NoError(t, err)
+		cart, err := service.AddItem(ctx, owner, product("p1", 100, nil), 2)
+
+		require.NoError(t, err)
+		require.Len(t, cart.Items, 1)
+		assert.Equal(t, 3, cart.Items[0].Quantity)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), cart.ExpiresAt, time.Minute)
+	})
+
+	t.Run("Stock is checked", func(t *testing.T) {
+		service := internal.NewCartService(setupTestRepository(t), time.Hour)
+		stock := 2
+
+		_, err := service.AddItem(ctx, owner, product("p1", 100, &stock), 3)
+
+		assert.ErrorIs(t, err, internal.ErrInsufficientStock)
+	})
+
+	t.Run("Owner must be an account or a session", func(t *testing.T) {
+		service := internal.NewCartService(setupTestRepository(t), time.Hour)
+
+		_, err := service.AddItem(ctx, models.Owner{AccountID: 1, SessionID: "session"}, product("p1", 100, nil), 1)
+
+		assert.ErrorIs(t, err, internal.ErrInvalidOwner)
+	})
+}
+
+func TestCartService_Revalidate(t *testing.T) {
+	ctx := context.Background()
+	owner := models.Owner{AccountID: 1}
+	service := internal.NewCartService(setupTestRepository(t), time.Hour)
+	_, err := service.AddItem(ctx, owner, product("p1", 100, nil), 1)
+	require.NoError(t, err)
+	cart, err := service.AddItem(ctx, owner, product("p2", 500, nil), 2)
+	require.NoError(t, err)
+
+	stock := 1
+	cart, err = service.Revalidate(ctx, cart, []models.ProductState{
+		product("p2", 450, &stock),
+	})
+
+	require.NoError(t, err)
+	removed, changed := cart.Item("p1"), cart.Item("p2")
+	assert.False(t, removed.Available)
+	assert.False(t, changed.Available)
+	require.NotNil(t, changed.PreviousUnitPrice)
+	assert.Equal(t, money.New(500, "USD"), *changed.PreviousUnitPrice)
+	assert.Equal(t, money.New(450, "USD"), changed.UnitPrice)
+
+	// The new price is stored, so it is only reported as changed once
+	cart, err = service.GetCart(ctx, owner)
+	require.NoError(t, err)
+	cart, err = service.Revalidate(ctx, cart, []models.ProductState{product("p2", 450, nil)})
+	require.NoError(t, err)
+	assert.True(t, cart.Item("p2").Available)
+	assert.Nil(t, cart.Item("p2").PreviousUnitPrice)
+}
+
+func TestCartService_MergeCarts(t *testing.T) {
+	ctx := context.Background()
+	session := models.Owner{SessionID: "session"}
+	account := models.Owner{AccountID: 1}
+	service := internal.NewCartService(setupTestRepository(t), time.Hour)
+
+	_, err := service.AddItem(ctx, account, product("p1", 100, nil), 1)
+	require.NoError(t, err)
+	_, err = service.AddItem(ctx, session, product("p1", 100, nil), 2)
+	require.NoError(t, err)
+	_, err = service.AddItem(ctx, session, product("p2", 200, nil), 1)
+	require.NoError(t, err)
+
+	cart, err := service.MergeCarts(ctx, session.SessionID, account.AccountID)
+
+	require.NoError(t, err)
+	require.Len(t, cart.Items, 2)
+	assert.Equal(t, 3, cart.Item("p1").Quantity)
+	assert.Equal(t, 1, cart.Item("p2").Quantity)
+
+	sessionCart, err := service.GetCart(ctx, session)
+	require.NoError(t, err)
+	assert.Zero(t, sessionCart.ID)
+	assert.Empty(t, sessionCart.Items)
+}
+
+func TestCartService_SetCoupon(t *testing.T) {
+	ctx := context.Background()
+	session := models.Owner{SessionID: "session"}
+	account := models.Owner{AccountID: 1}
+	service := internal.NewCartService(setupTestRepository(t), time.Hour)
+
+	_, err := service.SetCoupon(ctx, session, "SUMMER10")
+	assert.ErrorIs(t, err, internal.ErrEmptyCart)
+
+	_, err = service.AddItem(ctx, session, product("p1", 100, nil), 1)
+	require.NoError(t, err)
+	_, err = service.SetCoupon(ctx, session, "SUMMER10")
+	require.NoError(t, err)
+
+	// The coupon follows the session's items into the account's cart
+	cart, err := service.MergeCarts(ctx, session.SessionID, account.AccountID)
+	require.NoError(t, err)
+	assert.Equal(t, "SUMMER10", cart.CouponCode)
+
+	cart, err = service.SetCoupon(ctx, account, "")
+	require.NoError(t, err)
+	assert.Empty(t, cart.CouponCode)
+}
+
+func TestCartService_ExpiredCartIsEmpty(t *testing.T) {
+	ctx := context.Background()
+	owner := models.Owner{SessionID: "session"}
+	service := internal.NewCartService(setupTestRepository(t), -time.Minute)
+
+	_, err := service.AddItem(ctx, owner, product("p1", 100, nil), 1)
+	require.NoError(t, err)
+
+	cart, err := service.GetCart(ctx, owner)
+
+	require.NoError(t, err)
+	assert.Empty(t, cart.Items)
+}
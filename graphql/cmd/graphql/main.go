@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
@@ -10,10 +12,14 @@ import (
 	"github.com/rasadov/EcommerceAPI/graphql/config"
 	"github.com/rasadov/EcommerceAPI/graphql/graph"
 	"github.com/rasadov/EcommerceAPI/pkg/middleware"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 )
 
 func main() {
-	server, err := graph.NewGraphQLServer(config.AccountUrl, config.ProductUrl, config.OrderUrl, config.PaymentUrl, config.RecommenderUrl)
+	converter := money.NewConverter(money.NewRateSource(config.CurrencyRatesURL, config.CurrencyRatesFile))
+	converter.StartRefresh(context.Background(), time.Hour)
+
+	server, err := graph.NewGraphQLServer(config.AccountUrl, config.ProductUrl, config.OrderUrl, config.PaymentUrl, config.RecommenderUrl, config.WishlistUrl, config.CartUrl, config.AlertUrl, config.ShippingUrl, converter)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -33,8 +39,12 @@ func main() {
 	})
 	engine.POST("/graphql",
 		middleware.AuthorizeJWT(),
+		middleware.IdempotencyKey(),
+		middleware.CartSession(),
+		middleware.ClientIP(),
 		gin.WrapH(srv),
 	)
+	engine.GET("/invoices/:file", middleware.AuthorizeJWT(), server.InvoiceHandler())
 	engine.GET("/playground", gin.WrapH(playground.Handler("Playground", "/graphql")))
 
 	log.Fatal(engine.Run(":8080"))
@@ -1,15 +1,28 @@
 package config
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
 
 var (
-	AccountUrl     string
-	ProductUrl     string
-	OrderUrl       string
-	PaymentUrl     string
-	RecommenderUrl string
-	SecretKey      string
-	Issuer         string
+	AccountUrl        string
+	ProductUrl        string
+	OrderUrl          string
+	PaymentUrl        string
+	RecommenderUrl    string
+	WishlistUrl       string
+	CartUrl           string
+	AlertUrl          string
+	ShippingUrl       string
+	SecretKey         string
+	Issuer            string
+	CurrencyRatesURL  string
+	CurrencyRatesFile string
+	// AdminAccountIds are the accounts allowed to see and manage data of every account
+	AdminAccountIds map[uint64]bool
 )
 
 func init() {
@@ -18,6 +31,31 @@ func init() {
 	OrderUrl = os.Getenv("ORDER_SERVICE_URL")
 	PaymentUrl = os.Getenv("PAYMENT_SERVICE_URL")
 	RecommenderUrl = os.Getenv("RECOMMENDER_SERVICE_URL")
+	WishlistUrl = os.Getenv("WISHLIST_SERVICE_URL")
+	CartUrl = os.Getenv("CART_SERVICE_URL")
+	AlertUrl = os.Getenv("ALERT_SERVICE_URL")
+	ShippingUrl = os.Getenv("SHIPPING_SERVICE_URL")
 	SecretKey = os.Getenv("SECRET_KEY")
 	Issuer = os.Getenv("ISSUER")
+	CurrencyRatesURL = os.Getenv("CURRENCY_RATES_URL")
+	CurrencyRatesFile = os.Getenv("CURRENCY_RATES_FILE")
+	AdminAccountIds = parseAccountIds(os.Getenv("ADMIN_ACCOUNT_IDS"))
+}
+
+// parseAccountIds parses a comma-separated list of account IDs, skipping invalid entries.
+func parseAccountIds(value string) map[uint64]bool {
+	ids := map[uint64]bool{}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			log.Printf("Ignoring invalid admin account ID %q", field)
+			continue
+		}
+		ids[id] = true
+	}
+	return ids
 }
@@ -3,13 +3,78 @@
 package generated
 
 import (
+	"fmt"
+	"io"
+	"strconv"
 	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 )
 
+type Address struct {
+	RecipientName *string `json:"recipientName,omitempty"`
+	Line1         *string `json:"line1,omitempty"`
+	Line2         *string `json:"line2,omitempty"`
+	City          *string `json:"city,omitempty"`
+	PostalCode    *string `json:"postalCode,omitempty"`
+	Region        *string `json:"region,omitempty"`
+	Country       *string `json:"country,omitempty"`
+}
+
+type AddressInput struct {
+	RecipientName *string `json:"recipientName,omitempty"`
+	Line1         *string `json:"line1,omitempty"`
+	Line2         *string `json:"line2,omitempty"`
+	City          *string `json:"city,omitempty"`
+	PostalCode    *string `json:"postalCode,omitempty"`
+	Region        *string `json:"region,omitempty"`
+	Country       string  `json:"country"`
+}
+
 type AuthResponse struct {
 	Token string `json:"token"`
 }
 
+type Cart struct {
+	Items       []*CartItem    `json:"items"`
+	Subtotal    *money.Money   `json:"subtotal"`
+	CouponCode  *string        `json:"couponCode,omitempty"`
+	Discount    *OrderDiscount `json:"discount,omitempty"`
+	CouponError *string        `json:"couponError,omitempty"`
+	Total       *money.Money   `json:"total"`
+	ExpiresAt   *time.Time     `json:"expiresAt,omitempty"`
+}
+
+type CartCheckout struct {
+	Order       *Order `json:"order"`
+	CheckoutURL string `json:"checkoutUrl"`
+}
+
+type CartItem struct {
+	ProductID         string       `json:"productId"`
+	Name              string       `json:"name"`
+	Quantity          int          `json:"quantity"`
+	UnitPrice         *money.Money `json:"unitPrice"`
+	LineTotal         *money.Money `json:"lineTotal"`
+	PreviousUnitPrice *money.Money `json:"previousUnitPrice,omitempty"`
+	Available         bool         `json:"available"`
+}
+
+type CartItemInput struct {
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+}
+
+type CheckoutCartInput struct {
+	RedirectURL     string        `json:"redirectUrl"`
+	Currency        *string       `json:"currency,omitempty"`
+	ShippingAddress *AddressInput `json:"shippingAddress,omitempty"`
+	ShippingMethod  *string       `json:"shippingMethod,omitempty"`
+	BillingCountry  *string       `json:"billingCountry,omitempty"`
+	GiftCardCode    *string       `json:"giftCardCode,omitempty"`
+	UseStoreCredit  *bool         `json:"useStoreCredit,omitempty"`
+}
+
 type CheckoutInput struct {
 	AccountID   int                     `json:"accountId"`
 	Email       string                  `json:"email"`
@@ -17,6 +82,7 @@ type CheckoutInput struct {
 	RedirectURL string                  `json:"redirectUrl"`
 	Products    []*CheckoutProductInput `json:"products"`
 	OrderID     int                     `json:"orderId"`
+	Currency    *string                 `json:"currency,omitempty"`
 }
 
 type CheckoutProductInput struct {
@@ -28,6 +94,11 @@ type CreateProductInput struct {
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
+	Currency    *string `json:"currency,omitempty"`
+	Stock       *int    `json:"stock,omitempty"`
+	Category    *string `json:"category,omitempty"`
+	TaxCategory *string `json:"taxCategory,omitempty"`
+	WeightGrams *int    `json:"weightGrams,omitempty"`
 }
 
 type CustomerPortalSessionInput struct {
@@ -36,31 +107,136 @@ type CustomerPortalSessionInput struct {
 	Name      string `json:"name"`
 }
 
+type GiftCard struct {
+	Code      string       `json:"code"`
+	Initial   *money.Money `json:"initial"`
+	Balance   *money.Money `json:"balance"`
+	ExpiresAt *time.Time   `json:"expiresAt,omitempty"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type GiftCardInput struct {
+	Amount    float64    `json:"amount"`
+	Currency  *string    `json:"currency,omitempty"`
+	Code      *string    `json:"code,omitempty"`
+	Note      *string    `json:"note,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type GiftCardPurchaseInput struct {
+	Amount      float64 `json:"amount"`
+	Currency    *string `json:"currency,omitempty"`
+	RedirectURL string  `json:"redirectUrl"`
+}
+
 type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+type MoveWishlistItemInput struct {
+	ProductID      string `json:"productId"`
+	FromWishlistID *int   `json:"fromWishlistId,omitempty"`
+	ToWishlistID   *int   `json:"toWishlistId,omitempty"`
+}
+
 type Mutation struct {
 }
 
 type Order struct {
-	ID         int               `json:"id"`
-	CreatedAt  time.Time         `json:"createdAt"`
-	TotalPrice float64           `json:"totalPrice"`
-	Products   []*OrderedProduct `json:"products"`
+	ID              int                      `json:"id"`
+	CreatedAt       time.Time                `json:"createdAt"`
+	TotalPrice      float64                  `json:"totalPrice"`
+	Total           *money.Money             `json:"total"`
+	Currency        string                   `json:"currency"`
+	Status          OrderStatus              `json:"status"`
+	StatusHistory   []*OrderStatusTransition `json:"statusHistory"`
+	Products        []*OrderedProduct        `json:"products"`
+	Subtotal        *money.Money             `json:"subtotal"`
+	Discounts       []*OrderDiscount         `json:"discounts"`
+	ShippingAddress *Address                 `json:"shippingAddress,omitempty"`
+	Taxes           []*OrderTax              `json:"taxes"`
+	TaxTotal        *money.Money             `json:"taxTotal"`
+	ShippingMethod  *string                  `json:"shippingMethod,omitempty"`
+	ShippingCost    *money.Money             `json:"shippingCost,omitempty"`
+	Shipments       []*Shipment              `json:"shipments"`
+	Returns         []*OrderReturn           `json:"returns"`
+	InvoiceURL      *string                  `json:"invoiceUrl,omitempty"`
+	SubOrders       []*SubOrder              `json:"subOrders"`
+	RiskReview      *RiskReviewStatus        `json:"riskReview,omitempty"`
+}
+
+type OrderConnection struct {
+	Orders   []*Order  `json:"orders"`
+	PageInfo *PageInfo `json:"pageInfo"`
+}
+
+type OrderDiscount struct {
+	Code        string       `json:"code"`
+	Description string       `json:"description"`
+	Amount      *money.Money `json:"amount"`
+}
+
+type OrderFilterInput struct {
+	Statuses      []OrderStatus `json:"statuses,omitempty"`
+	CreatedAfter  *time.Time    `json:"createdAfter,omitempty"`
+	CreatedBefore *time.Time    `json:"createdBefore,omitempty"`
+	MinTotal      *float64      `json:"minTotal,omitempty"`
+	MaxTotal      *float64      `json:"maxTotal,omitempty"`
+	TotalCurrency *string       `json:"totalCurrency,omitempty"`
+	AccountID     *int          `json:"accountId,omitempty"`
+	AllAccounts   *bool         `json:"allAccounts,omitempty"`
 }
 
 type OrderInput struct {
-	Products []*OrderedProductInput `json:"products"`
+	Products        []*OrderedProductInput `json:"products"`
+	Currency        *string                `json:"currency,omitempty"`
+	CouponCode      *string                `json:"couponCode,omitempty"`
+	ShippingAddress *AddressInput          `json:"shippingAddress,omitempty"`
+	ShippingMethod  *string                `json:"shippingMethod,omitempty"`
+	BillingCountry  *string                `json:"billingCountry,omitempty"`
+	GiftCardCode    *string                `json:"giftCardCode,omitempty"`
+	UseStoreCredit  *bool                  `json:"useStoreCredit,omitempty"`
+}
+
+type OrderReturn struct {
+	ID                  int                 `json:"id"`
+	Status              ReturnStatus        `json:"status"`
+	Reason              string              `json:"reason"`
+	Lines               []*ReturnLine       `json:"lines"`
+	RefundAmount        *money.Money        `json:"refundAmount"`
+	RefundID            *string             `json:"refundId,omitempty"`
+	RefundToStoreCredit bool                `json:"refundToStoreCredit"`
+	History             []*ReturnTransition `json:"history"`
+	CreatedAt           time.Time           `json:"createdAt"`
+}
+
+type OrderStatusTransition struct {
+	From      *OrderStatus `json:"from,omitempty"`
+	To        OrderStatus  `json:"to"`
+	Actor     string       `json:"actor"`
+	Reason    string       `json:"reason"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type OrderTax struct {
+	ProductID   string       `json:"productId"`
+	TaxCategory string       `json:"taxCategory"`
+	Name        string       `json:"name"`
+	Rate        string       `json:"rate"`
+	Inclusive   bool         `json:"inclusive"`
+	Amount      *money.Money `json:"amount"`
 }
 
 type OrderedProduct struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Quantity    int     `json:"quantity"`
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Price       float64      `json:"price"`
+	UnitPrice   *money.Money `json:"unitPrice"`
+	Currency    string       `json:"currency"`
+	Quantity    int          `json:"quantity"`
+	LineTotal   *money.Money `json:"lineTotal"`
 }
 
 type OrderedProductInput struct {
@@ -68,22 +244,135 @@ type OrderedProductInput struct {
 	Quantity int    `json:"quantity"`
 }
 
+type PageInfo struct {
+	EndCursor   *string `json:"endCursor,omitempty"`
+	HasNextPage bool    `json:"hasNextPage"`
+}
+
 type PaginationInput struct {
 	Skip int `json:"skip"`
 	Take int `json:"take"`
 }
 
+type Payout struct {
+	ID          int          `json:"id"`
+	Sales       *money.Money `json:"sales"`
+	Commission  *money.Money `json:"commission"`
+	Refunds     *money.Money `json:"refunds"`
+	Amount      *money.Money `json:"amount"`
+	PeriodStart time.Time    `json:"periodStart"`
+	PeriodEnd   time.Time    `json:"periodEnd"`
+	CreatedAt   time.Time    `json:"createdAt"`
+}
+
+type PayoutConnection struct {
+	Payouts  []*Payout `json:"payouts"`
+	PageInfo *PageInfo `json:"pageInfo"`
+}
+
 type Product struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	AccountID   int     `json:"accountId"`
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Price       float64      `json:"price"`
+	UnitPrice   *money.Money `json:"unitPrice"`
+	Currency    string       `json:"currency"`
+	AccountID   int          `json:"accountId"`
+	Stock       *int         `json:"stock,omitempty"`
+	InStock     bool         `json:"inStock"`
+	Category    *string      `json:"category,omitempty"`
+	TaxCategory string       `json:"taxCategory"`
+	WeightGrams int          `json:"weightGrams"`
+}
+
+type ProductAlert struct {
+	ID        int              `json:"id"`
+	ProductID string           `json:"productId"`
+	Kind      ProductAlertKind `json:"kind"`
+	Threshold *money.Money     `json:"threshold,omitempty"`
+	Channel   AlertChannel     `json:"channel"`
+	Target    string           `json:"target"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+type ProductAlertInput struct {
+	ProductID  string           `json:"productId"`
+	Kind       ProductAlertKind `json:"kind"`
+	Threshold  *float64         `json:"threshold,omitempty"`
+	Currency   *string          `json:"currency,omitempty"`
+	Channel    *AlertChannel    `json:"channel,omitempty"`
+	WebhookURL *string          `json:"webhookUrl,omitempty"`
+}
+
+type Promotion struct {
+	ID                    int           `json:"id"`
+	Code                  string        `json:"code"`
+	Description           string        `json:"description"`
+	Kind                  PromotionKind `json:"kind"`
+	PercentOff            *int          `json:"percentOff,omitempty"`
+	AmountOff             *money.Money  `json:"amountOff,omitempty"`
+	MinSubtotal           *money.Money  `json:"minSubtotal,omitempty"`
+	ProductIds            []string      `json:"productIds"`
+	Categories            []string      `json:"categories"`
+	BuyQuantity           *int          `json:"buyQuantity,omitempty"`
+	GetQuantity           *int          `json:"getQuantity,omitempty"`
+	UsageLimitPerCustomer *int          `json:"usageLimitPerCustomer,omitempty"`
+	StartsAt              *time.Time    `json:"startsAt,omitempty"`
+	EndsAt                *time.Time    `json:"endsAt,omitempty"`
+	Active                bool          `json:"active"`
+	CreatedAt             time.Time     `json:"createdAt"`
+}
+
+type PromotionInput struct {
+	Code                  string        `json:"code"`
+	Description           *string       `json:"description,omitempty"`
+	Kind                  PromotionKind `json:"kind"`
+	PercentOff            *int          `json:"percentOff,omitempty"`
+	AmountOff             *float64      `json:"amountOff,omitempty"`
+	MinSubtotal           *float64      `json:"minSubtotal,omitempty"`
+	Currency              *string       `json:"currency,omitempty"`
+	ProductIds            []string      `json:"productIds,omitempty"`
+	Categories            []string      `json:"categories,omitempty"`
+	BuyQuantity           *int          `json:"buyQuantity,omitempty"`
+	GetQuantity           *int          `json:"getQuantity,omitempty"`
+	UsageLimitPerCustomer *int          `json:"usageLimitPerCustomer,omitempty"`
+	StartsAt              *time.Time    `json:"startsAt,omitempty"`
+	EndsAt                *time.Time    `json:"endsAt,omitempty"`
+	Active                *bool         `json:"active,omitempty"`
 }
 
 type Query struct {
 }
 
+type RecurringOrder struct {
+	ID             int                      `json:"id"`
+	Interval       RecurringInterval        `json:"interval"`
+	Status         RecurringOrderStatus     `json:"status"`
+	Products       []*RecurringOrderProduct `json:"products"`
+	Currency       string                   `json:"currency"`
+	ShippingMethod *string                  `json:"shippingMethod,omitempty"`
+	NextRunAt      time.Time                `json:"nextRunAt"`
+	LastOrderID    *int                     `json:"lastOrderId,omitempty"`
+	CheckoutURL    *string                  `json:"checkoutUrl,omitempty"`
+	LastError      *string                  `json:"lastError,omitempty"`
+	CreatedAt      time.Time                `json:"createdAt"`
+}
+
+type RecurringOrderInput struct {
+	Products        []*OrderedProductInput `json:"products"`
+	Interval        RecurringInterval      `json:"interval"`
+	Currency        *string                `json:"currency,omitempty"`
+	StartAt         *time.Time             `json:"startAt,omitempty"`
+	ShippingAddress *AddressInput          `json:"shippingAddress,omitempty"`
+	ShippingMethod  *string                `json:"shippingMethod,omitempty"`
+	RedirectURL     string                 `json:"redirectUrl"`
+}
+
+type RecurringOrderProduct struct {
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+}
+
 type RedirectResponse struct {
 	URL string `json:"url"`
 }
@@ -94,9 +383,623 @@ type RegisterInput struct {
 	Password string `json:"password"`
 }
 
+type Reorder struct {
+	Cart        *Cart          `json:"cart"`
+	Unavailable []*ReorderLine `json:"unavailable"`
+}
+
+type ReorderLine struct {
+	ProductID         string       `json:"productId"`
+	Name              string       `json:"name"`
+	Quantity          int          `json:"quantity"`
+	UnitPrice         *money.Money `json:"unitPrice"`
+	PreviousUnitPrice *money.Money `json:"previousUnitPrice"`
+	Available         bool         `json:"available"`
+}
+
+type ReturnInput struct {
+	OrderID             int                    `json:"orderId"`
+	Reason              string                 `json:"reason"`
+	Lines               []*OrderedProductInput `json:"lines"`
+	RefundToStoreCredit *bool                  `json:"refundToStoreCredit,omitempty"`
+}
+
+type ReturnLine struct {
+	ProductID    string       `json:"productId"`
+	Quantity     int          `json:"quantity"`
+	RefundAmount *money.Money `json:"refundAmount"`
+}
+
+type ReturnTransition struct {
+	From      *ReturnStatus `json:"from,omitempty"`
+	To        ReturnStatus  `json:"to"`
+	Actor     string        `json:"actor"`
+	Note      string        `json:"note"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+type RiskFinding struct {
+	Rule   string `json:"rule"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+type RiskReview struct {
+	Order       *Order           `json:"order"`
+	Score       int              `json:"score"`
+	Decision    RiskDecision     `json:"decision"`
+	Findings    []*RiskFinding   `json:"findings"`
+	Status      RiskReviewStatus `json:"status"`
+	ClientIP    *string          `json:"clientIp,omitempty"`
+	ReviewedBy  *string          `json:"reviewedBy,omitempty"`
+	Note        *string          `json:"note,omitempty"`
+	ReviewedAt  *time.Time       `json:"reviewedAt,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	CheckoutURL *string          `json:"checkoutUrl,omitempty"`
+}
+
+type RiskReviewConnection struct {
+	Reviews  []*RiskReview `json:"reviews"`
+	PageInfo *PageInfo     `json:"pageInfo"`
+}
+
+type SellerBalance struct {
+	Owed    *money.Money `json:"owed"`
+	PaidOut *money.Money `json:"paidOut"`
+}
+
+type SellerOrder struct {
+	ID              int               `json:"id"`
+	OrderID         int               `json:"orderId"`
+	Status          OrderStatus       `json:"status"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	Products        []*OrderedProduct `json:"products"`
+	ShippingAddress *Address          `json:"shippingAddress,omitempty"`
+	ShippingMethod  *string           `json:"shippingMethod,omitempty"`
+	Subtotal        *money.Money      `json:"subtotal"`
+	Discount        *money.Money      `json:"discount"`
+	Tax             *money.Money      `json:"tax"`
+	Shipping        *money.Money      `json:"shipping"`
+	Total           *money.Money      `json:"total"`
+}
+
+type SellerOrderConnection struct {
+	Orders   []*SellerOrder `json:"orders"`
+	PageInfo *PageInfo      `json:"pageInfo"`
+}
+
+type SellerOrderLine struct {
+	OrderID     int          `json:"orderId"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	ProductID   string       `json:"productId"`
+	ProductName string       `json:"productName"`
+	Price       float64      `json:"price"`
+	UnitPrice   *money.Money `json:"unitPrice"`
+	Quantity    int          `json:"quantity"`
+	Total       float64      `json:"total"`
+	LineTotal   *money.Money `json:"lineTotal"`
+	Currency    string       `json:"currency"`
+}
+
+type SellerSales struct {
+	OrderCount   int          `json:"orderCount"`
+	UnitsSold    int          `json:"unitsSold"`
+	TotalRevenue float64      `json:"totalRevenue"`
+	Revenue      *money.Money `json:"revenue"`
+	Currency     string       `json:"currency"`
+}
+
+type Shipment struct {
+	ID             int             `json:"id"`
+	Carrier        string          `json:"carrier"`
+	TrackingNumber string          `json:"trackingNumber"`
+	Status         string          `json:"status"`
+	ShippedAt      time.Time       `json:"shippedAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+	Lines          []*ShipmentLine `json:"lines"`
+}
+
+type ShipmentInput struct {
+	OrderID        int                    `json:"orderId"`
+	Carrier        *string                `json:"carrier,omitempty"`
+	TrackingNumber string                 `json:"trackingNumber"`
+	Lines          []*OrderedProductInput `json:"lines,omitempty"`
+}
+
+type ShipmentLine struct {
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+}
+
+type ShippingRate struct {
+	Method       string       `json:"method"`
+	Name         string       `json:"name"`
+	Carrier      string       `json:"carrier"`
+	Price        *money.Money `json:"price"`
+	DeliveryDays *int         `json:"deliveryDays,omitempty"`
+}
+
+type StoreCredit struct {
+	Balances     []*money.Money            `json:"balances"`
+	Transactions []*StoreCreditTransaction `json:"transactions"`
+	PageInfo     *PageInfo                 `json:"pageInfo"`
+}
+
+type StoreCreditTransaction struct {
+	ID        int             `json:"id"`
+	Kind      StoreCreditKind `json:"kind"`
+	Amount    *money.Money    `json:"amount"`
+	OrderID   *int            `json:"orderId,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type SubOrder struct {
+	ID        int          `json:"id"`
+	SellerID  int          `json:"sellerId"`
+	Status    OrderStatus  `json:"status"`
+	Subtotal  *money.Money `json:"subtotal"`
+	Discount  *money.Money `json:"discount"`
+	Tax       *money.Money `json:"tax"`
+	Shipping  *money.Money `json:"shipping"`
+	Total     *money.Money `json:"total"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
 type UpdateProductInput struct {
 	ID          string  `json:"id"`
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
+	Currency    *string `json:"currency,omitempty"`
+	Stock       *int    `json:"stock,omitempty"`
+	Category    *string `json:"category,omitempty"`
+	TaxCategory *string `json:"taxCategory,omitempty"`
+	WeightGrams *int    `json:"weightGrams,omitempty"`
+}
+
+type WishlistItem struct {
+	ProductID string    `json:"productId"`
+	AddedAt   time.Time `json:"addedAt"`
+	Product   *Product  `json:"product,omitempty"`
+}
+
+type WishlistItemInput struct {
+	ProductID  string `json:"productId"`
+	WishlistID *int   `json:"wishlistId,omitempty"`
+}
+
+type AlertChannel string
+
+const (
+	AlertChannelEmail   AlertChannel = "EMAIL"
+	AlertChannelWebhook AlertChannel = "WEBHOOK"
+)
+
+var AllAlertChannel = []AlertChannel{
+	AlertChannelEmail,
+	AlertChannelWebhook,
+}
+
+func (e AlertChannel) IsValid() bool {
+	switch e {
+	case AlertChannelEmail, AlertChannelWebhook:
+		return true
+	}
+	return false
+}
+
+func (e AlertChannel) String() string {
+	return string(e)
+}
+
+func (e *AlertChannel) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AlertChannel(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AlertChannel", str)
+	}
+	return nil
+}
+
+func (e AlertChannel) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OrderStatus string
+
+const (
+	OrderStatusPendingPayment OrderStatus = "PENDING_PAYMENT"
+	OrderStatusPaid           OrderStatus = "PAID"
+	OrderStatusFulfilling     OrderStatus = "FULFILLING"
+	OrderStatusShipped        OrderStatus = "SHIPPED"
+	OrderStatusDelivered      OrderStatus = "DELIVERED"
+	OrderStatusCancelled      OrderStatus = "CANCELLED"
+	OrderStatusRefunded       OrderStatus = "REFUNDED"
+)
+
+var AllOrderStatus = []OrderStatus{
+	OrderStatusPendingPayment,
+	OrderStatusPaid,
+	OrderStatusFulfilling,
+	OrderStatusShipped,
+	OrderStatusDelivered,
+	OrderStatusCancelled,
+	OrderStatusRefunded,
+}
+
+func (e OrderStatus) IsValid() bool {
+	switch e {
+	case OrderStatusPendingPayment, OrderStatusPaid, OrderStatusFulfilling, OrderStatusShipped, OrderStatusDelivered, OrderStatusCancelled, OrderStatusRefunded:
+		return true
+	}
+	return false
+}
+
+func (e OrderStatus) String() string {
+	return string(e)
+}
+
+func (e *OrderStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderStatus", str)
+	}
+	return nil
+}
+
+func (e OrderStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ProductAlertKind string
+
+const (
+	ProductAlertKindBackInStock ProductAlertKind = "BACK_IN_STOCK"
+	ProductAlertKindPriceBelow  ProductAlertKind = "PRICE_BELOW"
+)
+
+var AllProductAlertKind = []ProductAlertKind{
+	ProductAlertKindBackInStock,
+	ProductAlertKindPriceBelow,
+}
+
+func (e ProductAlertKind) IsValid() bool {
+	switch e {
+	case ProductAlertKindBackInStock, ProductAlertKindPriceBelow:
+		return true
+	}
+	return false
+}
+
+func (e ProductAlertKind) String() string {
+	return string(e)
+}
+
+func (e *ProductAlertKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ProductAlertKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ProductAlertKind", str)
+	}
+	return nil
+}
+
+func (e ProductAlertKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type PromotionKind string
+
+const (
+	PromotionKindPercentage PromotionKind = "PERCENTAGE"
+	PromotionKindFixed      PromotionKind = "FIXED"
+	PromotionKindBuyXGetY   PromotionKind = "BUY_X_GET_Y"
+)
+
+var AllPromotionKind = []PromotionKind{
+	PromotionKindPercentage,
+	PromotionKindFixed,
+	PromotionKindBuyXGetY,
+}
+
+func (e PromotionKind) IsValid() bool {
+	switch e {
+	case PromotionKindPercentage, PromotionKindFixed, PromotionKindBuyXGetY:
+		return true
+	}
+	return false
+}
+
+func (e PromotionKind) String() string {
+	return string(e)
+}
+
+func (e *PromotionKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PromotionKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PromotionKind", str)
+	}
+	return nil
+}
+
+func (e PromotionKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type RecurringInterval string
+
+const (
+	RecurringIntervalWeekly  RecurringInterval = "WEEKLY"
+	RecurringIntervalMonthly RecurringInterval = "MONTHLY"
+)
+
+var AllRecurringInterval = []RecurringInterval{
+	RecurringIntervalWeekly,
+	RecurringIntervalMonthly,
+}
+
+func (e RecurringInterval) IsValid() bool {
+	switch e {
+	case RecurringIntervalWeekly, RecurringIntervalMonthly:
+		return true
+	}
+	return false
+}
+
+func (e RecurringInterval) String() string {
+	return string(e)
+}
+
+func (e *RecurringInterval) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RecurringInterval(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RecurringInterval", str)
+	}
+	return nil
+}
+
+func (e RecurringInterval) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type RecurringOrderStatus string
+
+const (
+	RecurringOrderStatusActive    RecurringOrderStatus = "ACTIVE"
+	RecurringOrderStatusPaused    RecurringOrderStatus = "PAUSED"
+	RecurringOrderStatusCancelled RecurringOrderStatus = "CANCELLED"
+)
+
+var AllRecurringOrderStatus = []RecurringOrderStatus{
+	RecurringOrderStatusActive,
+	RecurringOrderStatusPaused,
+	RecurringOrderStatusCancelled,
+}
+
+func (e RecurringOrderStatus) IsValid() bool {
+	switch e {
+	case RecurringOrderStatusActive, RecurringOrderStatusPaused, RecurringOrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e RecurringOrderStatus) String() string {
+	return string(e)
+}
+
+func (e *RecurringOrderStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RecurringOrderStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RecurringOrderStatus", str)
+	}
+	return nil
+}
+
+func (e RecurringOrderStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReturnStatus string
+
+const (
+	ReturnStatusRequested ReturnStatus = "REQUESTED"
+	ReturnStatusApproved  ReturnStatus = "APPROVED"
+	ReturnStatusRejected  ReturnStatus = "REJECTED"
+	ReturnStatusReceived  ReturnStatus = "RECEIVED"
+	ReturnStatusRefunded  ReturnStatus = "REFUNDED"
+)
+
+var AllReturnStatus = []ReturnStatus{
+	ReturnStatusRequested,
+	ReturnStatusApproved,
+	ReturnStatusRejected,
+	ReturnStatusReceived,
+	ReturnStatusRefunded,
+}
+
+func (e ReturnStatus) IsValid() bool {
+	switch e {
+	case ReturnStatusRequested, ReturnStatusApproved, ReturnStatusRejected, ReturnStatusReceived, ReturnStatusRefunded:
+		return true
+	}
+	return false
+}
+
+func (e ReturnStatus) String() string {
+	return string(e)
+}
+
+func (e *ReturnStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReturnStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReturnStatus", str)
+	}
+	return nil
+}
+
+func (e ReturnStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type RiskDecision string
+
+const (
+	RiskDecisionAllow  RiskDecision = "ALLOW"
+	RiskDecisionReview RiskDecision = "REVIEW"
+	RiskDecisionBlock  RiskDecision = "BLOCK"
+)
+
+var AllRiskDecision = []RiskDecision{
+	RiskDecisionAllow,
+	RiskDecisionReview,
+	RiskDecisionBlock,
+}
+
+func (e RiskDecision) IsValid() bool {
+	switch e {
+	case RiskDecisionAllow, RiskDecisionReview, RiskDecisionBlock:
+		return true
+	}
+	return false
+}
+
+func (e RiskDecision) String() string {
+	return string(e)
+}
+
+func (e *RiskDecision) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RiskDecision(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RiskDecision", str)
+	}
+	return nil
+}
+
+func (e RiskDecision) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type RiskReviewStatus string
+
+const (
+	RiskReviewStatusPending  RiskReviewStatus = "PENDING"
+	RiskReviewStatusApproved RiskReviewStatus = "APPROVED"
+	RiskReviewStatusRejected RiskReviewStatus = "REJECTED"
+)
+
+var AllRiskReviewStatus = []RiskReviewStatus{
+	RiskReviewStatusPending,
+	RiskReviewStatusApproved,
+	RiskReviewStatusRejected,
+}
+
+func (e RiskReviewStatus) IsValid() bool {
+	switch e {
+	case RiskReviewStatusPending, RiskReviewStatusApproved, RiskReviewStatusRejected:
+		return true
+	}
+	return false
+}
+
+func (e RiskReviewStatus) String() string {
+	return string(e)
+}
+
+func (e *RiskReviewStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RiskReviewStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RiskReviewStatus", str)
+	}
+	return nil
+}
+
+func (e RiskReviewStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type StoreCreditKind string
+
+const (
+	StoreCreditKindRefund   StoreCreditKind = "REFUND"
+	StoreCreditKindSpent    StoreCreditKind = "SPENT"
+	StoreCreditKindReleased StoreCreditKind = "RELEASED"
+)
+
+var AllStoreCreditKind = []StoreCreditKind{
+	StoreCreditKindRefund,
+	StoreCreditKindSpent,
+	StoreCreditKindReleased,
+}
+
+func (e StoreCreditKind) IsValid() bool {
+	switch e {
+	case StoreCreditKindRefund, StoreCreditKindSpent, StoreCreditKindReleased:
+		return true
+	}
+	return false
+}
+
+func (e StoreCreditKind) String() string {
+	return string(e)
+}
+
+func (e *StoreCreditKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = StoreCreditKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid StoreCreditKind", str)
+	}
+	return nil
+}
+
+func (e StoreCreditKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
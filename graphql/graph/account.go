@@ -2,11 +2,13 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"log"
 	"time"
 
 	"github.com/rasadov/EcommerceAPI/graphql/generated"
 	"github.com/rasadov/EcommerceAPI/graphql/models"
+	"github.com/rasadov/EcommerceAPI/pkg/auth"
 )
 
 type accountResolver struct {
@@ -17,7 +19,7 @@ func (resolver *accountResolver) ID(ctx context.Context, obj *models.Account) (i
 	return int(obj.ID), nil
 }
 
-func (resolver *accountResolver) Orders(ctx context.Context, obj *models.Account) ([]*generated.Order, error) {
+func (resolver *accountResolver) Orders(ctx context.Context, obj *models.Account, currency *string) ([]*generated.Order, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
@@ -28,24 +30,78 @@ func (resolver *accountResolver) Orders(ctx context.Context, obj *models.Account
 	}
 
 	var orders []*generated.Order
-	for _, order := range orderList {
-		var products []*generated.OrderedProduct
-		for _, orderedProduct := range order.Products {
-			products = append(products, &generated.OrderedProduct{
-				ID:          orderedProduct.ID,
-				Name:        orderedProduct.Name,
-				Description: orderedProduct.Description,
-				Price:       orderedProduct.Price,
-				Quantity:    int(orderedProduct.Quantity),
-			})
+	for i := range orderList {
+		order, err := resolver.server.toOrder(&orderList[i], currency)
+		if err != nil {
+			log.Println(err)
+			return nil, err
 		}
-		orders = append(orders, &generated.Order{
-			ID:         int(order.ID),
-			CreatedAt:  order.CreatedAt,
-			TotalPrice: order.TotalPrice,
-			Products:   products,
-		})
+		orders = append(orders, order)
 	}
 
 	return orders, nil
 }
+
+// Wishlists are private, so they are only returned to the account owner.
+func (resolver *accountResolver) Wishlists(ctx context.Context, obj *models.Account) ([]*models.Wishlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, false)
+	if err != nil || uint64(accountId) != obj.ID {
+		return nil, errors.New("unauthorized")
+	}
+
+	wishlistList, err := resolver.server.wishlistClient.GetWishlists(ctx, obj.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	wishlists := make([]*models.Wishlist, 0, len(wishlistList))
+	for _, w := range wishlistList {
+		wishlists = append(wishlists, toWishlist(w))
+	}
+	return wishlists, nil
+}
+
+// ProductAlerts are private, so they are only returned to the account owner.
+func (resolver *accountResolver) ProductAlerts(ctx context.Context, obj *models.Account) ([]*generated.ProductAlert, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, false)
+	if err != nil || uint64(accountId) != obj.ID {
+		return nil, errors.New("unauthorized")
+	}
+
+	subscriptions, err := resolver.server.alertClient.GetSubscriptions(ctx, obj.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	alerts := make([]*generated.ProductAlert, 0, len(subscriptions))
+	for _, s := range subscriptions {
+		alerts = append(alerts, toProductAlert(s))
+	}
+	return alerts, nil
+}
+
+// CheckoutReminders is a private setting, so it is only returned to the account owner.
+func (resolver *accountResolver) CheckoutReminders(ctx context.Context, obj *models.Account) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, false)
+	if err != nil || uint64(accountId) != obj.ID {
+		return false, errors.New("unauthorized")
+	}
+
+	enabled, err := resolver.server.alertClient.GetCheckoutReminders(ctx, obj.ID)
+	if err != nil {
+		log.Println(err)
+		return false, err
+	}
+	return enabled, nil
+}
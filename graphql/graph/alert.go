@@ -0,0 +1,24 @@
+package graph
+
+import (
+	"strings"
+
+	alert "github.com/rasadov/EcommerceAPI/alert/models"
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+)
+
+func toProductAlert(s *alert.Subscription) *generated.ProductAlert {
+	productAlert := &generated.ProductAlert{
+		ID:        int(s.ID),
+		ProductID: s.ProductID,
+		Kind:      generated.ProductAlertKind(strings.ToUpper(s.Kind)),
+		Channel:   generated.AlertChannel(strings.ToUpper(s.Channel)),
+		Target:    s.Target,
+		CreatedAt: s.CreatedAt,
+	}
+	if s.Kind == alert.KindPriceBelow {
+		threshold := s.Threshold
+		productAlert.Threshold = &threshold
+	}
+	return productAlert
+}
@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	cart "github.com/rasadov/EcommerceAPI/cart/models"
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/auth"
+	"github.com/rasadov/EcommerceAPI/pkg/contextkeys"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"google.golang.org/grpc/status"
+)
+
+// cartOwner returns the owner of the caller's cart: the signed-in account, or otherwise
+// the anonymous session from the cart session cookie.
+func cartOwner(ctx context.Context) (cart.Owner, error) {
+	if accountId, err := auth.GetUserIdInt(ctx, false); err == nil {
+		return cart.Owner{AccountID: uint64(accountId)}, nil
+	}
+	session, ok := ctx.Value(contextkeys.CartSessionKey).(string)
+	if !ok || session == "" {
+		return cart.Owner{}, errors.New("no cart session")
+	}
+	return cart.Owner{SessionID: session}, nil
+}
+
+// mergeSessionCart moves the anonymous session's cart into the account that just signed in
+// with token. Failures are logged so they never prevent signing in.
+func (server *Server) mergeSessionCart(ctx context.Context, token string) {
+	session, ok := ctx.Value(contextkeys.CartSessionKey).(string)
+	if !ok || session == "" {
+		return
+	}
+
+	parsed, err := auth.ValidateToken(token)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	claims, ok := parsed.Claims.(*auth.JWTCustomClaims)
+	if !ok {
+		return
+	}
+
+	_, err = server.cartClient.MergeCarts(ctx, session, claims.UserID)
+	if err != nil {
+		log.Println("Error merging carts", err)
+	}
+}
+
+// cartCurrency returns the currency the cart's totals are given in: the requested currency,
+// or the currency of the first item when none was requested.
+func cartCurrency(c *cart.Cart, currency *string) string {
+	if currency != nil && *currency != "" {
+		return money.Normalize(*currency)
+	}
+	if len(c.Items) > 0 {
+		return c.Items[0].UnitPrice.Currency
+	}
+	return money.DefaultCurrency
+}
+
+// evaluateCoupon asks the order service for the discount the promotion with the given code
+// gives on the cart's products when ordered in currency.
+func (server *Server) evaluateCoupon(ctx context.Context, c *cart.Cart, code, currency string) (*order.OrderDiscount, error) {
+	products := make([]*order.OrderedProduct, 0, len(c.Items))
+	for _, item := range c.Items {
+		products = append(products, &order.OrderedProduct{
+			ID:       item.ProductID,
+			Quantity: uint32(item.Quantity),
+		})
+	}
+
+	var accountId uint64
+	if c.AccountID != nil {
+		accountId = *c.AccountID
+	}
+	evaluation, err := server.orderClient.EvaluatePromotion(ctx, code, accountId, currency, products)
+	if err != nil {
+		return nil, err
+	}
+	return evaluation.Discount, nil
+}
+
+// toCart converts the cart's prices to currency, if given. The totals are in the currency
+// returned by cartCurrency, with the discount of the applied coupon taken off the total.
+func (server *Server) toCart(ctx context.Context, c *cart.Cart, currency *string) (*generated.Cart, error) {
+	subtotalCurrency := cartCurrency(c, currency)
+
+	subtotal := money.Zero(subtotalCurrency)
+	items := make([]*generated.CartItem, 0, len(c.Items))
+	for _, item := range c.Items {
+		unitPrice, err := server.convert(item.UnitPrice, currency)
+		if err != nil {
+			return nil, err
+		}
+		lineTotal, err := server.convert(item.LineTotal(), currency)
+		if err != nil {
+			return nil, err
+		}
+
+		cartItem := &generated.CartItem{
+			ProductID: item.ProductID,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: &unitPrice,
+			LineTotal: &lineTotal,
+			Available: item.Available,
+		}
+		if item.PreviousUnitPrice != nil {
+			previous, err := server.convert(*item.PreviousUnitPrice, currency)
+			if err != nil {
+				return nil, err
+			}
+			cartItem.PreviousUnitPrice = &previous
+		}
+		items = append(items, cartItem)
+
+		lineSubtotal, err := server.converter.Convert(item.LineTotal(), subtotalCurrency)
+		if err != nil {
+			return nil, err
+		}
+		subtotal, err = subtotal.Add(lineSubtotal)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &generated.Cart{
+		Items:    items,
+		Subtotal: &subtotal,
+		Total:    &subtotal,
+	}
+	if c.CouponCode != "" {
+		result.CouponCode = &c.CouponCode
+		discount, err := server.evaluateCoupon(ctx, c, c.CouponCode, subtotalCurrency)
+		if err != nil {
+			log.Println("Error evaluating cart coupon", err)
+			message := status.Convert(err).Message()
+			result.CouponError = &message
+		} else {
+			total, err := subtotal.Sub(discount.Amount)
+			if err != nil {
+				return nil, err
+			}
+			// The cart may show older prices than the discount was computed from
+			if total.IsNegative() {
+				total = money.Zero(total.Currency)
+			}
+			result.Discount = toOrderDiscount(discount, discount.Amount)
+			result.Total = &total
+		}
+	}
+	if !c.ExpiresAt.IsZero() {
+		result.ExpiresAt = &c.ExpiresAt
+	}
+	return result, nil
+}
@@ -0,0 +1,194 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	"github.com/rasadov/EcommerceAPI/graphql/models"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	payment "github.com/rasadov/EcommerceAPI/payment/models"
+	"github.com/rasadov/EcommerceAPI/pkg/auth"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// orderCredit returns the gift card and store credit the caller puts towards an order.
+func orderCredit(giftCardCode *string, useStoreCredit *bool) order.OrderCredit {
+	credit := order.OrderCredit{UseStoreCredit: useStoreCredit != nil && *useStoreCredit}
+	if giftCardCode != nil {
+		credit.GiftCardCode = strings.TrimSpace(*giftCardCode)
+	}
+	return credit
+}
+
+func toGiftCard(card *payment.GiftCard) *generated.GiftCard {
+	return &generated.GiftCard{
+		Code:      card.Code,
+		Initial:   &card.Initial,
+		Balance:   &card.Balance,
+		ExpiresAt: card.ExpiresAt,
+		CreatedAt: card.CreatedAt,
+	}
+}
+
+// IssueGiftCard issues a gift card worth the given amount; admins only.
+func (resolver *mutationResolver) IssueGiftCard(ctx context.Context, in generated.GiftCardInput) (*generated.GiftCard, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil || !isAdmin(accountId) {
+		return nil, errors.New("unauthorized")
+	}
+
+	card := &payment.GiftCard{
+		Initial:   money.FromMajor(in.Amount, currencyOrDefault(in.Currency)),
+		IssuedBy:  fmt.Sprintf("account:%d", accountId),
+		ExpiresAt: in.ExpiresAt,
+	}
+	if in.Code != nil {
+		card.Code = *in.Code
+	}
+	if in.Note != nil {
+		card.Note = *in.Note
+	}
+
+	card, err = resolver.server.paymentClient.IssueGiftCard(ctx, card)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toGiftCard(card), nil
+}
+
+// PurchaseGiftCard opens a checkout for a gift card worth the given amount. The card is
+// issued to the caller once the checkout is paid and listed with their account's gift cards.
+func (resolver *mutationResolver) PurchaseGiftCard(ctx context.Context, in generated.GiftCardPurchaseInput) (*generated.RedirectResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	account, err := resolver.server.accountClient.GetAccount(ctx, uint64(accountId))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	checkoutURL, err := resolver.server.paymentClient.PurchaseGiftCard(ctx, uint64(accountId), account.Email, account.Name,
+		in.RedirectURL, money.FromMajor(in.Amount, currencyOrDefault(in.Currency)))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return &generated.RedirectResponse{URL: checkoutURL}, nil
+}
+
+// GiftCard looks up a gift card by its code, which is all it takes to spend it. Customers
+// only find the cards they bought or have spent, so codes and balances cannot be found by
+// trying them; admins find any card.
+func (resolver *queryResolver) GiftCard(ctx context.Context, code string) (*generated.GiftCard, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	userId := uint64(accountId)
+	if isAdmin(accountId) {
+		userId = 0
+	}
+	card, err := resolver.server.paymentClient.GetGiftCard(ctx, code, userId)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toGiftCard(card), nil
+}
+
+// StoreCredit returns the account's store credit balances and its transactions, newest
+// first. It is private, so it is only returned to the account owner.
+func (resolver *accountResolver) StoreCredit(ctx context.Context, obj *models.Account, after *string, first *int) (*generated.StoreCredit, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, false)
+	if err != nil || uint64(accountId) != obj.ID {
+		return nil, errors.New("unauthorized")
+	}
+
+	cursor, pageSize := "", 0
+	if after != nil {
+		cursor = *after
+	}
+	if first != nil {
+		if *first < 0 {
+			return nil, errors.New("first must not be negative")
+		}
+		pageSize = *first
+	}
+
+	balances, page, err := resolver.server.paymentClient.GetStoreCredit(ctx, obj.ID, cursor, pageSize)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	credit := &generated.StoreCredit{
+		Balances:     []*money.Money{},
+		Transactions: []*generated.StoreCreditTransaction{},
+		PageInfo:     &generated.PageInfo{HasNextPage: page.HasNextPage},
+	}
+	if page.EndCursor != "" {
+		credit.PageInfo.EndCursor = &page.EndCursor
+	}
+	for i := range balances {
+		credit.Balances = append(credit.Balances, &balances[i])
+	}
+	for _, transaction := range page.Transactions {
+		converted := &generated.StoreCreditTransaction{
+			ID:        int(transaction.ID),
+			Kind:      generated.StoreCreditKind(strings.ToUpper(transaction.Kind)),
+			Amount:    &transaction.Amount,
+			CreatedAt: transaction.CreatedAt,
+		}
+		if transaction.OrderId != 0 {
+			orderId := int(transaction.OrderId)
+			converted.OrderID = &orderId
+		}
+		credit.Transactions = append(credit.Transactions, converted)
+	}
+	return credit, nil
+}
+
+// GiftCards returns the gift cards the account bought, newest first. Their codes are all
+// it takes to spend them, so they are only returned to the account owner.
+func (resolver *accountResolver) GiftCards(ctx context.Context, obj *models.Account) ([]*generated.GiftCard, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, false)
+	if err != nil || uint64(accountId) != obj.ID {
+		return nil, errors.New("unauthorized")
+	}
+
+	cards, err := resolver.server.paymentClient.ListGiftCards(ctx, obj.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	giftCards := make([]*generated.GiftCard, 0, len(cards))
+	for _, card := range cards {
+		giftCards = append(giftCards, toGiftCard(card))
+	}
+	return giftCards, nil
+}
@@ -4,11 +4,16 @@ import (
 	"github.com/99designs/gqlgen/graphql"
 
 	account "github.com/rasadov/EcommerceAPI/account/client"
+	alert "github.com/rasadov/EcommerceAPI/alert/client"
+	cart "github.com/rasadov/EcommerceAPI/cart/client"
 	"github.com/rasadov/EcommerceAPI/graphql/generated"
 	order "github.com/rasadov/EcommerceAPI/order/client"
 	payment "github.com/rasadov/EcommerceAPI/payment/client"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 	product "github.com/rasadov/EcommerceAPI/product/client"
 	recommender "github.com/rasadov/EcommerceAPI/recommender/client"
+	shipping "github.com/rasadov/EcommerceAPI/shipping/client"
+	wishlist "github.com/rasadov/EcommerceAPI/wishlist/client"
 )
 
 type Server struct {
@@ -17,9 +22,14 @@ type Server struct {
 	orderClient       *order.Client
 	paymentClient     *payment.Client
 	recommenderClient *recommender.Client
+	wishlistClient    *wishlist.Client
+	cartClient        *cart.Client
+	alertClient       *alert.Client
+	shippingClient    *shipping.Client
+	converter         *money.Converter
 }
 
-func NewGraphQLServer(accountUrl, productUrl, orderUrl, paymentUrl, recommenderUrl string) (*Server, error) {
+func NewGraphQLServer(accountUrl, productUrl, orderUrl, paymentUrl, recommenderUrl, wishlistUrl, cartUrl, alertUrl, shippingUrl string, converter *money.Converter) (*Server, error) {
 	accClient, err := account.NewClient(accountUrl)
 	if err != nil {
 		return nil, err
@@ -54,12 +64,63 @@ func NewGraphQLServer(accountUrl, productUrl, orderUrl, paymentUrl, recommenderU
 		return nil, err
 	}
 
+	wishClient, err := wishlist.NewClient(wishlistUrl)
+	if err != nil {
+		accClient.Close()
+		prodClient.Close()
+		ordClient.Close()
+		paymentClient.Close()
+		recClient.Close()
+		return nil, err
+	}
+
+	cartClient, err := cart.NewClient(cartUrl)
+	if err != nil {
+		accClient.Close()
+		prodClient.Close()
+		ordClient.Close()
+		paymentClient.Close()
+		recClient.Close()
+		wishClient.Close()
+		return nil, err
+	}
+
+	alertClient, err := alert.NewClient(alertUrl)
+	if err != nil {
+		accClient.Close()
+		prodClient.Close()
+		ordClient.Close()
+		paymentClient.Close()
+		recClient.Close()
+		wishClient.Close()
+		cartClient.Close()
+		return nil, err
+	}
+
+	shippingClient, err := shipping.NewClient(shippingUrl)
+	if err != nil {
+		accClient.Close()
+		prodClient.Close()
+		ordClient.Close()
+		paymentClient.Close()
+		recClient.Close()
+		wishClient.Close()
+		cartClient.Close()
+		alertClient.Close()
+		return nil, err
+	}
+
 	return &Server{
 		accountClient:     accClient,
 		productClient:     prodClient,
 		orderClient:       ordClient,
 		paymentClient:     paymentClient,
 		recommenderClient: recClient,
+		wishlistClient:    wishClient,
+		cartClient:        cartClient,
+		alertClient:       alertClient,
+		shippingClient:    shippingClient,
+		converter:         converter,
 	}, nil
 }
 
@@ -81,6 +142,24 @@ func (server *Server) Account() generated.AccountResolver {
 	}
 }
 
+func (server *Server) Seller() generated.SellerResolver {
+	return &sellerResolver{
+		server: server,
+	}
+}
+
+func (server *Server) Order() generated.OrderResolver {
+	return &orderResolver{
+		server: server,
+	}
+}
+
+func (server *Server) Wishlist() generated.WishlistResolver {
+	return &wishlistResolver{
+		server: server,
+	}
+}
+
 func (server *Server) ToExecutableSchema() graphql.ExecutableSchema {
 	return generated.NewExecutableSchema(generated.Config{
 		Resolvers: server,
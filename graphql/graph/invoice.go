@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// invoiceURL is where the invoice of a paid order is downloaded, or nil while the order
+// has not been paid.
+func invoiceURL(o *order.Order) *string {
+	if !o.WasPaid() {
+		return nil
+	}
+	url := fmt.Sprintf("/invoices/%d.pdf", o.ID)
+	return &url
+}
+
+// InvoiceHandler serves the invoices of paid orders at /invoices/:file, where file is the
+// order ID followed by .pdf or .html. Customers can download the invoices of their own
+// orders and admins those of every order.
+func (server *Server) InvoiceHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		accountId, err := auth.GetUserIdInt(ctx, false)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		id, format, ok := strings.Cut(c.Param("file"), ".")
+		orderId, err := strconv.ParseUint(id, 10, 64)
+		if !ok || err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "invoice not found"})
+			return
+		}
+
+		owner := uint64(accountId)
+		if isAdmin(accountId) {
+			owner = 0
+		}
+		invoice, err := server.orderClient.GetInvoice(ctx, orderId, owner, format)
+		if err != nil {
+			log.Println(err)
+			c.AbortWithStatusJSON(invoiceErrorStatus(err), gin.H{"error": status.Convert(err).Message()})
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s.%s"`, invoice.Number, format))
+		c.Data(http.StatusOK, invoice.ContentType, invoice.Content)
+	}
+}
+
+// invoiceErrorStatus is the HTTP status answering a failed invoice download.
+func invoiceErrorStatus(err error) int {
+	switch status.Code(err) {
+	case codes.NotFound, codes.InvalidArgument:
+		return http.StatusNotFound
+	case codes.FailedPrecondition:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,198 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/pkg/tax"
+	product "github.com/rasadov/EcommerceAPI/product/models"
+)
+
+// convert returns m in the requested currency, or unchanged when no currency was requested.
+func (server *Server) convert(m money.Money, currency *string) (money.Money, error) {
+	if currency == nil || *currency == "" {
+		return m, nil
+	}
+	return server.converter.Convert(m, *currency)
+}
+
+func (server *Server) toProduct(p *product.Product, currency *string) (*generated.Product, error) {
+	price, err := server.convert(p.Price, currency)
+	if err != nil {
+		return nil, err
+	}
+	result := &generated.Product{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       price.Major(),
+		UnitPrice:   &price,
+		Currency:    price.Currency,
+		AccountID:   p.AccountID,
+		Stock:       p.Stock,
+		InStock:     p.InStock(),
+		TaxCategory: p.TaxCategory,
+		WeightGrams: p.WeightGrams,
+	}
+	// Products created before tax categories were introduced are taxed at the standard rate
+	if result.TaxCategory == "" {
+		result.TaxCategory = string(tax.CategoryStandard)
+	}
+	if p.Category != "" {
+		result.Category = &p.Category
+	}
+	return result, nil
+}
+
+func (server *Server) toProducts(productList []product.Product, currency *string) ([]*generated.Product, error) {
+	var products []*generated.Product
+	for i := range productList {
+		p, err := server.toProduct(&productList[i], currency)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, nil
+}
+
+func (server *Server) toOrder(o *order.Order, currency *string) (*generated.Order, error) {
+	totalPrice, err := server.convert(o.TotalPrice, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var products []*generated.OrderedProduct
+	for _, orderedProduct := range o.Products {
+		price, err := server.convert(orderedProduct.Price, currency)
+		if err != nil {
+			return nil, err
+		}
+		lineTotal := price.Mul(int64(orderedProduct.Quantity))
+		products = append(products, &generated.OrderedProduct{
+			ID:          orderedProduct.ID,
+			Name:        orderedProduct.Name,
+			Description: orderedProduct.Description,
+			Price:       price.Major(),
+			UnitPrice:   &price,
+			Currency:    price.Currency,
+			Quantity:    int(orderedProduct.Quantity),
+			LineTotal:   &lineTotal,
+		})
+	}
+
+	orderSubtotal, err := o.Subtotal()
+	if err != nil {
+		return nil, err
+	}
+	subtotal, err := server.convert(orderSubtotal, currency)
+	if err != nil {
+		return nil, err
+	}
+	discounts := []*generated.OrderDiscount{}
+	for _, discount := range o.Discounts {
+		amount, err := server.convert(discount.Amount, currency)
+		if err != nil {
+			return nil, err
+		}
+		discounts = append(discounts, toOrderDiscount(discount, amount))
+	}
+	taxes := []*generated.OrderTax{}
+	for _, t := range o.Taxes {
+		amount, err := server.convert(t.Amount, currency)
+		if err != nil {
+			return nil, err
+		}
+		taxes = append(taxes, toOrderTax(t, amount))
+	}
+	orderTaxTotal, err := o.TaxTotal()
+	if err != nil {
+		return nil, err
+	}
+	taxTotal, err := server.convert(orderTaxTotal, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	returns := []*generated.OrderReturn{}
+	for _, r := range o.Returns {
+		ret, err := server.toOrderReturn(r, currency)
+		if err != nil {
+			return nil, err
+		}
+		returns = append(returns, ret)
+	}
+
+	subOrders := []*generated.SubOrder{}
+	for _, sub := range o.SubOrders {
+		converted, err := server.toSubOrder(sub, currency)
+		if err != nil {
+			return nil, err
+		}
+		subOrders = append(subOrders, converted)
+	}
+
+	result := &generated.Order{
+		ID:              int(o.ID),
+		CreatedAt:       o.CreatedAt,
+		TotalPrice:      totalPrice.Major(),
+		Total:           &totalPrice,
+		Currency:        totalPrice.Currency,
+		Status:          toOrderStatus(o.Status),
+		StatusHistory:   toOrderStatusHistory(o.History),
+		Products:        products,
+		Subtotal:        &subtotal,
+		Discounts:       discounts,
+		Taxes:           taxes,
+		TaxTotal:        &taxTotal,
+		ShippingAddress: toAddress(o.ShippingAddress),
+		Returns:         returns,
+		SubOrders:       subOrders,
+		InvoiceURL:      invoiceURL(o),
+		RiskReview:      toRiskReviewStatus(o.RiskReview),
+	}
+	if o.ShippingMethod != "" {
+		shippingCost, err := server.convert(o.ShippingCost, currency)
+		if err != nil {
+			return nil, err
+		}
+		result.ShippingMethod = &o.ShippingMethod
+		result.ShippingCost = &shippingCost
+	}
+	return result, nil
+}
+
+// toOrderDiscount returns the discount with its amount given in amount's currency.
+func toOrderDiscount(discount *order.OrderDiscount, amount money.Money) *generated.OrderDiscount {
+	return &generated.OrderDiscount{
+		Code:        discount.Code,
+		Description: discount.Description,
+		Amount:      &amount,
+	}
+}
+
+// currencyOrDefault returns the requested currency code, or "" so that services apply their default.
+func currencyOrDefault(currency *string) string {
+	if currency == nil {
+		return ""
+	}
+	return *currency
+}
+
+// categoryOf returns the requested product category, or "" for uncategorised products.
+func categoryOf(category *string) string {
+	if category == nil {
+		return ""
+	}
+	return strings.TrimSpace(*category)
+}
+
+// weightOf returns the requested shipping weight, or 0 for digital goods.
+func weightOf(weightGrams *int) int {
+	if weightGrams == nil {
+		return 0
+	}
+	return *weightGrams
+}
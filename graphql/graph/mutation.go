@@ -3,15 +3,21 @@ package graph
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	cart "github.com/rasadov/EcommerceAPI/cart/models"
 	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	gqlmodels "github.com/rasadov/EcommerceAPI/graphql/models"
 	"github.com/rasadov/EcommerceAPI/order/models"
 	payment "github.com/rasadov/EcommerceAPI/payment/proto/pb"
 	"github.com/rasadov/EcommerceAPI/pkg/auth"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	shipping "github.com/rasadov/EcommerceAPI/shipping/models"
 )
 
 var (
@@ -31,6 +37,7 @@ func (resolver *mutationResolver) Register(ctx context.Context, in generated.Reg
 		log.Println(err)
 		return nil, err
 	}
+	resolver.server.mergeSessionCart(ctx, token)
 
 	ginContext, ok := ctx.Value("GinContextKey").(*gin.Context)
 	if !ok {
@@ -50,6 +57,7 @@ func (resolver *mutationResolver) Login(ctx context.Context, in generated.LoginI
 		log.Println(err)
 		return nil, err
 	}
+	resolver.server.mergeSessionCart(ctx, token)
 
 	ginContext, ok := ctx.Value("GinContextKey").(*gin.Context)
 	if !ok {
@@ -71,7 +79,8 @@ func (resolver *mutationResolver) CreateProduct(ctx context.Context, in generate
 		return nil, err
 	}
 	log.Println("CreateProduct called with accountId:", accountId)
-	postProduct, err := resolver.server.productClient.PostProduct(ctx, in.Name, in.Description, in.Price, int64(accountId))
+	price := money.FromMajor(in.Price, currencyOrDefault(in.Currency))
+	postProduct, err := resolver.server.productClient.PostProduct(ctx, in.Name, in.Description, categoryOf(in.Category), categoryOf(in.TaxCategory), price, in.Stock, weightOf(in.WeightGrams), int64(accountId))
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -80,13 +89,8 @@ func (resolver *mutationResolver) CreateProduct(ctx context.Context, in generate
 	log.Println("Created product:", postProduct)
 	log.Println("Product id: ", postProduct.ID)
 
-	return &generated.Product{
-		ID:          postProduct.ID,
-		Name:        postProduct.Name,
-		Description: postProduct.Description,
-		Price:       postProduct.Price,
-		AccountID:   accountId,
-	}, nil
+	postProduct.AccountID = accountId
+	return resolver.server.toProduct(postProduct, nil)
 }
 
 func (resolver *mutationResolver) UpdateProduct(ctx context.Context, in generated.UpdateProductInput) (*generated.Product, error) {
@@ -98,17 +102,13 @@ func (resolver *mutationResolver) UpdateProduct(ctx context.Context, in generate
 		return nil, err
 	}
 
-	updatedProduct, err := resolver.server.productClient.UpdateProduct(ctx, in.ID, in.Name, in.Description, in.Price, int64(accountId))
+	price := money.FromMajor(in.Price, currencyOrDefault(in.Currency))
+	updatedProduct, err := resolver.server.productClient.UpdateProduct(ctx, in.ID, in.Name, in.Description, categoryOf(in.Category), categoryOf(in.TaxCategory), price, in.Stock, weightOf(in.WeightGrams), int64(accountId))
 	if err != nil {
 		return nil, err
 	}
-	return &generated.Product{
-		ID:          updatedProduct.ID,
-		Name:        updatedProduct.Name,
-		Description: updatedProduct.Description,
-		Price:       updatedProduct.Price,
-		AccountID:   accountId,
-	}, nil
+	updatedProduct.AccountID = accountId
+	return resolver.server.toProduct(updatedProduct, nil)
 }
 
 func (resolver *mutationResolver) DeleteProduct(ctx context.Context, id string) (*bool, error) {
@@ -150,29 +150,38 @@ func (resolver *mutationResolver) CreateOrder(ctx context.Context, in generated.
 		return nil, errors.New("unauthorized")
 	}
 
-	postOrder, err := resolver.server.orderClient.PostOrder(ctx, uint64(accountId), products)
+	postOrder, _, err := resolver.server.orderClient.PostOrder(ctx, uint64(accountId), currencyOrDefault(in.Currency), products, couponCode(in.CouponCode), shippingAddress(in.ShippingAddress), shippingMethod(in.ShippingMethod), "", orderOrigin(ctx, in.BillingCountry), orderCredit(in.GiftCardCode, in.UseStoreCredit))
 	if err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
-	var orderedProducts []*generated.OrderedProduct
-	for _, orderedProduct := range postOrder.Products {
-		orderedProducts = append(orderedProducts, &generated.OrderedProduct{
-			ID:          orderedProduct.ID,
-			Name:        orderedProduct.Name,
-			Description: orderedProduct.Description,
-			Price:       orderedProduct.Price,
-			Quantity:    int(orderedProduct.Quantity),
-		})
+	return resolver.server.toOrder(postOrder, nil)
+}
+
+// CancelOrder cancels one of the caller's orders. A paid order is refunded and shows as
+// refunded once the payment provider confirms the refund.
+func (resolver *mutationResolver) CancelOrder(ctx context.Context, id int, reason *string) (*generated.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	cancelReason := "cancelled by customer"
+	if reason != nil && strings.TrimSpace(*reason) != "" {
+		cancelReason = strings.TrimSpace(*reason)
+	}
+
+	cancelledOrder, err := resolver.server.orderClient.CancelOrder(ctx, uint64(id), uint64(accountId), cancelReason)
+	if err != nil {
+		log.Println(err)
+		return nil, err
 	}
 
-	return &generated.Order{
-		ID:         int(postOrder.ID),
-		CreatedAt:  postOrder.CreatedAt,
-		TotalPrice: postOrder.TotalPrice,
-		Products:   orderedProducts,
-	}, nil
+	return resolver.server.toOrder(cancelledOrder, nil)
 }
 
 func (resolver *mutationResolver) CreateCustomerPortalSession(ctx context.Context, credentials *generated.CustomerPortalSessionInput) (*generated.RedirectResponse, error) {
@@ -200,7 +209,7 @@ func (resolver *mutationResolver) CreateCheckoutSession(ctx context.Context, det
 	}
 
 	UrlWithCheckoutSession, err := resolver.server.paymentClient.CreateCheckoutSession(ctx, details.OrderID,
-		details.AccountID, details.Email, details.Name, details.RedirectURL, products)
+		details.AccountID, details.Email, details.Name, details.RedirectURL, currencyOrDefault(details.Currency), products)
 
 	if err != nil {
 		log.Println(err)
@@ -208,3 +217,769 @@ func (resolver *mutationResolver) CreateCheckoutSession(ctx context.Context, det
 	}
 	return &generated.RedirectResponse{URL: UrlWithCheckoutSession}, nil
 }
+
+func (resolver *mutationResolver) CreateWishlist(ctx context.Context, name string) (*gqlmodels.Wishlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	w, err := resolver.server.wishlistClient.CreateWishlist(ctx, uint64(accountId), name)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toWishlist(w), nil
+}
+
+func (resolver *mutationResolver) RenameWishlist(ctx context.Context, id int, name string) (*gqlmodels.Wishlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	w, err := resolver.server.wishlistClient.RenameWishlist(ctx, uint64(accountId), wishlistId(&id), name)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toWishlist(w), nil
+}
+
+func (resolver *mutationResolver) DeleteWishlist(ctx context.Context, id int) (*bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	err = resolver.server.wishlistClient.DeleteWishlist(ctx, uint64(accountId), wishlistId(&id))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	success := true
+	return &success, nil
+}
+
+func (resolver *mutationResolver) ShareWishlist(ctx context.Context, id int, shared bool) (*gqlmodels.Wishlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	w, err := resolver.server.wishlistClient.ShareWishlist(ctx, uint64(accountId), wishlistId(&id), shared)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toWishlist(w), nil
+}
+
+func (resolver *mutationResolver) AddToWishlist(ctx context.Context, item generated.WishlistItemInput) (*gqlmodels.Wishlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	w, err := resolver.server.wishlistClient.AddItem(ctx, uint64(accountId), wishlistId(item.WishlistID), item.ProductID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toWishlist(w), nil
+}
+
+func (resolver *mutationResolver) RemoveFromWishlist(ctx context.Context, item generated.WishlistItemInput) (*gqlmodels.Wishlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	w, err := resolver.server.wishlistClient.RemoveItem(ctx, uint64(accountId), wishlistId(item.WishlistID), item.ProductID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toWishlist(w), nil
+}
+
+func (resolver *mutationResolver) MoveWishlistItem(ctx context.Context, item generated.MoveWishlistItemInput) (*gqlmodels.Wishlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	w, err := resolver.server.wishlistClient.MoveItem(ctx, uint64(accountId), wishlistId(item.FromWishlistID), wishlistId(item.ToWishlistID), item.ProductID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toWishlist(w), nil
+}
+
+// MoveWishlistItemToCart adds one unit of the product to the caller's cart and removes it
+// from the wishlist.
+func (resolver *mutationResolver) MoveWishlistItemToCart(ctx context.Context, item generated.WishlistItemInput) (*generated.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	c, err := resolver.server.cartClient.AddItem(ctx, cart.Owner{AccountID: uint64(accountId)}, item.ProductID, 1)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	_, err = resolver.server.wishlistClient.RemoveItem(ctx, uint64(accountId), wishlistId(item.WishlistID), item.ProductID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toCart(ctx, c, nil)
+}
+
+func (resolver *mutationResolver) AddToCart(ctx context.Context, item generated.CartItemInput) (*generated.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if item.Quantity <= 0 {
+		return nil, ErrInvalidParameter
+	}
+	owner, err := cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := resolver.server.cartClient.AddItem(ctx, owner, item.ProductID, item.Quantity)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toCart(ctx, c, nil)
+}
+
+// UpdateCartItem sets the quantity of a product in the cart. A quantity of 0 removes it.
+func (resolver *mutationResolver) UpdateCartItem(ctx context.Context, item generated.CartItemInput) (*generated.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if item.Quantity < 0 {
+		return nil, ErrInvalidParameter
+	}
+	owner, err := cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := resolver.server.cartClient.UpdateItem(ctx, owner, item.ProductID, item.Quantity)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toCart(ctx, c, nil)
+}
+
+func (resolver *mutationResolver) RemoveFromCart(ctx context.Context, productID string) (*generated.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	owner, err := cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := resolver.server.cartClient.RemoveItem(ctx, owner, productID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toCart(ctx, c, nil)
+}
+
+func (resolver *mutationResolver) ClearCart(ctx context.Context) (*bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	owner, err := cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = resolver.server.cartClient.ClearCart(ctx, owner)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	success := true
+	return &success, nil
+}
+
+// CheckoutCart places an order for the caller's cart, with its coupon applied, and opens a
+// checkout session for it. Checkout is refused while items are unavailable or their prices
+// changed since the cart was last viewed, so the customer always pays the prices they saw.
+func (resolver *mutationResolver) CheckoutCart(ctx context.Context, checkout generated.CheckoutCartInput) (*generated.CartCheckout, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+	owner := cart.Owner{AccountID: uint64(accountId)}
+
+	c, err := resolver.server.cartClient.GetCart(ctx, owner)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	if len(c.Items) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	var products []*models.OrderedProduct
+	for _, item := range c.Items {
+		if !item.Available {
+			return nil, fmt.Errorf("%s is no longer available in the requested quantity", item.Name)
+		}
+		if item.PreviousUnitPrice != nil {
+			return nil, errors.New("prices in the cart have changed, review the cart before checking out")
+		}
+		products = append(products, &models.OrderedProduct{
+			ID:       item.ProductID,
+			Quantity: uint32(item.Quantity),
+		})
+	}
+
+	// The order service opens the checkout session, and cancels the order if it cannot
+	postOrder, checkoutUrl, err := resolver.server.orderClient.PostOrder(ctx, uint64(accountId), currencyOrDefault(checkout.Currency),
+		products, c.CouponCode, shippingAddress(checkout.ShippingAddress), shippingMethod(checkout.ShippingMethod), checkout.RedirectURL,
+		orderOrigin(ctx, checkout.BillingCountry), orderCredit(checkout.GiftCardCode, checkout.UseStoreCredit))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	err = resolver.server.cartClient.ClearCart(ctx, owner)
+	if err != nil {
+		log.Println(err)
+	}
+
+	order, err := resolver.server.toOrder(postOrder, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &generated.CartCheckout{Order: order, CheckoutURL: checkoutUrl}, nil
+}
+
+// ApplyCoupon applies a promotion code to the caller's cart. Codes that give no discount on
+// the cart are refused.
+func (resolver *mutationResolver) ApplyCoupon(ctx context.Context, code string, currency *string) (*generated.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	code = couponCode(&code)
+	if code == "" {
+		return nil, ErrInvalidParameter
+	}
+	owner, err := cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := resolver.server.cartClient.GetCart(ctx, owner)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	if len(c.Items) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+	_, err = resolver.server.evaluateCoupon(ctx, c, code, cartCurrency(c, currency))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	c, err = resolver.server.cartClient.SetCoupon(ctx, owner, code)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toCart(ctx, c, currency)
+}
+
+func (resolver *mutationResolver) RemoveCoupon(ctx context.Context, currency *string) (*generated.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	owner, err := cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := resolver.server.cartClient.SetCoupon(ctx, owner, "")
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toCart(ctx, c, currency)
+}
+
+func (resolver *mutationResolver) CreatePromotion(ctx context.Context, in generated.PromotionInput) (*generated.Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil || !isAdmin(accountId) {
+		return nil, errors.New("unauthorized")
+	}
+
+	promotion, err := resolver.server.orderClient.CreatePromotion(ctx, fromPromotionInput(in))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toPromotion(promotion), nil
+}
+
+func (resolver *mutationResolver) UpdatePromotion(ctx context.Context, id int, in generated.PromotionInput) (*generated.Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil || !isAdmin(accountId) {
+		return nil, errors.New("unauthorized")
+	}
+
+	promotion := fromPromotionInput(in)
+	promotion.ID = uint(id)
+	promotion, err = resolver.server.orderClient.UpdatePromotion(ctx, promotion)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toPromotion(promotion), nil
+}
+
+// DeactivatePromotion stops a promotion from being used. Orders that already used it keep
+// their discount.
+func (resolver *mutationResolver) DeactivatePromotion(ctx context.Context, id int) (*generated.Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil || !isAdmin(accountId) {
+		return nil, errors.New("unauthorized")
+	}
+
+	promotion, err := resolver.server.orderClient.DeactivatePromotion(ctx, uint(id))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toPromotion(promotion), nil
+}
+
+// SubscribeToProductAlert registers a back-in-stock or price-drop alert. Email alerts are
+// sent to the address of the signed-in account.
+func (resolver *mutationResolver) SubscribeToProductAlert(ctx context.Context, in generated.ProductAlertInput) (*generated.ProductAlert, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	var threshold *money.Money
+	if in.Kind == generated.ProductAlertKindPriceBelow {
+		if in.Threshold == nil {
+			return nil, ErrInvalidParameter
+		}
+		price := money.FromMajor(*in.Threshold, currencyOrDefault(in.Currency))
+		threshold = &price
+	}
+
+	channel := generated.AlertChannelEmail
+	if in.Channel != nil {
+		channel = *in.Channel
+	}
+	var target string
+	switch channel {
+	case generated.AlertChannelEmail:
+		account, err := resolver.server.accountClient.GetAccount(ctx, uint64(accountId))
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		target = account.Email
+	case generated.AlertChannelWebhook:
+		if in.WebhookURL == nil {
+			return nil, ErrInvalidParameter
+		}
+		target = *in.WebhookURL
+	}
+
+	subscription, err := resolver.server.alertClient.Subscribe(ctx, uint64(accountId), in.ProductID,
+		strings.ToLower(in.Kind.String()), threshold, strings.ToLower(channel.String()), target)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toProductAlert(subscription), nil
+}
+
+func (resolver *mutationResolver) UnsubscribeFromProductAlert(ctx context.Context, id int) (*bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	err = resolver.server.alertClient.Unsubscribe(ctx, uint64(accountId), uint64(id))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	success := true
+	return &success, nil
+}
+
+// SetCheckoutReminders opts the caller in to or out of abandoned checkout reminder emails.
+func (resolver *mutationResolver) SetCheckoutReminders(ctx context.Context, enabled bool) (*bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	err = resolver.server.alertClient.SetCheckoutReminders(ctx, uint64(accountId), enabled)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	success := true
+	return &success, nil
+}
+
+// CreateShipment records a parcel sent for an order. Only admins ship orders.
+func (resolver *mutationResolver) CreateShipment(ctx context.Context, in generated.ShipmentInput) (*generated.Shipment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil || !isAdmin(accountId) {
+		return nil, errors.New("unauthorized")
+	}
+
+	var lines []shipping.ShipmentLine
+	for _, line := range in.Lines {
+		if line.Quantity <= 0 {
+			return nil, ErrInvalidParameter
+		}
+		lines = append(lines, shipping.ShipmentLine{ProductID: line.ID, Quantity: line.Quantity})
+	}
+	carrier := ""
+	if in.Carrier != nil {
+		carrier = *in.Carrier
+	}
+
+	shipment, err := resolver.server.shippingClient.CreateShipment(ctx, uint64(in.OrderID), carrier, in.TrackingNumber, lines)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toShipment(shipment), nil
+}
+
+func (resolver *mutationResolver) MarkShipmentDelivered(ctx context.Context, id int) (*generated.Shipment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil || !isAdmin(accountId) {
+		return nil, errors.New("unauthorized")
+	}
+
+	shipment, err := resolver.server.shippingClient.MarkDelivered(ctx, uint64(id))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toShipment(shipment), nil
+}
+
+// RequestReturn asks to send back products of one of the caller's delivered orders.
+func (resolver *mutationResolver) RequestReturn(ctx context.Context, in generated.ReturnInput) (*generated.OrderReturn, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	var lines []*models.ReturnLine
+	for _, line := range in.Lines {
+		if line.Quantity <= 0 {
+			return nil, ErrInvalidParameter
+		}
+		lines = append(lines, &models.ReturnLine{ProductID: line.ID, Quantity: line.Quantity})
+	}
+
+	toStoreCredit := in.RefundToStoreCredit != nil && *in.RefundToStoreCredit
+	ret, err := resolver.server.orderClient.RequestReturn(ctx, uint64(in.OrderID), uint64(accountId), lines, in.Reason, toStoreCredit)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toOrderReturn(ret, nil)
+}
+
+func (resolver *mutationResolver) ApproveReturn(ctx context.Context, id int, note *string) (*generated.OrderReturn, error) {
+	return resolver.decideReturn(ctx, id, true, note)
+}
+
+func (resolver *mutationResolver) RejectReturn(ctx context.Context, id int, note *string) (*generated.OrderReturn, error) {
+	return resolver.decideReturn(ctx, id, false, note)
+}
+
+func (resolver *mutationResolver) decideReturn(ctx context.Context, id int, approve bool, note *string) (*generated.OrderReturn, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+	actor, err := resolver.server.returnActor(ctx, id, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	decisionNote := ""
+	if note != nil {
+		decisionNote = *note
+	}
+	ret, err := resolver.server.orderClient.DecideReturn(ctx, uint(id), approve, actor, decisionNote)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toOrderReturn(ret, nil)
+}
+
+func (resolver *mutationResolver) ApproveRiskReview(ctx context.Context, orderID int, note *string) (*generated.RiskReview, error) {
+	return resolver.decideRiskReview(ctx, orderID, true, note)
+}
+
+func (resolver *mutationResolver) RejectRiskReview(ctx context.Context, orderID int, note *string) (*generated.RiskReview, error) {
+	return resolver.decideRiskReview(ctx, orderID, false, note)
+}
+
+// decideRiskReview approves or rejects an order held for review by risk screening. Approving
+// opens the order's checkout session, rejecting cancels the order.
+func (resolver *mutationResolver) decideRiskReview(ctx context.Context, orderID int, approve bool, note *string) (*generated.RiskReview, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil || !isAdmin(accountId) {
+		return nil, errors.New("unauthorized")
+	}
+
+	decisionNote := ""
+	if note != nil {
+		decisionNote = *note
+	}
+	review, checkoutUrl, err := resolver.server.orderClient.DecideRiskReview(ctx, uint64(orderID), approve,
+		fmt.Sprintf("account:%d", accountId), decisionNote)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	result, err := resolver.server.toRiskReview(review, nil)
+	if err != nil {
+		return nil, err
+	}
+	if checkoutUrl != "" {
+		result.CheckoutURL = &checkoutUrl
+	}
+	return result, nil
+}
+
+// ReceiveReturn records that the returned products arrived back, which restocks and
+// refunds them.
+func (resolver *mutationResolver) ReceiveReturn(ctx context.Context, id int) (*generated.OrderReturn, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+	actor, err := resolver.server.returnActor(ctx, id, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := resolver.server.orderClient.ReceiveReturn(ctx, uint(id), actor)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toOrderReturn(ret, nil)
+}
+
+// UpdateSellerOrderStatus moves one of the caller's sub-orders along as they fulfil it.
+// Admins can move any seller's sub-orders.
+func (resolver *mutationResolver) UpdateSellerOrderStatus(ctx context.Context, id int, status generated.OrderStatus) (*generated.SubOrder, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+	sellerId := uint64(accountId)
+	if isAdmin(accountId) {
+		sellerId = 0
+	}
+
+	sub, err := resolver.server.orderClient.UpdateSubOrderStatus(ctx, uint(id), sellerId, models.OrderStatus(strings.ToLower(status.String())), fmt.Sprintf("account:%d", accountId))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toSubOrder(sub, nil)
+}
+
+// Reorder adds the lines of one of the caller's past orders back to their cart, at today's
+// prices. Lines no longer sold or out of stock are returned as unavailable instead.
+func (resolver *mutationResolver) Reorder(ctx context.Context, orderID int) (*generated.Reorder, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+	owner := cart.Owner{AccountID: uint64(accountId)}
+
+	lines, err := resolver.server.orderClient.Reorder(ctx, uint64(orderID), uint64(accountId))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	result := &generated.Reorder{Unavailable: []*generated.ReorderLine{}}
+	var c *cart.Cart
+	for _, line := range lines {
+		if line.Available {
+			added, err := resolver.server.cartClient.AddItem(ctx, owner, line.ProductID, int(line.Quantity))
+			if err == nil {
+				c = added
+				continue
+			}
+			// The stock may have run out since the line was priced
+			log.Println(err)
+			line.Available = false
+		}
+		result.Unavailable = append(result.Unavailable, toReorderLine(line))
+	}
+	if c == nil {
+		c, err = resolver.server.cartClient.GetCart(ctx, owner)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	result.Cart, err = resolver.server.toCart(ctx, c, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CreateRecurringOrder schedules the products to be ordered for the caller every week or
+// month, each run opening a checkout session that returns to the given redirect URL.
+func (resolver *mutationResolver) CreateRecurringOrder(ctx context.Context, in generated.RecurringOrderInput) (*generated.RecurringOrder, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	recurring := &models.RecurringOrder{
+		AccountID:       uint64(accountId),
+		Currency:        currencyOrDefault(in.Currency),
+		Interval:        models.RecurringInterval(strings.ToLower(in.Interval.String())),
+		ShippingAddress: shippingAddress(in.ShippingAddress),
+		ShippingMethod:  shippingMethod(in.ShippingMethod),
+		RedirectURL:     in.RedirectURL,
+	}
+	for _, p := range in.Products {
+		if p.Quantity <= 0 {
+			return nil, ErrInvalidParameter
+		}
+		recurring.Items = append(recurring.Items, &models.RecurringItem{ProductID: p.ID, Quantity: uint32(p.Quantity)})
+	}
+	if in.StartAt != nil {
+		recurring.NextRunAt = in.StartAt.UTC()
+	}
+
+	recurring, err = resolver.server.orderClient.CreateRecurringOrder(ctx, recurring)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toRecurringOrder(recurring), nil
+}
+
+func (resolver *mutationResolver) PauseRecurringOrder(ctx context.Context, id int) (*generated.RecurringOrder, error) {
+	return resolver.server.updateRecurringOrder(ctx, id, models.SchedulePaused)
+}
+
+func (resolver *mutationResolver) ResumeRecurringOrder(ctx context.Context, id int) (*generated.RecurringOrder, error) {
+	return resolver.server.updateRecurringOrder(ctx, id, models.ScheduleActive)
+}
+
+func (resolver *mutationResolver) CancelRecurringOrder(ctx context.Context, id int) (*generated.RecurringOrder, error) {
+	return resolver.server.updateRecurringOrder(ctx, id, models.ScheduleCancelled)
+}
@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/rasadov/EcommerceAPI/graphql/config"
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// isAdmin reports whether the account may see and manage data of every account.
+func isAdmin(accountId int) bool {
+	return config.AdminAccountIds[uint64(accountId)]
+}
+
+func toOrderStatus(status order.OrderStatus) generated.OrderStatus {
+	return generated.OrderStatus(strings.ToUpper(status.String()))
+}
+
+func toOrderStatusHistory(history []*order.StatusTransition) []*generated.OrderStatusTransition {
+	transitions := []*generated.OrderStatusTransition{}
+	for _, t := range history {
+		transition := &generated.OrderStatusTransition{
+			To:        toOrderStatus(t.To),
+			Actor:     t.Actor,
+			Reason:    t.Reason,
+			CreatedAt: t.CreatedAt,
+		}
+		// The first entry records the order being placed and has no previous status
+		if t.From != "" {
+			from := toOrderStatus(t.From)
+			transition.From = &from
+		}
+		transitions = append(transitions, transition)
+	}
+	return transitions
+}
+
+// toOrderFilter builds the order filter requested by the caller. Only admins may list
+// orders of other accounts; everyone else is limited to their own.
+func toOrderFilter(in *generated.OrderFilterInput, accountId int) (order.OrderFilter, error) {
+	filter := order.OrderFilter{AccountID: uint64(accountId)}
+	if in == nil {
+		return filter, nil
+	}
+
+	if in.AccountID != nil || (in.AllAccounts != nil && *in.AllAccounts) {
+		if !isAdmin(accountId) {
+			return order.OrderFilter{}, errors.New("unauthorized")
+		}
+		filter.AccountID = 0
+		if in.AccountID != nil {
+			filter.AccountID = uint64(*in.AccountID)
+		}
+	}
+
+	for _, s := range in.Statuses {
+		filter.Statuses = append(filter.Statuses, order.OrderStatus(strings.ToLower(s.String())))
+	}
+	if in.CreatedAfter != nil {
+		filter.CreatedAfter = *in.CreatedAfter
+	}
+	if in.CreatedBefore != nil {
+		filter.CreatedBefore = *in.CreatedBefore
+	}
+
+	currency := money.Normalize(currencyOrDefault(in.TotalCurrency))
+	if in.MinTotal != nil {
+		minTotal := money.FromMajor(*in.MinTotal, currency)
+		filter.MinTotal = &minTotal
+	}
+	if in.MaxTotal != nil {
+		maxTotal := money.FromMajor(*in.MaxTotal, currency)
+		filter.MaxTotal = &maxTotal
+	}
+	return filter, nil
+}
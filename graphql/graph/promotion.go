@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// couponCode returns the requested coupon code in the form codes are stored in, or "".
+func couponCode(code *string) string {
+	if code == nil {
+		return ""
+	}
+	return order.NormalizeCode(*code)
+}
+
+// fromPromotionInput builds a promotion from its input. Promotions are active unless the
+// input says otherwise.
+func fromPromotionInput(in generated.PromotionInput) *order.Promotion {
+	promotion := &order.Promotion{
+		Code:       couponCode(&in.Code),
+		Kind:       order.PromotionKind(strings.ToLower(in.Kind.String())),
+		ProductIDs: in.ProductIds,
+		Categories: in.Categories,
+		StartsAt:   in.StartsAt,
+		EndsAt:     in.EndsAt,
+		Active:     in.Active == nil || *in.Active,
+	}
+	if in.Description != nil {
+		promotion.Description = *in.Description
+	}
+	if in.PercentOff != nil {
+		promotion.PercentOff = *in.PercentOff
+	}
+	if in.BuyQuantity != nil {
+		promotion.BuyQuantity = *in.BuyQuantity
+	}
+	if in.GetQuantity != nil {
+		promotion.GetQuantity = *in.GetQuantity
+	}
+	if in.UsageLimitPerCustomer != nil {
+		promotion.UsageLimitPerCustomer = *in.UsageLimitPerCustomer
+	}
+
+	currency := money.Normalize(currencyOrDefault(in.Currency))
+	if in.AmountOff != nil {
+		promotion.AmountOff = money.FromMajor(*in.AmountOff, currency)
+	}
+	if in.MinSubtotal != nil {
+		promotion.MinSubtotal = money.FromMajor(*in.MinSubtotal, currency)
+	}
+	return promotion
+}
+
+func toPromotion(p *order.Promotion) *generated.Promotion {
+	promotion := &generated.Promotion{
+		ID:          int(p.ID),
+		Code:        p.Code,
+		Description: p.Description,
+		Kind:        generated.PromotionKind(strings.ToUpper(string(p.Kind))),
+		ProductIds:  p.ProductIDs,
+		Categories:  p.Categories,
+		StartsAt:    p.StartsAt,
+		EndsAt:      p.EndsAt,
+		Active:      p.Active,
+		CreatedAt:   p.CreatedAt,
+	}
+	if promotion.ProductIds == nil {
+		promotion.ProductIds = []string{}
+	}
+	if promotion.Categories == nil {
+		promotion.Categories = []string{}
+	}
+
+	switch p.Kind {
+	case order.PromotionPercentage:
+		promotion.PercentOff = &p.PercentOff
+	case order.PromotionFixed:
+		promotion.AmountOff = &p.AmountOff
+	case order.PromotionBuyXGetY:
+		promotion.BuyQuantity = &p.BuyQuantity
+		promotion.GetQuantity = &p.GetQuantity
+	}
+	if !p.MinSubtotal.IsZero() {
+		promotion.MinSubtotal = &p.MinSubtotal
+	}
+	if p.UsageLimitPerCustomer > 0 {
+		promotion.UsageLimitPerCustomer = &p.UsageLimitPerCustomer
+	}
+	return promotion
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/rasadov/EcommerceAPI/graphql/generated"
 	"github.com/rasadov/EcommerceAPI/graphql/models"
 	"github.com/rasadov/EcommerceAPI/graphql/utils"
+	order "github.com/rasadov/EcommerceAPI/order/models"
 	"github.com/rasadov/EcommerceAPI/pkg/auth"
+	recommender "github.com/rasadov/EcommerceAPI/recommender/generated/pb"
 )
 
 type queryResolver struct {
@@ -66,6 +69,8 @@ func (resolver *queryResolver) Product(
 	query, id *string,
 	viewedProductsIds []*string,
 	byAccountId *bool,
+	recommended *bool,
+	currency *string,
 ) ([]*generated.Product, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
@@ -77,12 +82,12 @@ func (resolver *queryResolver) Product(
 			log.Println(err)
 			return nil, err
 		}
-		return []*generated.Product{{
-			ID:          res.ID,
-			Name:        res.Name,
-			Description: res.Description,
-			Price:       res.Price,
-		}}, nil
+		product, err := resolver.server.toProduct(res, currency)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		return []*generated.Product{product}, nil
 	}
 	skip, take := uint64(0), uint64(0)
 	if pagination != nil {
@@ -100,22 +105,11 @@ func (resolver *queryResolver) Product(
 			log.Println(err)
 			return nil, err
 		}
-		productList := res.GetRecommendedProducts()
-		var products []*generated.Product
-		for _, product := range productList {
-			products = append(products,
-				&generated.Product{
-					ID:          product.Id,
-					Name:        product.Name,
-					Description: product.Description,
-					Price:       product.Price,
-				},
-			)
-		}
-		return products, nil
+		return resolver.recommendedProducts(ctx, res.GetRecommendedProducts(), currency)
 	}
 
-	if byAccountId != nil && *byAccountId {
+	// Get recommendations for the current account; byAccountId is the name older clients use
+	if (recommended != nil && *recommended) || (byAccountId != nil && *byAccountId) {
 		accountId := auth.GetUserId(ctx, true)
 		if accountId == "" {
 			return nil, errors.New("unauthorized")
@@ -127,19 +121,7 @@ func (resolver *queryResolver) Product(
 			log.Println(err)
 			return nil, err
 		}
-		productList := res.GetRecommendedProducts()
-		var products []*generated.Product
-		for _, product := range productList {
-			products = append(products,
-				&generated.Product{
-					ID:          product.Id,
-					Name:        product.Name,
-					Description: product.Description,
-					Price:       product.Price,
-				},
-			)
-		}
-		return products, nil
+		return resolver.recommendedProducts(ctx, res.GetRecommendedProducts(), currency)
 	}
 
 	q := ""
@@ -152,17 +134,368 @@ func (resolver *queryResolver) Product(
 		return nil, err
 	}
 
-	var products []*generated.Product
-	for _, product := range productList {
-		products = append(products,
-			&generated.Product{
-				ID:          product.ID,
-				Name:        product.Name,
-				Description: product.Description,
-				Price:       product.Price,
-			},
-		)
+	return resolver.server.toProducts(productList, currency)
+}
+
+// recommendedProducts loads recommended products from the catalogue, since the recommender's
+// replica does not know which currency a product is priced in.
+func (resolver *queryResolver) recommendedProducts(ctx context.Context, replicas []*recommender.ProductReplica, currency *string) ([]*generated.Product, error) {
+	if len(replicas) == 0 {
+		return []*generated.Product{}, nil
+	}
+	productIds := make([]string, len(replicas))
+	for i, replica := range replicas {
+		productIds[i] = replica.Id
+	}
+	productList, err := resolver.server.productClient.GetProducts(ctx, 0, 0, productIds, "")
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toProducts(productList, currency)
+}
+
+func (resolver *queryResolver) Seller(ctx context.Context, id *int) (*models.Seller, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var sellerId uint64
+	if id != nil {
+		sellerId = uint64(*id)
+	} else {
+		accountId, err := auth.GetUserIdInt(ctx, true)
+		if err != nil {
+			return nil, errors.New("unauthorized")
+		}
+		sellerId = uint64(accountId)
+	}
+
+	res, err := resolver.server.accountClient.GetAccount(ctx, sellerId)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return &models.Seller{
+		ID:   res.ID,
+		Name: res.Name,
+	}, nil
+}
+
+func (resolver *queryResolver) SharedWishlist(ctx context.Context, shareToken string) (*models.Wishlist, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	w, err := resolver.server.wishlistClient.GetSharedWishlist(ctx, shareToken)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toWishlist(w), nil
+}
+
+// Order returns one of the caller's orders. Admins can look up any order.
+func (resolver *queryResolver) Order(ctx context.Context, id int, currency *string) (*generated.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
 	}
 
-	return products, nil
+	owner := uint64(accountId)
+	if isAdmin(accountId) {
+		owner = 0
+	}
+	o, err := resolver.server.orderClient.GetOrder(ctx, uint64(id), owner)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toOrder(o, currency)
+}
+
+// Orders lists the caller's orders, newest first. Admins can list the orders of another
+// account or of every account.
+func (resolver *queryResolver) Orders(
+	ctx context.Context,
+	filter *generated.OrderFilterInput,
+	after *string,
+	first *int,
+	currency *string,
+) (*generated.OrderConnection, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	orderFilter, err := toOrderFilter(filter, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, pageSize := "", 0
+	if after != nil {
+		cursor = *after
+	}
+	if first != nil {
+		if *first < 0 {
+			return nil, errors.New("first must not be negative")
+		}
+		pageSize = *first
+	}
+
+	page, err := resolver.server.orderClient.ListOrders(ctx, orderFilter, cursor, pageSize)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	connection := &generated.OrderConnection{
+		Orders:   []*generated.Order{},
+		PageInfo: &generated.PageInfo{HasNextPage: page.HasNextPage},
+	}
+	if page.EndCursor != "" {
+		connection.PageInfo.EndCursor = &page.EndCursor
+	}
+	for _, o := range page.Orders {
+		listed, err := resolver.server.toOrder(o, currency)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		connection.Orders = append(connection.Orders, listed)
+	}
+	return connection, nil
+}
+
+// RiskReviews lists the orders screened into review, oldest first, for admins to decide on.
+func (resolver *queryResolver) RiskReviews(
+	ctx context.Context,
+	status *generated.RiskReviewStatus,
+	after *string,
+	first *int,
+	currency *string,
+) (*generated.RiskReviewConnection, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil || !isAdmin(accountId) {
+		return nil, errors.New("unauthorized")
+	}
+
+	reviewStatus := order.ReviewPending
+	if status != nil {
+		reviewStatus = order.ReviewStatus(strings.ToLower(status.String()))
+	}
+	cursor, pageSize := "", 0
+	if after != nil {
+		cursor = *after
+	}
+	if first != nil {
+		if *first < 0 {
+			return nil, errors.New("first must not be negative")
+		}
+		pageSize = *first
+	}
+
+	page, err := resolver.server.orderClient.ListRiskReviews(ctx, reviewStatus, cursor, pageSize)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	connection := &generated.RiskReviewConnection{
+		Reviews:  []*generated.RiskReview{},
+		PageInfo: &generated.PageInfo{HasNextPage: page.HasNextPage},
+	}
+	if page.EndCursor != "" {
+		connection.PageInfo.EndCursor = &page.EndCursor
+	}
+	for _, review := range page.Reviews {
+		listed, err := resolver.server.toRiskReview(review, currency)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		connection.Reviews = append(connection.Reviews, listed)
+	}
+	return connection, nil
+}
+
+// SellerOrders lists the caller's sub-orders as a seller. Admins can list another
+// seller's by passing sellerId.
+func (resolver *queryResolver) SellerOrders(
+	ctx context.Context,
+	statuses []generated.OrderStatus,
+	sellerID *int,
+	after *string,
+	first *int,
+	currency *string,
+) (*generated.SellerOrderConnection, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+	sellerId := uint64(accountId)
+	if sellerID != nil && *sellerID != accountId {
+		if !isAdmin(accountId) {
+			return nil, errors.New("unauthorized")
+		}
+		sellerId = uint64(*sellerID)
+	}
+
+	var orderStatuses []order.OrderStatus
+	for _, s := range statuses {
+		orderStatuses = append(orderStatuses, order.OrderStatus(strings.ToLower(s.String())))
+	}
+	cursor, pageSize := "", 0
+	if after != nil {
+		cursor = *after
+	}
+	if first != nil {
+		if *first < 0 {
+			return nil, errors.New("first must not be negative")
+		}
+		pageSize = *first
+	}
+
+	page, err := resolver.server.orderClient.ListSellerOrders(ctx, sellerId, orderStatuses, cursor, pageSize)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	connection := &generated.SellerOrderConnection{
+		Orders:   []*generated.SellerOrder{},
+		PageInfo: &generated.PageInfo{HasNextPage: page.HasNextPage},
+	}
+	if page.EndCursor != "" {
+		connection.PageInfo.EndCursor = &page.EndCursor
+	}
+	for _, o := range page.Orders {
+		listed, err := resolver.server.toSellerOrder(o, currency)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		connection.Orders = append(connection.Orders, listed)
+	}
+	return connection, nil
+}
+
+// Cart returns the caller's cart, or the anonymous session's before sign-in.
+func (resolver *queryResolver) Cart(ctx context.Context, currency *string) (*generated.Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	owner, err := cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := resolver.server.cartClient.GetCart(ctx, owner)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return resolver.server.toCart(ctx, c, currency)
+}
+
+func (resolver *queryResolver) Promotions(ctx context.Context, includeInactive *bool) ([]*generated.Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil || !isAdmin(accountId) {
+		return nil, errors.New("unauthorized")
+	}
+
+	promotions, err := resolver.server.orderClient.ListPromotions(ctx, includeInactive != nil && *includeInactive)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	result := []*generated.Promotion{}
+	for _, promotion := range promotions {
+		result = append(result, toPromotion(promotion))
+	}
+	return result, nil
+}
+
+// RecurringOrders lists the caller's recurring orders, oldest first.
+func (resolver *queryResolver) RecurringOrders(ctx context.Context) ([]*generated.RecurringOrder, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	recurringOrders, err := resolver.server.orderClient.ListRecurringOrders(ctx, uint64(accountId))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	result := []*generated.RecurringOrder{}
+	for _, recurring := range recurringOrders {
+		result = append(result, toRecurringOrder(recurring))
+	}
+	return result, nil
+}
+
+// ShippingRates prices shipping products to country with every method delivering there.
+// Without products the caller's cart is priced.
+func (resolver *queryResolver) ShippingRates(
+	ctx context.Context,
+	country string,
+	products []*generated.OrderedProductInput,
+	currency *string,
+) ([]*generated.ShippingRate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	quantities := map[string]int{}
+	for _, p := range products {
+		if p.Quantity <= 0 {
+			return nil, ErrInvalidParameter
+		}
+		quantities[p.ID] += p.Quantity
+	}
+	if len(products) == 0 {
+		owner, err := cartOwner(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c, err := resolver.server.cartClient.GetCart(ctx, owner)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		for _, item := range c.Items {
+			quantities[item.ProductID] += item.Quantity
+		}
+	}
+
+	quotes, err := resolver.server.shippingClient.QuoteRates(ctx, country, currencyOrDefault(currency), quantities)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	rates := []*generated.ShippingRate{}
+	for _, quote := range quotes {
+		rates = append(rates, toShippingRate(quote))
+	}
+	return rates, nil
 }
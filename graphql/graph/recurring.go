@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/auth"
+)
+
+func toReorderLine(line *order.ReorderLine) *generated.ReorderLine {
+	return &generated.ReorderLine{
+		ProductID:         line.ProductID,
+		Name:              line.Name,
+		Quantity:          int(line.Quantity),
+		UnitPrice:         &line.UnitPrice,
+		PreviousUnitPrice: &line.PreviousUnitPrice,
+		Available:         line.Available,
+	}
+}
+
+func toRecurringOrder(r *order.RecurringOrder) *generated.RecurringOrder {
+	result := &generated.RecurringOrder{
+		ID:        int(r.ID),
+		Interval:  generated.RecurringInterval(strings.ToUpper(r.Interval.String())),
+		Status:    generated.RecurringOrderStatus(strings.ToUpper(r.Status.String())),
+		Products:  []*generated.RecurringOrderProduct{},
+		Currency:  r.Currency,
+		NextRunAt: r.NextRunAt,
+		CreatedAt: r.CreatedAt,
+	}
+	for _, item := range r.Items {
+		result.Products = append(result.Products, &generated.RecurringOrderProduct{
+			ProductID: item.ProductID,
+			Quantity:  int(item.Quantity),
+		})
+	}
+	if r.ShippingMethod != "" {
+		result.ShippingMethod = &r.ShippingMethod
+	}
+	if r.LastOrderID != 0 {
+		lastOrderId := int(r.LastOrderID)
+		result.LastOrderID = &lastOrderId
+	}
+	if r.LastCheckoutURL != "" {
+		result.CheckoutURL = &r.LastCheckoutURL
+	}
+	if r.LastError != "" {
+		result.LastError = &r.LastError
+	}
+	return result
+}
+
+// updateRecurringOrder moves one of the caller's recurring orders to status.
+func (server *Server) updateRecurringOrder(ctx context.Context, id int, status order.ScheduleStatus) (*generated.RecurringOrder, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	accountId, err := auth.GetUserIdInt(ctx, true)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	recurring, err := server.orderClient.UpdateRecurringOrderStatus(ctx, uint(id), uint64(accountId), status)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toRecurringOrder(recurring), nil
+}
@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+)
+
+func toReturnStatus(status order.ReturnStatus) generated.ReturnStatus {
+	return generated.ReturnStatus(strings.ToUpper(status.String()))
+}
+
+// toOrderReturn returns the return with its amounts converted to currency, or left in the
+// order currency when currency is nil.
+func (server *Server) toOrderReturn(r *order.Return, currency *string) (*generated.OrderReturn, error) {
+	refundAmount, err := server.convert(r.RefundAmount, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &generated.OrderReturn{
+		ID:                  int(r.ID),
+		Status:              toReturnStatus(r.Status),
+		Reason:              r.Reason,
+		Lines:               []*generated.ReturnLine{},
+		RefundAmount:        &refundAmount,
+		History:             []*generated.ReturnTransition{},
+		CreatedAt:           r.CreatedAt,
+		RefundToStoreCredit: r.RefundToStoreCredit,
+	}
+	if r.RefundID != "" {
+		result.RefundID = &r.RefundID
+	}
+
+	for _, line := range r.Lines {
+		amount, err := server.convert(line.RefundAmount, currency)
+		if err != nil {
+			return nil, err
+		}
+		result.Lines = append(result.Lines, &generated.ReturnLine{
+			ProductID:    line.ProductID,
+			Quantity:     line.Quantity,
+			RefundAmount: &amount,
+		})
+	}
+	for _, t := range r.History {
+		transition := &generated.ReturnTransition{
+			To:        toReturnStatus(t.To),
+			Actor:     t.Actor,
+			Note:      t.Note,
+			CreatedAt: t.CreatedAt,
+		}
+		// The first entry records the return being requested and has no previous status
+		if t.From != "" {
+			from := toReturnStatus(t.From)
+			transition.From = &from
+		}
+		result.History = append(result.History, transition)
+	}
+	return result, nil
+}
+
+// returnActor returns the actor recorded for accountId deciding or receiving the return.
+// Only admins and the seller of every returned product may do so.
+func (server *Server) returnActor(ctx context.Context, returnId int, accountId int) (string, error) {
+	actor := fmt.Sprintf("account:%d", accountId)
+	if isAdmin(accountId) {
+		return actor, nil
+	}
+
+	r, err := server.orderClient.GetReturn(ctx, uint(returnId))
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+	productIds := make([]string, 0, len(r.Lines))
+	for _, line := range r.Lines {
+		productIds = append(productIds, line.ProductID)
+	}
+	products, err := server.productClient.GetProducts(ctx, 0, 0, productIds, "")
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+
+	sold := map[string]bool{}
+	for _, p := range products {
+		if p.AccountID == accountId {
+			sold[p.ID] = true
+		}
+	}
+	for _, productId := range productIds {
+		if !sold[productId] {
+			return "", errors.New("unauthorized")
+		}
+	}
+	return actor, nil
+}
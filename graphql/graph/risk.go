@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/contextkeys"
+)
+
+// orderOrigin returns where the caller is placing an order from, for risk screening.
+func orderOrigin(ctx context.Context, billingCountry *string) order.OrderOrigin {
+	origin := order.OrderOrigin{}
+	origin.ClientIP, _ = ctx.Value(contextkeys.ClientIPKey).(string)
+	if billingCountry != nil {
+		origin.BillingCountry = strings.TrimSpace(*billingCountry)
+	}
+	return origin
+}
+
+func toRiskReviewStatus(status order.ReviewStatus) *generated.RiskReviewStatus {
+	if status == order.ReviewNone {
+		return nil
+	}
+	converted := generated.RiskReviewStatus(strings.ToUpper(status.String()))
+	return &converted
+}
+
+// toRiskReview returns the review with its order's amounts converted to currency, or left
+// in the order currency when currency is nil.
+func (server *Server) toRiskReview(review *order.RiskReview, currency *string) (*generated.RiskReview, error) {
+	o, err := server.toOrder(review.Order, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &generated.RiskReview{
+		Order:      o,
+		Score:      review.Score,
+		Decision:   generated.RiskDecision(strings.ToUpper(review.Decision.String())),
+		Findings:   []*generated.RiskFinding{},
+		Status:     generated.RiskReviewStatus(strings.ToUpper(review.ReviewStatus.String())),
+		ReviewedAt: review.ReviewedAt,
+		CreatedAt:  review.CreatedAt,
+	}
+	if review.ClientIP != "" {
+		result.ClientIP = &review.ClientIP
+	}
+	if review.ReviewedBy != "" {
+		result.ReviewedBy = &review.ReviewedBy
+	}
+	if review.ReviewNote != "" {
+		result.Note = &review.ReviewNote
+	}
+	for _, finding := range review.Findings {
+		result.Findings = append(result.Findings, &generated.RiskFinding{
+			Rule:   finding.Rule,
+			Score:  finding.Score,
+			Reason: finding.Reason,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,341 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	"github.com/rasadov/EcommerceAPI/graphql/models"
+	"github.com/rasadov/EcommerceAPI/graphql/utils"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/auth"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	product "github.com/rasadov/EcommerceAPI/product/models"
+)
+
+type sellerResolver struct {
+	server *Server
+}
+
+func (resolver *sellerResolver) ID(ctx context.Context, obj *models.Seller) (int, error) {
+	return int(obj.ID), nil
+}
+
+func (resolver *sellerResolver) Products(ctx context.Context, obj *models.Seller, pagination *generated.PaginationInput, currency *string) ([]*generated.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	skip, take := uint64(0), uint64(0)
+	if pagination != nil {
+		skip, take = utils.Bounds(pagination)
+	}
+	productList, err := resolver.server.productClient.ListProductsBySeller(ctx, int64(obj.ID), skip, take)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return resolver.server.toProducts(productList, currency)
+}
+
+// Sales adds up the seller's products on paid orders in a single currency. Units sold on
+// lines without a recorded price are counted at the product's current price.
+func (resolver *sellerResolver) Sales(ctx context.Context, obj *models.Seller, currency *string) (*generated.SellerSales, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	// Totals are only meaningful in a single currency
+	salesCurrency := money.DefaultCurrency
+	if currency != nil && *currency != "" {
+		salesCurrency = *currency
+	}
+	productsById, err := resolver.sellerProducts(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	revenue := money.Zero(salesCurrency)
+	sales := &generated.SellerSales{Currency: revenue.Currency, Revenue: &revenue}
+	if len(productsById) == 0 {
+		return sales, nil
+	}
+
+	summary, err := resolver.server.orderClient.GetSalesForProducts(ctx, productIds(productsById))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	sales.OrderCount = summary.OrderCount
+	for _, productSales := range summary.Products {
+		productRevenue, err := resolver.server.convert(money.New(productSales.Revenue, productSales.Currency), &salesCurrency)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		price, err := resolver.server.convert(productsById[productSales.ProductID].Price, &salesCurrency)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		productRevenue, err = productRevenue.Add(price.Mul(int64(productSales.UnpricedUnits)))
+		if err != nil {
+			return nil, err
+		}
+		revenue, err = revenue.Add(productRevenue)
+		if err != nil {
+			return nil, err
+		}
+		sales.UnitsSold += productSales.UnitsSold
+	}
+	sales.TotalRevenue = revenue.Major()
+	return sales, nil
+}
+
+// OrderLines returns the paid order lines of the seller's products, newest first.
+func (resolver *sellerResolver) OrderLines(ctx context.Context, obj *models.Seller, pagination *generated.PaginationInput, currency *string) ([]*generated.SellerOrderLine, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	productsById, err := resolver.sellerProducts(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(productsById) == 0 {
+		return []*generated.SellerOrderLine{}, nil
+	}
+
+	skip, take := uint64(0), uint64(0)
+	if pagination != nil {
+		skip, take = utils.Bounds(pagination)
+	}
+	orderLines, err := resolver.server.orderClient.GetOrderLinesForProducts(ctx, productIds(productsById), skip, take)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	lines := make([]*generated.SellerOrderLine, 0, len(orderLines))
+	for _, line := range orderLines {
+		p := productsById[line.ProductID]
+		// Lines record what the product sold for; older lines fall back to today's price
+		soldFor, name := line.UnitPrice, line.Name
+		if soldFor.Currency == "" {
+			soldFor, name = p.Price, p.Name
+		}
+		price, err := resolver.server.convert(soldFor, currency)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		lineTotal := price.Mul(int64(line.Quantity))
+		lines = append(lines, &generated.SellerOrderLine{
+			OrderID:     int(line.OrderID),
+			CreatedAt:   line.CreatedAt,
+			ProductID:   line.ProductID,
+			ProductName: name,
+			Price:       price.Major(),
+			UnitPrice:   &price,
+			Quantity:    line.Quantity,
+			Total:       lineTotal.Major(),
+			LineTotal:   &lineTotal,
+			Currency:    price.Currency,
+		})
+	}
+	return lines, nil
+}
+
+// Balances returns what the marketplace owes the seller and has paid them. Only the seller
+// and admins can see them.
+func (resolver *sellerResolver) Balances(ctx context.Context, obj *models.Seller) ([]*generated.SellerBalance, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := authorizeSellerAccounting(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := resolver.server.paymentClient.GetSellerBalances(ctx, obj.ID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	result := []*generated.SellerBalance{}
+	for _, balance := range balances {
+		owed := money.New(balance.Owed, balance.Currency)
+		paidOut := money.New(balance.PaidOut, balance.Currency)
+		result = append(result, &generated.SellerBalance{Owed: &owed, PaidOut: &paidOut})
+	}
+	return result, nil
+}
+
+// Payouts returns the seller's payout statements, newest first. Only the seller and admins
+// can see them.
+func (resolver *sellerResolver) Payouts(ctx context.Context, obj *models.Seller, after *string, first *int) (*generated.PayoutConnection, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := authorizeSellerAccounting(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, pageSize := "", 0
+	if after != nil {
+		cursor = *after
+	}
+	if first != nil {
+		if *first < 0 {
+			return nil, errors.New("first must not be negative")
+		}
+		pageSize = *first
+	}
+
+	page, err := resolver.server.paymentClient.ListPayouts(ctx, obj.ID, cursor, pageSize)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	connection := &generated.PayoutConnection{
+		Payouts:  []*generated.Payout{},
+		PageInfo: &generated.PageInfo{HasNextPage: page.HasNextPage},
+	}
+	if page.EndCursor != "" {
+		connection.PageInfo.EndCursor = &page.EndCursor
+	}
+	for _, payout := range page.Payouts {
+		sales := money.New(payout.Sales, payout.Currency)
+		commission := money.New(payout.Commission, payout.Currency)
+		refunds := money.New(payout.Refunds, payout.Currency)
+		amount := money.New(payout.Amount, payout.Currency)
+		connection.Payouts = append(connection.Payouts, &generated.Payout{
+			ID:          int(payout.ID),
+			Sales:       &sales,
+			Commission:  &commission,
+			Refunds:     &refunds,
+			Amount:      &amount,
+			PeriodStart: payout.PeriodStart,
+			PeriodEnd:   payout.PeriodEnd,
+			CreatedAt:   payout.CreatedAt,
+		})
+	}
+	return connection, nil
+}
+
+// authorizeSellerAccounting lets the seller themselves and admins see what the seller is owed.
+func authorizeSellerAccounting(ctx context.Context, obj *models.Seller) error {
+	accountId, err := auth.GetUserIdInt(ctx, false)
+	if err != nil || (uint64(accountId) != obj.ID && !isAdmin(accountId)) {
+		return errors.New("unauthorized")
+	}
+	return nil
+}
+
+// sellerProducts returns every product of the seller by ID. Sales data is only visible to
+// the seller themselves.
+func (resolver *sellerResolver) sellerProducts(ctx context.Context, obj *models.Seller) (map[string]product.Product, error) {
+	accountId, err := auth.GetUserIdInt(ctx, false)
+	if err != nil || uint64(accountId) != obj.ID {
+		return nil, errors.New("unauthorized")
+	}
+
+	productsById := make(map[string]product.Product)
+	for skip := uint64(0); ; skip += 100 {
+		productList, err := resolver.server.productClient.ListProductsBySeller(ctx, int64(obj.ID), skip, 100)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		for _, p := range productList {
+			productsById[p.ID] = p
+		}
+		if len(productList) < 100 {
+			break
+		}
+	}
+	return productsById, nil
+}
+
+func productIds(productsById map[string]product.Product) []string {
+	ids := make([]string, 0, len(productsById))
+	for id := range productsById {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// toSubOrder returns the sub-order with its amounts in the requested currency.
+func (server *Server) toSubOrder(sub *order.SubOrder, currency *string) (*generated.SubOrder, error) {
+	amounts, err := server.convertAll(currency, sub.Subtotal, sub.Discount, sub.Tax, sub.Shipping, sub.Total)
+	if err != nil {
+		return nil, err
+	}
+	return &generated.SubOrder{
+		ID:        int(sub.ID),
+		SellerID:  int(sub.SellerID),
+		Status:    toOrderStatus(sub.Status),
+		Subtotal:  &amounts[0],
+		Discount:  &amounts[1],
+		Tax:       &amounts[2],
+		Shipping:  &amounts[3],
+		Total:     &amounts[4],
+		UpdatedAt: sub.UpdatedAt,
+	}, nil
+}
+
+// toSellerOrder returns the sub-order as its seller sees it, with only the seller's lines.
+func (server *Server) toSellerOrder(o *order.SellerOrder, currency *string) (*generated.SellerOrder, error) {
+	amounts, err := server.convertAll(currency, o.Subtotal, o.Discount, o.Tax, o.Shipping, o.Total)
+	if err != nil {
+		return nil, err
+	}
+	result := &generated.SellerOrder{
+		ID:              int(o.ID),
+		OrderID:         int(o.OrderID),
+		Status:          toOrderStatus(o.Status),
+		CreatedAt:       o.CreatedAt,
+		Products:        []*generated.OrderedProduct{},
+		ShippingAddress: toAddress(o.ShippingAddress),
+		Subtotal:        &amounts[0],
+		Discount:        &amounts[1],
+		Tax:             &amounts[2],
+		Shipping:        &amounts[3],
+		Total:           &amounts[4],
+	}
+	if o.ShippingMethod != "" {
+		result.ShippingMethod = &o.ShippingMethod
+	}
+	for _, line := range o.Lines {
+		price, err := server.convert(line.UnitPrice, currency)
+		if err != nil {
+			return nil, err
+		}
+		lineTotal := price.Mul(int64(line.Quantity))
+		result.Products = append(result.Products, &generated.OrderedProduct{
+			ID:          line.ProductID,
+			Name:        line.Name,
+			Description: line.Description,
+			Price:       price.Major(),
+			UnitPrice:   &price,
+			Currency:    price.Currency,
+			Quantity:    line.Quantity,
+			LineTotal:   &lineTotal,
+		})
+	}
+	return result, nil
+}
+
+// convertAll returns the amounts in the requested currency, in the order given.
+func (server *Server) convertAll(currency *string, amounts ...money.Money) ([]money.Money, error) {
+	converted := make([]money.Money, len(amounts))
+	for i, amount := range amounts {
+		var err error
+		converted[i], err = server.convert(amount, currency)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return converted, nil
+}
@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	shipping "github.com/rasadov/EcommerceAPI/shipping/models"
+)
+
+type orderResolver struct {
+	server *Server
+}
+
+// Shipments lists the parcels sent for the order, oldest first.
+func (resolver *orderResolver) Shipments(ctx context.Context, obj *generated.Order) ([]*generated.Shipment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	shipmentList, err := resolver.server.shippingClient.GetShipments(ctx, uint64(obj.ID))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	shipments := []*generated.Shipment{}
+	for _, s := range shipmentList {
+		shipments = append(shipments, toShipment(s))
+	}
+	return shipments, nil
+}
+
+// shippingAddress returns the delivery address requested for an order. Orders without a
+// country are neither taxed nor shipped.
+func shippingAddress(in *generated.AddressInput) order.Address {
+	if in == nil {
+		return order.Address{}
+	}
+	address := order.Address{Country: in.Country}
+	if in.RecipientName != nil {
+		address.RecipientName = *in.RecipientName
+	}
+	if in.Line1 != nil {
+		address.Line1 = *in.Line1
+	}
+	if in.Line2 != nil {
+		address.Line2 = *in.Line2
+	}
+	if in.City != nil {
+		address.City = *in.City
+	}
+	if in.PostalCode != nil {
+		address.PostalCode = *in.PostalCode
+	}
+	if in.Region != nil {
+		address.Region = *in.Region
+	}
+	return address.Normalize()
+}
+
+// shippingMethod returns the requested shipping method code, or "" for orders that are not
+// shipped.
+func shippingMethod(method *string) string {
+	if method == nil {
+		return ""
+	}
+	return strings.TrimSpace(*method)
+}
+
+func toAddress(a order.Address) *generated.Address {
+	if a.Country == "" {
+		return nil
+	}
+	address := &generated.Address{Country: &a.Country}
+	if a.RecipientName != "" {
+		address.RecipientName = &a.RecipientName
+	}
+	if a.Line1 != "" {
+		address.Line1 = &a.Line1
+	}
+	if a.Line2 != "" {
+		address.Line2 = &a.Line2
+	}
+	if a.City != "" {
+		address.City = &a.City
+	}
+	if a.PostalCode != "" {
+		address.PostalCode = &a.PostalCode
+	}
+	if a.Region != "" {
+		address.Region = &a.Region
+	}
+	return address
+}
+
+func toShippingRate(quote *shipping.Quote) *generated.ShippingRate {
+	rate := &generated.ShippingRate{
+		Method:  quote.Method,
+		Name:    quote.Name,
+		Carrier: quote.Carrier,
+		Price:   &quote.Price,
+	}
+	if quote.DeliveryDays > 0 {
+		rate.DeliveryDays = &quote.DeliveryDays
+	}
+	return rate
+}
+
+func toShipment(s *shipping.Shipment) *generated.Shipment {
+	shipment := &generated.Shipment{
+		ID:             int(s.ID),
+		Carrier:        s.Carrier,
+		TrackingNumber: s.TrackingNumber,
+		Status:         strings.ToUpper(string(s.Status)),
+		ShippedAt:      s.ShippedAt,
+		DeliveredAt:    s.DeliveredAt,
+		Lines:          []*generated.ShipmentLine{},
+	}
+	for _, line := range s.Lines {
+		shipment.Lines = append(shipment.Lines, &generated.ShipmentLine{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+		})
+	}
+	return shipment
+}
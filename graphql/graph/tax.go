@@ -0,0 +1,19 @@
+package graph
+
+import (
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	order "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// toOrderTax returns the tax line with its amount given in amount's currency.
+func toOrderTax(t *order.OrderTax, amount money.Money) *generated.OrderTax {
+	return &generated.OrderTax{
+		ProductID:   t.ProductID,
+		TaxCategory: t.TaxCategory,
+		Name:        t.Name,
+		Rate:        t.Rate,
+		Inclusive:   t.Inclusive,
+		Amount:      &amount,
+	}
+}
@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/graphql/generated"
+	"github.com/rasadov/EcommerceAPI/graphql/models"
+	wishlist "github.com/rasadov/EcommerceAPI/wishlist/models"
+)
+
+type wishlistResolver struct {
+	server *Server
+}
+
+// Items returns the saved products, fetched from the product service in a single call.
+// Products that have since been deleted are returned without product details.
+func (resolver *wishlistResolver) Items(ctx context.Context, obj *models.Wishlist, currency *string) ([]*generated.WishlistItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	items := make([]*generated.WishlistItem, 0, len(obj.Items))
+	if len(obj.Items) == 0 {
+		return items, nil
+	}
+
+	productIds := make([]string, 0, len(obj.Items))
+	for _, item := range obj.Items {
+		productIds = append(productIds, item.ProductID)
+	}
+	productList, err := resolver.server.productClient.GetProducts(ctx, 0, 0, productIds, "")
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	products, err := resolver.server.toProducts(productList, currency)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	productsById := make(map[string]*generated.Product, len(products))
+	for _, p := range products {
+		productsById[p.ID] = p
+	}
+
+	for _, item := range obj.Items {
+		items = append(items, &generated.WishlistItem{
+			ProductID: item.ProductID,
+			AddedAt:   item.AddedAt,
+			Product:   productsById[item.ProductID],
+		})
+	}
+	return items, nil
+}
+
+func toWishlist(w *wishlist.Wishlist) *models.Wishlist {
+	return &models.Wishlist{
+		ID:         int(w.ID),
+		Name:       w.Name,
+		ShareToken: w.ShareToken,
+		CreatedAt:  w.CreatedAt,
+		Items:      w.Items,
+	}
+}
+
+// wishlistId maps an optional GraphQL list id to the wishlist service's, where 0 is the default list.
+func wishlistId(id *int) uint64 {
+	if id == nil || *id < 0 {
+		return 0
+	}
+	return uint64(*id)
+}
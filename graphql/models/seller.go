@@ -0,0 +1,6 @@
+package models
+
+type Seller struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	wishlist "github.com/rasadov/EcommerceAPI/wishlist/models"
+)
+
+type Wishlist struct {
+	ID         int                     `json:"id"`
+	Name       string                  `json:"name"`
+	ShareToken *string                 `json:"shareToken"`
+	CreatedAt  time.Time               `json:"createdAt"`
+	Items      []wishlist.WishlistItem `json:"-"`
+}
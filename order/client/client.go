@@ -2,11 +2,15 @@ package client
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log"
 	"time"
 
 	"github.com/rasadov/EcommerceAPI/order/models"
 	"github.com/rasadov/EcommerceAPI/order/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/idempotency"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/types/known/wrapperspb"
@@ -18,7 +22,10 @@ type Client struct {
 }
 
 func NewClient(url string) (*Client, error) {
-	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(url,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(idempotency.UnaryClientInterceptor()),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -33,43 +40,44 @@ func (client *Client) Close() {
 	}
 }
 
+// PostOrder places an order, putting credit towards it. With a redirectURL it also opens the
+// order's checkout session, for what the credit leaves to pay, and returns its URL.
 func (client *Client) PostOrder(
 	ctx context.Context,
 	accountID uint64,
+	currency string,
 	products []*models.OrderedProduct,
-) (*models.Order, error) {
-	var protoProducts []*pb.OrderProduct
-	for _, p := range products {
-		protoProducts = append(protoProducts, &pb.OrderProduct{
-			Id:       p.ID,
-			Quantity: p.Quantity,
-		})
-	}
-
+	couponCode string,
+	address models.Address,
+	shippingMethod string,
+	redirectURL string,
+	origin models.OrderOrigin,
+	credit models.OrderCredit,
+) (*models.Order, string, error) {
 	r, err := client.service.PostOrder(
 		ctx,
 		&pb.PostOrderRequest{
-			AccountId: accountID,
-			Products:  protoProducts,
+			AccountId:       accountID,
+			Products:        encodeOrderProducts(products),
+			Currency:        currency,
+			CouponCode:      couponCode,
+			ShippingAddress: encodeAddress(address),
+			ShippingMethod:  shippingMethod,
+			RedirectURL:     redirectURL,
+			ClientIp:        origin.ClientIP,
+			BillingCountry:  origin.BillingCountry,
+			GiftCardCode:    credit.GiftCardCode,
+			UseStoreCredit:  credit.UseStoreCredit,
 		},
 	)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	// Create response order
-	newOrder := r.Order
-	newOrderCreatedAt := time.Time{}
-	err = newOrderCreatedAt.UnmarshalBinary(newOrder.CreatedAt)
+	order, err := decodeOrder(r.Order)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return &models.Order{
-		ID:         uint(r.Order.GetId()),
-		CreatedAt:  newOrderCreatedAt,
-		TotalPrice: newOrder.TotalPrice,
-		AccountID:  newOrder.AccountId,
-		Products:   products,
-	}, nil
+	return order, r.CheckoutUrl, nil
 }
 
 func (client *Client) GetOrdersForAccount(ctx context.Context, accountID uint64) ([]models.Order, error) {
@@ -84,43 +92,847 @@ func (client *Client) GetOrdersForAccount(ctx context.Context, accountID uint64)
 	// Create response orders
 	var orders []models.Order
 	for _, orderProto := range r.Orders {
-		newOrder := models.Order{
-			ID:         uint(orderProto.Id),
-			TotalPrice: orderProto.TotalPrice,
-			AccountID:  orderProto.AccountId,
+		newOrder, err := decodeOrder(orderProto)
+		if err != nil {
+			return nil, err
 		}
-		newOrder.CreatedAt = time.Time{}
-		err = newOrder.CreatedAt.UnmarshalBinary(orderProto.CreatedAt)
+		orders = append(orders, *newOrder)
+	}
+	return orders, nil
+}
+
+// GetOrder returns an order owned by accountId, or any order when accountId is 0.
+func (client *Client) GetOrder(ctx context.Context, orderId, accountId uint64) (*models.Order, error) {
+	r, err := client.service.GetOrder(ctx, &pb.GetOrderRequest{
+		OrderId:   orderId,
+		AccountId: accountId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeOrder(r.Order)
+}
+
+// ListOrders returns a page of orders matching filter, newest first. Pass the previous
+// page's EndCursor as after to continue the listing.
+func (client *Client) ListOrders(ctx context.Context, filter models.OrderFilter, after string, first int) (*models.OrderPage, error) {
+	request := &pb.ListOrdersRequest{
+		AccountId: filter.AccountID,
+		After:     after,
+		First:     uint32(first),
+	}
+	if !filter.CreatedAfter.IsZero() {
+		request.CreatedAfter, _ = filter.CreatedAfter.MarshalBinary()
+	}
+	if !filter.CreatedBefore.IsZero() {
+		request.CreatedBefore, _ = filter.CreatedBefore.MarshalBinary()
+	}
+	for _, s := range filter.Statuses {
+		request.Statuses = append(request.Statuses, s.String())
+	}
+	if filter.MinTotal != nil {
+		request.MinTotal = filter.MinTotal.ToProto()
+	}
+	if filter.MaxTotal != nil {
+		request.MaxTotal = filter.MaxTotal.ToProto()
+	}
+
+	r, err := client.service.ListOrders(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.OrderPage{
+		EndCursor:   r.EndCursor,
+		HasNextPage: r.HasNextPage,
+	}
+	for _, orderProto := range r.Orders {
+		order, err := decodeOrder(orderProto)
 		if err != nil {
 			return nil, err
 		}
+		page.Orders = append(page.Orders, order)
+	}
+	return page, nil
+}
+
+// ExportOrders calls fn with every order placed from from until to, oldest first, as the
+// order service streams them, and stops at the first error fn returns.
+func (client *Client) ExportOrders(ctx context.Context, from, to time.Time, fn func(*models.Order) error) error {
+	request := &pb.ExportOrdersRequest{}
+	request.From, _ = from.MarshalBinary()
+	request.To, _ = to.MarshalBinary()
 
-		var products []*models.OrderedProduct
-		for _, p := range orderProto.Products {
-			products = append(products, &models.OrderedProduct{
-				ID:          p.Id,
-				Quantity:    p.Quantity,
-				Name:        p.Name,
-				Description: p.Description,
-				Price:       p.Price,
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := client.service.ExportOrders(ctx, request)
+	if err != nil {
+		return err
+	}
+	for {
+		orderProto, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		order, err := decodeOrder(orderProto)
+		if err != nil {
+			return err
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+}
+
+// ListSellerOrders returns a page of the seller's sub-orders, newest first, each with the
+// seller's lines only. Pass the previous page's EndCursor as after to continue the listing.
+func (client *Client) ListSellerOrders(ctx context.Context, sellerId uint64, statuses []models.OrderStatus, after string, first int) (*models.SellerOrderPage, error) {
+	request := &pb.ListSellerOrdersRequest{
+		SellerId: sellerId,
+		After:    after,
+		First:    uint32(first),
+	}
+	for _, s := range statuses {
+		request.Statuses = append(request.Statuses, s.String())
+	}
+
+	r, err := client.service.ListSellerOrders(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.SellerOrderPage{
+		EndCursor:   r.EndCursor,
+		HasNextPage: r.HasNextPage,
+	}
+	for _, orderProto := range r.Orders {
+		sub, err := decodeSubOrder(orderProto.SubOrder)
+		if err != nil {
+			return nil, err
+		}
+		order := &models.SellerOrder{
+			SubOrder:        *sub,
+			AccountID:       orderProto.AccountId,
+			ShippingAddress: decodeAddress(orderProto.ShippingAddress),
+			ShippingMethod:  orderProto.ShippingMethod,
+		}
+		for _, line := range orderProto.Lines {
+			order.Lines = append(order.Lines, models.ProductsInfo{
+				OrderID:     sub.OrderID,
+				ProductID:   line.Id,
+				SellerID:    line.SellerId,
+				Quantity:    int(line.Quantity),
+				Name:        line.Name,
+				Description: line.Description,
+				UnitPrice:   money.FromProto(line.GetPrice()),
+				LineTotal:   money.FromProto(line.GetLineTotal()),
 			})
 		}
-		newOrder.Products = products
+		page.Orders = append(page.Orders, order)
+	}
+	return page, nil
+}
 
-		orders = append(orders, newOrder)
+// UpdateSubOrderStatus moves a sub-order of sellerId, or any sub-order when sellerId is 0,
+// on in its fulfilment on behalf of actor.
+func (client *Client) UpdateSubOrderStatus(ctx context.Context, subOrderId uint, sellerId uint64, status models.OrderStatus, actor string) (*models.SubOrder, error) {
+	r, err := client.service.UpdateSubOrderStatus(ctx, &pb.UpdateSubOrderStatusRequest{
+		SubOrderId: uint64(subOrderId),
+		SellerId:   sellerId,
+		Status:     status.String(),
+		Actor:      actor,
+	})
+	if err != nil {
+		return nil, err
 	}
-	return orders, nil
+	return decodeSubOrder(r.SubOrder)
+}
+
+// GetOrderLinesForProducts returns a page of the paid order lines for the products, newest first.
+func (client *Client) GetOrderLinesForProducts(ctx context.Context, productIds []string, skip, take uint64) ([]models.OrderLine, error) {
+	r, err := client.service.GetOrderLinesForProducts(ctx, &pb.GetOrderLinesForProductsRequest{
+		ProductIds: productIds,
+		Skip:       skip,
+		Take:       take,
+	})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	var lines []models.OrderLine
+	for _, lineProto := range r.Lines {
+		line := models.OrderLine{
+			OrderID:   uint(lineProto.OrderId),
+			AccountID: lineProto.AccountId,
+			ProductID: lineProto.ProductId,
+			Name:      lineProto.ProductName,
+			Quantity:  int(lineProto.Quantity),
+		}
+		if lineProto.UnitPrice != nil {
+			line.UnitPrice = money.FromProto(lineProto.UnitPrice)
+		}
+		err = line.CreatedAt.UnmarshalBinary(lineProto.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// GetSalesForProducts adds up what the products sold on paid orders.
+func (client *Client) GetSalesForProducts(ctx context.Context, productIds []string) (*models.SalesSummary, error) {
+	r, err := client.service.GetSalesForProducts(ctx, &pb.GetSalesForProductsRequest{
+		ProductIds: productIds,
+	})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	summary := &models.SalesSummary{OrderCount: int(r.OrderCount)}
+	for _, sales := range r.Products {
+		revenue := money.FromProto(sales.Revenue)
+		summary.Products = append(summary.Products, &models.ProductSales{
+			ProductID:     sales.ProductId,
+			Currency:      revenue.Currency,
+			UnitsSold:     int(sales.UnitsSold),
+			Revenue:       revenue.Amount,
+			UnpricedUnits: int(sales.UnpricedUnits),
+		})
+	}
+	return summary, nil
 }
 
-func (client *Client) UpdateOrderStatus(ctx context.Context, orderId uint64, status string) error {
-	_, err := client.service.UpdateOrderStatus(ctx, &pb.UpdateOrderStatusRequest{
+// UpdateOrderStatus moves an order to status on behalf of actor, returning the updated order
+// without its products.
+func (client *Client) UpdateOrderStatus(ctx context.Context, orderId uint64, status models.OrderStatus, actor, reason string) (*models.Order, error) {
+	r, err := client.service.UpdateOrderStatus(ctx, &pb.UpdateOrderStatusRequest{
 		OrderId: orderId,
-		Status:  status,
+		Status:  status.String(),
+		Actor:   actor,
+		Reason:  reason,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeOrder(r.Order)
+}
 
+// CancelOrder cancels an order owned by accountId, or any order when accountId is 0.
+func (client *Client) CancelOrder(ctx context.Context, orderId, accountId uint64, reason string) (*models.Order, error) {
+	r, err := client.service.CancelOrder(ctx, &pb.CancelOrderRequest{
+		OrderId:   orderId,
+		AccountId: accountId,
+		Reason:    reason,
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return decodeOrder(r.Order)
+}
+
+// RequestReturn asks to send back units of one of accountId's delivered orders, refunded
+// to the account's store credit when toStoreCredit is set.
+func (client *Client) RequestReturn(ctx context.Context, orderId, accountId uint64, lines []*models.ReturnLine, reason string, toStoreCredit bool) (*models.Return, error) {
+	request := &pb.RequestReturnRequest{
+		OrderId:             orderId,
+		AccountId:           accountId,
+		Reason:              reason,
+		RefundToStoreCredit: toStoreCredit,
+	}
+	for _, line := range lines {
+		request.Lines = append(request.Lines, &pb.OrderProduct{Id: line.ProductID, Quantity: uint32(line.Quantity)})
+	}
+	r, err := client.service.RequestReturn(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return decodeReturn(r.OrderReturn)
+}
+
+func (client *Client) GetReturn(ctx context.Context, returnId uint) (*models.Return, error) {
+	r, err := client.service.GetReturn(ctx, &pb.GetReturnRequest{ReturnId: uint64(returnId)})
+	if err != nil {
+		return nil, err
+	}
+	return decodeReturn(r.OrderReturn)
+}
+
+// DecideReturn approves or rejects a requested return on behalf of actor.
+func (client *Client) DecideReturn(ctx context.Context, returnId uint, approve bool, actor, note string) (*models.Return, error) {
+	r, err := client.service.DecideReturn(ctx, &pb.DecideReturnRequest{
+		ReturnId: uint64(returnId),
+		Approve:  approve,
+		Actor:    actor,
+		Note:     note,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeReturn(r.OrderReturn)
+}
+
+// ListRiskReviews returns a page of the orders screened into review with reviewStatus,
+// pending unless one is given, oldest first. Pass the previous page's EndCursor as after to
+// continue the listing.
+func (client *Client) ListRiskReviews(ctx context.Context, reviewStatus models.ReviewStatus, after string, first int) (*models.RiskReviewPage, error) {
+	r, err := client.service.ListRiskReviews(ctx, &pb.ListRiskReviewsRequest{
+		ReviewStatus: reviewStatus.String(),
+		After:        after,
+		First:        uint32(first),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.RiskReviewPage{
+		EndCursor:   r.EndCursor,
+		HasNextPage: r.HasNextPage,
+	}
+	for _, reviewProto := range r.Reviews {
+		review, err := decodeRiskReview(reviewProto)
+		if err != nil {
+			return nil, err
+		}
+		page.Reviews = append(page.Reviews, review)
+	}
+	return page, nil
+}
+
+// DecideRiskReview approves or rejects an order held for review on behalf of actor. The
+// checkout URL of an approved order is returned with it.
+func (client *Client) DecideRiskReview(ctx context.Context, orderId uint64, approve bool, actor, note string) (*models.RiskReview, string, error) {
+	r, err := client.service.DecideRiskReview(ctx, &pb.DecideRiskReviewRequest{
+		OrderId: orderId,
+		Approve: approve,
+		Actor:   actor,
+		Note:    note,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	review, err := decodeRiskReview(r.Review)
+	if err != nil {
+		return nil, "", err
+	}
+	return review, r.CheckoutUrl, nil
+}
+
+// ReceiveReturn records that the returned products arrived back, which restocks and
+// refunds them.
+func (client *Client) ReceiveReturn(ctx context.Context, returnId uint, actor string) (*models.Return, error) {
+	r, err := client.service.ReceiveReturn(ctx, &pb.ReceiveReturnRequest{
+		ReturnId: uint64(returnId),
+		Actor:    actor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeReturn(r.OrderReturn)
+}
+
+// GetInvoice downloads the invoice of a paid order owned by accountId, or of any order when
+// accountId is 0, rendered as "pdf" or "html".
+func (client *Client) GetInvoice(ctx context.Context, orderId, accountId uint64, format string) (*models.InvoiceDocument, error) {
+	r, err := client.service.GetInvoice(ctx, &pb.GetInvoiceRequest{
+		OrderId:   orderId,
+		AccountId: accountId,
+		Format:    format,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	document := &models.InvoiceDocument{
+		Number:      r.Number,
+		OrderID:     uint(r.OrderId),
+		ContentType: r.ContentType,
+		Content:     r.Content,
+	}
+	err = document.IssuedAt.UnmarshalBinary(r.IssuedAt)
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// Reorder prices the lines of one of accountId's past orders as they would be ordered
+// again today.
+func (client *Client) Reorder(ctx context.Context, orderId, accountId uint64) ([]*models.ReorderLine, error) {
+	r, err := client.service.Reorder(ctx, &pb.ReorderRequest{
+		OrderId:   orderId,
+		AccountId: accountId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]*models.ReorderLine, 0, len(r.Lines))
+	for _, line := range r.Lines {
+		lines = append(lines, &models.ReorderLine{
+			ProductID:         line.ProductId,
+			Name:              line.Name,
+			Quantity:          line.Quantity,
+			UnitPrice:         money.FromProto(line.GetUnitPrice()),
+			PreviousUnitPrice: money.FromProto(line.GetPreviousUnitPrice()),
+			Available:         line.Available,
+		})
+	}
+	return lines, nil
+}
+
+// CreateRecurringOrder schedules an order to be placed every week or month, the first time
+// at recurring.NextRunAt or right away when it is not set.
+func (client *Client) CreateRecurringOrder(ctx context.Context, recurring *models.RecurringOrder) (*models.RecurringOrder, error) {
+	encoded := &pb.RecurringOrder{
+		AccountId:       recurring.AccountID,
+		Currency:        recurring.Currency,
+		Interval:        recurring.Interval.String(),
+		ShippingAddress: encodeAddress(recurring.ShippingAddress),
+		ShippingMethod:  recurring.ShippingMethod,
+		RedirectURL:     recurring.RedirectURL,
+	}
+	for _, item := range recurring.Items {
+		encoded.Products = append(encoded.Products, &pb.OrderProduct{Id: item.ProductID, Quantity: item.Quantity})
+	}
+	if !recurring.NextRunAt.IsZero() {
+		encoded.NextRunAt, _ = recurring.NextRunAt.MarshalBinary()
 	}
 
-	return nil
+	r, err := client.service.CreateRecurringOrder(ctx, &pb.CreateRecurringOrderRequest{RecurringOrder: encoded})
+	if err != nil {
+		return nil, err
+	}
+	return decodeRecurringOrder(r.RecurringOrder)
+}
+
+func (client *Client) ListRecurringOrders(ctx context.Context, accountId uint64) ([]*models.RecurringOrder, error) {
+	r, err := client.service.ListRecurringOrders(ctx, &pb.ListRecurringOrdersRequest{AccountId: accountId})
+	if err != nil {
+		return nil, err
+	}
+
+	recurringOrders := make([]*models.RecurringOrder, 0, len(r.RecurringOrders))
+	for _, recurringProto := range r.RecurringOrders {
+		recurring, err := decodeRecurringOrder(recurringProto)
+		if err != nil {
+			return nil, err
+		}
+		recurringOrders = append(recurringOrders, recurring)
+	}
+	return recurringOrders, nil
+}
+
+// UpdateRecurringOrderStatus pauses, resumes or cancels one of accountId's recurring orders.
+func (client *Client) UpdateRecurringOrderStatus(ctx context.Context, id uint, accountId uint64, status models.ScheduleStatus) (*models.RecurringOrder, error) {
+	r, err := client.service.UpdateRecurringOrderStatus(ctx, &pb.UpdateRecurringOrderStatusRequest{
+		RecurringOrderId: uint64(id),
+		AccountId:        accountId,
+		Status:           status.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeRecurringOrder(r.RecurringOrder)
+}
+
+func (client *Client) CreatePromotion(ctx context.Context, promotion *models.Promotion) (*models.Promotion, error) {
+	r, err := client.service.CreatePromotion(ctx, &pb.PromotionRequest{Promotion: encodePromotion(promotion)})
+	if err != nil {
+		return nil, err
+	}
+	return decodePromotion(r.Promotion)
+}
+
+// UpdatePromotion replaces the rules of the promotion with promotion.ID.
+func (client *Client) UpdatePromotion(ctx context.Context, promotion *models.Promotion) (*models.Promotion, error) {
+	r, err := client.service.UpdatePromotion(ctx, &pb.PromotionRequest{Promotion: encodePromotion(promotion)})
+	if err != nil {
+		return nil, err
+	}
+	return decodePromotion(r.Promotion)
+}
+
+func (client *Client) DeactivatePromotion(ctx context.Context, promotionId uint) (*models.Promotion, error) {
+	r, err := client.service.DeactivatePromotion(ctx, &pb.DeactivatePromotionRequest{PromotionId: uint64(promotionId)})
+	if err != nil {
+		return nil, err
+	}
+	return decodePromotion(r.Promotion)
+}
+
+func (client *Client) ListPromotions(ctx context.Context, includeInactive bool) ([]*models.Promotion, error) {
+	r, err := client.service.ListPromotions(ctx, &pb.ListPromotionsRequest{IncludeInactive: includeInactive})
+	if err != nil {
+		return nil, err
+	}
+
+	var promotions []*models.Promotion
+	for _, promotionProto := range r.Promotions {
+		promotion, err := decodePromotion(promotionProto)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+	return promotions, nil
+}
+
+// EvaluatePromotion returns the discount the promotion with the given code would give on
+// products if they were ordered in currency. accountID 0 evaluates an anonymous cart.
+func (client *Client) EvaluatePromotion(ctx context.Context, code string, accountID uint64, currency string, products []*models.OrderedProduct) (*models.PromotionEvaluation, error) {
+	r, err := client.service.EvaluatePromotion(ctx, &pb.EvaluatePromotionRequest{
+		Code:      code,
+		AccountId: accountID,
+		Currency:  currency,
+		Products:  encodeOrderProducts(products),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PromotionEvaluation{
+		Discount: decodeDiscount(r.Discount),
+		Subtotal: money.FromProto(r.Subtotal),
+		Total:    money.FromProto(r.Total),
+	}, nil
+}
+
+func encodeOrderProducts(products []*models.OrderedProduct) []*pb.OrderProduct {
+	var protoProducts []*pb.OrderProduct
+	for _, p := range products {
+		protoProducts = append(protoProducts, &pb.OrderProduct{
+			Id:       p.ID,
+			Quantity: p.Quantity,
+		})
+	}
+	return protoProducts
+}
+
+func encodePromotion(promotion *models.Promotion) *pb.Promotion {
+	encoded := &pb.Promotion{
+		Id:                    uint64(promotion.ID),
+		Code:                  promotion.Code,
+		Description:           promotion.Description,
+		Kind:                  string(promotion.Kind),
+		PercentOff:            uint32(promotion.PercentOff),
+		ProductIds:            promotion.ProductIDs,
+		Categories:            promotion.Categories,
+		BuyQuantity:           uint32(promotion.BuyQuantity),
+		GetQuantity:           uint32(promotion.GetQuantity),
+		UsageLimitPerCustomer: uint32(promotion.UsageLimitPerCustomer),
+		Active:                promotion.Active,
+	}
+	if promotion.AmountOff.Currency != "" {
+		encoded.AmountOff = promotion.AmountOff.ToProto()
+	}
+	if !promotion.MinSubtotal.IsZero() {
+		encoded.MinSubtotal = promotion.MinSubtotal.ToProto()
+	}
+	if promotion.StartsAt != nil {
+		encoded.StartsAt, _ = promotion.StartsAt.MarshalBinary()
+	}
+	if promotion.EndsAt != nil {
+		encoded.EndsAt, _ = promotion.EndsAt.MarshalBinary()
+	}
+	return encoded
+}
+
+func decodePromotion(promotionProto *pb.Promotion) (*models.Promotion, error) {
+	promotion := &models.Promotion{
+		ID:                    uint(promotionProto.Id),
+		Code:                  promotionProto.Code,
+		Description:           promotionProto.Description,
+		Kind:                  models.PromotionKind(promotionProto.Kind),
+		PercentOff:            int(promotionProto.PercentOff),
+		ProductIDs:            promotionProto.ProductIds,
+		Categories:            promotionProto.Categories,
+		BuyQuantity:           int(promotionProto.BuyQuantity),
+		GetQuantity:           int(promotionProto.GetQuantity),
+		UsageLimitPerCustomer: int(promotionProto.UsageLimitPerCustomer),
+		Active:                promotionProto.Active,
+	}
+	if promotionProto.AmountOff != nil {
+		promotion.AmountOff = money.FromProto(promotionProto.AmountOff)
+	}
+	if promotionProto.MinSubtotal != nil {
+		promotion.MinSubtotal = money.FromProto(promotionProto.MinSubtotal)
+	}
+	if len(promotionProto.StartsAt) > 0 {
+		promotion.StartsAt = new(time.Time)
+		if err := promotion.StartsAt.UnmarshalBinary(promotionProto.StartsAt); err != nil {
+			return nil, err
+		}
+	}
+	if len(promotionProto.EndsAt) > 0 {
+		promotion.EndsAt = new(time.Time)
+		if err := promotion.EndsAt.UnmarshalBinary(promotionProto.EndsAt); err != nil {
+			return nil, err
+		}
+	}
+	err := promotion.CreatedAt.UnmarshalBinary(promotionProto.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+func decodeDiscount(discountProto *pb.OrderDiscount) *models.OrderDiscount {
+	return &models.OrderDiscount{
+		PromotionID: uint(discountProto.PromotionId),
+		Code:        discountProto.Code,
+		Description: discountProto.Description,
+		Amount:      money.FromProto(discountProto.GetAmount()),
+	}
+}
+
+func decodeOrder(orderProto *pb.Order) (*models.Order, error) {
+	order := &models.Order{
+		ID:         uint(orderProto.Id),
+		TotalPrice: money.FromProto(orderProto.GetTotalPrice()),
+		AccountID:  orderProto.AccountId,
+		Status:     models.OrderStatus(orderProto.Status),
+	}
+	order.ShippingAddress = decodeAddress(orderProto.ShippingAddress)
+	order.ShippingMethod = orderProto.ShippingMethod
+	order.RiskReview = models.ReviewStatus(orderProto.RiskReview)
+	if orderProto.ShippingCost != nil {
+		order.ShippingCost = money.FromProto(orderProto.ShippingCost)
+	}
+	err := order.CreatedAt.UnmarshalBinary(orderProto.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	order.History, err = decodeHistory(orderProto.History)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range orderProto.Products {
+		order.Products = append(order.Products, &models.OrderedProduct{
+			ID:          p.Id,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       money.FromProto(p.GetPrice()),
+			Quantity:    p.Quantity,
+			SellerID:    p.SellerId,
+		})
+	}
+	for _, d := range orderProto.Discounts {
+		order.Discounts = append(order.Discounts, decodeDiscount(d))
+	}
+	for _, t := range orderProto.Taxes {
+		order.Taxes = append(order.Taxes, &models.OrderTax{
+			ProductID:   t.ProductId,
+			TaxCategory: t.TaxCategory,
+			Name:        t.Name,
+			Rate:        t.Rate,
+			Inclusive:   t.Inclusive,
+			Amount:      money.FromProto(t.GetAmount()),
+		})
+	}
+	for _, r := range orderProto.Returns {
+		ret, err := decodeReturn(r)
+		if err != nil {
+			return nil, err
+		}
+		order.Returns = append(order.Returns, ret)
+	}
+	for _, s := range orderProto.SubOrders {
+		sub, err := decodeSubOrder(s)
+		if err != nil {
+			return nil, err
+		}
+		order.SubOrders = append(order.SubOrders, sub)
+	}
+	return order, nil
+}
+
+func encodeAddress(address models.Address) *pb.OrderAddress {
+	return &pb.OrderAddress{
+		RecipientName: address.RecipientName,
+		Line1:         address.Line1,
+		Line2:         address.Line2,
+		City:          address.City,
+		PostalCode:    address.PostalCode,
+		Region:        address.Region,
+		Country:       address.Country,
+	}
+}
+
+func decodeAddress(address *pb.OrderAddress) models.Address {
+	if address == nil {
+		return models.Address{}
+	}
+	return models.Address{
+		RecipientName: address.RecipientName,
+		Line1:         address.Line1,
+		Line2:         address.Line2,
+		City:          address.City,
+		PostalCode:    address.PostalCode,
+		Region:        address.Region,
+		Country:       address.Country,
+	}
+}
+
+func decodeSubOrder(subProto *pb.SubOrder) (*models.SubOrder, error) {
+	sub := &models.SubOrder{
+		ID:       uint(subProto.Id),
+		OrderID:  uint(subProto.OrderId),
+		SellerID: subProto.SellerId,
+		Status:   models.OrderStatus(subProto.Status),
+		Subtotal: money.FromProto(subProto.GetSubtotal()),
+		Discount: money.FromProto(subProto.GetDiscount()),
+		Tax:      money.FromProto(subProto.GetTax()),
+		Shipping: money.FromProto(subProto.GetShipping()),
+		Total:    money.FromProto(subProto.GetTotal()),
+	}
+	err := sub.CreatedAt.UnmarshalBinary(subProto.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	err = sub.UpdatedAt.UnmarshalBinary(subProto.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func decodeRecurringOrder(recurringProto *pb.RecurringOrder) (*models.RecurringOrder, error) {
+	recurring := &models.RecurringOrder{
+		ID:              uint(recurringProto.Id),
+		AccountID:       recurringProto.AccountId,
+		Currency:        recurringProto.Currency,
+		Interval:        models.RecurringInterval(recurringProto.Interval),
+		Status:          models.ScheduleStatus(recurringProto.Status),
+		ShippingAddress: decodeAddress(recurringProto.ShippingAddress),
+		ShippingMethod:  recurringProto.ShippingMethod,
+		RedirectURL:     recurringProto.RedirectURL,
+		LastOrderID:     uint(recurringProto.LastOrderId),
+		LastCheckoutURL: recurringProto.LastCheckoutUrl,
+		LastError:       recurringProto.LastError,
+	}
+	for _, p := range recurringProto.Products {
+		recurring.Items = append(recurring.Items, &models.RecurringItem{
+			RecurringOrderID: recurring.ID,
+			ProductID:        p.Id,
+			Quantity:         p.Quantity,
+		})
+	}
+	err := recurring.NextRunAt.UnmarshalBinary(recurringProto.NextRunAt)
+	if err != nil {
+		return nil, err
+	}
+	err = recurring.CreatedAt.UnmarshalBinary(recurringProto.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return recurring, nil
+}
+
+func decodeReturn(returnProto *pb.OrderReturn) (*models.Return, error) {
+	ret := &models.Return{
+		ID:                  uint(returnProto.Id),
+		OrderID:             uint(returnProto.OrderId),
+		AccountID:           returnProto.AccountId,
+		Status:              models.ReturnStatus(returnProto.Status),
+		Reason:              returnProto.Reason,
+		RefundAmount:        money.FromProto(returnProto.GetRefundAmount()),
+		RefundID:            returnProto.RefundId,
+		RefundToStoreCredit: returnProto.RefundToStoreCredit,
+	}
+	err := ret.CreatedAt.UnmarshalBinary(returnProto.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range returnProto.Lines {
+		ret.Lines = append(ret.Lines, &models.ReturnLine{
+			ProductID:    line.ProductId,
+			Quantity:     int(line.Quantity),
+			RefundAmount: money.FromProto(line.GetRefundAmount()),
+		})
+	}
+	for _, t := range returnProto.History {
+		transition := &models.ReturnTransition{
+			From:  models.ReturnStatus(t.From),
+			To:    models.ReturnStatus(t.To),
+			Actor: t.Actor,
+			Note:  t.Note,
+		}
+		err = transition.CreatedAt.UnmarshalBinary(t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		ret.History = append(ret.History, transition)
+	}
+	return ret, nil
+}
+
+func decodeRiskReview(reviewProto *pb.RiskReview) (*models.RiskReview, error) {
+	review := &models.RiskReview{
+		RiskAssessment: models.RiskAssessment{
+			OrderID:      uint(reviewProto.OrderId),
+			ClientIP:     reviewProto.ClientIp,
+			Score:        int(reviewProto.Score),
+			Decision:     models.RiskDecision(reviewProto.Decision),
+			Findings:     []models.RiskFinding{},
+			ReviewStatus: models.ReviewStatus(reviewProto.ReviewStatus),
+			ReviewedBy:   reviewProto.ReviewedBy,
+			ReviewNote:   reviewProto.ReviewNote,
+		},
+	}
+	err := review.CreatedAt.UnmarshalBinary(reviewProto.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(reviewProto.ReviewedAt) > 0 {
+		reviewedAt := time.Time{}
+		err = reviewedAt.UnmarshalBinary(reviewProto.ReviewedAt)
+		if err != nil {
+			return nil, err
+		}
+		review.ReviewedAt = &reviewedAt
+	}
+	for _, finding := range reviewProto.Findings {
+		review.Findings = append(review.Findings, models.RiskFinding{
+			Rule:   finding.Rule,
+			Score:  int(finding.Score),
+			Reason: finding.Reason,
+		})
+	}
+	if reviewProto.Order != nil {
+		review.Order, err = decodeOrder(reviewProto.Order)
+		if err != nil {
+			return nil, err
+		}
+		review.AccountID = review.Order.AccountID
+	}
+	return review, nil
+}
+
+func decodeHistory(history []*pb.OrderStatusTransition) ([]*models.StatusTransition, error) {
+	var transitions []*models.StatusTransition
+	for _, t := range history {
+		transition := &models.StatusTransition{
+			From:   models.OrderStatus(t.From),
+			To:     models.OrderStatus(t.To),
+			Actor:  t.Actor,
+			Reason: t.Reason,
+		}
+		err := transition.CreatedAt.UnmarshalBinary(t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, transition)
+	}
+	return transitions, nil
 }
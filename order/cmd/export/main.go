@@ -0,0 +1,86 @@
+// Command export writes the orders placed in a date range, with their lines, taxes and
+// payment status, for accounting:
+//
+//	export -from 2026-09-01 -to 2026-10-01 -format parquet -out orders.parquet
+//
+// Orders are streamed from the order service and written as they arrive.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/client"
+	"github.com/rasadov/EcommerceAPI/order/export"
+	"github.com/rasadov/EcommerceAPI/order/models"
+)
+
+func main() {
+	from := flag.String("from", "", "export orders placed from this date (YYYY-MM-DD, UTC) or RFC 3339 time")
+	to := flag.String("to", "", "export orders placed before this date (YYYY-MM-DD, UTC) or RFC 3339 time")
+	format := flag.String("format", export.FormatCSV, "csv, jsonl or parquet")
+	outPath := flag.String("out", "", "file to write, standard output when empty")
+	orderUrl := flag.String("order-url", os.Getenv("ORDER_SERVICE_URL"), "address of the order service")
+	flag.Parse()
+
+	fromTime, err := parseTime(*from)
+	if err != nil {
+		log.Fatalf("Invalid -from: %v", err)
+	}
+	toTime, err := parseTime(*to)
+	if err != nil {
+		log.Fatalf("Invalid -to: %v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		out = file
+	}
+	writer, err := export.NewWriter(*format, out)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	orderClient, err := client.NewClient(*orderUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer orderClient.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	count := 0
+	err = orderClient.ExportOrders(ctx, fromTime, toTime, func(order *models.Order) error {
+		count++
+		return writer.Write(order)
+	})
+	if err != nil {
+		log.Fatal("Failed to export orders: ", err)
+	}
+	if err := writer.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Exported %d orders", count)
+}
+
+// parseTime accepts a date, taken as midnight UTC, or an RFC 3339 time.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("a date is required")
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
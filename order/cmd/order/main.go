@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/rasadov/EcommerceAPI/order/config"
 	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/order/risk"
+	"github.com/rasadov/EcommerceAPI/pkg/blob"
+	"github.com/rasadov/EcommerceAPI/pkg/idempotency"
+	"github.com/rasadov/EcommerceAPI/pkg/kafka"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/pkg/tax"
 	"github.com/tinrab/retry"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -14,6 +22,7 @@ import (
 
 func main() {
 	var repository internal.Repository
+	var idempotencyStore *idempotency.Store
 
 	producer, err := sarama.NewAsyncProducer([]string{config.BootstrapServers}, nil)
 	if err != nil {
@@ -32,6 +41,11 @@ func main() {
 			log.Println(err)
 		}
 		repository, err = internal.NewPostgresRepository(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		idempotencyStore, err = idempotency.NewStore(db, config.IdempotencyTTL)
 		if err != nil {
 			log.Println(err)
 		}
@@ -39,6 +53,64 @@ func main() {
 	})
 	defer repository.Close()
 	log.Println("Listening on port 8080...")
-	service := internal.NewOrderService(repository, producer)
-	log.Fatal(internal.ListenGRPC(service, config.AccountUrl, config.ProductUrl, 8080))
+	converter := money.NewConverter(money.NewRateSource(config.CurrencyRatesURL, config.CurrencyRatesFile))
+	converter.StartRefresh(context.Background(), time.Hour)
+
+	// Orders are not taxed until a rate table is configured
+	var taxCalculator tax.Calculator
+	if config.TaxRatesFile != "" {
+		rates, err := tax.LoadRateTable(config.TaxRatesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		taxCalculator = tax.NewTableCalculator(rates)
+	}
+
+	service := internal.NewOrderService(repository, producer, converter, taxCalculator)
+
+	// Shipments move orders through fulfilment
+	consumer, err := sarama.NewConsumer([]string{config.BootstrapServers}, nil)
+	if err != nil {
+		log.Println(err)
+	} else {
+		eventConsumer := internal.NewEventConsumer(consumer, service)
+		defer kafka.CloseConsumer(eventConsumer)
+		go func() {
+			err := eventConsumer.StartShippingEventsConsumer(context.Background())
+			if err != nil {
+				log.Println("Failed to consume shipping events:", err)
+			}
+		}()
+	}
+
+	invoiceStore, err := blob.NewDiskStore(config.InvoiceDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	seller := models.Party{
+		Name:  config.SellerName,
+		Email: config.SellerEmail,
+		TaxID: config.SellerTaxID,
+		Address: models.Address{
+			Line1:      config.SellerLine1,
+			Line2:      config.SellerLine2,
+			City:       config.SellerCity,
+			PostalCode: config.SellerPostalCode,
+			Country:    config.SellerCountry,
+		}.Normalize(),
+	}
+
+	highOrderValue, err := money.ParseMajor(config.RiskHighOrderValue, config.RiskCurrency)
+	if err != nil {
+		log.Fatal(err)
+	}
+	screening := risk.NewEngine(config.RiskReviewScore, config.RiskBlockScore,
+		risk.AccountVelocity{History: service, Window: time.Hour, Limit: 5, Score: 40},
+		risk.IPVelocity{History: service, Window: time.Hour, Limit: 10, Score: 50},
+		risk.OrderValue{Threshold: highOrderValue, Converter: converter, Score: 30},
+		risk.NewAccount{History: service, Score: 20},
+		risk.CountryMismatch{Score: 25},
+	)
+
+	log.Fatal(internal.ListenGRPC(service, converter, idempotencyStore, invoiceStore, seller, screening, config.AccountUrl, config.ProductUrl, config.PaymentUrl, config.ShippingUrl, config.PaymentTimeout, 8080))
 }
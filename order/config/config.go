@@ -1,17 +1,91 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 var (
-	DatabaseUrl      string
-	AccountUrl       string
-	ProductUrl       string
-	BootstrapServers string
+	DatabaseUrl       string
+	AccountUrl        string
+	ProductUrl        string
+	PaymentUrl        string
+	ShippingUrl       string
+	BootstrapServers  string
+	CurrencyRatesURL  string
+	CurrencyRatesFile string
+	TaxRatesFile      string
+	IdempotencyTTL    time.Duration
+	// PaymentTimeout is how long a placed order may stay unpaid before it is cancelled
+	PaymentTimeout time.Duration
+	// InvoiceDir is where rendered invoices are stored
+	InvoiceDir string
+	// The seller named on invoices
+	SellerName       string
+	SellerEmail      string
+	SellerTaxID      string
+	SellerLine1      string
+	SellerLine2      string
+	SellerCity       string
+	SellerPostalCode string
+	SellerCountry    string
+	// Orders scoring RiskReviewScore wait for an admin's review, and those scoring
+	// RiskBlockScore are declined
+	RiskReviewScore int
+	RiskBlockScore  int
+	// RiskHighOrderValue is the order total, in major units of RiskCurrency, from which
+	// orders are scored as high value
+	RiskHighOrderValue string
+	RiskCurrency       string
 )
 
 func init() {
 	DatabaseUrl = os.Getenv("DATABASE_URL")
 	AccountUrl = os.Getenv("ACCOUNT_SERVICE_URL")
 	ProductUrl = os.Getenv("PRODUCT_SERVICE_URL")
+	PaymentUrl = os.Getenv("PAYMENT_SERVICE_URL")
+	ShippingUrl = os.Getenv("SHIPPING_SERVICE_URL")
 	BootstrapServers = os.Getenv("KAFKA_BOOTSTRAP_SERVERS")
+	CurrencyRatesURL = os.Getenv("CURRENCY_RATES_URL")
+	CurrencyRatesFile = os.Getenv("CURRENCY_RATES_FILE")
+	TaxRatesFile = os.Getenv("TAX_RATES_FILE")
+	IdempotencyTTL = durationOrDefault(os.Getenv("IDEMPOTENCY_TTL"), 24*time.Hour)
+	PaymentTimeout = durationOrDefault(os.Getenv("PAYMENT_TIMEOUT"), time.Hour)
+	InvoiceDir = stringOrDefault(os.Getenv("INVOICE_DIR"), "/var/lib/order/invoices")
+	SellerName = os.Getenv("SELLER_NAME")
+	SellerEmail = os.Getenv("SELLER_EMAIL")
+	SellerTaxID = os.Getenv("SELLER_TAX_ID")
+	SellerLine1 = os.Getenv("SELLER_ADDRESS_LINE1")
+	SellerLine2 = os.Getenv("SELLER_ADDRESS_LINE2")
+	SellerCity = os.Getenv("SELLER_CITY")
+	SellerPostalCode = os.Getenv("SELLER_POSTAL_CODE")
+	SellerCountry = os.Getenv("SELLER_COUNTRY")
+	RiskReviewScore = intOrDefault(os.Getenv("RISK_REVIEW_SCORE"), 50)
+	RiskBlockScore = intOrDefault(os.Getenv("RISK_BLOCK_SCORE"), 80)
+	RiskHighOrderValue = stringOrDefault(os.Getenv("RISK_HIGH_ORDER_VALUE"), "1000")
+	RiskCurrency = stringOrDefault(os.Getenv("RISK_CURRENCY"), "USD")
+}
+
+func durationOrDefault(value string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+func stringOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func intOrDefault(value string, fallback int) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
 }
@@ -0,0 +1,119 @@
+// Package export writes orders out for accounting: with their lines, taxes and payment
+// status, one order at a time, as CSV, JSON Lines or Parquet.
+package export
+
+import (
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// Record is an order as exported. Amounts are decimal strings in the order's currency.
+type Record struct {
+	OrderID        uint64       `json:"order_id"`
+	PlacedAt       time.Time    `json:"placed_at"`
+	AccountID      uint64       `json:"account_id"`
+	Status         string       `json:"status"`
+	PaymentStatus  string       `json:"payment_status"`
+	Currency       string       `json:"currency"`
+	Subtotal       string       `json:"subtotal"`
+	Discount       string       `json:"discount"`
+	ShippingMethod string       `json:"shipping_method"`
+	ShippingCost   string       `json:"shipping_cost"`
+	Tax            string       `json:"tax"`
+	Total          string       `json:"total"`
+	Country        string       `json:"country"`
+	Lines          []LineRecord `json:"lines"`
+}
+
+// LineRecord is one line of an exported order, with its share of the order's discounts
+// and the taxes charged on it.
+type LineRecord struct {
+	Line        int    `json:"line"`
+	ProductID   string `json:"product_id"`
+	ProductName string `json:"product_name"`
+	SellerID    uint64 `json:"seller_id"`
+	Quantity    int64  `json:"quantity"`
+	UnitPrice   string `json:"unit_price"`
+	LineTotal   string `json:"line_total"`
+	Discount    string `json:"discount"`
+	// TaxName and TaxRate are those of the line's first tax, empty for untaxed lines
+	TaxName      string `json:"tax_name"`
+	TaxRate      string `json:"tax_rate"`
+	TaxInclusive bool   `json:"tax_inclusive"`
+	Tax          string `json:"tax"`
+}
+
+// NewRecord returns the order as exported.
+func NewRecord(order *models.Order) (*Record, error) {
+	currency := order.TotalPrice.Currency
+	subtotal, err := order.Subtotal()
+	if err != nil {
+		return nil, err
+	}
+	tax, err := order.TaxTotal()
+	if err != nil {
+		return nil, err
+	}
+	discounts, err := models.DiscountShares(currency, order.Products, order.Discounts)
+	if err != nil {
+		return nil, err
+	}
+	discount := money.Zero(currency)
+	for _, share := range discounts {
+		discount, err = discount.Add(share)
+		if err != nil {
+			return nil, err
+		}
+	}
+	shippingCost := order.ShippingCost
+	if shippingCost.Currency == "" {
+		shippingCost = money.Zero(currency)
+	}
+
+	record := &Record{
+		OrderID:        uint64(order.ID),
+		PlacedAt:       order.CreatedAt.UTC(),
+		AccountID:      order.AccountID,
+		Status:         order.Status.String(),
+		PaymentStatus:  order.PaymentStatus(),
+		Currency:       currency,
+		Subtotal:       subtotal.Decimal(),
+		Discount:       discount.Decimal(),
+		ShippingMethod: order.ShippingMethod,
+		ShippingCost:   shippingCost.Decimal(),
+		Tax:            tax.Decimal(),
+		Total:          order.TotalPrice.Decimal(),
+		Country:        order.ShippingAddress.Country,
+		Lines:          make([]LineRecord, 0, len(order.Products)),
+	}
+	for i, product := range order.Products {
+		line := LineRecord{
+			Line:        i + 1,
+			ProductID:   product.ID,
+			ProductName: product.Name,
+			SellerID:    product.SellerID,
+			Quantity:    int64(product.Quantity),
+			UnitPrice:   product.Price.Decimal(),
+			LineTotal:   product.LineTotal().Decimal(),
+			Discount:    discounts[i].Decimal(),
+		}
+		lineTax := money.Zero(currency)
+		for _, t := range order.Taxes {
+			if t.ProductID != product.ID {
+				continue
+			}
+			if line.TaxName == "" {
+				line.TaxName, line.TaxRate, line.TaxInclusive = t.Name, t.Rate, t.Inclusive
+			}
+			lineTax, err = lineTax.Add(t.Amount)
+			if err != nil {
+				return nil, err
+			}
+		}
+		line.Tax = lineTax.Decimal()
+		record.Lines = append(record.Lines, line)
+	}
+	return record, nil
+}
@@ -0,0 +1,199 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/parquet"
+)
+
+// Export formats. CSV and Parquet have one row per order line, repeating the order's
+// columns, while JSON Lines has one object per order with its lines nested.
+const (
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format, expected csv, jsonl or parquet")
+
+// Writer writes exported orders. Close must be called once every order was written.
+type Writer interface {
+	Write(order *models.Order) error
+	Close() error
+}
+
+// NewWriter returns a Writer of orders in format to out. Closing it does not close out.
+func NewWriter(format string, out io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		w := csv.NewWriter(out)
+		if err := w.Write(columnNames()); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w}, nil
+	case FormatJSONL:
+		buffered := bufio.NewWriter(out)
+		return &jsonlWriter{buffered, json.NewEncoder(buffered)}, nil
+	case FormatParquet:
+		buffered := bufio.NewWriter(out)
+		w, err := parquet.NewWriter(buffered, parquetColumns)
+		if err != nil {
+			return nil, err
+		}
+		return &parquetWriter{buffered, w}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// parquetColumns are the columns of a line row, in the order of lineRow.
+var parquetColumns = []parquet.Column{
+	{Name: "order_id", Type: parquet.Int64},
+	{Name: "placed_at", Type: parquet.Timestamp},
+	{Name: "account_id", Type: parquet.Int64},
+	{Name: "status", Type: parquet.String},
+	{Name: "payment_status", Type: parquet.String},
+	{Name: "currency", Type: parquet.String},
+	{Name: "order_subtotal", Type: parquet.String},
+	{Name: "order_discount", Type: parquet.String},
+	{Name: "shipping_method", Type: parquet.String},
+	{Name: "shipping_cost", Type: parquet.String},
+	{Name: "order_tax", Type: parquet.String},
+	{Name: "order_total", Type: parquet.String},
+	{Name: "country", Type: parquet.String},
+	{Name: "line", Type: parquet.Int64},
+	{Name: "product_id", Type: parquet.String},
+	{Name: "product_name", Type: parquet.String},
+	{Name: "seller_id", Type: parquet.Int64},
+	{Name: "quantity", Type: parquet.Int64},
+	{Name: "unit_price", Type: parquet.String},
+	{Name: "line_total", Type: parquet.String},
+	{Name: "line_discount", Type: parquet.String},
+	{Name: "tax_name", Type: parquet.String},
+	{Name: "tax_rate", Type: parquet.String},
+	{Name: "tax_inclusive", Type: parquet.Boolean},
+	{Name: "line_tax", Type: parquet.String},
+}
+
+func columnNames() []string {
+	names := make([]string, len(parquetColumns))
+	for i, column := range parquetColumns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// lineRow is the row of one line of the record, with the column types of parquetColumns.
+func lineRow(record *Record, line LineRecord) []any {
+	return []any{
+		int64(record.OrderID),
+		record.PlacedAt,
+		int64(record.AccountID),
+		record.Status,
+		record.PaymentStatus,
+		record.Currency,
+		record.Subtotal,
+		record.Discount,
+		record.ShippingMethod,
+		record.ShippingCost,
+		record.Tax,
+		record.Total,
+		record.Country,
+		int64(line.Line),
+		line.ProductID,
+		line.ProductName,
+		int64(line.SellerID),
+		line.Quantity,
+		line.UnitPrice,
+		line.LineTotal,
+		line.Discount,
+		line.TaxName,
+		line.TaxRate,
+		line.TaxInclusive,
+		line.Tax,
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) Write(order *models.Order) error {
+	record, err := NewRecord(order)
+	if err != nil {
+		return err
+	}
+	for _, line := range record.Lines {
+		row := lineRow(record, line)
+		fields := make([]string, len(row))
+		for i, value := range row {
+			switch v := value.(type) {
+			case string:
+				fields[i] = v
+			case int64:
+				fields[i] = strconv.FormatInt(v, 10)
+			case bool:
+				fields[i] = strconv.FormatBool(v)
+			case time.Time:
+				fields[i] = v.Format(time.RFC3339)
+			}
+		}
+		if err := w.w.Write(fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonlWriter struct {
+	out     *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Write(order *models.Order) error {
+	record, err := NewRecord(order)
+	if err != nil {
+		return err
+	}
+	return w.encoder.Encode(record)
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.out.Flush()
+}
+
+type parquetWriter struct {
+	out *bufio.Writer
+	w   *parquet.Writer
+}
+
+func (w *parquetWriter) Write(order *models.Order) error {
+	record, err := NewRecord(order)
+	if err != nil {
+		return err
+	}
+	for _, line := range record.Lines {
+		if err := w.w.Write(lineRow(record, line)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.w.Close(); err != nil {
+		return err
+	}
+	return w.out.Flush()
+}
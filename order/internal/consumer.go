@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/IBM/sarama"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/kafka"
+)
+
+type EventConsumer struct {
+	consumer sarama.Consumer
+	service  Service
+}
+
+func NewEventConsumer(consumer sarama.Consumer, service Service) *EventConsumer {
+	return &EventConsumer{
+		consumer: consumer,
+		service:  service,
+	}
+}
+
+func (ec *EventConsumer) GetConsumer() sarama.Consumer {
+	return ec.consumer
+}
+
+func (ec *EventConsumer) StartShippingEventsConsumer(ctx context.Context) error {
+	return kafka.StartEventsConsumer(ctx, ec, "shipping_events", ec.handleShippingEvent)
+}
+
+func (ec *EventConsumer) handleShippingEvent(partition int32, pc sarama.PartitionConsumer) {
+	for {
+		select {
+		case message := <-pc.Messages():
+			if message == nil {
+				continue
+			}
+
+			var event models.ShipmentEvent
+			if err := json.Unmarshal(message.Value, &event); err != nil {
+				log.Printf("Failed to unmarshal shipping event: %v", err)
+				continue
+			}
+			ec.handleShipmentEvent(context.Background(), event)
+
+		case err := <-pc.Errors():
+			if err != nil {
+				log.Printf("Kafka consumer error: %v", err)
+			}
+		}
+	}
+}
+
+func (ec *EventConsumer) handleShipmentEvent(ctx context.Context, event models.ShipmentEvent) {
+	reason := fmt.Sprintf("shipment %d via %s (%s)", event.Data.ShipmentId, event.Data.Carrier, event.Data.TrackingNumber)
+
+	var err error
+	switch event.Type {
+	case "order_shipped":
+		_, err = ec.service.RecordShipment(ctx, event.Data.OrderId, event.Data.Complete, reason)
+	case "order_delivered":
+		if !event.Data.Complete {
+			return
+		}
+		_, err = ec.service.RecordDelivery(ctx, event.Data.OrderId, reason)
+	default:
+		log.Printf("Unknown event type: %s", event.Type)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to apply %s to order %d: %v", event.Type, event.Data.OrderId, err)
+	}
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/models"
+	productmodels "github.com/rasadov/EcommerceAPI/product/models"
+)
+
+const expiryBatchSize = 100
+
+// OrderExpirer cancels orders whose payment has not succeeded within window of them being
+// placed, puts their stock back and expires their checkout sessions. Replicas running it
+// side by side each expire different orders.
+type OrderExpirer struct {
+	service   Service
+	inventory Inventory
+	checkout  CheckoutSessions
+	window    time.Duration
+}
+
+func NewOrderExpirer(service Service, inventory Inventory, checkout CheckoutSessions, window time.Duration) *OrderExpirer {
+	return &OrderExpirer{service, inventory, checkout, window}
+}
+
+// Run expires overdue orders every interval until ctx is done.
+func (expirer *OrderExpirer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		expired, err := expirer.ExpireOrders(ctx)
+		if err != nil {
+			log.Println("Failed to expire unpaid orders:", err)
+		}
+		if expired > 0 {
+			log.Printf("Expired %d unpaid orders", expired)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireOrders expires every order that is overdue now and returns how many were.
+func (expirer *OrderExpirer) ExpireOrders(ctx context.Context) (int, error) {
+	placedBefore := time.Now().UTC().Add(-expirer.window)
+	expired := 0
+	for {
+		orders, err := expirer.service.ExpireOrders(ctx, placedBefore, expiryBatchSize)
+		if err != nil {
+			return expired, err
+		}
+		for _, order := range orders {
+			expirer.release(ctx, order)
+		}
+		expired += len(orders)
+		if len(orders) < expiryBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// release puts the expired order's stock back and expires its checkout sessions.
+func (expirer *OrderExpirer) release(ctx context.Context, order *models.Order) {
+	if order.StockReserved {
+		stockItems := make([]productmodels.StockItem, 0, len(order.ProductsInfos))
+		for _, info := range order.ProductsInfos {
+			stockItems = append(stockItems, productmodels.StockItem{ProductID: info.ProductID, Quantity: info.Quantity})
+		}
+		err := expirer.inventory.ReleaseStock(ctx, stockItems)
+		if err != nil {
+			log.Println("Error releasing stock of expired order", order.ID, err)
+		}
+	}
+
+	err := expirer.checkout.ExpireCheckoutSessions(ctx, uint64(order.ID))
+	if err != nil {
+		log.Println("Error expiring checkout sessions of order", order.ID, err)
+	}
+}
@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"math/big"
+	"strings"
+
+	accountmodels "github.com/rasadov/EcommerceAPI/account/models"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/blob"
+	"github.com/rasadov/EcommerceAPI/pkg/pdf"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// InvoiceFormats maps the formats invoices are rendered in to their content types.
+var InvoiceFormats = map[string]string{
+	"pdf":  "application/pdf",
+	"html": "text/html; charset=utf-8",
+}
+
+var ErrInvalidInvoiceFormat = status.Error(codes.InvalidArgument, "invoices are rendered as pdf or html")
+
+// Accounts looks up the account an order was placed by, who the invoice is made out to.
+type Accounts interface {
+	GetAccount(ctx context.Context, id uint64) (*accountmodels.Account, error)
+}
+
+// Invoicer issues the invoices of paid orders and renders them. Rendered documents are
+// written to the store once and read back from it afterwards, so an invoice always comes
+// out the same.
+type Invoicer struct {
+	service  Service
+	store    blob.Store
+	accounts Accounts
+	seller   models.Party
+}
+
+func NewInvoicer(service Service, store blob.Store, accounts Accounts, seller models.Party) *Invoicer {
+	return &Invoicer{service, store, accounts, seller}
+}
+
+// Invoice returns the invoice of the paid order, issuing it and storing its documents the
+// first time it is asked for.
+func (invoicer *Invoicer) Invoice(ctx context.Context, order *models.Order) (*models.Invoice, error) {
+	invoice, err := invoicer.service.GetInvoice(ctx, uint64(order.ID))
+	if status.Code(err) != codes.NotFound {
+		return invoice, err
+	}
+	if !order.WasPaid() {
+		return nil, ErrOrderNotPaid
+	}
+
+	account, err := invoicer.accounts.GetAccount(ctx, order.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	buyer := models.Party{Name: account.Name, Email: account.Email, Address: order.ShippingAddress}
+	invoice, err = invoicer.service.IssueInvoice(ctx, order, invoicer.seller, buyer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Documents that cannot be stored now are rendered when they are first downloaded
+	for format := range InvoiceFormats {
+		_, _, err = invoicer.Document(ctx, invoice, format)
+		if err != nil {
+			log.Println("Error storing invoice", invoice.Number, format, err)
+		}
+	}
+	return invoice, nil
+}
+
+// Document returns the invoice rendered in format, with its content type.
+func (invoicer *Invoicer) Document(ctx context.Context, invoice *models.Invoice, format string) ([]byte, string, error) {
+	contentType, ok := InvoiceFormats[format]
+	if !ok {
+		return nil, "", ErrInvalidInvoiceFormat
+	}
+
+	key := invoice.DocumentKey(format)
+	data, err := invoicer.store.Get(ctx, key)
+	if !errors.Is(err, blob.ErrNotFound) {
+		return data, contentType, err
+	}
+
+	if format == "pdf" {
+		data = RenderInvoicePDF(invoice)
+	} else {
+		data, err = RenderInvoiceHTML(invoice)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	err = invoicer.store.Put(ctx, key, data)
+	if errors.Is(err, blob.ErrExists) {
+		// Rendered concurrently; the stored document is the one handed out
+		data, err = invoicer.store.Get(ctx, key)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return data, contentType, nil
+}
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"address": addressLines,
+	"percent": percent,
+	"date":    func(invoice *models.Invoice) string { return invoice.IssuedAt.UTC().Format("2006-01-02") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 4px 8px; text-align: left; }
+td.amount, th.amount { text-align: right; }
+.parties { display: flex; gap: 4em; margin: 1em 0 2em; }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Number}}</h1>
+<p>Issued {{date .}} for order {{.OrderID}}</p>
+<div class="parties">
+<div><h2>Seller</h2>
+<p>{{.Seller.Name}}{{range address .Seller.Address}}<br>{{.}}{{end}}{{if .Seller.Email}}<br>{{.Seller.Email}}{{end}}{{if .Seller.TaxID}}<br>Tax ID: {{.Seller.TaxID}}{{end}}</p>
+</div>
+<div><h2>Bill to</h2>
+<p>{{.Buyer.Name}}{{range address .Buyer.Address}}<br>{{.}}{{end}}{{if .Buyer.Email}}<br>{{.Buyer.Email}}{{end}}{{if .Buyer.TaxID}}<br>Tax ID: {{.Buyer.TaxID}}{{end}}</p>
+</div>
+</div>
+<table>
+<tr><th>Product</th><th class="amount">Quantity</th><th class="amount">Unit price</th><th class="amount">Amount</th><th>Tax</th></tr>
+{{range .Lines}}<tr><td>{{.Name}}</td><td class="amount">{{.Quantity}}</td><td class="amount">{{.UnitPrice}}</td><td class="amount">{{.Amount}}</td><td>{{if .TaxName}}{{.TaxName}} {{percent .TaxRate}}: {{.Tax}}{{end}}</td></tr>
+{{end}}</table>
+<table>
+<tr><td>Subtotal</td><td class="amount">{{.Subtotal}}</td></tr>
+{{if not .Discount.IsZero}}<tr><td>Discount</td><td class="amount">-{{.Discount}}</td></tr>
+{{end}}{{if not .Shipping.IsZero}}<tr><td>Shipping</td><td class="amount">{{.Shipping}}</td></tr>
+{{end}}{{if not .Tax.IsZero}}<tr><td>{{if .TaxIncluded}}Tax included{{else}}Tax{{end}}</td><td class="amount">{{.Tax}}</td></tr>
+{{end}}<tr><th>Total</th><th class="amount">{{.Total}}</th></tr>
+</table>
+</body>
+</html>
+`))
+
+// RenderInvoiceHTML renders the invoice as an HTML page.
+func RenderInvoiceHTML(invoice *models.Invoice) ([]byte, error) {
+	var buf bytes.Buffer
+	err := invoiceTemplate.Execute(&buf, invoice)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderInvoicePDF renders the invoice as a PDF document, with its lines in columns.
+func RenderInvoicePDF(invoice *models.Invoice) []byte {
+	lines := []pdf.Line{
+		{Text: "Invoice " + invoice.Number, Bold: true, Size: 16},
+		{},
+		{Text: fmt.Sprintf("Issued %s for order %d", invoice.IssuedAt.UTC().Format("2006-01-02"), invoice.OrderID)},
+		{},
+	}
+	for _, party := range []struct {
+		title string
+		party models.Party
+	}{{"Seller", invoice.Seller}, {"Bill to", invoice.Buyer}} {
+		lines = append(lines, pdf.Line{Text: party.title, Bold: true}, pdf.Line{Text: party.party.Name})
+		for _, line := range addressLines(party.party.Address) {
+			lines = append(lines, pdf.Line{Text: line})
+		}
+		if party.party.Email != "" {
+			lines = append(lines, pdf.Line{Text: party.party.Email})
+		}
+		if party.party.TaxID != "" {
+			lines = append(lines, pdf.Line{Text: "Tax ID: " + party.party.TaxID})
+		}
+		lines = append(lines, pdf.Line{})
+	}
+
+	lines = append(lines,
+		pdf.Line{Text: fmt.Sprintf("%-34s %8s %16s %16s", "Product", "Quantity", "Unit price", "Amount"), Bold: true},
+		pdf.Line{Text: strings.Repeat("-", 77)},
+	)
+	for _, line := range invoice.Lines {
+		lines = append(lines, pdf.Line{Text: fmt.Sprintf("%-34s %8d %16s %16s", truncate(line.Name, 34), line.Quantity, line.UnitPrice, line.Amount)})
+		if line.TaxName != "" {
+			lines = append(lines, pdf.Line{Text: fmt.Sprintf("  %s %s: %s", line.TaxName, percent(line.TaxRate), line.Tax)})
+		}
+	}
+	lines = append(lines, pdf.Line{Text: strings.Repeat("-", 77)})
+
+	total := func(label, amount string, bold bool) {
+		lines = append(lines, pdf.Line{Text: fmt.Sprintf("%-60s %16s", label, amount), Bold: bold})
+	}
+	total("Subtotal", invoice.Subtotal.String(), false)
+	if !invoice.Discount.IsZero() {
+		total("Discount", "-"+invoice.Discount.String(), false)
+	}
+	if !invoice.Shipping.IsZero() {
+		total("Shipping", invoice.Shipping.String(), false)
+	}
+	if !invoice.Tax.IsZero() {
+		label := "Tax"
+		if invoice.TaxIncluded {
+			label = "Tax included"
+		}
+		total(label, invoice.Tax.String(), false)
+	}
+	total("Total", invoice.Total.String(), true)
+	return pdf.Render(lines)
+}
+
+// addressLines formats an address as the lines of a letter, leaving out empty ones.
+func addressLines(address models.Address) []string {
+	var lines []string
+	for _, line := range []string{
+		address.Line1,
+		address.Line2,
+		strings.TrimSpace(address.PostalCode + " " + address.City),
+		strings.TrimSpace(address.Region + " " + address.Country),
+	} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// percent formats a decimal tax rate, e.g. "0.19", as a percentage.
+func percent(rate string) string {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok {
+		return rate
+	}
+	r.Mul(r, big.NewRat(100, 1))
+	return strings.TrimRight(strings.TrimRight(r.FloatString(4), "0"), ".") + "%"
+}
+
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "."
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"context"
+	"log"
+
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	productmodels "github.com/rasadov/EcommerceAPI/product/models"
+	shippingmodels "github.com/rasadov/EcommerceAPI/shipping/models"
+)
+
+// Catalog looks up products with their current prices and stock.
+type Catalog interface {
+	GetProducts(ctx context.Context, skip, take uint64, ids []string, query string) ([]productmodels.Product, error)
+}
+
+// ShippingQuotes prices delivering products with a shipping method.
+type ShippingQuotes interface {
+	QuoteMethod(ctx context.Context, method, country, currency string, quantities map[string]int) (*shippingmodels.Quote, error)
+}
+
+// Pricer prices orders at the products' current prices, converted to the order currency,
+// and quotes their shipping.
+type Pricer struct {
+	catalog   Catalog
+	shipping  ShippingQuotes
+	converter *money.Converter
+}
+
+func NewPricer(catalog Catalog, shipping ShippingQuotes, converter *money.Converter) *Pricer {
+	return &Pricer{catalog, shipping, converter}
+}
+
+// PriceProducts looks up the requested products, of which only the IDs and quantities are
+// read, and prices them in currency. Products that no longer exist or were requested
+// without a quantity are left out.
+func (pricer *Pricer) PriceProducts(ctx context.Context, requested []*models.OrderedProduct, currency string) ([]*models.OrderedProduct, error) {
+	var productIDs []string
+	for _, p := range requested {
+		productIDs = append(productIDs, p.ID)
+	}
+	orderedProducts, err := pricer.catalog.GetProducts(ctx, 0, 0, productIDs, "")
+	if err != nil {
+		log.Println("Error getting ordered products", err)
+		return nil, err
+	}
+
+	var products []*models.OrderedProduct
+
+	for _, p := range orderedProducts {
+		// Every line of an order is priced in the order currency
+		price, err := pricer.converter.Convert(p.Price, currency)
+		if err != nil {
+			log.Println("Error converting product price", err)
+			return nil, err
+		}
+		productObj := &models.OrderedProduct{
+			ID:          p.ID,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       price,
+			Quantity:    0,
+			Category:    p.Category,
+			TaxCategory: p.TaxCategory,
+			SellerID:    uint64(p.AccountID),
+		}
+		for _, requestProduct := range requested {
+			if requestProduct.ID == p.ID {
+				productObj.Quantity = requestProduct.Quantity
+				break
+			}
+		}
+
+		if productObj.Quantity != 0 {
+			products = append(products, productObj)
+		}
+	}
+	return products, nil
+}
+
+// QuoteShipping prices delivering products to address with the chosen shipping method.
+// Orders without a method are not charged for shipping.
+func (pricer *Pricer) QuoteShipping(ctx context.Context, method string, address models.Address, currency string, products []*models.OrderedProduct) (models.Shipping, error) {
+	if method == "" {
+		return models.Shipping{}, nil
+	}
+	address = address.Normalize()
+	if !address.Deliverable() {
+		return models.Shipping{}, ErrIncompleteAddress
+	}
+
+	quantities := make(map[string]int, len(products))
+	for _, p := range products {
+		quantities[p.ID] += int(p.Quantity)
+	}
+	quote, err := pricer.shipping.QuoteMethod(ctx, method, address.Country, currency, quantities)
+	if err != nil {
+		log.Println("Error quoting shipping", err)
+		return models.Shipping{}, err
+	}
+	return models.Shipping{Method: quote.Method, Cost: quote.Price}, nil
+}
+
+// Reorder returns the order's lines as they would be ordered again today: at the current
+// prices in the order's currency, and whether there is still enough stock for them.
+func (pricer *Pricer) Reorder(ctx context.Context, order *models.Order) ([]*models.ReorderLine, error) {
+	productIDs := make([]string, 0, len(order.Products))
+	for _, p := range order.Products {
+		productIDs = append(productIDs, p.ID)
+	}
+	current, err := pricer.catalog.GetProducts(ctx, 0, 0, productIDs, "")
+	if err != nil {
+		log.Println("Error getting reordered products", err)
+		return nil, err
+	}
+	byID := make(map[string]productmodels.Product, len(current))
+	for _, p := range current {
+		byID[p.ID] = p
+	}
+
+	currency := order.TotalPrice.Currency
+	lines := make([]*models.ReorderLine, 0, len(order.Products))
+	for _, ordered := range order.Products {
+		line := &models.ReorderLine{
+			ProductID:         ordered.ID,
+			Name:              ordered.Name,
+			Quantity:          ordered.Quantity,
+			UnitPrice:         money.Zero(currency),
+			PreviousUnitPrice: ordered.Price,
+		}
+		if p, ok := byID[ordered.ID]; ok {
+			line.Name = p.Name
+			line.UnitPrice, err = pricer.converter.Convert(p.Price, currency)
+			if err != nil {
+				log.Println("Error converting product price", err)
+				return nil, err
+			}
+			line.Available = p.Stock == nil || *p.Stock >= int(ordered.Quantity)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
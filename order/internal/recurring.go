@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const recurringBatchSize = 100
+
+var (
+	ErrInvalidInterval       = status.Error(codes.InvalidArgument, "recurring orders are placed weekly or monthly")
+	ErrInvalidSchedule       = status.Error(codes.InvalidArgument, "a recurring order needs products with a positive quantity")
+	ErrInvalidScheduleStatus = status.Error(codes.InvalidArgument, "recurring orders can be active, paused or cancelled")
+	ErrNothingToOrder        = status.Error(codes.FailedPrecondition, "none of the products are sold anymore")
+)
+
+// RecurringScheduler places the orders of recurring orders as they fall due, each through
+// the placement saga so that its stock is reserved and its checkout session opened.
+// Replicas running it side by side each place different runs.
+type RecurringScheduler struct {
+	service  Service
+	saga     *OrderSaga
+	pricer   *Pricer
+	accounts Accounts
+}
+
+func NewRecurringScheduler(service Service, saga *OrderSaga, pricer *Pricer, accounts Accounts) *RecurringScheduler {
+	return &RecurringScheduler{service, saga, pricer, accounts}
+}
+
+// Run places due recurring orders every interval until ctx is done.
+func (scheduler *RecurringScheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		placed, err := scheduler.PlaceDueOrders(ctx)
+		if err != nil {
+			log.Println("Failed to place recurring orders:", err)
+		}
+		if placed > 0 {
+			log.Printf("Placed %d recurring orders", placed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PlaceDueOrders places the order of every recurring order due now and returns how many
+// were placed. A run is claimed before its order is placed, so a run that is interrupted
+// is skipped rather than placed twice.
+func (scheduler *RecurringScheduler) PlaceDueOrders(ctx context.Context) (int, error) {
+	placed := 0
+	for {
+		due, err := scheduler.service.ClaimDueRecurringOrders(ctx, time.Now().UTC(), recurringBatchSize)
+		if err != nil {
+			return placed, err
+		}
+		for _, recurring := range due {
+			order, checkoutURL, err := scheduler.place(ctx, recurring)
+			if err != nil {
+				log.Println("Error placing recurring order", recurring.ID, err)
+			} else {
+				placed++
+			}
+			err = scheduler.service.RecordRecurringRun(ctx, recurring, order, checkoutURL, err)
+			if err != nil {
+				log.Println("Error recording run of recurring order", recurring.ID, err)
+			}
+		}
+		if len(due) < recurringBatchSize {
+			return placed, nil
+		}
+	}
+}
+
+// place prices the recurring order's products as of today and places its order.
+func (scheduler *RecurringScheduler) place(ctx context.Context, recurring *models.RecurringOrder) (*models.Order, string, error) {
+	account, err := scheduler.accounts.GetAccount(ctx, recurring.AccountID)
+	if err != nil {
+		return nil, "", err
+	}
+	products, err := scheduler.pricer.PriceProducts(ctx, recurring.OrderedProducts(), recurring.Currency)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(products) == 0 {
+		return nil, "", ErrNothingToOrder
+	}
+	shipping, err := scheduler.pricer.QuoteShipping(ctx, recurring.ShippingMethod, recurring.ShippingAddress, recurring.Currency, products)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return scheduler.saga.Place(ctx, Placement{
+		AccountID:   recurring.AccountID,
+		Email:       account.Email,
+		Name:        account.Name,
+		Currency:    recurring.Currency,
+		Products:    products,
+		Address:     recurring.ShippingAddress,
+		Shipping:    shipping,
+		RedirectURL: recurring.RedirectURL,
+	})
+}
@@ -2,17 +2,79 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrStatusConflict    = errors.New("order status changed concurrently")
+	ErrPromotionNotFound = errors.New("promotion not found")
+	ErrReturnNotFound    = errors.New("return not found")
+	ErrReturnConflict    = errors.New("return status changed concurrently")
+	ErrSagaNotFound      = errors.New("saga not found")
+	ErrSagaConflict      = errors.New("saga state changed concurrently")
+	ErrInvoiceNotFound   = errors.New("invoice not found")
+	ErrInvoiceExists     = errors.New("order already has an invoice")
+	ErrSubOrderNotFound  = errors.New("sub-order not found")
+	ErrSubOrderConflict  = errors.New("sub-order status changed concurrently")
+	ErrScheduleNotFound  = errors.New("recurring order not found")
+	ErrScheduleConflict  = errors.New("recurring order status changed concurrently")
+	ErrReviewNotFound    = errors.New("risk assessment not found")
+	ErrReviewConflict    = errors.New("risk review decided concurrently")
 )
 
 type Repository interface {
 	Close()
 	PutOrder(ctx context.Context, order *models.Order) error
 	GetOrdersForAccount(ctx context.Context, accountId uint64) ([]*models.Order, error)
-	UpdateOrderPaymentStatus(ctx context.Context, orderId uint64, status string) error
+	GetOrderLinesForProducts(ctx context.Context, productIds []string, skip, take uint64) ([]*models.OrderLine, error)
+	GetSalesForProducts(ctx context.Context, productIds []string) (*models.SalesSummary, error)
+	GetOrder(ctx context.Context, orderId uint64) (*models.Order, error)
+	ListOrders(ctx context.Context, filter models.OrderFilter, afterID uint, limit int) ([]*models.Order, error)
+	ExportOrders(ctx context.Context, from, to time.Time, fn func(*models.Order) error) error
+	GetOrdersWithoutLineSnapshots(ctx context.Context, limit int) ([]*models.Order, error)
+	SaveLineSnapshots(ctx context.Context, lines []*models.ProductsInfo) error
+	TransitionOrder(ctx context.Context, transition *models.StatusTransition) error
+	ExpireOrders(ctx context.Context, placedBefore time.Time, limit int, actor, reason string) ([]*models.Order, error)
+	CreatePromotion(ctx context.Context, promotion *models.Promotion) error
+	UpdatePromotion(ctx context.Context, promotion *models.Promotion) error
+	GetPromotion(ctx context.Context, promotionId uint) (*models.Promotion, error)
+	GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error)
+	ListPromotions(ctx context.Context, includeInactive bool) ([]*models.Promotion, error)
+	CountRedemptions(ctx context.Context, promotionId uint, accountId uint64) (int64, error)
+	CreateReturn(ctx context.Context, ret *models.Return) error
+	GetReturn(ctx context.Context, returnId uint) (*models.Return, error)
+	TransitionReturn(ctx context.Context, transition *models.ReturnTransition, refundId string) error
+	CreateSaga(ctx context.Context, saga *models.PlacementSaga) error
+	AdvanceSaga(ctx context.Context, saga *models.PlacementSaga, from models.SagaState) error
+	ListPendingSagas(ctx context.Context, afterID uint, limit int) ([]*models.PlacementSaga, error)
+	GetSagaForOrder(ctx context.Context, orderId uint) (*models.PlacementSaga, error)
+	CreateInvoice(ctx context.Context, invoice *models.Invoice) error
+	GetInvoiceForOrder(ctx context.Context, orderId uint64) (*models.Invoice, error)
+	GetSubOrder(ctx context.Context, subOrderId uint) (*models.SubOrder, error)
+	TransitionSubOrder(ctx context.Context, subOrder *models.SubOrder, from models.OrderStatus) error
+	ListSellerOrders(ctx context.Context, sellerId uint64, statuses []models.OrderStatus, afterID uint, limit int) ([]*models.SellerOrder, error)
+	CreateRecurringOrder(ctx context.Context, recurring *models.RecurringOrder) error
+	GetRecurringOrder(ctx context.Context, recurringOrderId uint) (*models.RecurringOrder, error)
+	ListRecurringOrders(ctx context.Context, accountId uint64) ([]*models.RecurringOrder, error)
+	UpdateRecurringOrder(ctx context.Context, recurring *models.RecurringOrder, from models.ScheduleStatus) error
+	ClaimDueRecurringOrders(ctx context.Context, now time.Time, limit int) ([]*models.RecurringOrder, error)
+	RecordRecurringRun(ctx context.Context, recurring *models.RecurringOrder) error
+	CreateRiskAssessment(ctx context.Context, assessment *models.RiskAssessment) error
+	GetRiskAssessment(ctx context.Context, orderId uint) (*models.RiskAssessment, error)
+	ListRiskAssessments(ctx context.Context, reviewStatus models.ReviewStatus, afterID uint, limit int) ([]*models.RiskAssessment, error)
+	DecideRiskReview(ctx context.Context, assessment *models.RiskAssessment) error
+	CountAccountAttempts(ctx context.Context, accountId uint64, since time.Time) (int64, error)
+	CountIPAttempts(ctx context.Context, clientIP string, since time.Time) (int64, error)
+	CountPaidOrders(ctx context.Context, accountId uint64) (int64, error)
 }
 
 type postgresRepository struct {
@@ -30,7 +92,21 @@ func NewPostgresRepository(db *gorm.DB) (Repository, error) {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&models.Order{}, &models.ProductsInfo{})
+	err = db.AutoMigrate(&models.Order{}, &models.ProductsInfo{}, &models.StatusTransition{},
+		&models.Promotion{}, &models.PromotionRedemption{}, &models.OrderDiscount{}, &models.OrderTax{},
+		&models.Return{}, &models.ReturnLine{}, &models.ReturnTransition{}, &models.PlacementSaga{},
+		&models.Invoice{}, &models.InvoiceCounter{}, &models.SubOrder{}, &models.RecurringOrder{}, &models.RecurringItem{},
+		&models.RiskAssessment{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = migrateLegacyStatuses(db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = migrateLegacyTotals(db)
 	if err != nil {
 		return nil, err
 	}
@@ -38,6 +114,85 @@ func NewPostgresRepository(db *gorm.DB) (Repository, error) {
 	return &postgresRepository{db}, nil
 }
 
+// migrateLegacyTotals copies totals stored by older versions as a floating point
+// total_price column into the exact total_price_amount column, and line prices stored as a
+// floating point unit_price column into unit_price_amount.
+func migrateLegacyTotals(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if tx.Migrator().HasColumn("order_products", "unit_price") {
+			err := tx.Exec("UPDATE order_products op SET unit_price_amount = ROUND(op.unit_price::numeric * POWER(10, " +
+				legacyExponent("o.total_price_currency") + ")) FROM orders o " +
+				"WHERE o.id = op.order_id AND op.unit_price IS NOT NULL").Error
+			if err != nil {
+				return err
+			}
+			err = tx.Migrator().DropColumn("order_products", "unit_price")
+			if err != nil {
+				return err
+			}
+		}
+
+		if tx.Migrator().HasColumn("orders", "total_price") {
+			err := tx.Exec("UPDATE orders SET total_price_amount = ROUND(total_price::numeric * POWER(10, " +
+				legacyExponent("total_price_currency") + ")), " +
+				"total_price_currency = " + legacyCurrency("total_price_currency") + " WHERE total_price IS NOT NULL").Error
+			if err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn("orders", "total_price")
+		}
+		return nil
+	})
+}
+
+// legacyCurrency falls back to the default currency for orders stored without one.
+func legacyCurrency(column string) string {
+	return "COALESCE(NULLIF(" + column + ", ''), '" + money.DefaultCurrency + "')"
+}
+
+// legacyExponent is the minor-unit exponent of the currency in column. Legacy amounts are in
+// major units, so they are scaled by it.
+func legacyExponent(column string) string {
+	exponent := "CASE " + legacyCurrency(column)
+	for _, code := range money.Currencies() {
+		if exp := money.Exponent(code); exp != 2 {
+			exponent += fmt.Sprintf(" WHEN '%s' THEN %d", code, exp)
+		}
+	}
+	return exponent + " ELSE 2 END"
+}
+
+// migrateLegacyStatuses moves orders written before statuses were typed, which only
+// tracked the free-text payment_status column, into the order state machine. Paid orders
+// are given the transition to paid their history would have recorded.
+func migrateLegacyStatuses(db *gorm.DB) error {
+	if !db.Migrator().HasColumn("orders", "payment_status") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE orders SET status = ? WHERE payment_status = ?", models.StatusPaid, "Success").Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`INSERT INTO order_status_transitions (order_id, "from", "to", actor, reason, created_at)
+			SELECT id, ?, ?, ?, ?, created_at FROM orders WHERE payment_status = ?`,
+			models.StatusPendingPayment, models.StatusPaid, "system", "paid before order statuses were tracked", "Success").Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("UPDATE orders SET status = ? WHERE status IS NULL OR status = ''", models.StatusPendingPayment).Error
+		if err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&models.Order{}, "payment_status")
+	})
+}
+
 func (repository *postgresRepository) Close() {
 	sqlDB, err := repository.db.DB()
 	if err == nil {
@@ -49,9 +204,34 @@ func (repository *postgresRepository) Close() {
 	}
 }
 
+// PutOrder stores the order with its lines and redeems its discounts. A promotion that
+// reached the account's usage limit in the meantime fails with models.ErrPromotionUsageLimit.
 func (repository *postgresRepository) PutOrder(ctx context.Context, order *models.Order) error {
 	tx := repository.db.WithContext(ctx).Begin()
 
+	// Locking the promotion serializes concurrent orders of the same code, so the count
+	// below includes redemptions of orders placed moments ago
+	for _, discount := range order.Discounts {
+		var promotion models.Promotion
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&promotion, discount.PromotionID).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if promotion.UsageLimitPerCustomer == 0 || order.AccountID == 0 {
+			continue
+		}
+		used, err := countRedemptions(tx, promotion.ID, order.AccountID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if used >= int64(promotion.UsageLimitPerCustomer) {
+			tx.Rollback()
+			return models.ErrPromotionUsageLimit
+		}
+	}
+
 	err := tx.WithContext(ctx).Create(&order).Error
 	if err != nil {
 		tx.Rollback()
@@ -60,15 +240,35 @@ func (repository *postgresRepository) PutOrder(ctx context.Context, order *model
 
 	for _, product := range order.Products {
 		orderedProduct := models.ProductsInfo{
-			OrderID:   order.ID,
-			ProductID: product.ID,
-			Quantity:  int(product.Quantity),
+			OrderID:     order.ID,
+			ProductID:   product.ID,
+			SellerID:    product.SellerID,
+			Quantity:    int(product.Quantity),
+			Name:        product.Name,
+			Description: product.Description,
+			UnitPrice:   product.Price,
+			LineTotal:   product.LineTotal(),
 		}
 		err = tx.Create(&orderedProduct).Error
 		if err != nil {
 			tx.Rollback()
 			return err
 		}
+		order.ProductsInfos = append(order.ProductsInfos, orderedProduct)
+	}
+
+	// Discounts are created with the order; record the promotions they redeem
+	for _, discount := range order.Discounts {
+		err = tx.Create(&models.PromotionRedemption{
+			PromotionID: discount.PromotionID,
+			AccountID:   order.AccountID,
+			OrderID:     order.ID,
+			CreatedAt:   order.CreatedAt,
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 	if err = tx.Commit().Error; err != nil {
 		return err
@@ -79,22 +279,819 @@ func (repository *postgresRepository) PutOrder(ctx context.Context, order *model
 
 func (repository *postgresRepository) GetOrdersForAccount(ctx context.Context, accountId uint64) ([]*models.Order, error) {
 	var orders []*models.Order
-	err := repository.db.WithContext(ctx).
-		Table("orders o").
-		Select("o.id, o.created_at, o.account_id, o.total_price::money::numeric::float8, op.product_id, op.quantity").
-		Joins("JOIN order_products op on o.id = op.order_id").
-		Where("o.account_id = ?", accountId).
-		Order("o.id").
-		Scan(&orders).Error
+	err := repository.withLines(ctx).
+		Where("account_id = ?", accountId).
+		Order("id").
+		Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		loadProducts(order)
+	}
+	return orders, nil
+}
+
+// withLines preloads the lines, status history, discounts, taxes, returns and sub-orders of
+// the orders queried.
+func (repository *postgresRepository) withLines(ctx context.Context) *gorm.DB {
+	return preloadLines(repository.db.WithContext(ctx))
+}
+
+func preloadLines(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("ProductsInfos", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("History", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at, id")
+		}).
+		Preload("Discounts", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("Taxes", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("Returns", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("Returns.Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("Returns.History", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at, id")
+		}).
+		Preload("SubOrders", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		})
+}
+
+// loadProducts fills the order's products from the snapshots on its lines.
+func loadProducts(order *models.Order) {
+	order.Products = make([]*models.OrderedProduct, 0, len(order.ProductsInfos))
+	for _, info := range order.ProductsInfos {
+		order.Products = append(order.Products, info.OrderedProduct())
+	}
+}
+
+// GetOrderLinesForProducts returns the lines of paid orders for the products, newest first.
+func (repository *postgresRepository) GetOrderLinesForProducts(ctx context.Context, productIds []string, skip, take uint64) ([]*models.OrderLine, error) {
+	var lines []*models.OrderLine
+	err := repository.paidLines(ctx, productIds).
+		Select("op.order_id, o.account_id, o.created_at, op.product_id, op.name, op.quantity, op.unit_price_amount, op.unit_price_currency").
+		Order("o.created_at DESC, op.id").
+		Offset(int(skip)).
+		Limit(int(take)).
+		Scan(&lines).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// GetSalesForProducts adds up the lines of paid orders for the products, per product and
+// currency they sold in.
+func (repository *postgresRepository) GetSalesForProducts(ctx context.Context, productIds []string) (*models.SalesSummary, error) {
+	var orderCount int64
+	err := repository.paidLines(ctx, productIds).
+		Select("COUNT(DISTINCT op.order_id)").
+		Scan(&orderCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.SalesSummary{OrderCount: int(orderCount)}
+	err = repository.paidLines(ctx, productIds).
+		Select("op.product_id, COALESCE(op.unit_price_currency, '') AS currency, SUM(op.quantity) AS units_sold, " +
+			"COALESCE(SUM(op.line_total_amount), 0) AS revenue, " +
+			"SUM(CASE WHEN COALESCE(op.unit_price_currency, '') = '' THEN op.quantity ELSE 0 END) AS unpriced_units").
+		Group("op.product_id, COALESCE(op.unit_price_currency, '')").
+		Order("op.product_id, currency").
+		Scan(&summary.Products).Error
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// paidLines selects the lines for the products that belong to orders in one of the
+// SoldStatuses, joined as op with their order as o.
+func (repository *postgresRepository) paidLines(ctx context.Context, productIds []string) *gorm.DB {
+	return repository.db.WithContext(ctx).
+		Table("order_products op").
+		Joins("JOIN orders o on o.id = op.order_id").
+		Where("op.product_id IN ?", productIds).
+		Where("o.status IN ?", models.SoldStatuses)
+}
+
+func (repository *postgresRepository) GetOrder(ctx context.Context, orderId uint64) (*models.Order, error) {
+	var order models.Order
+	err := repository.withLines(ctx).First(&order, orderId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	loadProducts(&order)
+	return &order, nil
+}
+
+// ListOrders returns up to limit orders matching filter, newest first. A non-zero
+// afterID continues a previous listing with the orders placed before that order.
+func (repository *postgresRepository) ListOrders(ctx context.Context, filter models.OrderFilter, afterID uint, limit int) ([]*models.Order, error) {
+	query := repository.withLines(ctx)
+	if filter.AccountID != 0 {
+		query = query.Where("account_id = ?", filter.AccountID)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedBefore)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.MinTotal != nil {
+		query = query.Where("total_price_currency = ? AND total_price_amount >= ?", filter.MinTotal.Currency, filter.MinTotal.Amount)
+	}
+	if filter.MaxTotal != nil {
+		query = query.Where("total_price_currency = ? AND total_price_amount <= ?", filter.MaxTotal.Currency, filter.MaxTotal.Amount)
+	}
+	if afterID != 0 {
+		query = query.Where("id < ?", afterID)
+	}
+
+	var orders []*models.Order
+	err := query.Order("id DESC").Limit(limit).Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		loadProducts(order)
+	}
+	return orders, nil
+}
+
+// exportBatchSize is how many orders ExportOrders loads at a time.
+const exportBatchSize = 100
+
+// ExportOrders calls fn with every order placed from from until to, oldest first, and stops
+// at the first error fn returns. Orders are loaded in batches, so the whole range is never
+// held in memory at once.
+func (repository *postgresRepository) ExportOrders(ctx context.Context, from, to time.Time, fn func(*models.Order) error) error {
+	var afterID uint
+	for {
+		var orders []*models.Order
+		err := repository.withLines(ctx).
+			Where("created_at >= ? AND created_at < ? AND id > ?", from, to, afterID).
+			Order("id").
+			Limit(exportBatchSize).
+			Find(&orders).Error
+		if err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			loadProducts(order)
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+		if len(orders) < exportBatchSize {
+			return nil
+		}
+		afterID = orders[len(orders)-1].ID
+	}
+}
+
+// GetOrdersWithoutLineSnapshots returns up to limit orders placed before lines kept a
+// product snapshot, with their lines.
+func (repository *postgresRepository) GetOrdersWithoutLineSnapshots(ctx context.Context, limit int) ([]*models.Order, error) {
+	var orders []*models.Order
+	err := repository.withLines(ctx).
+		Where("id IN (?)", repository.db.
+			Table("order_products").
+			Select("order_id").
+			Where("unit_price_currency IS NULL OR unit_price_currency = ''")).
+		Order("id").
+		Limit(limit).
+		Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		loadProducts(order)
+	}
+	return orders, nil
+}
 
+// SaveLineSnapshots stores the product snapshots of existing order lines.
+func (repository *postgresRepository) SaveLineSnapshots(ctx context.Context, lines []*models.ProductsInfo) error {
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, line := range lines {
+			err := tx.Model(&models.ProductsInfo{}).
+				Where("id = ?", line.ID).
+				Updates(map[string]any{
+					"name":                line.Name,
+					"description":         line.Description,
+					"unit_price_amount":   line.UnitPrice.Amount,
+					"unit_price_currency": line.UnitPrice.Currency,
+					"line_total_amount":   line.LineTotal.Amount,
+					"line_total_currency": line.LineTotal.Currency,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// TransitionOrder moves the order from transition.From to transition.To and records
+// the transition, taking along the sub-orders when the status cascades. It fails with
+// ErrStatusConflict if the order is no longer in transition.From, so concurrent updates
+// cannot both succeed.
+func (repository *postgresRepository) TransitionOrder(ctx context.Context, transition *models.StatusTransition) error {
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ?", transition.OrderID, transition.From).
+			Update("status", transition.To)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStatusConflict
+		}
+		err := cascadeToSubOrders(tx, []uint{transition.OrderID}, transition.To, transition.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return tx.Create(transition).Error
+	})
+}
+
+// cascadeToSubOrders moves the sub-orders of the orders that can still get to status to
+// along with them.
+func cascadeToSubOrders(tx *gorm.DB, orderIds []uint, to models.OrderStatus, at time.Time) error {
+	if !to.Cascades() {
+		return nil
+	}
+	return tx.Model(&models.SubOrder{}).
+		Where("order_id IN ? AND status IN ?", orderIds, models.StatusesReaching(to)).
+		Updates(map[string]any{"status": to, "updated_at": at}).Error
+}
+
+// ExpireOrders cancels up to limit orders still awaiting payment that were placed before
+// placedBefore, recording the transition with actor and reason, and returns them. Orders
+// held for risk review are left alone, and those approved count from their approval. The
+// orders are locked while they are cancelled and orders locked by another replica are
+// skipped, so every order is expired exactly once.
+func (repository *postgresRepository) ExpireOrders(ctx context.Context, placedBefore time.Time, limit int, actor, reason string) ([]*models.Order, error) {
+	var orders []*models.Order
+	err := repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Model(&models.Order{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND created_at < ?", models.StatusPendingPayment, placedBefore).
+			Where("NOT EXISTS (SELECT 1 FROM order_risk_assessments r WHERE r.order_id = orders.id AND (r.review_status = ? OR r.reviewed_at >= ?))",
+				models.ReviewPending, placedBefore).
+			Order("id").
+			Limit(limit).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		err = tx.Model(&models.Order{}).Where("id IN ?", ids).Update("status", models.StatusCancelled).Error
+		if err != nil {
+			return err
+		}
+		expiredAt := time.Now().UTC()
+		err = cascadeToSubOrders(tx, ids, models.StatusCancelled, expiredAt)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			err = tx.Create(&models.StatusTransition{
+				OrderID:   id,
+				From:      models.StatusPendingPayment,
+				To:        models.StatusCancelled,
+				Actor:     actor,
+				Reason:    reason,
+				CreatedAt: expiredAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return preloadLines(tx).Where("id IN ?", ids).Order("id").Find(&orders).Error
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	for _, order := range orders {
+		loadProducts(order)
+	}
 	return orders, nil
 }
 
-func (repository *postgresRepository) UpdateOrderPaymentStatus(ctx context.Context, orderId uint64, status string) error {
-	return repository.db.WithContext(ctx).Table("orders o").
-		Where("id = ?", orderId).
-		Update("payment_status", status).Error
+func (repository *postgresRepository) CreatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	return repository.db.WithContext(ctx).Create(promotion).Error
+}
+
+func (repository *postgresRepository) UpdatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	return repository.db.WithContext(ctx).Save(promotion).Error
+}
+
+func (repository *postgresRepository) GetPromotion(ctx context.Context, promotionId uint) (*models.Promotion, error) {
+	var promotion models.Promotion
+	err := repository.db.WithContext(ctx).First(&promotion, promotionId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPromotionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+func (repository *postgresRepository) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	var promotion models.Promotion
+	err := repository.db.WithContext(ctx).Where("code = ?", code).First(&promotion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPromotionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+func (repository *postgresRepository) ListPromotions(ctx context.Context, includeInactive bool) ([]*models.Promotion, error) {
+	query := repository.db.WithContext(ctx)
+	if !includeInactive {
+		query = query.Where("active = ?", true)
+	}
+
+	var promotions []*models.Promotion
+	err := query.Order("id").Find(&promotions).Error
+	if err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+// CountRedemptions counts the account's orders that used the promotion. Orders that were
+// cancelled or refunded give the use back.
+func (repository *postgresRepository) CountRedemptions(ctx context.Context, promotionId uint, accountId uint64) (int64, error) {
+	return countRedemptions(repository.db.WithContext(ctx), promotionId, accountId)
+}
+
+func countRedemptions(db *gorm.DB, promotionId uint, accountId uint64) (int64, error) {
+	var count int64
+	err := db.
+		Table("promotion_redemptions r").
+		Joins("JOIN orders o ON o.id = r.order_id").
+		Where("r.promotion_id = ? AND r.account_id = ?", promotionId, accountId).
+		Where("o.status NOT IN ?", []models.OrderStatus{models.StatusCancelled, models.StatusRefunded}).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateReturn stores the return together with its lines and history.
+func (repository *postgresRepository) CreateReturn(ctx context.Context, ret *models.Return) error {
+	return repository.db.WithContext(ctx).Create(ret).Error
+}
+
+func (repository *postgresRepository) GetReturn(ctx context.Context, returnId uint) (*models.Return, error) {
+	var ret models.Return
+	err := repository.db.WithContext(ctx).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("History", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at, id")
+		}).
+		First(&ret, returnId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReturnNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// TransitionReturn moves a return to the transition's status, provided it is still in the
+// status the transition starts from, and records the step. A non-empty refundId is stored
+// with it.
+func (repository *postgresRepository) TransitionReturn(ctx context.Context, transition *models.ReturnTransition, refundId string) error {
+	updates := map[string]any{"status": transition.To}
+	if refundId != "" {
+		updates["refund_id"] = refundId
+	}
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Return{}).
+			Where("id = ? AND status = ?", transition.ReturnID, transition.From).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrReturnConflict
+		}
+		return tx.Create(transition).Error
+	})
+}
+
+func (repository *postgresRepository) CreateSaga(ctx context.Context, saga *models.PlacementSaga) error {
+	return repository.db.WithContext(ctx).Create(saga).Error
+}
+
+// AdvanceSaga stores the saga's state and progress, provided it is still in state from. It
+// fails with ErrSagaConflict otherwise, so a saga is only ever moved on by one replica.
+func (repository *postgresRepository) AdvanceSaga(ctx context.Context, saga *models.PlacementSaga, from models.SagaState) error {
+	saga.UpdatedAt = time.Now().UTC()
+	result := repository.db.WithContext(ctx).Model(&models.PlacementSaga{}).
+		Where("id = ? AND state = ?", saga.ID, from).
+		Updates(map[string]any{
+			"state":            saga.State,
+			"order_id":         saga.OrderID,
+			"checkout_url":     saga.CheckoutURL,
+			"paid_with_credit": saga.PaidWithCredit,
+			"error":            saga.Error,
+			"updated_at":       saga.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSagaConflict
+	}
+	return nil
+}
+
+// ListPendingSagas returns up to limit sagas that have not ended, after afterID in id order.
+func (repository *postgresRepository) ListPendingSagas(ctx context.Context, afterID uint, limit int) ([]*models.PlacementSaga, error) {
+	var sagas []*models.PlacementSaga
+	err := repository.db.WithContext(ctx).
+		Where("state IN ? AND id > ?", models.PendingSagaStates, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&sagas).Error
+	if err != nil {
+		return nil, err
+	}
+	return sagas, nil
+}
+
+func (repository *postgresRepository) GetSagaForOrder(ctx context.Context, orderId uint) (*models.PlacementSaga, error) {
+	var saga models.PlacementSaga
+	err := repository.db.WithContext(ctx).Where("order_id = ?", orderId).First(&saga).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSagaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &saga, nil
+}
+
+// CreateInvoice numbers the invoice with the next number of its series and stores it. The
+// series counter is locked until the invoice is stored, so numbers are given out in order
+// and a failed insert does not leave a gap. It fails with ErrInvoiceExists if the order
+// already has an invoice.
+func (repository *postgresRepository) CreateInvoice(ctx context.Context, invoice *models.Invoice) error {
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		err := tx.Model(&models.Invoice{}).Where("order_id = ?", invoice.OrderID).Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrInvoiceExists
+		}
+
+		series := models.InvoiceSeries(invoice.IssuedAt)
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.InvoiceCounter{Series: series}).Error
+		if err != nil {
+			return err
+		}
+		var counter models.InvoiceCounter
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&counter, "series = ?", series).Error
+		if err != nil {
+			return err
+		}
+		counter.Last++
+		err = tx.Model(&models.InvoiceCounter{}).Where("series = ?", series).Update("last", counter.Last).Error
+		if err != nil {
+			return err
+		}
+
+		invoice.Number = models.InvoiceNumber(series, counter.Last)
+		return tx.Create(invoice).Error
+	})
+}
+
+func (repository *postgresRepository) GetInvoiceForOrder(ctx context.Context, orderId uint64) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := repository.db.WithContext(ctx).Where("order_id = ?", orderId).First(&invoice).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (repository *postgresRepository) GetSubOrder(ctx context.Context, subOrderId uint) (*models.SubOrder, error) {
+	var subOrder models.SubOrder
+	err := repository.db.WithContext(ctx).First(&subOrder, subOrderId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subOrder, nil
+}
+
+// TransitionSubOrder stores the sub-order's status, provided it is still in status from. It
+// fails with ErrSubOrderConflict otherwise.
+func (repository *postgresRepository) TransitionSubOrder(ctx context.Context, subOrder *models.SubOrder, from models.OrderStatus) error {
+	subOrder.UpdatedAt = time.Now().UTC()
+	result := repository.db.WithContext(ctx).Model(&models.SubOrder{}).
+		Where("id = ? AND status = ?", subOrder.ID, from).
+		Updates(map[string]any{"status": subOrder.Status, "updated_at": subOrder.UpdatedAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSubOrderConflict
+	}
+	return nil
+}
+
+// ListSellerOrders returns up to limit of the seller's sub-orders, newest first, with the
+// seller's lines of each order. A non-empty statuses only lists sub-orders in those
+// statuses, and a non-zero afterID continues a previous listing.
+func (repository *postgresRepository) ListSellerOrders(ctx context.Context, sellerId uint64, statuses []models.OrderStatus, afterID uint, limit int) ([]*models.SellerOrder, error) {
+	query := repository.db.WithContext(ctx).Where("seller_id = ?", sellerId)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if afterID != 0 {
+		query = query.Where("id < ?", afterID)
+	}
+	var subOrders []*models.SubOrder
+	err := query.Order("id DESC").Limit(limit).Find(&subOrders).Error
+	if err != nil || len(subOrders) == 0 {
+		return nil, err
+	}
+
+	orderIds := make([]uint, 0, len(subOrders))
+	for _, sub := range subOrders {
+		orderIds = append(orderIds, sub.OrderID)
+	}
+	var orders []*models.Order
+	err = repository.db.WithContext(ctx).Where("id IN ?", orderIds).Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	var lines []models.ProductsInfo
+	err = repository.db.WithContext(ctx).
+		Where("order_id IN ? AND seller_id = ?", orderIds, sellerId).
+		Order("id").
+		Find(&lines).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sellerOrders := make([]*models.SellerOrder, 0, len(subOrders))
+	for _, sub := range subOrders {
+		sellerOrder := &models.SellerOrder{SubOrder: *sub}
+		for _, order := range orders {
+			if order.ID == sub.OrderID {
+				sellerOrder.AccountID = order.AccountID
+				sellerOrder.ShippingAddress = order.ShippingAddress
+				sellerOrder.ShippingMethod = order.ShippingMethod
+				break
+			}
+		}
+		for _, line := range lines {
+			if line.OrderID == sub.OrderID {
+				sellerOrder.Lines = append(sellerOrder.Lines, line)
+			}
+		}
+		sellerOrders = append(sellerOrders, sellerOrder)
+	}
+	return sellerOrders, nil
+}
+
+func (repository *postgresRepository) CreateRecurringOrder(ctx context.Context, recurring *models.RecurringOrder) error {
+	return repository.db.WithContext(ctx).Create(recurring).Error
+}
+
+func (repository *postgresRepository) GetRecurringOrder(ctx context.Context, recurringOrderId uint) (*models.RecurringOrder, error) {
+	var recurring models.RecurringOrder
+	err := repository.withItems(ctx).First(&recurring, recurringOrderId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &recurring, nil
+}
+
+// ListRecurringOrders returns the account's recurring orders, oldest first.
+func (repository *postgresRepository) ListRecurringOrders(ctx context.Context, accountId uint64) ([]*models.RecurringOrder, error) {
+	var recurring []*models.RecurringOrder
+	err := repository.withItems(ctx).
+		Where("account_id = ?", accountId).
+		Order("id").
+		Find(&recurring).Error
+	if err != nil {
+		return nil, err
+	}
+	return recurring, nil
+}
+
+func (repository *postgresRepository) withItems(ctx context.Context) *gorm.DB {
+	return repository.db.WithContext(ctx).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
+
+// UpdateRecurringOrder saves the recurring order's status and next run, provided its status
+// is still from.
+func (repository *postgresRepository) UpdateRecurringOrder(ctx context.Context, recurring *models.RecurringOrder, from models.ScheduleStatus) error {
+	recurring.UpdatedAt = time.Now().UTC()
+	result := repository.db.WithContext(ctx).Model(&models.RecurringOrder{}).
+		Where("id = ? AND status = ?", recurring.ID, from).
+		Updates(map[string]any{"status": recurring.Status, "next_run_at": recurring.NextRunAt, "updated_at": recurring.UpdatedAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrScheduleConflict
+	}
+	return nil
+}
+
+// ClaimDueRecurringOrders returns up to limit active recurring orders due at now, with their
+// next run moved past now. Rows locked by another replica are skipped, so each run is
+// claimed once.
+func (repository *postgresRepository) ClaimDueRecurringOrders(ctx context.Context, now time.Time, limit int) ([]*models.RecurringOrder, error) {
+	var due []*models.RecurringOrder
+	err := repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_run_at <= ?", models.ScheduleActive, now).
+			Order("next_run_at, id").
+			Limit(limit).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		byID := make(map[uint]*models.RecurringOrder, len(due))
+		ids := make([]uint, 0, len(due))
+		for _, recurring := range due {
+			recurring.Advance(now)
+			recurring.UpdatedAt = now
+			err = tx.Model(&models.RecurringOrder{}).
+				Where("id = ?", recurring.ID).
+				Updates(map[string]any{"next_run_at": recurring.NextRunAt, "updated_at": recurring.UpdatedAt}).Error
+			if err != nil {
+				return err
+			}
+			byID[recurring.ID] = recurring
+			ids = append(ids, recurring.ID)
+		}
+
+		var items []*models.RecurringItem
+		err = tx.Where("recurring_order_id IN ?", ids).Order("id").Find(&items).Error
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			recurring := byID[item.RecurringOrderID]
+			recurring.Items = append(recurring.Items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// RecordRecurringRun saves the outcome of the recurring order's last run.
+func (repository *postgresRepository) RecordRecurringRun(ctx context.Context, recurring *models.RecurringOrder) error {
+	return repository.db.WithContext(ctx).Model(&models.RecurringOrder{}).
+		Where("id = ?", recurring.ID).
+		Updates(map[string]any{
+			"last_order_id":     recurring.LastOrderID,
+			"last_checkout_url": recurring.LastCheckoutURL,
+			"last_error":        recurring.LastError,
+		}).Error
+}
+
+// CreateRiskAssessment stores the assessment, and marks its order as held when it is held
+// for review.
+func (repository *postgresRepository) CreateRiskAssessment(ctx context.Context, assessment *models.RiskAssessment) error {
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(assessment).Error
+		if err != nil || assessment.ReviewStatus == models.ReviewNone {
+			return err
+		}
+		return tx.Model(&models.Order{}).Where("id = ?", assessment.OrderID).Update("risk_review", assessment.ReviewStatus).Error
+	})
+}
+
+func (repository *postgresRepository) GetRiskAssessment(ctx context.Context, orderId uint) (*models.RiskAssessment, error) {
+	var assessment models.RiskAssessment
+	err := repository.db.WithContext(ctx).Where("order_id = ?", orderId).First(&assessment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &assessment, nil
+}
+
+// ListRiskAssessments returns up to limit assessments of orders held for review whose
+// review is in reviewStatus, oldest first. A non-zero afterID continues a previous listing.
+func (repository *postgresRepository) ListRiskAssessments(ctx context.Context, reviewStatus models.ReviewStatus, afterID uint, limit int) ([]*models.RiskAssessment, error) {
+	var assessments []*models.RiskAssessment
+	err := repository.db.WithContext(ctx).
+		Where("review_status = ? AND id > ?", reviewStatus, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&assessments).Error
+	if err != nil {
+		return nil, err
+	}
+	return assessments, nil
+}
+
+// DecideRiskReview stores the review decided on the assessment, and on its order, provided
+// it is still pending. It fails with ErrReviewConflict otherwise, so a review is only
+// decided once.
+func (repository *postgresRepository) DecideRiskReview(ctx context.Context, assessment *models.RiskAssessment) error {
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RiskAssessment{}).
+			Where("id = ? AND review_status = ?", assessment.ID, models.ReviewPending).
+			Updates(map[string]any{
+				"review_status": assessment.ReviewStatus,
+				"reviewed_by":   assessment.ReviewedBy,
+				"review_note":   assessment.ReviewNote,
+				"reviewed_at":   assessment.ReviewedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrReviewConflict
+		}
+		return tx.Model(&models.Order{}).Where("id = ?", assessment.OrderID).Update("risk_review", assessment.ReviewStatus).Error
+	})
+}
+
+// CountAccountAttempts counts the orders the account placed since since that were screened.
+func (repository *postgresRepository) CountAccountAttempts(ctx context.Context, accountId uint64, since time.Time) (int64, error) {
+	var count int64
+	err := repository.db.WithContext(ctx).Model(&models.RiskAssessment{}).
+		Where("account_id = ? AND created_at >= ?", accountId, since).
+		Count(&count).Error
+	return count, err
+}
+
+// CountIPAttempts counts the orders placed from clientIP since since that were screened.
+func (repository *postgresRepository) CountIPAttempts(ctx context.Context, clientIP string, since time.Time) (int64, error) {
+	var count int64
+	err := repository.db.WithContext(ctx).Model(&models.RiskAssessment{}).
+		Where("client_ip = ? AND created_at >= ?", clientIP, since).
+		Count(&count).Error
+	return count, err
+}
+
+// CountPaidOrders counts the account's orders that were paid and kept: neither cancelled
+// nor refunded since.
+func (repository *postgresRepository) CountPaidOrders(ctx context.Context, accountId uint64) (int64, error) {
+	var count int64
+	err := repository.db.WithContext(ctx).Model(&models.Order{}).
+		Where("account_id = ? AND status IN ?", accountId,
+			[]models.OrderStatus{models.StatusPaid, models.StatusFulfilling, models.StatusShipped, models.StatusDelivered}).
+		Count(&count).Error
+	return count, err
 }
@@ -0,0 +1,14 @@
+package internal
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrOrderDeclined tells customers no more than that their order was blocked, so that
+	// the rules cannot be probed
+	ErrOrderDeclined       = status.Error(codes.PermissionDenied, "the order was declined")
+	ErrInvalidReviewStatus = status.Error(codes.InvalidArgument, "risk reviews can be pending, approved or rejected")
+	ErrNotInReview         = status.Error(codes.FailedPrecondition, "the order is not waiting for a risk review")
+)
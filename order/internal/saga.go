@@ -0,0 +1,419 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/order/risk"
+	paymentpb "github.com/rasadov/EcommerceAPI/payment/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/kafka"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	productmodels "github.com/rasadov/EcommerceAPI/product/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// sagaStepTimeout is how long a placement may stay mid-step before it is taken to have
+	// been interrupted
+	sagaStepTimeout = time.Minute
+	// compensationTimeout bounds undoing a placement, which goes on after the request that
+	// started it was cancelled
+	compensationTimeout = 10 * time.Second
+	sagaBatchSize       = 100
+)
+
+// Inventory reserves and releases the stock of ordered products.
+type Inventory interface {
+	ReserveStock(ctx context.Context, items []productmodels.StockItem) error
+	ReleaseStock(ctx context.Context, items []productmodels.StockItem) error
+}
+
+// CheckoutSessions opens and expires the payment provider's checkout sessions of an order,
+// and puts gift cards and store credit towards it. Expiring the sessions of an unpaid order
+// gives its credit back.
+type CheckoutSessions interface {
+	CreateCheckoutSession(ctx context.Context, orderId, userId int, email, name, redirectUrl, currency string, products []*paymentpb.CartItem) (string, error)
+	ExpireCheckoutSessions(ctx context.Context, orderId uint64) error
+	ApplyCredit(ctx context.Context, orderId, userId uint64, giftCardCode string, useStoreCredit bool) (money.Money, error)
+	CaptureCredit(ctx context.Context, orderId uint64) error
+}
+
+// Placement is an order to place, with its products priced and its shipping quoted.
+type Placement struct {
+	AccountID  uint64
+	Email      string
+	Name       string
+	Currency   string
+	Products   []*models.OrderedProduct
+	CouponCode string
+	Address    models.Address
+	Shipping   models.Shipping
+	// RedirectURL is where the checkout session returns to; no session is opened when empty
+	RedirectURL string
+	Origin      models.OrderOrigin
+	// Credit is put towards the order before what is left is paid at checkout
+	Credit models.OrderCredit
+}
+
+// OrderSaga places orders in steps: it reserves their stock, writes the order, puts the
+// customer's credit towards it, screens it and opens its checkout session, or pays it right
+// away when the credit covers it. Every step is recorded, and a failed or interrupted
+// placement is undone by releasing the stock and cancelling the order. Orders screening
+// blocks are undone the same way, while those it holds for review wait for an admin before
+// their checkout session is opened. A placed order's saga ends once the order is paid, or
+// cancelled, which is how OrderExpirer undoes orders that are not paid in time.
+type OrderSaga struct {
+	service   Service
+	inventory Inventory
+	checkout  CheckoutSessions
+	screening *risk.Engine
+}
+
+// NewOrderSaga returns a saga placing orders. Every order is allowed when screening is nil.
+func NewOrderSaga(service Service, inventory Inventory, checkout CheckoutSessions, screening *risk.Engine) *OrderSaga {
+	return &OrderSaga{service, inventory, checkout, screening}
+}
+
+// Place places the order and returns it with the URL of its checkout session, which is
+// empty when no redirect URL was given or the order is held for review. Whatever was done
+// before a step failed is undone.
+func (saga *OrderSaga) Place(ctx context.Context, placement Placement) (*models.Order, string, error) {
+	state := &models.PlacementSaga{
+		AccountID: placement.AccountID,
+		State:     models.SagaStarted,
+	}
+	for _, p := range placement.Products {
+		state.Items = append(state.Items, models.SagaItem{ProductID: p.ID, Quantity: int(p.Quantity)})
+	}
+	err := saga.service.CreateSaga(ctx, state)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = saga.inventory.ReserveStock(ctx, stockItems(state.Items))
+	if err != nil {
+		log.Println("Error reserving stock", err)
+		// Unless the product service refused the reservation, it may have gone through
+		saga.compensate(ctx, state, nil, !reservationRefused(err), "stock could not be reserved")
+		return nil, "", err
+	}
+	err = saga.advance(ctx, state, models.SagaStockReserved)
+	if err != nil {
+		saga.compensate(ctx, state, nil, true, "placement could not be recorded")
+		return nil, "", err
+	}
+
+	order, err := saga.service.PostOrder(ctx, placement.AccountID, placement.Currency, placement.Products,
+		placement.CouponCode, placement.Address, placement.Shipping)
+	if err != nil {
+		log.Println("Error posting order", err)
+		saga.compensate(ctx, state, nil, true, "order could not be placed")
+		return nil, "", err
+	}
+	state.OrderID = order.ID
+	err = saga.advance(ctx, state, models.SagaOrderPlaced)
+	if err != nil {
+		saga.compensate(ctx, state, order, true, "placement could not be recorded")
+		return nil, "", err
+	}
+
+	if placement.Credit.Requested() {
+		remaining, err := saga.checkout.ApplyCredit(ctx, uint64(order.ID), order.AccountID,
+			placement.Credit.GiftCardCode, placement.Credit.UseStoreCredit)
+		if err != nil {
+			log.Println("Error applying credit", err)
+			saga.compensate(ctx, state, order, true, "credit could not be applied")
+			return nil, "", err
+		}
+		state.PaidWithCredit = remaining.IsZero()
+	}
+
+	decision, err := saga.screen(ctx, order, placement)
+	if err != nil {
+		log.Println("Error screening order", err)
+		saga.compensate(ctx, state, order, true, "order could not be screened")
+		return nil, "", err
+	}
+	switch decision {
+	case models.DecisionBlock:
+		saga.compensate(ctx, state, order, true, "order was declined by risk screening")
+		return nil, "", ErrOrderDeclined
+	case models.DecisionReview:
+		err = saga.advance(ctx, state, models.SagaInReview)
+		if err != nil {
+			saga.compensate(ctx, state, order, true, "placement could not be recorded")
+			return nil, "", err
+		}
+		order.RiskReview = models.ReviewPending
+		return order, "", nil
+	}
+
+	// openCheckout undoes the placement itself when it fails
+	checkoutURL, err := saga.openCheckout(ctx, state, order, placement.Email, placement.Name, placement.RedirectURL)
+	if err != nil {
+		return nil, "", err
+	}
+	return order, checkoutURL, nil
+}
+
+// screen assesses the risk of the placed order, records the assessment and returns what
+// was decided.
+func (saga *OrderSaga) screen(ctx context.Context, order *models.Order, placement Placement) (models.RiskDecision, error) {
+	if saga.screening == nil {
+		return models.DecisionAllow, nil
+	}
+	assessment, err := saga.screening.Evaluate(ctx, risk.Check{
+		OrderID:         order.ID,
+		AccountID:       order.AccountID,
+		Total:           order.TotalPrice,
+		ShippingCountry: order.ShippingAddress.Country,
+		Origin:          placement.Origin,
+		PlacedAt:        order.CreatedAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	assessment.RedirectURL = placement.RedirectURL
+	err = saga.service.RecordRiskAssessment(ctx, assessment)
+	if err != nil {
+		return "", err
+	}
+	return assessment.Decision, nil
+}
+
+// openCheckout opens the checkout session of the placed order for the customer with email
+// and name, unless redirectURL is empty, and records that the order awaits payment. Orders
+// their credit pays for in full are paid instead, without a session. The placement is undone
+// when no session could be opened, the credit could not be captured or the order awaiting
+// payment could not be recorded, unless the order was paid by then.
+func (saga *OrderSaga) openCheckout(ctx context.Context, state *models.PlacementSaga, order *models.Order, email, name, redirectURL string) (string, error) {
+	if state.PaidWithCredit {
+		err := saga.checkout.CaptureCredit(ctx, uint64(order.ID))
+		if err != nil {
+			log.Println("Error capturing credit", err)
+			saga.compensate(ctx, state, order, true, "credit could not be captured")
+			return "", err
+		}
+	} else if redirectURL != "" {
+		cartItems := make([]*paymentpb.CartItem, 0, len(order.Products))
+		for _, p := range order.Products {
+			cartItems = append(cartItems, &paymentpb.CartItem{ProductId: p.ID, Quantity: uint64(p.Quantity)})
+		}
+		var err error
+		state.CheckoutURL, err = saga.checkout.CreateCheckoutSession(ctx, int(order.ID), int(order.AccountID),
+			email, name, redirectURL, order.TotalPrice.Currency, cartItems)
+		if err != nil {
+			log.Println("Error creating checkout session", err)
+			saga.compensate(ctx, state, order, true, "checkout session could not be created")
+			return "", err
+		}
+	}
+
+	err := saga.advance(ctx, state, models.SagaAwaitingPayment)
+	if err != nil && state.PaidWithCredit {
+		// The credit paid for the order, so resuming the placement completes it
+		return state.CheckoutURL, nil
+	}
+	if err != nil {
+		saga.compensate(ctx, state, order, true, "placement could not be recorded")
+		return "", err
+	}
+	return state.CheckoutURL, nil
+}
+
+// Review decides on an order held for review on behalf of actor. An approved order has its
+// checkout session opened for the customer with email and name, whose URL is returned,
+// while a rejected one is cancelled and its stock released.
+func (saga *OrderSaga) Review(ctx context.Context, review *models.RiskReview, approve bool, actor, note, email, name string) (string, error) {
+	order := review.Order
+	if approve && order.Status != models.StatusPendingPayment {
+		return "", status.Errorf(codes.FailedPrecondition, "order %d is %s and can no longer be approved", order.ID, order.Status)
+	}
+	state, err := saga.service.GetSagaForOrder(ctx, order.ID)
+	if err != nil {
+		return "", err
+	}
+	err = saga.service.DecideRiskReview(ctx, &review.RiskAssessment, approve, actor, note)
+	if err != nil {
+		return "", err
+	}
+	order.RiskReview = review.ReviewStatus
+
+	if !approve {
+		// Orders the customer cancelled meanwhile already had their stock put back
+		if state.State == models.SagaInReview && order.Status == models.StatusPendingPayment {
+			saga.compensate(ctx, state, order, true, "order was rejected in risk review")
+		}
+		return "", nil
+	}
+	if state.State != models.SagaInReview {
+		return "", status.Errorf(codes.FailedPrecondition, "order %d is no longer held for review", order.ID)
+	}
+
+	checkoutURL, err := saga.openCheckout(ctx, state, order, email, name, review.RedirectURL)
+	if err != nil {
+		return "", err
+	}
+	err = kafka.SendMessageToRecommender(saga.service, models.RiskEvent{
+		Type: "order_review_approved",
+		Data: models.RiskData{
+			OrderId:     order.ID,
+			AccountId:   int(order.AccountID),
+			Score:       review.Score,
+			Decision:    review.Decision,
+			CheckoutURL: checkoutURL,
+		},
+	}, "order_events")
+	if err != nil {
+		log.Println("Failed to send review event:", err)
+	}
+	return checkoutURL, nil
+}
+
+// Run resumes the pending sagas, left over from before a restart, and then does so again
+// every interval until ctx is done.
+func (saga *OrderSaga) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := saga.Resume(ctx)
+		if err != nil {
+			log.Println("Failed to resume order placements:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Resume moves every pending saga on as far as it can: placements interrupted mid-step are
+// undone and those whose order was paid or cancelled end.
+func (saga *OrderSaga) Resume(ctx context.Context) error {
+	var afterID uint
+	for {
+		sagas, err := saga.service.ListPendingSagas(ctx, afterID, sagaBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, state := range sagas {
+			saga.resume(ctx, state)
+			afterID = state.ID
+		}
+		if len(sagas) < sagaBatchSize {
+			return nil
+		}
+	}
+}
+
+func (saga *OrderSaga) resume(ctx context.Context, state *models.PlacementSaga) {
+	now := time.Now().UTC()
+	interrupted := now.Sub(state.UpdatedAt) >= sagaStepTimeout
+
+	switch state.State {
+	case models.SagaStarted, models.SagaStockReserved:
+		if interrupted {
+			saga.compensate(ctx, state, nil, true, "order placement was interrupted")
+		}
+	case models.SagaOrderPlaced, models.SagaInReview, models.SagaAwaitingPayment:
+		order, err := saga.service.GetOrder(ctx, uint64(state.OrderID), 0)
+		if err != nil {
+			log.Println("Error getting order of placement", state.ID, err)
+			return
+		}
+		switch {
+		case order.WasPaid():
+			saga.complete(ctx, state)
+		case order.Status != models.StatusPendingPayment:
+			// The order was cancelled or expired, which already put its stock back
+			saga.compensate(ctx, state, nil, false, "order was cancelled")
+		case state.State == models.SagaOrderPlaced && interrupted:
+			saga.compensate(ctx, state, order, true, "order placement was interrupted")
+		}
+	}
+}
+
+// compensate undoes the placement: it cancels order, when given, along with any checkout
+// session opened for it, and puts the reserved stock back when releaseStock is set.
+// Cancelling the order, or else recording the saga as compensated, is done first so that
+// the stock is released at most once even when several replicas compensate the same saga.
+func (saga *OrderSaga) compensate(ctx context.Context, state *models.PlacementSaga, order *models.Order, releaseStock bool, reason string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), compensationTimeout)
+	defer cancel()
+
+	if order != nil {
+		_, err := saga.service.CancelOrder(ctx, uint64(order.ID), 0, reason)
+		if err != nil {
+			// The order may have been paid or cancelled meanwhile; the next resume settles it
+			log.Println("Error cancelling order", order.ID, err)
+			return
+		}
+		err = saga.checkout.ExpireCheckoutSessions(ctx, uint64(order.ID))
+		if err != nil {
+			log.Println("Error expiring checkout sessions of order", order.ID, err)
+		}
+	}
+
+	from := state.State
+	state.State = models.SagaCompensated
+	state.Error = reason
+	err := saga.service.AdvanceSaga(ctx, state, from)
+	if err != nil {
+		log.Println("Error compensating placement", state.ID, err)
+		state.State = from
+		// Without an order to cancel, recording the saga is what claims the compensation
+		if order == nil {
+			return
+		}
+	}
+
+	if releaseStock {
+		err := saga.inventory.ReleaseStock(ctx, stockItems(state.Items))
+		if err != nil {
+			log.Println("Error releasing stock of placement", state.ID, err)
+		}
+	}
+}
+
+func (saga *OrderSaga) complete(ctx context.Context, state *models.PlacementSaga) {
+	err := saga.advance(ctx, state, models.SagaCompleted)
+	if err != nil && !errors.Is(err, ErrSagaConflict) {
+		log.Println("Error completing placement", state.ID, err)
+	}
+}
+
+// advance records that the saga moved on to state to.
+func (saga *OrderSaga) advance(ctx context.Context, state *models.PlacementSaga, to models.SagaState) error {
+	from := state.State
+	state.State = to
+	err := saga.service.AdvanceSaga(ctx, state, from)
+	if err != nil {
+		log.Println("Error recording placement", state.ID, "as", to, err)
+		state.State = from
+		return err
+	}
+	return nil
+}
+
+// reservationRefused reports whether the product service turned the stock reservation down,
+// in which case nothing was reserved.
+func reservationRefused(err error) bool {
+	switch status.Code(err) {
+	case codes.FailedPrecondition, codes.NotFound, codes.InvalidArgument:
+		return true
+	}
+	return false
+}
+
+func stockItems(items []models.SagaItem) []productmodels.StockItem {
+	stock := make([]productmodels.StockItem, 0, len(items))
+	for _, item := range items {
+		stock = append(stock, productmodels.StockItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	return stock
+}
@@ -5,15 +5,25 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
 	account "github.com/rasadov/EcommerceAPI/account/client"
 	"github.com/rasadov/EcommerceAPI/order/models"
 	"github.com/rasadov/EcommerceAPI/order/proto/pb"
+	"github.com/rasadov/EcommerceAPI/order/risk"
+	payment "github.com/rasadov/EcommerceAPI/payment/client"
+	paymentpb "github.com/rasadov/EcommerceAPI/payment/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/blob"
+	"github.com/rasadov/EcommerceAPI/pkg/idempotency"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 	product "github.com/rasadov/EcommerceAPI/product/client"
+	productmodels "github.com/rasadov/EcommerceAPI/product/models"
+	shipping "github.com/rasadov/EcommerceAPI/shipping/client"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
-	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
@@ -22,9 +32,18 @@ type grpcServer struct {
 	service       Service
 	accountClient *account.Client
 	productClient *product.Client
+	paymentClient *payment.Client
+	converter     *money.Converter
+	pricer        *Pricer
+	saga          *OrderSaga
+	invoicer      *Invoicer
 }
 
-func ListenGRPC(service Service, accountURL string, productURL string, port int) error {
+// ListenGRPC serves the order service on port. Orders are screened before they can be paid,
+// unless screening is nil. Orders still unpaid paymentTimeout after they were placed are
+// cancelled, and paid orders are invoiced by seller, with the invoice documents kept in
+// invoiceStore.
+func ListenGRPC(service Service, converter *money.Converter, idempotencyStore *idempotency.Store, invoiceStore blob.Store, seller models.Party, screening *risk.Engine, accountURL, productURL, paymentURL, shippingURL string, paymentTimeout time.Duration, port int) error {
 	accountClient, err := account.NewClient(accountURL)
 	if err != nil {
 		return err
@@ -36,162 +55,1023 @@ func ListenGRPC(service Service, accountURL string, productURL string, port int)
 		return err
 	}
 
+	paymentClient, err := payment.NewClient(paymentURL)
+	if err != nil {
+		accountClient.Close()
+		productClient.Close()
+		return err
+	}
+
+	shippingClient, err := shipping.NewClient(shippingURL)
+	if err != nil {
+		accountClient.Close()
+		productClient.Close()
+		paymentClient.Close()
+		return err
+	}
+
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
 		accountClient.Close()
 		productClient.Close()
+		paymentClient.Close()
+		shippingClient.Close()
 		return err
 	}
 
-	serv := grpc.NewServer()
-	pb.RegisterOrderServiceServer(serv, &grpcServer{
+	// Retried orders return the order placed by the first attempt
+	serv := grpc.NewServer(grpc.UnaryInterceptor(
+		idempotency.UnaryServerInterceptor(idempotencyStore, pb.OrderService_PostOrder_FullMethodName),
+	))
+	server := &grpcServer{
 		pb.UnimplementedOrderServiceServer{},
 		service,
 		accountClient,
 		productClient,
-	})
+		paymentClient,
+		converter,
+		NewPricer(productClient, shippingClient, converter),
+		NewOrderSaga(service, productClient, paymentClient, screening),
+		NewInvoicer(service, invoiceStore, accountClient, seller),
+	}
+	pb.RegisterOrderServiceServer(serv, server)
 	reflection.Register(serv)
 
+	go server.backfillLineSnapshots(context.Background())
+	go server.saga.Run(context.Background(), time.Minute)
+	go NewOrderExpirer(service, productClient, paymentClient, paymentTimeout).Run(context.Background(), time.Minute)
+	go NewRecurringScheduler(service, server.saga, server.pricer, accountClient).Run(context.Background(), time.Minute)
+
 	return serv.Serve(lis)
 }
 
 func (server *grpcServer) PostOrder(ctx context.Context, request *pb.PostOrderRequest) (*pb.PostOrderResponse, error) {
-	_, err := server.accountClient.GetAccount(ctx, request.AccountId)
+	currency, err := money.Validate(request.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := server.accountClient.GetAccount(ctx, request.AccountId)
 	if err != nil {
 		log.Println("Error getting account", err)
 		return nil, err
 	}
-	var productIDs []string
-	for _, p := range request.Products {
-		productIDs = append(productIDs, p.Id)
+	products, err := server.pricer.PriceProducts(ctx, requestedProducts(request.Products), currency)
+	if err != nil {
+		return nil, err
 	}
-	orderedProducts, err := server.productClient.GetProducts(ctx, 0, 0, productIDs, "")
+
+	address := decodeAddress(request.ShippingAddress)
+	shippingCost, err := server.pricer.QuoteShipping(ctx, request.ShippingMethod, address, currency, products)
 	if err != nil {
-		log.Println("Error getting ordered products", err)
 		return nil, err
 	}
 
-	var products []*models.OrderedProduct
-	totalPrice := 0.0
+	postOrder, checkoutUrl, err := server.saga.Place(ctx, Placement{
+		AccountID:   request.AccountId,
+		Email:       account.Email,
+		Name:        account.Name,
+		Currency:    currency,
+		Products:    products,
+		CouponCode:  request.CouponCode,
+		Address:     address,
+		Shipping:    shippingCost,
+		RedirectURL: request.RedirectURL,
+		Origin: models.OrderOrigin{
+			ClientIP:       request.ClientIp,
+			BillingCountry: request.BillingCountry,
+		},
+		Credit: models.OrderCredit{
+			GiftCardCode:   request.GiftCardCode,
+			UseStoreCredit: request.UseStoreCredit,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	for _, p := range orderedProducts {
-		productObj := &models.OrderedProduct{
-			ID:          p.ID,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-			Quantity:    0,
+	return &pb.PostOrderResponse{
+		Order:       encodeOrder(postOrder),
+		CheckoutUrl: checkoutUrl,
+	}, nil
+}
+
+// requestedProducts returns the IDs and quantities of the requested products, to be priced.
+func requestedProducts(requested []*pb.OrderProduct) []*models.OrderedProduct {
+	products := make([]*models.OrderedProduct, 0, len(requested))
+	for _, p := range requested {
+		products = append(products, &models.OrderedProduct{ID: p.Id, Quantity: p.Quantity})
+	}
+	return products
+}
+
+// GetOrdersForAccount returns the account's orders as they were placed, without
+// consulting the product service.
+func (server *grpcServer) GetOrdersForAccount(ctx context.Context, request *wrapperspb.UInt64Value) (*pb.GetOrdersForAccountResponse, error) {
+	accountOrders, err := server.service.GetOrdersForAccount(ctx, request.Value)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	var orders []*pb.Order
+	for _, order := range accountOrders {
+		orders = append(orders, encodeOrder(order))
+	}
+	return &pb.GetOrdersForAccountResponse{Orders: orders}, nil
+}
+
+func (server *grpcServer) GetOrder(ctx context.Context, request *pb.GetOrderRequest) (*pb.GetOrderResponse, error) {
+	order, err := server.service.GetOrder(ctx, request.OrderId, request.AccountId)
+	if err != nil {
+		log.Println("Error getting order", err)
+		return nil, err
+	}
+
+	return &pb.GetOrderResponse{Order: encodeOrder(order)}, nil
+}
+
+func (server *grpcServer) ListOrders(ctx context.Context, request *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	filter := models.OrderFilter{AccountID: request.AccountId}
+	if len(request.CreatedAfter) > 0 {
+		if err := filter.CreatedAfter.UnmarshalBinary(request.CreatedAfter); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid createdAfter")
 		}
-		for _, requestProduct := range request.Products {
-			if requestProduct.Id == p.ID {
-				productObj.Quantity = requestProduct.Quantity
-				break
-			}
+	}
+	if len(request.CreatedBefore) > 0 {
+		if err := filter.CreatedBefore.UnmarshalBinary(request.CreatedBefore); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid createdBefore")
 		}
+	}
+	for _, s := range request.Statuses {
+		filter.Statuses = append(filter.Statuses, models.OrderStatus(s))
+	}
+	if request.MinTotal != nil {
+		minTotal := money.FromProto(request.MinTotal)
+		filter.MinTotal = &minTotal
+	}
+	if request.MaxTotal != nil {
+		maxTotal := money.FromProto(request.MaxTotal)
+		filter.MaxTotal = &maxTotal
+	}
+
+	page, err := server.service.ListOrders(ctx, filter, request.After, int(request.First))
+	if err != nil {
+		log.Println("Error listing orders", err)
+		return nil, err
+	}
+
+	response := &pb.ListOrdersResponse{
+		Orders:      []*pb.Order{},
+		EndCursor:   page.EndCursor,
+		HasNextPage: page.HasNextPage,
+	}
+	for _, order := range page.Orders {
+		response.Orders = append(response.Orders, encodeOrder(order))
+	}
+	return response, nil
+}
 
-		if productObj.Quantity != 0 {
-			products = append(products, productObj)
-			totalPrice += productObj.Price * float64(productObj.Quantity)
+// ExportOrders streams the orders placed in the requested range one at a time, so exports
+// of any size use the same memory.
+func (server *grpcServer) ExportOrders(request *pb.ExportOrdersRequest, stream pb.OrderService_ExportOrdersServer) error {
+	var from, to time.Time
+	if err := from.UnmarshalBinary(request.From); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid from")
+	}
+	if err := to.UnmarshalBinary(request.To); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid to")
+	}
+
+	err := server.service.ExportOrders(stream.Context(), from, to, func(order *models.Order) error {
+		return stream.Send(encodeOrder(order))
+	})
+	if err != nil {
+		log.Println("Error exporting orders", err)
+		return err
+	}
+	return nil
+}
+
+func (server *grpcServer) GetOrderLinesForProducts(ctx context.Context, request *pb.GetOrderLinesForProductsRequest) (*pb.GetOrderLinesForProductsResponse, error) {
+	lines, err := server.service.GetOrderLinesForProducts(ctx, request.ProductIds, request.Skip, request.Take)
+	if err != nil {
+		log.Println("Error getting order lines for products", err)
+		return nil, err
+	}
+
+	response := &pb.GetOrderLinesForProductsResponse{}
+	for _, line := range lines {
+		encodedLine := &pb.OrderLine{
+			OrderId:     uint64(line.OrderID),
+			AccountId:   line.AccountID,
+			ProductId:   line.ProductID,
+			Quantity:    uint32(line.Quantity),
+			ProductName: line.Name,
+		}
+		if line.UnitPrice.Currency != "" {
+			encodedLine.UnitPrice = line.UnitPrice.ToProto()
 		}
+		encodedLine.CreatedAt, _ = line.CreatedAt.MarshalBinary()
+		response.Lines = append(response.Lines, encodedLine)
 	}
+	return response, nil
+}
 
-	postOrder, err := server.service.PostOrder(ctx, request.AccountId, totalPrice, products)
+func (server *grpcServer) GetSalesForProducts(ctx context.Context, request *pb.GetSalesForProductsRequest) (*pb.GetSalesForProductsResponse, error) {
+	summary, err := server.service.GetSalesForProducts(ctx, request.ProductIds)
 	if err != nil {
-		log.Println("Error posting postOrder", err)
+		log.Println("Error getting sales for products", err)
 		return nil, err
 	}
 
-	orderProto := &pb.Order{
-		Id:         uint64(postOrder.ID),
-		AccountId:  postOrder.AccountID,
-		TotalPrice: postOrder.TotalPrice,
-		Products:   []*pb.ProductInfo{},
+	response := &pb.GetSalesForProductsResponse{OrderCount: uint32(summary.OrderCount)}
+	for _, sales := range summary.Products {
+		response.Products = append(response.Products, &pb.ProductSales{
+			ProductId:     sales.ProductID,
+			UnitsSold:     uint32(sales.UnitsSold),
+			Revenue:       money.New(sales.Revenue, sales.Currency).ToProto(),
+			UnpricedUnits: uint32(sales.UnpricedUnits),
+		})
 	}
-	orderProto.CreatedAt, _ = postOrder.CreatedAt.MarshalBinary()
-	for _, p := range postOrder.Products {
-		orderProto.Products = append(orderProto.Products, &pb.ProductInfo{
-			Id:          p.ID,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-			Quantity:    p.Quantity,
+	return response, nil
+}
+
+func (server *grpcServer) UpdateOrderStatus(ctx context.Context, request *pb.UpdateOrderStatusRequest) (*pb.UpdateOrderStatusResponse, error) {
+	order, err := server.service.UpdateOrderStatus(ctx, request.OrderId, request.Status, request.Actor, request.Reason)
+	if err != nil {
+		log.Println("Error updating order status", err)
+		return nil, err
+	}
+
+	// Paid orders are invoiced in the background; invoices that fail here are issued when
+	// they are first downloaded
+	if order.Status == models.StatusPaid {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+			defer cancel()
+			_, err := server.invoicer.Invoice(ctx, order)
+			if err != nil {
+				log.Println("Error issuing invoice for order", order.ID, err)
+			}
+		}()
+	}
+
+	return &pb.UpdateOrderStatusResponse{Order: encodeOrder(order)}, nil
+}
+
+// ListSellerOrders lists a seller's sub-orders, newest first, with the seller's lines.
+func (server *grpcServer) ListSellerOrders(ctx context.Context, request *pb.ListSellerOrdersRequest) (*pb.ListSellerOrdersResponse, error) {
+	var statuses []models.OrderStatus
+	for _, s := range request.Statuses {
+		statuses = append(statuses, models.OrderStatus(s))
+	}
+
+	page, err := server.service.ListSellerOrders(ctx, request.SellerId, statuses, request.After, int(request.First))
+	if err != nil {
+		log.Println("Error listing seller orders", err)
+		return nil, err
+	}
+
+	response := &pb.ListSellerOrdersResponse{
+		Orders:      []*pb.SellerOrder{},
+		EndCursor:   page.EndCursor,
+		HasNextPage: page.HasNextPage,
+	}
+	for _, order := range page.Orders {
+		response.Orders = append(response.Orders, encodeSellerOrder(order))
+	}
+	return response, nil
+}
+
+func (server *grpcServer) UpdateSubOrderStatus(ctx context.Context, request *pb.UpdateSubOrderStatusRequest) (*pb.UpdateSubOrderStatusResponse, error) {
+	sub, err := server.service.UpdateSubOrderStatus(ctx, uint(request.SubOrderId), request.SellerId, request.Status, request.Actor)
+	if err != nil {
+		log.Println("Error updating sub-order status", err)
+		return nil, err
+	}
+	return &pb.UpdateSubOrderStatusResponse{SubOrder: encodeSubOrder(sub)}, nil
+}
+
+// Reorder prices the lines of a past order as they would be ordered again today, for the
+// customer to put back into their cart.
+func (server *grpcServer) Reorder(ctx context.Context, request *pb.ReorderRequest) (*pb.ReorderResponse, error) {
+	order, err := server.service.GetOrder(ctx, request.OrderId, request.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := server.pricer.Reorder(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.ReorderResponse{Lines: []*pb.ReorderLine{}}
+	for _, line := range lines {
+		response.Lines = append(response.Lines, &pb.ReorderLine{
+			ProductId:         line.ProductID,
+			Name:              line.Name,
+			Quantity:          line.Quantity,
+			UnitPrice:         line.UnitPrice.ToProto(),
+			PreviousUnitPrice: line.PreviousUnitPrice.ToProto(),
+			Available:         line.Available,
 		})
 	}
+	return response, nil
+}
 
-	return &pb.PostOrderResponse{
-		Order: orderProto,
-	}, nil
+func (server *grpcServer) CreateRecurringOrder(ctx context.Context, request *pb.CreateRecurringOrderRequest) (*pb.RecurringOrderResponse, error) {
+	recurring, err := decodeRecurringOrder(request.RecurringOrder)
+	if err != nil {
+		return nil, err
+	}
+	recurring, err = server.service.CreateRecurringOrder(ctx, recurring)
+	if err != nil {
+		log.Println("Error creating recurring order", err)
+		return nil, err
+	}
+	return &pb.RecurringOrderResponse{RecurringOrder: encodeRecurringOrder(recurring)}, nil
 }
 
-func (server *grpcServer) GetOrdersForAccount(ctx context.Context, request *wrapperspb.UInt64Value) (*pb.GetOrdersForAccountResponse, error) {
-	accountOrders, err := server.service.GetOrdersForAccount(ctx, request.Value)
+func (server *grpcServer) ListRecurringOrders(ctx context.Context, request *pb.ListRecurringOrdersRequest) (*pb.ListRecurringOrdersResponse, error) {
+	recurringOrders, err := server.service.ListRecurringOrders(ctx, request.AccountId)
 	if err != nil {
-		log.Println(err)
+		log.Println("Error listing recurring orders", err)
+		return nil, err
+	}
+	response := &pb.ListRecurringOrdersResponse{RecurringOrders: []*pb.RecurringOrder{}}
+	for _, recurring := range recurringOrders {
+		response.RecurringOrders = append(response.RecurringOrders, encodeRecurringOrder(recurring))
+	}
+	return response, nil
+}
+
+// UpdateRecurringOrderStatus pauses, resumes or cancels a recurring order.
+func (server *grpcServer) UpdateRecurringOrderStatus(ctx context.Context, request *pb.UpdateRecurringOrderStatusRequest) (*pb.RecurringOrderResponse, error) {
+	recurring, err := server.service.UpdateRecurringOrderStatus(ctx, uint(request.RecurringOrderId), request.AccountId, request.Status)
+	if err != nil {
+		log.Println("Error updating recurring order status", err)
+		return nil, err
+	}
+	return &pb.RecurringOrderResponse{RecurringOrder: encodeRecurringOrder(recurring)}, nil
+}
+
+// ListRiskReviews lists the orders held for review by risk screening, oldest first.
+func (server *grpcServer) ListRiskReviews(ctx context.Context, request *pb.ListRiskReviewsRequest) (*pb.ListRiskReviewsResponse, error) {
+	page, err := server.service.ListRiskReviews(ctx, request.ReviewStatus, request.After, int(request.First))
+	if err != nil {
+		log.Println("Error listing risk reviews", err)
 		return nil, err
 	}
 
-	// Taking unique products. We use set to avoid repeating
-	productIDsSet := mapset.NewSet[string]()
-	for _, o := range accountOrders {
-		for _, p := range o.Products {
-			productIDsSet.Add(p.ID)
+	response := &pb.ListRiskReviewsResponse{
+		Reviews:     []*pb.RiskReview{},
+		EndCursor:   page.EndCursor,
+		HasNextPage: page.HasNextPage,
+	}
+	for _, review := range page.Reviews {
+		response.Reviews = append(response.Reviews, encodeRiskReview(review))
+	}
+	return response, nil
+}
+
+// DecideRiskReview approves an order held for review, which opens its checkout session, or
+// rejects it, which cancels it.
+func (server *grpcServer) DecideRiskReview(ctx context.Context, request *pb.DecideRiskReviewRequest) (*pb.DecideRiskReviewResponse, error) {
+	review, err := server.service.GetRiskReview(ctx, uint(request.OrderId))
+	if err != nil {
+		return nil, err
+	}
+	var email, name string
+	if request.Approve {
+		account, err := server.accountClient.GetAccount(ctx, review.Order.AccountID)
+		if err != nil {
+			log.Println("Error getting account", err)
+			return nil, err
 		}
+		email, name = account.Email, account.Name
 	}
 
-	productIDs := productIDsSet.ToSlice()
+	checkoutURL, err := server.saga.Review(ctx, review, request.Approve, request.Actor, request.Note, email, name)
+	if err != nil {
+		log.Println("Error deciding risk review", err)
+		return nil, err
+	}
+	review, err = server.service.GetRiskReview(ctx, uint(request.OrderId))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DecideRiskReviewResponse{Review: encodeRiskReview(review), CheckoutUrl: checkoutURL}, nil
+}
+
+// GetInvoice returns the invoice of a paid order rendered in the requested format, PDF
+// unless one is given.
+func (server *grpcServer) GetInvoice(ctx context.Context, request *pb.GetInvoiceRequest) (*pb.GetInvoiceResponse, error) {
+	format := request.Format
+	if format == "" {
+		format = "pdf"
+	}
+	if _, ok := InvoiceFormats[format]; !ok {
+		return nil, ErrInvalidInvoiceFormat
+	}
+
+	order, err := server.service.GetOrder(ctx, request.OrderId, request.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	invoice, err := server.invoicer.Invoice(ctx, order)
+	if err != nil {
+		log.Println("Error getting invoice", err)
+		return nil, err
+	}
+	content, contentType, err := server.invoicer.Document(ctx, invoice, format)
+	if err != nil {
+		log.Println("Error rendering invoice", err)
+		return nil, err
+	}
+
+	response := &pb.GetInvoiceResponse{
+		Number:      invoice.Number,
+		OrderId:     uint64(invoice.OrderID),
+		ContentType: contentType,
+		Content:     content,
+	}
+	response.IssuedAt, _ = invoice.IssuedAt.MarshalBinary()
+	return response, nil
+}
 
-	products, err := server.productClient.GetProducts(ctx, 0, 0, productIDs, "")
+// CancelOrder cancels the order, puts its products back into stock and, if it was paid,
+// refunds the payment in full. The order moves on to refunded once the refund succeeds.
+// Unpaid orders have their checkout sessions expired and their credit given back instead.
+func (server *grpcServer) CancelOrder(ctx context.Context, request *pb.CancelOrderRequest) (*pb.CancelOrderResponse, error) {
+	order, err := server.service.CancelOrder(ctx, request.OrderId, request.AccountId, request.Reason)
 	if err != nil {
-		log.Println("Error getting account products: ", err)
+		log.Println("Error cancelling order", err)
 		return nil, err
 	}
 
-	// Collecting orders
+	if order.StockReserved {
+		stockItems := make([]productmodels.StockItem, 0, len(order.ProductsInfos))
+		for _, info := range order.ProductsInfos {
+			stockItems = append(stockItems, productmodels.StockItem{ProductID: info.ProductID, Quantity: info.Quantity})
+		}
+		err = server.productClient.ReleaseStock(ctx, stockItems)
+		if err != nil {
+			log.Println("Error releasing stock", err)
+		}
+	}
 
-	var orders []*pb.Order
-	for _, order := range accountOrders {
-		// Encode order
-		encodedOrder := &pb.Order{
-			AccountId:  order.AccountID,
-			Id:         uint64(order.ID),
-			TotalPrice: order.TotalPrice,
-			Products:   []*pb.ProductInfo{},
-		}
-		encodedOrder.CreatedAt, _ = order.CreatedAt.MarshalBinary()
-
-		// Decorate orders with products
-		for _, orderedProduct := range order.Products {
-			// Populate product fields
-			for _, prod := range products {
-				if prod.ID == orderedProduct.ID {
-					orderedProduct.Name = prod.Name
-					orderedProduct.Description = prod.Description
-					orderedProduct.Price = prod.Price
+	if order.WasPaid() {
+		_, err = server.paymentClient.RefundPayment(ctx, uint64(order.ID), nil, request.Reason, false)
+		if err != nil {
+			log.Println("Error refunding payment", err)
+			return nil, status.Errorf(codes.Internal, "order %d was cancelled but its refund could not be started", order.ID)
+		}
+	} else {
+		err = server.paymentClient.ExpireCheckoutSessions(ctx, uint64(order.ID))
+		if err != nil {
+			log.Println("Error expiring checkout sessions", err)
+		}
+	}
+
+	return &pb.CancelOrderResponse{Order: encodeOrder(order)}, nil
+}
+
+func (server *grpcServer) RequestReturn(ctx context.Context, request *pb.RequestReturnRequest) (*pb.ReturnResponse, error) {
+	lines := make([]*models.ReturnLine, 0, len(request.Lines))
+	for _, line := range request.Lines {
+		lines = append(lines, &models.ReturnLine{ProductID: line.Id, Quantity: int(line.Quantity)})
+	}
+
+	ret, err := server.service.RequestReturn(ctx, request.OrderId, request.AccountId, lines, request.Reason, request.RefundToStoreCredit)
+	if err != nil {
+		log.Println("Error requesting return", err)
+		return nil, err
+	}
+	return &pb.ReturnResponse{OrderReturn: encodeReturn(ret)}, nil
+}
+
+func (server *grpcServer) GetReturn(ctx context.Context, request *pb.GetReturnRequest) (*pb.ReturnResponse, error) {
+	ret, err := server.service.GetReturn(ctx, uint(request.ReturnId))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ReturnResponse{OrderReturn: encodeReturn(ret)}, nil
+}
+
+func (server *grpcServer) DecideReturn(ctx context.Context, request *pb.DecideReturnRequest) (*pb.ReturnResponse, error) {
+	ret, err := server.service.DecideReturn(ctx, uint(request.ReturnId), request.Approve, request.Actor, request.Note)
+	if err != nil {
+		log.Println("Error deciding return", err)
+		return nil, err
+	}
+	return &pb.ReturnResponse{OrderReturn: encodeReturn(ret)}, nil
+}
+
+// ReceiveReturn records that the products of an approved return arrived back, puts them
+// back into stock and refunds what they were paid. Receiving a return whose refund could
+// not be started retries the refund.
+func (server *grpcServer) ReceiveReturn(ctx context.Context, request *pb.ReceiveReturnRequest) (*pb.ReturnResponse, error) {
+	ret, received, err := server.service.ReceiveReturn(ctx, uint(request.ReturnId), request.Actor)
+	if err != nil {
+		log.Println("Error receiving return", err)
+		return nil, err
+	}
+
+	if received {
+		stockItems := make([]productmodels.StockItem, 0, len(ret.Lines))
+		for _, line := range ret.Lines {
+			stockItems = append(stockItems, productmodels.StockItem{ProductID: line.ProductID, Quantity: line.Quantity})
+		}
+		err = server.productClient.ReleaseStock(ctx, stockItems)
+		if err != nil {
+			log.Println("Error restocking returned products", err)
+		}
+	}
+
+	var items []*paymentpb.RefundItem
+	for _, line := range ret.Lines {
+		if line.RefundAmount.IsZero() {
+			continue
+		}
+		items = append(items, &paymentpb.RefundItem{ProductId: line.ProductID, Amount: line.RefundAmount.ToProto()})
+	}
+	var refundId string
+	if len(items) > 0 {
+		refund, err := server.paymentClient.RefundPayment(ctx, uint64(ret.OrderID), items, "return: "+ret.Reason, ret.RefundToStoreCredit)
+		if err != nil {
+			log.Println("Error refunding return", err)
+			return nil, status.Errorf(codes.Internal, "return %d was received but its refund could not be started", ret.ID)
+		}
+		refundId = refund.RefundId
+	}
+
+	ret, err = server.service.RecordReturnRefund(ctx, ret.ID, refundId, request.Actor)
+	if err != nil {
+		log.Println("Error recording return refund", err)
+		return nil, err
+	}
+	return &pb.ReturnResponse{OrderReturn: encodeReturn(ret)}, nil
+}
+
+func (server *grpcServer) CreatePromotion(ctx context.Context, request *pb.PromotionRequest) (*pb.PromotionResponse, error) {
+	promotion, err := decodePromotion(request.Promotion)
+	if err != nil {
+		return nil, err
+	}
+	promotion, err = server.service.CreatePromotion(ctx, promotion)
+	if err != nil {
+		log.Println("Error creating promotion", err)
+		return nil, err
+	}
+	return &pb.PromotionResponse{Promotion: encodePromotion(promotion)}, nil
+}
+
+func (server *grpcServer) UpdatePromotion(ctx context.Context, request *pb.PromotionRequest) (*pb.PromotionResponse, error) {
+	promotion, err := decodePromotion(request.Promotion)
+	if err != nil {
+		return nil, err
+	}
+	promotion, err = server.service.UpdatePromotion(ctx, promotion)
+	if err != nil {
+		log.Println("Error updating promotion", err)
+		return nil, err
+	}
+	return &pb.PromotionResponse{Promotion: encodePromotion(promotion)}, nil
+}
+
+func (server *grpcServer) DeactivatePromotion(ctx context.Context, request *pb.DeactivatePromotionRequest) (*pb.PromotionResponse, error) {
+	promotion, err := server.service.DeactivatePromotion(ctx, uint(request.PromotionId))
+	if err != nil {
+		log.Println("Error deactivating promotion", err)
+		return nil, err
+	}
+	return &pb.PromotionResponse{Promotion: encodePromotion(promotion)}, nil
+}
+
+func (server *grpcServer) ListPromotions(ctx context.Context, request *pb.ListPromotionsRequest) (*pb.ListPromotionsResponse, error) {
+	promotions, err := server.service.ListPromotions(ctx, request.IncludeInactive)
+	if err != nil {
+		log.Println("Error listing promotions", err)
+		return nil, err
+	}
+
+	response := &pb.ListPromotionsResponse{Promotions: []*pb.Promotion{}}
+	for _, promotion := range promotions {
+		response.Promotions = append(response.Promotions, encodePromotion(promotion))
+	}
+	return response, nil
+}
+
+// EvaluatePromotion prices the products like PostOrder would and returns the discount the
+// promotion gives on them, without placing an order.
+func (server *grpcServer) EvaluatePromotion(ctx context.Context, request *pb.EvaluatePromotionRequest) (*pb.EvaluatePromotionResponse, error) {
+	currency, err := money.Validate(request.Currency)
+	if err != nil {
+		return nil, err
+	}
+	products, err := server.pricer.PriceProducts(ctx, requestedProducts(request.Products), currency)
+	if err != nil {
+		return nil, err
+	}
+
+	discount, err := server.service.EvaluatePromotion(ctx, request.Code, request.AccountId, currency, products)
+	if err != nil {
+		return nil, err
+	}
+	subtotal, err := models.OrderTotal(currency, products)
+	if err != nil {
+		return nil, err
+	}
+	total, err := subtotal.Sub(discount.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.EvaluatePromotionResponse{
+		Discount: encodeDiscount(discount),
+		Subtotal: subtotal.ToProto(),
+		Total:    total.ToProto(),
+	}, nil
+}
+
+// backfillLineSnapshots gives lines of orders placed before lines kept a product snapshot
+// the product's current details. Lines that already recorded their unit price keep it, and
+// products deleted since keep an empty snapshot.
+func (server *grpcServer) backfillLineSnapshots(ctx context.Context) {
+	for {
+		orders, err := server.service.GetOrdersWithoutLineSnapshots(ctx, 100)
+		if err != nil {
+			log.Println("Error getting orders without line snapshots", err)
+			return
+		}
+		if len(orders) == 0 {
+			return
+		}
+
+		productIDsSet := mapset.NewSet[string]()
+		for _, order := range orders {
+			for _, info := range order.ProductsInfos {
+				productIDsSet.Add(info.ProductID)
+			}
+		}
+		products, err := server.productClient.GetProducts(ctx, 0, 0, productIDsSet.ToSlice(), "")
+		if err != nil {
+			log.Println("Error getting products for line snapshots", err)
+			return
+		}
+
+		var lines []*models.ProductsInfo
+		for _, order := range orders {
+			currency := order.TotalPrice.Currency
+			for i := range order.ProductsInfos {
+				line := &order.ProductsInfos[i]
+				if line.HasSnapshot() {
+					continue
+				}
+				// Lines stored with only a unit price amount keep what they sold for
+				recorded := line.UnitPrice.Amount != 0
+				line.UnitPrice = money.New(line.UnitPrice.Amount, currency)
+				for _, p := range products {
+					if p.ID != line.ProductID {
+						continue
+					}
+					line.Name = p.Name
+					line.Description = p.Description
+					if !recorded {
+						line.UnitPrice, err = server.converter.Convert(p.Price, currency)
+						if err != nil {
+							log.Println("Error converting product price for line snapshot", err)
+							return
+						}
+					}
 					break
 				}
+				line.LineTotal = line.UnitPrice.Mul(int64(line.Quantity))
+				lines = append(lines, line)
 			}
+		}
 
-			encodedOrder.Products = append(encodedOrder.Products, &pb.ProductInfo{
-				Id:          orderedProduct.ID,
-				Name:        orderedProduct.Name,
-				Description: orderedProduct.Description,
-				Price:       orderedProduct.Price,
-				Quantity:    orderedProduct.Quantity,
-			})
+		err = server.service.SaveLineSnapshots(ctx, lines)
+		if err != nil {
+			log.Println("Error saving line snapshots", err)
+			return
 		}
+		log.Printf("Stored product snapshots for %d order lines", len(lines))
+	}
+}
 
-		orders = append(orders, encodedOrder)
+// encodeOrder encodes an order with its products and status history.
+func encodeOrder(order *models.Order) *pb.Order {
+	encodedOrder := &pb.Order{
+		Id:              uint64(order.ID),
+		AccountId:       order.AccountID,
+		TotalPrice:      order.TotalPrice.ToProto(),
+		Status:          order.Status.String(),
+		Products:        []*pb.ProductInfo{},
+		ShippingAddress: encodeAddress(order.ShippingAddress),
+		ShippingMethod:  order.ShippingMethod,
+		RiskReview:      order.RiskReview.String(),
 	}
-	return &pb.GetOrdersForAccountResponse{Orders: orders}, nil
+	if order.ShippingMethod != "" {
+		encodedOrder.ShippingCost = order.ShippingCost.ToProto()
+	}
+	encodedOrder.CreatedAt, _ = order.CreatedAt.MarshalBinary()
+
+	for _, p := range order.Products {
+		encodedOrder.Products = append(encodedOrder.Products, &pb.ProductInfo{
+			Id:          p.ID,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       p.Price.ToProto(),
+			LineTotal:   p.LineTotal().ToProto(),
+			Quantity:    p.Quantity,
+			SellerId:    p.SellerID,
+		})
+	}
+
+	for _, transition := range order.History {
+		encodedTransition := &pb.OrderStatusTransition{
+			From:   transition.From.String(),
+			To:     transition.To.String(),
+			Actor:  transition.Actor,
+			Reason: transition.Reason,
+		}
+		encodedTransition.CreatedAt, _ = transition.CreatedAt.MarshalBinary()
+		encodedOrder.History = append(encodedOrder.History, encodedTransition)
+	}
+
+	for _, discount := range order.Discounts {
+		encodedOrder.Discounts = append(encodedOrder.Discounts, encodeDiscount(discount))
+	}
+
+	for _, t := range order.Taxes {
+		encodedOrder.Taxes = append(encodedOrder.Taxes, &pb.OrderTax{
+			ProductId:   t.ProductID,
+			TaxCategory: t.TaxCategory,
+			Name:        t.Name,
+			Rate:        t.Rate,
+			Inclusive:   t.Inclusive,
+			Amount:      t.Amount.ToProto(),
+		})
+	}
+
+	for _, ret := range order.Returns {
+		encodedOrder.Returns = append(encodedOrder.Returns, encodeReturn(ret))
+	}
+
+	for _, sub := range order.SubOrders {
+		encodedOrder.SubOrders = append(encodedOrder.SubOrders, encodeSubOrder(sub))
+	}
+	return encodedOrder
 }
 
-func (server *grpcServer) UpdateOrderStatus(ctx context.Context, request *pb.UpdateOrderStatusRequest) (*emptypb.Empty, error) {
-	err := server.service.UpdateOrderPaymentStatus(ctx, request.OrderId, request.Status)
+func encodeAddress(address models.Address) *pb.OrderAddress {
+	return &pb.OrderAddress{
+		RecipientName: address.RecipientName,
+		Line1:         address.Line1,
+		Line2:         address.Line2,
+		City:          address.City,
+		PostalCode:    address.PostalCode,
+		Region:        address.Region,
+		Country:       address.Country,
+	}
+}
 
-	if err != nil {
-		log.Println("Error updating order status", err)
-		return nil, err
+func encodeSubOrder(sub *models.SubOrder) *pb.SubOrder {
+	encoded := &pb.SubOrder{
+		Id:       uint64(sub.ID),
+		OrderId:  uint64(sub.OrderID),
+		SellerId: sub.SellerID,
+		Status:   sub.Status.String(),
+		Subtotal: sub.Subtotal.ToProto(),
+		Discount: sub.Discount.ToProto(),
+		Tax:      sub.Tax.ToProto(),
+		Shipping: sub.Shipping.ToProto(),
+		Total:    sub.Total.ToProto(),
 	}
+	encoded.CreatedAt, _ = sub.CreatedAt.MarshalBinary()
+	encoded.UpdatedAt, _ = sub.UpdatedAt.MarshalBinary()
+	return encoded
+}
 
-	return &emptypb.Empty{}, nil
+// encodeSellerOrder encodes a sub-order with the seller's lines.
+func encodeSellerOrder(order *models.SellerOrder) *pb.SellerOrder {
+	encoded := &pb.SellerOrder{
+		SubOrder:        encodeSubOrder(&order.SubOrder),
+		AccountId:       order.AccountID,
+		ShippingAddress: encodeAddress(order.ShippingAddress),
+		ShippingMethod:  order.ShippingMethod,
+		Lines:           []*pb.ProductInfo{},
+	}
+	for _, line := range order.Lines {
+		encoded.Lines = append(encoded.Lines, &pb.ProductInfo{
+			Id:          line.ProductID,
+			Name:        line.Name,
+			Description: line.Description,
+			Price:       line.UnitPrice.ToProto(),
+			LineTotal:   line.LineTotal.ToProto(),
+			Quantity:    uint32(line.Quantity),
+			SellerId:    line.SellerID,
+		})
+	}
+	return encoded
+}
+
+func encodeRiskReview(review *models.RiskReview) *pb.RiskReview {
+	encoded := &pb.RiskReview{
+		OrderId:      uint64(review.OrderID),
+		Score:        int32(review.Score),
+		Decision:     review.Decision.String(),
+		Findings:     []*pb.RiskFinding{},
+		ReviewStatus: review.ReviewStatus.String(),
+		ClientIp:     review.ClientIP,
+		ReviewedBy:   review.ReviewedBy,
+		ReviewNote:   review.ReviewNote,
+		Order:        encodeOrder(review.Order),
+	}
+	for _, finding := range review.Findings {
+		encoded.Findings = append(encoded.Findings, &pb.RiskFinding{
+			Rule:   finding.Rule,
+			Score:  int32(finding.Score),
+			Reason: finding.Reason,
+		})
+	}
+	if review.ReviewedAt != nil {
+		encoded.ReviewedAt, _ = review.ReviewedAt.MarshalBinary()
+	}
+	encoded.CreatedAt, _ = review.CreatedAt.MarshalBinary()
+	return encoded
+}
+
+func encodeRecurringOrder(recurring *models.RecurringOrder) *pb.RecurringOrder {
+	encoded := &pb.RecurringOrder{
+		Id:              uint64(recurring.ID),
+		AccountId:       recurring.AccountID,
+		Currency:        recurring.Currency,
+		Interval:        recurring.Interval.String(),
+		Status:          recurring.Status.String(),
+		Products:        []*pb.OrderProduct{},
+		ShippingAddress: encodeAddress(recurring.ShippingAddress),
+		ShippingMethod:  recurring.ShippingMethod,
+		RedirectURL:     recurring.RedirectURL,
+		LastOrderId:     uint64(recurring.LastOrderID),
+		LastCheckoutUrl: recurring.LastCheckoutURL,
+		LastError:       recurring.LastError,
+	}
+	for _, item := range recurring.Items {
+		encoded.Products = append(encoded.Products, &pb.OrderProduct{Id: item.ProductID, Quantity: item.Quantity})
+	}
+	encoded.NextRunAt, _ = recurring.NextRunAt.MarshalBinary()
+	encoded.CreatedAt, _ = recurring.CreatedAt.MarshalBinary()
+	return encoded
+}
+
+// decodeRecurringOrder reads the schedule of a recurring order to create.
+func decodeRecurringOrder(encoded *pb.RecurringOrder) (*models.RecurringOrder, error) {
+	if encoded == nil {
+		return nil, ErrInvalidSchedule
+	}
+	recurring := &models.RecurringOrder{
+		AccountID:       encoded.AccountId,
+		Currency:        encoded.Currency,
+		Interval:        models.RecurringInterval(encoded.Interval),
+		ShippingAddress: decodeAddress(encoded.ShippingAddress),
+		ShippingMethod:  encoded.ShippingMethod,
+		RedirectURL:     encoded.RedirectURL,
+	}
+	for _, p := range encoded.Products {
+		recurring.Items = append(recurring.Items, &models.RecurringItem{ProductID: p.Id, Quantity: p.Quantity})
+	}
+	if len(encoded.NextRunAt) > 0 {
+		if err := recurring.NextRunAt.UnmarshalBinary(encoded.NextRunAt); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid nextRunAt")
+		}
+	}
+	return recurring, nil
+}
+
+// encodeReturn encodes a return with its lines and history.
+func encodeReturn(ret *models.Return) *pb.OrderReturn {
+	encoded := &pb.OrderReturn{
+		Id:                  uint64(ret.ID),
+		OrderId:             uint64(ret.OrderID),
+		AccountId:           ret.AccountID,
+		Status:              ret.Status.String(),
+		Reason:              ret.Reason,
+		RefundAmount:        ret.RefundAmount.ToProto(),
+		RefundId:            ret.RefundID,
+		RefundToStoreCredit: ret.RefundToStoreCredit,
+	}
+	encoded.CreatedAt, _ = ret.CreatedAt.MarshalBinary()
+
+	for _, line := range ret.Lines {
+		encoded.Lines = append(encoded.Lines, &pb.OrderReturnLine{
+			ProductId:    line.ProductID,
+			Quantity:     uint32(line.Quantity),
+			RefundAmount: line.RefundAmount.ToProto(),
+		})
+	}
+	for _, transition := range ret.History {
+		encodedTransition := &pb.OrderReturnTransition{
+			From:  transition.From.String(),
+			To:    transition.To.String(),
+			Actor: transition.Actor,
+			Note:  transition.Note,
+		}
+		encodedTransition.CreatedAt, _ = transition.CreatedAt.MarshalBinary()
+		encoded.History = append(encoded.History, encodedTransition)
+	}
+	return encoded
+}
+
+func encodeDiscount(discount *models.OrderDiscount) *pb.OrderDiscount {
+	return &pb.OrderDiscount{
+		PromotionId: uint64(discount.PromotionID),
+		Code:        discount.Code,
+		Description: discount.Description,
+		Amount:      discount.Amount.ToProto(),
+	}
+}
+
+func encodePromotion(promotion *models.Promotion) *pb.Promotion {
+	encoded := &pb.Promotion{
+		Id:                    uint64(promotion.ID),
+		Code:                  promotion.Code,
+		Description:           promotion.Description,
+		Kind:                  string(promotion.Kind),
+		PercentOff:            uint32(promotion.PercentOff),
+		ProductIds:            promotion.ProductIDs,
+		Categories:            promotion.Categories,
+		BuyQuantity:           uint32(promotion.BuyQuantity),
+		GetQuantity:           uint32(promotion.GetQuantity),
+		UsageLimitPerCustomer: uint32(promotion.UsageLimitPerCustomer),
+		Active:                promotion.Active,
+	}
+	if promotion.AmountOff.Currency != "" {
+		encoded.AmountOff = promotion.AmountOff.ToProto()
+	}
+	if !promotion.MinSubtotal.IsZero() {
+		encoded.MinSubtotal = promotion.MinSubtotal.ToProto()
+	}
+	if promotion.StartsAt != nil {
+		encoded.StartsAt, _ = promotion.StartsAt.MarshalBinary()
+	}
+	if promotion.EndsAt != nil {
+		encoded.EndsAt, _ = promotion.EndsAt.MarshalBinary()
+	}
+	encoded.CreatedAt, _ = promotion.CreatedAt.MarshalBinary()
+	return encoded
+}
+
+func decodePromotion(encoded *pb.Promotion) (*models.Promotion, error) {
+	if encoded == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing promotion")
+	}
+	promotion := &models.Promotion{
+		ID:                    uint(encoded.Id),
+		Code:                  encoded.Code,
+		Description:           encoded.Description,
+		Kind:                  models.PromotionKind(encoded.Kind),
+		PercentOff:            int(encoded.PercentOff),
+		ProductIDs:            encoded.ProductIds,
+		Categories:            encoded.Categories,
+		BuyQuantity:           int(encoded.BuyQuantity),
+		GetQuantity:           int(encoded.GetQuantity),
+		UsageLimitPerCustomer: int(encoded.UsageLimitPerCustomer),
+		Active:                encoded.Active,
+	}
+	if encoded.AmountOff != nil {
+		promotion.AmountOff = money.FromProto(encoded.AmountOff)
+	}
+	if encoded.MinSubtotal != nil {
+		promotion.MinSubtotal = money.FromProto(encoded.MinSubtotal)
+	}
+	if len(encoded.StartsAt) > 0 {
+		promotion.StartsAt = new(time.Time)
+		if err := promotion.StartsAt.UnmarshalBinary(encoded.StartsAt); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid startsAt")
+		}
+	}
+	if len(encoded.EndsAt) > 0 {
+		promotion.EndsAt = new(time.Time)
+		if err := promotion.EndsAt.UnmarshalBinary(encoded.EndsAt); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid endsAt")
+		}
+	}
+	return promotion, nil
+}
+
+func decodeAddress(address *pb.OrderAddress) models.Address {
+	if address == nil {
+		return models.Address{}
+	}
+	return models.Address{
+		RecipientName: address.RecipientName,
+		Line1:         address.Line1,
+		Line2:         address.Line2,
+		City:          address.City,
+		PostalCode:    address.PostalCode,
+		Region:        address.Region,
+		Country:       address.Country,
+	}
 }
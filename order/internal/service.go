@@ -2,42 +2,188 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/rasadov/EcommerceAPI/order/models"
 	"github.com/rasadov/EcommerceAPI/pkg/kafka"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/pkg/tax"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrInvalidOrderStatus = status.Error(codes.InvalidArgument, "invalid order status")
+	ErrMissingActor       = status.Error(codes.InvalidArgument, "status change requires an actor")
+	ErrInvalidCursor      = status.Error(codes.InvalidArgument, models.ErrInvalidCursor.Error())
+	ErrInvalidDateRange   = status.Error(codes.InvalidArgument, "createdAfter must be before createdBefore")
+	ErrInvalidExportRange = status.Error(codes.InvalidArgument, "exports need a from date before the to date")
+	ErrInvalidTotalRange  = status.Error(codes.InvalidArgument, "minTotal and maxTotal must be in the same currency, with minTotal not above maxTotal")
+	ErrPromotionExists    = status.Error(codes.AlreadyExists, "a promotion with this code already exists")
+	ErrInvalidPromotion   = status.Error(codes.InvalidArgument, "promotion rules are incomplete or inconsistent")
+	ErrIncompleteAddress  = status.Error(codes.InvalidArgument, "shipped orders need a recipient, street, city and country")
+	ErrOrderNotReturnable = status.Error(codes.FailedPrecondition, "only delivered orders can be returned")
+	ErrMissingReturnInfo  = status.Error(codes.InvalidArgument, "a return needs a reason and at least one line")
+	ErrInvalidReturnLine  = status.Error(codes.InvalidArgument, "return lines must name ordered products with a positive quantity")
+	ErrReturnExceedsOrder = status.Error(codes.FailedPrecondition, "return lines exceed the units left to return")
+	ErrOrderNotPaid       = status.Error(codes.FailedPrecondition, "only paid orders are invoiced")
+	ErrInvalidSellerState = status.Error(codes.InvalidArgument, "sellers can only move their orders to fulfilling, shipped or delivered")
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
 )
 
 type Service interface {
-	PostOrder(ctx context.Context, accountID uint64, totalPrice float64, products []*models.OrderedProduct) (*models.Order, error)
+	PostOrder(ctx context.Context, accountID uint64, currency string, products []*models.OrderedProduct, couponCode string, address models.Address, shipping models.Shipping) (*models.Order, error)
 	GetOrdersForAccount(ctx context.Context, accountID uint64) ([]*models.Order, error)
-	UpdateOrderPaymentStatus(ctx context.Context, orderId uint64, status string) error
+	GetOrder(ctx context.Context, orderId, accountId uint64) (*models.Order, error)
+	ListOrders(ctx context.Context, filter models.OrderFilter, after string, first int) (*models.OrderPage, error)
+	ExportOrders(ctx context.Context, from, to time.Time, fn func(*models.Order) error) error
+	GetOrderLinesForProducts(ctx context.Context, productIds []string, skip, take uint64) ([]*models.OrderLine, error)
+	GetSalesForProducts(ctx context.Context, productIds []string) (*models.SalesSummary, error)
+	UpdateOrderStatus(ctx context.Context, orderId uint64, status string, actor, reason string) (*models.Order, error)
+	CancelOrder(ctx context.Context, orderId, accountId uint64, reason string) (*models.Order, error)
+	ExpireOrders(ctx context.Context, placedBefore time.Time, limit int) ([]*models.Order, error)
+	RecordShipment(ctx context.Context, orderId uint64, complete bool, reason string) (*models.Order, error)
+	RecordDelivery(ctx context.Context, orderId uint64, reason string) (*models.Order, error)
+	RequestReturn(ctx context.Context, orderId, accountId uint64, lines []*models.ReturnLine, reason string, toStoreCredit bool) (*models.Return, error)
+	GetReturn(ctx context.Context, returnId uint) (*models.Return, error)
+	DecideReturn(ctx context.Context, returnId uint, approve bool, actor, note string) (*models.Return, error)
+	ReceiveReturn(ctx context.Context, returnId uint, actor string) (*models.Return, bool, error)
+	RecordReturnRefund(ctx context.Context, returnId uint, refundId, actor string) (*models.Return, error)
+	GetOrdersWithoutLineSnapshots(ctx context.Context, limit int) ([]*models.Order, error)
+	SaveLineSnapshots(ctx context.Context, lines []*models.ProductsInfo) error
+	CreateSaga(ctx context.Context, saga *models.PlacementSaga) error
+	AdvanceSaga(ctx context.Context, saga *models.PlacementSaga, from models.SagaState) error
+	ListPendingSagas(ctx context.Context, afterID uint, limit int) ([]*models.PlacementSaga, error)
+	GetSagaForOrder(ctx context.Context, orderId uint) (*models.PlacementSaga, error)
+	IssueInvoice(ctx context.Context, order *models.Order, seller, buyer models.Party) (*models.Invoice, error)
+	GetInvoice(ctx context.Context, orderId uint64) (*models.Invoice, error)
+	UpdateSubOrderStatus(ctx context.Context, subOrderId uint, sellerId uint64, status, actor string) (*models.SubOrder, error)
+	ListSellerOrders(ctx context.Context, sellerId uint64, statuses []models.OrderStatus, after string, first int) (*models.SellerOrderPage, error)
+	CreateRecurringOrder(ctx context.Context, recurring *models.RecurringOrder) (*models.RecurringOrder, error)
+	GetRecurringOrder(ctx context.Context, recurringOrderId uint, accountId uint64) (*models.RecurringOrder, error)
+	ListRecurringOrders(ctx context.Context, accountId uint64) ([]*models.RecurringOrder, error)
+	UpdateRecurringOrderStatus(ctx context.Context, recurringOrderId uint, accountId uint64, status string) (*models.RecurringOrder, error)
+	ClaimDueRecurringOrders(ctx context.Context, now time.Time, limit int) ([]*models.RecurringOrder, error)
+	RecordRecurringRun(ctx context.Context, recurring *models.RecurringOrder, order *models.Order, checkoutURL string, runErr error) error
+	RecordRiskAssessment(ctx context.Context, assessment *models.RiskAssessment) error
+	GetRiskReview(ctx context.Context, orderId uint) (*models.RiskReview, error)
+	ListRiskReviews(ctx context.Context, reviewStatus string, after string, first int) (*models.RiskReviewPage, error)
+	DecideRiskReview(ctx context.Context, assessment *models.RiskAssessment, approve bool, actor, note string) error
+	CountAccountAttempts(ctx context.Context, accountId uint64, since time.Time) (int64, error)
+	CountIPAttempts(ctx context.Context, clientIP string, since time.Time) (int64, error)
+	CountPaidOrders(ctx context.Context, accountId uint64) (int64, error)
+	CreatePromotion(ctx context.Context, promotion *models.Promotion) (*models.Promotion, error)
+	UpdatePromotion(ctx context.Context, promotion *models.Promotion) (*models.Promotion, error)
+	DeactivatePromotion(ctx context.Context, promotionId uint) (*models.Promotion, error)
+	ListPromotions(ctx context.Context, includeInactive bool) ([]*models.Promotion, error)
+	EvaluatePromotion(ctx context.Context, code string, accountID uint64, currency string, products []*models.OrderedProduct) (*models.OrderDiscount, error)
 	GetProducer() sarama.AsyncProducer
 }
 
 type orderService struct {
-	repository Repository
-	producer   sarama.AsyncProducer
+	repository    Repository
+	producer      sarama.AsyncProducer
+	converter     *money.Converter
+	taxCalculator tax.Calculator
 }
 
-func NewOrderService(repository Repository, producer sarama.AsyncProducer) Service {
-	return &orderService{repository, producer}
+// NewOrderService returns an order service. The converter prices promotion amounts in the
+// order currency and taxCalculator works out the taxes of orders; orders are not taxed
+// when it is nil.
+func NewOrderService(repository Repository, producer sarama.AsyncProducer, converter *money.Converter, taxCalculator tax.Calculator) Service {
+	return &orderService{repository, producer, converter, taxCalculator}
 }
 
 func (service orderService) GetProducer() sarama.AsyncProducer {
 	return service.producer
 }
 
-func (service orderService) PostOrder(ctx context.Context, accountID uint64, totalPrice float64, products []*models.OrderedProduct) (*models.Order, error) {
+// PostOrder places an order for the products delivered to address, shipped as priced by
+// shipping unless its method is empty. A non-empty couponCode applies that promotion, which
+// is then redeemed by the account.
+func (service orderService) PostOrder(ctx context.Context, accountID uint64, currency string, products []*models.OrderedProduct, couponCode string, address models.Address, shipping models.Shipping) (*models.Order, error) {
+	totalPrice, err := models.OrderTotal(currency, products)
+	if err != nil {
+		return nil, err
+	}
+
+	var discounts []*models.OrderDiscount
+	if couponCode != "" {
+		discount, err := service.EvaluatePromotion(ctx, couponCode, accountID, currency, products)
+		if err != nil {
+			return nil, err
+		}
+		totalPrice, err = totalPrice.Sub(discount.Amount)
+		if err != nil {
+			return nil, err
+		}
+		discounts = append(discounts, discount)
+	}
+
+	address = address.Normalize()
+	if shipping.Method != "" {
+		if !address.Deliverable() {
+			return nil, ErrIncompleteAddress
+		}
+		totalPrice, err = totalPrice.Add(shipping.Cost)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	taxes, err := service.calculateTaxes(ctx, address.TaxLocation(), currency, products, discounts)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range taxes {
+		if t.Inclusive {
+			continue
+		}
+		totalPrice, err = totalPrice.Add(t.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	createdAt := time.Now().UTC()
 	order := models.Order{
 		AccountID:  accountID,
 		TotalPrice: totalPrice,
-		Products:   products,
-		CreatedAt:  time.Now().UTC(),
+		Status:     models.StatusPendingPayment,
+		// The server reserves stock for every order before posting it
+		StockReserved: true,
+		History: []*models.StatusTransition{{
+			To:        models.StatusPendingPayment,
+			Actor:     fmt.Sprintf("account:%d", accountID),
+			Reason:    "order placed",
+			CreatedAt: createdAt,
+		}},
+		ShippingAddress: address,
+		ShippingMethod:  shipping.Method,
+		ShippingCost:    shipping.Cost,
+		Discounts:       discounts,
+		Taxes:           taxes,
+		Products:        products,
+		CreatedAt:       createdAt,
+	}
+	// Every seller fulfils their part of the order on their own
+	order.SubOrders, err = models.SplitBySeller(&order)
+	if err != nil {
+		return nil, err
+	}
+	err = service.repository.PutOrder(ctx, &order)
+	if errors.Is(err, models.ErrPromotionUsageLimit) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
 	}
-	err := service.repository.PutOrder(ctx, &order)
 	if err != nil {
 		return nil, err
 	}
@@ -65,10 +211,1042 @@ func (service orderService) PostOrder(ctx context.Context, accountID uint64, tot
 	return &order, nil
 }
 
+// calculateTaxes works out the tax lines of products delivered to location. Each line is
+// taxed on its price less its share of the discounts.
+func (service orderService) calculateTaxes(ctx context.Context, location tax.Location, currency string, products []*models.OrderedProduct, discounts []*models.OrderDiscount) ([]*models.OrderTax, error) {
+	if service.taxCalculator == nil || len(products) == 0 {
+		return nil, nil
+	}
+
+	shares, err := models.DiscountShares(currency, products, discounts)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]tax.Line, 0, len(products))
+	for i, product := range products {
+		category, err := tax.ParseCategory(product.TaxCategory)
+		if err != nil {
+			category = tax.CategoryStandard
+		}
+		amount := product.LineTotal()
+		if !shares[i].IsZero() {
+			amount, err = amount.Sub(shares[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+		lines = append(lines, tax.Line{ProductID: product.ID, Category: category, Amount: amount})
+	}
+
+	assessment, err := service.taxCalculator.Calculate(ctx, location, currency, lines)
+	if err != nil {
+		return nil, err
+	}
+	taxes := make([]*models.OrderTax, 0, len(assessment.Lines))
+	for _, line := range assessment.Lines {
+		taxes = append(taxes, &models.OrderTax{
+			ProductID:   line.ProductID,
+			TaxCategory: string(line.Category),
+			Name:        line.Name,
+			Rate:        line.Rate,
+			Inclusive:   assessment.Inclusive,
+			Amount:      line.Amount,
+		})
+	}
+	return taxes, nil
+}
+
 func (service orderService) GetOrdersForAccount(ctx context.Context, accountID uint64) ([]*models.Order, error) {
 	return service.repository.GetOrdersForAccount(ctx, accountID)
 }
 
-func (service orderService) UpdateOrderPaymentStatus(ctx context.Context, orderId uint64, paymnetStatus string) error {
-	return service.repository.UpdateOrderPaymentStatus(ctx, orderId, paymnetStatus)
+// GetOrder returns an order owned by accountId, or any order when accountId is 0.
+// Orders of other accounts are reported as not found.
+func (service orderService) GetOrder(ctx context.Context, orderId, accountId uint64) (*models.Order, error) {
+	order, err := service.getOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	if accountId != 0 && order.AccountID != accountId {
+		return nil, status.Errorf(codes.NotFound, "order %d not found", orderId)
+	}
+	return order, nil
+}
+
+// ListOrders returns the first orders matching filter after the cursor, newest first.
+// first defaults to DefaultPageSize and is capped at MaxPageSize.
+func (service orderService) ListOrders(ctx context.Context, filter models.OrderFilter, after string, first int) (*models.OrderPage, error) {
+	for _, s := range filter.Statuses {
+		if _, ok := models.ParseOrderStatus(s.String()); !ok {
+			return nil, ErrInvalidOrderStatus
+		}
+	}
+	if !filter.CreatedAfter.IsZero() && !filter.CreatedBefore.IsZero() && !filter.CreatedAfter.Before(filter.CreatedBefore) {
+		return nil, ErrInvalidDateRange
+	}
+	if filter.MinTotal != nil && filter.MaxTotal != nil {
+		cmp, err := filter.MinTotal.Cmp(*filter.MaxTotal)
+		if err != nil || cmp > 0 {
+			return nil, ErrInvalidTotalRange
+		}
+	}
+
+	var afterID uint
+	if after != "" {
+		var err error
+		afterID, err = models.DecodeCursor(after)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+	first = min(first, MaxPageSize)
+
+	// Fetch one extra order to learn whether another page follows
+	orders, err := service.repository.ListOrders(ctx, filter, afterID, first+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.OrderPage{Orders: orders}
+	if len(orders) > first {
+		page.Orders = orders[:first]
+		page.HasNextPage = true
+	}
+	if len(page.Orders) > 0 {
+		page.EndCursor = models.EncodeCursor(page.Orders[len(page.Orders)-1].ID)
+	}
+	return page, nil
+}
+
+// ExportOrders calls fn with every order placed from from until to, oldest first, for
+// accounting exports.
+func (service orderService) ExportOrders(ctx context.Context, from, to time.Time, fn func(*models.Order) error) error {
+	if from.IsZero() || to.IsZero() || !from.Before(to) {
+		return ErrInvalidExportRange
+	}
+	return service.repository.ExportOrders(ctx, from, to, fn)
+}
+
+func (service orderService) GetOrdersWithoutLineSnapshots(ctx context.Context, limit int) ([]*models.Order, error) {
+	return service.repository.GetOrdersWithoutLineSnapshots(ctx, limit)
+}
+
+func (service orderService) SaveLineSnapshots(ctx context.Context, lines []*models.ProductsInfo) error {
+	return service.repository.SaveLineSnapshots(ctx, lines)
+}
+
+func (service orderService) CreateSaga(ctx context.Context, saga *models.PlacementSaga) error {
+	return service.repository.CreateSaga(ctx, saga)
+}
+
+func (service orderService) AdvanceSaga(ctx context.Context, saga *models.PlacementSaga, from models.SagaState) error {
+	return service.repository.AdvanceSaga(ctx, saga, from)
+}
+
+func (service orderService) ListPendingSagas(ctx context.Context, afterID uint, limit int) ([]*models.PlacementSaga, error) {
+	return service.repository.ListPendingSagas(ctx, afterID, limit)
+}
+
+// UpdateSubOrderStatus moves a sub-order of sellerId, or any sub-order when sellerId is 0,
+// on in its fulfilment on behalf of actor. The order follows once its sub-orders are all
+// on their way: it is fulfilling as soon as one is, and shipped or delivered once they
+// all are.
+func (service orderService) UpdateSubOrderStatus(ctx context.Context, subOrderId uint, sellerId uint64, newStatus, actor string) (*models.SubOrder, error) {
+	to, ok := models.ParseOrderStatus(newStatus)
+	if !ok {
+		return nil, ErrInvalidOrderStatus
+	}
+	if !to.SellerCanSet() {
+		return nil, ErrInvalidSellerState
+	}
+	if actor == "" {
+		return nil, ErrMissingActor
+	}
+
+	sub, err := service.repository.GetSubOrder(ctx, subOrderId)
+	if errors.Is(err, ErrSubOrderNotFound) || (err == nil && sellerId != 0 && sub.SellerID != sellerId) {
+		return nil, status.Errorf(codes.NotFound, "sub-order %d not found", subOrderId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == to {
+		return sub, nil
+	}
+	if !sub.Status.CanTransitionTo(to) {
+		return nil, status.Errorf(codes.FailedPrecondition, "sub-order %d cannot move from %s to %s", sub.ID, sub.Status, to)
+	}
+
+	from := sub.Status
+	sub.Status = to
+	err = service.repository.TransitionSubOrder(ctx, sub, from)
+	if errors.Is(err, ErrSubOrderConflict) {
+		return nil, status.Errorf(codes.Aborted, "sub-order %d changed status concurrently", sub.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := service.getOrder(ctx, uint64(sub.OrderID))
+	if err != nil {
+		return nil, err
+	}
+	target, ok := models.FulfilmentStatus(order.SubOrders)
+	for ok && order.Status != target {
+		next, found := models.NextStatusToward(order.Status, target)
+		if !found {
+			break
+		}
+		order, err = service.transition(ctx, order, next, actor, fmt.Sprintf("sub-order %d %s", sub.ID, to))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sub, nil
+}
+
+// CreateRecurringOrder schedules the account's recurring order. Its first order is placed
+// at NextRunAt, or right away when that is not in the future.
+func (service orderService) CreateRecurringOrder(ctx context.Context, recurring *models.RecurringOrder) (*models.RecurringOrder, error) {
+	if !recurring.Interval.Valid() {
+		return nil, ErrInvalidInterval
+	}
+	if len(recurring.Items) == 0 {
+		return nil, ErrInvalidSchedule
+	}
+	for _, item := range recurring.Items {
+		if item.ProductID == "" || item.Quantity == 0 {
+			return nil, ErrInvalidSchedule
+		}
+	}
+	currency, err := money.Validate(recurring.Currency)
+	if err != nil {
+		return nil, err
+	}
+	recurring.Currency = currency
+	recurring.ShippingAddress = recurring.ShippingAddress.Normalize()
+	if recurring.ShippingMethod != "" && !recurring.ShippingAddress.Deliverable() {
+		return nil, ErrIncompleteAddress
+	}
+
+	now := time.Now().UTC()
+	if recurring.NextRunAt.Before(now) {
+		recurring.NextRunAt = now
+	}
+	recurring.Status = models.ScheduleActive
+	err = service.repository.CreateRecurringOrder(ctx, recurring)
+	if err != nil {
+		return nil, err
+	}
+	return recurring, nil
+}
+
+// GetRecurringOrder returns a recurring order of accountId, or of any account when
+// accountId is 0. Recurring orders of other accounts are reported as not found.
+func (service orderService) GetRecurringOrder(ctx context.Context, recurringOrderId uint, accountId uint64) (*models.RecurringOrder, error) {
+	recurring, err := service.repository.GetRecurringOrder(ctx, recurringOrderId)
+	if errors.Is(err, ErrScheduleNotFound) || (err == nil && accountId != 0 && recurring.AccountID != accountId) {
+		return nil, status.Errorf(codes.NotFound, "recurring order %d not found", recurringOrderId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return recurring, nil
+}
+
+func (service orderService) ListRecurringOrders(ctx context.Context, accountId uint64) ([]*models.RecurringOrder, error) {
+	return service.repository.ListRecurringOrders(ctx, accountId)
+}
+
+// UpdateRecurringOrderStatus pauses, resumes or cancels one of the account's recurring
+// orders. A resumed schedule skips the runs it missed while paused.
+func (service orderService) UpdateRecurringOrderStatus(ctx context.Context, recurringOrderId uint, accountId uint64, newStatus string) (*models.RecurringOrder, error) {
+	to, ok := models.ParseScheduleStatus(newStatus)
+	if !ok {
+		return nil, ErrInvalidScheduleStatus
+	}
+	recurring, err := service.GetRecurringOrder(ctx, recurringOrderId, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if recurring.Status == to {
+		return recurring, nil
+	}
+	if !recurring.Status.CanTransitionTo(to) {
+		return nil, status.Errorf(codes.FailedPrecondition, "recurring order %d cannot move from %s to %s", recurring.ID, recurring.Status, to)
+	}
+
+	from := recurring.Status
+	recurring.Status = to
+	if to == models.ScheduleActive {
+		recurring.Advance(time.Now().UTC())
+	}
+	err = service.repository.UpdateRecurringOrder(ctx, recurring, from)
+	if errors.Is(err, ErrScheduleConflict) {
+		return nil, status.Errorf(codes.Aborted, "recurring order %d changed status concurrently", recurring.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return recurring, nil
+}
+
+// ClaimDueRecurringOrders returns up to limit active recurring orders due at now, and moves
+// their next run on. Placing their orders is left to the caller.
+func (service orderService) ClaimDueRecurringOrders(ctx context.Context, now time.Time, limit int) ([]*models.RecurringOrder, error) {
+	return service.repository.ClaimDueRecurringOrders(ctx, now, limit)
+}
+
+// RecordRecurringRun saves the order a run of the recurring order placed, or the error that
+// kept it from placing one, and announces the run on order_events.
+func (service orderService) RecordRecurringRun(ctx context.Context, recurring *models.RecurringOrder, order *models.Order, checkoutURL string, runErr error) error {
+	event := models.RecurringRunEvent{
+		Type: "recurring_order_placed",
+		Data: models.RecurringRunData{
+			RecurringOrderId: recurring.ID,
+			AccountId:        int(recurring.AccountID),
+			NextRunAt:        recurring.NextRunAt,
+		},
+	}
+	if runErr != nil {
+		recurring.LastError = status.Convert(runErr).Message()
+		event.Type = "recurring_order_failed"
+		event.Data.Error = recurring.LastError
+	} else {
+		recurring.LastOrderID = order.ID
+		recurring.LastCheckoutURL = checkoutURL
+		recurring.LastError = ""
+		event.Data.OrderId = order.ID
+		event.Data.CheckoutURL = checkoutURL
+	}
+	err := service.repository.RecordRecurringRun(ctx, recurring)
+	if err != nil {
+		return err
+	}
+
+	err = kafka.SendMessageToRecommender(service, event, "order_events")
+	if err != nil {
+		log.Println("Failed to send recurring order event:", err)
+	}
+	return nil
+}
+
+func (service orderService) GetSagaForOrder(ctx context.Context, orderId uint) (*models.PlacementSaga, error) {
+	return service.repository.GetSagaForOrder(ctx, orderId)
+}
+
+// RecordRiskAssessment stores the screening of an order, and announces orders held for
+// review or blocked on order_events.
+func (service orderService) RecordRiskAssessment(ctx context.Context, assessment *models.RiskAssessment) error {
+	err := service.repository.CreateRiskAssessment(ctx, assessment)
+	if err != nil {
+		return err
+	}
+	if assessment.Decision == models.DecisionAllow {
+		return nil
+	}
+
+	err = kafka.SendMessageToRecommender(service, models.RiskEvent{
+		Type: "order_risk_flagged",
+		Data: models.RiskData{
+			OrderId:   assessment.OrderID,
+			AccountId: int(assessment.AccountID),
+			Score:     assessment.Score,
+			Decision:  assessment.Decision,
+			Findings:  assessment.Findings,
+		},
+	}, "order_events")
+	if err != nil {
+		log.Println("Failed to send risk event:", err)
+	}
+	return nil
+}
+
+// GetRiskReview returns the screening of an order along with the order.
+func (service orderService) GetRiskReview(ctx context.Context, orderId uint) (*models.RiskReview, error) {
+	assessment, err := service.repository.GetRiskAssessment(ctx, orderId)
+	if errors.Is(err, ErrReviewNotFound) {
+		return nil, status.Errorf(codes.NotFound, "order %d was not screened", orderId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	order, err := service.GetOrder(ctx, uint64(orderId), 0)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RiskReview{RiskAssessment: *assessment, Order: order}, nil
+}
+
+// ListRiskReviews returns the first of the orders held for review after the cursor, oldest
+// first, whose review is in reviewStatus; pending reviews when it is empty.
+func (service orderService) ListRiskReviews(ctx context.Context, reviewStatus string, after string, first int) (*models.RiskReviewPage, error) {
+	inStatus := models.ReviewPending
+	if reviewStatus != "" {
+		var ok bool
+		inStatus, ok = models.ParseReviewStatus(reviewStatus)
+		if !ok {
+			return nil, ErrInvalidReviewStatus
+		}
+	}
+
+	var afterID uint
+	if after != "" {
+		var err error
+		afterID, err = models.DecodeCursor(after)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+	first = min(first, MaxPageSize)
+
+	// Fetch one extra assessment to learn whether another page follows
+	assessments, err := service.repository.ListRiskAssessments(ctx, inStatus, afterID, first+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.RiskReviewPage{Reviews: []*models.RiskReview{}}
+	if len(assessments) > first {
+		assessments = assessments[:first]
+		page.HasNextPage = true
+	}
+	for _, assessment := range assessments {
+		order, err := service.GetOrder(ctx, uint64(assessment.OrderID), 0)
+		if err != nil {
+			return nil, err
+		}
+		page.Reviews = append(page.Reviews, &models.RiskReview{RiskAssessment: *assessment, Order: order})
+	}
+	if len(assessments) > 0 {
+		page.EndCursor = models.EncodeCursor(assessments[len(assessments)-1].ID)
+	}
+	return page, nil
+}
+
+// DecideRiskReview records actor approving or rejecting the order held for review. Acting
+// on the decision is left to the caller.
+func (service orderService) DecideRiskReview(ctx context.Context, assessment *models.RiskAssessment, approve bool, actor, note string) error {
+	if assessment.ReviewStatus != models.ReviewPending {
+		return ErrNotInReview
+	}
+	reviewedAt := time.Now().UTC()
+	assessment.ReviewStatus = models.ReviewRejected
+	if approve {
+		assessment.ReviewStatus = models.ReviewApproved
+	}
+	assessment.ReviewedBy = actor
+	assessment.ReviewNote = note
+	assessment.ReviewedAt = &reviewedAt
+
+	err := service.repository.DecideRiskReview(ctx, assessment)
+	if errors.Is(err, ErrReviewConflict) {
+		return status.Errorf(codes.Aborted, "the review of order %d was decided concurrently", assessment.OrderID)
+	}
+	return err
+}
+
+func (service orderService) CountAccountAttempts(ctx context.Context, accountId uint64, since time.Time) (int64, error) {
+	return service.repository.CountAccountAttempts(ctx, accountId, since)
+}
+
+func (service orderService) CountIPAttempts(ctx context.Context, clientIP string, since time.Time) (int64, error) {
+	return service.repository.CountIPAttempts(ctx, clientIP, since)
+}
+
+func (service orderService) CountPaidOrders(ctx context.Context, accountId uint64) (int64, error) {
+	return service.repository.CountPaidOrders(ctx, accountId)
+}
+
+// ListSellerOrders returns the first of the seller's sub-orders after the cursor, newest
+// first, each with the seller's own lines only. A non-empty statuses only lists sub-orders
+// in those statuses.
+func (service orderService) ListSellerOrders(ctx context.Context, sellerId uint64, statuses []models.OrderStatus, after string, first int) (*models.SellerOrderPage, error) {
+	for _, s := range statuses {
+		if _, ok := models.ParseOrderStatus(s.String()); !ok {
+			return nil, ErrInvalidOrderStatus
+		}
+	}
+
+	var afterID uint
+	if after != "" {
+		var err error
+		afterID, err = models.DecodeCursor(after)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+	first = min(first, MaxPageSize)
+
+	// Fetch one extra sub-order to learn whether another page follows
+	orders, err := service.repository.ListSellerOrders(ctx, sellerId, statuses, afterID, first+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.SellerOrderPage{Orders: orders}
+	if len(orders) > first {
+		page.Orders = orders[:first]
+		page.HasNextPage = true
+	}
+	if len(page.Orders) > 0 {
+		page.EndCursor = models.EncodeCursor(page.Orders[len(page.Orders)-1].ID)
+	}
+	return page, nil
+}
+
+// IssueInvoice issues the invoice of the paid order, sold by seller to buyer. An order is
+// only invoiced once; issuing it again returns the invoice already issued.
+func (service orderService) IssueInvoice(ctx context.Context, order *models.Order, seller, buyer models.Party) (*models.Invoice, error) {
+	if !order.WasPaid() {
+		return nil, ErrOrderNotPaid
+	}
+
+	invoice, err := service.repository.GetInvoiceForOrder(ctx, uint64(order.ID))
+	if !errors.Is(err, ErrInvoiceNotFound) {
+		return invoice, err
+	}
+
+	invoice, err = models.NewInvoice(order, seller, buyer, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	err = service.repository.CreateInvoice(ctx, invoice)
+	if err != nil {
+		// Another request may have issued the invoice in the meantime
+		existing, getErr := service.repository.GetInvoiceForOrder(ctx, uint64(order.ID))
+		if getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (service orderService) GetInvoice(ctx context.Context, orderId uint64) (*models.Invoice, error) {
+	invoice, err := service.repository.GetInvoiceForOrder(ctx, orderId)
+	if errors.Is(err, ErrInvoiceNotFound) {
+		return nil, status.Errorf(codes.NotFound, "order %d has no invoice", orderId)
+	}
+	return invoice, err
+}
+
+// GetOrderLinesForProducts returns a page of the paid order lines for the products, of at
+// most 100 lines.
+func (service orderService) GetOrderLinesForProducts(ctx context.Context, productIds []string, skip, take uint64) ([]*models.OrderLine, error) {
+	if len(productIds) == 0 {
+		return nil, nil
+	}
+	if take == 0 || take > 100 {
+		take = 100
+	}
+	return service.repository.GetOrderLinesForProducts(ctx, productIds, skip, take)
+}
+
+func (service orderService) GetSalesForProducts(ctx context.Context, productIds []string) (*models.SalesSummary, error) {
+	if len(productIds) == 0 {
+		return &models.SalesSummary{}, nil
+	}
+	return service.repository.GetSalesForProducts(ctx, productIds)
+}
+
+// UpdateOrderStatus moves an order to status on behalf of actor, enforcing the
+// allowed transitions. Moving an order to the status it is already in is a no-op,
+// so redelivered payment webhooks are harmless.
+func (service orderService) UpdateOrderStatus(ctx context.Context, orderId uint64, newStatus string, actor, reason string) (*models.Order, error) {
+	to, ok := models.ParseOrderStatus(newStatus)
+	if !ok {
+		return nil, ErrInvalidOrderStatus
+	}
+	if actor == "" {
+		return nil, ErrMissingActor
+	}
+
+	order, err := service.getOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status == to {
+		return order, nil
+	}
+	return service.transition(ctx, order, to, actor, reason)
+}
+
+// CancelOrder cancels an order on behalf of its owner, or of the system when accountId is 0.
+// Releasing stock and refunding a paid order are left to the caller.
+func (service orderService) CancelOrder(ctx context.Context, orderId, accountId uint64, reason string) (*models.Order, error) {
+	order, err := service.getOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := "system"
+	if accountId != 0 {
+		if order.AccountID != accountId {
+			return nil, status.Errorf(codes.PermissionDenied, "order %d belongs to another account", order.ID)
+		}
+		actor = fmt.Sprintf("account:%d", accountId)
+	}
+
+	return service.transition(ctx, order, models.StatusCancelled, actor, reason)
+}
+
+// ExpireOrders cancels up to limit orders placed before placedBefore that are still awaiting
+// payment and announces them with an order_expired event. Releasing their stock and closing
+// their checkout sessions are left to the caller.
+func (service orderService) ExpireOrders(ctx context.Context, placedBefore time.Time, limit int) ([]*models.Order, error) {
+	orders, err := service.repository.ExpireOrders(ctx, placedBefore, limit, "system", "payment window expired")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		transition := order.History[len(order.History)-1]
+		service.announceTransition(order, transition)
+		err = kafka.SendMessageToRecommender(service, models.ExpiredEvent{
+			Type: "order_expired",
+			Data: models.ExpiredData{
+				OrderId:   order.ID,
+				AccountId: int(order.AccountID),
+				Total:     order.TotalPrice,
+				PlacedAt:  order.CreatedAt,
+				ExpiredAt: transition.CreatedAt,
+			},
+		}, "order_events")
+		if err != nil {
+			log.Println("Failed to send order expired event:", err)
+		}
+	}
+	return orders, nil
+}
+
+// RecordShipment moves a paid order to fulfilling once a parcel of it has been sent, and
+// to shipped once every unit has been. Orders already past that point are left alone, so
+// redelivered shipping events are harmless.
+func (service orderService) RecordShipment(ctx context.Context, orderId uint64, complete bool, reason string) (*models.Order, error) {
+	order, err := service.getOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status == models.StatusPaid {
+		order, err = service.transition(ctx, order, models.StatusFulfilling, "shipping", reason)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if complete && order.Status == models.StatusFulfilling {
+		return service.transition(ctx, order, models.StatusShipped, "shipping", reason)
+	}
+	return order, nil
+}
+
+// RecordDelivery moves a shipped order to delivered. Orders in any other status are left
+// alone.
+func (service orderService) RecordDelivery(ctx context.Context, orderId uint64, reason string) (*models.Order, error) {
+	order, err := service.getOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.StatusShipped {
+		return order, nil
+	}
+	return service.transition(ctx, order, models.StatusDelivered, "shipping", reason)
+}
+
+// RequestReturn asks to send back units of a delivered order on behalf of its owner. Each
+// line is refunded what its units were paid, shipping excepted, to the owner's store credit
+// when toStoreCredit is set.
+func (service orderService) RequestReturn(ctx context.Context, orderId, accountId uint64, lines []*models.ReturnLine, reason string, toStoreCredit bool) (*models.Return, error) {
+	order, err := service.GetOrder(ctx, orderId, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.StatusDelivered {
+		return nil, ErrOrderNotReturnable
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(lines) == 0 {
+		return nil, ErrMissingReturnInfo
+	}
+
+	ordered := map[string]int{}
+	for _, product := range order.Products {
+		ordered[product.ID] += int(product.Quantity)
+	}
+	returned := order.ReturnedQuantities()
+
+	// Lines naming the same product are merged
+	var merged []*models.ReturnLine
+	quantities := map[string]int{}
+	for _, line := range lines {
+		if _, ok := ordered[line.ProductID]; !ok || line.Quantity <= 0 {
+			return nil, ErrInvalidReturnLine
+		}
+		if quantities[line.ProductID] == 0 {
+			merged = append(merged, &models.ReturnLine{ProductID: line.ProductID})
+		}
+		quantities[line.ProductID] += line.Quantity
+		if returned[line.ProductID]+quantities[line.ProductID] > ordered[line.ProductID] {
+			return nil, ErrReturnExceedsOrder
+		}
+	}
+
+	refundAmount := money.Zero(order.TotalPrice.Currency)
+	for _, line := range merged {
+		line.Quantity = quantities[line.ProductID]
+		line.RefundAmount, err = order.RefundAmount(line.ProductID, returned[line.ProductID], line.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		refundAmount, err = refundAmount.Add(line.RefundAmount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	createdAt := time.Now().UTC()
+	ret := &models.Return{
+		OrderID:      order.ID,
+		AccountID:    order.AccountID,
+		Status:       models.ReturnRequested,
+		Reason:       reason,
+		Lines:        merged,
+		RefundAmount: refundAmount,
+		// The refund comes after the return arrives, so how it is paid is kept until then
+		RefundToStoreCredit: toStoreCredit,
+		History: []*models.ReturnTransition{{
+			To:        models.ReturnRequested,
+			Actor:     fmt.Sprintf("account:%d", order.AccountID),
+			Note:      reason,
+			CreatedAt: createdAt,
+		}},
+		CreatedAt: createdAt,
+	}
+	err = service.repository.CreateReturn(ctx, ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (service orderService) GetReturn(ctx context.Context, returnId uint) (*models.Return, error) {
+	ret, err := service.repository.GetReturn(ctx, returnId)
+	if errors.Is(err, ErrReturnNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return ret, err
+}
+
+// DecideReturn approves or rejects a requested return on behalf of actor.
+func (service orderService) DecideReturn(ctx context.Context, returnId uint, approve bool, actor, note string) (*models.Return, error) {
+	if actor == "" {
+		return nil, ErrMissingActor
+	}
+	ret, err := service.GetReturn(ctx, returnId)
+	if err != nil {
+		return nil, err
+	}
+
+	to := models.ReturnRejected
+	if approve {
+		to = models.ReturnApproved
+	}
+	return service.transitionReturn(ctx, ret, to, actor, strings.TrimSpace(note), "")
+}
+
+// ReceiveReturn records that the products of an approved return arrived back, reporting
+// whether this call received them. Receiving a return again is a no-op, so the caller can
+// retry its refund; putting the products back into stock and refunding are left to the
+// caller.
+func (service orderService) ReceiveReturn(ctx context.Context, returnId uint, actor string) (*models.Return, bool, error) {
+	if actor == "" {
+		return nil, false, ErrMissingActor
+	}
+	ret, err := service.GetReturn(ctx, returnId)
+	if err != nil {
+		return nil, false, err
+	}
+	if ret.Status == models.ReturnReceived {
+		return ret, false, nil
+	}
+
+	ret, err = service.transitionReturn(ctx, ret, models.ReturnReceived, actor, "products received", "")
+	if err != nil {
+		return nil, false, err
+	}
+	return ret, true, nil
+}
+
+// RecordReturnRefund marks a received return as refunded by the payment provider's refund.
+func (service orderService) RecordReturnRefund(ctx context.Context, returnId uint, refundId, actor string) (*models.Return, error) {
+	ret, err := service.GetReturn(ctx, returnId)
+	if err != nil {
+		return nil, err
+	}
+
+	note := "nothing to refund"
+	if refundId != "" {
+		note = "refund " + refundId
+	}
+	return service.transitionReturn(ctx, ret, models.ReturnRefunded, actor, note, refundId)
+}
+
+// transitionReturn moves ret to status to and records the step.
+func (service orderService) transitionReturn(ctx context.Context, ret *models.Return, to models.ReturnStatus, actor, note, refundId string) (*models.Return, error) {
+	if !ret.Status.CanTransitionTo(to) {
+		return nil, status.Errorf(codes.FailedPrecondition, "return %d cannot move from %s to %s", ret.ID, ret.Status, to)
+	}
+
+	transition := &models.ReturnTransition{
+		ReturnID:  ret.ID,
+		From:      ret.Status,
+		To:        to,
+		Actor:     actor,
+		Note:      note,
+		CreatedAt: time.Now().UTC(),
+	}
+	err := service.repository.TransitionReturn(ctx, transition, refundId)
+	if errors.Is(err, ErrReturnConflict) {
+		return nil, status.Errorf(codes.Aborted, "return %d changed status concurrently", ret.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret.Status = to
+	if refundId != "" {
+		ret.RefundID = refundId
+	}
+	ret.History = append(ret.History, transition)
+	return ret, nil
+}
+
+func (service orderService) getOrder(ctx context.Context, orderId uint64) (*models.Order, error) {
+	order, err := service.repository.GetOrder(ctx, orderId)
+	if errors.Is(err, ErrOrderNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return order, err
+}
+
+// transition moves order to status to and records and announces the change.
+func (service orderService) transition(ctx context.Context, order *models.Order, to models.OrderStatus, actor, reason string) (*models.Order, error) {
+	if !order.Status.CanTransitionTo(to) {
+		return nil, status.Errorf(codes.FailedPrecondition, "order %d cannot move from %s to %s", order.ID, order.Status, to)
+	}
+
+	transition := &models.StatusTransition{
+		OrderID:   order.ID,
+		From:      order.Status,
+		To:        to,
+		Actor:     actor,
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	}
+	err := service.repository.TransitionOrder(ctx, transition)
+	if errors.Is(err, ErrStatusConflict) {
+		return nil, status.Errorf(codes.Aborted, "order %d changed status concurrently", order.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	order.Status = to
+	order.History = append(order.History, transition)
+	for _, sub := range order.SubOrders {
+		if to.Cascades() && sub.Status.CanReach(to) {
+			sub.Status = to
+			sub.UpdatedAt = transition.CreatedAt
+		}
+	}
+	service.announceTransition(order, transition)
+	return order, nil
+}
+
+// announceTransition publishes the order's status change on order_events.
+func (service orderService) announceTransition(order *models.Order, transition *models.StatusTransition) {
+	err := kafka.SendMessageToRecommender(service, models.StatusChangedEvent{
+		Type: "order_status_changed",
+		Data: models.StatusChangedData{
+			OrderId:   order.ID,
+			AccountId: int(order.AccountID),
+			From:      transition.From,
+			To:        transition.To,
+			Actor:     transition.Actor,
+			Reason:    transition.Reason,
+			ChangedAt: transition.CreatedAt,
+		},
+	}, "order_events")
+	if err != nil {
+		log.Println("Failed to send order status event:", err)
+	}
+}
+
+func (service orderService) CreatePromotion(ctx context.Context, promotion *models.Promotion) (*models.Promotion, error) {
+	promotion.Code = models.NormalizeCode(promotion.Code)
+	if err := service.validatePromotion(promotion); err != nil {
+		return nil, err
+	}
+
+	_, err := service.repository.GetPromotionByCode(ctx, promotion.Code)
+	if err == nil {
+		return nil, ErrPromotionExists
+	}
+	if !errors.Is(err, ErrPromotionNotFound) {
+		return nil, err
+	}
+
+	promotion.ID = 0
+	promotion.CreatedAt = time.Now().UTC()
+	err = service.repository.CreatePromotion(ctx, promotion)
+	if err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+// UpdatePromotion replaces the rules of an existing promotion. Orders already placed keep
+// the discount they were given.
+func (service orderService) UpdatePromotion(ctx context.Context, promotion *models.Promotion) (*models.Promotion, error) {
+	existing, err := service.getPromotion(ctx, promotion.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion.Code = models.NormalizeCode(promotion.Code)
+	if err := service.validatePromotion(promotion); err != nil {
+		return nil, err
+	}
+	if promotion.Code != existing.Code {
+		_, err = service.repository.GetPromotionByCode(ctx, promotion.Code)
+		if err == nil {
+			return nil, ErrPromotionExists
+		}
+		if !errors.Is(err, ErrPromotionNotFound) {
+			return nil, err
+		}
+	}
+
+	promotion.CreatedAt = existing.CreatedAt
+	err = service.repository.UpdatePromotion(ctx, promotion)
+	if err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+func (service orderService) DeactivatePromotion(ctx context.Context, promotionId uint) (*models.Promotion, error) {
+	promotion, err := service.getPromotion(ctx, promotionId)
+	if err != nil {
+		return nil, err
+	}
+	if !promotion.Active {
+		return promotion, nil
+	}
+
+	promotion.Active = false
+	err = service.repository.UpdatePromotion(ctx, promotion)
+	if err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+func (service orderService) ListPromotions(ctx context.Context, includeInactive bool) ([]*models.Promotion, error) {
+	return service.repository.ListPromotions(ctx, includeInactive)
+}
+
+// EvaluatePromotion works out the discount the promotion with the given code gives on
+// products, all priced in currency. accountID 0 evaluates a cart of an anonymous session,
+// whose per-customer usage is only checked once the order is placed.
+func (service orderService) EvaluatePromotion(ctx context.Context, code string, accountID uint64, currency string, products []*models.OrderedProduct) (*models.OrderDiscount, error) {
+	promotion, err := service.repository.GetPromotionByCode(ctx, models.NormalizeCode(code))
+	if errors.Is(err, ErrPromotionNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !promotion.ActiveAt(time.Now()) {
+		return nil, status.Error(codes.FailedPrecondition, models.ErrPromotionInactive.Error())
+	}
+
+	if promotion.UsageLimitPerCustomer > 0 && accountID != 0 {
+		used, err := service.repository.CountRedemptions(ctx, promotion.ID, accountID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= int64(promotion.UsageLimitPerCustomer) {
+			return nil, status.Error(codes.FailedPrecondition, models.ErrPromotionUsageLimit.Error())
+		}
+	}
+
+	subtotal, err := models.OrderTotal(currency, products)
+	if err != nil {
+		return nil, err
+	}
+	var amountOff, minSubtotal money.Money
+	if promotion.Kind == models.PromotionFixed {
+		amountOff, err = service.converter.Convert(promotion.AmountOff, currency)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !promotion.MinSubtotal.IsZero() {
+		minSubtotal, err = service.converter.Convert(promotion.MinSubtotal, currency)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	amount, err := promotion.Discount(products, subtotal, amountOff, minSubtotal)
+	if errors.Is(err, models.ErrMinimumNotReached) || errors.Is(err, models.ErrNoEligibleProducts) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OrderDiscount{
+		PromotionID: promotion.ID,
+		Code:        promotion.Code,
+		Description: promotion.Description,
+		Amount:      amount,
+	}, nil
+}
+
+func (service orderService) getPromotion(ctx context.Context, promotionId uint) (*models.Promotion, error) {
+	promotion, err := service.repository.GetPromotion(ctx, promotionId)
+	if errors.Is(err, ErrPromotionNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return promotion, err
+}
+
+func (service orderService) validatePromotion(promotion *models.Promotion) error {
+	if promotion.Validate() != nil {
+		return ErrInvalidPromotion
+	}
+	if promotion.Kind == models.PromotionFixed {
+		promotion.AmountOff.Currency = money.Normalize(promotion.AmountOff.Currency)
+	}
+	if !promotion.MinSubtotal.IsZero() {
+		promotion.MinSubtotal.Currency = money.Normalize(promotion.MinSubtotal.Currency)
+	}
+	return nil
 }
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/pkg/tax"
+)
+
+// Address is where an order is delivered. Orders of digital goods may only carry a
+// country and region, which decide their taxes.
+type Address struct {
+	RecipientName string
+	Line1         string
+	Line2         string
+	City          string
+	PostalCode    string `gorm:"size:16"`
+	Region        string `gorm:"size:8"`
+	Country       string `gorm:"size:2"`
+}
+
+// Normalize trims the address and upper-cases its country and region codes.
+func (a Address) Normalize() Address {
+	a.RecipientName = strings.TrimSpace(a.RecipientName)
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	a.PostalCode = strings.TrimSpace(a.PostalCode)
+	a.Region = strings.ToUpper(strings.TrimSpace(a.Region))
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	return a
+}
+
+// Deliverable reports whether a parcel can be sent to the address.
+func (a Address) Deliverable() bool {
+	return a.RecipientName != "" && a.Line1 != "" && a.City != "" && len(a.Country) == 2
+}
+
+// TaxLocation is the location the address is taxed at.
+func (a Address) TaxLocation() tax.Location {
+	return tax.Location{Country: a.Country, Region: a.Region}
+}
+
+// Shipping is the shipping method chosen for an order and what it costs.
+type Shipping struct {
+	Method string
+	Cost   money.Money
+}
@@ -0,0 +1,13 @@
+package models
+
+// OrderCredit is the gift card and store credit a customer puts towards an order they
+// place, which is taken off what they pay at checkout.
+type OrderCredit struct {
+	GiftCardCode   string
+	UseStoreCredit bool
+}
+
+// Requested reports whether any credit is to be put towards the order.
+func (credit OrderCredit) Requested() bool {
+	return credit.GiftCardCode != "" || credit.UseStoreCredit
+}
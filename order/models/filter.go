@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// OrderFilter selects the orders returned by a listing. Zero fields match every order.
+type OrderFilter struct {
+	// AccountID limits the listing to one account's orders; 0 lists every account's
+	AccountID     uint64
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Statuses      []OrderStatus
+	// MinTotal and MaxTotal are inclusive and only match orders placed in their currency
+	MinTotal *money.Money
+	MaxTotal *money.Money
+}
+
+// OrderPage is one page of a listing, newest orders first.
+type OrderPage struct {
+	Orders []*Order
+	// EndCursor continues the listing after the last order of the page
+	EndCursor   string
+	HasNextPage bool
+}
+
+// EncodeCursor returns an opaque cursor pointing after the order with the given ID.
+func EncodeCursor(orderID uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(orderID), 10)))
+}
+
+// DecodeCursor returns the order ID a cursor from EncodeCursor points after.
+func DecodeCursor(cursor string) (uint, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(string(decoded), 10, 64)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidCursor
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// Party is the seller or the buyer named on an invoice.
+type Party struct {
+	Name    string  `json:"name"`
+	Email   string  `json:"email,omitempty"`
+	TaxID   string  `json:"taxId,omitempty"`
+	Address Address `json:"address"`
+}
+
+// InvoiceLine is an invoiced product with its share of the order's discounts and its tax.
+type InvoiceLine struct {
+	ProductID string      `json:"productId"`
+	Name      string      `json:"name"`
+	Quantity  int         `json:"quantity"`
+	UnitPrice money.Money `json:"unitPrice"`
+	Amount    money.Money `json:"amount"`
+	Discount  money.Money `json:"discount"`
+	TaxName   string      `json:"taxName,omitempty"`
+	TaxRate   string      `json:"taxRate,omitempty"`
+	Tax       money.Money `json:"tax"`
+}
+
+// Invoice is the invoice of a paid order. It is a snapshot taken when the order was paid
+// and is never changed afterwards; its documents are rendered from it.
+type Invoice struct {
+	ID uint `gorm:"primaryKey;autoIncrement"`
+	// Number is gapless within the year the invoice was issued, e.g. "INV-2026-000001"
+	Number    string        `gorm:"uniqueIndex;size:32"`
+	OrderID   uint          `gorm:"uniqueIndex"`
+	AccountID uint64        `gorm:"index"`
+	Seller    Party         `gorm:"serializer:json"`
+	Buyer     Party         `gorm:"serializer:json"`
+	Lines     []InvoiceLine `gorm:"serializer:json"`
+	Subtotal  money.Money   `gorm:"embedded;embeddedPrefix:subtotal_"`
+	Discount  money.Money   `gorm:"embedded;embeddedPrefix:discount_"`
+	Shipping  money.Money   `gorm:"embedded;embeddedPrefix:shipping_"`
+	Tax       money.Money   `gorm:"embedded;embeddedPrefix:tax_"`
+	Total     money.Money   `gorm:"embedded;embeddedPrefix:total_"`
+	// TaxIncluded is set when the taxes are part of the line amounts rather than added on top
+	TaxIncluded bool
+	IssuedAt    time.Time
+}
+
+func (Invoice) TableName() string {
+	return "order_invoices"
+}
+
+// InvoiceCounter is the last number given out in an invoice series.
+type InvoiceCounter struct {
+	Series string `gorm:"primaryKey;size:16"`
+	Last   int
+}
+
+func (InvoiceCounter) TableName() string {
+	return "order_invoice_counters"
+}
+
+// InvoiceSeries is the numbering series of invoices issued at issuedAt.
+func InvoiceSeries(issuedAt time.Time) string {
+	return fmt.Sprintf("INV-%d", issuedAt.UTC().Year())
+}
+
+// InvoiceNumber formats the n-th number of series.
+func InvoiceNumber(series string, n int) string {
+	return fmt.Sprintf("%s-%06d", series, n)
+}
+
+// DocumentKey is the blob key of the invoice rendered in format, e.g. "pdf".
+func (i Invoice) DocumentKey(format string) string {
+	return fmt.Sprintf("invoices/%d/%s.%s", i.IssuedAt.UTC().Year(), i.Number, format)
+}
+
+// NewInvoice takes the invoice of the paid order, sold by seller to buyer, as it stands at
+// issuedAt. The invoice is numbered when it is stored.
+func NewInvoice(order *Order, seller, buyer Party, issuedAt time.Time) (*Invoice, error) {
+	currency := order.TotalPrice.Currency
+	shares, err := DiscountShares(currency, order.Products, order.Discounts)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := &Invoice{
+		OrderID:   order.ID,
+		AccountID: order.AccountID,
+		Seller:    seller,
+		Buyer:     buyer,
+		Shipping:  money.Zero(currency),
+		Total:     order.TotalPrice,
+		IssuedAt:  issuedAt.UTC(),
+	}
+	if order.ShippingMethod != "" {
+		invoice.Shipping = order.ShippingCost
+	}
+	for i, product := range order.Products {
+		line := InvoiceLine{
+			ProductID: product.ID,
+			Name:      product.Name,
+			Quantity:  int(product.Quantity),
+			UnitPrice: product.Price,
+			Amount:    product.LineTotal(),
+			Discount:  shares[i],
+			Tax:       money.Zero(currency),
+		}
+		for _, t := range order.Taxes {
+			if t.ProductID != product.ID {
+				continue
+			}
+			line.TaxName = t.Name
+			line.TaxRate = t.Rate
+			line.Tax, err = line.Tax.Add(t.Amount)
+			if err != nil {
+				return nil, err
+			}
+			invoice.TaxIncluded = t.Inclusive
+		}
+		invoice.Lines = append(invoice.Lines, line)
+	}
+
+	invoice.Subtotal, err = order.Subtotal()
+	if err != nil {
+		return nil, err
+	}
+	invoice.Tax, err = order.TaxTotal()
+	if err != nil {
+		return nil, err
+	}
+	invoice.Discount = money.Zero(currency)
+	for _, share := range shares {
+		invoice.Discount, err = invoice.Discount.Add(share)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return invoice, nil
+}
+
+// InvoiceDocument is an invoice rendered in one format, as downloaded.
+type InvoiceDocument struct {
+	Number      string
+	OrderID     uint
+	IssuedAt    time.Time
+	ContentType string
+	Content     []byte
+}
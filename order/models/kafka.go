@@ -1,5 +1,11 @@
 package models
 
+import (
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
 type EventData struct {
 	AccountId int    `json:"user_id"`
 	ProductId string `json:"product_id"`
@@ -9,3 +15,83 @@ type Event struct {
 	Type      string    `json:"type"`
 	EventData EventData `json:"data"`
 }
+
+type StatusChangedData struct {
+	OrderId   uint        `json:"order_id"`
+	AccountId int         `json:"user_id"`
+	From      OrderStatus `json:"from"`
+	To        OrderStatus `json:"to"`
+	Actor     string      `json:"actor"`
+	Reason    string      `json:"reason"`
+	ChangedAt time.Time   `json:"changed_at"`
+}
+
+type StatusChangedEvent struct {
+	Type string            `json:"type"`
+	Data StatusChangedData `json:"data"`
+}
+
+// ExpiredData is the payload of the order_expired event, sent on order_events when an
+// order is cancelled because it was not paid in time.
+type ExpiredData struct {
+	OrderId   uint        `json:"order_id"`
+	AccountId int         `json:"user_id"`
+	Total     money.Money `json:"total"`
+	PlacedAt  time.Time   `json:"placed_at"`
+	ExpiredAt time.Time   `json:"expired_at"`
+}
+
+type ExpiredEvent struct {
+	Type string      `json:"type"`
+	Data ExpiredData `json:"data"`
+}
+
+// RecurringRunData is the payload of the recurring_order_placed and recurring_order_failed
+// events, sent on order_events when a recurring order runs.
+type RecurringRunData struct {
+	RecurringOrderId uint   `json:"recurring_order_id"`
+	AccountId        int    `json:"user_id"`
+	OrderId          uint   `json:"order_id,omitempty"`
+	CheckoutURL      string `json:"checkout_url,omitempty"`
+	// Error tells why no order was placed
+	Error     string    `json:"error,omitempty"`
+	NextRunAt time.Time `json:"next_run_at"`
+}
+
+type RecurringRunEvent struct {
+	Type string           `json:"type"`
+	Data RecurringRunData `json:"data"`
+}
+
+// ShipmentEventData is the payload of the order_shipped and order_delivered events the
+// shipping service publishes on shipping_events.
+// RiskData is the payload of the order_risk_flagged event, sent on order_events when risk
+// screening holds an order for review or blocks it, and of the order_review_approved
+// event, which carries the checkout URL of an approved order.
+type RiskData struct {
+	OrderId     uint          `json:"order_id"`
+	AccountId   int           `json:"user_id"`
+	Score       int           `json:"score"`
+	Decision    RiskDecision  `json:"decision"`
+	Findings    []RiskFinding `json:"findings,omitempty"`
+	CheckoutURL string        `json:"checkout_url,omitempty"`
+}
+
+type RiskEvent struct {
+	Type string   `json:"type"`
+	Data RiskData `json:"data"`
+}
+
+type ShipmentEventData struct {
+	OrderId        uint64 `json:"order_id"`
+	ShipmentId     uint   `json:"shipment_id"`
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"tracking_number"`
+	// Complete is set once every ordered unit has been shipped, or delivered
+	Complete bool `json:"complete"`
+}
+
+type ShipmentEvent struct {
+	Type string            `json:"type"`
+	Data ShipmentEventData `json:"data"`
+}
@@ -1,22 +1,173 @@
 package models
 
-import "time"
+import (
+	"slices"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
 
 type Order struct {
-	ID            uint `gorm:"primaryKey;autoIncrement"`
-	CreatedAt     time.Time
-	TotalPrice    float64
-	AccountID     uint64
-	Status        string
-	PaymentStatus string
-	ProductsInfos []ProductsInfo    `gorm:"foreignKey:OrderID"`
-	Products      []*OrderedProduct `gorm:"-"`
+	ID         uint `gorm:"primaryKey;autoIncrement"`
+	CreatedAt  time.Time
+	TotalPrice money.Money `gorm:"embedded;embeddedPrefix:total_price_"`
+	AccountID  uint64
+	// ShippingAddress is where the order is delivered, which also decides its taxes
+	ShippingAddress Address `gorm:"embedded"`
+	// ShippingMethod is empty for orders that are not shipped
+	ShippingMethod string
+	ShippingCost   money.Money `gorm:"embedded;embeddedPrefix:shipping_cost_"`
+	Status         OrderStatus `gorm:"type:varchar(20);default:pending_payment"`
+	// StockReserved is set once the ordered products have been taken out of stock
+	StockReserved bool
+	// RiskReview is pending while risk screening holds the order for an admin to review
+	RiskReview    ReviewStatus        `gorm:"type:varchar(10)"`
+	ProductsInfos []ProductsInfo      `gorm:"foreignKey:OrderID"`
+	History       []*StatusTransition `gorm:"foreignKey:OrderID"`
+	Discounts     []*OrderDiscount    `gorm:"foreignKey:OrderID"`
+	Taxes         []*OrderTax         `gorm:"foreignKey:OrderID"`
+	Returns       []*Return           `gorm:"foreignKey:OrderID"`
+	SubOrders     []*SubOrder         `gorm:"foreignKey:OrderID"`
+	Products      []*OrderedProduct   `gorm:"-"`
 }
 
 type OrderedProduct struct {
 	ID          string
 	Name        string
 	Description string
-	Price       float64
+	Price       money.Money
 	Quantity    uint32
+	SellerID    uint64
+	// Category and TaxCategory are only known while the order is placed, to match
+	// category promotions and tax rates
+	Category    string
+	TaxCategory string
+}
+
+// LineTotal is the unit price multiplied by the ordered quantity.
+func (p OrderedProduct) LineTotal() money.Money {
+	return p.Price.Mul(int64(p.Quantity))
+}
+
+// WasPaid reports whether the order's payment ever succeeded. Besides a transition to paid,
+// its current or a past status being one only paid orders reach tells, as orders paid before
+// their history was recorded have none.
+func (o Order) WasPaid() bool {
+	if o.Status == StatusRefunded || slices.Contains(SoldStatuses, o.Status) {
+		return true
+	}
+	for _, transition := range o.History {
+		if transition.To == StatusPaid || slices.Contains(SoldStatuses, transition.From) {
+			return true
+		}
+	}
+	return false
+}
+
+// Payment statuses reported for orders in exports.
+const (
+	PaymentUnpaid            = "unpaid"
+	PaymentPaid              = "paid"
+	PaymentPartiallyRefunded = "partially_refunded"
+	PaymentRefunded          = "refunded"
+)
+
+// PaymentStatus tells whether the order was paid for, and whether the payment was since
+// refunded in full or for some of the returned lines.
+func (o Order) PaymentStatus() string {
+	if o.Status == StatusRefunded {
+		return PaymentRefunded
+	}
+	for _, ret := range o.Returns {
+		if ret.Status == ReturnRefunded {
+			return PaymentPartiallyRefunded
+		}
+	}
+	if o.WasPaid() {
+		return PaymentPaid
+	}
+	return PaymentUnpaid
+}
+
+// Subtotal is the order total before discounts and without the shipping and the taxes
+// added on top of it.
+func (o Order) Subtotal() (money.Money, error) {
+	subtotal := o.TotalPrice
+	if !o.ShippingCost.IsZero() {
+		var err error
+		subtotal, err = subtotal.Sub(o.ShippingCost)
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+	for _, discount := range o.Discounts {
+		var err error
+		subtotal, err = subtotal.Add(discount.Amount)
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+	for _, tax := range o.Taxes {
+		if tax.Inclusive {
+			continue
+		}
+		var err error
+		subtotal, err = subtotal.Sub(tax.Amount)
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+	return subtotal, nil
+}
+
+// TaxTotal adds up the order's tax lines, whether or not they are included in its prices.
+func (o Order) TaxTotal() (money.Money, error) {
+	total := money.Zero(o.TotalPrice.Currency)
+	for _, tax := range o.Taxes {
+		var err error
+		total, err = total.Add(tax.Amount)
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// OrderTotal adds up the line totals of products, all of which must be priced in currency.
+func OrderTotal(currency string, products []*OrderedProduct) (money.Money, error) {
+	total := money.Zero(currency)
+	for _, product := range products {
+		var err error
+		total, err = total.Add(product.LineTotal())
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// DiscountShares splits the discounts of an order placed in currency across products in
+// proportion to their line totals.
+func DiscountShares(currency string, products []*OrderedProduct, discounts []*OrderDiscount) ([]money.Money, error) {
+	discount := money.Zero(currency)
+	for _, d := range discounts {
+		var err error
+		discount, err = discount.Add(d.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shares := make([]money.Money, len(products))
+	if discount.IsZero() {
+		for i := range shares {
+			shares[i] = money.Zero(currency)
+		}
+		return shares, nil
+	}
+	weights := make([]int64, len(products))
+	for i, product := range products {
+		weights[i] = product.LineTotal().Amount
+	}
+	return discount.Allocate(weights...)
 }
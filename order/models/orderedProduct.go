@@ -1,12 +1,73 @@
 package models
 
+import (
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// ProductsInfo is a stored order line. Besides the product and quantity it keeps a
+// snapshot of the product as it was sold, so later price changes or deletions do
+// not alter the order.
 type ProductsInfo struct {
 	ID        uint `gorm:"primaryKey;autoIncrement"`
 	OrderID   uint
 	ProductID string
-	Quantity  int
+	// SellerID is the account selling the product, 0 for lines stored before sellers were
+	// tracked
+	SellerID    uint64 `gorm:"index"`
+	Quantity    int
+	Name        string
+	Description string
+	UnitPrice   money.Money `gorm:"embedded;embeddedPrefix:unit_price_"`
+	LineTotal   money.Money `gorm:"embedded;embeddedPrefix:line_total_"`
 }
 
 func (ProductsInfo) TableName() string {
 	return "order_products"
 }
+
+// HasSnapshot reports whether the line was stored with its product snapshot.
+// Lines of orders placed by older versions only have the product ID and quantity.
+func (p ProductsInfo) HasSnapshot() bool {
+	return p.UnitPrice.Currency != ""
+}
+
+// OrderedProduct returns the product as it was sold on this line.
+func (p ProductsInfo) OrderedProduct() *OrderedProduct {
+	return &OrderedProduct{
+		ID:          p.ProductID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.UnitPrice,
+		Quantity:    uint32(p.Quantity),
+		SellerID:    p.SellerID,
+	}
+}
+
+// OrderLine is a single ordered product joined with the order it belongs to.
+type OrderLine struct {
+	OrderID   uint
+	AccountID uint64
+	CreatedAt time.Time
+	ProductID string
+	Name      string
+	Quantity  int
+	UnitPrice money.Money `gorm:"embedded;embeddedPrefix:unit_price_"`
+}
+
+// ProductSales adds up the paid order lines of one product in one currency. UnpricedUnits
+// were sold on lines stored without a snapshot, so they are not part of Revenue.
+type ProductSales struct {
+	ProductID     string
+	Currency      string
+	UnitsSold     int
+	Revenue       int64
+	UnpricedUnits int
+}
+
+// SalesSummary is what a set of products sold on paid orders.
+type SalesSummary struct {
+	OrderCount int
+	Products   []*ProductSales
+}
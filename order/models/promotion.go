@@ -0,0 +1,195 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+var (
+	ErrPromotionInactive   = errors.New("promotion is not active")
+	ErrMinimumNotReached   = errors.New("order subtotal is below the promotion minimum")
+	ErrNoEligibleProducts  = errors.New("no product in the order is eligible for the promotion")
+	ErrInvalidPromotion    = errors.New("invalid promotion")
+	ErrPromotionUsageLimit = errors.New("promotion usage limit reached")
+)
+
+type PromotionKind string
+
+const (
+	// PromotionPercentage takes PercentOff percent off the eligible products
+	PromotionPercentage PromotionKind = "percentage"
+	// PromotionFixed takes AmountOff off the eligible products, at most their total
+	PromotionFixed PromotionKind = "fixed"
+	// PromotionBuyXGetY gives GetQuantity units free for every BuyQuantity units bought
+	// of the same eligible product
+	PromotionBuyXGetY PromotionKind = "buy_x_get_y"
+)
+
+// Promotion is a discount customers claim with its code.
+type Promotion struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	Code        string `gorm:"uniqueIndex;size:64"`
+	Description string
+	Kind        PromotionKind `gorm:"type:varchar(20)"`
+	PercentOff  int
+	AmountOff   money.Money `gorm:"embedded;embeddedPrefix:amount_off_"`
+	// MinSubtotal is the order subtotal required before the promotion applies; zero for none
+	MinSubtotal money.Money `gorm:"embedded;embeddedPrefix:min_subtotal_"`
+	// ProductIDs and Categories restrict the promotion to those products. When both are
+	// empty every product is eligible.
+	ProductIDs  []string `gorm:"serializer:json"`
+	Categories  []string `gorm:"serializer:json"`
+	BuyQuantity int
+	GetQuantity int
+	// UsageLimitPerCustomer caps how often one account may use the code; 0 for no limit
+	UsageLimitPerCustomer int
+	StartsAt              *time.Time
+	EndsAt                *time.Time
+	Active                bool
+	CreatedAt             time.Time
+}
+
+// NormalizeCode returns the form promotion codes are stored and looked up in.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks that the promotion's rules are complete and consistent.
+func (p Promotion) Validate() error {
+	if len(p.Code) < 3 || len(p.Code) > 64 {
+		return ErrInvalidPromotion
+	}
+	switch p.Kind {
+	case PromotionPercentage:
+		if p.PercentOff <= 0 || p.PercentOff > 100 {
+			return ErrInvalidPromotion
+		}
+	case PromotionFixed:
+		if p.AmountOff.Amount <= 0 || !money.IsValid(p.AmountOff.Currency) {
+			return ErrInvalidPromotion
+		}
+	case PromotionBuyXGetY:
+		if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+			return ErrInvalidPromotion
+		}
+	default:
+		return ErrInvalidPromotion
+	}
+	if p.MinSubtotal.IsNegative() || (!p.MinSubtotal.IsZero() && !money.IsValid(p.MinSubtotal.Currency)) {
+		return ErrInvalidPromotion
+	}
+	if p.UsageLimitPerCustomer < 0 {
+		return ErrInvalidPromotion
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.StartsAt.Before(*p.EndsAt) {
+		return ErrInvalidPromotion
+	}
+	return nil
+}
+
+// ActiveAt reports whether the promotion can be used at t.
+func (p Promotion) ActiveAt(t time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.StartsAt != nil && t.Before(*p.StartsAt) {
+		return false
+	}
+	return p.EndsAt == nil || t.Before(*p.EndsAt)
+}
+
+// Eligible reports whether the promotion applies to the product.
+func (p Promotion) Eligible(product *OrderedProduct) bool {
+	if len(p.ProductIDs) == 0 && len(p.Categories) == 0 {
+		return true
+	}
+	if slices.Contains(p.ProductIDs, product.ID) {
+		return true
+	}
+	return product.Category != "" && slices.ContainsFunc(p.Categories, func(category string) bool {
+		return strings.EqualFold(category, product.Category)
+	})
+}
+
+// Discount returns how much the promotion takes off products, all priced in the currency
+// of subtotal. amountOff and minSubtotal are the promotion's AmountOff and MinSubtotal
+// expressed in that currency.
+func (p Promotion) Discount(products []*OrderedProduct, subtotal, amountOff, minSubtotal money.Money) (money.Money, error) {
+	if !minSubtotal.IsZero() {
+		cmp, err := subtotal.Cmp(minSubtotal)
+		if err != nil {
+			return money.Money{}, err
+		}
+		if cmp < 0 {
+			return money.Money{}, ErrMinimumNotReached
+		}
+	}
+
+	eligible := money.Zero(subtotal.Currency)
+	free := money.Zero(subtotal.Currency)
+	found := false
+	for _, product := range products {
+		if !p.Eligible(product) {
+			continue
+		}
+		found = true
+		var err error
+		eligible, err = eligible.Add(product.LineTotal())
+		if err != nil {
+			return money.Money{}, err
+		}
+		if p.Kind == PromotionBuyXGetY {
+			freeUnits := int64(product.Quantity) / int64(p.BuyQuantity+p.GetQuantity) * int64(p.GetQuantity)
+			free, err = free.Add(product.Price.Mul(freeUnits))
+			if err != nil {
+				return money.Money{}, err
+			}
+		}
+	}
+	if !found {
+		return money.Money{}, ErrNoEligibleProducts
+	}
+
+	switch p.Kind {
+	case PromotionPercentage:
+		return eligible.MulRatio(int64(p.PercentOff), 100, money.RoundDown)
+	case PromotionFixed:
+		if cmp, err := amountOff.Cmp(eligible); err != nil || cmp > 0 {
+			return eligible, err
+		}
+		return amountOff, nil
+	case PromotionBuyXGetY:
+		return free, nil
+	}
+	return money.Money{}, ErrInvalidPromotion
+}
+
+// PromotionRedemption records a promotion used by an order, to enforce per-customer limits.
+type PromotionRedemption struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	PromotionID uint   `gorm:"index:idx_redemption_customer"`
+	AccountID   uint64 `gorm:"index:idx_redemption_customer"`
+	OrderID     uint
+	CreatedAt   time.Time
+}
+
+// OrderDiscount is a discount line of an order.
+type OrderDiscount struct {
+	ID          uint `gorm:"primaryKey;autoIncrement"`
+	OrderID     uint `gorm:"index"`
+	PromotionID uint
+	Code        string
+	Description string
+	Amount      money.Money `gorm:"embedded;embeddedPrefix:amount_"`
+}
+
+// PromotionEvaluation is the discount a promotion gives on products that are not ordered yet.
+type PromotionEvaluation struct {
+	Discount *OrderDiscount
+	Subtotal money.Money
+	Total    money.Money
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// RecurringInterval is how often a recurring order is placed.
+type RecurringInterval string
+
+const (
+	IntervalWeekly  RecurringInterval = "weekly"
+	IntervalMonthly RecurringInterval = "monthly"
+)
+
+// Valid reports whether i is a known interval.
+func (i RecurringInterval) Valid() bool {
+	return i == IntervalWeekly || i == IntervalMonthly
+}
+
+// Next is when the order placed at t is placed again.
+func (i RecurringInterval) Next(t time.Time) time.Time {
+	if i == IntervalMonthly {
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 7)
+}
+
+func (i RecurringInterval) String() string {
+	return string(i)
+}
+
+type ScheduleStatus string
+
+const (
+	ScheduleActive    ScheduleStatus = "active"
+	SchedulePaused    ScheduleStatus = "paused"
+	ScheduleCancelled ScheduleStatus = "cancelled"
+)
+
+// scheduleTransitions lists the statuses each schedule status may move to. Cancelled
+// schedules stay cancelled.
+var scheduleTransitions = map[ScheduleStatus][]ScheduleStatus{
+	ScheduleActive:    {SchedulePaused, ScheduleCancelled},
+	SchedulePaused:    {ScheduleActive, ScheduleCancelled},
+	ScheduleCancelled: {},
+}
+
+// ParseScheduleStatus returns the status named by s, reporting whether it is a known status.
+func ParseScheduleStatus(s string) (ScheduleStatus, bool) {
+	status := ScheduleStatus(s)
+	_, ok := scheduleTransitions[status]
+	return status, ok
+}
+
+// CanTransitionTo reports whether a schedule in status s may move to status to.
+func (s ScheduleStatus) CanTransitionTo(to ScheduleStatus) bool {
+	for _, allowed := range scheduleTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (s ScheduleStatus) String() string {
+	return string(s)
+}
+
+// RecurringOrder is a customer's schedule for placing the same order every week or month.
+// Each run places a new order at the products' prices of the day and opens its checkout
+// session, which the customer pays like any other.
+type RecurringOrder struct {
+	ID        uint              `gorm:"primaryKey;autoIncrement"`
+	AccountID uint64            `gorm:"index"`
+	Currency  string            `gorm:"size:3"`
+	Interval  RecurringInterval `gorm:"type:varchar(10)"`
+	Status    ScheduleStatus    `gorm:"type:varchar(10);index:idx_recurring_due"`
+	Items     []*RecurringItem  `gorm:"foreignKey:RecurringOrderID"`
+	// ShippingAddress, ShippingMethod and RedirectURL are used for every order placed
+	ShippingAddress Address `gorm:"embedded"`
+	ShippingMethod  string
+	RedirectURL     string
+	// NextRunAt is when the next order is placed
+	NextRunAt time.Time `gorm:"index:idx_recurring_due"`
+	// LastOrderID and LastCheckoutURL are the order placed by the last successful run
+	LastOrderID     uint
+	LastCheckoutURL string
+	// LastError tells why the last run placed no order, empty when it did
+	LastError string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (RecurringOrder) TableName() string {
+	return "recurring_orders"
+}
+
+// RecurringItem is a product ordered on every run of a recurring order.
+type RecurringItem struct {
+	ID               uint `gorm:"primaryKey;autoIncrement"`
+	RecurringOrderID uint `gorm:"index"`
+	ProductID        string
+	Quantity         uint32
+}
+
+func (RecurringItem) TableName() string {
+	return "recurring_order_items"
+}
+
+// OrderedProducts returns the schedule's products with their quantities, to be priced.
+func (r *RecurringOrder) OrderedProducts() []*OrderedProduct {
+	products := make([]*OrderedProduct, 0, len(r.Items))
+	for _, item := range r.Items {
+		products = append(products, &OrderedProduct{ID: item.ProductID, Quantity: item.Quantity})
+	}
+	return products
+}
+
+// Advance moves NextRunAt past now, skipping the runs missed while the schedule was
+// paused or the scheduler was down, so they are not all placed at once.
+func (r *RecurringOrder) Advance(now time.Time) {
+	for !r.NextRunAt.After(now) {
+		r.NextRunAt = r.Interval.Next(r.NextRunAt)
+	}
+}
+
+// ReorderLine is a line of a past order as it would be ordered again today.
+type ReorderLine struct {
+	ProductID string
+	// Name is the product's current name, or its name on the order once it is gone
+	Name     string
+	Quantity uint32
+	// UnitPrice is the current price in the order's currency, zero once the product is gone
+	UnitPrice money.Money
+	// PreviousUnitPrice is what the unit cost on the order
+	PreviousUnitPrice money.Money
+	// Available reports whether the product is still sold with enough stock for Quantity
+	Available bool
+}
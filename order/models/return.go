@@ -0,0 +1,145 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+var ErrProductNotOrdered = errors.New("product is not part of the order")
+
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "requested"
+	ReturnApproved  ReturnStatus = "approved"
+	ReturnRejected  ReturnStatus = "rejected"
+	ReturnReceived  ReturnStatus = "received"
+	ReturnRefunded  ReturnStatus = "refunded"
+)
+
+// returnTransitions lists the statuses each return status may move to. A return is
+// refunded once the returned products have been received back.
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnRequested: {ReturnApproved, ReturnRejected},
+	ReturnApproved:  {ReturnReceived},
+	ReturnReceived:  {ReturnRefunded},
+	ReturnRejected:  {},
+	ReturnRefunded:  {},
+}
+
+// CanTransitionTo reports whether a return in status s may move to status to.
+func (s ReturnStatus) CanTransitionTo(to ReturnStatus) bool {
+	for _, allowed := range returnTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (s ReturnStatus) String() string {
+	return string(s)
+}
+
+// Return is a customer's request to send back some of the units of a delivered order
+// (a return merchandise authorization).
+type Return struct {
+	ID        uint `gorm:"primaryKey;autoIncrement"`
+	OrderID   uint `gorm:"index"`
+	AccountID uint64
+	Status    ReturnStatus `gorm:"type:varchar(20)"`
+	Reason    string
+	Lines     []*ReturnLine `gorm:"foreignKey:ReturnID"`
+	// RefundAmount is what the returned units were paid, which is refunded once they arrive
+	RefundAmount money.Money `gorm:"embedded;embeddedPrefix:refund_amount_"`
+	// RefundID is the payment provider's refund, set once the return is refunded
+	RefundID string
+	// RefundToStoreCredit returns are refunded to the customer's store credit
+	RefundToStoreCredit bool
+	History             []*ReturnTransition `gorm:"foreignKey:ReturnID"`
+	CreatedAt           time.Time
+}
+
+func (Return) TableName() string {
+	return "order_returns"
+}
+
+// ReturnLine is a quantity of one ordered product sent back, with what it is refunded.
+type ReturnLine struct {
+	ID           uint `gorm:"primaryKey;autoIncrement"`
+	ReturnID     uint `gorm:"index"`
+	ProductID    string
+	Quantity     int
+	RefundAmount money.Money `gorm:"embedded;embeddedPrefix:refund_amount_"`
+}
+
+func (ReturnLine) TableName() string {
+	return "order_return_lines"
+}
+
+// ReturnTransition records a single step of a return.
+type ReturnTransition struct {
+	ID        uint         `gorm:"primaryKey;autoIncrement"`
+	ReturnID  uint         `gorm:"index"`
+	From      ReturnStatus `gorm:"type:varchar(20)"`
+	To        ReturnStatus `gorm:"type:varchar(20)"`
+	Actor     string
+	Note      string
+	CreatedAt time.Time
+}
+
+func (ReturnTransition) TableName() string {
+	return "order_return_transitions"
+}
+
+// ReturnedQuantities returns the units of each product already asked back in returns
+// that were not rejected.
+func (o Order) ReturnedQuantities() map[string]int {
+	returned := map[string]int{}
+	for _, r := range o.Returns {
+		if r.Status == ReturnRejected {
+			continue
+		}
+		for _, line := range r.Lines {
+			returned[line.ProductID] += line.Quantity
+		}
+	}
+	return returned
+}
+
+// RefundAmount returns what quantity units of the product were paid when returned units
+// of it have already been refunded: the line price less its share of the discounts plus
+// the taxes added on top of it. Successive returns of a line add up to what it was paid.
+func (o Order) RefundAmount(productId string, returned, quantity int) (money.Money, error) {
+	shares, err := DiscountShares(o.TotalPrice.Currency, o.Products, o.Discounts)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	for i, product := range o.Products {
+		if product.ID != productId {
+			continue
+		}
+		paid, err := product.LineTotal().Sub(shares[i])
+		if err != nil {
+			return money.Money{}, err
+		}
+		for _, t := range o.Taxes {
+			if t.ProductID != productId || t.Inclusive {
+				continue
+			}
+			paid, err = paid.Add(t.Amount)
+			if err != nil {
+				return money.Money{}, err
+			}
+		}
+
+		ordered := int64(product.Quantity)
+		before := paid.Amount * int64(returned) / ordered
+		after := paid.Amount * int64(returned+quantity) / ordered
+		return money.New(after-before, paid.Currency), nil
+	}
+	return money.Money{}, ErrProductNotOrdered
+}
@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// RiskDecision is what risk screening decided to do with an order.
+type RiskDecision string
+
+const (
+	// DecisionAllow orders go on to checkout
+	DecisionAllow RiskDecision = "allow"
+	// DecisionReview orders wait for an admin to approve them before they can be paid
+	DecisionReview RiskDecision = "review"
+	// DecisionBlock orders are cancelled before they can be paid
+	DecisionBlock RiskDecision = "block"
+)
+
+func (d RiskDecision) String() string {
+	return string(d)
+}
+
+// ReviewStatus tracks the manual review of an order held by risk screening.
+type ReviewStatus string
+
+const (
+	// ReviewNone is the status of orders that were not held for review
+	ReviewNone     ReviewStatus = ""
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// ParseReviewStatus returns the review status named by s, reporting whether it is the
+// status of a review.
+func ParseReviewStatus(s string) (ReviewStatus, bool) {
+	status := ReviewStatus(s)
+	switch status {
+	case ReviewPending, ReviewApproved, ReviewRejected:
+		return status, true
+	}
+	return status, false
+}
+
+func (s ReviewStatus) String() string {
+	return string(s)
+}
+
+// OrderOrigin is where an order was placed from, as far as the customer's client tells.
+type OrderOrigin struct {
+	ClientIP string
+	// BillingCountry is the ISO 3166-1 alpha-2 country of the customer's billing address
+	BillingCountry string
+}
+
+// RiskFinding is a risk rule that matched an order, and how much it added to its score.
+type RiskFinding struct {
+	Rule   string `json:"rule"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+// RiskAssessment is the outcome of screening an order before it could be paid. Every
+// placement is assessed, so assessments also count the attempts of an account or client.
+type RiskAssessment struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	OrderID   uint   `gorm:"uniqueIndex"`
+	AccountID uint64 `gorm:"index:idx_risk_account_attempts"`
+	ClientIP  string `gorm:"index:idx_risk_ip_attempts"`
+	Score     int
+	Decision  RiskDecision  `gorm:"type:varchar(10)"`
+	Findings  []RiskFinding `gorm:"serializer:json"`
+	// ReviewStatus is pending while an admin has yet to decide on an order held for review
+	ReviewStatus ReviewStatus `gorm:"type:varchar(10);index"`
+	// RedirectURL is where the checkout session opened on approval returns to
+	RedirectURL string
+	ReviewedBy  string
+	ReviewNote  string
+	ReviewedAt  *time.Time
+	CreatedAt   time.Time `gorm:"index:idx_risk_account_attempts;index:idx_risk_ip_attempts"`
+}
+
+func (RiskAssessment) TableName() string {
+	return "order_risk_assessments"
+}
+
+// RiskReview is an assessed order as admins review it.
+type RiskReview struct {
+	RiskAssessment
+	Order *Order
+}
+
+type RiskReviewPage struct {
+	Reviews []*RiskReview
+	// EndCursor continues the listing after the last review of the page
+	EndCursor   string
+	HasNextPage bool
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+type SagaState string
+
+const (
+	// SagaStarted sagas are reserving the products' stock
+	SagaStarted SagaState = "started"
+	// SagaStockReserved sagas hold the stock and are writing the order
+	SagaStockReserved SagaState = "stock_reserved"
+	// SagaOrderPlaced sagas have written the order and are opening its checkout session
+	SagaOrderPlaced SagaState = "order_placed"
+	// SagaInReview sagas hold an order that risk screening sent for review, and open its
+	// checkout session once an admin approves it
+	SagaInReview SagaState = "in_review"
+	// SagaAwaitingPayment sagas wait for the order to be paid, or cancelled
+	SagaAwaitingPayment SagaState = "awaiting_payment"
+	// SagaCompleted sagas ended with a paid order
+	SagaCompleted SagaState = "completed"
+	// SagaCompensated sagas were undone: their stock was released and their order cancelled,
+	// or their order was cancelled or expired after it was placed
+	SagaCompensated SagaState = "compensated"
+)
+
+// PendingSagaStates lists the states of sagas that have not ended yet.
+var PendingSagaStates = []SagaState{SagaStarted, SagaStockReserved, SagaOrderPlaced, SagaInReview, SagaAwaitingPayment}
+
+func (s SagaState) String() string {
+	return string(s)
+}
+
+// Done reports whether a saga in state s has ended.
+func (s SagaState) Done() bool {
+	return s == SagaCompleted || s == SagaCompensated
+}
+
+// SagaItem is a quantity of a product whose stock the saga reserves.
+type SagaItem struct {
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+}
+
+// PlacementSaga is the persisted progress of placing an order, from reserving its stock
+// until it is paid, so that an interrupted placement can be finished or undone after a
+// restart.
+type PlacementSaga struct {
+	ID        uint `gorm:"primaryKey;autoIncrement"`
+	AccountID uint64
+	State     SagaState  `gorm:"type:varchar(20);index"`
+	Items     []SagaItem `gorm:"serializer:json"`
+	// OrderID is set once the order is written
+	OrderID     uint
+	CheckoutURL string
+	// PaidWithCredit is set when gift cards and store credit cover the whole order, which
+	// is then paid without a checkout session
+	PaidWithCredit bool
+	// Error is why the saga was compensated
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (PlacementSaga) TableName() string {
+	return "order_placement_sagas"
+}
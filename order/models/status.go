@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+type OrderStatus string
+
+const (
+	StatusPendingPayment OrderStatus = "pending_payment"
+	StatusPaid           OrderStatus = "paid"
+	StatusFulfilling     OrderStatus = "fulfilling"
+	StatusShipped        OrderStatus = "shipped"
+	StatusDelivered      OrderStatus = "delivered"
+	StatusCancelled      OrderStatus = "cancelled"
+	StatusRefunded       OrderStatus = "refunded"
+)
+
+// SoldStatuses are the statuses of orders that were paid for and have not been cancelled
+// or refunded.
+var SoldStatuses = []OrderStatus{StatusPaid, StatusFulfilling, StatusShipped, StatusDelivered}
+
+// orderTransitions lists the statuses each status may move to. Cancelled and
+// delivered orders can still be refunded once the money has been returned.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	StatusPendingPayment: {StatusPaid, StatusCancelled},
+	StatusPaid:           {StatusFulfilling, StatusCancelled, StatusRefunded},
+	StatusFulfilling:     {StatusShipped, StatusCancelled, StatusRefunded},
+	StatusShipped:        {StatusDelivered, StatusRefunded},
+	StatusDelivered:      {StatusRefunded},
+	StatusCancelled:      {StatusRefunded},
+	StatusRefunded:       {},
+}
+
+// ParseOrderStatus returns the status named by s, reporting whether it is a known status.
+func ParseOrderStatus(s string) (OrderStatus, bool) {
+	status := OrderStatus(s)
+	_, ok := orderTransitions[status]
+	return status, ok
+}
+
+// CanTransitionTo reports whether an order in status s may move to status to.
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Terminal reports whether no further transitions are possible from s.
+func (s OrderStatus) Terminal() bool {
+	return len(orderTransitions[s]) == 0
+}
+
+func (s OrderStatus) String() string {
+	return string(s)
+}
+
+// StatusTransition records a single change of an order's status.
+type StatusTransition struct {
+	ID        uint        `gorm:"primaryKey;autoIncrement"`
+	OrderID   uint        `gorm:"index"`
+	From      OrderStatus `gorm:"type:varchar(20)"`
+	To        OrderStatus `gorm:"type:varchar(20)"`
+	Actor     string
+	Reason    string
+	CreatedAt time.Time
+}
+
+func (StatusTransition) TableName() string {
+	return "order_status_transitions"
+}
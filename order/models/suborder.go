@@ -0,0 +1,229 @@
+package models
+
+import (
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// SubOrder is the part of an order sold by one seller. The customer places and pays for
+// the order as a whole, while each seller fulfils their sub-order and is paid out for it.
+type SubOrder struct {
+	ID       uint        `gorm:"primaryKey;autoIncrement"`
+	OrderID  uint        `gorm:"index"`
+	SellerID uint64      `gorm:"index"`
+	Status   OrderStatus `gorm:"type:varchar(20);default:pending_payment"`
+	// Subtotal adds up the seller's line totals
+	Subtotal money.Money `gorm:"embedded;embeddedPrefix:subtotal_"`
+	// Discount, Tax and Shipping are the seller's shares of the order's discounts, taxes
+	// and shipping cost
+	Discount money.Money `gorm:"embedded;embeddedPrefix:discount_"`
+	Tax      money.Money `gorm:"embedded;embeddedPrefix:tax_"`
+	Shipping money.Money `gorm:"embedded;embeddedPrefix:shipping_"`
+	// Total is what the customer paid for the sub-order
+	Total     money.Money `gorm:"embedded;embeddedPrefix:total_"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (SubOrder) TableName() string {
+	return "order_sub_orders"
+}
+
+// SellerOrder is a sub-order as its seller sees it: with the seller's lines only, and
+// where they are delivered.
+type SellerOrder struct {
+	SubOrder
+	AccountID       uint64
+	ShippingAddress Address
+	ShippingMethod  string
+	Lines           []ProductsInfo
+}
+
+// sellerStatuses are the statuses sellers move their sub-orders to as they fulfil them.
+var sellerStatuses = []OrderStatus{StatusFulfilling, StatusShipped, StatusDelivered}
+
+// SellerCanSet reports whether sellers may move their sub-orders to status s.
+func (s OrderStatus) SellerCanSet() bool {
+	for _, status := range sellerStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Cascades reports whether an order moving to status s takes its sub-orders with it.
+// Fulfilment is tracked per sub-order, so an order only becomes fulfilling on its own.
+func (s OrderStatus) Cascades() bool {
+	return s != StatusFulfilling && s != StatusPendingPayment
+}
+
+// CanReach reports whether a sub-order in status s can get to status to through one or
+// more transitions.
+func (s OrderStatus) CanReach(to OrderStatus) bool {
+	seen := map[OrderStatus]bool{s: true}
+	queue := []OrderStatus{s}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range orderTransitions[current] {
+			if next == to {
+				return true
+			}
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
+
+// StatusesReaching lists the statuses that can reach status to, which are the sub-orders
+// an order moving to status to takes along.
+func StatusesReaching(to OrderStatus) []OrderStatus {
+	var statuses []OrderStatus
+	for status := range orderTransitions {
+		if status.CanReach(to) {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// FulfilmentStatus is where the order stands once its sub-orders are as given: delivered
+// when every sub-order still going ahead was delivered, shipped when they all left, and
+// fulfilling as soon as one of them is on its way. ok is false while none has started.
+func FulfilmentStatus(subOrders []*SubOrder) (status OrderStatus, ok bool) {
+	active, started, shipped, delivered := 0, 0, 0, 0
+	for _, sub := range subOrders {
+		switch sub.Status {
+		case StatusCancelled, StatusRefunded:
+			continue
+		case StatusDelivered:
+			delivered++
+			fallthrough
+		case StatusShipped:
+			shipped++
+			fallthrough
+		case StatusFulfilling:
+			started++
+		}
+		active++
+	}
+	switch {
+	case active == 0 || started == 0:
+		return "", false
+	case delivered == active:
+		return StatusDelivered, true
+	case shipped == active:
+		return StatusShipped, true
+	default:
+		return StatusFulfilling, true
+	}
+}
+
+// NextStatusToward is the status an order in status from moves to next on its way to
+// status to, without passing through cancelled or refunded.
+func NextStatusToward(from, to OrderStatus) (OrderStatus, bool) {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return next, true
+		}
+	}
+	for _, next := range orderTransitions[from] {
+		if next != StatusCancelled && next != StatusRefunded && next.CanReach(to) {
+			return next, true
+		}
+	}
+	return "", false
+}
+
+// SplitBySeller divides the order into one sub-order per seller of its products. The
+// order's discounts and shipping cost are shared out in proportion to the line totals, so
+// the sub-order totals add up to the order total.
+func SplitBySeller(order *Order) ([]*SubOrder, error) {
+	currency := order.TotalPrice.Currency
+	discounts, err := DiscountShares(currency, order.Products, order.Discounts)
+	if err != nil {
+		return nil, err
+	}
+	shipping := make([]money.Money, len(order.Products))
+	for i := range shipping {
+		shipping[i] = money.Zero(currency)
+	}
+	if order.ShippingMethod != "" && !order.ShippingCost.IsZero() {
+		weights := make([]int64, len(order.Products))
+		for i, product := range order.Products {
+			weights[i] = product.LineTotal().Amount
+		}
+		shipping, err = order.ShippingCost.Allocate(weights...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var subOrders []*SubOrder
+	bySeller := map[uint64]*SubOrder{}
+	for i, product := range order.Products {
+		sub, ok := bySeller[product.SellerID]
+		if !ok {
+			sub = &SubOrder{
+				SellerID:  product.SellerID,
+				Status:    order.Status,
+				Subtotal:  money.Zero(currency),
+				Discount:  money.Zero(currency),
+				Tax:       money.Zero(currency),
+				Shipping:  money.Zero(currency),
+				Total:     money.Zero(currency),
+				CreatedAt: order.CreatedAt,
+				UpdatedAt: order.CreatedAt,
+			}
+			bySeller[product.SellerID] = sub
+			subOrders = append(subOrders, sub)
+		}
+
+		lineTotal := product.LineTotal()
+		amounts := []money.Money{lineTotal, discounts[i].Neg(), shipping[i]}
+		sub.Subtotal, err = sub.Subtotal.Add(lineTotal)
+		if err != nil {
+			return nil, err
+		}
+		sub.Discount, err = sub.Discount.Add(discounts[i])
+		if err != nil {
+			return nil, err
+		}
+		sub.Shipping, err = sub.Shipping.Add(shipping[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range order.Taxes {
+			if t.ProductID != product.ID {
+				continue
+			}
+			sub.Tax, err = sub.Tax.Add(t.Amount)
+			if err != nil {
+				return nil, err
+			}
+			if !t.Inclusive {
+				amounts = append(amounts, t.Amount)
+			}
+		}
+		for _, amount := range amounts {
+			sub.Total, err = sub.Total.Add(amount)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return subOrders, nil
+}
+
+// SellerOrderPage is one page of a seller's sub-orders, newest first.
+type SellerOrderPage struct {
+	Orders []*SellerOrder
+	// EndCursor continues the listing after the last sub-order of the page
+	EndCursor   string
+	HasNextPage bool
+}
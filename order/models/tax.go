@@ -0,0 +1,19 @@
+package models
+
+import "github.com/rasadov/EcommerceAPI/pkg/money"
+
+// OrderTax is the tax charged on one line of an order.
+type OrderTax struct {
+	ID          uint `gorm:"primaryKey;autoIncrement"`
+	OrderID     uint `gorm:"index"`
+	ProductID   string
+	TaxCategory string `gorm:"type:varchar(20)"`
+	// Name is the name of the tax, e.g. "VAT"
+	Name string
+	// Rate is the decimal rate applied, e.g. "0.19"
+	Rate string `gorm:"type:varchar(16)"`
+	// Inclusive is set when the tax was already part of the product price rather than
+	// added to the order total
+	Inclusive bool
+	Amount    money.Money `gorm:"embedded;embeddedPrefix:amount_"`
+}
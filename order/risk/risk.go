@@ -0,0 +1,68 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// Check is an order as it is screened, before its checkout session is opened.
+type Check struct {
+	OrderID   uint
+	AccountID uint64
+	// Total is what the customer is about to pay, in the order currency
+	Total           money.Money
+	ShippingCountry string
+	Origin          models.OrderOrigin
+	PlacedAt        time.Time
+}
+
+// Rule scores one risk signal of an order. It returns nil when the signal is absent.
+type Rule interface {
+	Evaluate(ctx context.Context, check Check) (*models.RiskFinding, error)
+}
+
+// Engine screens orders with its rules. The scores of the matching rules add up, and the
+// order is held for review from reviewAt and blocked from blockAt.
+type Engine struct {
+	rules    []Rule
+	reviewAt int
+	blockAt  int
+}
+
+func NewEngine(reviewAt, blockAt int, rules ...Rule) *Engine {
+	return &Engine{rules, reviewAt, blockAt}
+}
+
+// Evaluate scores the order with every rule and decides whether it may be paid.
+func (engine *Engine) Evaluate(ctx context.Context, check Check) (*models.RiskAssessment, error) {
+	assessment := &models.RiskAssessment{
+		OrderID:   check.OrderID,
+		AccountID: check.AccountID,
+		ClientIP:  check.Origin.ClientIP,
+		Decision:  models.DecisionAllow,
+		Findings:  []models.RiskFinding{},
+	}
+	for _, rule := range engine.rules {
+		finding, err := rule.Evaluate(ctx, check)
+		if err != nil {
+			return nil, err
+		}
+		if finding == nil {
+			continue
+		}
+		assessment.Score += finding.Score
+		assessment.Findings = append(assessment.Findings, *finding)
+	}
+
+	switch {
+	case assessment.Score >= engine.blockAt:
+		assessment.Decision = models.DecisionBlock
+	case assessment.Score >= engine.reviewAt:
+		assessment.Decision = models.DecisionReview
+		assessment.ReviewStatus = models.ReviewPending
+	}
+	return assessment, nil
+}
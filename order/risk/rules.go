@@ -0,0 +1,139 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// History is the record of past placements that rules compare an order with. Attempts are
+// every order screened, including those that were blocked.
+type History interface {
+	CountAccountAttempts(ctx context.Context, accountId uint64, since time.Time) (int64, error)
+	CountIPAttempts(ctx context.Context, clientIP string, since time.Time) (int64, error)
+	CountPaidOrders(ctx context.Context, accountId uint64) (int64, error)
+}
+
+// AccountVelocity matches accounts placing more than Limit orders within Window, the
+// screened order included.
+type AccountVelocity struct {
+	History History
+	Window  time.Duration
+	Limit   int64
+	Score   int
+}
+
+func (rule AccountVelocity) Evaluate(ctx context.Context, check Check) (*models.RiskFinding, error) {
+	attempts, err := rule.History.CountAccountAttempts(ctx, check.AccountID, check.PlacedAt.Add(-rule.Window))
+	if err != nil {
+		return nil, err
+	}
+	if attempts+1 <= rule.Limit {
+		return nil, nil
+	}
+	return &models.RiskFinding{
+		Rule:   "account_velocity",
+		Score:  rule.Score,
+		Reason: fmt.Sprintf("%d orders from the account within %s", attempts+1, rule.Window),
+	}, nil
+}
+
+// IPVelocity matches clients placing more than Limit orders within Window, the screened
+// order included, whichever accounts they use. Card testing shows as many orders from one
+// address. Orders without a client address are not matched.
+type IPVelocity struct {
+	History History
+	Window  time.Duration
+	Limit   int64
+	Score   int
+}
+
+func (rule IPVelocity) Evaluate(ctx context.Context, check Check) (*models.RiskFinding, error) {
+	if check.Origin.ClientIP == "" {
+		return nil, nil
+	}
+	attempts, err := rule.History.CountIPAttempts(ctx, check.Origin.ClientIP, check.PlacedAt.Add(-rule.Window))
+	if err != nil {
+		return nil, err
+	}
+	if attempts+1 <= rule.Limit {
+		return nil, nil
+	}
+	return &models.RiskFinding{
+		Rule:   "ip_velocity",
+		Score:  rule.Score,
+		Reason: fmt.Sprintf("%d orders from %s within %s", attempts+1, check.Origin.ClientIP, rule.Window),
+	}, nil
+}
+
+// OrderValue matches orders totalling at least Threshold, to which they are converted
+// with Converter.
+type OrderValue struct {
+	Threshold money.Money
+	Converter *money.Converter
+	Score     int
+}
+
+func (rule OrderValue) Evaluate(ctx context.Context, check Check) (*models.RiskFinding, error) {
+	total, err := rule.Converter.Convert(check.Total, rule.Threshold.Currency)
+	if err != nil {
+		return nil, err
+	}
+	cmp, err := total.Cmp(rule.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	if cmp < 0 {
+		return nil, nil
+	}
+	return &models.RiskFinding{
+		Rule:   "order_value",
+		Score:  rule.Score,
+		Reason: fmt.Sprintf("order total %s is at least %s", check.Total, rule.Threshold),
+	}, nil
+}
+
+// NewAccount matches accounts that have not paid for an order yet. Stolen cards are
+// mostly tried on accounts made for the purpose.
+type NewAccount struct {
+	History History
+	Score   int
+}
+
+func (rule NewAccount) Evaluate(ctx context.Context, check Check) (*models.RiskFinding, error) {
+	paid, err := rule.History.CountPaidOrders(ctx, check.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if paid > 0 {
+		return nil, nil
+	}
+	return &models.RiskFinding{
+		Rule:   "new_account",
+		Score:  rule.Score,
+		Reason: "the account has no paid orders",
+	}, nil
+}
+
+// CountryMismatch matches orders billed to another country than they are delivered to.
+// Orders without either country are not matched.
+type CountryMismatch struct {
+	Score int
+}
+
+func (rule CountryMismatch) Evaluate(ctx context.Context, check Check) (*models.RiskFinding, error) {
+	billing := strings.ToUpper(strings.TrimSpace(check.Origin.BillingCountry))
+	shipping := strings.ToUpper(strings.TrimSpace(check.ShippingCountry))
+	if billing == "" || shipping == "" || billing == shipping {
+		return nil, nil
+	}
+	return &models.RiskFinding{
+		Rule:   "country_mismatch",
+		Score:  rule.Score,
+		Reason: fmt.Sprintf("billed in %s but delivered to %s", billing, shipping),
+	}, nil
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderExpirer_ExpireOrders(t *testing.T) {
+	ctx := context.Background()
+	lamps := []*models.OrderedProduct{{ID: "lamp", Price: money.New(2500, "EUR"), Quantity: 2}}
+
+	t.Run("Unpaid orders are cancelled, released and announced", func(t *testing.T) {
+		// Two purchases, one payment, and a status change and order_expired for the unpaid order
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 5), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 1}}
+		checkout := &fakeCheckout{}
+		expirer := internal.NewOrderExpirer(service, inventory, checkout, 0)
+
+		unpaid, err := service.PostOrder(ctx, 1, "EUR", lamps, "", models.Address{}, models.Shipping{})
+		require.NoError(t, err)
+		paid, err := service.PostOrder(ctx, 2, "EUR", lamps, "", models.Address{}, models.Shipping{})
+		require.NoError(t, err)
+		_, err = service.UpdateOrderStatus(ctx, uint64(paid.ID), "paid", "payment", "")
+		require.NoError(t, err)
+
+		expired, err := expirer.ExpireOrders(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, expired)
+
+		unpaid, err = service.GetOrder(ctx, uint64(unpaid.ID), 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusCancelled, unpaid.Status)
+		require.Len(t, unpaid.History, 2)
+		assert.Equal(t, "system", unpaid.History[1].Actor)
+		assert.Equal(t, "payment window expired", unpaid.History[1].Reason)
+		assert.Equal(t, 3, inventory.Stock("lamp"))
+		assert.Equal(t, []uint64{uint64(unpaid.ID)}, checkout.expired)
+
+		paid, err = service.GetOrder(ctx, uint64(paid.ID), 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusPaid, paid.Status)
+
+		// Orders are only expired once
+		expired, err = expirer.ExpireOrders(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, expired)
+		assert.Equal(t, 1, inventory.releases)
+	})
+
+	t.Run("Orders within the payment window are kept", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 1), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{}}
+		expirer := internal.NewOrderExpirer(service, inventory, &fakeCheckout{}, time.Hour)
+
+		order, err := service.PostOrder(ctx, 1, "EUR", lamps, "", models.Address{}, models.Shipping{})
+		require.NoError(t, err)
+
+		expired, err := expirer.ExpireOrders(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, expired)
+		order, err = service.GetOrder(ctx, uint64(order.ID), 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusPendingPayment, order.Status)
+		assert.Zero(t, inventory.releases)
+	})
+
+	t.Run("Expired orders end their placement without releasing stock twice", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 3), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		checkout := &fakeCheckout{}
+		saga := internal.NewOrderSaga(service, inventory, checkout, nil)
+		expirer := internal.NewOrderExpirer(service, inventory, checkout, 0)
+		_, _, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+		require.NoError(t, err)
+
+		_, err = expirer.ExpireOrders(ctx)
+		require.NoError(t, err)
+		require.NoError(t, saga.Resume(ctx))
+
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		assert.Equal(t, 1, inventory.releases)
+		assert.Empty(t, pendingSagas(t, ctx, service))
+	})
+}
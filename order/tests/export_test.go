@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/export"
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderRepository_ExportOrders(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	placed := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// More orders than are loaded in one batch
+	for i := 0; i < 105; i++ {
+		require.NoError(t, repo.PutOrder(ctx, &models.Order{
+			AccountID:  1,
+			TotalPrice: money.New(500, "EUR"),
+			CreatedAt:  placed.Add(time.Duration(i) * time.Hour),
+			Products: []*models.OrderedProduct{
+				{ID: "a", Price: money.New(500, "EUR"), Quantity: 1},
+			},
+		}))
+	}
+
+	var ids []uint
+	err := repo.ExportOrders(ctx, placed.Add(time.Hour), placed.Add(104*time.Hour), func(order *models.Order) error {
+		require.Len(t, order.Products, 1)
+		ids = append(ids, order.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 103)
+	assert.Equal(t, uint(2), ids[0])
+	assert.Equal(t, uint(104), ids[len(ids)-1])
+
+	stop := errors.New("stop")
+	count := 0
+	err = repo.ExportOrders(ctx, placed, placed.AddDate(0, 0, 30), func(order *models.Order) error {
+		count++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, count)
+}
+
+func TestOrderService_ExportOrders(t *testing.T) {
+	ctx := context.Background()
+	service := internal.NewOrderService(new(MockRepository), nil, nil, nil)
+	now := time.Now()
+
+	err := service.ExportOrders(ctx, now, now.Add(-time.Hour), func(*models.Order) error { return nil })
+	assert.ErrorIs(t, err, internal.ErrInvalidExportRange)
+	err = service.ExportOrders(ctx, time.Time{}, now, func(*models.Order) error { return nil })
+	assert.ErrorIs(t, err, internal.ErrInvalidExportRange)
+}
+
+// exportedOrder places and pays for an order of three lines with a discount and taxes, and
+// returns it as it is exported.
+func exportedOrder(t *testing.T, ctx context.Context) *models.Order {
+	repo := setupTestRepository(t)
+	service := internal.NewOrderService(repo, setupProducer(t, 4), nil, setupTaxCalculator(t, false))
+	order := postMarketplaceOrder(t, ctx, service)
+	_, err := service.UpdateOrderStatus(ctx, uint64(order.ID), "paid", "payment", "")
+	require.NoError(t, err)
+
+	var exported []*models.Order
+	err = service.ExportOrders(ctx, order.CreatedAt.Add(-time.Minute), order.CreatedAt.Add(time.Minute), func(order *models.Order) error {
+		exported = append(exported, order)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, exported, 1)
+	return exported[0]
+}
+
+func TestExport_Writers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CSV has a row per line", func(t *testing.T) {
+		order := exportedOrder(t, ctx)
+		var out bytes.Buffer
+		writer, err := export.NewWriter(export.FormatCSV, &out)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(order))
+		require.NoError(t, writer.Close())
+
+		rows, err := csv.NewReader(&out).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 4)
+		column := map[string]int{}
+		for i, name := range rows[0] {
+			column[name] = i
+		}
+		book := rows[1]
+		assert.Equal(t, "book", book[column["product_id"]])
+		assert.Equal(t, "paid", book[column["payment_status"]])
+		assert.Equal(t, "7", book[column["seller_id"]])
+		assert.Equal(t, "19.99", book[column["unit_price"]])
+		assert.Equal(t, "VAT", book[column["tax_name"]])
+		assert.Equal(t, "0.07", book[column["tax_rate"]])
+		assert.Equal(t, "7.99", book[column["order_discount"]])
+		assert.Equal(t, order.TotalPrice.Decimal(), book[column["order_total"]])
+		assert.Equal(t, "2", rows[2][column["quantity"]])
+
+		// The lines' shares of the discount add up to the order's
+		var discount int64
+		for _, row := range rows[1:] {
+			share, err := money.ParseMajor(row[column["line_discount"]], "EUR")
+			require.NoError(t, err)
+			discount += share.Amount
+		}
+		assert.Equal(t, int64(799), discount)
+	})
+
+	t.Run("JSON Lines has an object per order", func(t *testing.T) {
+		order := exportedOrder(t, ctx)
+		var out bytes.Buffer
+		writer, err := export.NewWriter(export.FormatJSONL, &out)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(order))
+		require.NoError(t, writer.Write(order))
+		require.NoError(t, writer.Close())
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 2)
+		var record export.Record
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+		assert.Equal(t, uint64(order.ID), record.OrderID)
+		assert.Equal(t, models.PaymentPaid, record.PaymentStatus)
+		assert.Equal(t, "DE", record.Country)
+		require.Len(t, record.Lines, 3)
+		assert.Equal(t, "lamp", record.Lines[1].ProductID)
+		assert.Equal(t, "50.00", record.Lines[1].LineTotal)
+	})
+
+	t.Run("Parquet is framed by its magic number", func(t *testing.T) {
+		order := exportedOrder(t, ctx)
+		var out bytes.Buffer
+		writer, err := export.NewWriter(export.FormatParquet, &out)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(order))
+		require.NoError(t, writer.Close())
+
+		assert.True(t, bytes.HasPrefix(out.Bytes(), []byte("PAR1")))
+		assert.True(t, bytes.HasSuffix(out.Bytes(), []byte("PAR1")))
+		assert.Contains(t, out.String(), "payment_status")
+	})
+
+	t.Run("Unknown formats are rejected", func(t *testing.T) {
+		_, err := export.NewWriter("xlsx", &bytes.Buffer{})
+		assert.ErrorIs(t, err, export.ErrUnknownFormat)
+	})
+}
+
+func TestOrder_PaymentStatus(t *testing.T) {
+	paid := []*models.StatusTransition{{From: models.StatusPendingPayment, To: models.StatusPaid}}
+
+	assert.Equal(t, models.PaymentUnpaid, models.Order{Status: models.StatusPendingPayment}.PaymentStatus())
+	assert.Equal(t, models.PaymentPaid, models.Order{Status: models.StatusDelivered, History: paid}.PaymentStatus())
+	assert.Equal(t, models.PaymentPartiallyRefunded, models.Order{
+		Status:  models.StatusDelivered,
+		History: paid,
+		Returns: []*models.Return{{Status: models.ReturnRefunded}},
+	}.PaymentStatus())
+	assert.Equal(t, models.PaymentRefunded, models.Order{Status: models.StatusRefunded, History: paid}.PaymentStatus())
+}
+
+func TestOrder_WasPaid(t *testing.T) {
+	expired := []*models.StatusTransition{{From: models.StatusPendingPayment, To: models.StatusCancelled}}
+	// Orders paid before their history was recorded only have what happened since
+	cancelledLegacy := []*models.StatusTransition{{From: models.StatusFulfilling, To: models.StatusCancelled}}
+
+	assert.False(t, models.Order{Status: models.StatusPendingPayment}.WasPaid())
+	assert.False(t, models.Order{Status: models.StatusCancelled, History: expired}.WasPaid())
+	assert.True(t, models.Order{Status: models.StatusPaid}.WasPaid())
+	assert.True(t, models.Order{Status: models.StatusDelivered}.WasPaid())
+	assert.True(t, models.Order{Status: models.StatusCancelled, History: cancelledLegacy}.WasPaid())
+	assert.True(t, models.Order{Status: models.StatusRefunded}.WasPaid())
+}
@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	accountmodels "github.com/rasadov/EcommerceAPI/account/models"
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/blob"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAccounts struct{}
+
+func (fakeAccounts) GetAccount(ctx context.Context, id uint64) (*accountmodels.Account, error) {
+	return &accountmodels.Account{ID: id, Name: "Ada <Lovelace>", Email: "ada@example.com"}, nil
+}
+
+var seller = models.Party{
+	Name:    "Lamp & Book GmbH",
+	TaxID:   "DE123456789",
+	Address: models.Address{Line1: "Hauptstraße 1", City: "Berlin", PostalCode: "10115", Country: "DE"},
+}
+
+// setupPaidOrder places a taxed and discounted order and pays it.
+func setupPaidOrder(t *testing.T, ctx context.Context, service internal.Service, accountID uint64) *models.Order {
+	order, err := service.PostOrder(ctx, accountID, "EUR", []*models.OrderedProduct{
+		{ID: "book", Name: "Book", Price: money.New(1999, "EUR"), Quantity: 1, TaxCategory: "reduced"},
+		{ID: "lamp", Name: "Lamp (blue)", Price: money.New(2500, "EUR"), Quantity: 2},
+	}, "TENOFF", models.Address{RecipientName: "Ada", Line1: "Street 2", City: "Hamburg", Country: "DE"}, models.Shipping{})
+	require.NoError(t, err)
+	order, err = service.UpdateOrderStatus(ctx, uint64(order.ID), "paid", "payment", "")
+	require.NoError(t, err)
+	return order
+}
+
+func TestOrderService_IssueInvoice(t *testing.T) {
+	ctx := context.Background()
+	buyer := models.Party{Name: "Ada"}
+
+	t.Run("Invoices are numbered in sequence and issued once", func(t *testing.T) {
+		// Two products and a payment per order
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 6), nil, setupTaxCalculator(t, false))
+		_, err := service.CreatePromotion(ctx, &models.Promotion{Code: "TENOFF", Kind: models.PromotionPercentage, PercentOff: 10, Active: true})
+		require.NoError(t, err)
+		first := setupPaidOrder(t, ctx, service, 1)
+		second := setupPaidOrder(t, ctx, service, 2)
+
+		invoice, err := service.IssueInvoice(ctx, first, seller, buyer)
+		require.NoError(t, err)
+		series := models.InvoiceSeries(time.Now())
+		assert.Equal(t, series+"-000001", invoice.Number)
+
+		again, err := service.IssueInvoice(ctx, first, seller, buyer)
+		require.NoError(t, err)
+		assert.Equal(t, invoice.ID, again.ID)
+
+		invoice, err = service.IssueInvoice(ctx, second, seller, buyer)
+		require.NoError(t, err)
+		assert.Equal(t, series+"-000002", invoice.Number)
+	})
+
+	t.Run("Invoice totals match the order", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 3), nil, setupTaxCalculator(t, false))
+		_, err := service.CreatePromotion(ctx, &models.Promotion{Code: "TENOFF", Kind: models.PromotionPercentage, PercentOff: 10, Active: true})
+		require.NoError(t, err)
+		order := setupPaidOrder(t, ctx, service, 1)
+
+		_, err = service.IssueInvoice(ctx, order, seller, buyer)
+		require.NoError(t, err)
+		invoice, err := service.GetInvoice(ctx, uint64(order.ID))
+		require.NoError(t, err)
+
+		assert.Equal(t, seller, invoice.Seller)
+		assert.Equal(t, order.TotalPrice, invoice.Total)
+		assert.Equal(t, money.New(6999, "EUR"), invoice.Subtotal)
+		assert.Equal(t, money.New(699, "EUR"), invoice.Discount)
+		taxTotal, err := order.TaxTotal()
+		require.NoError(t, err)
+		assert.Equal(t, taxTotal, invoice.Tax)
+		assert.False(t, invoice.TaxIncluded)
+
+		require.Len(t, invoice.Lines, 2)
+		lamp := invoice.Lines[1]
+		assert.Equal(t, "Lamp (blue)", lamp.Name)
+		assert.Equal(t, 2, lamp.Quantity)
+		assert.Equal(t, money.New(5000, "EUR"), lamp.Amount)
+		assert.Equal(t, "VAT", lamp.TaxName)
+		assert.Equal(t, "0.19", lamp.TaxRate)
+		// 19% of the lamps' 50.00 less their share of the discount
+		assert.Equal(t, money.New(855, "EUR"), lamp.Tax)
+	})
+
+	t.Run("Unpaid orders are not invoiced", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 1), nil, nil)
+		order, err := service.PostOrder(ctx, 1, "EUR", []*models.OrderedProduct{{ID: "lamp", Price: money.New(2500, "EUR"), Quantity: 1}}, "", models.Address{}, models.Shipping{})
+		require.NoError(t, err)
+
+		_, err = service.IssueInvoice(ctx, order, seller, buyer)
+
+		assert.ErrorIs(t, err, internal.ErrOrderNotPaid)
+		_, err = service.GetInvoice(ctx, uint64(order.ID))
+		assert.Error(t, err)
+	})
+}
+
+func TestInvoicer(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (internal.Service, *internal.Invoicer, *models.Order) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 3), nil, setupTaxCalculator(t, false))
+		_, err := service.CreatePromotion(ctx, &models.Promotion{Code: "TENOFF", Kind: models.PromotionPercentage, PercentOff: 10, Active: true})
+		require.NoError(t, err)
+		store, err := blob.NewDiskStore(t.TempDir())
+		require.NoError(t, err)
+		return service, internal.NewInvoicer(service, store, fakeAccounts{}, seller), setupPaidOrder(t, ctx, service, 1)
+	}
+
+	t.Run("Invoices are made out to the account and rendered", func(t *testing.T) {
+		_, invoicer, order := setup(t)
+
+		invoice, err := invoicer.Invoice(ctx, order)
+		require.NoError(t, err)
+		assert.Equal(t, "Ada <Lovelace>", invoice.Buyer.Name)
+		assert.Equal(t, "ada@example.com", invoice.Buyer.Email)
+		assert.Equal(t, "Hamburg", invoice.Buyer.Address.City)
+
+		document, contentType, err := invoicer.Document(ctx, invoice, "pdf")
+		require.NoError(t, err)
+		assert.Equal(t, "application/pdf", contentType)
+		assert.True(t, bytes.HasPrefix(document, []byte("%PDF-")))
+		assert.Contains(t, string(document), "(Invoice "+invoice.Number+") Tj")
+		assert.Contains(t, string(document), `Lamp \(blue\)`)
+
+		document, contentType, err = invoicer.Document(ctx, invoice, "html")
+		require.NoError(t, err)
+		assert.Equal(t, "text/html; charset=utf-8", contentType)
+		assert.Contains(t, string(document), "<h1>Invoice "+invoice.Number+"</h1>")
+		assert.Contains(t, string(document), "Ada &lt;Lovelace&gt;")
+		assert.Contains(t, string(document), "VAT 19%: 8.55 EUR")
+		assert.Contains(t, string(document), "Tax ID: DE123456789")
+	})
+
+	t.Run("Documents are stored once", func(t *testing.T) {
+		service, invoicer, order := setup(t)
+		invoice, err := invoicer.Invoice(ctx, order)
+		require.NoError(t, err)
+		stored, _, err := invoicer.Document(ctx, invoice, "html")
+		require.NoError(t, err)
+
+		// The stored document is served even if the invoice would render differently now
+		changed := *invoice
+		changed.Seller.Name = "Someone else"
+		document, _, err := invoicer.Document(ctx, &changed, "html")
+		require.NoError(t, err)
+		assert.Equal(t, stored, document)
+
+		again, err := invoicer.Invoice(ctx, order)
+		require.NoError(t, err)
+		assert.Equal(t, invoice.Number, again.Number)
+		_, err = service.GetInvoice(ctx, uint64(order.ID))
+		require.NoError(t, err)
+	})
+
+	t.Run("Unknown formats are refused", func(t *testing.T) {
+		_, invoicer, order := setup(t)
+		invoice, err := invoicer.Invoice(ctx, order)
+		require.NoError(t, err)
+
+		_, _, err = invoicer.Document(ctx, invoice, "docx")
+
+		assert.ErrorIs(t, err, internal.ErrInvalidInvoiceFormat)
+	})
+}
@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPromotion_Discount(t *testing.T) {
+	products := []*models.OrderedProduct{
+		{ID: "shirt", Price: money.New(2000, "USD"), Quantity: 3, Category: "Apparel"},
+		{ID: "mug", Price: money.New(999, "USD"), Quantity: 1, Category: "Kitchen"},
+	}
+	subtotal := money.New(6999, "USD")
+
+	t.Run("Percentage off every product, rounded down", func(t *testing.T) {
+		promotion := models.Promotion{Kind: models.PromotionPercentage, PercentOff: 15}
+
+		discount, err := promotion.Discount(products, subtotal, money.Money{}, money.Money{})
+
+		require.NoError(t, err)
+		assert.Equal(t, money.New(1049, "USD"), discount)
+	})
+
+	t.Run("Percentage off a category", func(t *testing.T) {
+		promotion := models.Promotion{Kind: models.PromotionPercentage, PercentOff: 10, Categories: []string{"apparel"}}
+
+		discount, err := promotion.Discount(products, subtotal, money.Money{}, money.Money{})
+
+		require.NoError(t, err)
+		assert.Equal(t, money.New(600, "USD"), discount)
+	})
+
+	t.Run("Fixed amount is capped at the eligible products", func(t *testing.T) {
+		promotion := models.Promotion{Kind: models.PromotionFixed, ProductIDs: []string{"mug"}}
+
+		discount, err := promotion.Discount(products, subtotal, money.New(1500, "USD"), money.Money{})
+
+		require.NoError(t, err)
+		assert.Equal(t, money.New(999, "USD"), discount)
+	})
+
+	t.Run("Buy two get one free", func(t *testing.T) {
+		promotion := models.Promotion{Kind: models.PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1}
+
+		discount, err := promotion.Discount(products, subtotal, money.Money{}, money.Money{})
+
+		require.NoError(t, err)
+		assert.Equal(t, money.New(2000, "USD"), discount)
+	})
+
+	t.Run("Minimum subtotal not reached", func(t *testing.T) {
+		promotion := models.Promotion{Kind: models.PromotionPercentage, PercentOff: 10}
+
+		_, err := promotion.Discount(products, subtotal, money.Money{}, money.New(10000, "USD"))
+
+		assert.ErrorIs(t, err, models.ErrMinimumNotReached)
+	})
+
+	t.Run("No eligible products", func(t *testing.T) {
+		promotion := models.Promotion{Kind: models.PromotionPercentage, PercentOff: 10, ProductIDs: []string{"lamp"}}
+
+		_, err := promotion.Discount(products, subtotal, money.Money{}, money.Money{})
+
+		assert.ErrorIs(t, err, models.ErrNoEligibleProducts)
+	})
+}
+
+func TestPromotion_ActiveAt(t *testing.T) {
+	now := time.Now()
+	yesterday := now.Add(-24 * time.Hour)
+	tomorrow := now.Add(24 * time.Hour)
+
+	assert.True(t, models.Promotion{Active: true}.ActiveAt(now))
+	assert.False(t, models.Promotion{Active: false}.ActiveAt(now))
+	assert.True(t, models.Promotion{Active: true, StartsAt: &yesterday, EndsAt: &tomorrow}.ActiveAt(now))
+	assert.False(t, models.Promotion{Active: true, StartsAt: &tomorrow}.ActiveAt(now))
+	assert.False(t, models.Promotion{Active: true, EndsAt: &yesterday}.ActiveAt(now))
+}
+
+func TestOrderService_Promotions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Codes are unique regardless of case", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 0), nil, nil)
+
+		created, err := service.CreatePromotion(ctx, &models.Promotion{Code: " summer10 ", Kind: models.PromotionPercentage, PercentOff: 10, Active: true})
+		require.NoError(t, err)
+		assert.Equal(t, "SUMMER10", created.Code)
+
+		_, err = service.CreatePromotion(ctx, &models.Promotion{Code: "Summer10", Kind: models.PromotionPercentage, PercentOff: 20, Active: true})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("Incomplete rules are rejected", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 0), nil, nil)
+
+		_, err := service.CreatePromotion(ctx, &models.Promotion{Code: "BROKEN", Kind: models.PromotionFixed, Active: true})
+
+		assert.ErrorIs(t, err, internal.ErrInvalidPromotion)
+	})
+
+	t.Run("Order is discounted and the discount persisted", func(t *testing.T) {
+		repo := setupTestRepository(t)
+		service := internal.NewOrderService(repo, setupProducer(t, 1), nil, nil)
+		_, err := service.CreatePromotion(ctx, &models.Promotion{Code: "TENOFF", Description: "10% off", Kind: models.PromotionPercentage, PercentOff: 10, Active: true})
+		require.NoError(t, err)
+
+		products := []*models.OrderedProduct{{ID: "a", Price: money.New(2500, "USD"), Quantity: 2}}
+		order, err := service.PostOrder(ctx, 1, "USD", products, "tenoff", models.Address{}, models.Shipping{})
+		require.NoError(t, err)
+		assert.Equal(t, money.New(4500, "USD"), order.TotalPrice)
+
+		stored, err := repo.GetOrder(ctx, uint64(order.ID))
+		require.NoError(t, err)
+		require.Len(t, stored.Discounts, 1)
+		assert.Equal(t, "TENOFF", stored.Discounts[0].Code)
+		assert.Equal(t, money.New(500, "USD"), stored.Discounts[0].Amount)
+		subtotal, err := stored.Subtotal()
+		require.NoError(t, err)
+		assert.Equal(t, money.New(5000, "USD"), subtotal)
+	})
+
+	t.Run("Per-customer usage limit", func(t *testing.T) {
+		repo := setupTestRepository(t)
+		// Three orders are placed and one is cancelled
+		service := internal.NewOrderService(repo, setupProducer(t, 4), nil, nil)
+		_, err := service.CreatePromotion(ctx, &models.Promotion{Code: "ONCE", Kind: models.PromotionPercentage, PercentOff: 5, UsageLimitPerCustomer: 1, Active: true})
+		require.NoError(t, err)
+		products := []*models.OrderedProduct{{ID: "a", Price: money.New(1000, "USD"), Quantity: 1}}
+
+		first, err := service.PostOrder(ctx, 1, "USD", products, "ONCE", models.Address{}, models.Shipping{})
+		require.NoError(t, err)
+
+		_, err = service.PostOrder(ctx, 1, "USD", products, "ONCE", models.Address{}, models.Shipping{})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		// Other customers have their own allowance
+		_, err = service.PostOrder(ctx, 2, "USD", products, "ONCE", models.Address{}, models.Shipping{})
+		require.NoError(t, err)
+
+		// Cancelling the order gives the use back
+		_, err = service.CancelOrder(ctx, uint64(first.ID), 1, "changed my mind")
+		require.NoError(t, err)
+		_, err = service.PostOrder(ctx, 1, "USD", products, "ONCE", models.Address{}, models.Shipping{})
+		require.NoError(t, err)
+	})
+
+	t.Run("Inactive and unknown codes", func(t *testing.T) {
+		repo := setupTestRepository(t)
+		service := internal.NewOrderService(repo, setupProducer(t, 0), nil, nil)
+		created, err := service.CreatePromotion(ctx, &models.Promotion{Code: "GONE", Kind: models.PromotionPercentage, PercentOff: 5, Active: true})
+		require.NoError(t, err)
+		_, err = service.DeactivatePromotion(ctx, created.ID)
+		require.NoError(t, err)
+		products := []*models.OrderedProduct{{ID: "a", Price: money.New(1000, "USD"), Quantity: 1}}
+
+		_, err = service.EvaluatePromotion(ctx, "GONE", 1, "USD", products)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = service.EvaluatePromotion(ctx, "NOPE", 1, "USD", products)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestOrderRepository_PutOrderUsageLimit(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	promotion := &models.Promotion{Code: "ONCE", Kind: models.PromotionPercentage, PercentOff: 5, UsageLimitPerCustomer: 1, Active: true}
+	require.NoError(t, repo.CreatePromotion(ctx, promotion))
+	newOrder := func(accountID uint64) *models.Order {
+		return &models.Order{
+			AccountID:  accountID,
+			TotalPrice: money.New(950, "USD"),
+			Status:     models.StatusPendingPayment,
+			Discounts:  []*models.OrderDiscount{{PromotionID: promotion.ID, Code: "ONCE", Amount: money.New(50, "USD")}},
+		}
+	}
+
+	// Both orders passed evaluation before either was stored
+	require.NoError(t, repo.PutOrder(ctx, newOrder(1)))
+	err := repo.PutOrder(ctx, newOrder(1))
+	assert.ErrorIs(t, err, models.ErrPromotionUsageLimit)
+
+	used, err := repo.CountRedemptions(ctx, promotion.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), used)
+	assert.NoError(t, repo.PutOrder(ctx, newOrder(2)))
+}
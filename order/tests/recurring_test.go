@@ -0,0 +1,267 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	productmodels "github.com/rasadov/EcommerceAPI/product/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeCatalog stands in for the product service, listing the products still sold
+type fakeCatalog struct {
+	products []productmodels.Product
+}
+
+func (f *fakeCatalog) GetProducts(ctx context.Context, skip, take uint64, ids []string, query string) ([]productmodels.Product, error) {
+	var found []productmodels.Product
+	for _, p := range f.products {
+		for _, id := range ids {
+			if p.ID == id {
+				found = append(found, p)
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func stock(n int) *int {
+	return &n
+}
+
+// Test helper to schedule weekly lamps for account 1, due at nextRunAt
+func lampSchedule(nextRunAt time.Time, status models.ScheduleStatus) *models.RecurringOrder {
+	return &models.RecurringOrder{
+		AccountID: 1,
+		Currency:  "EUR",
+		Interval:  models.IntervalWeekly,
+		Status:    status,
+		Items:     []*models.RecurringItem{{ProductID: "lamp", Quantity: 2}},
+		NextRunAt: nextRunAt,
+	}
+}
+
+func TestOrderService_CreateRecurringOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Schedules are validated", func(t *testing.T) {
+		service := internal.NewOrderService(new(MockRepository), nil, nil, nil)
+
+		invalid := lampSchedule(time.Time{}, "")
+		invalid.Interval = "daily"
+		_, err := service.CreateRecurringOrder(ctx, invalid)
+		assert.ErrorIs(t, err, internal.ErrInvalidInterval)
+
+		invalid = lampSchedule(time.Time{}, "")
+		invalid.Items = nil
+		_, err = service.CreateRecurringOrder(ctx, invalid)
+		assert.ErrorIs(t, err, internal.ErrInvalidSchedule)
+
+		invalid = lampSchedule(time.Time{}, "")
+		invalid.Items[0].Quantity = 0
+		_, err = service.CreateRecurringOrder(ctx, invalid)
+		assert.ErrorIs(t, err, internal.ErrInvalidSchedule)
+
+		invalid = lampSchedule(time.Time{}, "")
+		invalid.Currency = "XXX"
+		_, err = service.CreateRecurringOrder(ctx, invalid)
+		assert.ErrorIs(t, err, money.ErrUnknownCurrency)
+
+		invalid = lampSchedule(time.Time{}, "")
+		invalid.ShippingMethod = "standard"
+		_, err = service.CreateRecurringOrder(ctx, invalid)
+		assert.ErrorIs(t, err, internal.ErrIncompleteAddress)
+	})
+
+	t.Run("A schedule starting in the past runs right away", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, nil, nil, nil)
+		mockRepo.On("CreateRecurringOrder", ctx, mock.AnythingOfType("*models.RecurringOrder")).Return(nil).Once()
+
+		before := time.Now().UTC()
+		recurring, err := service.CreateRecurringOrder(ctx, lampSchedule(before.AddDate(0, 0, -3), "paused"))
+
+		require.NoError(t, err)
+		assert.Equal(t, models.ScheduleActive, recurring.Status)
+		assert.False(t, recurring.NextRunAt.Before(before))
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestOrderService_UpdateRecurringOrderStatus(t *testing.T) {
+	ctx := context.Background()
+	repo := setupTestRepository(t)
+	service := internal.NewOrderService(repo, nil, nil, nil)
+
+	// Paused for two and a half weeks
+	start := time.Now().UTC().Add(-time.Hour).AddDate(0, 0, -14)
+	recurring := lampSchedule(start, models.ScheduleActive)
+	require.NoError(t, repo.CreateRecurringOrder(ctx, recurring))
+	_, err := service.UpdateRecurringOrderStatus(ctx, recurring.ID, 1, "paused")
+	require.NoError(t, err)
+
+	t.Run("Other accounts cannot see the schedule", func(t *testing.T) {
+		_, err := service.UpdateRecurringOrderStatus(ctx, recurring.ID, 2, "active")
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Unknown statuses are rejected", func(t *testing.T) {
+		_, err := service.UpdateRecurringOrderStatus(ctx, recurring.ID, 1, "finished")
+		assert.ErrorIs(t, err, internal.ErrInvalidScheduleStatus)
+	})
+
+	t.Run("Resuming skips the runs missed while paused", func(t *testing.T) {
+		resumed, err := service.UpdateRecurringOrderStatus(ctx, recurring.ID, 1, "active")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.ScheduleActive, resumed.Status)
+		assert.WithinDuration(t, start.AddDate(0, 0, 21), resumed.NextRunAt, time.Second)
+
+		stored, err := service.GetRecurringOrder(ctx, recurring.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, models.ScheduleActive, stored.Status)
+		assert.WithinDuration(t, resumed.NextRunAt, stored.NextRunAt, time.Second)
+		require.Len(t, stored.Items, 1)
+	})
+
+	t.Run("Cancelled schedules stay cancelled", func(t *testing.T) {
+		_, err := service.UpdateRecurringOrderStatus(ctx, recurring.ID, 1, "cancelled")
+		require.NoError(t, err)
+
+		_, err = service.UpdateRecurringOrderStatus(ctx, recurring.ID, 1, "active")
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestOrderRepository_ClaimDueRecurringOrders(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	now := time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC)
+
+	// Due two weeks and a day ago, having missed two runs
+	overdue := lampSchedule(now.AddDate(0, 0, -15), models.ScheduleActive)
+	due := lampSchedule(now.Add(-time.Minute), models.ScheduleActive)
+	due.Interval = models.IntervalMonthly
+	due.Items = append(due.Items, &models.RecurringItem{ProductID: "book", Quantity: 1})
+	for _, recurring := range []*models.RecurringOrder{
+		overdue,
+		due,
+		lampSchedule(now.Add(time.Hour), models.ScheduleActive),
+		lampSchedule(now.AddDate(0, 0, -1), models.SchedulePaused),
+	} {
+		require.NoError(t, repo.CreateRecurringOrder(ctx, recurring))
+	}
+
+	claimed, err := repo.ClaimDueRecurringOrders(ctx, now, 10)
+
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, overdue.ID, claimed[0].ID)
+	assert.True(t, now.AddDate(0, 0, 6).Equal(claimed[0].NextRunAt))
+	require.Len(t, claimed[0].Items, 1)
+	assert.Equal(t, due.ID, claimed[1].ID)
+	assert.True(t, now.Add(-time.Minute).AddDate(0, 1, 0).Equal(claimed[1].NextRunAt))
+	require.Len(t, claimed[1].Items, 2)
+	assert.Equal(t, "book", claimed[1].Items[1].ProductID)
+
+	stored, err := repo.GetRecurringOrder(ctx, overdue.ID)
+	require.NoError(t, err)
+	assert.True(t, claimed[0].NextRunAt.Equal(stored.NextRunAt))
+
+	// Each run is claimed once
+	claimed, err = repo.ClaimDueRecurringOrders(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+}
+
+func TestPricer_Reorder(t *testing.T) {
+	ctx := context.Background()
+	catalog := &fakeCatalog{products: []productmodels.Product{
+		{ID: "lamp", Name: "Desk lamp", Price: money.New(2700, "EUR")},
+		{ID: "kettle", Name: "Kettle", Price: money.New(3000, "EUR"), Stock: stock(1)},
+	}}
+	pricer := internal.NewPricer(catalog, nil, nil)
+	order := &models.Order{
+		TotalPrice: money.New(13500, "EUR"),
+		Products: []*models.OrderedProduct{
+			{ID: "lamp", Name: "Lamp", Price: money.New(2500, "EUR"), Quantity: 2},
+			{ID: "kettle", Name: "Kettle", Price: money.New(3000, "EUR"), Quantity: 2},
+			{ID: "book", Name: "Book", Price: money.New(1999, "EUR"), Quantity: 1},
+		},
+	}
+
+	lines, err := pricer.Reorder(ctx, order)
+
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "Desk lamp", lines[0].Name)
+	assert.Equal(t, money.New(2700, "EUR"), lines[0].UnitPrice)
+	assert.Equal(t, money.New(2500, "EUR"), lines[0].PreviousUnitPrice)
+	assert.True(t, lines[0].Available)
+	// Not enough stock left for two kettles
+	assert.False(t, lines[1].Available)
+	assert.Equal(t, uint32(2), lines[1].Quantity)
+	// No longer sold
+	assert.Equal(t, "Book", lines[2].Name)
+	assert.True(t, lines[2].UnitPrice.IsZero())
+	assert.False(t, lines[2].Available)
+}
+
+func TestRecurringScheduler_PlaceDueOrders(t *testing.T) {
+	ctx := context.Background()
+	repo := setupTestRepository(t)
+	// The lamp order and the run events of both schedules
+	service := internal.NewOrderService(repo, setupProducer(t, 3), nil, nil)
+	inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+	checkout := &fakeCheckout{}
+	saga := internal.NewOrderSaga(service, inventory, checkout, nil)
+	catalog := &fakeCatalog{products: []productmodels.Product{
+		{ID: "lamp", Name: "Lamp", Price: money.New(2500, "EUR")},
+	}}
+	scheduler := internal.NewRecurringScheduler(service, saga, internal.NewPricer(catalog, nil, nil), fakeAccounts{})
+
+	lamps := lampSchedule(time.Now().UTC().Add(-time.Minute), models.ScheduleActive)
+	lamps.RedirectURL = "https://shop.example/done"
+	require.NoError(t, repo.CreateRecurringOrder(ctx, lamps))
+	discontinued := lampSchedule(time.Now().UTC().Add(-time.Minute), models.ScheduleActive)
+	discontinued.Items[0].ProductID = "book"
+	require.NoError(t, repo.CreateRecurringOrder(ctx, discontinued))
+	require.NoError(t, repo.CreateRecurringOrder(ctx, lampSchedule(time.Now().UTC().Add(time.Hour), models.ScheduleActive)))
+
+	placed, err := scheduler.PlaceDueOrders(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, placed)
+	assert.Equal(t, 3, inventory.Stock("lamp"))
+
+	stored, err := service.GetRecurringOrder(ctx, lamps.ID, 1)
+	require.NoError(t, err)
+	require.NotZero(t, stored.LastOrderID)
+	assert.Equal(t, "https://pay.example/ada@example.com", stored.LastCheckoutURL)
+	assert.Empty(t, stored.LastError)
+	assert.True(t, stored.NextRunAt.After(time.Now()))
+	assert.Equal(t, []int{int(stored.LastOrderID)}, checkout.orderIds)
+
+	order, err := service.GetOrder(ctx, uint64(stored.LastOrderID), 1)
+	require.NoError(t, err)
+	assert.Equal(t, money.New(5000, "EUR"), order.TotalPrice)
+
+	stored, err = service.GetRecurringOrder(ctx, discontinued.ID, 1)
+	require.NoError(t, err)
+	assert.Zero(t, stored.LastOrderID)
+	assert.Equal(t, status.Convert(internal.ErrNothingToOrder).Message(), stored.LastError)
+
+	// Nothing else is due
+	placed, err = scheduler.PlaceDueOrders(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, placed)
+}
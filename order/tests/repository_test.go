@@ -0,0 +1,313 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Test helper to create a repository backed by an in-memory SQLite database
+func setupTestRepository(t *testing.T) internal.Repository {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	r, err := internal.NewPostgresRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestOrderRepository_PutOrder(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	order := &models.Order{
+		AccountID:  1,
+		TotalPrice: money.New(6047, "USD"),
+		Products: []*models.OrderedProduct{
+			{ID: "a", Price: money.New(10, "USD"), Quantity: 3},
+			{ID: "b", Price: money.New(1999, "USD"), Quantity: 3},
+		},
+	}
+	require.NoError(t, repo.PutOrder(ctx, order))
+	assert.NotZero(t, order.ID)
+
+	orders, err := repo.GetOrdersForAccount(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, money.New(6047, "USD"), orders[0].TotalPrice)
+	require.Len(t, orders[0].Products, 2)
+	assert.Equal(t, money.New(10, "USD"), orders[0].Products[0].Price)
+	assert.Equal(t, money.New(5997, "USD"), orders[0].Products[1].LineTotal())
+}
+
+func TestOrderRepository_MigrateLegacyStatuses(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	// Orders written before statuses were typed only kept a payment status
+	require.NoError(t, db.AutoMigrate(&models.Order{}))
+	require.NoError(t, db.Exec("ALTER TABLE orders ADD COLUMN payment_status text").Error)
+	require.NoError(t, db.Exec(`INSERT INTO orders (id, created_at, account_id, status, payment_status) VALUES
+		(1, ?, 1, '', 'Success'), (2, ?, 1, '', 'Pending')`, time.Now(), time.Now()).Error)
+
+	repo, err := internal.NewPostgresRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(repo.Close)
+
+	paid, err := repo.GetOrder(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPaid, paid.Status)
+	assert.True(t, paid.WasPaid())
+	require.Len(t, paid.History, 1)
+	assert.Equal(t, models.StatusPendingPayment, paid.History[0].From)
+
+	unpaid, err := repo.GetOrder(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPendingPayment, unpaid.Status)
+	assert.False(t, unpaid.WasPaid())
+}
+
+func TestOrderRepository_LineSnapshots(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	order := &models.Order{
+		AccountID:  1,
+		TotalPrice: money.New(2500, "EUR"),
+		Status:     models.StatusPaid,
+		Products: []*models.OrderedProduct{
+			{ID: "a", Name: "Kettle", Description: "1.7 l", Price: money.New(1250, "EUR"), Quantity: 2},
+		},
+	}
+	require.NoError(t, repo.PutOrder(ctx, order))
+
+	stored, err := repo.GetOrder(ctx, uint64(order.ID))
+	require.NoError(t, err)
+	require.Len(t, stored.ProductsInfos, 1)
+	line := stored.ProductsInfos[0]
+	assert.True(t, line.HasSnapshot())
+	assert.Equal(t, "Kettle", line.Name)
+	assert.Equal(t, "1.7 l", line.Description)
+	assert.Equal(t, money.New(1250, "EUR"), line.UnitPrice)
+	assert.Equal(t, money.New(2500, "EUR"), line.LineTotal)
+
+	lines, err := repo.GetOrderLinesForProducts(ctx, []string{"a"}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "Kettle", lines[0].Name)
+	assert.Equal(t, money.New(1250, "EUR"), lines[0].UnitPrice)
+
+	legacy, err := repo.GetOrdersWithoutLineSnapshots(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, legacy)
+}
+
+func TestOrderRepository_BackfillLineSnapshots(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	// Older versions stored only the product and quantity
+	order := &models.Order{
+		AccountID:     1,
+		TotalPrice:    money.New(300, "USD"),
+		ProductsInfos: []models.ProductsInfo{{ProductID: "a", Quantity: 3}},
+	}
+	require.NoError(t, repo.PutOrder(ctx, order))
+
+	legacy, err := repo.GetOrdersWithoutLineSnapshots(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, legacy, 1)
+	require.Len(t, legacy[0].ProductsInfos, 1)
+	assert.False(t, legacy[0].ProductsInfos[0].HasSnapshot())
+
+	line := &legacy[0].ProductsInfos[0]
+	line.Name = "Mug"
+	line.UnitPrice = money.New(100, "USD")
+	line.LineTotal = money.New(300, "USD")
+	require.NoError(t, repo.SaveLineSnapshots(ctx, []*models.ProductsInfo{line}))
+
+	legacy, err = repo.GetOrdersWithoutLineSnapshots(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, legacy)
+
+	orders, err := repo.GetOrdersForAccount(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	require.Len(t, orders[0].Products, 1)
+	assert.Equal(t, "Mug", orders[0].Products[0].Name)
+	assert.Equal(t, money.New(100, "USD"), orders[0].Products[0].Price)
+}
+
+// Test helper to store an order for the products in a status
+func putOrder(t *testing.T, repo internal.Repository, status models.OrderStatus, products ...*models.OrderedProduct) *models.Order {
+	order := &models.Order{AccountID: 1, TotalPrice: money.Zero("USD"), Status: status, Products: products}
+	require.NoError(t, repo.PutOrder(context.Background(), order))
+	return order
+}
+
+func TestOrderRepository_GetOrderLinesForProducts(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	paid := putOrder(t, repo, models.StatusPaid,
+		&models.OrderedProduct{ID: "a", Price: money.New(250, "USD"), Quantity: 2},
+		&models.OrderedProduct{ID: "other", Price: money.New(100, "USD"), Quantity: 1})
+	putOrder(t, repo, models.StatusPendingPayment, &models.OrderedProduct{ID: "a", Price: money.New(250, "USD"), Quantity: 5})
+	putOrder(t, repo, models.StatusCancelled, &models.OrderedProduct{ID: "b", Price: money.New(400, "USD"), Quantity: 1})
+	putOrder(t, repo, models.StatusRefunded, &models.OrderedProduct{ID: "a", Price: money.New(250, "USD"), Quantity: 1})
+	shipped := putOrder(t, repo, models.StatusShipped, &models.OrderedProduct{ID: "b", Price: money.New(400, "USD"), Quantity: 3})
+
+	t.Run("Only lines of paid orders", func(t *testing.T) {
+		lines, err := repo.GetOrderLinesForProducts(ctx, []string{"a", "b"}, 0, 10)
+
+		require.NoError(t, err)
+		require.Len(t, lines, 2)
+		orderIds := []uint{lines[0].OrderID, lines[1].OrderID}
+		assert.ElementsMatch(t, []uint{paid.ID, shipped.ID}, orderIds)
+		for _, line := range lines {
+			assert.Equal(t, "USD", line.UnitPrice.Currency)
+		}
+	})
+
+	t.Run("Pages in the query", func(t *testing.T) {
+		first, err := repo.GetOrderLinesForProducts(ctx, []string{"a", "b"}, 0, 1)
+		require.NoError(t, err)
+		second, err := repo.GetOrderLinesForProducts(ctx, []string{"a", "b"}, 1, 1)
+		require.NoError(t, err)
+
+		require.Len(t, first, 1)
+		require.Len(t, second, 1)
+		assert.NotEqual(t, first[0].OrderID, second[0].OrderID)
+	})
+}
+
+func TestOrderRepository_GetSalesForProducts(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	putOrder(t, repo, models.StatusPaid,
+		&models.OrderedProduct{ID: "a", Price: money.New(250, "USD"), Quantity: 2},
+		&models.OrderedProduct{ID: "b", Price: money.New(400, "USD"), Quantity: 1})
+	putOrder(t, repo, models.StatusDelivered, &models.OrderedProduct{ID: "a", Price: money.New(300, "USD"), Quantity: 1})
+	putOrder(t, repo, models.StatusPendingPayment, &models.OrderedProduct{ID: "a", Price: money.New(250, "USD"), Quantity: 10})
+	putOrder(t, repo, models.StatusCancelled, &models.OrderedProduct{ID: "b", Price: money.New(400, "USD"), Quantity: 4})
+	// Older versions stored only the product and quantity
+	require.NoError(t, repo.PutOrder(ctx, &models.Order{
+		AccountID:     1,
+		TotalPrice:    money.New(800, "USD"),
+		Status:        models.StatusPaid,
+		ProductsInfos: []models.ProductsInfo{{ProductID: "b", Quantity: 2}},
+	}))
+
+	summary, err := repo.GetSalesForProducts(ctx, []string{"a", "b"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.OrderCount)
+	require.Len(t, summary.Products, 3)
+	assert.Equal(t, models.ProductSales{ProductID: "a", Currency: "USD", UnitsSold: 3, Revenue: 800}, *summary.Products[0])
+	assert.Equal(t, models.ProductSales{ProductID: "b", UnitsSold: 2, UnpricedUnits: 2}, *summary.Products[1])
+	assert.Equal(t, models.ProductSales{ProductID: "b", Currency: "USD", UnitsSold: 1, Revenue: 400}, *summary.Products[2])
+}
+
+func TestOrderRepository_TransitionOrder(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+
+	order := &models.Order{
+		AccountID:  1,
+		TotalPrice: money.New(100, "USD"),
+		Status:     models.StatusPendingPayment,
+	}
+	require.NoError(t, repo.PutOrder(ctx, order))
+
+	err := repo.TransitionOrder(ctx, &models.StatusTransition{
+		OrderID: order.ID,
+		From:    models.StatusPendingPayment,
+		To:      models.StatusPaid,
+		Actor:   "payment",
+	})
+	require.NoError(t, err)
+
+	// A second writer that still sees the order as pending loses
+	err = repo.TransitionOrder(ctx, &models.StatusTransition{
+		OrderID: order.ID,
+		From:    models.StatusPendingPayment,
+		To:      models.StatusCancelled,
+		Actor:   "account:1",
+	})
+	assert.ErrorIs(t, err, internal.ErrStatusConflict)
+
+	stored, err := repo.GetOrder(ctx, uint64(order.ID))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPaid, stored.Status)
+	require.Len(t, stored.History, 1)
+	assert.Equal(t, models.StatusPaid, stored.History[0].To)
+
+	_, err = repo.GetOrder(ctx, 999)
+	assert.ErrorIs(t, err, internal.ErrOrderNotFound)
+}
+
+func TestOrderRepository_ListOrders(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	placed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, o := range []struct {
+		accountID uint64
+		total     money.Money
+		status    models.OrderStatus
+	}{
+		{1, money.New(500, "USD"), models.StatusPendingPayment},
+		{1, money.New(1500, "USD"), models.StatusPaid},
+		{2, money.New(2500, "USD"), models.StatusPaid},
+		{1, money.New(2500, "EUR"), models.StatusPaid},
+	} {
+		require.NoError(t, repo.PutOrder(ctx, &models.Order{
+			AccountID:  o.accountID,
+			TotalPrice: o.total,
+			Status:     o.status,
+			CreatedAt:  placed.AddDate(0, 0, i),
+			Products: []*models.OrderedProduct{
+				{ID: "a", Price: o.total, Quantity: 1},
+			},
+		}))
+	}
+
+	orders, err := repo.ListOrders(ctx, models.OrderFilter{AccountID: 1}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, orders, 3)
+	assert.Equal(t, []uint{4, 2, 1}, []uint{orders[0].ID, orders[1].ID, orders[2].ID})
+	require.Len(t, orders[0].Products, 1)
+
+	orders, err = repo.ListOrders(ctx, models.OrderFilter{AccountID: 1}, 2, 10)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, uint(1), orders[0].ID)
+
+	minTotal := money.New(1000, "USD")
+	orders, err = repo.ListOrders(ctx, models.OrderFilter{
+		Statuses: []models.OrderStatus{models.StatusPaid},
+		MinTotal: &minTotal,
+	}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, uint(3), orders[0].ID)
+	assert.Equal(t, uint(2), orders[1].ID)
+
+	orders, err = repo.ListOrders(ctx, models.OrderFilter{
+		CreatedAfter:  placed.AddDate(0, 0, 1),
+		CreatedBefore: placed.AddDate(0, 0, 3),
+	}, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, uint(3), orders[0].ID)
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Test helper to place an order with a 10% discount and deliver it
+func setupDeliveredOrder(t *testing.T, ctx context.Context, service internal.Service) *models.Order {
+	_, err := service.CreatePromotion(ctx, &models.Promotion{Code: "TENOFF", Kind: models.PromotionPercentage, PercentOff: 10, Active: true})
+	require.NoError(t, err)
+
+	order, err := service.PostOrder(ctx, 1, "EUR", []*models.OrderedProduct{
+		{ID: "book", Price: money.New(1999, "EUR"), Quantity: 1, TaxCategory: "reduced"},
+		{ID: "lamp", Price: money.New(2500, "EUR"), Quantity: 3},
+	}, "TENOFF", models.Address{Country: "DE"}, models.Shipping{})
+	require.NoError(t, err)
+
+	_, err = service.UpdateOrderStatus(ctx, uint64(order.ID), "paid", "payment", "")
+	require.NoError(t, err)
+	_, err = service.RecordShipment(ctx, uint64(order.ID), true, "")
+	require.NoError(t, err)
+	order, err = service.RecordDelivery(ctx, uint64(order.ID), "")
+	require.NoError(t, err)
+	return order
+}
+
+func TestOrderService_Returns(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Return is approved, received and refunded", func(t *testing.T) {
+		repo := setupTestRepository(t)
+		service := internal.NewOrderService(repo, setupProducer(t, 6), nil, setupTaxCalculator(t, false))
+		order := setupDeliveredOrder(t, ctx, service)
+
+		ret, err := service.RequestReturn(ctx, uint64(order.ID), 1, []*models.ReturnLine{
+			{ProductID: "lamp", Quantity: 1},
+			{ProductID: "lamp", Quantity: 1},
+		}, " broken ", true)
+		require.NoError(t, err)
+		assert.Equal(t, models.ReturnRequested, ret.Status)
+		assert.Equal(t, "broken", ret.Reason)
+		require.Len(t, ret.Lines, 1)
+		assert.Equal(t, 2, ret.Lines[0].Quantity)
+		// The lamps cost 75.00 less 7.49 of the 9.50 discount, plus 19% VAT of 67.51: 80.34
+		// for three, so two are refunded 53.56
+		assert.Equal(t, money.New(5356, "EUR"), ret.RefundAmount)
+
+		ret, err = service.DecideReturn(ctx, ret.ID, true, "account:9", "ok")
+		require.NoError(t, err)
+		assert.Equal(t, models.ReturnApproved, ret.Status)
+
+		ret, received, err := service.ReceiveReturn(ctx, ret.ID, "account:9")
+		require.NoError(t, err)
+		assert.True(t, received)
+		assert.True(t, ret.RefundToStoreCredit)
+
+		// Receiving again leaves the return as it is
+		_, received, err = service.ReceiveReturn(ctx, ret.ID, "account:9")
+		require.NoError(t, err)
+		assert.False(t, received)
+
+		_, err = service.RecordReturnRefund(ctx, ret.ID, "ref_1", "account:9")
+		require.NoError(t, err)
+
+		stored, err := repo.GetOrder(ctx, uint64(order.ID))
+		require.NoError(t, err)
+		require.Len(t, stored.Returns, 1)
+		assert.Equal(t, models.ReturnRefunded, stored.Returns[0].Status)
+		assert.Equal(t, "ref_1", stored.Returns[0].RefundID)
+		require.Len(t, stored.Returns[0].History, 4)
+		assert.Equal(t, models.ReturnReceived, stored.Returns[0].History[2].To)
+
+		// The last lamp is refunded the remaining 26.78
+		last, err := service.RequestReturn(ctx, uint64(order.ID), 1, []*models.ReturnLine{{ProductID: "lamp", Quantity: 1}}, "broken too", false)
+		require.NoError(t, err)
+		assert.Equal(t, money.New(2678, "EUR"), last.RefundAmount)
+
+		_, err = service.RequestReturn(ctx, uint64(order.ID), 1, []*models.ReturnLine{{ProductID: "lamp", Quantity: 1}}, "again", false)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Rejected returns free their units", func(t *testing.T) {
+		repo := setupTestRepository(t)
+		service := internal.NewOrderService(repo, setupProducer(t, 6), nil, nil)
+		order := setupDeliveredOrder(t, ctx, service)
+
+		ret, err := service.RequestReturn(ctx, uint64(order.ID), 1, []*models.ReturnLine{{ProductID: "book", Quantity: 1}}, "changed my mind", false)
+		require.NoError(t, err)
+		ret, err = service.DecideReturn(ctx, ret.ID, false, "account:9", "opened")
+		require.NoError(t, err)
+		assert.Equal(t, models.ReturnRejected, ret.Status)
+
+		_, _, err = service.ReceiveReturn(ctx, ret.ID, "account:9")
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = service.RequestReturn(ctx, uint64(order.ID), 1, []*models.ReturnLine{{ProductID: "book", Quantity: 1}}, "changed my mind", false)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		repo := setupTestRepository(t)
+		service := internal.NewOrderService(repo, setupProducer(t, 6), nil, nil)
+		order := setupDeliveredOrder(t, ctx, service)
+		lines := []*models.ReturnLine{{ProductID: "book", Quantity: 1}}
+
+		_, err := service.RequestReturn(ctx, uint64(order.ID), 2, lines, "not mine", false)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = service.RequestReturn(ctx, uint64(order.ID), 1, lines, " ", false)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = service.RequestReturn(ctx, uint64(order.ID), 1, []*models.ReturnLine{{ProductID: "chair", Quantity: 1}}, "broken", false)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = service.RequestReturn(ctx, uint64(order.ID), 1, []*models.ReturnLine{{ProductID: "book", Quantity: 2}}, "broken", false)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Undelivered order", func(t *testing.T) {
+		repo := setupTestRepository(t)
+		service := internal.NewOrderService(repo, setupProducer(t, 1), nil, nil)
+		order, err := service.PostOrder(ctx, 1, "EUR", []*models.OrderedProduct{
+			{ID: "book", Price: money.New(1999, "EUR"), Quantity: 1},
+		}, "", models.Address{}, models.Shipping{})
+		require.NoError(t, err)
+
+		_, err = service.RequestReturn(ctx, uint64(order.ID), 1, []*models.ReturnLine{{ProductID: "book", Quantity: 1}}, "broken", false)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
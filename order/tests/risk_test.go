@@ -0,0 +1,348 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/order/risk"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeHistory stands in for the record of past placements
+type fakeHistory struct {
+	accountAttempts int64
+	ipAttempts      int64
+	paidOrders      int64
+}
+
+func (f fakeHistory) CountAccountAttempts(ctx context.Context, accountId uint64, since time.Time) (int64, error) {
+	return f.accountAttempts, nil
+}
+
+func (f fakeHistory) CountIPAttempts(ctx context.Context, clientIP string, since time.Time) (int64, error) {
+	return f.ipAttempts, nil
+}
+
+func (f fakeHistory) CountPaidOrders(ctx context.Context, accountId uint64) (int64, error) {
+	return f.paidOrders, nil
+}
+
+// Test helper to hold orders delivered to another country than they are billed in for
+// review, and to decline them when they also come from a new account
+func mismatchScreening(history risk.History) *risk.Engine {
+	return risk.NewEngine(50, 80,
+		risk.CountryMismatch{Score: 50},
+		risk.NewAccount{History: history, Score: 30},
+	)
+}
+
+// Test helper to place two lamps billed in billedIn and delivered to Germany
+func screenedLampPlacement(billedIn string) internal.Placement {
+	placement := lampPlacement("https://shop.example/done")
+	placement.Address = models.Address{Country: "DE"}
+	placement.Origin = models.OrderOrigin{ClientIP: "203.0.113.7", BillingCountry: billedIn}
+	return placement
+}
+
+func TestRiskEngine_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	check := risk.Check{
+		OrderID:         7,
+		AccountID:       1,
+		Total:           money.New(150000, "EUR"),
+		ShippingCountry: "DE",
+		Origin:          models.OrderOrigin{ClientIP: "203.0.113.7", BillingCountry: "de"},
+		PlacedAt:        time.Now().UTC(),
+	}
+
+	t.Run("Orders without risk signals are allowed", func(t *testing.T) {
+		engine := risk.NewEngine(50, 80,
+			risk.AccountVelocity{History: fakeHistory{accountAttempts: 4}, Window: time.Hour, Limit: 5, Score: 40},
+			risk.IPVelocity{History: fakeHistory{ipAttempts: 9}, Window: time.Hour, Limit: 10, Score: 50},
+			risk.NewAccount{History: fakeHistory{paidOrders: 1}, Score: 20},
+			risk.CountryMismatch{Score: 25},
+		)
+
+		assessment, err := engine.Evaluate(ctx, check)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.DecisionAllow, assessment.Decision)
+		assert.Equal(t, models.ReviewNone, assessment.ReviewStatus)
+		assert.Zero(t, assessment.Score)
+		assert.Empty(t, assessment.Findings)
+		assert.Equal(t, uint(7), assessment.OrderID)
+		assert.Equal(t, "203.0.113.7", assessment.ClientIP)
+	})
+
+	t.Run("Scores add up to a review", func(t *testing.T) {
+		engine := risk.NewEngine(50, 80,
+			risk.OrderValue{Threshold: money.New(100000, "EUR"), Converter: money.NewConverter(nil), Score: 30},
+			risk.NewAccount{History: fakeHistory{}, Score: 20},
+		)
+
+		assessment, err := engine.Evaluate(ctx, check)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.DecisionReview, assessment.Decision)
+		assert.Equal(t, models.ReviewPending, assessment.ReviewStatus)
+		assert.Equal(t, 50, assessment.Score)
+		require.Len(t, assessment.Findings, 2)
+		assert.Equal(t, "order_value", assessment.Findings[0].Rule)
+		assert.Equal(t, "new_account", assessment.Findings[1].Rule)
+	})
+
+	t.Run("Velocity and mismatched countries block", func(t *testing.T) {
+		mismatched := check
+		mismatched.Origin.BillingCountry = "NG"
+		engine := risk.NewEngine(50, 80,
+			risk.AccountVelocity{History: fakeHistory{accountAttempts: 5}, Window: time.Hour, Limit: 5, Score: 40},
+			risk.IPVelocity{History: fakeHistory{ipAttempts: 10}, Window: time.Hour, Limit: 10, Score: 50},
+			risk.CountryMismatch{Score: 25},
+		)
+
+		assessment, err := engine.Evaluate(ctx, mismatched)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.DecisionBlock, assessment.Decision)
+		assert.Equal(t, models.ReviewNone, assessment.ReviewStatus)
+		assert.Equal(t, 115, assessment.Score)
+		require.Len(t, assessment.Findings, 3)
+		assert.Equal(t, "billed in NG but delivered to DE", assessment.Findings[2].Reason)
+	})
+
+	t.Run("Clients without an address are not matched by IP", func(t *testing.T) {
+		anonymous := check
+		anonymous.Origin.ClientIP = ""
+		engine := risk.NewEngine(50, 80,
+			risk.IPVelocity{History: fakeHistory{ipAttempts: 100}, Window: time.Hour, Limit: 10, Score: 50},
+		)
+
+		assessment, err := engine.Evaluate(ctx, anonymous)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.DecisionAllow, assessment.Decision)
+	})
+}
+
+func TestOrderRepository_RiskAssessments(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for _, assessment := range []*models.RiskAssessment{
+		{OrderID: 1, AccountID: 1, ClientIP: "203.0.113.7", Decision: models.DecisionAllow},
+		{OrderID: 2, AccountID: 1, ClientIP: "203.0.113.7", Decision: models.DecisionBlock},
+		{OrderID: 3, AccountID: 2, ClientIP: "203.0.113.7", Decision: models.DecisionReview, ReviewStatus: models.ReviewPending},
+		{OrderID: 4, AccountID: 1, ClientIP: "198.51.100.1", Decision: models.DecisionAllow, CreatedAt: now.Add(-2 * time.Hour)},
+	} {
+		require.NoError(t, repo.CreateRiskAssessment(ctx, assessment))
+	}
+
+	t.Run("Attempts are counted within the window, blocked ones included", func(t *testing.T) {
+		attempts, err := repo.CountAccountAttempts(ctx, 1, now.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), attempts)
+
+		attempts, err = repo.CountIPAttempts(ctx, "203.0.113.7", now.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), attempts)
+	})
+
+	t.Run("Paid orders are counted", func(t *testing.T) {
+		for _, orderStatus := range []models.OrderStatus{models.StatusPendingPayment, models.StatusPaid, models.StatusDelivered, models.StatusCancelled} {
+			require.NoError(t, repo.PutOrder(ctx, &models.Order{AccountID: 5, Status: orderStatus, TotalPrice: money.New(100, "EUR")}))
+		}
+
+		paid, err := repo.CountPaidOrders(ctx, 5)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), paid)
+	})
+
+	t.Run("Reviews are decided once", func(t *testing.T) {
+		pending, err := repo.ListRiskAssessments(ctx, models.ReviewPending, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, uint(3), pending[0].OrderID)
+
+		reviewedAt := now
+		decided := *pending[0]
+		decided.ReviewStatus = models.ReviewApproved
+		decided.ReviewedBy = "account:9"
+		decided.ReviewedAt = &reviewedAt
+		require.NoError(t, repo.DecideRiskReview(ctx, &decided))
+
+		decided.ReviewStatus = models.ReviewRejected
+		assert.ErrorIs(t, repo.DecideRiskReview(ctx, &decided), internal.ErrReviewConflict)
+
+		stored, err := repo.GetRiskAssessment(ctx, 3)
+		require.NoError(t, err)
+		assert.Equal(t, models.ReviewApproved, stored.ReviewStatus)
+		assert.Equal(t, "account:9", stored.ReviewedBy)
+	})
+}
+
+func TestOrderSaga_Screening(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Blocked orders are cancelled before checkout", func(t *testing.T) {
+		// The lamp, the order flagged and its cancellation
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 3), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		checkout := &fakeCheckout{}
+		saga := internal.NewOrderSaga(service, inventory, checkout, mismatchScreening(service))
+
+		_, _, err := saga.Place(ctx, screenedLampPlacement("NG"))
+
+		assert.ErrorIs(t, err, internal.ErrOrderDeclined)
+		assert.Empty(t, checkout.orderIds)
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		orders, err := service.GetOrdersForAccount(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, models.StatusCancelled, orders[0].Status)
+		assert.Equal(t, "order was declined by risk screening", orders[0].History[1].Reason)
+		assert.Empty(t, pendingSagas(t, ctx, service))
+
+		review, err := service.GetRiskReview(ctx, orders[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.DecisionBlock, review.Decision)
+		assert.Equal(t, 80, review.Score)
+	})
+
+	t.Run("Orders held for review wait for checkout", func(t *testing.T) {
+		// The lamp and the order flagged
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 2), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		checkout := &fakeCheckout{}
+		saga := internal.NewOrderSaga(service, inventory, checkout, mismatchScreening(fakeHistory{paidOrders: 3}))
+
+		order, checkoutUrl, err := saga.Place(ctx, screenedLampPlacement("NG"))
+
+		require.NoError(t, err)
+		assert.Empty(t, checkoutUrl)
+		assert.Empty(t, checkout.orderIds)
+		assert.Equal(t, models.ReviewPending, order.RiskReview)
+		assert.Equal(t, 3, inventory.Stock("lamp"))
+		sagas := pendingSagas(t, ctx, service)
+		require.Len(t, sagas, 1)
+		assert.Equal(t, models.SagaInReview, sagas[0].State)
+
+		stored, err := service.GetOrder(ctx, uint64(order.ID), 1)
+		require.NoError(t, err)
+		assert.Equal(t, models.ReviewPending, stored.RiskReview)
+
+		// Resuming leaves the order to its reviewer
+		require.NoError(t, saga.Resume(ctx))
+		assert.Len(t, pendingSagas(t, ctx, service), 1)
+	})
+
+	t.Run("Allowed orders go on to checkout", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 1), nil, nil)
+		checkout := &fakeCheckout{}
+		saga := internal.NewOrderSaga(service, &fakeInventory{stock: map[string]int{"lamp": 5}}, checkout, mismatchScreening(service))
+
+		order, checkoutUrl, err := saga.Place(ctx, screenedLampPlacement("DE"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://pay.example/ann@example.com", checkoutUrl)
+		assert.Equal(t, []int{int(order.ID)}, checkout.orderIds)
+		review, err := service.GetRiskReview(ctx, order.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.DecisionAllow, review.Decision)
+		assert.Equal(t, models.ReviewNone, review.ReviewStatus)
+	})
+}
+
+func TestOrderSaga_Review(t *testing.T) {
+	ctx := context.Background()
+
+	// placeHeld places an order that is held for review
+	placeHeld := func(t *testing.T, events int) (internal.Service, *internal.OrderSaga, *fakeInventory, *fakeCheckout, *models.Order) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, events), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		checkout := &fakeCheckout{}
+		saga := internal.NewOrderSaga(service, inventory, checkout, mismatchScreening(fakeHistory{paidOrders: 1}))
+		order, _, err := saga.Place(ctx, screenedLampPlacement("NG"))
+		require.NoError(t, err)
+		return service, saga, inventory, checkout, order
+	}
+
+	t.Run("Approved orders have their checkout opened", func(t *testing.T) {
+		// The lamp, the order flagged and approved
+		service, saga, _, checkout, order := placeHeld(t, 3)
+		review, err := service.GetRiskReview(ctx, order.ID)
+		require.NoError(t, err)
+
+		checkoutUrl, err := saga.Review(ctx, review, true, "account:9", "called the customer", "ann@example.com", "Ann")
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://pay.example/ann@example.com", checkoutUrl)
+		assert.Equal(t, []int{int(order.ID)}, checkout.orderIds)
+		sagas := pendingSagas(t, ctx, service)
+		require.Len(t, sagas, 1)
+		assert.Equal(t, models.SagaAwaitingPayment, sagas[0].State)
+
+		review, err = service.GetRiskReview(ctx, order.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ReviewApproved, review.ReviewStatus)
+		assert.Equal(t, "account:9", review.ReviewedBy)
+		assert.Equal(t, "called the customer", review.ReviewNote)
+		assert.NotNil(t, review.ReviewedAt)
+		assert.Equal(t, models.ReviewApproved, review.Order.RiskReview)
+
+		// Reviews are decided once
+		_, err = saga.Review(ctx, review, false, "account:9", "", "", "")
+		assert.ErrorIs(t, err, internal.ErrNotInReview)
+	})
+
+	t.Run("Rejected orders are cancelled and release their stock", func(t *testing.T) {
+		// The lamp, the order flagged and its cancellation
+		service, saga, inventory, checkout, order := placeHeld(t, 3)
+		review, err := service.GetRiskReview(ctx, order.ID)
+		require.NoError(t, err)
+
+		checkoutUrl, err := saga.Review(ctx, review, false, "account:9", "stolen card", "", "")
+
+		require.NoError(t, err)
+		assert.Empty(t, checkoutUrl)
+		assert.Empty(t, checkout.orderIds)
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		assert.Empty(t, pendingSagas(t, ctx, service))
+
+		stored, err := service.GetOrder(ctx, uint64(order.ID), 1)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusCancelled, stored.Status)
+		assert.Equal(t, models.ReviewRejected, stored.RiskReview)
+	})
+
+	t.Run("Held orders are listed for review and never expire", func(t *testing.T) {
+		service, _, inventory, checkout, order := placeHeld(t, 2)
+
+		page, err := service.ListRiskReviews(ctx, "", "", 0)
+		require.NoError(t, err)
+		require.Len(t, page.Reviews, 1)
+		assert.Equal(t, order.ID, page.Reviews[0].Order.ID)
+		assert.Equal(t, []models.RiskFinding{{Rule: "country_mismatch", Score: 50, Reason: "billed in NG but delivered to DE"}}, page.Reviews[0].Findings)
+
+		_, err = service.ListRiskReviews(ctx, "maybe", "", 0)
+		assert.ErrorIs(t, err, internal.ErrInvalidReviewStatus)
+
+		expired, err := internal.NewOrderExpirer(service, inventory, checkout, 0).ExpireOrders(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, expired)
+	})
+
+	t.Run("Unscreened orders cannot be reviewed", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), nil, nil, nil)
+
+		_, err := service.GetRiskReview(ctx, 42)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
@@ -0,0 +1,444 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	paymentpb "github.com/rasadov/EcommerceAPI/payment/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	productmodels "github.com/rasadov/EcommerceAPI/product/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeInventory stands in for the product service, keeping the stock of each product
+type fakeInventory struct {
+	mu       sync.Mutex
+	stock    map[string]int
+	releases int
+	// reserveErr is returned after the stock was reserved, as when the reply is lost
+	reserveErr error
+}
+
+func (f *fakeInventory) ReserveStock(ctx context.Context, items []productmodels.StockItem) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range items {
+		if f.stock[item.ProductID] < item.Quantity {
+			return status.Error(codes.FailedPrecondition, "insufficient stock")
+		}
+	}
+	for _, item := range items {
+		f.stock[item.ProductID] -= item.Quantity
+	}
+	return f.reserveErr
+}
+
+func (f *fakeInventory) ReleaseStock(ctx context.Context, items []productmodels.StockItem) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range items {
+		f.stock[item.ProductID] += item.Quantity
+	}
+	f.releases++
+	return nil
+}
+
+func (f *fakeInventory) Stock(productId string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stock[productId]
+}
+
+// fakeCheckout stands in for the payment service
+type fakeCheckout struct {
+	err      error
+	orderIds []int
+	expired  []uint64
+	// creditLeft is what applied credit leaves to pay, unless creditErr fails applying it
+	creditLeft money.Money
+	creditErr  error
+	captured   []uint64
+}
+
+func (f *fakeCheckout) CreateCheckoutSession(ctx context.Context, orderId, userId int, email, name, redirectUrl, currency string, products []*paymentpb.CartItem) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.orderIds = append(f.orderIds, orderId)
+	return "https://pay.example/" + email, nil
+}
+
+func (f *fakeCheckout) ExpireCheckoutSessions(ctx context.Context, orderId uint64) error {
+	f.expired = append(f.expired, orderId)
+	return nil
+}
+
+func (f *fakeCheckout) ApplyCredit(ctx context.Context, orderId, userId uint64, giftCardCode string, useStoreCredit bool) (money.Money, error) {
+	return f.creditLeft, f.creditErr
+}
+
+func (f *fakeCheckout) CaptureCredit(ctx context.Context, orderId uint64) error {
+	f.captured = append(f.captured, orderId)
+	return nil
+}
+
+// flakySagaService fails to record the saga moving on to failAt
+type flakySagaService struct {
+	internal.Service
+	failAt models.SagaState
+}
+
+func (f flakySagaService) AdvanceSaga(ctx context.Context, saga *models.PlacementSaga, from models.SagaState) error {
+	if saga.State == f.failAt {
+		return errors.New("connection lost")
+	}
+	return f.Service.AdvanceSaga(ctx, saga, from)
+}
+
+// Test helper to place two lamps for account 1
+func lampPlacement(redirectURL string) internal.Placement {
+	return internal.Placement{
+		AccountID:   1,
+		Email:       "ann@example.com",
+		Name:        "Ann",
+		Currency:    "EUR",
+		Products:    []*models.OrderedProduct{{ID: "lamp", Price: money.New(2500, "EUR"), Quantity: 2}},
+		RedirectURL: redirectURL,
+	}
+}
+
+func pendingSagas(t *testing.T, ctx context.Context, service internal.Service) []*models.PlacementSaga {
+	sagas, err := service.ListPendingSagas(ctx, 0, 10)
+	require.NoError(t, err)
+	return sagas
+}
+
+func TestOrderSaga_Place(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Stock is reserved, order placed and checkout opened", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 1), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		checkout := &fakeCheckout{}
+		saga := internal.NewOrderSaga(service, inventory, checkout, nil)
+
+		order, checkoutUrl, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusPendingPayment, order.Status)
+		assert.Equal(t, "https://pay.example/ann@example.com", checkoutUrl)
+		assert.Equal(t, []int{int(order.ID)}, checkout.orderIds)
+		assert.Equal(t, 3, inventory.Stock("lamp"))
+
+		sagas := pendingSagas(t, ctx, service)
+		require.Len(t, sagas, 1)
+		assert.Equal(t, models.SagaAwaitingPayment, sagas[0].State)
+		assert.Equal(t, order.ID, sagas[0].OrderID)
+		assert.Equal(t, checkoutUrl, sagas[0].CheckoutURL)
+	})
+
+	t.Run("No checkout is opened without a redirect URL", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 1), nil, nil)
+		checkout := &fakeCheckout{}
+		saga := internal.NewOrderSaga(service, &fakeInventory{stock: map[string]int{"lamp": 5}}, checkout, nil)
+
+		_, checkoutUrl, err := saga.Place(ctx, lampPlacement(""))
+
+		require.NoError(t, err)
+		assert.Empty(t, checkoutUrl)
+		assert.Empty(t, checkout.orderIds)
+	})
+
+	t.Run("Refused reservation leaves stock alone", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 0), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 1}}
+		saga := internal.NewOrderSaga(service, inventory, &fakeCheckout{}, nil)
+
+		_, _, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Equal(t, 1, inventory.Stock("lamp"))
+		assert.Zero(t, inventory.releases)
+		assert.Empty(t, pendingSagas(t, ctx, service))
+	})
+
+	t.Run("Reservation with an unknown outcome is released", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 0), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}, reserveErr: status.Error(codes.Unavailable, "connection reset")}
+		saga := internal.NewOrderSaga(service, inventory, &fakeCheckout{}, nil)
+
+		_, _, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		assert.Empty(t, pendingSagas(t, ctx, service))
+	})
+
+	t.Run("Order that cannot be placed releases its stock", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 0), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		saga := internal.NewOrderSaga(service, inventory, &fakeCheckout{}, nil)
+		placement := lampPlacement("https://shop.example/done")
+		placement.CouponCode = "UNKNOWN"
+
+		_, _, err := saga.Place(ctx, placement)
+
+		assert.Error(t, err)
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		orders, err := service.GetOrdersForAccount(ctx, 1)
+		require.NoError(t, err)
+		assert.Empty(t, orders)
+	})
+
+	t.Run("Failed checkout cancels the order and releases its stock", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 2), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		checkout := &fakeCheckout{err: errors.New("provider down")}
+		saga := internal.NewOrderSaga(service, inventory, checkout, nil)
+
+		_, _, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+
+		assert.Error(t, err)
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		orders, err := service.GetOrdersForAccount(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, models.StatusCancelled, orders[0].Status)
+		require.Len(t, orders[0].History, 2)
+		assert.Equal(t, "checkout session could not be created", orders[0].History[1].Reason)
+		assert.Equal(t, []uint64{uint64(orders[0].ID)}, checkout.expired)
+		assert.Equal(t, 1, inventory.releases)
+		assert.Empty(t, pendingSagas(t, ctx, service))
+	})
+
+	t.Run("Unrecorded reservation is released", func(t *testing.T) {
+		service := flakySagaService{internal.NewOrderService(setupTestRepository(t), setupProducer(t, 0), nil, nil), models.SagaStockReserved}
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		saga := internal.NewOrderSaga(service, inventory, &fakeCheckout{}, nil)
+
+		_, _, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+
+		assert.Error(t, err)
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		assert.Empty(t, pendingSagas(t, ctx, service))
+	})
+
+	t.Run("Unrecorded order is cancelled and its stock released", func(t *testing.T) {
+		service := flakySagaService{internal.NewOrderService(setupTestRepository(t), setupProducer(t, 2), nil, nil), models.SagaOrderPlaced}
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		saga := internal.NewOrderSaga(service, inventory, &fakeCheckout{}, nil)
+
+		_, _, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+
+		assert.Error(t, err)
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		orders, err := service.GetOrdersForAccount(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, models.StatusCancelled, orders[0].Status)
+		assert.Empty(t, pendingSagas(t, ctx, service))
+	})
+
+	t.Run("Order whose review hold is unrecorded is cancelled and its stock released", func(t *testing.T) {
+		// The lamp, the order flagged and its cancellation
+		service := flakySagaService{internal.NewOrderService(setupTestRepository(t), setupProducer(t, 3), nil, nil), models.SagaInReview}
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		checkout := &fakeCheckout{}
+		saga := internal.NewOrderSaga(service, inventory, checkout, mismatchScreening(fakeHistory{paidOrders: 1}))
+
+		_, _, err := saga.Place(ctx, screenedLampPlacement("NG"))
+
+		assert.Error(t, err)
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		assert.Equal(t, 1, inventory.releases)
+		orders, err := service.GetOrdersForAccount(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, models.StatusCancelled, orders[0].Status)
+		assert.Equal(t, "placement could not be recorded", orders[0].History[1].Reason)
+		assert.Empty(t, pendingSagas(t, ctx, service))
+	})
+
+	t.Run("Order whose checkout is unrecorded is cancelled once", func(t *testing.T) {
+		service := flakySagaService{internal.NewOrderService(setupTestRepository(t), setupProducer(t, 2), nil, nil), models.SagaAwaitingPayment}
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		checkout := &fakeCheckout{}
+		saga := internal.NewOrderSaga(service, inventory, checkout, nil)
+
+		_, _, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+
+		assert.Error(t, err)
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		assert.Equal(t, 1, inventory.releases)
+		orders, err := service.GetOrdersForAccount(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, models.StatusCancelled, orders[0].Status)
+		require.Len(t, orders[0].History, 2)
+		assert.Equal(t, "placement could not be recorded", orders[0].History[1].Reason)
+		assert.Equal(t, []int{int(orders[0].ID)}, checkout.orderIds)
+		assert.Equal(t, []uint64{uint64(orders[0].ID)}, checkout.expired)
+		assert.Empty(t, pendingSagas(t, ctx, service))
+	})
+
+	t.Run("Order its credit paid is kept though unrecorded", func(t *testing.T) {
+		// The lamp and the payment
+		service := flakySagaService{internal.NewOrderService(setupTestRepository(t), setupProducer(t, 2), nil, nil), models.SagaAwaitingPayment}
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		checkout := &fakeCheckout{creditLeft: money.Zero("EUR")}
+		saga := internal.NewOrderSaga(service, inventory, checkout, nil)
+		placement := lampPlacement("https://shop.example/done")
+		placement.Credit = models.OrderCredit{GiftCardCode: "GIFT-CARD-CODE"}
+
+		order, _, err := saga.Place(ctx, placement)
+
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{uint64(order.ID)}, checkout.captured)
+		assert.Empty(t, checkout.expired)
+		assert.Equal(t, 3, inventory.Stock("lamp"))
+
+		// Capturing the credit pays the order, after which resuming completes the placement
+		_, err = service.UpdateOrderStatus(ctx, uint64(order.ID), "paid", "payment", "")
+		require.NoError(t, err)
+		require.NoError(t, saga.Resume(ctx))
+		assert.Empty(t, pendingSagas(t, ctx, service))
+	})
+
+	t.Run("Order its credit covers is paid without a checkout", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 1), nil, nil)
+		checkout := &fakeCheckout{creditLeft: money.Zero("EUR")}
+		saga := internal.NewOrderSaga(service, &fakeInventory{stock: map[string]int{"lamp": 5}}, checkout, nil)
+		placement := lampPlacement("https://shop.example/done")
+		placement.Credit = models.OrderCredit{GiftCardCode: "GIFT-CARD-CODE"}
+
+		order, checkoutUrl, err := saga.Place(ctx, placement)
+
+		require.NoError(t, err)
+		assert.Empty(t, checkoutUrl)
+		assert.Empty(t, checkout.orderIds)
+		assert.Equal(t, []uint64{uint64(order.ID)}, checkout.captured)
+		sagas := pendingSagas(t, ctx, service)
+		require.Len(t, sagas, 1)
+		assert.True(t, sagas[0].PaidWithCredit)
+	})
+
+	t.Run("Order its credit partly covers opens a checkout", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 1), nil, nil)
+		checkout := &fakeCheckout{creditLeft: money.New(1500, "EUR")}
+		saga := internal.NewOrderSaga(service, &fakeInventory{stock: map[string]int{"lamp": 5}}, checkout, nil)
+		placement := lampPlacement("https://shop.example/done")
+		placement.Credit = models.OrderCredit{UseStoreCredit: true}
+
+		order, checkoutUrl, err := saga.Place(ctx, placement)
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, checkoutUrl)
+		assert.Equal(t, []int{int(order.ID)}, checkout.orderIds)
+		assert.Empty(t, checkout.captured)
+	})
+
+	t.Run("Credit that cannot be applied cancels the order", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 2), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		checkout := &fakeCheckout{creditErr: status.Error(codes.FailedPrecondition, "gift card has expired")}
+		saga := internal.NewOrderSaga(service, inventory, checkout, nil)
+		placement := lampPlacement("https://shop.example/done")
+		placement.Credit = models.OrderCredit{GiftCardCode: "GIFT-CARD-CODE"}
+
+		_, _, err := saga.Place(ctx, placement)
+
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		orders, err := service.GetOrdersForAccount(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, models.StatusCancelled, orders[0].Status)
+		assert.Equal(t, "credit could not be applied", orders[0].History[1].Reason)
+		assert.Equal(t, []uint64{uint64(orders[0].ID)}, checkout.expired)
+		assert.Empty(t, checkout.orderIds)
+	})
+}
+
+func TestOrderSaga_Resume(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Paid orders complete their saga", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 2), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		saga := internal.NewOrderSaga(service, inventory, &fakeCheckout{}, nil)
+		order, _, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+		require.NoError(t, err)
+		_, err = service.UpdateOrderStatus(ctx, uint64(order.ID), "paid", "payment", "")
+		require.NoError(t, err)
+
+		require.NoError(t, saga.Resume(ctx))
+
+		assert.Empty(t, pendingSagas(t, ctx, service))
+		assert.Equal(t, 3, inventory.Stock("lamp"))
+	})
+
+	t.Run("Orders awaiting payment are left alone", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 1), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		saga := internal.NewOrderSaga(service, inventory, &fakeCheckout{}, nil)
+		order, _, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+		require.NoError(t, err)
+
+		require.NoError(t, saga.Resume(ctx))
+
+		order, err = service.GetOrder(ctx, uint64(order.ID), 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusPendingPayment, order.Status)
+		assert.Len(t, pendingSagas(t, ctx, service), 1)
+	})
+
+	t.Run("Orders cancelled meanwhile do not release stock twice", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 2), nil, nil)
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 5}}
+		saga := internal.NewOrderSaga(service, inventory, &fakeCheckout{}, nil)
+		order, _, err := saga.Place(ctx, lampPlacement("https://shop.example/done"))
+		require.NoError(t, err)
+		_, err = service.CancelOrder(ctx, uint64(order.ID), 1, "changed my mind")
+		require.NoError(t, err)
+
+		require.NoError(t, saga.Resume(ctx))
+
+		assert.Zero(t, inventory.releases)
+		assert.Empty(t, pendingSagas(t, ctx, service))
+	})
+
+	t.Run("Placements interrupted by a restart are undone", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 0), nil, nil)
+		// Two lamps were reserved before the service went down
+		inventory := &fakeInventory{stock: map[string]int{"lamp": 3}}
+		saga := internal.NewOrderSaga(service, inventory, &fakeCheckout{}, nil)
+		interrupted := &models.PlacementSaga{
+			AccountID: 1,
+			State:     models.SagaStockReserved,
+			Items:     []models.SagaItem{{ProductID: "lamp", Quantity: 2}},
+			UpdatedAt: time.Now().UTC().Add(-5 * time.Minute),
+		}
+		require.NoError(t, service.CreateSaga(ctx, interrupted))
+		inFlight := &models.PlacementSaga{
+			AccountID: 2,
+			State:     models.SagaStarted,
+			Items:     []models.SagaItem{{ProductID: "lamp", Quantity: 1}},
+		}
+		require.NoError(t, service.CreateSaga(ctx, inFlight))
+
+		require.NoError(t, saga.Resume(ctx))
+
+		assert.Equal(t, 5, inventory.Stock("lamp"))
+		sagas := pendingSagas(t, ctx, service)
+		require.Len(t, sagas, 1)
+		assert.Equal(t, inFlight.ID, sagas[0].ID)
+	})
+}
@@ -0,0 +1,719 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama/mocks"
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MockRepository implements the Repository interface for testing
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Close() {
+
+}
+
+func (m *MockRepository) PutOrder(ctx context.Context, order *models.Order) error {
+	args := m.Called(ctx, order)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetOrdersForAccount(ctx context.Context, accountId uint64) ([]*models.Order, error) {
+	args := m.Called(ctx, accountId)
+	return args.Get(0).([]*models.Order), args.Error(1)
+}
+
+func (m *MockRepository) GetOrderLinesForProducts(ctx context.Context, productIds []string, skip, take uint64) ([]*models.OrderLine, error) {
+	args := m.Called(ctx, productIds, skip, take)
+	return args.Get(0).([]*models.OrderLine), args.Error(1)
+}
+
+func (m *MockRepository) GetSalesForProducts(ctx context.Context, productIds []string) (*models.SalesSummary, error) {
+	args := m.Called(ctx, productIds)
+	return args.Get(0).(*models.SalesSummary), args.Error(1)
+}
+
+func (m *MockRepository) GetOrder(ctx context.Context, orderId uint64) (*models.Order, error) {
+	args := m.Called(ctx, orderId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (m *MockRepository) ListOrders(ctx context.Context, filter models.OrderFilter, afterID uint, limit int) ([]*models.Order, error) {
+	args := m.Called(ctx, filter, afterID, limit)
+	return args.Get(0).([]*models.Order), args.Error(1)
+}
+
+func (m *MockRepository) ExportOrders(ctx context.Context, from, to time.Time, fn func(*models.Order) error) error {
+	args := m.Called(ctx, from, to, fn)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetOrdersWithoutLineSnapshots(ctx context.Context, limit int) ([]*models.Order, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]*models.Order), args.Error(1)
+}
+
+func (m *MockRepository) SaveLineSnapshots(ctx context.Context, lines []*models.ProductsInfo) error {
+	args := m.Called(ctx, lines)
+	return args.Error(0)
+}
+
+func (m *MockRepository) TransitionOrder(ctx context.Context, transition *models.StatusTransition) error {
+	args := m.Called(ctx, transition)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	args := m.Called(ctx, promotion)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	args := m.Called(ctx, promotion)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetPromotion(ctx context.Context, promotionId uint) (*models.Promotion, error) {
+	args := m.Called(ctx, promotionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Promotion), args.Error(1)
+}
+
+func (m *MockRepository) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Promotion), args.Error(1)
+}
+
+func (m *MockRepository) ListPromotions(ctx context.Context, includeInactive bool) ([]*models.Promotion, error) {
+	args := m.Called(ctx, includeInactive)
+	return args.Get(0).([]*models.Promotion), args.Error(1)
+}
+
+func (m *MockRepository) CountRedemptions(ctx context.Context, promotionId uint, accountId uint64) (int64, error) {
+	args := m.Called(ctx, promotionId, accountId)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) CreateReturn(ctx context.Context, ret *models.Return) error {
+	args := m.Called(ctx, ret)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetReturn(ctx context.Context, returnId uint) (*models.Return, error) {
+	args := m.Called(ctx, returnId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Return), args.Error(1)
+}
+
+func (m *MockRepository) TransitionReturn(ctx context.Context, transition *models.ReturnTransition, refundId string) error {
+	args := m.Called(ctx, transition, refundId)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ExpireOrders(ctx context.Context, placedBefore time.Time, limit int, actor, reason string) ([]*models.Order, error) {
+	args := m.Called(ctx, placedBefore, limit, actor, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Order), args.Error(1)
+}
+
+func (m *MockRepository) CreateSaga(ctx context.Context, saga *models.PlacementSaga) error {
+	args := m.Called(ctx, saga)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AdvanceSaga(ctx context.Context, saga *models.PlacementSaga, from models.SagaState) error {
+	args := m.Called(ctx, saga, from)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListPendingSagas(ctx context.Context, afterID uint, limit int) ([]*models.PlacementSaga, error) {
+	args := m.Called(ctx, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.PlacementSaga), args.Error(1)
+}
+
+func (m *MockRepository) CreateInvoice(ctx context.Context, invoice *models.Invoice) error {
+	args := m.Called(ctx, invoice)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetInvoiceForOrder(ctx context.Context, orderId uint64) (*models.Invoice, error) {
+	args := m.Called(ctx, orderId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Invoice), args.Error(1)
+}
+
+func (m *MockRepository) GetSubOrder(ctx context.Context, subOrderId uint) (*models.SubOrder, error) {
+	args := m.Called(ctx, subOrderId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SubOrder), args.Error(1)
+}
+
+func (m *MockRepository) TransitionSubOrder(ctx context.Context, subOrder *models.SubOrder, from models.OrderStatus) error {
+	args := m.Called(ctx, subOrder, from)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListSellerOrders(ctx context.Context, sellerId uint64, statuses []models.OrderStatus, afterID uint, limit int) ([]*models.SellerOrder, error) {
+	args := m.Called(ctx, sellerId, statuses, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SellerOrder), args.Error(1)
+}
+
+func (m *MockRepository) CreateRecurringOrder(ctx context.Context, recurring *models.RecurringOrder) error {
+	args := m.Called(ctx, recurring)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetRecurringOrder(ctx context.Context, recurringOrderId uint) (*models.RecurringOrder, error) {
+	args := m.Called(ctx, recurringOrderId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RecurringOrder), args.Error(1)
+}
+
+func (m *MockRepository) ListRecurringOrders(ctx context.Context, accountId uint64) ([]*models.RecurringOrder, error) {
+	args := m.Called(ctx, accountId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.RecurringOrder), args.Error(1)
+}
+
+func (m *MockRepository) UpdateRecurringOrder(ctx context.Context, recurring *models.RecurringOrder, from models.ScheduleStatus) error {
+	args := m.Called(ctx, recurring, from)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ClaimDueRecurringOrders(ctx context.Context, now time.Time, limit int) ([]*models.RecurringOrder, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.RecurringOrder), args.Error(1)
+}
+
+func (m *MockRepository) RecordRecurringRun(ctx context.Context, recurring *models.RecurringOrder) error {
+	args := m.Called(ctx, recurring)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetSagaForOrder(ctx context.Context, orderId uint) (*models.PlacementSaga, error) {
+	args := m.Called(ctx, orderId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PlacementSaga), args.Error(1)
+}
+
+func (m *MockRepository) CreateRiskAssessment(ctx context.Context, assessment *models.RiskAssessment) error {
+	args := m.Called(ctx, assessment)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetRiskAssessment(ctx context.Context, orderId uint) (*models.RiskAssessment, error) {
+	args := m.Called(ctx, orderId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RiskAssessment), args.Error(1)
+}
+
+func (m *MockRepository) ListRiskAssessments(ctx context.Context, reviewStatus models.ReviewStatus, afterID uint, limit int) ([]*models.RiskAssessment, error) {
+	args := m.Called(ctx, reviewStatus, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.RiskAssessment), args.Error(1)
+}
+
+func (m *MockRepository) DecideRiskReview(ctx context.Context, assessment *models.RiskAssessment) error {
+	args := m.Called(ctx, assessment)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CountAccountAttempts(ctx context.Context, accountId uint64, since time.Time) (int64, error) {
+	args := m.Called(ctx, accountId, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) CountIPAttempts(ctx context.Context, clientIP string, since time.Time) (int64, error) {
+	args := m.Called(ctx, clientIP, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) CountPaidOrders(ctx context.Context, accountId uint64) (int64, error) {
+	args := m.Called(ctx, accountId)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// Test helper to create a mock producer that accepts count recommender events
+func setupProducer(t *testing.T, count int) *mocks.AsyncProducer {
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	producer := mocks.NewAsyncProducer(t, config)
+	for i := 0; i < count; i++ {
+		producer.ExpectInputAndSucceed()
+	}
+	t.Cleanup(func() {
+		for i := 0; i < count; i++ {
+			<-producer.Successes()
+		}
+		require.NoError(t, producer.Close())
+	})
+	return producer
+}
+
+func TestOrderService_PostOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Total reconciles to the cent", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 3), nil, nil)
+
+		// As floats, 3 * 0.1 + 19.99 * 3 + 0.2 is 60.470000000000006
+		products := []*models.OrderedProduct{
+			{ID: "a", Price: money.FromMajor(0.1, "USD"), Quantity: 3},
+			{ID: "b", Price: money.FromMajor(19.99, "USD"), Quantity: 3},
+			{ID: "c", Price: money.FromMajor(0.2, "USD"), Quantity: 1},
+		}
+		mockRepo.On("PutOrder", ctx, mock.AnythingOfType("*models.Order")).Return(nil).Once()
+
+		order, err := service.PostOrder(ctx, 1, "USD", products, "", models.Address{}, models.Shipping{})
+
+		require.NoError(t, err)
+		assert.Equal(t, money.New(6047, "USD"), order.TotalPrice)
+		assert.Equal(t, "60.47 USD", order.TotalPrice.String())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Zero decimal currency", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 1), nil, nil)
+
+		products := []*models.OrderedProduct{
+			{ID: "a", Price: money.New(1500, "JPY"), Quantity: 2},
+		}
+		mockRepo.On("PutOrder", ctx, mock.AnythingOfType("*models.Order")).Return(nil).Once()
+
+		order, err := service.PostOrder(ctx, 1, "JPY", products, "", models.Address{}, models.Shipping{})
+
+		require.NoError(t, err)
+		assert.Equal(t, money.New(3000, "JPY"), order.TotalPrice)
+	})
+
+	t.Run("Lines in another currency are rejected", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		products := []*models.OrderedProduct{
+			{ID: "a", Price: money.New(100, "USD"), Quantity: 1},
+			{ID: "b", Price: money.New(100, "EUR"), Quantity: 1},
+		}
+
+		_, err := service.PostOrder(ctx, 1, "USD", products, "", models.Address{}, models.Shipping{})
+
+		assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+		mockRepo.AssertNotCalled(t, "PutOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Shipping is charged on top", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 1), nil, nil)
+
+		products := []*models.OrderedProduct{
+			{ID: "a", Price: money.New(2000, "EUR"), Quantity: 1},
+		}
+		address := models.Address{RecipientName: " Ada ", Line1: "Hauptstr. 1", City: "Berlin", Country: "de"}
+		mockRepo.On("PutOrder", ctx, mock.AnythingOfType("*models.Order")).Return(nil).Once()
+
+		order, err := service.PostOrder(ctx, 1, "EUR", products, "", address, models.Shipping{Method: "standard", Cost: money.New(499, "EUR")})
+
+		require.NoError(t, err)
+		assert.Equal(t, money.New(2499, "EUR"), order.TotalPrice)
+		assert.Equal(t, "DE", order.ShippingAddress.Country)
+		assert.Equal(t, "Ada", order.ShippingAddress.RecipientName)
+		subtotal, err := order.Subtotal()
+		require.NoError(t, err)
+		assert.Equal(t, money.New(2000, "EUR"), subtotal)
+	})
+
+	t.Run("Shipped orders need a full address", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		products := []*models.OrderedProduct{
+			{ID: "a", Price: money.New(2000, "EUR"), Quantity: 1},
+		}
+
+		_, err := service.PostOrder(ctx, 1, "EUR", products, "", models.Address{Country: "DE"}, models.Shipping{Method: "standard", Cost: money.New(499, "EUR")})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		mockRepo.AssertNotCalled(t, "PutOrder", mock.Anything, mock.Anything)
+	})
+}
+
+func TestOrderService_UpdateOrderStatus(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Allowed transition is recorded", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 1), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, AccountID: 2, Status: models.StatusPendingPayment}, nil).Once()
+		mockRepo.On("TransitionOrder", ctx, mock.MatchedBy(func(transition *models.StatusTransition) bool {
+			return transition.OrderID == 1 &&
+				transition.From == models.StatusPendingPayment &&
+				transition.To == models.StatusPaid &&
+				transition.Actor == "payment"
+		})).Return(nil).Once()
+
+		order, err := service.UpdateOrderStatus(ctx, 1, "paid", "payment", "payment succeeded")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusPaid, order.Status)
+		require.Len(t, order.History, 1)
+		assert.Equal(t, "payment succeeded", order.History[0].Reason)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown status", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		_, err := service.UpdateOrderStatus(ctx, 1, "Success", "payment", "")
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		mockRepo.AssertNotCalled(t, "GetOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Missing actor", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		_, err := service.UpdateOrderStatus(ctx, 1, "paid", "", "")
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Illegal transition", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, Status: models.StatusPendingPayment}, nil).Once()
+
+		_, err := service.UpdateOrderStatus(ctx, 1, "shipped", "seller:3", "")
+
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		mockRepo.AssertNotCalled(t, "TransitionOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Repeated status is a no-op", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, Status: models.StatusPaid}, nil).Once()
+
+		order, err := service.UpdateOrderStatus(ctx, 1, "paid", "payment", "")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusPaid, order.Status)
+		mockRepo.AssertNotCalled(t, "TransitionOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Missing order", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(9)).Return(nil, internal.ErrOrderNotFound).Once()
+
+		_, err := service.UpdateOrderStatus(ctx, 9, "paid", "payment", "")
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestOrderService_CancelOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Owner cancels a pending order", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 1), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, AccountID: 2, Status: models.StatusPendingPayment}, nil).Once()
+		mockRepo.On("TransitionOrder", ctx, mock.MatchedBy(func(transition *models.StatusTransition) bool {
+			return transition.To == models.StatusCancelled && transition.Actor == "account:2"
+		})).Return(nil).Once()
+
+		order, err := service.CancelOrder(ctx, 1, 2, "changed my mind")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusCancelled, order.Status)
+		assert.False(t, order.WasPaid())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Paid order reports its payment", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 1), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).Return(&models.Order{
+			ID:        1,
+			AccountID: 2,
+			Status:    models.StatusPaid,
+			History: []*models.StatusTransition{
+				{To: models.StatusPendingPayment},
+				{From: models.StatusPendingPayment, To: models.StatusPaid},
+			},
+		}, nil).Once()
+		mockRepo.On("TransitionOrder", ctx, mock.AnythingOfType("*models.StatusTransition")).Return(nil).Once()
+
+		order, err := service.CancelOrder(ctx, 1, 0, "out of stock")
+
+		require.NoError(t, err)
+		assert.True(t, order.WasPaid())
+		assert.Equal(t, "system", order.History[len(order.History)-1].Actor)
+	})
+
+	t.Run("Order paid before its history was recorded reports its payment", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 1), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, AccountID: 2, Status: models.StatusFulfilling}, nil).Once()
+		mockRepo.On("TransitionOrder", ctx, mock.AnythingOfType("*models.StatusTransition")).Return(nil).Once()
+
+		order, err := service.CancelOrder(ctx, 1, 2, "changed my mind")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusCancelled, order.Status)
+		assert.True(t, order.WasPaid())
+	})
+
+	t.Run("Another account's order", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, AccountID: 2, Status: models.StatusPendingPayment}, nil).Once()
+
+		_, err := service.CancelOrder(ctx, 1, 3, "")
+
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		mockRepo.AssertNotCalled(t, "TransitionOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Shipped order", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, AccountID: 2, Status: models.StatusShipped}, nil).Once()
+
+		_, err := service.CancelOrder(ctx, 1, 2, "")
+
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestOrderService_RecordShipment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Partial shipment starts fulfilment", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 1), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, AccountID: 2, Status: models.StatusPaid}, nil).Once()
+		mockRepo.On("TransitionOrder", ctx, mock.MatchedBy(func(transition *models.StatusTransition) bool {
+			return transition.To == models.StatusFulfilling && transition.Actor == "shipping"
+		})).Return(nil).Once()
+
+		order, err := service.RecordShipment(ctx, 1, false, "shipment 1")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusFulfilling, order.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Complete shipment of a paid order", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 2), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, AccountID: 2, Status: models.StatusPaid}, nil).Once()
+		mockRepo.On("TransitionOrder", ctx, mock.AnythingOfType("*models.StatusTransition")).Return(nil).Twice()
+
+		order, err := service.RecordShipment(ctx, 1, true, "shipment 1")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusShipped, order.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Redelivered event is ignored", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, AccountID: 2, Status: models.StatusShipped}, nil).Once()
+
+		order, err := service.RecordShipment(ctx, 1, true, "shipment 1")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusShipped, order.Status)
+		mockRepo.AssertNotCalled(t, "TransitionOrder", mock.Anything, mock.Anything)
+	})
+}
+
+func TestOrderService_RecordDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Shipped order is delivered", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 1), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, AccountID: 2, Status: models.StatusShipped}, nil).Once()
+		mockRepo.On("TransitionOrder", ctx, mock.MatchedBy(func(transition *models.StatusTransition) bool {
+			return transition.To == models.StatusDelivered
+		})).Return(nil).Once()
+
+		order, err := service.RecordDelivery(ctx, 1, "shipment 1")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusDelivered, order.Status)
+	})
+
+	t.Run("Order still being fulfilled", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+
+		mockRepo.On("GetOrder", ctx, uint64(1)).
+			Return(&models.Order{ID: 1, AccountID: 2, Status: models.StatusFulfilling}, nil).Once()
+
+		order, err := service.RecordDelivery(ctx, 1, "shipment 1")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusFulfilling, order.Status)
+		mockRepo.AssertNotCalled(t, "TransitionOrder", mock.Anything, mock.Anything)
+	})
+}
+
+func TestOrderService_GetOrder(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+	mockRepo.On("GetOrder", ctx, uint64(5)).Return(&models.Order{ID: 5, AccountID: 1}, nil)
+
+	order, err := service.GetOrder(ctx, 5, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint(5), order.ID)
+
+	// Admins look orders up without an account
+	_, err = service.GetOrder(ctx, 5, 0)
+	require.NoError(t, err)
+
+	_, err = service.GetOrder(ctx, 5, 2)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestOrderService_ListOrders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Pages with a cursor", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+		filter := models.OrderFilter{AccountID: 1}
+		mockRepo.On("ListOrders", ctx, filter, uint(0), 3).
+			Return([]*models.Order{{ID: 9}, {ID: 7}, {ID: 4}}, nil).Once()
+
+		page, err := service.ListOrders(ctx, filter, "", 2)
+
+		require.NoError(t, err)
+		require.Len(t, page.Orders, 2)
+		assert.True(t, page.HasNextPage)
+		assert.Equal(t, models.EncodeCursor(7), page.EndCursor)
+
+		mockRepo.On("ListOrders", ctx, filter, uint(7), 3).
+			Return([]*models.Order{{ID: 4}}, nil).Once()
+
+		page, err = service.ListOrders(ctx, filter, page.EndCursor, 2)
+
+		require.NoError(t, err)
+		require.Len(t, page.Orders, 1)
+		assert.False(t, page.HasNextPage)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Page size is capped", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+		mockRepo.On("ListOrders", ctx, models.OrderFilter{}, uint(0), internal.MaxPageSize+1).
+			Return([]*models.Order{}, nil).Once()
+
+		page, err := service.ListOrders(ctx, models.OrderFilter{}, "", 1000)
+
+		require.NoError(t, err)
+		assert.Empty(t, page.EndCursor)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid filters are rejected", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := internal.NewOrderService(mockRepo, setupProducer(t, 0), nil, nil)
+		usd, eur := money.New(100, "USD"), money.New(100, "EUR")
+		now := time.Now()
+
+		_, err := service.ListOrders(ctx, models.OrderFilter{}, "not a cursor", 0)
+		assert.ErrorIs(t, err, internal.ErrInvalidCursor)
+
+		_, err = service.ListOrders(ctx, models.OrderFilter{Statuses: []models.OrderStatus{"lost"}}, "", 0)
+		assert.ErrorIs(t, err, internal.ErrInvalidOrderStatus)
+
+		_, err = service.ListOrders(ctx, models.OrderFilter{CreatedAfter: now, CreatedBefore: now.Add(-time.Hour)}, "", 0)
+		assert.ErrorIs(t, err, internal.ErrInvalidDateRange)
+
+		_, err = service.ListOrders(ctx, models.OrderFilter{MinTotal: &usd, MaxTotal: &eur}, "", 0)
+		assert.ErrorIs(t, err, internal.ErrInvalidTotalRange)
+
+		mockRepo.AssertNotCalled(t, "ListOrders", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// postMarketplaceOrder places an order for two products of seller 7 and one of seller 8.
+func postMarketplaceOrder(t *testing.T, ctx context.Context, service internal.Service) *models.Order {
+	_, err := service.CreatePromotion(ctx, &models.Promotion{Code: "TENOFF", Kind: models.PromotionPercentage, PercentOff: 10, Active: true})
+	require.NoError(t, err)
+	order, err := service.PostOrder(ctx, 1, "EUR", []*models.OrderedProduct{
+		{ID: "book", Name: "Book", Price: money.New(1999, "EUR"), Quantity: 1, TaxCategory: "reduced", SellerID: 7},
+		{ID: "lamp", Name: "Lamp", Price: money.New(2500, "EUR"), Quantity: 2, SellerID: 8},
+		{ID: "pen", Name: "Pen", Price: money.New(333, "EUR"), Quantity: 3, SellerID: 7},
+	}, "TENOFF", models.Address{RecipientName: "Ada", Line1: "Street 2", City: "Hamburg", Country: "DE"}, models.Shipping{})
+	require.NoError(t, err)
+	return order
+}
+
+// subOrderOf returns the order's sub-order for the seller.
+func subOrderOf(t *testing.T, order *models.Order, sellerId uint64) *models.SubOrder {
+	for _, sub := range order.SubOrders {
+		if sub.SellerID == sellerId {
+			return sub
+		}
+	}
+	require.Failf(t, "sub-order not found", "order %d has no sub-order for seller %d", order.ID, sellerId)
+	return nil
+}
+
+func TestOrderService_SubOrders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Orders are split by seller and the parts add up", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 3), nil, setupTaxCalculator(t, false))
+		order := postMarketplaceOrder(t, ctx, service)
+
+		order, err := service.GetOrder(ctx, uint64(order.ID), 1)
+		require.NoError(t, err)
+		require.Len(t, order.SubOrders, 2)
+		books, lamps := subOrderOf(t, order, 7), subOrderOf(t, order, 8)
+		assert.Equal(t, money.New(2998, "EUR"), books.Subtotal)
+		assert.Equal(t, money.New(5000, "EUR"), lamps.Subtotal)
+
+		total, err := books.Total.Add(lamps.Total)
+		require.NoError(t, err)
+		assert.Equal(t, order.TotalPrice, total)
+		discount, err := books.Discount.Add(lamps.Discount)
+		require.NoError(t, err)
+		assert.Equal(t, money.New(799, "EUR"), discount)
+		taxTotal, err := order.TaxTotal()
+		require.NoError(t, err)
+		tax, err := books.Tax.Add(lamps.Tax)
+		require.NoError(t, err)
+		assert.Equal(t, taxTotal, tax)
+		assert.Equal(t, models.StatusPendingPayment, books.Status)
+	})
+
+	t.Run("Sellers fulfil their parts and the order follows", func(t *testing.T) {
+		// Three products, the payment, and the order becoming fulfilling, shipped and delivered
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 7), nil, nil)
+		order := postMarketplaceOrder(t, ctx, service)
+		_, err := service.UpdateOrderStatus(ctx, uint64(order.ID), "paid", "payment", "")
+		require.NoError(t, err)
+		order, err = service.GetOrder(ctx, uint64(order.ID), 1)
+		require.NoError(t, err)
+		books, lamps := subOrderOf(t, order, 7), subOrderOf(t, order, 8)
+		assert.Equal(t, models.StatusPaid, books.Status)
+		assert.Equal(t, models.StatusPaid, lamps.Status)
+
+		steps := []struct {
+			sub      *models.SubOrder
+			status   models.OrderStatus
+			expected models.OrderStatus
+		}{
+			{books, models.StatusFulfilling, models.StatusFulfilling},
+			{books, models.StatusShipped, models.StatusFulfilling},
+			{lamps, models.StatusFulfilling, models.StatusFulfilling},
+			{lamps, models.StatusShipped, models.StatusShipped},
+			{lamps, models.StatusDelivered, models.StatusShipped},
+			{books, models.StatusDelivered, models.StatusDelivered},
+		}
+		for _, step := range steps {
+			sub, err := service.UpdateSubOrderStatus(ctx, step.sub.ID, step.sub.SellerID, step.status.String(), "account:7")
+			require.NoError(t, err)
+			assert.Equal(t, step.status, sub.Status)
+			order, err = service.GetOrder(ctx, uint64(order.ID), 1)
+			require.NoError(t, err)
+			assert.Equal(t, step.expected, order.Status, "after sub-order %d became %s", sub.ID, step.status)
+		}
+		assert.Equal(t, fmt.Sprintf("sub-order %d delivered", books.ID), order.History[len(order.History)-1].Reason)
+	})
+
+	t.Run("Sellers only move their own parts forward", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 4), nil, nil)
+		order := postMarketplaceOrder(t, ctx, service)
+		books := subOrderOf(t, order, 7)
+
+		_, err := service.UpdateSubOrderStatus(ctx, books.ID, 7, "shipped", "account:7")
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = service.UpdateOrderStatus(ctx, uint64(order.ID), "paid", "payment", "")
+		require.NoError(t, err)
+		_, err = service.UpdateSubOrderStatus(ctx, books.ID, 8, "fulfilling", "account:8")
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		_, err = service.UpdateSubOrderStatus(ctx, books.ID, 7, "refunded", "account:7")
+		assert.ErrorIs(t, err, internal.ErrInvalidSellerState)
+	})
+
+	t.Run("Sellers list only their own lines", func(t *testing.T) {
+		service := internal.NewOrderService(setupTestRepository(t), setupProducer(t, 4), nil, nil)
+		first := postMarketplaceOrder(t, ctx, service)
+		second, err := service.PostOrder(ctx, 2, "EUR", []*models.OrderedProduct{
+			{ID: "pen", Name: "Pen", Price: money.New(333, "EUR"), Quantity: 1, SellerID: 7},
+		}, "", models.Address{}, models.Shipping{})
+		require.NoError(t, err)
+
+		page, err := service.ListSellerOrders(ctx, 8, nil, "", 0)
+		require.NoError(t, err)
+		require.Len(t, page.Orders, 1)
+		assert.Equal(t, first.ID, page.Orders[0].OrderID)
+		require.Len(t, page.Orders[0].Lines, 1)
+		assert.Equal(t, "lamp", page.Orders[0].Lines[0].ProductID)
+		assert.Equal(t, "Hamburg", page.Orders[0].ShippingAddress.City)
+
+		page, err = service.ListSellerOrders(ctx, 7, nil, "", 1)
+		require.NoError(t, err)
+		require.Len(t, page.Orders, 1)
+		assert.Equal(t, second.ID, page.Orders[0].OrderID)
+		assert.True(t, page.HasNextPage)
+
+		page, err = service.ListSellerOrders(ctx, 7, nil, page.EndCursor, 1)
+		require.NoError(t, err)
+		require.Len(t, page.Orders, 1)
+		assert.Equal(t, first.ID, page.Orders[0].OrderID)
+		assert.Len(t, page.Orders[0].Lines, 2)
+		assert.False(t, page.HasNextPage)
+	})
+}
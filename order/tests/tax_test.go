@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/order/internal"
+	"github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/pkg/tax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test helper to create a calculator with German VAT rates
+func setupTaxCalculator(t *testing.T, inclusive bool) tax.Calculator {
+	table, err := tax.DecodeRateTable([]byte(`{"rates": [
+		{"country": "DE", "name": "VAT", "rate": "0.19"},
+		{"country": "DE", "category": "reduced", "name": "VAT", "rate": "0.07"}
+	]}`))
+	require.NoError(t, err)
+	table.PricesIncludeTax = inclusive
+	return tax.NewTableCalculator(table)
+}
+
+func TestOrderService_Taxes(t *testing.T) {
+	ctx := context.Background()
+	products := func() []*models.OrderedProduct {
+		return []*models.OrderedProduct{
+			{ID: "book", Price: money.New(1999, "EUR"), Quantity: 1, TaxCategory: "reduced"},
+			{ID: "lamp", Price: money.New(2500, "EUR"), Quantity: 2},
+		}
+	}
+
+	t.Run("Exclusive taxes are added after discounts", func(t *testing.T) {
+		repo := setupTestRepository(t)
+		service := internal.NewOrderService(repo, setupProducer(t, 2), nil, setupTaxCalculator(t, false))
+		_, err := service.CreatePromotion(ctx, &models.Promotion{Code: "TENOFF", Kind: models.PromotionPercentage, PercentOff: 10, Active: true})
+		require.NoError(t, err)
+
+		order, err := service.PostOrder(ctx, 1, "EUR", products(), "TENOFF", models.Address{Country: "de"}, models.Shipping{})
+		require.NoError(t, err)
+
+		stored, err := repo.GetOrder(ctx, uint64(order.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "DE", stored.ShippingAddress.Country)
+		require.Len(t, stored.Taxes, 2)
+		// The 6.99 discount is shared 2.00 / 4.99, leaving 17.99 and 45.01 to tax
+		assert.Equal(t, "book", stored.Taxes[0].ProductID)
+		assert.Equal(t, "0.07", stored.Taxes[0].Rate)
+		assert.Equal(t, money.New(126, "EUR"), stored.Taxes[0].Amount)
+		assert.Equal(t, money.New(855, "EUR"), stored.Taxes[1].Amount)
+		assert.False(t, stored.Taxes[1].Inclusive)
+
+		assert.Equal(t, money.New(6999-699+981, "EUR"), stored.TotalPrice)
+		subtotal, err := stored.Subtotal()
+		require.NoError(t, err)
+		assert.Equal(t, money.New(6999, "EUR"), subtotal)
+		taxTotal, err := stored.TaxTotal()
+		require.NoError(t, err)
+		assert.Equal(t, money.New(981, "EUR"), taxTotal)
+	})
+
+	t.Run("Inclusive taxes leave the total unchanged", func(t *testing.T) {
+		repo := setupTestRepository(t)
+		service := internal.NewOrderService(repo, setupProducer(t, 2), nil, setupTaxCalculator(t, true))
+
+		order, err := service.PostOrder(ctx, 1, "EUR", products(), "", models.Address{Country: "DE"}, models.Shipping{})
+		require.NoError(t, err)
+
+		assert.Equal(t, money.New(6999, "EUR"), order.TotalPrice)
+		require.Len(t, order.Taxes, 2)
+		assert.True(t, order.Taxes[0].Inclusive)
+		subtotal, err := order.Subtotal()
+		require.NoError(t, err)
+		assert.Equal(t, money.New(6999, "EUR"), subtotal)
+	})
+
+	t.Run("Orders outside the rate table are not taxed", func(t *testing.T) {
+		repo := setupTestRepository(t)
+		service := internal.NewOrderService(repo, setupProducer(t, 2), nil, setupTaxCalculator(t, false))
+
+		order, err := service.PostOrder(ctx, 1, "EUR", products(), "", models.Address{Country: "FR"}, models.Shipping{})
+		require.NoError(t, err)
+
+		assert.Empty(t, order.Taxes)
+		assert.Equal(t, money.New(6999, "EUR"), order.TotalPrice)
+	})
+}
@@ -3,8 +3,12 @@ package client
 import (
 	"context"
 	"log"
+	"time"
 
+	"github.com/rasadov/EcommerceAPI/payment/models"
 	"github.com/rasadov/EcommerceAPI/payment/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/idempotency"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -15,7 +19,10 @@ type Client struct {
 }
 
 func NewClient(url string) (*Client, error) {
-	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(url,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(idempotency.UnaryClientInterceptor()),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +51,7 @@ func (client *Client) CreateCustomerPortalSession(ctx context.Context, userId ui
 }
 
 func (client *Client) CreateCheckoutSession(ctx context.Context, orderId, userId int,
-	email, name, redirectUrl string, products []*pb.CartItem) (string, error) {
+	email, name, redirectUrl, currency string, products []*pb.CartItem) (string, error) {
 	res, err := client.service.CreateCheckoutSession(ctx, &pb.CheckoutRequest{
 		UserId:      uint64(userId),
 		Email:       email,
@@ -52,6 +59,7 @@ func (client *Client) CreateCheckoutSession(ctx context.Context, orderId, userId
 		RedirectURL: redirectUrl,
 		Products:    products,
 		OrderId:     uint64(orderId),
+		Currency:    currency,
 	})
 	if err != nil {
 		log.Println(err)
@@ -59,3 +67,264 @@ func (client *Client) CreateCheckoutSession(ctx context.Context, orderId, userId
 	}
 	return res.Value, nil
 }
+
+// ExpireCheckoutSessions expires the order's open checkout sessions.
+func (client *Client) ExpireCheckoutSessions(ctx context.Context, orderId uint64) error {
+	_, err := client.service.ExpireCheckoutSessions(ctx, &pb.ExpireCheckoutSessionsRequest{OrderId: orderId})
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+// RenewCheckoutSession opens a fresh checkout session for an order whose checkout was
+// abandoned and returns its URL.
+func (client *Client) RenewCheckoutSession(ctx context.Context, orderId uint64) (string, error) {
+	res, err := client.service.RenewCheckoutSession(ctx, &pb.RenewCheckoutSessionRequest{OrderId: orderId})
+	if err != nil {
+		return "", err
+	}
+	return res.Value, nil
+}
+
+// RefundPayment refunds an order's payment; with no items the whole payment is refunded.
+// With toStoreCredit the refund is credited to the customer's store credit.
+func (client *Client) RefundPayment(ctx context.Context, orderId uint64, items []*pb.RefundItem, reason string, toStoreCredit bool) (*models.Refund, error) {
+	res, err := client.service.RefundPayment(ctx, &pb.RefundPaymentRequest{
+		OrderId:       orderId,
+		Items:         items,
+		Reason:        reason,
+		ToStoreCredit: toStoreCredit,
+	})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	amount := money.FromProto(res.GetAmount())
+	refund := &models.Refund{
+		OrderId:   res.OrderId,
+		RefundId:  res.RefundId,
+		Amount:    amount.Amount,
+		Currency:  amount.Currency,
+		IsPartial: res.IsPartial,
+		Reason:    res.Reason,
+		Status:    res.Status,
+		Method:    res.Method,
+	}
+	err = refund.CreatedAt.UnmarshalBinary(res.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// GetSellerBalances returns what the marketplace owes the seller and has paid them, per currency.
+func (client *Client) GetSellerBalances(ctx context.Context, sellerId uint64) ([]*models.SellerBalance, error) {
+	res, err := client.service.GetSellerBalance(ctx, &pb.SellerBalanceRequest{SellerId: sellerId})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	var balances []*models.SellerBalance
+	for _, balance := range res.Balances {
+		owed := money.FromProto(balance.GetOwed())
+		balances = append(balances, &models.SellerBalance{
+			SellerId: sellerId,
+			Currency: owed.Currency,
+			Owed:     owed.Amount,
+			PaidOut:  money.FromProto(balance.GetPaidOut()).Amount,
+		})
+	}
+	return balances, nil
+}
+
+// ListPayouts returns a page of the seller's payouts, newest first.
+func (client *Client) ListPayouts(ctx context.Context, sellerId uint64, after string, first int) (*models.PayoutPage, error) {
+	res, err := client.service.ListPayouts(ctx, &pb.ListPayoutsRequest{
+		SellerId: sellerId,
+		After:    after,
+		First:    uint32(first),
+	})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	page := &models.PayoutPage{EndCursor: res.EndCursor, HasNextPage: res.HasNextPage}
+	for _, p := range res.Payouts {
+		amount := money.FromProto(p.GetAmount())
+		payout := &models.Payout{
+			ID:         uint(p.Id),
+			SellerId:   p.SellerId,
+			Currency:   amount.Currency,
+			Sales:      money.FromProto(p.GetSales()).Amount,
+			Commission: money.FromProto(p.GetCommission()).Amount,
+			Refunds:    money.FromProto(p.GetRefunds()).Amount,
+			Amount:     amount.Amount,
+		}
+		err = payout.PeriodStart.UnmarshalBinary(p.PeriodStart)
+		if err != nil {
+			return nil, err
+		}
+		err = payout.PeriodEnd.UnmarshalBinary(p.PeriodEnd)
+		if err != nil {
+			return nil, err
+		}
+		err = payout.CreatedAt.UnmarshalBinary(p.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		page.Payouts = append(page.Payouts, payout)
+	}
+	return page, nil
+}
+
+// IssueGiftCard issues a gift card worth card.Initial, under a random code unless card has one.
+func (client *Client) IssueGiftCard(ctx context.Context, card *models.GiftCard) (*models.GiftCard, error) {
+	request := &pb.IssueGiftCardRequest{
+		Code:     card.Code,
+		Amount:   card.Initial.ToProto(),
+		IssuedBy: card.IssuedBy,
+		Note:     card.Note,
+	}
+	if card.ExpiresAt != nil {
+		request.ExpiresAt, _ = card.ExpiresAt.MarshalBinary()
+	}
+	res, err := client.service.IssueGiftCard(ctx, request)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return decodeGiftCard(res)
+}
+
+// GetGiftCard returns the gift card with code if the user bought it or has put it towards
+// an order. userId 0 looks the card up for admins.
+func (client *Client) GetGiftCard(ctx context.Context, code string, userId uint64) (*models.GiftCard, error) {
+	res, err := client.service.GetGiftCard(ctx, &pb.GetGiftCardRequest{Code: code, UserId: userId})
+	if err != nil {
+		return nil, err
+	}
+	return decodeGiftCard(res)
+}
+
+// PurchaseGiftCard opens a checkout for a gift card worth amount and returns its url. The
+// card is issued to the user once the checkout is paid.
+func (client *Client) PurchaseGiftCard(ctx context.Context, userId uint64, email, name, redirectUrl string, amount money.Money) (string, error) {
+	res, err := client.service.PurchaseGiftCard(ctx, &pb.PurchaseGiftCardRequest{
+		UserId:      userId,
+		Email:       email,
+		Name:        name,
+		Amount:      amount.ToProto(),
+		RedirectURL: redirectUrl,
+	})
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+	return res.Value, nil
+}
+
+// ListGiftCards returns the gift cards the user bought, newest first.
+func (client *Client) ListGiftCards(ctx context.Context, userId uint64) ([]*models.GiftCard, error) {
+	res, err := client.service.ListGiftCards(ctx, &pb.ListGiftCardsRequest{UserId: userId})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	cards := make([]*models.GiftCard, 0, len(res.GiftCards))
+	for _, encoded := range res.GiftCards {
+		card, err := decodeGiftCard(encoded)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+func decodeGiftCard(res *pb.GiftCard) (*models.GiftCard, error) {
+	card := &models.GiftCard{
+		ID:       uint(res.Id),
+		Code:     res.Code,
+		Initial:  money.FromProto(res.GetInitial()),
+		Balance:  money.FromProto(res.GetBalance()),
+		IssuedBy: res.IssuedBy,
+		Note:     res.Note,
+		OwnerId:  res.OwnerId,
+	}
+	if len(res.ExpiresAt) > 0 {
+		card.ExpiresAt = &time.Time{}
+		err := card.ExpiresAt.UnmarshalBinary(res.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := card.CreatedAt.UnmarshalBinary(res.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// ApplyCredit puts the gift card with giftCardCode and, when useStoreCredit is set, the
+// user's store credit towards the unpaid order, returning what is left to pay at checkout.
+func (client *Client) ApplyCredit(ctx context.Context, orderId, userId uint64, giftCardCode string, useStoreCredit bool) (money.Money, error) {
+	res, err := client.service.ApplyCredit(ctx, &pb.ApplyCreditRequest{
+		OrderId:        orderId,
+		UserId:         userId,
+		GiftCardCode:   giftCardCode,
+		UseStoreCredit: useStoreCredit,
+	})
+	if err != nil {
+		log.Println(err)
+		return money.Money{}, err
+	}
+	return money.FromProto(res.GetRemaining()), nil
+}
+
+// CaptureCredit takes the payment of an unpaid order its credit pays for in full.
+func (client *Client) CaptureCredit(ctx context.Context, orderId uint64) error {
+	_, err := client.service.CaptureCredit(ctx, &pb.CaptureCreditRequest{OrderId: orderId})
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+// GetStoreCredit returns the user's store credit balances, one per currency, and a page of
+// their store credit transactions, newest first.
+func (client *Client) GetStoreCredit(ctx context.Context, userId uint64, after string, first int) ([]money.Money, *models.StoreCreditPage, error) {
+	res, err := client.service.GetStoreCredit(ctx, &pb.StoreCreditRequest{
+		UserId: userId,
+		After:  after,
+		First:  uint32(first),
+	})
+	if err != nil {
+		log.Println(err)
+		return nil, nil, err
+	}
+
+	var balances []money.Money
+	for _, balance := range res.Balances {
+		balances = append(balances, money.FromProto(balance))
+	}
+	page := &models.StoreCreditPage{EndCursor: res.EndCursor, HasNextPage: res.HasNextPage}
+	for _, t := range res.Transactions {
+		transaction := &models.StoreCreditTransaction{
+			ID:      uint(t.Id),
+			UserId:  userId,
+			Kind:    t.Kind,
+			Amount:  money.FromProto(t.GetAmount()),
+			OrderId: t.OrderId,
+		}
+		err = transaction.CreatedAt.UnmarshalBinary(t.CreatedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		page.Transactions = append(page.Transactions, transaction)
+	}
+	return balances, page, nil
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/rasadov/EcommerceAPI/payment/config"
 	"github.com/rasadov/EcommerceAPI/payment/internal"
+	"github.com/rasadov/EcommerceAPI/pkg/idempotency"
 	"github.com/tinrab/retry"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -14,6 +16,7 @@ import (
 
 func main() {
 	var repository internal.Repository
+	var idempotencyStore *idempotency.Store
 
 	retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
 		db, err := gorm.Open(postgres.Open(config.DatabaseURL), &gorm.Config{})
@@ -21,6 +24,11 @@ func main() {
 			log.Println(err)
 		}
 		repository, err = internal.NewPostgresRepository(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		idempotencyStore, err = idempotency.NewStore(db, config.IdempotencyTTL)
 		if err != nil {
 			log.Println(err)
 		}
@@ -43,7 +51,26 @@ func main() {
 	}
 
 	dodoClient := internal.NewDodoClient(config.DodoAPIKEY, config.DodoTestMode)
-	service := internal.NewPaymentService(dodoClient, repository)
+	service := internal.NewPaymentService(dodoClient, repository, config.CommissionBasisPoints)
+
+	go internal.NewPayoutScheduler(service, config.PayoutPeriod).Run(context.Background())
+
+	// Abandoned checkouts are announced for the alert service to remind customers
+	if config.KafkaBrokers != "" {
+		producer, err := sarama.NewAsyncProducer([]string{config.KafkaBrokers}, nil)
+		if err != nil {
+			log.Println(err)
+		} else {
+			defer func(producer sarama.AsyncProducer) {
+				err := producer.Close()
+				if err != nil {
+					log.Println(err)
+				}
+			}(producer)
+			detector := internal.NewAbandonmentDetector(service, producer, config.PaymentEventsTopic, config.CheckoutAbandonAfter)
+			go detector.Run(context.Background(), time.Minute)
+		}
+	}
 
-	log.Fatal(internal.StartServers(service, consumer, config.OrderServiceURL, config.GrpcPort, config.WebhookPort))
+	log.Fatal(internal.StartServers(service, consumer, idempotencyStore, config.OrderServiceURL, config.GrpcPort, config.WebhookPort))
 }
@@ -1,6 +1,10 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 var (
 	DatabaseURL       string
@@ -11,6 +15,16 @@ var (
 	OrderServiceURL   string
 	KafkaBrokers      string
 	ProductEventsTopic string
+	IdempotencyTTL     time.Duration
+	// CommissionBasisPoints is the marketplace's commission on seller sales, e.g. 1000 for 10%
+	CommissionBasisPoints int64
+	// PayoutPeriod is how often sellers are paid out what they are owed
+	PayoutPeriod time.Duration
+	// PaymentEventsTopic is where abandoned checkouts are announced
+	PaymentEventsTopic string
+	// CheckoutAbandonAfter is how long a checkout session may stay unpaid before it counts
+	// as abandoned
+	CheckoutAbandonAfter time.Duration
 )
 
 const (
@@ -30,4 +44,28 @@ func init() {
 	if ProductEventsTopic == "" {
 		ProductEventsTopic = "product_events"
 	}
+	IdempotencyTTL = durationOrDefault(os.Getenv("IDEMPOTENCY_TTL"), 24*time.Hour)
+	CommissionBasisPoints = basisPointsOrDefault(os.Getenv("COMMISSION_BASIS_POINTS"), 1000)
+	PayoutPeriod = durationOrDefault(os.Getenv("PAYOUT_PERIOD"), 7*24*time.Hour)
+	PaymentEventsTopic = os.Getenv("PAYMENT_EVENTS_TOPIC")
+	if PaymentEventsTopic == "" {
+		PaymentEventsTopic = "payment_events"
+	}
+	CheckoutAbandonAfter = durationOrDefault(os.Getenv("CHECKOUT_ABANDON_AFTER"), time.Hour)
+}
+
+func durationOrDefault(value string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+func basisPointsOrDefault(value string, fallback int64) int64 {
+	basisPoints, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || basisPoints < 0 || basisPoints > 10000 {
+		return fallback
+	}
+	return basisPoints
 }
@@ -7,6 +7,8 @@ type EventData struct {
 	Name        *string  `json:"name"`
 	Description *string  `json:"description"`
 	Price       *float64 `json:"price"`
+	Amount      *int64   `json:"amount"`
+	Currency    *string  `json:"currency"`
 	AccountID   *int     `json:"accountID"`
 }
 
@@ -3,8 +3,9 @@ package dto
 import "github.com/dodopayments/dodopayments-go"
 
 type WebhookMetadata struct {
-	OrderId uint64 `json:"order_id"`
-	UserId  uint64 `json:"user_id"`
+	OrderId            uint64 `json:"order_id"`
+	UserId             uint64 `json:"user_id"`
+	GiftCardPurchaseId uint64 `json:"gift_card_purchase_id"`
 }
 
 type WebhookPayload struct {
@@ -21,6 +22,10 @@ type WebhookPayload struct {
 		} `json:"product_cart"` // Product cart is going to be a slice of one element since
 		// we always pass one product with the quantity one
 		PaymentId     string                `json:"payment_id"`
+		RefundId      string                `json:"refund_id"`
+		Amount        int64                 `json:"amount"`
+		IsPartial     bool                  `json:"is_partial"`
+		Reason        string                `json:"reason"`
 		Metadata      WebhookMetadata       `json:"metadata"`
 		TotalAmount   int64                 `json:"total_amount"`
 		SettledAmount int64                 `json:"settled_amount"`
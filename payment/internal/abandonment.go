@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/rasadov/EcommerceAPI/payment/models"
+	"github.com/rasadov/EcommerceAPI/pkg/kafka"
+)
+
+const abandonmentBatchSize = 100
+
+// AbandonmentDetector announces checkout sessions that were not paid within delay of being
+// opened, so that the customer can be reminded. Each session is announced once, even with
+// replicas running it side by side.
+type AbandonmentDetector struct {
+	service  Service
+	producer sarama.AsyncProducer
+	topic    string
+	delay    time.Duration
+}
+
+func NewAbandonmentDetector(service Service, producer sarama.AsyncProducer, topic string, delay time.Duration) *AbandonmentDetector {
+	return &AbandonmentDetector{service, producer, topic, delay}
+}
+
+func (detector *AbandonmentDetector) GetProducer() sarama.AsyncProducer {
+	return detector.producer
+}
+
+// Run detects abandoned checkouts every interval until ctx is done.
+func (detector *AbandonmentDetector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		abandoned, err := detector.DetectAbandoned(ctx)
+		if err != nil {
+			log.Println("Failed to detect abandoned checkouts:", err)
+		}
+		if abandoned > 0 {
+			log.Printf("Detected %d abandoned checkouts", abandoned)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DetectAbandoned publishes a checkout_abandoned event for every checkout session abandoned
+// by now and returns how many were.
+func (detector *AbandonmentDetector) DetectAbandoned(ctx context.Context) (int, error) {
+	openedBefore := time.Now().UTC().Add(-detector.delay)
+	abandoned := 0
+	for {
+		checkouts, err := detector.service.ClaimAbandonedCheckouts(ctx, openedBefore, abandonmentBatchSize)
+		if err != nil {
+			return abandoned, err
+		}
+		for _, checkout := range checkouts {
+			err = kafka.SendMessageToRecommender(detector, models.CheckoutEvent{
+				Type: "checkout_abandoned",
+				Data: models.CheckoutEventData{
+					OrderId:     checkout.Session.OrderId,
+					UserId:      checkout.Session.UserId,
+					Email:       checkout.Email,
+					Name:        checkout.Name,
+					Currency:    checkout.Session.Currency,
+					CheckoutURL: checkout.Session.CheckoutURL,
+					OpenedAt:    checkout.Session.CreatedAt.UTC().Format(time.RFC3339),
+				},
+			}, detector.topic)
+			if err != nil {
+				log.Println("Failed to send checkout event:", err)
+			}
+		}
+		abandoned += len(checkouts)
+		if len(checkouts) < abandonmentBatchSize {
+			return abandoned, nil
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/IBM/sarama"
 	"github.com/rasadov/EcommerceAPI/payment/models"
 	"github.com/rasadov/EcommerceAPI/pkg/kafka"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 )
 
 type EventConsumer struct {
@@ -51,6 +52,8 @@ func (ec *EventConsumer) handleProductEvent(partition int32, pc sarama.Partition
 				ec.handleProductUpdated(event)
 			case "product_deleted":
 				ec.handleProductDeleted(event)
+			case "product_stock_changed":
+				// Stock is not part of the Dodo product
 			default:
 				log.Printf("Unknown event type: %s", event.Type)
 			}
@@ -64,30 +67,32 @@ func (ec *EventConsumer) handleProductEvent(partition int32, pc sarama.Partition
 }
 
 func (ec *EventConsumer) handleProductCreated(event models.ProductEvent) {
-	if event.Data.ProductID == nil || event.Data.Name == nil || event.Data.Price == nil {
+	price, ok := eventPrice(event)
+	if event.Data.ProductID == nil || event.Data.Name == nil || !ok {
 		log.Printf("Invalid product created event: missing required fields")
 		return
 	}
 
-	log.Printf("Payment service received product created event: ID=%s, Name=%s, Price=%.2f",
-		*event.Data.ProductID, *event.Data.Name, *event.Data.Price)
+	log.Printf("Payment service received product created event: ID=%s, Name=%s, Price=%s",
+		*event.Data.ProductID, *event.Data.Name, money.New(price, eventCurrency(event)))
 
 	ctx := context.Background()
-	err := ec.service.RegisterProduct(ctx, *event.Data.Name, int64(*event.Data.Price*100), "", *event.Data.ProductID)
+	err := ec.service.RegisterProduct(ctx, *event.Data.Name, price, eventCurrency(event), eventTaxCategory(event), "", *event.Data.ProductID)
 	if err != nil {
 		log.Printf("Failed to register product with payment provider: %v", err)
 	}
 }
 
 func (ec *EventConsumer) handleProductUpdated(event models.ProductEvent) {
-	if event.Data.ProductID == nil {
-		log.Printf("Invalid product updated event: missing product ID")
+	price, ok := eventPrice(event)
+	if event.Data.ProductID == nil || event.Data.Name == nil || !ok {
+		log.Printf("Invalid product updated event: missing required fields")
 		return
 	}
 
 	log.Printf("Payment service received product updated event: ID=%s", *event.Data.ProductID)
 	ctx := context.Background()
-	err := ec.service.UpdateProduct(ctx, *event.Data.ProductID, *event.Data.Name, int64(*event.Data.Price*100))
+	err := ec.service.UpdateProduct(ctx, *event.Data.ProductID, *event.Data.Name, price, eventCurrency(event), eventTaxCategory(event))
 	if err != nil {
 		log.Printf("Failed to update product with payment provider: %v", err)
 	}
@@ -107,3 +112,32 @@ func (ec *EventConsumer) handleProductDeleted(event models.ProductEvent) {
 		log.Printf("Failed to delete product with payment provider: %v", err)
 	}
 }
+
+// eventCurrency returns the product currency, treating events from before multi-currency support as USD.
+func eventCurrency(event models.ProductEvent) string {
+	if event.Data.Currency == nil {
+		return money.DefaultCurrency
+	}
+	return *event.Data.Currency
+}
+
+// eventTaxCategory returns the product's tax category, or "" for events from before
+// products had one.
+func eventTaxCategory(event models.ProductEvent) string {
+	if event.Data.TaxCategory == nil {
+		return ""
+	}
+	return *event.Data.TaxCategory
+}
+
+// eventPrice returns the product price in minor units. Events from before exact amounts were
+// published only carry the major-unit price, which is converted without float truncation.
+func eventPrice(event models.ProductEvent) (int64, bool) {
+	if event.Data.Amount != nil {
+		return *event.Data.Amount, true
+	}
+	if event.Data.Price == nil {
+		return 0, false
+	}
+	return money.FromMajor(*event.Data.Price, eventCurrency(event)).Amount, true
+}
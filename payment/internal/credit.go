@@ -0,0 +1,281 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dodopayments/dodopayments-go"
+	ordermodels "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/payment/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrGiftCardNotFound = errors.New("gift card not found")
+	ErrOrderNotCovered  = errors.New("order is not paid in full by its credit")
+)
+
+// IssueGiftCard issues a card worth its initial amount, under a random code unless it
+// has one.
+func (d *paymentService) IssueGiftCard(ctx context.Context, card *models.GiftCard) (*models.GiftCard, error) {
+	currency, err := money.Validate(card.Initial.Currency)
+	if err != nil {
+		return nil, err
+	}
+	card.Initial.Currency = currency
+
+	if card.Code == "" {
+		card.Code, err = models.NewGiftCardCode()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		card.Code = models.NormalizeGiftCardCode(card.Code)
+	}
+	if err = card.Validate(); err != nil {
+		return nil, err
+	}
+
+	card.Balance = card.Initial
+	card.CreatedAt = time.Now().UTC()
+	err = d.paymentRepository.SaveGiftCard(ctx, card)
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// GetGiftCard returns the gift card with code to the account that bought it or has put it
+// towards an order. Other accounts are told it does not exist, so that codes and balances
+// cannot be found by trying them. userId 0 looks the card up for admins.
+func (d *paymentService) GetGiftCard(ctx context.Context, code string, userId uint64) (*models.GiftCard, error) {
+	card, err := d.paymentRepository.GetGiftCardByCode(ctx, models.NormalizeGiftCardCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGiftCardNotFound
+	}
+	if err != nil || userId == 0 || card.OwnerId == userId {
+		return card, err
+	}
+
+	redeemer, err := d.paymentRepository.IsGiftCardRedeemer(ctx, card.ID, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !redeemer {
+		return nil, ErrGiftCardNotFound
+	}
+	return card, nil
+}
+
+// ListGiftCards returns the gift cards the account bought, newest first.
+func (d *paymentService) ListGiftCards(ctx context.Context, userId uint64) ([]*models.GiftCard, error) {
+	return d.paymentRepository.ListGiftCardsForOwner(ctx, userId)
+}
+
+// PurchaseGiftCard opens a checkout for a gift card worth amount and returns its url. The
+// card is issued to the account once the checkout is paid.
+func (d *paymentService) PurchaseGiftCard(ctx context.Context, userId uint64, customerId, redirect string, amount money.Money) (string, error) {
+	currency, err := money.Validate(amount.Currency)
+	if err != nil {
+		return "", err
+	}
+	if amount.Amount <= 0 {
+		return "", models.ErrInvalidGiftCard
+	}
+	amount.Currency = currency
+
+	chargeProductId, err := d.chargeProduct(ctx, currency)
+	if err != nil {
+		return "", err
+	}
+	purchase := &models.GiftCardPurchase{
+		UserId: userId,
+		Amount: amount,
+		Status: models.PurchasePending,
+	}
+	err = d.paymentRepository.SaveGiftCardPurchase(ctx, purchase)
+	if err != nil {
+		return "", err
+	}
+
+	dodoProducts := []dodopayments.CheckoutSessionRequestProductCartParam{{
+		ProductID: dodopayments.F(chargeProductId),
+		Quantity:  dodopayments.F[int64](1),
+		Amount:    dodopayments.F(amount.Amount),
+	}}
+	checkoutURL, sessionID, err := d.client.CreateCheckoutSession(ctx, userId, customerId, redirect,
+		dodoProducts, map[string]string{"gift_card_purchase_id": strconv.FormatUint(uint64(purchase.ID), 10)},
+		dodopayments.Currency(currency))
+	if err != nil {
+		return "", err
+	}
+
+	purchase.SessionId = sessionID
+	err = d.paymentRepository.SaveGiftCardPurchase(ctx, purchase)
+	if err != nil {
+		return "", err
+	}
+	return checkoutURL, nil
+}
+
+// completeGiftCardPurchase issues the gift card of a paid purchase. Webhooks delivered
+// again get the card issued the first time.
+func (d *paymentService) completeGiftCardPurchase(ctx context.Context, purchase *models.GiftCardPurchase) error {
+	code, err := models.NewGiftCardCode()
+	if err != nil {
+		return err
+	}
+	card := &models.GiftCard{
+		Code:      code,
+		IssuedBy:  fmt.Sprintf("purchase:%d", purchase.ID),
+		Note:      "Bought at checkout",
+		CreatedAt: time.Now().UTC(),
+	}
+	card, err = d.paymentRepository.CompleteGiftCardPurchase(ctx, purchase.ID, purchase.PaymentId, card)
+	if err != nil {
+		return err
+	}
+	purchase.GiftCardId = card.ID
+	return nil
+}
+
+// ApplyCredit puts the gift card with giftCardCode and, when useStoreCredit is set, the
+// account's store credit towards the order's total. What they do not cover is left to pay
+// at checkout, where it is all that is charged.
+func (d *paymentService) ApplyCredit(ctx context.Context, orderId, userId uint64, total money.Money, giftCardCode string, useStoreCredit bool) (*models.AppliedCredit, error) {
+	if giftCardCode != "" {
+		giftCardCode = models.NormalizeGiftCardCode(giftCardCode)
+	}
+	applied, err := d.paymentRepository.ApplyCredit(ctx, orderId, userId, total, giftCardCode, useStoreCredit, time.Now().UTC())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGiftCardNotFound
+	}
+	return applied, err
+}
+
+// CaptureCredit takes the payment of an order its credit pays for in full, without a
+// checkout, and posts it to the ledger shared between the sellers of shares. Capturing
+// the payment again returns the payment already captured.
+func (d *paymentService) CaptureCredit(ctx context.Context, orderId, userId uint64, total money.Money, shares []models.SellerShare) (*models.Transaction, error) {
+	transaction, err := d.paymentRepository.GetSuccessfulTransaction(ctx, orderId)
+	if err == nil {
+		return transaction, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	credit, err := d.orderCredit(ctx, orderId, total.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if credit != total.Amount {
+		return nil, ErrOrderNotCovered
+	}
+
+	transaction = &models.Transaction{
+		OrderId:      orderId,
+		UserId:       userId,
+		PaymentId:    fmt.Sprintf("credit_%d", orderId),
+		Currency:     total.Currency,
+		Status:       models.Success.String(),
+		CreditAmount: credit,
+	}
+	err = d.paymentRepository.RegisterTransaction(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	return transaction, d.RecordPayment(ctx, transaction, shares)
+}
+
+// orderCredit adds up the credit put towards the order, all in currency.
+func (d *paymentService) orderCredit(ctx context.Context, orderId uint64, currency string) (int64, error) {
+	credits, err := d.paymentRepository.GetOrderCredits(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, credit := range credits {
+		if credit.Amount.Currency != currency {
+			return 0, money.ErrCurrencyMismatch
+		}
+		total += credit.Amount.Amount
+	}
+	return total, nil
+}
+
+// refundToStoreCredit refunds amount of the order's payment to the customer's store credit
+// and posts the refund to the ledger.
+func (d *paymentService) refundToStoreCredit(ctx context.Context, transaction *models.Transaction, previous int, amount int64, partial bool, reason string) (*models.Refund, error) {
+	refund := &models.Refund{
+		OrderId:   transaction.OrderId,
+		PaymentId: transaction.PaymentId,
+		RefundId:  fmt.Sprintf("credit_%d_%d", transaction.OrderId, previous+1),
+		Amount:    amount,
+		Currency:  transaction.Currency,
+		IsPartial: partial,
+		Reason:    reason,
+		Status:    models.RefundSucceeded,
+		Method:    models.RefundToStoreCredit,
+	}
+	err := d.paymentRepository.SaveRefund(ctx, refund)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.paymentRepository.AddStoreCredit(ctx, &models.StoreCreditTransaction{
+		UserId:    transaction.UserId,
+		Kind:      models.StoreCreditRefund,
+		Amount:    money.New(amount, transaction.Currency),
+		OrderId:   transaction.OrderId,
+		Reference: models.StoreCreditRefund + ":" + refund.RefundId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, d.RecordRefund(ctx, refund)
+}
+
+// GetStoreCredit returns the account's store credit in every currency it holds some in.
+func (d *paymentService) GetStoreCredit(ctx context.Context, userId uint64) ([]*models.CreditBalance, error) {
+	return d.paymentRepository.GetCreditBalances(ctx, userId)
+}
+
+// ListStoreCreditTransactions returns the first of the account's store credit transactions
+// after the cursor, newest first. first defaults to DefaultPageSize and is capped at
+// MaxPageSize.
+func (d *paymentService) ListStoreCreditTransactions(ctx context.Context, userId uint64, after string, first int) (*models.StoreCreditPage, error) {
+	var afterID uint
+	if after != "" {
+		var err error
+		afterID, err = ordermodels.DecodeCursor(after)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+	first = min(first, MaxPageSize)
+
+	// Fetch one extra transaction to learn whether another page follows
+	transactions, err := d.paymentRepository.ListStoreCreditTransactions(ctx, userId, afterID, first+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.StoreCreditPage{Transactions: transactions}
+	if len(transactions) > first {
+		page.Transactions = transactions[:first]
+		page.HasNextPage = true
+	}
+	if len(page.Transactions) > 0 {
+		page.EndCursor = ordermodels.EncodeCursor(page.Transactions[len(page.Transactions)-1].ID)
+	}
+	return page, nil
+}
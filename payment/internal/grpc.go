@@ -2,9 +2,18 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"log"
+	"time"
 
 	order "github.com/rasadov/EcommerceAPI/order/client"
+	ordermodels "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/payment/models"
 	"github.com/rasadov/EcommerceAPI/payment/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	moneypb "github.com/rasadov/EcommerceAPI/pkg/money/proto/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
@@ -20,7 +29,20 @@ func (s *grpcServer) CreateCheckoutSession(ctx context.Context, request *pb.Chec
 		return nil, err
 	}
 
-	checkoutUrl, err := s.service.CreateCheckoutSession(ctx, request.UserId, customer.CustomerId, request.RedirectURL, request.Products, request.OrderId)
+	// The amount charged comes from the placed order rather than the request, so it cannot be forged
+	placedOrder, err := s.orderClient.GetOrder(ctx, request.OrderId, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if placedOrder.Status != ordermodels.StatusPendingPayment {
+		return nil, status.Errorf(codes.FailedPrecondition, "order %d is no longer awaiting payment", placedOrder.ID)
+	}
+
+	checkoutUrl, err := s.service.CreateCheckoutSession(ctx, request.UserId, customer.CustomerId, request.RedirectURL, request.Products, request.OrderId,
+		placedOrder.TotalPrice)
+	if errors.Is(err, ErrNothingDue) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -46,3 +68,306 @@ func (s *grpcServer) CreateCustomerPortalSession(ctx context.Context, request *p
 		Value: link,
 	}, nil
 }
+
+func (s *grpcServer) RefundPayment(ctx context.Context, request *pb.RefundPaymentRequest) (*pb.Refund, error) {
+	refund, err := s.service.RefundPayment(ctx, request.OrderId, request.Items, request.Reason, request.ToStoreCredit)
+	switch {
+	case errors.Is(err, ErrNoPayment), errors.Is(err, ErrAlreadyRefunded):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrInvalidRefund), errors.Is(err, ErrUnknownRefundItem), errors.Is(err, ErrCreditRefund),
+		errors.Is(err, ErrRefundAmountRequired), errors.Is(err, money.ErrCurrencyMismatch):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	// Store credit refunds succeed at once, with no webhook to report them
+	if refund.Method == models.RefundToStoreCredit && !refund.IsPartial {
+		_, err = s.orderClient.UpdateOrderStatus(ctx, refund.OrderId, ordermodels.StatusRefunded,
+			"payment", "refund "+refund.RefundId+" credited to store credit")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	response := &pb.Refund{
+		RefundId:  refund.RefundId,
+		OrderId:   refund.OrderId,
+		Amount:    money.New(refund.Amount, refund.Currency).ToProto(),
+		IsPartial: refund.IsPartial,
+		Reason:    refund.Reason,
+		Status:    refund.Status,
+		Method:    refund.Method,
+	}
+	response.CreatedAt, _ = refund.CreatedAt.MarshalBinary()
+	return response, nil
+}
+
+func (s *grpcServer) ExpireCheckoutSessions(ctx context.Context, request *pb.ExpireCheckoutSessionsRequest) (*pb.ExpireCheckoutSessionsResponse, error) {
+	expired, err := s.service.ExpireCheckoutSessions(ctx, request.OrderId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ExpireCheckoutSessionsResponse{Expired: uint32(expired)}, nil
+}
+
+// RenewCheckoutSession opens a fresh checkout session for an unpaid order whose checkout
+// was abandoned, and returns its URL.
+func (s *grpcServer) RenewCheckoutSession(ctx context.Context, request *pb.RenewCheckoutSessionRequest) (*wrapperspb.StringValue, error) {
+	placedOrder, err := s.orderClient.GetOrder(ctx, request.OrderId, 0)
+	if err != nil {
+		return nil, err
+	}
+	if placedOrder.Status != ordermodels.StatusPendingPayment {
+		return nil, status.Errorf(codes.FailedPrecondition, "order %d is no longer awaiting payment", placedOrder.ID)
+	}
+
+	checkoutUrl, err := s.service.RenewCheckoutSession(ctx, request.OrderId, placedOrder.TotalPrice)
+	switch {
+	case errors.Is(err, ErrNoCheckout):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrCheckoutNotAbandoned), errors.Is(err, ErrNothingDue):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, err
+	}
+	return &wrapperspb.StringValue{Value: checkoutUrl}, nil
+}
+
+func (s *grpcServer) GetSellerBalance(ctx context.Context, request *pb.SellerBalanceRequest) (*pb.SellerBalanceResponse, error) {
+	balances, err := s.service.GetSellerBalances(ctx, request.SellerId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.SellerBalanceResponse{Balances: []*pb.SellerBalance{}}
+	for _, balance := range balances {
+		response.Balances = append(response.Balances, &pb.SellerBalance{
+			Owed:    money.New(balance.Owed, balance.Currency).ToProto(),
+			PaidOut: money.New(balance.PaidOut, balance.Currency).ToProto(),
+		})
+	}
+	return response, nil
+}
+
+func (s *grpcServer) ListPayouts(ctx context.Context, request *pb.ListPayoutsRequest) (*pb.ListPayoutsResponse, error) {
+	page, err := s.service.ListPayouts(ctx, request.SellerId, request.After, int(request.First))
+	if errors.Is(err, ErrInvalidCursor) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.ListPayoutsResponse{
+		Payouts:     []*pb.Payout{},
+		EndCursor:   page.EndCursor,
+		HasNextPage: page.HasNextPage,
+	}
+	for _, payout := range page.Payouts {
+		encoded := &pb.Payout{
+			Id:         uint64(payout.ID),
+			SellerId:   payout.SellerId,
+			Sales:      money.New(payout.Sales, payout.Currency).ToProto(),
+			Commission: money.New(payout.Commission, payout.Currency).ToProto(),
+			Refunds:    money.New(payout.Refunds, payout.Currency).ToProto(),
+			Amount:     money.New(payout.Amount, payout.Currency).ToProto(),
+		}
+		encoded.PeriodStart, _ = payout.PeriodStart.MarshalBinary()
+		encoded.PeriodEnd, _ = payout.PeriodEnd.MarshalBinary()
+		encoded.CreatedAt, _ = payout.CreatedAt.MarshalBinary()
+		response.Payouts = append(response.Payouts, encoded)
+	}
+	return response, nil
+}
+
+func (s *grpcServer) IssueGiftCard(ctx context.Context, request *pb.IssueGiftCardRequest) (*pb.GiftCard, error) {
+	card := &models.GiftCard{
+		Code:     request.Code,
+		Initial:  money.FromProto(request.Amount),
+		IssuedBy: request.IssuedBy,
+		Note:     request.Note,
+	}
+	if len(request.ExpiresAt) > 0 {
+		var expiresAt time.Time
+		if err := expiresAt.UnmarshalBinary(request.ExpiresAt); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		card.ExpiresAt = &expiresAt
+	}
+
+	card, err := s.service.IssueGiftCard(ctx, card)
+	switch {
+	case errors.Is(err, models.ErrInvalidGiftCard), errors.Is(err, money.ErrUnknownCurrency):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, err
+	}
+	return encodeGiftCard(card), nil
+}
+
+func (s *grpcServer) GetGiftCard(ctx context.Context, request *pb.GetGiftCardRequest) (*pb.GiftCard, error) {
+	card, err := s.service.GetGiftCard(ctx, request.Code, request.UserId)
+	if errors.Is(err, ErrGiftCardNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encodeGiftCard(card), nil
+}
+
+func (s *grpcServer) PurchaseGiftCard(ctx context.Context, request *pb.PurchaseGiftCardRequest) (*wrapperspb.StringValue, error) {
+	customer, err := s.service.FindOrCreateCustomer(ctx, request.UserId, request.Email, request.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	checkoutUrl, err := s.service.PurchaseGiftCard(ctx, request.UserId, customer.CustomerId, request.RedirectURL,
+		money.FromProto(request.Amount))
+	switch {
+	case errors.Is(err, models.ErrInvalidGiftCard), errors.Is(err, money.ErrUnknownCurrency):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, err
+	}
+	return &wrapperspb.StringValue{
+		Value: checkoutUrl,
+	}, nil
+}
+
+func (s *grpcServer) ListGiftCards(ctx context.Context, request *pb.ListGiftCardsRequest) (*pb.ListGiftCardsResponse, error) {
+	cards, err := s.service.ListGiftCards(ctx, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+	response := &pb.ListGiftCardsResponse{}
+	for _, card := range cards {
+		response.GiftCards = append(response.GiftCards, encodeGiftCard(card))
+	}
+	return response, nil
+}
+
+func encodeGiftCard(card *models.GiftCard) *pb.GiftCard {
+	encoded := &pb.GiftCard{
+		Id:       uint64(card.ID),
+		Code:     card.Code,
+		Initial:  card.Initial.ToProto(),
+		Balance:  card.Balance.ToProto(),
+		IssuedBy: card.IssuedBy,
+		Note:     card.Note,
+		OwnerId:  card.OwnerId,
+	}
+	if card.ExpiresAt != nil {
+		encoded.ExpiresAt, _ = card.ExpiresAt.MarshalBinary()
+	}
+	encoded.CreatedAt, _ = card.CreatedAt.MarshalBinary()
+	return encoded
+}
+
+// ApplyCredit puts a gift card and store credit towards the total of an unpaid order of
+// the user, which comes from the placed order so it cannot be forged.
+func (s *grpcServer) ApplyCredit(ctx context.Context, request *pb.ApplyCreditRequest) (*pb.ApplyCreditResponse, error) {
+	placedOrder, err := s.orderClient.GetOrder(ctx, request.OrderId, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if placedOrder.Status != ordermodels.StatusPendingPayment {
+		return nil, status.Errorf(codes.FailedPrecondition, "order %d is no longer awaiting payment", placedOrder.ID)
+	}
+
+	applied, err := s.service.ApplyCredit(ctx, request.OrderId, request.UserId, placedOrder.TotalPrice,
+		request.GiftCardCode, request.UseStoreCredit)
+	switch {
+	case errors.Is(err, ErrGiftCardNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, models.ErrGiftCardExpired), errors.Is(err, models.ErrGiftCardEmpty), errors.Is(err, ErrCreditApplied):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, money.ErrCurrencyMismatch):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	credit := money.Zero(placedOrder.TotalPrice.Currency)
+	for _, orderCredit := range applied.Credits {
+		credit, err = credit.Add(orderCredit.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &pb.ApplyCreditResponse{
+		Applied:   credit.ToProto(),
+		Remaining: applied.Remaining.ToProto(),
+	}, nil
+}
+
+// CaptureCredit takes the payment of an unpaid order its credit pays for in full and marks
+// the order paid. Should the order not take the payment, it is refunded to store credit.
+func (s *grpcServer) CaptureCredit(ctx context.Context, request *pb.CaptureCreditRequest) (*pb.CaptureCreditResponse, error) {
+	placedOrder, err := s.orderClient.GetOrder(ctx, request.OrderId, 0)
+	if err != nil {
+		return nil, err
+	}
+	if placedOrder.Status != ordermodels.StatusPendingPayment {
+		return nil, status.Errorf(codes.FailedPrecondition, "order %d is no longer awaiting payment", placedOrder.ID)
+	}
+	shares, err := paymentShares(placedOrder, placedOrder.TotalPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.service.CaptureCredit(ctx, request.OrderId, placedOrder.AccountID, placedOrder.TotalPrice, shares)
+	switch {
+	case errors.Is(err, ErrOrderNotCovered), errors.Is(err, money.ErrCurrencyMismatch):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	_, err = s.orderClient.UpdateOrderStatus(ctx, request.OrderId, ordermodels.StatusPaid,
+		"payment", "payment "+transaction.PaymentId+" succeeded")
+	if err != nil {
+		_, refundErr := s.service.RefundPayment(ctx, request.OrderId, nil, "order could not be paid", true)
+		if refundErr != nil {
+			log.Println("Failed to refund payment", transaction.PaymentId, "of unpayable order:", refundErr)
+		}
+		return nil, err
+	}
+	return &pb.CaptureCreditResponse{PaymentId: transaction.PaymentId}, nil
+}
+
+func (s *grpcServer) GetStoreCredit(ctx context.Context, request *pb.StoreCreditRequest) (*pb.StoreCreditResponse, error) {
+	balances, err := s.service.GetStoreCredit(ctx, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.service.ListStoreCreditTransactions(ctx, request.UserId, request.After, int(request.First))
+	if errors.Is(err, ErrInvalidCursor) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.StoreCreditResponse{
+		Balances:     []*moneypb.Money{},
+		Transactions: []*pb.StoreCreditTransaction{},
+		EndCursor:    page.EndCursor,
+		HasNextPage:  page.HasNextPage,
+	}
+	for _, balance := range balances {
+		response.Balances = append(response.Balances, money.New(balance.Amount, balance.Currency).ToProto())
+	}
+	for _, transaction := range page.Transactions {
+		encoded := &pb.StoreCreditTransaction{
+			Id:      uint64(transaction.ID),
+			Kind:    transaction.Kind,
+			Amount:  transaction.Amount.ToProto(),
+			OrderId: transaction.OrderId,
+		}
+		encoded.CreatedAt, _ = transaction.CreatedAt.MarshalBinary()
+		response.Transactions = append(response.Transactions, encoded)
+	}
+	return response, nil
+}
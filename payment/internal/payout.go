@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"log"
+	"time"
+
+	ordermodels "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/payment/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// PayoutScheduler pays sellers out what the ledger owes them once every period. Replicas
+// running it side by side never pay the same entries out twice.
+type PayoutScheduler struct {
+	service Service
+	period  time.Duration
+}
+
+func NewPayoutScheduler(service Service, period time.Duration) *PayoutScheduler {
+	return &PayoutScheduler{service, period}
+}
+
+// Run creates payouts at the end of every period until ctx is done. The first period
+// starts when Run is called, so restarts do not pay out early.
+func (scheduler *PayoutScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(scheduler.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		payouts, err := scheduler.service.CreatePayouts(ctx, time.Now().UTC())
+		if err != nil {
+			log.Println("Failed to create seller payouts:", err)
+			continue
+		}
+		for _, payout := range payouts {
+			log.Printf("Paying seller %d out %s", payout.SellerId, money.New(payout.Amount, payout.Currency))
+		}
+	}
+}
+
+// paymentShares divides the amount paid for the order between the sellers of its
+// sub-orders, in proportion to the sub-order totals. Orders placed before they were split
+// by seller, and free ones, are the marketplace's own sales.
+func paymentShares(order *ordermodels.Order, paid money.Money) ([]models.SellerShare, error) {
+	weights := make([]int64, len(order.SubOrders))
+	var total int64
+	for i, sub := range order.SubOrders {
+		weights[i] = sub.Total.Amount
+		total += sub.Total.Amount
+	}
+	if total == 0 {
+		return []models.SellerShare{{Amount: paid}}, nil
+	}
+	amounts, err := paid.Allocate(weights...)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]models.SellerShare, len(order.SubOrders))
+	for i, sub := range order.SubOrders {
+		shares[i] = models.SellerShare{SellerId: sub.SellerID, Amount: amounts[i]}
+	}
+	return shares, nil
+}
@@ -2,10 +2,21 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/rasadov/EcommerceAPI/payment/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrJournalExists     = errors.New("journal was already posted")
+	ErrCreditApplied     = errors.New("credit was already applied to the order")
+	ErrStoreCreditExists = errors.New("store credit transaction was already applied")
 )
 
 type Repository interface {
@@ -20,9 +31,41 @@ type Repository interface {
 	SaveProduct(ctx context.Context, product *models.Product) error
 	UpdateProduct(ctx context.Context, product *models.Product) error
 	DeleteProduct(ctx context.Context, productId string) error
+	GetChargeProduct(ctx context.Context, currency string) (*models.ChargeProduct, error)
+	SaveChargeProduct(ctx context.Context, product *models.ChargeProduct) error
 
 	RegisterTransaction(ctx context.Context, transaction *models.Transaction) error
 	UpdateTransaction(ctx context.Context, transaction *models.Transaction) error
+	GetSuccessfulTransaction(ctx context.Context, orderId uint64) (*models.Transaction, error)
+
+	SaveRefund(ctx context.Context, refund *models.Refund) error
+	GetRefundsForOrder(ctx context.Context, orderId uint64) ([]*models.Refund, error)
+	UpdateRefundStatus(ctx context.Context, refundId, status string) (*models.Refund, error)
+
+	SaveCheckoutSession(ctx context.Context, session *models.CheckoutSession) error
+	GetLatestCheckoutSession(ctx context.Context, orderId uint64) (*models.CheckoutSession, error)
+	ExpireCheckoutSessions(ctx context.Context, orderId uint64) (int64, error)
+	CompleteCheckoutSessions(ctx context.Context, orderId uint64) (int64, error)
+	ClaimAbandonedCheckouts(ctx context.Context, openedBefore time.Time, limit int) ([]*models.CheckoutSession, error)
+
+	PostJournal(ctx context.Context, journal *models.Journal) error
+	GetJournal(ctx context.Context, reference string) (*models.Journal, error)
+	CreatePayouts(ctx context.Context, periodEnd time.Time) ([]*models.Payout, error)
+	GetSellerBalances(ctx context.Context, sellerId uint64) ([]*models.SellerBalance, error)
+	ListPayouts(ctx context.Context, sellerId uint64, afterID uint, limit int) ([]*models.Payout, error)
+
+	SaveGiftCard(ctx context.Context, card *models.GiftCard) error
+	GetGiftCardByCode(ctx context.Context, code string) (*models.GiftCard, error)
+	ListGiftCardsForOwner(ctx context.Context, ownerId uint64) ([]*models.GiftCard, error)
+	IsGiftCardRedeemer(ctx context.Context, giftCardId uint, userId uint64) (bool, error)
+	SaveGiftCardPurchase(ctx context.Context, purchase *models.GiftCardPurchase) error
+	CompleteGiftCardPurchase(ctx context.Context, purchaseId uint, paymentId string, card *models.GiftCard) (*models.GiftCard, error)
+	ApplyCredit(ctx context.Context, orderId, userId uint64, total money.Money, giftCardCode string, useStoreCredit bool, now time.Time) (*models.AppliedCredit, error)
+	GetOrderCredits(ctx context.Context, orderId uint64) ([]*models.OrderCredit, error)
+	ReleaseOrderCredits(ctx context.Context, orderId uint64) (int64, error)
+	AddStoreCredit(ctx context.Context, transaction *models.StoreCreditTransaction) error
+	GetCreditBalances(ctx context.Context, userId uint64) ([]*models.CreditBalance, error)
+	ListStoreCreditTransactions(ctx context.Context, userId uint64, afterID uint, limit int) ([]*models.StoreCreditTransaction, error)
 }
 
 type postgresRepository struct {
@@ -35,7 +78,7 @@ func NewPostgresRepository(db *gorm.DB) (Repository, error) {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&models.Product{})
+	err = db.AutoMigrate(&models.Product{}, &models.ChargeProduct{})
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +88,26 @@ func NewPostgresRepository(db *gorm.DB) (Repository, error) {
 		return nil, err
 	}
 
+	err = db.AutoMigrate(&models.Refund{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&models.CheckoutSession{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&models.Journal{}, &models.LedgerEntry{}, &models.Payout{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&models.GiftCard{}, &models.GiftCardPurchase{}, &models.OrderCredit{}, &models.CreditBalance{}, &models.StoreCreditTransaction{})
+	if err != nil {
+		return nil, err
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
@@ -121,6 +184,20 @@ func (repository *postgresRepository) DeleteProduct(ctx context.Context, product
 	return repository.db.WithContext(ctx).Delete(&models.Product{}, "product_id = ?", productId).Error
 }
 
+func (repository *postgresRepository) GetChargeProduct(ctx context.Context, currency string) (*models.ChargeProduct, error) {
+	var product models.ChargeProduct
+	err := repository.db.WithContext(ctx).First(&product, "currency = ?", currency).Error
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// SaveChargeProduct saves the charge product of its currency unless one was saved already.
+func (repository *postgresRepository) SaveChargeProduct(ctx context.Context, product *models.ChargeProduct) error {
+	return repository.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(product).Error
+}
+
 func (repository *postgresRepository) RegisterTransaction(ctx context.Context, transaction *models.Transaction) error {
 	return repository.db.WithContext(ctx).Create(&transaction).Error
 }
@@ -128,3 +205,562 @@ func (repository *postgresRepository) RegisterTransaction(ctx context.Context, t
 func (repository *postgresRepository) UpdateTransaction(ctx context.Context, transaction *models.Transaction) error {
 	return repository.db.WithContext(ctx).Save(&transaction).Error
 }
+
+// GetSuccessfulTransaction returns the latest successful payment for the order.
+func (repository *postgresRepository) GetSuccessfulTransaction(ctx context.Context, orderId uint64) (*models.Transaction, error) {
+	var transaction models.Transaction
+	err := repository.db.WithContext(ctx).
+		Where("order_id = ? AND status = ?", orderId, models.Success.String()).
+		Order("created_at DESC").
+		First(&transaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func (repository *postgresRepository) SaveRefund(ctx context.Context, refund *models.Refund) error {
+	return repository.db.WithContext(ctx).Create(&refund).Error
+}
+
+func (repository *postgresRepository) GetRefundsForOrder(ctx context.Context, orderId uint64) ([]*models.Refund, error) {
+	var refunds []*models.Refund
+	err := repository.db.WithContext(ctx).
+		Where("order_id = ?", orderId).
+		Order("created_at").
+		Find(&refunds).Error
+	if err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// UpdateRefundStatus sets the status of a refund and returns the stored refund.
+func (repository *postgresRepository) UpdateRefundStatus(ctx context.Context, refundId, status string) (*models.Refund, error) {
+	var refund models.Refund
+	err := repository.db.WithContext(ctx).First(&refund, "refund_id = ?", refundId).Error
+	if err != nil {
+		return nil, err
+	}
+
+	refund.Status = status
+	err = repository.db.WithContext(ctx).Model(&refund).Update("status", status).Error
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (repository *postgresRepository) SaveCheckoutSession(ctx context.Context, session *models.CheckoutSession) error {
+	return repository.db.WithContext(ctx).Create(session).Error
+}
+
+// GetLatestCheckoutSession returns the checkout session last opened for the order.
+func (repository *postgresRepository) GetLatestCheckoutSession(ctx context.Context, orderId uint64) (*models.CheckoutSession, error) {
+	var session models.CheckoutSession
+	err := repository.db.WithContext(ctx).Where("order_id = ?", orderId).Order("id DESC").First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ExpireCheckoutSessions marks the order's open and abandoned checkout sessions expired and
+// returns how many were.
+func (repository *postgresRepository) ExpireCheckoutSessions(ctx context.Context, orderId uint64) (int64, error) {
+	result := repository.db.WithContext(ctx).Model(&models.CheckoutSession{}).
+		Where("order_id = ? AND status IN ?", orderId, []string{models.CheckoutOpen, models.CheckoutAbandoned}).
+		Update("status", models.CheckoutExpired)
+	return result.RowsAffected, result.Error
+}
+
+// CompleteCheckoutSessions marks the checkout sessions of the paid order completed, whichever
+// of them it was paid through, and returns how many were.
+func (repository *postgresRepository) CompleteCheckoutSessions(ctx context.Context, orderId uint64) (int64, error) {
+	result := repository.db.WithContext(ctx).Model(&models.CheckoutSession{}).
+		Where("order_id = ? AND status IN ?", orderId, []string{models.CheckoutOpen, models.CheckoutAbandoned}).
+		Update("status", models.CheckoutCompleted)
+	return result.RowsAffected, result.Error
+}
+
+// ClaimAbandonedCheckouts marks up to limit checkout sessions still open since before
+// openedBefore abandoned and returns them, oldest first. Sessions opened by a reminder are
+// left open. Replicas claiming side by side never claim the same session twice.
+func (repository *postgresRepository) ClaimAbandonedCheckouts(ctx context.Context, openedBefore time.Time, limit int) ([]*models.CheckoutSession, error) {
+	var sessions []*models.CheckoutSession
+	err := repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND reminder = ? AND created_at < ?", models.CheckoutOpen, false, openedBefore).
+			Order("id").
+			Limit(limit).
+			Find(&sessions).Error
+		if err != nil || len(sessions) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(sessions))
+		for i, session := range sessions {
+			ids[i] = session.ID
+			session.Status = models.CheckoutAbandoned
+		}
+		return tx.Model(&models.CheckoutSession{}).Where("id IN ?", ids).Update("status", models.CheckoutAbandoned).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// PostJournal posts the balanced journal with its entries, or returns ErrJournalExists when
+// its reference was posted before.
+func (repository *postgresRepository) PostJournal(ctx context.Context, journal *models.Journal) error {
+	err := journal.Balanced()
+	if err != nil {
+		return err
+	}
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&models.Journal{}).Where("reference = ?", journal.Reference).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrJournalExists
+		}
+		return tx.Create(journal).Error
+	})
+}
+
+// GetJournal returns the journal posted under reference with its entries.
+func (repository *postgresRepository) GetJournal(ctx context.Context, reference string) (*models.Journal, error) {
+	var journal models.Journal
+	err := repository.db.WithContext(ctx).Preload("Entries").First(&journal, "reference = ?", reference).Error
+	if err != nil {
+		return nil, err
+	}
+	return &journal, nil
+}
+
+// CreatePayouts pays every seller out what their payable account owes them for entries
+// posted before periodEnd, one payout per seller and currency. Balances that refunds have
+// left at or below zero are carried over to the next period.
+func (repository *postgresRepository) CreatePayouts(ctx context.Context, periodEnd time.Time) ([]*models.Payout, error) {
+	var payouts []*models.Payout
+	err := repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var entries []*models.LedgerEntry
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("account = ? AND payout_id = 0 AND created_at < ?", models.AccountSellerPayable, periodEnd).
+			Order("seller_id, currency, id").
+			Find(&entries).Error
+		if err != nil {
+			return err
+		}
+
+		for start := 0; start < len(entries); {
+			end := start
+			for end < len(entries) && entries[end].SellerId == entries[start].SellerId && entries[end].Currency == entries[start].Currency {
+				end++
+			}
+			group := entries[start:end]
+			start = end
+
+			payout := models.NewPayout(group[0].SellerId, group[0].Currency, group, periodEnd)
+			if payout.Amount <= 0 {
+				continue
+			}
+			err = tx.Create(payout).Error
+			if err != nil {
+				return err
+			}
+			journal := models.PayoutJournal(payout)
+			journal.Reference = fmt.Sprintf("%s:%d", models.JournalPayout, payout.ID)
+			err = tx.Create(journal).Error
+			if err != nil {
+				return err
+			}
+
+			ids := []uint{journal.Entries[0].ID}
+			for _, entry := range group {
+				ids = append(ids, entry.ID)
+			}
+			err = tx.Model(&models.LedgerEntry{}).Where("id IN ?", ids).Update("payout_id", payout.ID).Error
+			if err != nil {
+				return err
+			}
+			payouts = append(payouts, payout)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payouts, nil
+}
+
+// GetSellerBalances returns what is owed to the seller and was paid out to them, per currency.
+func (repository *postgresRepository) GetSellerBalances(ctx context.Context, sellerId uint64) ([]*models.SellerBalance, error) {
+	var owed []struct {
+		Currency string
+		Amount   int64
+	}
+	err := repository.db.WithContext(ctx).Model(&models.LedgerEntry{}).
+		Select("currency, -SUM(amount) AS amount").
+		Where("account = ? AND seller_id = ? AND payout_id = 0", models.AccountSellerPayable, sellerId).
+		Group("currency").
+		Scan(&owed).Error
+	if err != nil {
+		return nil, err
+	}
+	var paidOut []struct {
+		Currency string
+		Amount   int64
+	}
+	err = repository.db.WithContext(ctx).Model(&models.Payout{}).
+		Select("currency, SUM(amount) AS amount").
+		Where("seller_id = ?", sellerId).
+		Group("currency").
+		Scan(&paidOut).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byCurrency := map[string]*models.SellerBalance{}
+	var balances []*models.SellerBalance
+	balance := func(currency string) *models.SellerBalance {
+		b, ok := byCurrency[currency]
+		if !ok {
+			b = &models.SellerBalance{SellerId: sellerId, Currency: currency}
+			byCurrency[currency] = b
+			balances = append(balances, b)
+		}
+		return b
+	}
+	for _, row := range owed {
+		balance(row.Currency).Owed = row.Amount
+	}
+	for _, row := range paidOut {
+		balance(row.Currency).PaidOut = row.Amount
+	}
+	return balances, nil
+}
+
+// ListPayouts returns up to limit of the seller's payouts with an ID below afterID, newest
+// first. An afterID of 0 starts from the newest payout.
+func (repository *postgresRepository) ListPayouts(ctx context.Context, sellerId uint64, afterID uint, limit int) ([]*models.Payout, error) {
+	query := repository.db.WithContext(ctx).Where("seller_id = ?", sellerId)
+	if afterID > 0 {
+		query = query.Where("id < ?", afterID)
+	}
+	var payouts []*models.Payout
+	err := query.Order("id DESC").Limit(limit).Find(&payouts).Error
+	if err != nil {
+		return nil, err
+	}
+	return payouts, nil
+}
+
+func (repository *postgresRepository) SaveGiftCard(ctx context.Context, card *models.GiftCard) error {
+	return repository.db.WithContext(ctx).Create(card).Error
+}
+
+func (repository *postgresRepository) GetGiftCardByCode(ctx context.Context, code string) (*models.GiftCard, error) {
+	var card models.GiftCard
+	err := repository.db.WithContext(ctx).First(&card, "code = ?", code).Error
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// ListGiftCardsForOwner returns the gift cards the account bought, newest first.
+func (repository *postgresRepository) ListGiftCardsForOwner(ctx context.Context, ownerId uint64) ([]*models.GiftCard, error) {
+	var cards []*models.GiftCard
+	err := repository.db.WithContext(ctx).Where("owner_id = ?", ownerId).Order("id DESC").Find(&cards).Error
+	if err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// IsGiftCardRedeemer reports whether the account has put the gift card towards an order.
+func (repository *postgresRepository) IsGiftCardRedeemer(ctx context.Context, giftCardId uint, userId uint64) (bool, error) {
+	var count int64
+	err := repository.db.WithContext(ctx).Model(&models.OrderCredit{}).
+		Where("gift_card_id = ? AND user_id = ?", giftCardId, userId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (repository *postgresRepository) SaveGiftCardPurchase(ctx context.Context, purchase *models.GiftCardPurchase) error {
+	return repository.db.WithContext(ctx).Save(purchase).Error
+}
+
+// CompleteGiftCardPurchase records the purchase paid by paymentId and saves card, issued
+// for it to the buyer for the amount bought. A purchase is completed once; completing it
+// again returns the card issued the first time.
+func (repository *postgresRepository) CompleteGiftCardPurchase(ctx context.Context, purchaseId uint, paymentId string, card *models.GiftCard) (*models.GiftCard, error) {
+	err := repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var purchase models.GiftCardPurchase
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&purchase, purchaseId).Error
+		if err != nil {
+			return err
+		}
+		if purchase.Status == models.PurchasePaid {
+			*card = models.GiftCard{}
+			return tx.First(card, purchase.GiftCardId).Error
+		}
+
+		card.Initial = purchase.Amount
+		card.Balance = purchase.Amount
+		card.OwnerId = purchase.UserId
+		err = tx.Create(card).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&purchase).Updates(map[string]any{
+			"status":       models.PurchasePaid,
+			"payment_id":   paymentId,
+			"gift_card_id": card.ID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// ApplyCredit puts the gift card with giftCardCode, when given, and then the account's store
+// credit, when useStoreCredit is set, towards the order's total, each as far as it goes.
+// Credit can only be applied once to an order, until it is released.
+func (repository *postgresRepository) ApplyCredit(ctx context.Context, orderId, userId uint64, total money.Money, giftCardCode string, useStoreCredit bool, now time.Time) (*models.AppliedCredit, error) {
+	applied := &models.AppliedCredit{Remaining: total}
+	err := repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		alreadyApplied, err := creditApplied(tx, orderId)
+		if err != nil {
+			return err
+		}
+		if alreadyApplied {
+			return ErrCreditApplied
+		}
+
+		if giftCardCode != "" {
+			var card models.GiftCard
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&card, "code = ?", giftCardCode).Error
+			if err != nil {
+				return err
+			}
+			if err = card.Usable(now); err != nil {
+				return err
+			}
+			if card.Balance.Currency != total.Currency {
+				return money.ErrCurrencyMismatch
+			}
+			amount := min(card.Balance.Amount, applied.Remaining.Amount)
+			if amount > 0 {
+				err = tx.Model(&models.GiftCard{}).Where("id = ?", card.ID).
+					Update("balance_amount", gorm.Expr("balance_amount - ?", amount)).Error
+				if err != nil {
+					return err
+				}
+				err = applyOrderCredit(tx, applied, &models.OrderCredit{
+					OrderId:    orderId,
+					UserId:     userId,
+					Kind:       models.CreditGiftCard,
+					GiftCardId: card.ID,
+					Amount:     money.New(amount, total.Currency),
+					CreatedAt:  now,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if useStoreCredit && applied.Remaining.Amount > 0 {
+			var balance models.CreditBalance
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("user_id = ? AND currency = ?", userId, total.Currency).
+				Limit(1).Find(&balance).Error
+			if err != nil {
+				return err
+			}
+			amount := min(balance.Amount, applied.Remaining.Amount)
+			if amount > 0 {
+				credit := &models.OrderCredit{
+					OrderId:   orderId,
+					UserId:    userId,
+					Kind:      models.CreditStoreCredit,
+					Amount:    money.New(amount, total.Currency),
+					CreatedAt: now,
+				}
+				err = applyOrderCredit(tx, applied, credit)
+				if err != nil {
+					return err
+				}
+				err = addStoreCredit(tx, &models.StoreCreditTransaction{
+					UserId:    userId,
+					Kind:      models.StoreCreditSpent,
+					Amount:    money.New(-amount, total.Currency),
+					OrderId:   orderId,
+					Reference: fmt.Sprintf("%s:credit:%d", models.StoreCreditSpent, credit.ID),
+					CreatedAt: now,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// A concurrent application may have taken the order's first credit number first
+		if alreadyApplied, countErr := creditApplied(repository.db.WithContext(ctx), orderId); countErr == nil && alreadyApplied {
+			return nil, ErrCreditApplied
+		}
+		return nil, err
+	}
+	return applied, nil
+}
+
+// creditApplied reports whether credit that was not released was put towards the order.
+func creditApplied(db *gorm.DB, orderId uint64) (bool, error) {
+	var count int64
+	err := db.Model(&models.OrderCredit{}).Where("order_id = ? AND released = ?", orderId, false).Count(&count).Error
+	return count > 0, err
+}
+
+func applyOrderCredit(tx *gorm.DB, applied *models.AppliedCredit, credit *models.OrderCredit) error {
+	credit.Seq = len(applied.Credits) + 1
+	err := tx.Create(credit).Error
+	if err != nil {
+		return err
+	}
+	applied.Credits = append(applied.Credits, credit)
+	applied.Remaining.Amount -= credit.Amount.Amount
+	return nil
+}
+
+// GetOrderCredits returns the credit put towards the order that was not released.
+func (repository *postgresRepository) GetOrderCredits(ctx context.Context, orderId uint64) ([]*models.OrderCredit, error) {
+	var credits []*models.OrderCredit
+	err := repository.db.WithContext(ctx).
+		Where("order_id = ? AND released = ?", orderId, false).
+		Order("id").
+		Find(&credits).Error
+	if err != nil {
+		return nil, err
+	}
+	return credits, nil
+}
+
+// ReleaseOrderCredits gives the credit put towards the order back to the gift cards and
+// store credit it came from, and returns how much credit was released.
+func (repository *postgresRepository) ReleaseOrderCredits(ctx context.Context, orderId uint64) (int64, error) {
+	var released int64
+	err := repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var credits []*models.OrderCredit
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND released = ?", orderId, false).
+			Find(&credits).Error
+		if err != nil || len(credits) == 0 {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for _, credit := range credits {
+			switch credit.Kind {
+			case models.CreditGiftCard:
+				err = tx.Model(&models.GiftCard{}).Where("id = ?", credit.GiftCardId).
+					Update("balance_amount", gorm.Expr("balance_amount + ?", credit.Amount.Amount)).Error
+			case models.CreditStoreCredit:
+				err = addStoreCredit(tx, &models.StoreCreditTransaction{
+					UserId:    credit.UserId,
+					Kind:      models.StoreCreditReleased,
+					Amount:    credit.Amount,
+					OrderId:   credit.OrderId,
+					Reference: fmt.Sprintf("%s:credit:%d", models.StoreCreditReleased, credit.ID),
+					CreatedAt: now,
+				})
+			}
+			if err != nil {
+				return err
+			}
+			err = tx.Model(credit).Update("released", true).Error
+			if err != nil {
+				return err
+			}
+			released += credit.Amount.Amount
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return released, nil
+}
+
+// AddStoreCredit applies the transaction to the account's store credit, or returns
+// ErrStoreCreditExists when its reference was applied before.
+func (repository *postgresRepository) AddStoreCredit(ctx context.Context, transaction *models.StoreCreditTransaction) error {
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return addStoreCredit(tx, transaction)
+	})
+}
+
+func addStoreCredit(tx *gorm.DB, transaction *models.StoreCreditTransaction) error {
+	var count int64
+	err := tx.Model(&models.StoreCreditTransaction{}).Where("reference = ?", transaction.Reference).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrStoreCreditExists
+	}
+	if transaction.CreatedAt.IsZero() {
+		transaction.CreatedAt = time.Now().UTC()
+	}
+	err = tx.Create(transaction).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "currency"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"amount":     gorm.Expr("credit_balances.amount + ?", transaction.Amount.Amount),
+			"updated_at": transaction.CreatedAt,
+		}),
+	}).Create(&models.CreditBalance{
+		UserId:    transaction.UserId,
+		Currency:  transaction.Amount.Currency,
+		Amount:    transaction.Amount.Amount,
+		UpdatedAt: transaction.CreatedAt,
+	}).Error
+}
+
+func (repository *postgresRepository) GetCreditBalances(ctx context.Context, userId uint64) ([]*models.CreditBalance, error) {
+	var balances []*models.CreditBalance
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ? AND amount <> 0", userId).
+		Order("currency").
+		Find(&balances).Error
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// ListStoreCreditTransactions returns up to limit of the account's store credit
+// transactions before afterID, newest first.
+func (repository *postgresRepository) ListStoreCreditTransactions(ctx context.Context, userId uint64, afterID uint, limit int) ([]*models.StoreCreditTransaction, error) {
+	query := repository.db.WithContext(ctx).Where("user_id = ?", userId)
+	if afterID > 0 {
+		query = query.Where("id < ?", afterID)
+	}
+	var transactions []*models.StoreCreditTransaction
+	err := query.Order("id DESC").Limit(limit).Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
@@ -27,7 +27,9 @@ type PaymentClient interface {
 		customerId, productId string) (*dodopayments.Product, error)
 	UpdateProduct(ctx context.Context,
 		productId string,
-		name string, price int64) error
+		name string, price int64,
+		currency dodopayments.Currency,
+		taxCategory dodopayments.TaxCategory) error
 	ArchiveProduct(ctx context.Context, productId string) error
 
 	CreateCustomer(ctx context.Context, userId uint64, email, name string) (*models.Customer, error)
@@ -36,9 +38,16 @@ type PaymentClient interface {
 	CreateCheckoutSession(ctx context.Context,
 		userId uint64,
 		customerId string, redirect string,
-		dodoProducts []dodopayments.CheckoutSessionRequestProductCartParam, orderId uint64) (checkoutURL string, err error)
+		dodoProducts []dodopayments.CheckoutSessionRequestProductCartParam, metadata map[string]string,
+		currency dodopayments.Currency) (checkoutURL, sessionID string, err error)
+	CreateChargeProduct(ctx context.Context, currency dodopayments.Currency) (*dodopayments.Product, error)
+
+	CreateRefund(ctx context.Context,
+		paymentId string,
+		items []dodopayments.RefundNewParamsItem,
+		reason string) (*models.Refund, error)
 
-	HandleWebhook(w http.ResponseWriter, r *http.Request) (*models.Transaction, error)
+	HandleWebhook(w http.ResponseWriter, r *http.Request) (*models.WebhookEvent, error)
 }
 
 func NewDodoClient(apiKey string, testMode bool) PaymentClient {
@@ -106,14 +115,17 @@ func (d *dodoClient) CreateCustomer(ctx context.Context, userId uint64, email, n
 
 func (d *dodoClient) UpdateProduct(ctx context.Context,
 	productId string,
-	name string, price int64) error {
+	name string, price int64,
+	currency dodopayments.Currency,
+	taxCategory dodopayments.TaxCategory) error {
 
 	return d.client.Products.Update(ctx, productId, dodopayments.ProductUpdateParams{
-		Name: dodopayments.F(name),
+		Name:        dodopayments.F(name),
+		TaxCategory: dodopayments.F(taxCategory),
 		Price: dodopayments.F[dodopayments.PriceUnionParam](
 			dodopayments.PriceOneTimePriceParam{
 				Price:    dodopayments.F(price),
-				Currency: dodopayments.F(dodopayments.CurrencyUsd),
+				Currency: dodopayments.F(currency),
 				Discount: dodopayments.F[int64](0),
 			},
 		),
@@ -124,32 +136,60 @@ func (d *dodoClient) ArchiveProduct(ctx context.Context, productId string) error
 	return d.client.Products.Archive(ctx, productId)
 }
 
+// CreateCheckoutSession opens a checkout for the cart. The metadata says what the checkout
+// pays for and comes back with its payment webhooks, along with the user.
 func (d *dodoClient) CreateCheckoutSession(ctx context.Context,
 	userId uint64,
 	customerId string, redirect string,
-	dodoProducts []dodopayments.CheckoutSessionRequestProductCartParam, orderId uint64) (checkoutURL string, err error) {
+	dodoProducts []dodopayments.CheckoutSessionRequestProductCartParam, metadata map[string]string,
+	currency dodopayments.Currency) (checkoutURL, sessionID string, err error) {
+
+	sessionMetadata := map[string]string{"user_id": fmt.Sprintf("%d", userId)}
+	for key, value := range metadata {
+		sessionMetadata[key] = value
+	}
+
+	request := dodopayments.CheckoutSessionRequestParam{
+		Customer: dodopayments.F[dodopayments.CustomerRequestUnionParam](
+			dodopayments.AttachExistingCustomerParam{
+				CustomerID: dodopayments.F(customerId),
+			},
+		),
+		ReturnURL:       dodopayments.F(redirect),
+		ProductCart:     dodopayments.F(dodoProducts),
+		BillingCurrency: dodopayments.F(currency),
+		Metadata:        dodopayments.F(sessionMetadata),
+	}
 
 	checkoutSession, err := d.client.CheckoutSessions.New(ctx, dodopayments.CheckoutSessionNewParams{
-		CheckoutSessionRequest: dodopayments.CheckoutSessionRequestParam{
-			Customer: dodopayments.F[dodopayments.CustomerRequestUnionParam](
-				dodopayments.AttachExistingCustomerParam{
-					CustomerID: dodopayments.F(customerId),
-				},
-			),
-			ReturnURL:   dodopayments.F(redirect),
-			ProductCart: dodopayments.F(dodoProducts),
-			Metadata: dodopayments.F(map[string]string{
-				"order_id": fmt.Sprintf("%d", orderId),
-				"user_id":  fmt.Sprintf("%d", userId),
-			}),
-		},
+		CheckoutSessionRequest: request,
 	})
 
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return checkoutSession.CheckoutURL, nil
+	return checkoutSession.CheckoutURL, checkoutSession.SessionID, nil
+}
+
+// CreateChargeProduct creates the pay-what-you-want product that checkouts in currency
+// charge the amount due through. Its price is the minimum payment, and taxes are included
+// in the amount charged, as they are worked out with the order.
+func (d *dodoClient) CreateChargeProduct(ctx context.Context, currency dodopayments.Currency) (*dodopayments.Product, error) {
+	return d.client.Products.New(ctx, dodopayments.ProductNewParams{
+		Name: dodopayments.F("Order payment"),
+		Price: dodopayments.F[dodopayments.PriceUnionParam](
+			dodopayments.PriceOneTimePriceParam{
+				Price:          dodopayments.F[int64](1),
+				Currency:       dodopayments.F(currency),
+				Discount:       dodopayments.F[int64](0),
+				Type:           dodopayments.F(dodopayments.PriceOneTimePriceTypeOneTimePrice),
+				PayWhatYouWant: dodopayments.F(true),
+				TaxInclusive:   dodopayments.F(true),
+			},
+		),
+		TaxCategory: dodopayments.F(dodopayments.TaxCategoryDigitalProducts),
+	})
 }
 
 func (d *dodoClient) CreateCustomerSession(ctx context.Context, customerId string) (string, error) {
@@ -161,6 +201,38 @@ func (d *dodoClient) CreateCustomerSession(ctx context.Context, customerId strin
 	return customerPortal.Link, nil
 }
 
+// CreateRefund refunds the listed items of a payment, or the whole payment when items is empty.
+func (d *dodoClient) CreateRefund(ctx context.Context,
+	paymentId string,
+	items []dodopayments.RefundNewParamsItem,
+	reason string) (*models.Refund, error) {
+
+	params := dodopayments.RefundNewParams{
+		PaymentID: dodopayments.F(paymentId),
+	}
+	if len(items) > 0 {
+		params.Items = dodopayments.F(items)
+	}
+	if reason != "" {
+		params.Reason = dodopayments.F(reason)
+	}
+
+	refund, err := d.client.Refunds.New(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Refund{
+		PaymentId: refund.PaymentID,
+		RefundId:  refund.RefundID,
+		Amount:    refund.Amount,
+		Currency:  string(refund.Currency),
+		IsPartial: refund.IsPartial,
+		Reason:    refund.Reason,
+		Status:    string(refund.Status),
+	}, nil
+}
+
 func (d *dodoClient) verifyWebhookSignature(signature string, payload []byte) bool {
 	h := hmac.New(sha256.New, []byte(config.DodoWebhookSecret))
 	h.Write(payload)
@@ -169,7 +241,7 @@ func (d *dodoClient) verifyWebhookSignature(signature string, payload []byte) bo
 	return hmac.Equal([]byte(signature), []byte(expectedSignature))
 }
 
-func (d *dodoClient) HandleWebhook(w http.ResponseWriter, r *http.Request) (*models.Transaction, error) {
+func (d *dodoClient) HandleWebhook(w http.ResponseWriter, r *http.Request) (*models.WebhookEvent, error) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return nil, errors.New("method not allowed")
@@ -196,28 +268,55 @@ func (d *dodoClient) HandleWebhook(w http.ResponseWriter, r *http.Request) (*mod
 		return nil, err
 	}
 
-	transaction := &models.Transaction{
-		OrderId:      payload.Data.Metadata.OrderId,
-		UserId:       payload.Data.Metadata.UserId,
-		CustomerId:   payload.Data.Customer.CustomerID,
-		PaymentId:    payload.Data.PaymentId,
-		TotalPrice:   payload.Data.TotalAmount,
-		SettledPrice: payload.Data.SettledAmount,
-		Currency:     string(payload.Data.Currency),
-		Status:       string(payload.Data.Status),
-	}
+	event := &models.WebhookEvent{Type: payload.Type}
 
 	// Process the webhook based on event type
 	switch payload.Type {
-	case "payment.succeeded":
-		transaction.Status = string(models.Success)
-	case "payment.failed":
-		transaction.Status = string(models.Failed)
+	case "payment.succeeded", "payment.failed":
+		if payload.Data.Metadata.GiftCardPurchaseId != 0 {
+			// Gift card checkouts pay for a purchase rather than an order
+			event.GiftCardPurchase = &models.GiftCardPurchase{
+				ID:        uint(payload.Data.Metadata.GiftCardPurchaseId),
+				UserId:    payload.Data.Metadata.UserId,
+				PaymentId: payload.Data.PaymentId,
+				Status:    models.PurchasePaid,
+			}
+			if payload.Type == "payment.failed" {
+				event.GiftCardPurchase.Status = models.PurchasePending
+			}
+			break
+		}
+		event.Transaction = &models.Transaction{
+			OrderId:      payload.Data.Metadata.OrderId,
+			UserId:       payload.Data.Metadata.UserId,
+			CustomerId:   payload.Data.Customer.CustomerID,
+			PaymentId:    payload.Data.PaymentId,
+			TotalPrice:   payload.Data.TotalAmount,
+			SettledPrice: payload.Data.SettledAmount,
+			Currency:     string(payload.Data.Currency),
+			Status:       string(models.Success),
+		}
+		if payload.Type == "payment.failed" {
+			event.Transaction.Status = string(models.Failed)
+		}
+	case "refund.succeeded", "refund.failed":
+		event.Refund = &models.Refund{
+			PaymentId: payload.Data.PaymentId,
+			RefundId:  payload.Data.RefundId,
+			Amount:    payload.Data.Amount,
+			Currency:  string(payload.Data.Currency),
+			IsPartial: payload.Data.IsPartial,
+			Reason:    payload.Data.Reason,
+			Status:    models.RefundSucceeded,
+		}
+		if payload.Type == "refund.failed" {
+			event.Refund.Status = models.RefundFailed
+		}
 	default:
 		log.Printf("Unhandled webhook event type: %s", payload.Type)
 	}
 
 	// Return a 200 OK to acknowledge receipt of the webhook
 	w.WriteHeader(http.StatusOK)
-	return transaction, nil
+	return event, nil
 }
@@ -11,12 +11,13 @@ import (
 	"github.com/IBM/sarama"
 	order "github.com/rasadov/EcommerceAPI/order/client"
 	"github.com/rasadov/EcommerceAPI/payment/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/idempotency"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
 
 // StartServers runs both gRPC and HTTP webhook servers concurrently
-func StartServers(service Service, consumer sarama.Consumer, orderURL string, grpcPort, webhookPort int) error {
+func StartServers(service Service, consumer sarama.Consumer, idempotencyStore *idempotency.Store, orderURL string, grpcPort, webhookPort int) error {
 	var wg sync.WaitGroup
 	errCh := make(chan error, 3)
 
@@ -37,7 +38,7 @@ func StartServers(service Service, consumer sarama.Consumer, orderURL string, gr
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := ListenGRPC(service, orderURL, grpcPort); err != nil {
+		if err := ListenGRPC(service, idempotencyStore, orderURL, grpcPort); err != nil {
 			errCh <- fmt.Errorf("gRPC server error: %w", err)
 		}
 	}()
@@ -60,7 +61,7 @@ func StartServers(service Service, consumer sarama.Consumer, orderURL string, gr
 	return <-errCh
 }
 
-func ListenGRPC(service Service, orderURL string, port int) error {
+func ListenGRPC(service Service, idempotencyStore *idempotency.Store, orderURL string, port int) error {
 	orderClient, err := order.NewClient(orderURL)
 	if err != nil {
 		return err
@@ -72,7 +73,10 @@ func ListenGRPC(service Service, orderURL string, port int) error {
 		return err
 	}
 
-	serv := grpc.NewServer()
+	// Retried checkouts return the session opened by the first attempt
+	serv := grpc.NewServer(grpc.UnaryInterceptor(
+		idempotency.UnaryServerInterceptor(idempotencyStore, pb.PaymentService_CreateCheckoutSession_FullMethodName),
+	))
 	pb.RegisterPaymentServiceServer(serv, &grpcServer{
 		pb.UnimplementedPaymentServiceServer{},
 		service,
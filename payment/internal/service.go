@@ -4,18 +4,23 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/dodopayments/dodopayments-go"
+	ordermodels "github.com/rasadov/EcommerceAPI/order/models"
 	"github.com/rasadov/EcommerceAPI/payment/models"
 	"github.com/rasadov/EcommerceAPI/payment/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/pkg/tax"
 	"gorm.io/gorm"
 )
 
 type Service interface {
 	RegisterProduct(ctx context.Context,
-		name string, price int64,
+		name string, price int64, currency, taxCategory string,
 		customerId, productId string) error
-	UpdateProduct(ctx context.Context, productId string, name string, price int64) error
+	UpdateProduct(ctx context.Context, productId string, name string, price int64, currency, taxCategory string) error
 	DeleteProduct(ctx context.Context, productId string) error
 
 	CreateCustomerPortalSession(ctx context.Context,
@@ -29,29 +34,77 @@ type Service interface {
 		customerId string,
 		redirect string,
 		products []*pb.CartItem, orderId uint64,
+		total money.Money,
 	) (checkoutURL string, err error)
 
-	HandlePaymentWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.Transaction, error)
+	ExpireCheckoutSessions(ctx context.Context, orderId uint64) (int64, error)
+	ClaimAbandonedCheckouts(ctx context.Context, openedBefore time.Time, limit int) ([]*models.AbandonedCheckout, error)
+	RenewCheckoutSession(ctx context.Context, orderId uint64, total money.Money) (checkoutURL string, err error)
+
+	RefundPayment(ctx context.Context, orderId uint64, items []*pb.RefundItem, reason string, toStoreCredit bool) (*models.Refund, error)
+
+	HandlePaymentWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.WebhookEvent, error)
+
+	RecordPayment(ctx context.Context, transaction *models.Transaction, shares []models.SellerShare) error
+	RecordRefund(ctx context.Context, refund *models.Refund) error
+	CreatePayouts(ctx context.Context, periodEnd time.Time) ([]*models.Payout, error)
+	GetSellerBalances(ctx context.Context, sellerId uint64) ([]*models.SellerBalance, error)
+	ListPayouts(ctx context.Context, sellerId uint64, after string, first int) (*models.PayoutPage, error)
+
+	IssueGiftCard(ctx context.Context, card *models.GiftCard) (*models.GiftCard, error)
+	GetGiftCard(ctx context.Context, code string, userId uint64) (*models.GiftCard, error)
+	ListGiftCards(ctx context.Context, userId uint64) ([]*models.GiftCard, error)
+	PurchaseGiftCard(ctx context.Context, userId uint64, customerId, redirect string, amount money.Money) (string, error)
+	ApplyCredit(ctx context.Context, orderId, userId uint64, total money.Money, giftCardCode string, useStoreCredit bool) (*models.AppliedCredit, error)
+	CaptureCredit(ctx context.Context, orderId, userId uint64, total money.Money, shares []models.SellerShare) (*models.Transaction, error)
+	GetStoreCredit(ctx context.Context, userId uint64) ([]*models.CreditBalance, error)
+	ListStoreCreditTransactions(ctx context.Context, userId uint64, after string, first int) (*models.StoreCreditPage, error)
 }
 
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var (
+	ErrNoPayment            = errors.New("order has no successful payment")
+	ErrAlreadyRefunded      = errors.New("order has already been refunded in full")
+	ErrInvalidRefund        = errors.New("refund amount must be positive")
+	ErrUnknownRefundItem    = errors.New("refund item is not a registered product")
+	ErrNoPaymentJournal     = errors.New("refunded payment was not posted to the ledger")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrNoCheckout           = errors.New("order has no checkout session")
+	ErrCheckoutNotAbandoned = errors.New("order's checkout was not abandoned")
+	ErrCreditRefund         = errors.New("refunds to store credit need item amounts within what was paid")
+	ErrNothingDue           = errors.New("order is covered by the credit put towards it")
+	ErrRefundAmountRequired = errors.New("refund items of orders charged as a single payment need amounts")
+)
+
 type paymentService struct {
 	client            PaymentClient
 	paymentRepository Repository
+	// commission is the marketplace's commission on seller sales, in basis points
+	commission int64
 }
 
-func NewPaymentService(client PaymentClient, paymentRepository Repository) Service {
-	return &paymentService{client: client, paymentRepository: paymentRepository}
+func NewPaymentService(client PaymentClient, paymentRepository Repository, commission int64) Service {
+	return &paymentService{client: client, paymentRepository: paymentRepository, commission: commission}
 }
 
-// RegisterProduct - registers product with Dodopayments and returns productId and error.
+// RegisterProduct - registers product with Dodopayments in the product's own currency
+// and tax category.
 func (d *paymentService) RegisterProduct(ctx context.Context,
-	name string, price int64,
+	name string, price int64, currency, taxCategory string,
 	customerId, productId string) error {
 
-	// We will use USD as currency and Digital Products as tax category for now to keep it simple
+	currency, err := money.Validate(currency)
+	if err != nil {
+		return err
+	}
+
 	product, err := d.client.CreateProduct(ctx, name, price,
-		dodopayments.CurrencyUsd,
-		dodopayments.TaxCategoryDigitalProducts,
+		dodopayments.Currency(currency),
+		dodoTaxCategory(taxCategory),
 		customerId, productId)
 
 	if err != nil {
@@ -68,8 +121,13 @@ func (d *paymentService) RegisterProduct(ctx context.Context,
 
 func (d *paymentService) UpdateProduct(ctx context.Context,
 	productId string,
-	name string, price int64) error {
-	err := d.client.UpdateProduct(ctx, productId, name, price)
+	name string, price int64, currency, taxCategory string) error {
+	currency, err := money.Validate(currency)
+	if err != nil {
+		return err
+	}
+
+	err = d.client.UpdateProduct(ctx, productId, name, price, dodopayments.Currency(currency), dodoTaxCategory(taxCategory))
 	if err != nil {
 		return err
 	}
@@ -79,8 +137,9 @@ func (d *paymentService) UpdateProduct(ctx context.Context,
 		return err
 	}
 
-	if product.Price != price {
+	if product.Price != price || product.Currency != currency {
 		product.Price = price
+		product.Currency = currency
 		err = d.paymentRepository.UpdateProduct(ctx, product)
 		if err != nil {
 			return err
@@ -89,6 +148,24 @@ func (d *paymentService) UpdateProduct(ctx context.Context,
 	return nil
 }
 
+// dodoTaxCategory maps a product tax category to the Dodopayments one. Dodopayments only
+// sells digital goods, so categories it does not know are registered as digital products.
+func dodoTaxCategory(category string) dodopayments.TaxCategory {
+	parsed, err := tax.ParseCategory(category)
+	if err != nil {
+		return dodopayments.TaxCategoryDigitalProducts
+	}
+	switch parsed {
+	case tax.CategorySaaS:
+		return dodopayments.TaxCategorySaas
+	case tax.CategoryEBook:
+		return dodopayments.TaxCategoryEBook
+	case tax.CategoryEdtech:
+		return dodopayments.TaxCategoryEdtech
+	}
+	return dodopayments.TaxCategoryDigitalProducts
+}
+
 func (d *paymentService) DeleteProduct(ctx context.Context, productId string) error {
 	err := d.client.ArchiveProduct(ctx, productId)
 	if err != nil {
@@ -99,35 +176,190 @@ func (d *paymentService) DeleteProduct(ctx context.Context, productId string) er
 }
 
 // CreateCheckoutSession - returns url to check out page and error.
+// The checkout charges the order's total, less the gift card and store credit put towards
+// it, as a single payment, since the provider cannot take shipping, taxes or fixed amounts
+// off its products. The products are kept with the session to show what it was for.
 func (d *paymentService) CreateCheckoutSession(ctx context.Context,
 	userId uint64,
 	customerId string,
 	redirect string,
-	products []*pb.CartItem, orderId uint64) (checkoutURL string, err error) {
+	products []*pb.CartItem, orderId uint64,
+	total money.Money) (checkoutURL string, err error) {
 
-	productIds := make([]string, len(products))
-	productQuantities := make(map[string]uint64, len(products))
+	currency, err := money.Validate(total.Currency)
+	if err != nil {
+		return "", err
+	}
 
-	for i, product := range products {
-		productIds[i] = product.ProductId
-		productQuantities[product.ProductId] = product.Quantity
+	session := &models.CheckoutSession{
+		OrderId:     orderId,
+		UserId:      userId,
+		CustomerId:  customerId,
+		RedirectURL: redirect,
+		Currency:    currency,
+	}
+	for _, product := range products {
+		session.Items = append(session.Items, models.CheckoutItem{
+			ProductId: product.ProductId,
+			Quantity:  product.Quantity,
+		})
 	}
+	return d.openCheckoutSession(ctx, session, total)
+}
 
-	modelsProducts, err := d.paymentRepository.GetProductsByIDs(ctx, productIds)
+// openCheckoutSession opens the session at Dodo for what is due of total, and saves it open.
+func (d *paymentService) openCheckoutSession(ctx context.Context, session *models.CheckoutSession, total money.Money) (string, error) {
+	due, err := d.amountDue(ctx, session.OrderId, total)
+	if err != nil {
+		return "", err
+	}
+	chargeProductId, err := d.chargeProduct(ctx, session.Currency)
+	if err != nil {
+		return "", err
+	}
+	dodoProducts := []dodopayments.CheckoutSessionRequestProductCartParam{{
+		ProductID: dodopayments.F(chargeProductId),
+		Quantity:  dodopayments.F[int64](1),
+		Amount:    dodopayments.F(due.Amount),
+	}}
+
+	checkoutURL, sessionID, err := d.client.CreateCheckoutSession(ctx, session.UserId, session.CustomerId, session.RedirectURL,
+		dodoProducts, map[string]string{"order_id": strconv.FormatUint(session.OrderId, 10)}, dodopayments.Currency(session.Currency))
 	if err != nil {
 		return "", err
 	}
 
-	var dodoProducts []dodopayments.CheckoutSessionRequestProductCartParam
+	session.SessionId = sessionID
+	session.CheckoutURL = checkoutURL
+	session.ChargeProductId = chargeProductId
+	session.Status = models.CheckoutOpen
+	err = d.paymentRepository.SaveCheckoutSession(ctx, session)
+	if err != nil {
+		return "", err
+	}
+	return checkoutURL, nil
+}
 
-	for _, product := range modelsProducts {
-		dodoProducts = append(dodoProducts, dodopayments.CheckoutSessionRequestProductCartParam{
-			ProductID: dodopayments.F(product.DodoProductID),
-			Quantity:  dodopayments.F(int64(productQuantities[product.ProductID])),
-		})
+// amountDue returns what is left to pay of the order's total once the gift card and store
+// credit put towards it are taken off.
+func (d *paymentService) amountDue(ctx context.Context, orderId uint64, total money.Money) (money.Money, error) {
+	credits, err := d.paymentRepository.GetOrderCredits(ctx, orderId)
+	if err != nil {
+		return money.Money{}, err
+	}
+	due := total
+	for _, credit := range credits {
+		due, err = due.Sub(credit.Amount)
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+	if due.Amount <= 0 {
+		return money.Money{}, ErrNothingDue
+	}
+	return due, nil
+}
+
+// chargeProduct returns the Dodo product checkouts in currency charge through, creating it
+// the first time the currency is checked out in.
+func (d *paymentService) chargeProduct(ctx context.Context, currency string) (string, error) {
+	product, err := d.paymentRepository.GetChargeProduct(ctx, currency)
+	if err == nil {
+		return product.DodoProductID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
 	}
 
-	return d.client.CreateCheckoutSession(ctx, userId, customerId, redirect, dodoProducts, orderId)
+	dodoProduct, err := d.client.CreateChargeProduct(ctx, dodopayments.Currency(currency))
+	if err != nil {
+		return "", err
+	}
+	err = d.paymentRepository.SaveChargeProduct(ctx, &models.ChargeProduct{Currency: currency, DodoProductID: dodoProduct.ProductID})
+	if err != nil {
+		return "", err
+	}
+	// A concurrent checkout may have saved its own first
+	product, err = d.paymentRepository.GetChargeProduct(ctx, currency)
+	if err != nil {
+		return "", err
+	}
+	return product.DodoProductID, nil
+}
+
+// ClaimAbandonedCheckouts marks up to limit checkout sessions left unpaid since before
+// openedBefore abandoned, and returns them with the customers who opened them.
+func (d *paymentService) ClaimAbandonedCheckouts(ctx context.Context, openedBefore time.Time, limit int) ([]*models.AbandonedCheckout, error) {
+	sessions, err := d.paymentRepository.ClaimAbandonedCheckouts(ctx, openedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	abandoned := make([]*models.AbandonedCheckout, 0, len(sessions))
+	for _, session := range sessions {
+		checkout := &models.AbandonedCheckout{Session: session}
+		customer, err := d.paymentRepository.GetCustomerByUserID(ctx, session.UserId)
+		if err == nil {
+			checkout.Email = customer.BillingEmail
+			checkout.Name = customer.BillingName
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		abandoned = append(abandoned, checkout)
+	}
+	return abandoned, nil
+}
+
+// RenewCheckoutSession opens a fresh checkout session for an order whose last session was
+// abandoned, for the same products, and returns its URL.
+func (d *paymentService) RenewCheckoutSession(ctx context.Context, orderId uint64, total money.Money) (string, error) {
+	last, err := d.paymentRepository.GetLatestCheckoutSession(ctx, orderId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNoCheckout
+	}
+	if err != nil {
+		return "", err
+	}
+	if last.Status != models.CheckoutAbandoned {
+		return "", ErrCheckoutNotAbandoned
+	}
+	if total.Currency != last.Currency {
+		return "", money.ErrCurrencyMismatch
+	}
+
+	return d.openCheckoutSession(ctx, &models.CheckoutSession{
+		OrderId:     last.OrderId,
+		UserId:      last.UserId,
+		CustomerId:  last.CustomerId,
+		RedirectURL: last.RedirectURL,
+		Currency:    last.Currency,
+		Items:       last.Items,
+		Reminder:    true,
+	}, total)
+}
+
+// ExpireCheckoutSessions expires the order's open checkout sessions, returning how many were,
+// and gives back the gift card and store credit put towards the order unless it was paid,
+// in which case refunds give it back. A payment still made through one of the sessions is
+// refunded once its order turns out cancelled.
+func (d *paymentService) ExpireCheckoutSessions(ctx context.Context, orderId uint64) (int64, error) {
+	expired, err := d.paymentRepository.ExpireCheckoutSessions(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = d.paymentRepository.GetSuccessfulTransaction(ctx, orderId)
+	if err == nil {
+		return expired, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	_, err = d.paymentRepository.ReleaseOrderCredits(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	return expired, nil
 }
 
 func (d *paymentService) CreateCustomerPortalSession(ctx context.Context, customer *models.Customer) (string, error) {
@@ -160,16 +392,378 @@ func (d *paymentService) FindOrCreateCustomer(ctx context.Context, userId uint64
 	return customer, err
 }
 
-func (d *paymentService) HandlePaymentWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.Transaction, error) {
-	updatedTransaction, err := d.client.HandleWebhook(w, r)
+// RefundPayment refunds the order's payment. Without items what is left of the payment is
+// refunded; otherwise each item refunds its amount, or the whole item when no amount is given.
+// What was paid with gift cards and store credit is refunded first, to store credit, and
+// the rest back to the card; with toStoreCredit all of it is refunded to store credit.
+// Requesting a full refund again returns the refund already in progress.
+func (d *paymentService) RefundPayment(ctx context.Context, orderId uint64, items []*pb.RefundItem, reason string, toStoreCredit bool) (*models.Refund, error) {
+	transaction, err := d.paymentRepository.GetSuccessfulTransaction(ctx, orderId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoPayment
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refunds, err := d.paymentRepository.GetRefundsForOrder(ctx, orderId)
 	if err != nil {
 		return nil, err
 	}
+	var refunded, credited int64
+	for _, refund := range refunds {
+		if refund.Status == models.RefundFailed {
+			continue
+		}
+		refunded += refund.Amount
+		if refund.Method == models.RefundToStoreCredit {
+			credited += refund.Amount
+		}
+		if refund.IsPartial {
+			continue
+		}
+		if len(items) == 0 {
+			return refund, nil
+		}
+		return nil, ErrAlreadyRefunded
+	}
+
+	// Store credit refunds first take back what was paid with credit
+	creditLeft := max(transaction.CreditAmount-credited, 0)
+	if toStoreCredit {
+		creditLeft = max(transaction.TotalPrice+transaction.CreditAmount-refunded, 0)
+	}
 
-	err = d.paymentRepository.RegisterTransaction(ctx, updatedTransaction)
+	credit := creditLeft
+	refundCard := !toStoreCredit && transaction.TotalPrice > 0
+	var cardItems []*pb.RefundItem
+	if len(items) == 0 && refunded > 0 {
+		// Only what earlier partial refunds left is refunded
+		left := max(transaction.TotalPrice+transaction.CreditAmount-refunded, 0)
+		credit = min(creditLeft, left)
+		refundCard = refundCard && left > credit
+		if refundCard {
+			cardItems, err = d.remainingRefundItems(ctx, transaction, left-credit)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(items) > 0 {
+		credit, cardItems, err = splitRefund(items, transaction.Currency, creditLeft)
+		if err != nil {
+			return nil, err
+		}
+		if len(cardItems) > 0 && !refundCard {
+			return nil, ErrCreditRefund
+		}
+		refundCard = len(cardItems) > 0
+	}
+
+	var refund *models.Refund
+	if credit > 0 {
+		refund, err = d.refundToStoreCredit(ctx, transaction, len(refunds), credit, len(items) > 0 || refundCard, reason)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !refundCard {
+		if refund == nil {
+			return nil, ErrAlreadyRefunded
+		}
+		return refund, nil
+	}
+
+	dodoItems, err := d.refundItems(ctx, transaction, cardItems)
 	if err != nil {
 		return nil, err
 	}
 
-	return updatedTransaction, nil
+	refund, err = d.client.CreateRefund(ctx, transaction.PaymentId, dodoItems, reason)
+	if err != nil {
+		return nil, err
+	}
+	refund.OrderId = orderId
+	if refund.Status == "" {
+		refund.Status = models.RefundPending
+	}
+	if len(items) == 0 {
+		// Refunding what earlier partial refunds left completes the refund of the order
+		refund.IsPartial = false
+	}
+
+	err = d.paymentRepository.SaveRefund(ctx, refund)
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// splitRefund takes what items refund out of creditLeft first, returning what is refunded
+// to store credit and the items, with what is left of their amounts, to refund to the card.
+func splitRefund(items []*pb.RefundItem, currency string, creditLeft int64) (int64, []*pb.RefundItem, error) {
+	var credit int64
+	var cardItems []*pb.RefundItem
+	for _, item := range items {
+		if item.Amount == nil {
+			cardItems = append(cardItems, item)
+			continue
+		}
+		amount := money.FromProto(item.Amount)
+		if amount.Currency != currency {
+			return 0, nil, money.ErrCurrencyMismatch
+		}
+		if amount.Amount <= 0 {
+			return 0, nil, ErrInvalidRefund
+		}
+
+		fromCredit := min(amount.Amount, creditLeft)
+		creditLeft -= fromCredit
+		credit += fromCredit
+		if fromCredit < amount.Amount {
+			cardItems = append(cardItems, &pb.RefundItem{
+				ProductId: item.ProductId,
+				Amount:    money.New(amount.Amount-fromCredit, currency).ToProto(),
+			})
+		}
+	}
+	return credit, cardItems, nil
+}
+
+// refundItems returns the items of the transaction's payment to refund for items. Payments
+// of checkouts charged as a single payment refund their amounts from the charge product.
+func (d *paymentService) refundItems(ctx context.Context, transaction *models.Transaction, items []*pb.RefundItem) ([]dodopayments.RefundNewParamsItem, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	session, err := d.paymentRepository.GetLatestCheckoutSession(ctx, transaction.OrderId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && session.ChargeProductId != "" {
+		return chargeRefundItems(session.ChargeProductId, transaction.Currency, items)
+	}
+
+	productIds := make([]string, len(items))
+	for i, item := range items {
+		productIds[i] = item.ProductId
+	}
+	products, err := d.paymentRepository.GetProductsByIDs(ctx, productIds)
+	if err != nil {
+		return nil, err
+	}
+	dodoProductIds := make(map[string]string, len(products))
+	for _, product := range products {
+		dodoProductIds[product.ProductID] = product.DodoProductID
+	}
+
+	var dodoItems []dodopayments.RefundNewParamsItem
+	for _, item := range items {
+		dodoProductId, ok := dodoProductIds[item.ProductId]
+		if !ok {
+			return nil, ErrUnknownRefundItem
+		}
+		dodoItem := dodopayments.RefundNewParamsItem{
+			ItemID: dodopayments.F(dodoProductId),
+		}
+		if item.Amount != nil {
+			amount := money.FromProto(item.Amount)
+			if amount.Currency != transaction.Currency {
+				return nil, money.ErrCurrencyMismatch
+			}
+			if amount.Amount <= 0 {
+				return nil, ErrInvalidRefund
+			}
+			dodoItem.Amount = dodopayments.F(amount.Amount)
+		}
+		dodoItems = append(dodoItems, dodoItem)
+	}
+	return dodoItems, nil
+}
+
+// remainingRefundItems returns the items that refund amount, what is left of the
+// transaction's card payment, shared between the products the checkout charged in
+// proportion to their prices when it charged them separately.
+func (d *paymentService) remainingRefundItems(ctx context.Context, transaction *models.Transaction, amount int64) ([]*pb.RefundItem, error) {
+	session, err := d.paymentRepository.GetLatestCheckoutSession(ctx, transaction.OrderId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoCheckout
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.ChargeProductId != "" {
+		return []*pb.RefundItem{{Amount: money.New(amount, transaction.Currency).ToProto()}}, nil
+	}
+
+	productIds := make([]string, len(session.Items))
+	for i, item := range session.Items {
+		productIds[i] = item.ProductId
+	}
+	products, err := d.paymentRepository.GetProductsByIDs(ctx, productIds)
+	if err != nil {
+		return nil, err
+	}
+	prices := make(map[string]int64, len(products))
+	for _, product := range products {
+		prices[product.ProductID] = product.Price
+	}
+	weights := make([]int64, len(session.Items))
+	for i, item := range session.Items {
+		weights[i] = prices[item.ProductId] * int64(item.Quantity)
+	}
+	shares, err := money.New(amount, transaction.Currency).Allocate(weights...)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*pb.RefundItem
+	for i, item := range session.Items {
+		if shares[i].Amount > 0 {
+			items = append(items, &pb.RefundItem{ProductId: item.ProductId, Amount: shares[i].ToProto()})
+		}
+	}
+	return items, nil
+}
+
+// chargeRefundItems refunds the amounts of items together from the charge product a
+// payment was made through.
+func chargeRefundItems(chargeProductId, currency string, items []*pb.RefundItem) ([]dodopayments.RefundNewParamsItem, error) {
+	total := money.Zero(currency)
+	for _, item := range items {
+		if item.Amount == nil {
+			return nil, ErrRefundAmountRequired
+		}
+		amount := money.FromProto(item.Amount)
+		if amount.Amount <= 0 {
+			return nil, ErrInvalidRefund
+		}
+		var err error
+		total, err = total.Add(amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []dodopayments.RefundNewParamsItem{{
+		ItemID: dodopayments.F(chargeProductId),
+		Amount: dodopayments.F(total.Amount),
+	}}, nil
+}
+
+// HandlePaymentWebhook records the payment or refund a verified webhook reports, and
+// issues the gift cards paid for.
+func (d *paymentService) HandlePaymentWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.WebhookEvent, error) {
+	event, err := d.client.HandleWebhook(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case event.GiftCardPurchase != nil:
+		if event.GiftCardPurchase.Status == models.PurchasePaid {
+			err = d.completeGiftCardPurchase(ctx, event.GiftCardPurchase)
+		}
+	case event.Transaction != nil:
+		// The checkout charged only what the order's credit left to pay
+		event.Transaction.CreditAmount, err = d.orderCredit(ctx, event.Transaction.OrderId, event.Transaction.Currency)
+		if err != nil {
+			return nil, err
+		}
+		err = d.paymentRepository.RegisterTransaction(ctx, event.Transaction)
+		if err == nil && event.Transaction.Status == models.Success.String() {
+			// Paid checkouts are not abandoned
+			_, err = d.paymentRepository.CompleteCheckoutSessions(ctx, event.Transaction.OrderId)
+		}
+	case event.Refund != nil:
+		// Refund webhooks do not carry our metadata, so the order comes from the stored refund
+		event.Refund, err = d.paymentRepository.UpdateRefundStatus(ctx, event.Refund.RefundId, event.Refund.Status)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// RecordPayment posts the captured payment to the ledger, owing each seller their share
+// less commission. Posting a payment again does nothing.
+func (d *paymentService) RecordPayment(ctx context.Context, transaction *models.Transaction, shares []models.SellerShare) error {
+	err := d.paymentRepository.PostJournal(ctx, models.PaymentJournal(transaction, shares, d.commission))
+	if errors.Is(err, ErrJournalExists) {
+		return nil
+	}
+	return err
+}
+
+// RecordRefund posts the succeeded refund to the ledger against the order's payment.
+// Posting a refund again does nothing.
+func (d *paymentService) RecordRefund(ctx context.Context, refund *models.Refund) error {
+	transaction, err := d.paymentRepository.GetSuccessfulTransaction(ctx, refund.OrderId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNoPayment
+	}
+	if err != nil {
+		return err
+	}
+	payment, err := d.paymentRepository.GetJournal(ctx, models.JournalPayment+":"+transaction.PaymentId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNoPaymentJournal
+	}
+	if err != nil {
+		return err
+	}
+
+	journal, err := models.RefundJournal(refund, payment)
+	if err != nil {
+		return err
+	}
+	err = d.paymentRepository.PostJournal(ctx, journal)
+	if errors.Is(err, ErrJournalExists) {
+		return nil
+	}
+	return err
+}
+
+// CreatePayouts pays sellers out what they are owed for everything posted before periodEnd.
+func (d *paymentService) CreatePayouts(ctx context.Context, periodEnd time.Time) ([]*models.Payout, error) {
+	return d.paymentRepository.CreatePayouts(ctx, periodEnd)
+}
+
+func (d *paymentService) GetSellerBalances(ctx context.Context, sellerId uint64) ([]*models.SellerBalance, error) {
+	return d.paymentRepository.GetSellerBalances(ctx, sellerId)
+}
+
+// ListPayouts returns the first of the seller's payouts after the cursor, newest first.
+// first defaults to DefaultPageSize and is capped at MaxPageSize.
+func (d *paymentService) ListPayouts(ctx context.Context, sellerId uint64, after string, first int) (*models.PayoutPage, error) {
+	var afterID uint
+	if after != "" {
+		var err error
+		afterID, err = ordermodels.DecodeCursor(after)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+	first = min(first, MaxPageSize)
+
+	// Fetch one extra payout to learn whether another page follows
+	payouts, err := d.paymentRepository.ListPayouts(ctx, sellerId, afterID, first+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.PayoutPage{Payouts: payouts}
+	if len(payouts) > first {
+		page.Payouts = payouts[:first]
+		page.HasNextPage = true
+	}
+	if len(page.Payouts) > 0 {
+		page.EndCursor = ordermodels.EncodeCursor(page.Payouts[len(page.Payouts)-1].ID)
+	}
+	return page, nil
 }
@@ -7,6 +7,9 @@ import (
 	"time"
 
 	order "github.com/rasadov/EcommerceAPI/order/client"
+	ordermodels "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/payment/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 )
 
 type WebhookServer struct {
@@ -17,13 +20,87 @@ type WebhookServer struct {
 func (s *WebhookServer) HandlePaymentWebhook(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	transaction, err := s.service.HandlePaymentWebhook(ctx, w, r)
+	event, err := s.service.HandlePaymentWebhook(ctx, w, r)
 	if err != nil {
 		log.Println(err.Error())
 		return
 	}
 
-	err = s.orderClient.UpdateOrderStatus(ctx, transaction.OrderId, transaction.Status)
+	switch {
+	case event.Transaction != nil:
+		s.handleTransaction(ctx, event.Transaction)
+	case event.Refund != nil:
+		s.handleRefund(ctx, event.Refund)
+	}
+}
+
+func (s *WebhookServer) handleTransaction(ctx context.Context, transaction *models.Transaction) {
+	// Only a successful payment moves the order forward; a failed one leaves it
+	// pending so the customer can retry checkout.
+	if transaction.Status != models.Success.String() {
+		return
+	}
+	s.recordPayment(ctx, transaction)
+
+	_, err := s.orderClient.UpdateOrderStatus(ctx, transaction.OrderId, ordermodels.StatusPaid,
+		"payment", "payment "+transaction.PaymentId+" succeeded")
+	if err != nil {
+		log.Println(err.Error())
+		s.refundUnpayableOrder(ctx, transaction)
+	}
+}
+
+// refundUnpayableOrder refunds a payment made for an order that was cancelled before it was
+// paid, e.g. through the checkout session of an order that expired meanwhile.
+func (s *WebhookServer) refundUnpayableOrder(ctx context.Context, transaction *models.Transaction) {
+	placedOrder, err := s.orderClient.GetOrder(ctx, transaction.OrderId, 0)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	if placedOrder.Status != ordermodels.StatusCancelled || placedOrder.WasPaid() {
+		return
+	}
+
+	_, err = s.service.RefundPayment(ctx, transaction.OrderId, nil, "order was cancelled before it was paid", false)
+	if err != nil {
+		log.Println("Failed to refund payment", transaction.PaymentId, "of cancelled order:", err)
+	}
+}
+
+// recordPayment posts the captured payment, together with the credit put towards its order,
+// to the ledger, shared between the sellers of the order.
+func (s *WebhookServer) recordPayment(ctx context.Context, transaction *models.Transaction) {
+	placedOrder, err := s.orderClient.GetOrder(ctx, transaction.OrderId, 0)
+	if err != nil {
+		log.Println("Failed to post payment", transaction.PaymentId, "to the ledger:", err)
+		return
+	}
+	shares, err := paymentShares(placedOrder, money.New(transaction.TotalPrice+transaction.CreditAmount, transaction.Currency))
+	if err == nil {
+		err = s.service.RecordPayment(ctx, transaction, shares)
+	}
+	if err != nil {
+		log.Println("Failed to post payment", transaction.PaymentId, "to the ledger:", err)
+	}
+}
+
+func (s *WebhookServer) handleRefund(ctx context.Context, refund *models.Refund) {
+	if refund.Status != models.RefundSucceeded {
+		return
+	}
+	err := s.service.RecordRefund(ctx, refund)
+	if err != nil {
+		log.Println("Failed to post refund", refund.RefundId, "to the ledger:", err)
+	}
+
+	// Partial refunds, e.g. for returned items, leave the rest of the order as it is
+	if refund.IsPartial {
+		return
+	}
+
+	_, err = s.orderClient.UpdateOrderStatus(ctx, refund.OrderId, ordermodels.StatusRefunded,
+		"payment", "refund "+refund.RefundId+" succeeded")
 	if err != nil {
 		log.Println(err.Error())
 	}
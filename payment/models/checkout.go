@@ -0,0 +1,47 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	CheckoutOpen    = "open"
+	CheckoutExpired = "expired"
+	// CheckoutCompleted sessions belong to orders that were paid
+	CheckoutCompleted = "completed"
+	// CheckoutAbandoned sessions were left unpaid for longer than the abandonment delay.
+	// They can still be paid until their order expires.
+	CheckoutAbandoned = "abandoned"
+)
+
+// CheckoutSession is a checkout opened at Dodo for an order. Dodo cannot close a session,
+// so sessions of orders that expired are only marked expired here.
+type CheckoutSession struct {
+	gorm.Model
+	OrderId     uint64         `json:"order_id" gorm:"index"`
+	SessionId   string         `json:"session_id" gorm:"uniqueIndex"`
+	Status      string         `json:"status" gorm:"type:varchar(20);index"`
+	UserId      uint64         `json:"user_id"`
+	CustomerId  string         `json:"customer_id"`
+	CheckoutURL string         `json:"checkout_url"`
+	RedirectURL string         `json:"redirect_url"`
+	Currency    string         `json:"currency"`
+	Items       []CheckoutItem `json:"items" gorm:"serializer:json"`
+	// ChargeProductId is the Dodo product the session charges the amount due through. Sessions
+	// opened before orders were charged as a single payment charged their products instead.
+	ChargeProductId string `json:"charge_product_id"`
+	// Reminder sessions were opened to win back an abandoned checkout, and are not
+	// reminded about again
+	Reminder bool `json:"reminder"`
+}
+
+// CheckoutItem is a product in a checkout session, so that the session can be opened again.
+type CheckoutItem struct {
+	ProductId string `json:"product_id"`
+	Quantity  uint64 `json:"quantity"`
+}
+
+// AbandonedCheckout is an abandoned checkout session along with the customer who opened it.
+type AbandonedCheckout struct {
+	Session *CheckoutSession
+	Email   string
+	Name    string
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+var (
+	ErrInvalidGiftCard = errors.New("invalid gift card")
+	ErrGiftCardExpired = errors.New("gift card has expired")
+	ErrGiftCardEmpty   = errors.New("gift card has no balance left")
+)
+
+// Kinds of credit put towards an order.
+const (
+	CreditGiftCard    = "gift_card"
+	CreditStoreCredit = "store_credit"
+)
+
+// Kinds of store credit transactions.
+const (
+	// StoreCreditRefund credits a refund given as store credit
+	StoreCreditRefund = "refund"
+	// StoreCreditSpent takes what was put towards an order
+	StoreCreditSpent = "spent"
+	// StoreCreditReleased gives back what was put towards an order that was not paid
+	StoreCreditReleased = "released"
+)
+
+// giftCardAlphabet leaves out letters and digits that are easily mistaken for one another.
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GiftCard is a prepaid balance that anyone holding its code can spend on orders in its
+// currency, over as many orders as it takes.
+type GiftCard struct {
+	ID      uint        `gorm:"primaryKey;autoIncrement"`
+	Code    string      `gorm:"uniqueIndex;size:32"`
+	Initial money.Money `gorm:"embedded;embeddedPrefix:initial_"`
+	Balance money.Money `gorm:"embedded;embeddedPrefix:balance_"`
+	// IssuedBy is the admin who issued the card, or the purchase it was bought with, and
+	// Note what it was issued for
+	IssuedBy string
+	Note     string
+	// OwnerId is the account that bought the card, 0 for cards admins issued
+	OwnerId   uint64 `gorm:"index"`
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+}
+
+// NewGiftCardCode returns a random code of 16 characters in groups of four.
+func NewGiftCardCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	var code strings.Builder
+	for i, c := range b {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(giftCardAlphabet[int(c)%len(giftCardAlphabet)])
+	}
+	return code.String(), nil
+}
+
+// NormalizeGiftCardCode returns the form gift card codes are stored and looked up in, so
+// that codes can be typed in lower case and with or without spaces.
+func NormalizeGiftCardCode(code string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(code), ""))
+}
+
+// Validate checks that a card about to be issued has a code and a positive amount.
+func (card GiftCard) Validate() error {
+	if len(card.Code) < 8 || len(card.Code) > 32 {
+		return ErrInvalidGiftCard
+	}
+	if card.Initial.Amount <= 0 || !money.IsValid(card.Initial.Currency) {
+		return ErrInvalidGiftCard
+	}
+	return nil
+}
+
+// Usable reports why the card cannot be spent at t, if it cannot.
+func (card GiftCard) Usable(t time.Time) error {
+	if card.ExpiresAt != nil && !t.Before(*card.ExpiresAt) {
+		return ErrGiftCardExpired
+	}
+	if card.Balance.Amount <= 0 {
+		return ErrGiftCardEmpty
+	}
+	return nil
+}
+
+// Statuses of gift card purchases.
+const (
+	PurchasePending = "pending"
+	PurchasePaid    = "paid"
+)
+
+// GiftCardPurchase is a gift card a customer buys at checkout. The card is issued to them
+// once the checkout is paid.
+type GiftCardPurchase struct {
+	ID        uint        `gorm:"primaryKey;autoIncrement"`
+	UserId    uint64      `gorm:"index"`
+	Amount    money.Money `gorm:"embedded;embeddedPrefix:amount_"`
+	Status    string      `gorm:"type:varchar(10)"`
+	SessionId string
+	// PaymentId and GiftCardId are set once the purchase is paid
+	PaymentId  string
+	GiftCardId uint
+	CreatedAt  time.Time
+}
+
+// OrderCredit is an amount of a gift card or of store credit put towards an order, which
+// is taken off what the customer pays at checkout.
+type OrderCredit struct {
+	ID      uint   `gorm:"primaryKey;autoIncrement"`
+	OrderId uint64 `gorm:"index;uniqueIndex:idx_order_credits_unreleased,where:released = false"`
+	// Seq numbers the credits applied together, from 1. Only one application of credit
+	// to an order holds its first number until it is released.
+	Seq    int `gorm:"uniqueIndex:idx_order_credits_unreleased,where:released = false"`
+	UserId uint64
+	Kind   string `gorm:"type:varchar(20)"`
+	// GiftCardId is the card the credit came from, 0 for store credit
+	GiftCardId uint
+	Amount     money.Money `gorm:"embedded;embeddedPrefix:amount_"`
+	// Released credit was given back because its order was not paid
+	Released  bool
+	CreatedAt time.Time
+}
+
+// CreditBalance is the store credit an account holds in one currency.
+type CreditBalance struct {
+	UserId    uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Currency  string `gorm:"primaryKey;size:3"`
+	Amount    int64
+	UpdatedAt time.Time
+}
+
+// StoreCreditTransaction is one change to an account's store credit. Credits are positive
+// and spending negative, so an account's transactions add up to its balance.
+type StoreCreditTransaction struct {
+	ID      uint        `gorm:"primaryKey;autoIncrement"`
+	UserId  uint64      `gorm:"index"`
+	Kind    string      `gorm:"type:varchar(20)"`
+	Amount  money.Money `gorm:"embedded;embeddedPrefix:amount_"`
+	OrderId uint64
+	// Reference names what the transaction was for, e.g. "refund:credit_42_1", so it is
+	// applied only once
+	Reference string `gorm:"uniqueIndex;size:128"`
+	CreatedAt time.Time
+}
+
+// StoreCreditPage is one page of an account's store credit transactions, newest first.
+type StoreCreditPage struct {
+	Transactions []*StoreCreditTransaction
+	EndCursor    string
+	HasNextPage  bool
+}
+
+// AppliedCredit is what an order's credit covers and what is left to pay at checkout.
+type AppliedCredit struct {
+	Credits   []*OrderCredit
+	Remaining money.Money
+}
@@ -5,10 +5,29 @@ type ProductEventData struct {
 	Name        *string  `json:"name"`
 	Description *string  `json:"description"`
 	Price       *float64 `json:"price"`
+	Amount      *int64   `json:"amount"`
+	Currency    *string  `json:"currency"`
 	AccountID   *int     `json:"accountID"`
+	TaxCategory *string  `json:"tax_category,omitempty"`
 }
 
 type ProductEvent struct {
 	Type string           `json:"type"`
 	Data ProductEventData `json:"data"`
 }
+
+// CheckoutEventData describes a checkout session in the events the payment service publishes.
+type CheckoutEventData struct {
+	OrderId     uint64 `json:"order_id"`
+	UserId      uint64 `json:"user_id"`
+	Email       string `json:"email"`
+	Name        string `json:"name"`
+	Currency    string `json:"currency"`
+	CheckoutURL string `json:"checkout_url"`
+	OpenedAt    string `json:"opened_at"`
+}
+
+type CheckoutEvent struct {
+	Type string            `json:"type"`
+	Data CheckoutEventData `json:"data"`
+}
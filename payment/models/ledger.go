@@ -0,0 +1,251 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// Ledger accounts. Cash is the money the payment provider holds for the marketplace, and
+// what the marketplace owes a seller is kept in that seller's payable account. Store
+// credit takes what customers pay with gift cards and store credit, and what they are
+// refunded as store credit.
+const (
+	AccountCash          = "cash"
+	AccountStoreCredit   = "store_credit"
+	AccountSellerPayable = "seller_payable"
+	AccountCommission    = "commission"
+	// AccountSales takes the sales of products without a seller, which the marketplace
+	// sells itself
+	AccountSales = "sales"
+)
+
+// Kinds of journals.
+const (
+	JournalPayment = "payment"
+	JournalRefund  = "refund"
+	JournalPayout  = "payout"
+)
+
+// Memos telling apart the entries of a seller's payable account on their statements.
+const (
+	MemoSale             = "sale"
+	MemoCommission       = "commission"
+	MemoRefund           = "refund"
+	MemoCommissionRefund = "commission_refund"
+	MemoPayout           = "payout"
+)
+
+var ErrUnbalancedJournal = errors.New("journal debits and credits do not balance")
+
+// Journal is one balanced posting to the ledger: a captured payment, a refund or a payout.
+type Journal struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement"`
+	Kind string `gorm:"type:varchar(20)"`
+	// Reference names what was posted, e.g. "payment:pay_123", so it is posted only once
+	Reference string         `gorm:"uniqueIndex;size:128"`
+	OrderId   uint64         `gorm:"index"`
+	Entries   []*LedgerEntry `gorm:"foreignKey:JournalId"`
+	CreatedAt time.Time
+}
+
+func (Journal) TableName() string {
+	return "ledger_journals"
+}
+
+// LedgerEntry debits or credits one account. Debits are positive and credits negative, so
+// the entries of a journal add up to zero.
+type LedgerEntry struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	JournalId uint   `gorm:"index"`
+	Account   string `gorm:"type:varchar(20);index:idx_ledger_account"`
+	// SellerId is the seller whose payable account is posted to, 0 for other accounts
+	SellerId uint64 `gorm:"index:idx_ledger_account"`
+	Amount   int64
+	Currency string `gorm:"size:3"`
+	Memo     string `gorm:"type:varchar(20)"`
+	// PayoutId is the payout a seller payable entry was paid out with, 0 until it is
+	PayoutId  uint `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (LedgerEntry) TableName() string {
+	return "ledger_entries"
+}
+
+// Balanced reports an error unless the journal's entries add up to zero in each currency.
+func (j *Journal) Balanced() error {
+	sums := map[string]int64{}
+	for _, entry := range j.Entries {
+		sums[entry.Currency] += entry.Amount
+	}
+	for _, sum := range sums {
+		if sum != 0 {
+			return ErrUnbalancedJournal
+		}
+	}
+	return nil
+}
+
+// SellerShare is the part of a payment a seller sold, with SellerId 0 for the marketplace.
+type SellerShare struct {
+	SellerId uint64
+	Amount   money.Money
+}
+
+// Commission is the marketplace's commission of basisPoints on amount, rounded down to the
+// minor unit.
+func Commission(amount money.Money, basisPoints int64) money.Money {
+	return money.New(amount.Amount*basisPoints/10000, amount.Currency)
+}
+
+// PaymentJournal posts the captured payment: the cash received, and the credit spent, is
+// owed to the sellers of its shares, less the marketplace's commission of basisPoints.
+func PaymentJournal(transaction *Transaction, shares []SellerShare, basisPoints int64) *Journal {
+	journal := &Journal{
+		Kind:      JournalPayment,
+		Reference: JournalPayment + ":" + transaction.PaymentId,
+		OrderId:   transaction.OrderId,
+	}
+	journal.post(AccountCash, 0, transaction.TotalPrice, transaction.Currency, "")
+	journal.post(AccountStoreCredit, 0, transaction.CreditAmount, transaction.Currency, "")
+	for _, share := range shares {
+		if share.SellerId == 0 {
+			journal.post(AccountSales, 0, -share.Amount.Amount, share.Amount.Currency, MemoSale)
+			continue
+		}
+		journal.post(AccountSellerPayable, share.SellerId, -share.Amount.Amount, share.Amount.Currency, MemoSale)
+		commission := Commission(share.Amount, basisPoints)
+		if !commission.IsZero() {
+			journal.post(AccountSellerPayable, share.SellerId, commission.Amount, commission.Currency, MemoCommission)
+			journal.post(AccountCommission, 0, -commission.Amount, commission.Currency, MemoCommission)
+		}
+	}
+	return journal
+}
+
+// RefundJournal posts the refund against the payment it returns money from. Refunds are
+// shared between the payment's sellers in proportion to their sales, and each gets the same
+// part of their commission back.
+func RefundJournal(refund *Refund, payment *Journal) (*Journal, error) {
+	var sales []*LedgerEntry
+	var weights []int64
+	commissions := map[uint64]int64{}
+	for _, entry := range payment.Entries {
+		switch entry.Memo {
+		case MemoSale:
+			sales = append(sales, entry)
+			weights = append(weights, -entry.Amount)
+		case MemoCommission:
+			if entry.Account == AccountSellerPayable {
+				commissions[entry.SellerId] += entry.Amount
+			}
+		}
+	}
+	if len(sales) == 0 {
+		return nil, ErrUnbalancedJournal
+	}
+
+	amounts, err := money.New(refund.Amount, refund.Currency).Allocate(weights...)
+	if err != nil {
+		return nil, err
+	}
+
+	journal := &Journal{
+		Kind:      JournalRefund,
+		Reference: JournalRefund + ":" + refund.RefundId,
+		OrderId:   refund.OrderId,
+	}
+	refundedFrom := AccountCash
+	if refund.Method == RefundToStoreCredit {
+		refundedFrom = AccountStoreCredit
+	}
+	journal.post(refundedFrom, 0, -refund.Amount, refund.Currency, "")
+	for i, sale := range sales {
+		journal.post(sale.Account, sale.SellerId, amounts[i].Amount, refund.Currency, MemoRefund)
+		commission := commissions[sale.SellerId] * amounts[i].Amount / weights[i]
+		if sale.Account == AccountSellerPayable && commission != 0 {
+			journal.post(AccountCommission, 0, commission, refund.Currency, MemoCommissionRefund)
+			journal.post(AccountSellerPayable, sale.SellerId, -commission, refund.Currency, MemoCommissionRefund)
+		}
+	}
+	return journal, nil
+}
+
+func (j *Journal) post(account string, sellerId uint64, amount int64, currency, memo string) {
+	if amount == 0 {
+		return
+	}
+	j.Entries = append(j.Entries, &LedgerEntry{
+		Account:  account,
+		SellerId: sellerId,
+		Amount:   amount,
+		Currency: currency,
+		Memo:     memo,
+	})
+}
+
+// Payout is a seller's payout statement: the sales, commission and refunds posted to their
+// payable account in the period, and the amount paid out to them for it.
+type Payout struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	SellerId    uint64 `gorm:"index"`
+	Currency    string `gorm:"size:3"`
+	Sales       int64
+	Commission  int64
+	Refunds     int64
+	Amount      int64
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	CreatedAt   time.Time
+}
+
+func (Payout) TableName() string {
+	return "seller_payouts"
+}
+
+// NewPayout sums up the seller's unpaid payable entries, all in the same currency, into a
+// statement for the period ending at periodEnd.
+func NewPayout(sellerId uint64, currency string, entries []*LedgerEntry, periodEnd time.Time) *Payout {
+	payout := &Payout{SellerId: sellerId, Currency: currency, PeriodStart: periodEnd, PeriodEnd: periodEnd}
+	for _, entry := range entries {
+		switch entry.Memo {
+		case MemoSale:
+			payout.Sales -= entry.Amount
+		case MemoCommission, MemoCommissionRefund:
+			payout.Commission += entry.Amount
+		case MemoRefund:
+			payout.Refunds += entry.Amount
+		}
+		payout.Amount -= entry.Amount
+		if entry.CreatedAt.Before(payout.PeriodStart) {
+			payout.PeriodStart = entry.CreatedAt
+		}
+	}
+	return payout
+}
+
+// PayoutJournal posts the payout, paying the seller out of cash.
+func PayoutJournal(payout *Payout) *Journal {
+	journal := &Journal{Kind: JournalPayout}
+	journal.post(AccountSellerPayable, payout.SellerId, payout.Amount, payout.Currency, MemoPayout)
+	journal.post(AccountCash, 0, -payout.Amount, payout.Currency, "")
+	return journal
+}
+
+// SellerBalance is what the marketplace owes a seller in one currency and has paid them so far.
+type SellerBalance struct {
+	SellerId uint64
+	Currency string
+	// Owed has not been paid out yet; it is negative while refunds exceed new sales
+	Owed    int64
+	PaidOut int64
+}
+
+// PayoutPage is one page of a seller's payouts, newest first.
+type PayoutPage struct {
+	Payouts     []*Payout
+	EndCursor   string
+	HasNextPage bool
+}
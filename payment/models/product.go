@@ -14,3 +14,13 @@ type Product struct {
 	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;"`
 	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updated_at;"`
 }
+
+// ChargeProduct is the pay-what-you-want product checkouts in a currency charge the amount
+// due through, so that everything the order adds up to, including shipping, taxes and the
+// credit put towards it, is charged exactly.
+type ChargeProduct struct {
+	Currency      string `json:"currency" gorm:"primarykey;type:varchar(3)"`
+	DodoProductID string `json:"dodoProductId"`
+
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;"`
+}
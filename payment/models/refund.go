@@ -0,0 +1,37 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+
+	// RefundToStoreCredit refunds are credited to the customer's store credit rather
+	// than paid back through the payment provider
+	RefundToStoreCredit = "store_credit"
+)
+
+// Refund is money returned to the customer for all or part of an order's payment.
+type Refund struct {
+	gorm.Model
+	OrderId   uint64 `json:"order_id" gorm:"index"`
+	PaymentId string `json:"payment_id"`
+	RefundId  string `json:"refund_id" gorm:"uniqueIndex"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	IsPartial bool   `json:"is_partial"`
+	Reason    string `json:"reason"`
+	Status    string `json:"status" gorm:"type:varchar(20)"`
+	// Method is RefundToStoreCredit for refunds given as store credit, empty otherwise
+	Method string `json:"method" gorm:"type:varchar(20)"`
+}
+
+// WebhookEvent is a verified Dodo webhook. Payment events carry a Transaction, or a
+// GiftCardPurchase when they pay for a gift card, and refund events carry a Refund.
+type WebhookEvent struct {
+	Type             string
+	Transaction      *Transaction
+	GiftCardPurchase *GiftCardPurchase
+	Refund           *Refund
+}
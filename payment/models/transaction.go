@@ -25,4 +25,6 @@ type Transaction struct {
 	SettledPrice int64  `json:"settled_price"`
 	Currency     string `json:"currency"`
 	Status       string `json:"status" gorm:"type:varchar(20)"`
+	// CreditAmount is what gift cards and store credit paid on top of TotalPrice
+	CreditAmount int64 `json:"credit_amount"`
 }
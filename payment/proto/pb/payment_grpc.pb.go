@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
 // versions:
 // - protoc-gen-go-grpc v1.5.1
-// - protoc             v3.21.12
+// - protoc             v6.30.0
 // source: payment.proto
 
 package pb
@@ -22,6 +22,18 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	PaymentService_CreateCheckoutSession_FullMethodName       = "/pb.PaymentService/CreateCheckoutSession"
 	PaymentService_CreateCustomerPortalSession_FullMethodName = "/pb.PaymentService/CreateCustomerPortalSession"
+	PaymentService_RefundPayment_FullMethodName               = "/pb.PaymentService/RefundPayment"
+	PaymentService_ExpireCheckoutSessions_FullMethodName      = "/pb.PaymentService/ExpireCheckoutSessions"
+	PaymentService_RenewCheckoutSession_FullMethodName        = "/pb.PaymentService/RenewCheckoutSession"
+	PaymentService_GetSellerBalance_FullMethodName            = "/pb.PaymentService/GetSellerBalance"
+	PaymentService_ListPayouts_FullMethodName                 = "/pb.PaymentService/ListPayouts"
+	PaymentService_IssueGiftCard_FullMethodName               = "/pb.PaymentService/IssueGiftCard"
+	PaymentService_GetGiftCard_FullMethodName                 = "/pb.PaymentService/GetGiftCard"
+	PaymentService_PurchaseGiftCard_FullMethodName            = "/pb.PaymentService/PurchaseGiftCard"
+	PaymentService_ListGiftCards_FullMethodName               = "/pb.PaymentService/ListGiftCards"
+	PaymentService_ApplyCredit_FullMethodName                 = "/pb.PaymentService/ApplyCredit"
+	PaymentService_CaptureCredit_FullMethodName               = "/pb.PaymentService/CaptureCredit"
+	PaymentService_GetStoreCredit_FullMethodName              = "/pb.PaymentService/GetStoreCredit"
 )
 
 // PaymentServiceClient is the client API for PaymentService service.
@@ -30,6 +42,22 @@ const (
 type PaymentServiceClient interface {
 	CreateCheckoutSession(ctx context.Context, in *CheckoutRequest, opts ...grpc.CallOption) (*wrapperspb.StringValue, error)
 	CreateCustomerPortalSession(ctx context.Context, in *CustomerPortalRequest, opts ...grpc.CallOption) (*wrapperspb.StringValue, error)
+	RefundPayment(ctx context.Context, in *RefundPaymentRequest, opts ...grpc.CallOption) (*Refund, error)
+	ExpireCheckoutSessions(ctx context.Context, in *ExpireCheckoutSessionsRequest, opts ...grpc.CallOption) (*ExpireCheckoutSessionsResponse, error)
+	// Opens a fresh checkout session for an order whose checkout was abandoned
+	RenewCheckoutSession(ctx context.Context, in *RenewCheckoutSessionRequest, opts ...grpc.CallOption) (*wrapperspb.StringValue, error)
+	GetSellerBalance(ctx context.Context, in *SellerBalanceRequest, opts ...grpc.CallOption) (*SellerBalanceResponse, error)
+	ListPayouts(ctx context.Context, in *ListPayoutsRequest, opts ...grpc.CallOption) (*ListPayoutsResponse, error)
+	IssueGiftCard(ctx context.Context, in *IssueGiftCardRequest, opts ...grpc.CallOption) (*GiftCard, error)
+	GetGiftCard(ctx context.Context, in *GetGiftCardRequest, opts ...grpc.CallOption) (*GiftCard, error)
+	// Opens a checkout for a gift card, issued to the buyer once it is paid
+	PurchaseGiftCard(ctx context.Context, in *PurchaseGiftCardRequest, opts ...grpc.CallOption) (*wrapperspb.StringValue, error)
+	ListGiftCards(ctx context.Context, in *ListGiftCardsRequest, opts ...grpc.CallOption) (*ListGiftCardsResponse, error)
+	// Puts a gift card and store credit towards an unpaid order
+	ApplyCredit(ctx context.Context, in *ApplyCreditRequest, opts ...grpc.CallOption) (*ApplyCreditResponse, error)
+	// Takes the payment of an order its credit pays for in full
+	CaptureCredit(ctx context.Context, in *CaptureCreditRequest, opts ...grpc.CallOption) (*CaptureCreditResponse, error)
+	GetStoreCredit(ctx context.Context, in *StoreCreditRequest, opts ...grpc.CallOption) (*StoreCreditResponse, error)
 }
 
 type paymentServiceClient struct {
@@ -60,12 +88,148 @@ func (c *paymentServiceClient) CreateCustomerPortalSession(ctx context.Context,
 	return out, nil
 }
 
+func (c *paymentServiceClient) RefundPayment(ctx context.Context, in *RefundPaymentRequest, opts ...grpc.CallOption) (*Refund, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Refund)
+	err := c.cc.Invoke(ctx, PaymentService_RefundPayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ExpireCheckoutSessions(ctx context.Context, in *ExpireCheckoutSessionsRequest, opts ...grpc.CallOption) (*ExpireCheckoutSessionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExpireCheckoutSessionsResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ExpireCheckoutSessions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) RenewCheckoutSession(ctx context.Context, in *RenewCheckoutSessionRequest, opts ...grpc.CallOption) (*wrapperspb.StringValue, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(wrapperspb.StringValue)
+	err := c.cc.Invoke(ctx, PaymentService_RenewCheckoutSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetSellerBalance(ctx context.Context, in *SellerBalanceRequest, opts ...grpc.CallOption) (*SellerBalanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SellerBalanceResponse)
+	err := c.cc.Invoke(ctx, PaymentService_GetSellerBalance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListPayouts(ctx context.Context, in *ListPayoutsRequest, opts ...grpc.CallOption) (*ListPayoutsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPayoutsResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListPayouts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) IssueGiftCard(ctx context.Context, in *IssueGiftCardRequest, opts ...grpc.CallOption) (*GiftCard, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GiftCard)
+	err := c.cc.Invoke(ctx, PaymentService_IssueGiftCard_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetGiftCard(ctx context.Context, in *GetGiftCardRequest, opts ...grpc.CallOption) (*GiftCard, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GiftCard)
+	err := c.cc.Invoke(ctx, PaymentService_GetGiftCard_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) PurchaseGiftCard(ctx context.Context, in *PurchaseGiftCardRequest, opts ...grpc.CallOption) (*wrapperspb.StringValue, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(wrapperspb.StringValue)
+	err := c.cc.Invoke(ctx, PaymentService_PurchaseGiftCard_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ListGiftCards(ctx context.Context, in *ListGiftCardsRequest, opts ...grpc.CallOption) (*ListGiftCardsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGiftCardsResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ListGiftCards_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) ApplyCredit(ctx context.Context, in *ApplyCreditRequest, opts ...grpc.CallOption) (*ApplyCreditResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ApplyCreditResponse)
+	err := c.cc.Invoke(ctx, PaymentService_ApplyCredit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CaptureCredit(ctx context.Context, in *CaptureCreditRequest, opts ...grpc.CallOption) (*CaptureCreditResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CaptureCreditResponse)
+	err := c.cc.Invoke(ctx, PaymentService_CaptureCredit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetStoreCredit(ctx context.Context, in *StoreCreditRequest, opts ...grpc.CallOption) (*StoreCreditResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StoreCreditResponse)
+	err := c.cc.Invoke(ctx, PaymentService_GetStoreCredit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PaymentServiceServer is the server API for PaymentService service.
 // All implementations must embed UnimplementedPaymentServiceServer
 // for forward compatibility.
 type PaymentServiceServer interface {
 	CreateCheckoutSession(context.Context, *CheckoutRequest) (*wrapperspb.StringValue, error)
 	CreateCustomerPortalSession(context.Context, *CustomerPortalRequest) (*wrapperspb.StringValue, error)
+	RefundPayment(context.Context, *RefundPaymentRequest) (*Refund, error)
+	ExpireCheckoutSessions(context.Context, *ExpireCheckoutSessionsRequest) (*ExpireCheckoutSessionsResponse, error)
+	// Opens a fresh checkout session for an order whose checkout was abandoned
+	RenewCheckoutSession(context.Context, *RenewCheckoutSessionRequest) (*wrapperspb.StringValue, error)
+	GetSellerBalance(context.Context, *SellerBalanceRequest) (*SellerBalanceResponse, error)
+	ListPayouts(context.Context, *ListPayoutsRequest) (*ListPayoutsResponse, error)
+	IssueGiftCard(context.Context, *IssueGiftCardRequest) (*GiftCard, error)
+	GetGiftCard(context.Context, *GetGiftCardRequest) (*GiftCard, error)
+	// Opens a checkout for a gift card, issued to the buyer once it is paid
+	PurchaseGiftCard(context.Context, *PurchaseGiftCardRequest) (*wrapperspb.StringValue, error)
+	ListGiftCards(context.Context, *ListGiftCardsRequest) (*ListGiftCardsResponse, error)
+	// Puts a gift card and store credit towards an unpaid order
+	ApplyCredit(context.Context, *ApplyCreditRequest) (*ApplyCreditResponse, error)
+	// Takes the payment of an order its credit pays for in full
+	CaptureCredit(context.Context, *CaptureCreditRequest) (*CaptureCreditResponse, error)
+	GetStoreCredit(context.Context, *StoreCreditRequest) (*StoreCreditResponse, error)
 	mustEmbedUnimplementedPaymentServiceServer()
 }
 
@@ -82,6 +246,42 @@ func (UnimplementedPaymentServiceServer) CreateCheckoutSession(context.Context,
 func (UnimplementedPaymentServiceServer) CreateCustomerPortalSession(context.Context, *CustomerPortalRequest) (*wrapperspb.StringValue, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateCustomerPortalSession not implemented")
 }
+func (UnimplementedPaymentServiceServer) RefundPayment(context.Context, *RefundPaymentRequest) (*Refund, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefundPayment not implemented")
+}
+func (UnimplementedPaymentServiceServer) ExpireCheckoutSessions(context.Context, *ExpireCheckoutSessionsRequest) (*ExpireCheckoutSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExpireCheckoutSessions not implemented")
+}
+func (UnimplementedPaymentServiceServer) RenewCheckoutSession(context.Context, *RenewCheckoutSessionRequest) (*wrapperspb.StringValue, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewCheckoutSession not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetSellerBalance(context.Context, *SellerBalanceRequest) (*SellerBalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSellerBalance not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListPayouts(context.Context, *ListPayoutsRequest) (*ListPayoutsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPayouts not implemented")
+}
+func (UnimplementedPaymentServiceServer) IssueGiftCard(context.Context, *IssueGiftCardRequest) (*GiftCard, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueGiftCard not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetGiftCard(context.Context, *GetGiftCardRequest) (*GiftCard, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGiftCard not implemented")
+}
+func (UnimplementedPaymentServiceServer) PurchaseGiftCard(context.Context, *PurchaseGiftCardRequest) (*wrapperspb.StringValue, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurchaseGiftCard not implemented")
+}
+func (UnimplementedPaymentServiceServer) ListGiftCards(context.Context, *ListGiftCardsRequest) (*ListGiftCardsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGiftCards not implemented")
+}
+func (UnimplementedPaymentServiceServer) ApplyCredit(context.Context, *ApplyCreditRequest) (*ApplyCreditResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyCredit not implemented")
+}
+func (UnimplementedPaymentServiceServer) CaptureCredit(context.Context, *CaptureCreditRequest) (*CaptureCreditResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CaptureCredit not implemented")
+}
+func (UnimplementedPaymentServiceServer) GetStoreCredit(context.Context, *StoreCreditRequest) (*StoreCreditResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStoreCredit not implemented")
+}
 func (UnimplementedPaymentServiceServer) mustEmbedUnimplementedPaymentServiceServer() {}
 func (UnimplementedPaymentServiceServer) testEmbeddedByValue()                        {}
 
@@ -139,6 +339,222 @@ func _PaymentService_CreateCustomerPortalSession_Handler(srv interface{}, ctx co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PaymentService_RefundPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefundPaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).RefundPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_RefundPayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).RefundPayment(ctx, req.(*RefundPaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ExpireCheckoutSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpireCheckoutSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ExpireCheckoutSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ExpireCheckoutSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ExpireCheckoutSessions(ctx, req.(*ExpireCheckoutSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_RenewCheckoutSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewCheckoutSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).RenewCheckoutSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_RenewCheckoutSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).RenewCheckoutSession(ctx, req.(*RenewCheckoutSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetSellerBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SellerBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetSellerBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetSellerBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetSellerBalance(ctx, req.(*SellerBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListPayouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPayoutsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListPayouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListPayouts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListPayouts(ctx, req.(*ListPayoutsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_IssueGiftCard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueGiftCardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).IssueGiftCard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_IssueGiftCard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).IssueGiftCard(ctx, req.(*IssueGiftCardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetGiftCard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGiftCardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetGiftCard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetGiftCard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetGiftCard(ctx, req.(*GetGiftCardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_PurchaseGiftCard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurchaseGiftCardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).PurchaseGiftCard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_PurchaseGiftCard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).PurchaseGiftCard(ctx, req.(*PurchaseGiftCardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ListGiftCards_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGiftCardsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ListGiftCards(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ListGiftCards_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ListGiftCards(ctx, req.(*ListGiftCardsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_ApplyCredit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyCreditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).ApplyCredit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_ApplyCredit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).ApplyCredit(ctx, req.(*ApplyCreditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CaptureCredit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CaptureCreditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CaptureCredit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_CaptureCredit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CaptureCredit(ctx, req.(*CaptureCreditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetStoreCredit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreCreditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetStoreCredit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaymentService_GetStoreCredit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetStoreCredit(ctx, req.(*StoreCreditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PaymentService_ServiceDesc is the grpc.ServiceDesc for PaymentService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -154,6 +570,54 @@ var PaymentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateCustomerPortalSession",
 			Handler:    _PaymentService_CreateCustomerPortalSession_Handler,
 		},
+		{
+			MethodName: "RefundPayment",
+			Handler:    _PaymentService_RefundPayment_Handler,
+		},
+		{
+			MethodName: "ExpireCheckoutSessions",
+			Handler:    _PaymentService_ExpireCheckoutSessions_Handler,
+		},
+		{
+			MethodName: "RenewCheckoutSession",
+			Handler:    _PaymentService_RenewCheckoutSession_Handler,
+		},
+		{
+			MethodName: "GetSellerBalance",
+			Handler:    _PaymentService_GetSellerBalance_Handler,
+		},
+		{
+			MethodName: "ListPayouts",
+			Handler:    _PaymentService_ListPayouts_Handler,
+		},
+		{
+			MethodName: "IssueGiftCard",
+			Handler:    _PaymentService_IssueGiftCard_Handler,
+		},
+		{
+			MethodName: "GetGiftCard",
+			Handler:    _PaymentService_GetGiftCard_Handler,
+		},
+		{
+			MethodName: "PurchaseGiftCard",
+			Handler:    _PaymentService_PurchaseGiftCard_Handler,
+		},
+		{
+			MethodName: "ListGiftCards",
+			Handler:    _PaymentService_ListGiftCards_Handler,
+		},
+		{
+			MethodName: "ApplyCredit",
+			Handler:    _PaymentService_ApplyCredit_Handler,
+		},
+		{
+			MethodName: "CaptureCredit",
+			Handler:    _PaymentService_CaptureCredit_Handler,
+		},
+		{
+			MethodName: "GetStoreCredit",
+			Handler:    _PaymentService_GetStoreCredit_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "payment.proto",
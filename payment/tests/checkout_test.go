@@ -0,0 +1,339 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama/mocks"
+	"github.com/dodopayments/dodopayments-go"
+	ordermodels "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/payment/internal"
+	"github.com/rasadov/EcommerceAPI/payment/models"
+	"github.com/rasadov/EcommerceAPI/payment/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCheckoutClient opens numbered checkout sessions and records the carts they were opened for.
+type fakeCheckoutClient struct {
+	internal.PaymentClient
+	carts          [][]dodopayments.CheckoutSessionRequestProductCartParam
+	metadata       []map[string]string
+	chargeProducts []dodopayments.Currency
+	refunds        [][]dodopayments.RefundNewParamsItem
+	// webhook is the event the next webhook delivers
+	webhook *models.WebhookEvent
+}
+
+func (c *fakeCheckoutClient) HandleWebhook(w http.ResponseWriter, r *http.Request) (*models.WebhookEvent, error) {
+	return c.webhook, nil
+}
+
+func (c *fakeCheckoutClient) CreateRefund(ctx context.Context, paymentId string, items []dodopayments.RefundNewParamsItem, reason string) (*models.Refund, error) {
+	c.refunds = append(c.refunds, items)
+	var amount int64
+	for _, item := range items {
+		amount += item.Amount.Value
+	}
+	refundId := fmt.Sprintf("ref_%d", len(c.refunds))
+	return &models.Refund{PaymentId: paymentId, RefundId: refundId, Amount: amount, Currency: "USD", IsPartial: len(items) > 0}, nil
+}
+
+func (c *fakeCheckoutClient) CreateCheckoutSession(ctx context.Context,
+	userId uint64,
+	customerId string, redirect string,
+	dodoProducts []dodopayments.CheckoutSessionRequestProductCartParam, metadata map[string]string,
+	currency dodopayments.Currency) (string, string, error) {
+	c.carts = append(c.carts, dodoProducts)
+	c.metadata = append(c.metadata, metadata)
+	sessionId := fmt.Sprintf("cs_%d", len(c.carts))
+	return "https://checkout.test/" + sessionId, sessionId, nil
+}
+
+func (c *fakeCheckoutClient) CreateChargeProduct(ctx context.Context, currency dodopayments.Currency) (*dodopayments.Product, error) {
+	c.chargeProducts = append(c.chargeProducts, currency)
+	return &dodopayments.Product{ProductID: "pdt_charge_" + string(currency)}, nil
+}
+
+// charged returns the amount the nth checkout opened charges.
+func (c *fakeCheckoutClient) charged(t *testing.T, n int) int64 {
+	require.Greater(t, len(c.carts), n)
+	require.Len(t, c.carts[n], 1)
+	assert.Equal(t, int64(1), c.carts[n][0].Quantity.Value)
+	return c.carts[n][0].Amount.Value
+}
+
+// setupCheckout opens a checkout session for order 1 of user 5, for two of product p1.
+func setupCheckout(t *testing.T, ctx context.Context) (internal.Repository, internal.Service, *fakeCheckoutClient) {
+	repository := setupTestRepository(t)
+	client := &fakeCheckoutClient{}
+	service := internal.NewPaymentService(client, repository, 1000)
+
+	require.NoError(t, repository.SaveProduct(ctx, &models.Product{ProductID: "p1", DodoProductID: "pdt_1", Price: 1000, Currency: "USD"}))
+	require.NoError(t, repository.SaveCustomer(ctx, &models.Customer{UserId: 5, CustomerId: "cus_5", BillingEmail: "ann@example.com", BillingName: "Ann"}))
+
+	checkoutURL, err := service.CreateCheckoutSession(ctx, 5, "cus_5", "https://shop.test/orders/1",
+		[]*pb.CartItem{{ProductId: "p1", Quantity: 2}}, 1, money.New(2000, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://checkout.test/cs_1", checkoutURL)
+	return repository, service, client
+}
+
+func TestPaymentService_CheckoutCharge(t *testing.T) {
+	ctx := context.Background()
+	// A placed order of 69.99 EUR, 6.99 off with a promotion and 9.81 of VAT added on top
+	placed := ordermodels.Order{
+		ID:         2,
+		TotalPrice: money.New(6999-699+981, "EUR"),
+		Discounts:  []*ordermodels.OrderDiscount{{Code: "TENOFF", Amount: money.New(699, "EUR")}},
+		Taxes: []*ordermodels.OrderTax{
+			{ProductID: "book", Rate: "0.07", Amount: money.New(126, "EUR")},
+			{ProductID: "lamp", Rate: "0.19", Amount: money.New(855, "EUR")},
+		},
+	}
+
+	t.Run("Checkouts charge the order total with its taxes", func(t *testing.T) {
+		_, service, client := setupCheckout(t, ctx)
+
+		_, err := service.CreateCheckoutSession(ctx, 5, "cus_5", "https://shop.test/orders/2",
+			[]*pb.CartItem{{ProductId: "p1", Quantity: 1}}, uint64(placed.ID), placed.TotalPrice)
+		require.NoError(t, err)
+
+		assert.Equal(t, "pdt_charge_EUR", client.carts[1][0].ProductID.Value)
+		subtotal, err := placed.Subtotal()
+		require.NoError(t, err)
+		taxes, err := placed.TaxTotal()
+		require.NoError(t, err)
+		assert.Equal(t, subtotal.Amount-699+taxes.Amount, client.charged(t, 1))
+		assert.Equal(t, placed.TotalPrice.Amount, client.charged(t, 1))
+		assert.Equal(t, []dodopayments.Currency{"USD", "EUR"}, client.chargeProducts)
+	})
+
+	t.Run("Checkouts charge the order's shipping", func(t *testing.T) {
+		_, service, client := setupCheckout(t, ctx)
+		shipped := placed
+		shipped.ShippingMethod = "express"
+		shipped.ShippingCost = money.New(1490, "EUR")
+		shipped.TotalPrice = money.New(6999-699+981+1490, "EUR")
+
+		_, err := service.CreateCheckoutSession(ctx, 5, "cus_5", "https://shop.test/orders/2",
+			[]*pb.CartItem{{ProductId: "p1", Quantity: 1}}, uint64(shipped.ID), shipped.TotalPrice)
+		require.NoError(t, err)
+
+		subtotal, err := shipped.Subtotal()
+		require.NoError(t, err)
+		assert.Equal(t, money.New(6999, "EUR"), subtotal)
+		assert.Equal(t, shipped.TotalPrice.Amount, client.charged(t, 1))
+	})
+
+	t.Run("Credit put towards the order is taken off exactly", func(t *testing.T) {
+		repository, service, client := setupCheckout(t, ctx)
+		require.NoError(t, repository.AddStoreCredit(ctx, &models.StoreCreditTransaction{
+			UserId: 5, Kind: models.StoreCreditRefund, Amount: money.New(1234, "EUR"), Reference: "refund:test",
+		}))
+		_, err := service.ApplyCredit(ctx, 2, 5, placed.TotalPrice, "", true)
+		require.NoError(t, err)
+
+		_, err = service.CreateCheckoutSession(ctx, 5, "cus_5", "https://shop.test/orders/2",
+			[]*pb.CartItem{{ProductId: "p1", Quantity: 1}}, 2, placed.TotalPrice)
+		require.NoError(t, err)
+
+		assert.Equal(t, placed.TotalPrice.Amount-1234, client.charged(t, 1))
+	})
+
+	t.Run("Orders covered by credit are not checked out", func(t *testing.T) {
+		repository, service, client := setupCheckout(t, ctx)
+		require.NoError(t, repository.AddStoreCredit(ctx, &models.StoreCreditTransaction{
+			UserId: 5, Kind: models.StoreCreditRefund, Amount: money.New(10000, "EUR"), Reference: "refund:test",
+		}))
+		_, err := service.ApplyCredit(ctx, 2, 5, placed.TotalPrice, "", true)
+		require.NoError(t, err)
+
+		_, err = service.CreateCheckoutSession(ctx, 5, "cus_5", "https://shop.test/orders/2",
+			[]*pb.CartItem{{ProductId: "p1", Quantity: 1}}, 2, placed.TotalPrice)
+		assert.ErrorIs(t, err, internal.ErrNothingDue)
+		assert.Len(t, client.carts, 1)
+	})
+
+	t.Run("Refunds are taken from the charge", func(t *testing.T) {
+		repository, service, client := setupCheckout(t, ctx)
+		require.NoError(t, repository.RegisterTransaction(ctx, &models.Transaction{OrderId: 1, UserId: 5, PaymentId: "pay_1",
+			TotalPrice: 2000, Currency: "USD", Status: models.Success.String()}))
+
+		_, err := service.RefundPayment(ctx, 1, []*pb.RefundItem{
+			{ProductId: "p1", Amount: money.New(700, "USD").ToProto()},
+			{ProductId: "p1", Amount: money.New(300, "USD").ToProto()},
+		}, "return", false)
+		require.NoError(t, err)
+		require.Len(t, client.refunds, 1)
+		require.Len(t, client.refunds[0], 1)
+		assert.Equal(t, "pdt_charge_USD", client.refunds[0][0].ItemID.Value)
+		assert.Equal(t, int64(1000), client.refunds[0][0].Amount.Value)
+
+		_, err = service.RefundPayment(ctx, 1, []*pb.RefundItem{{ProductId: "p1"}}, "return", false)
+		assert.ErrorIs(t, err, internal.ErrRefundAmountRequired)
+	})
+
+	t.Run("Full refunds after partial ones refund what is left", func(t *testing.T) {
+		repository, service, client := setupCheckout(t, ctx)
+		require.NoError(t, repository.RegisterTransaction(ctx, &models.Transaction{OrderId: 1, UserId: 5, PaymentId: "pay_1",
+			TotalPrice: 2000, Currency: "USD", Status: models.Success.String()}))
+		_, err := service.RefundPayment(ctx, 1, []*pb.RefundItem{{ProductId: "p1", Amount: money.New(700, "USD").ToProto()}}, "return", false)
+		require.NoError(t, err)
+
+		refund, err := service.RefundPayment(ctx, 1, nil, "cancelled", false)
+
+		require.NoError(t, err)
+		assert.False(t, refund.IsPartial)
+		require.Len(t, client.refunds, 2)
+		require.Len(t, client.refunds[1], 1)
+		assert.Equal(t, "pdt_charge_USD", client.refunds[1][0].ItemID.Value)
+		assert.Equal(t, int64(1300), client.refunds[1][0].Amount.Value)
+
+		again, err := service.RefundPayment(ctx, 1, nil, "cancelled", false)
+		require.NoError(t, err)
+		assert.Equal(t, refund.RefundId, again.RefundId)
+		assert.Len(t, client.refunds, 2)
+	})
+
+	t.Run("Full refunds of product checkouts share what is left between the products", func(t *testing.T) {
+		repository := setupTestRepository(t)
+		client := &fakeCheckoutClient{}
+		service := internal.NewPaymentService(client, repository, 1000)
+		require.NoError(t, repository.SaveProduct(ctx, &models.Product{ProductID: "p1", DodoProductID: "pdt_1", Price: 1000, Currency: "USD"}))
+		require.NoError(t, repository.SaveProduct(ctx, &models.Product{ProductID: "p2", DodoProductID: "pdt_2", Price: 3000, Currency: "USD"}))
+		require.NoError(t, repository.SaveCheckoutSession(ctx, &models.CheckoutSession{OrderId: 3, SessionId: "cs_legacy", Status: models.CheckoutCompleted,
+			Currency: "USD", Items: []models.CheckoutItem{{ProductId: "p1", Quantity: 1}, {ProductId: "p2", Quantity: 1}}}))
+		require.NoError(t, repository.RegisterTransaction(ctx, &models.Transaction{OrderId: 3, UserId: 5, PaymentId: "pay_3",
+			TotalPrice: 4000, Currency: "USD", Status: models.Success.String()}))
+		_, err := service.RefundPayment(ctx, 3, []*pb.RefundItem{{ProductId: "p2", Amount: money.New(2000, "USD").ToProto()}}, "return", false)
+		require.NoError(t, err)
+
+		_, err = service.RefundPayment(ctx, 3, nil, "cancelled", false)
+
+		require.NoError(t, err)
+		require.Len(t, client.refunds, 2)
+		require.Len(t, client.refunds[1], 2)
+		assert.Equal(t, "pdt_1", client.refunds[1][0].ItemID.Value)
+		assert.Equal(t, int64(500), client.refunds[1][0].Amount.Value)
+		assert.Equal(t, "pdt_2", client.refunds[1][1].ItemID.Value)
+		assert.Equal(t, int64(1500), client.refunds[1][1].Amount.Value)
+	})
+}
+
+func TestPaymentService_AbandonedCheckout(t *testing.T) {
+	ctx := context.Background()
+	later := time.Now().UTC().Add(time.Minute)
+
+	t.Run("Sessions are claimed once after the delay", func(t *testing.T) {
+		_, service, _ := setupCheckout(t, ctx)
+
+		abandoned, err := service.ClaimAbandonedCheckouts(ctx, time.Now().UTC().Add(-time.Hour), 10)
+		require.NoError(t, err)
+		assert.Empty(t, abandoned)
+
+		abandoned, err = service.ClaimAbandonedCheckouts(ctx, later, 10)
+		require.NoError(t, err)
+		require.Len(t, abandoned, 1)
+		assert.Equal(t, uint64(1), abandoned[0].Session.OrderId)
+		assert.Equal(t, models.CheckoutAbandoned, abandoned[0].Session.Status)
+		assert.Equal(t, "https://checkout.test/cs_1", abandoned[0].Session.CheckoutURL)
+		assert.Equal(t, "USD", abandoned[0].Session.Currency)
+		assert.Equal(t, "ann@example.com", abandoned[0].Email)
+		assert.Equal(t, "Ann", abandoned[0].Name)
+
+		abandoned, err = service.ClaimAbandonedCheckouts(ctx, later, 10)
+		require.NoError(t, err)
+		assert.Empty(t, abandoned)
+	})
+
+	t.Run("Paid sessions are not abandoned", func(t *testing.T) {
+		repository, service, _ := setupCheckout(t, ctx)
+
+		completed, err := repository.CompleteCheckoutSessions(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), completed)
+
+		abandoned, err := service.ClaimAbandonedCheckouts(ctx, later, 10)
+		require.NoError(t, err)
+		assert.Empty(t, abandoned)
+	})
+
+	t.Run("Renewal reopens the same cart once abandoned", func(t *testing.T) {
+		repository, service, client := setupCheckout(t, ctx)
+
+		_, err := service.RenewCheckoutSession(ctx, 1, money.New(2000, "USD"))
+		assert.ErrorIs(t, err, internal.ErrCheckoutNotAbandoned)
+		_, err = service.RenewCheckoutSession(ctx, 2, money.New(2000, "USD"))
+		assert.ErrorIs(t, err, internal.ErrNoCheckout)
+
+		_, err = service.ClaimAbandonedCheckouts(ctx, later, 10)
+		require.NoError(t, err)
+
+		checkoutURL, err := service.RenewCheckoutSession(ctx, 1, money.New(2000, "USD"))
+		require.NoError(t, err)
+		assert.Equal(t, "https://checkout.test/cs_2", checkoutURL)
+		require.Len(t, client.carts, 2)
+		assert.Equal(t, "pdt_charge_USD", client.carts[1][0].ProductID.Value)
+		assert.Equal(t, int64(2000), client.charged(t, 1))
+		assert.Len(t, client.chargeProducts, 1)
+
+		session, err := repository.GetLatestCheckoutSession(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "cs_2", session.SessionId)
+		assert.Equal(t, models.CheckoutOpen, session.Status)
+		assert.True(t, session.Reminder)
+
+		// The reminder session is not reminded about again
+		abandoned, err := service.ClaimAbandonedCheckouts(ctx, time.Now().UTC().Add(time.Hour), 10)
+		require.NoError(t, err)
+		assert.Empty(t, abandoned)
+
+		_, err = service.RenewCheckoutSession(ctx, 1, money.New(2000, "USD"))
+		assert.ErrorIs(t, err, internal.ErrCheckoutNotAbandoned)
+
+		completed, err := repository.CompleteCheckoutSessions(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), completed)
+	})
+}
+
+func TestAbandonmentDetector_DetectAbandoned(t *testing.T) {
+	ctx := context.Background()
+	_, service, _ := setupCheckout(t, ctx)
+
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	producer := mocks.NewAsyncProducer(t, config)
+	producer.ExpectInputAndSucceed()
+	t.Cleanup(func() {
+		require.NoError(t, producer.Close())
+	})
+
+	detector := internal.NewAbandonmentDetector(service, producer, "payment_events", -time.Minute)
+	abandoned, err := detector.DetectAbandoned(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, abandoned)
+
+	message := <-producer.Successes()
+	assert.Equal(t, "payment_events", message.Topic)
+	value, err := message.Value.Encode()
+	require.NoError(t, err)
+
+	var event models.CheckoutEvent
+	require.NoError(t, json.Unmarshal(value, &event))
+	assert.Equal(t, "checkout_abandoned", event.Type)
+	assert.Equal(t, uint64(1), event.Data.OrderId)
+	assert.Equal(t, uint64(5), event.Data.UserId)
+	assert.Equal(t, "ann@example.com", event.Data.Email)
+	assert.Equal(t, "https://checkout.test/cs_1", event.Data.CheckoutURL)
+
+	abandoned, err = detector.DetectAbandoned(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, abandoned)
+}
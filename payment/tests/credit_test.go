@@ -0,0 +1,392 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dodopayments/dodopayments-go"
+	"github.com/rasadov/EcommerceAPI/payment/internal"
+	"github.com/rasadov/EcommerceAPI/payment/models"
+	"github.com/rasadov/EcommerceAPI/payment/proto/pb"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeRefundClient records the card refunds it is asked for.
+type fakeRefundClient struct {
+	internal.PaymentClient
+	refunds [][]dodopayments.RefundNewParamsItem
+}
+
+func (c *fakeRefundClient) CreateRefund(ctx context.Context, paymentId string, items []dodopayments.RefundNewParamsItem, reason string) (*models.Refund, error) {
+	c.refunds = append(c.refunds, items)
+	var amount int64
+	for _, item := range items {
+		amount += item.Amount.Value
+	}
+	return &models.Refund{PaymentId: paymentId, RefundId: "ref_card", Amount: amount, Currency: "EUR", IsPartial: len(items) > 0}, nil
+}
+
+// setupCredit issues a gift card GIFT-0001 of 30.00 EUR and gives user 5 store credit of
+// 50.00 EUR.
+func setupCredit(t *testing.T, ctx context.Context) (internal.Repository, internal.Service) {
+	repository := setupTestRepository(t)
+	service := internal.NewPaymentService(nil, repository, 1000)
+
+	_, err := service.IssueGiftCard(ctx, &models.GiftCard{Code: " gift-0001 ", Initial: money.New(3000, "eur")})
+	require.NoError(t, err)
+	require.NoError(t, repository.AddStoreCredit(ctx, &models.StoreCreditTransaction{
+		UserId:    5,
+		Kind:      models.StoreCreditRefund,
+		Amount:    money.New(5000, "EUR"),
+		Reference: "refund:test",
+	}))
+	return repository, service
+}
+
+func storeCredit(t *testing.T, ctx context.Context, service internal.Service, userId uint64) int64 {
+	balances, err := service.GetStoreCredit(ctx, userId)
+	require.NoError(t, err)
+	var amount int64
+	for _, balance := range balances {
+		assert.Equal(t, "EUR", balance.Currency)
+		amount += balance.Amount
+	}
+	return amount
+}
+
+func giftCardBalance(t *testing.T, ctx context.Context, service internal.Service, code string) int64 {
+	card, err := service.GetGiftCard(ctx, code, 0)
+	require.NoError(t, err)
+	return card.Balance.Amount
+}
+
+func TestPaymentService_GiftCards(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Issued cards get a code and their initial balance", func(t *testing.T) {
+		_, service := setupCredit(t, ctx)
+
+		card, err := service.IssueGiftCard(ctx, &models.GiftCard{Initial: money.New(2500, "usd"), IssuedBy: "account:1"})
+
+		require.NoError(t, err)
+		assert.Len(t, card.Code, 19)
+		assert.Equal(t, money.New(2500, "USD"), card.Balance)
+		found, err := service.GetGiftCard(ctx, card.Code, 0)
+		require.NoError(t, err)
+		assert.Equal(t, card.ID, found.ID)
+	})
+
+	t.Run("Invalid cards are not issued", func(t *testing.T) {
+		_, service := setupCredit(t, ctx)
+
+		_, err := service.IssueGiftCard(ctx, &models.GiftCard{Initial: money.New(0, "EUR")})
+		assert.ErrorIs(t, err, models.ErrInvalidGiftCard)
+		_, err = service.IssueGiftCard(ctx, &models.GiftCard{Code: "short", Initial: money.New(100, "EUR")})
+		assert.ErrorIs(t, err, models.ErrInvalidGiftCard)
+		_, err = service.IssueGiftCard(ctx, &models.GiftCard{Code: "GIFT-0001", Initial: money.New(100, "EUR")})
+		assert.Error(t, err)
+	})
+
+	t.Run("Gift card and store credit pay part of an order", func(t *testing.T) {
+		_, service := setupCredit(t, ctx)
+
+		applied, err := service.ApplyCredit(ctx, 2, 5, money.New(10000, "EUR"), "gift-0001", true)
+
+		require.NoError(t, err)
+		require.Len(t, applied.Credits, 2)
+		assert.Equal(t, models.CreditGiftCard, applied.Credits[0].Kind)
+		assert.Equal(t, money.New(3000, "EUR"), applied.Credits[0].Amount)
+		assert.Equal(t, money.New(5000, "EUR"), applied.Credits[1].Amount)
+		assert.Equal(t, money.New(2000, "EUR"), applied.Remaining)
+		assert.Zero(t, giftCardBalance(t, ctx, service, "GIFT-0001"))
+		assert.Zero(t, storeCredit(t, ctx, service, 5))
+
+		page, err := service.ListStoreCreditTransactions(ctx, 5, "", 1)
+		require.NoError(t, err)
+		require.Len(t, page.Transactions, 1)
+		assert.Equal(t, models.StoreCreditSpent, page.Transactions[0].Kind)
+		assert.Equal(t, money.New(-5000, "EUR"), page.Transactions[0].Amount)
+		assert.True(t, page.HasNextPage)
+
+		_, err = service.ApplyCredit(ctx, 2, 5, money.New(10000, "EUR"), "GIFT-0001", false)
+		assert.ErrorIs(t, err, internal.ErrCreditApplied)
+	})
+
+	t.Run("Cards pay no more than the order total", func(t *testing.T) {
+		_, service := setupCredit(t, ctx)
+
+		applied, err := service.ApplyCredit(ctx, 2, 5, money.New(1200, "EUR"), "GIFT-0001", true)
+
+		require.NoError(t, err)
+		require.Len(t, applied.Credits, 1)
+		assert.True(t, applied.Remaining.IsZero())
+		assert.Equal(t, int64(1800), giftCardBalance(t, ctx, service, "GIFT-0001"))
+		assert.Equal(t, int64(5000), storeCredit(t, ctx, service, 5))
+	})
+
+	t.Run("Unusable cards are refused", func(t *testing.T) {
+		_, service := setupCredit(t, ctx)
+		expired := time.Now().UTC().Add(-time.Hour)
+		_, err := service.IssueGiftCard(ctx, &models.GiftCard{Code: "OLD-CARD-1", Initial: money.New(1000, "EUR"), ExpiresAt: &expired})
+		require.NoError(t, err)
+
+		_, err = service.ApplyCredit(ctx, 2, 5, money.New(1000, "EUR"), "OLD-CARD-1", false)
+		assert.ErrorIs(t, err, models.ErrGiftCardExpired)
+		_, err = service.ApplyCredit(ctx, 2, 5, money.New(1000, "EUR"), "NO-SUCH-CARD", false)
+		assert.ErrorIs(t, err, internal.ErrGiftCardNotFound)
+		_, err = service.ApplyCredit(ctx, 2, 5, money.New(1000, "USD"), "GIFT-0001", false)
+		assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+		assert.Equal(t, int64(3000), giftCardBalance(t, ctx, service, "GIFT-0001"))
+	})
+
+	t.Run("Expiring an unpaid order gives its credit back", func(t *testing.T) {
+		_, service := setupCredit(t, ctx)
+		_, err := service.ApplyCredit(ctx, 2, 5, money.New(10000, "EUR"), "GIFT-0001", true)
+		require.NoError(t, err)
+
+		_, err = service.ExpireCheckoutSessions(ctx, 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(3000), giftCardBalance(t, ctx, service, "GIFT-0001"))
+		assert.Equal(t, int64(5000), storeCredit(t, ctx, service, 5))
+		page, err := service.ListStoreCreditTransactions(ctx, 5, "", 0)
+		require.NoError(t, err)
+		require.Len(t, page.Transactions, 3)
+		assert.Equal(t, models.StoreCreditReleased, page.Transactions[0].Kind)
+
+		// Released credit can be applied again
+		_, err = service.ApplyCredit(ctx, 2, 5, money.New(10000, "EUR"), "GIFT-0001", false)
+		assert.NoError(t, err)
+	})
+}
+
+func TestPaymentService_GiftCardPurchases(t *testing.T) {
+	ctx := context.Background()
+
+	// paid delivers the webhook of the purchase's checkout
+	paid := func(t *testing.T, service internal.Service, client *fakeCheckoutClient, purchaseId uint, status string) {
+		client.webhook = &models.WebhookEvent{
+			Type:             "payment.succeeded",
+			GiftCardPurchase: &models.GiftCardPurchase{ID: purchaseId, UserId: 7, PaymentId: "pay_gift", Status: status},
+		}
+		_, err := service.HandlePaymentWebhook(ctx, nil, nil)
+		require.NoError(t, err)
+	}
+
+	t.Run("Bought cards are issued to the buyer once paid", func(t *testing.T) {
+		repository := setupTestRepository(t)
+		client := &fakeCheckoutClient{}
+		service := internal.NewPaymentService(client, repository, 1000)
+
+		checkoutURL, err := service.PurchaseGiftCard(ctx, 7, "cus_7", "https://shop.test/gift-cards", money.New(2500, "usd"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://checkout.test/cs_1", checkoutURL)
+		assert.Equal(t, int64(2500), client.charged(t, 0))
+		assert.Equal(t, "1", client.metadata[0]["gift_card_purchase_id"])
+		cards, err := service.ListGiftCards(ctx, 7)
+		require.NoError(t, err)
+		assert.Empty(t, cards)
+
+		paid(t, service, client, 1, models.PurchasePaid)
+		// Redelivered webhooks issue no second card
+		paid(t, service, client, 1, models.PurchasePaid)
+
+		cards, err = service.ListGiftCards(ctx, 7)
+		require.NoError(t, err)
+		require.Len(t, cards, 1)
+		assert.Equal(t, money.New(2500, "USD"), cards[0].Initial)
+		assert.Equal(t, money.New(2500, "USD"), cards[0].Balance)
+		assert.Equal(t, uint64(7), cards[0].OwnerId)
+		assert.Equal(t, "purchase:1", cards[0].IssuedBy)
+		found, err := service.GetGiftCard(ctx, cards[0].Code, 7)
+		require.NoError(t, err)
+		assert.Equal(t, cards[0].ID, found.ID)
+	})
+
+	t.Run("Unpaid purchases issue no card", func(t *testing.T) {
+		repository := setupTestRepository(t)
+		client := &fakeCheckoutClient{}
+		service := internal.NewPaymentService(client, repository, 1000)
+
+		_, err := service.PurchaseGiftCard(ctx, 7, "cus_7", "https://shop.test/gift-cards", money.New(2500, "USD"))
+		require.NoError(t, err)
+		paid(t, service, client, 1, models.PurchasePending)
+
+		cards, err := service.ListGiftCards(ctx, 7)
+		require.NoError(t, err)
+		assert.Empty(t, cards)
+	})
+
+	t.Run("Cards without a positive amount are not bought", func(t *testing.T) {
+		client := &fakeCheckoutClient{}
+		service := internal.NewPaymentService(client, setupTestRepository(t), 1000)
+
+		_, err := service.PurchaseGiftCard(ctx, 7, "cus_7", "https://shop.test/gift-cards", money.New(0, "USD"))
+		assert.ErrorIs(t, err, models.ErrInvalidGiftCard)
+		_, err = service.PurchaseGiftCard(ctx, 7, "cus_7", "https://shop.test/gift-cards", money.New(2500, "XXX"))
+		assert.ErrorIs(t, err, money.ErrUnknownCurrency)
+		assert.Empty(t, client.carts)
+	})
+
+	t.Run("Cards are only found by their owners and redeemers", func(t *testing.T) {
+		_, service := setupCredit(t, ctx)
+
+		_, err := service.GetGiftCard(ctx, "GIFT-0001", 5)
+		assert.ErrorIs(t, err, internal.ErrGiftCardNotFound)
+		_, err = service.GetGiftCard(ctx, "GIFT-0001", 0)
+		assert.NoError(t, err)
+
+		_, err = service.ApplyCredit(ctx, 2, 5, money.New(1000, "EUR"), "GIFT-0001", false)
+		require.NoError(t, err)
+
+		card, err := service.GetGiftCard(ctx, "gift-0001", 5)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2000), card.Balance.Amount)
+		_, err = service.GetGiftCard(ctx, "GIFT-0001", 6)
+		assert.ErrorIs(t, err, internal.ErrGiftCardNotFound)
+	})
+}
+
+func TestPaymentRepository_OrderCreditsAppliedOnce(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	repository, err := internal.NewPostgresRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(repository.Close)
+	require.NoError(t, repository.AddStoreCredit(ctx, &models.StoreCreditTransaction{
+		UserId:    5,
+		Kind:      models.StoreCreditRefund,
+		Amount:    money.New(5000, "EUR"),
+		Reference: "refund:test",
+	}))
+
+	applied, err := repository.ApplyCredit(ctx, 2, 5, money.New(1000, "EUR"), "", true, time.Now())
+	require.NoError(t, err)
+	require.Len(t, applied.Credits, 1)
+	assert.Equal(t, 1, applied.Credits[0].Seq)
+
+	// A concurrent application past the check still cannot store its credit
+	competing := &models.OrderCredit{OrderId: 2, Seq: 1, UserId: 5, Kind: models.CreditStoreCredit, Amount: money.New(1000, "EUR")}
+	assert.Error(t, db.Create(competing).Error)
+
+	_, err = repository.ReleaseOrderCredits(ctx, 2)
+	require.NoError(t, err)
+	assert.NoError(t, db.Create(competing).Error)
+}
+
+func TestPaymentService_CreditPayments(t *testing.T) {
+	ctx := context.Background()
+	shares := []models.SellerShare{{SellerId: 7, Amount: money.New(6000, "EUR")}}
+
+	t.Run("Orders credit covers are captured and posted", func(t *testing.T) {
+		repository, service := setupCredit(t, ctx)
+		_, err := service.ApplyCredit(ctx, 2, 5, money.New(6000, "EUR"), "GIFT-0001", true)
+		require.NoError(t, err)
+
+		transaction, err := service.CaptureCredit(ctx, 2, 5, money.New(6000, "EUR"), shares)
+
+		require.NoError(t, err)
+		assert.Equal(t, "credit_2", transaction.PaymentId)
+		assert.Equal(t, int64(6000), transaction.CreditAmount)
+		assert.Zero(t, transaction.TotalPrice)
+		assert.Equal(t, int64(5400), owed(t, ctx, service, 7))
+		journal, err := repository.GetJournal(ctx, "payment:credit_2")
+		require.NoError(t, err)
+		assert.NoError(t, journal.Balanced())
+
+		again, err := service.CaptureCredit(ctx, 2, 5, money.New(6000, "EUR"), shares)
+		require.NoError(t, err)
+		assert.Equal(t, transaction.ID, again.ID)
+
+		// Credit spent on a paid order is given back by refunds only
+		_, err = service.ExpireCheckoutSessions(ctx, 2)
+		require.NoError(t, err)
+		assert.Zero(t, giftCardBalance(t, ctx, service, "GIFT-0001"))
+	})
+
+	t.Run("Orders credit does not cover are not captured", func(t *testing.T) {
+		_, service := setupCredit(t, ctx)
+		_, err := service.ApplyCredit(ctx, 2, 5, money.New(10000, "EUR"), "GIFT-0001", false)
+		require.NoError(t, err)
+
+		_, err = service.CaptureCredit(ctx, 2, 5, money.New(10000, "EUR"), shares)
+
+		assert.ErrorIs(t, err, internal.ErrOrderNotCovered)
+	})
+
+	t.Run("Credit payments are refunded to store credit", func(t *testing.T) {
+		_, service := setupCredit(t, ctx)
+		_, err := service.ApplyCredit(ctx, 2, 5, money.New(6000, "EUR"), "GIFT-0001", true)
+		require.NoError(t, err)
+		_, err = service.CaptureCredit(ctx, 2, 5, money.New(6000, "EUR"), shares)
+		require.NoError(t, err)
+
+		refund, err := service.RefundPayment(ctx, 2, nil, "cancelled", false)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.RefundToStoreCredit, refund.Method)
+		assert.Equal(t, models.RefundSucceeded, refund.Status)
+		assert.False(t, refund.IsPartial)
+		assert.Equal(t, int64(6000), refund.Amount)
+		assert.Equal(t, int64(2000+6000), storeCredit(t, ctx, service, 5))
+		assert.Zero(t, owed(t, ctx, service, 7))
+
+		again, err := service.RefundPayment(ctx, 2, nil, "cancelled", false)
+		require.NoError(t, err)
+		assert.Equal(t, refund.RefundId, again.RefundId)
+		assert.Equal(t, int64(8000), storeCredit(t, ctx, service, 5))
+	})
+
+	t.Run("Mixed payments refund the credit before the card", func(t *testing.T) {
+		repository := setupTestRepository(t)
+		client := &fakeRefundClient{}
+		service := internal.NewPaymentService(client, repository, 1000)
+		require.NoError(t, repository.SaveProduct(ctx, &models.Product{ProductID: "p1", DodoProductID: "pdt_1", Price: 5000, Currency: "EUR"}))
+		transaction := &models.Transaction{OrderId: 3, UserId: 5, PaymentId: "pay_3", TotalPrice: 4000, CreditAmount: 6000,
+			Currency: "EUR", Status: models.Success.String()}
+		require.NoError(t, repository.RegisterTransaction(ctx, transaction))
+		require.NoError(t, service.RecordPayment(ctx, transaction, []models.SellerShare{{SellerId: 7, Amount: money.New(10000, "EUR")}}))
+
+		refund, err := service.RefundPayment(ctx, 3, []*pb.RefundItem{{ProductId: "p1", Amount: money.New(5000, "EUR").ToProto()}}, "return", false)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(5000), refund.Amount)
+		assert.Empty(t, client.refunds)
+		assert.Equal(t, int64(5000), storeCredit(t, ctx, service, 5))
+
+		refund, err = service.RefundPayment(ctx, 3, []*pb.RefundItem{{ProductId: "p1", Amount: money.New(3000, "EUR").ToProto()}}, "return", false)
+
+		require.NoError(t, err)
+		assert.Equal(t, "ref_card", refund.RefundId)
+		require.Len(t, client.refunds, 1)
+		assert.Equal(t, int64(2000), client.refunds[0][0].Amount.Value)
+		assert.Equal(t, int64(6000), storeCredit(t, ctx, service, 5))
+	})
+
+	t.Run("Returns can be refunded to store credit in full", func(t *testing.T) {
+		repository := setupTestRepository(t)
+		client := &fakeRefundClient{}
+		service := internal.NewPaymentService(client, repository, 1000)
+		transaction := &models.Transaction{OrderId: 4, UserId: 5, PaymentId: "pay_4", TotalPrice: 10000, Currency: "EUR", Status: models.Success.String()}
+		require.NoError(t, repository.RegisterTransaction(ctx, transaction))
+		require.NoError(t, service.RecordPayment(ctx, transaction, []models.SellerShare{{SellerId: 7, Amount: money.New(10000, "EUR")}}))
+
+		refund, err := service.RefundPayment(ctx, 4, []*pb.RefundItem{{ProductId: "p1", Amount: money.New(2500, "EUR").ToProto()}}, "return", true)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.RefundToStoreCredit, refund.Method)
+		assert.True(t, refund.IsPartial)
+		assert.Empty(t, client.refunds)
+		assert.Equal(t, int64(2500), storeCredit(t, ctx, service, 5))
+
+		_, err = service.RefundPayment(ctx, 4, []*pb.RefundItem{{ProductId: "p1", Amount: money.New(8000, "EUR").ToProto()}}, "return", true)
+		assert.ErrorIs(t, err, internal.ErrCreditRefund)
+	})
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/payment/internal"
+	"github.com/rasadov/EcommerceAPI/payment/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestRepository(t *testing.T) internal.Repository {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	r, err := internal.NewPostgresRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+	return r
+}
+
+// setupPayment records a successful payment of 100.00 EUR for order 1, of which seller 7
+// sold 60.00 and seller 8 40.00, with a commission of 10%.
+func setupPayment(t *testing.T, ctx context.Context) (internal.Repository, internal.Service) {
+	repository := setupTestRepository(t)
+	service := internal.NewPaymentService(nil, repository, 1000)
+
+	transaction := &models.Transaction{OrderId: 1, PaymentId: "pay_1", TotalPrice: 10000, Currency: "EUR", Status: models.Success.String()}
+	require.NoError(t, repository.RegisterTransaction(ctx, transaction))
+	err := service.RecordPayment(ctx, transaction, []models.SellerShare{
+		{SellerId: 7, Amount: money.New(6000, "EUR")},
+		{SellerId: 8, Amount: money.New(4000, "EUR")},
+	})
+	require.NoError(t, err)
+	return repository, service
+}
+
+func owed(t *testing.T, ctx context.Context, service internal.Service, sellerId uint64) int64 {
+	balances, err := service.GetSellerBalances(ctx, sellerId)
+	require.NoError(t, err)
+	require.Len(t, balances, 1)
+	assert.Equal(t, "EUR", balances[0].Currency)
+	return balances[0].Owed
+}
+
+func TestPaymentService_Ledger(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Payments are owed to sellers less commission", func(t *testing.T) {
+		repository, service := setupPayment(t, ctx)
+
+		assert.Equal(t, int64(5400), owed(t, ctx, service, 7))
+		assert.Equal(t, int64(3600), owed(t, ctx, service, 8))
+
+		journal, err := repository.GetJournal(ctx, "payment:pay_1")
+		require.NoError(t, err)
+		assert.NoError(t, journal.Balanced())
+		var commission int64
+		for _, entry := range journal.Entries {
+			if entry.Account == models.AccountCommission {
+				commission -= entry.Amount
+			}
+		}
+		assert.Equal(t, int64(1000), commission)
+	})
+
+	t.Run("Payments are posted once", func(t *testing.T) {
+		_, service := setupPayment(t, ctx)
+
+		err := service.RecordPayment(ctx, &models.Transaction{OrderId: 1, PaymentId: "pay_1", TotalPrice: 10000, Currency: "EUR"},
+			[]models.SellerShare{{SellerId: 7, Amount: money.New(10000, "EUR")}})
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(5400), owed(t, ctx, service, 7))
+	})
+
+	t.Run("Refunds are shared by sales and return commission", func(t *testing.T) {
+		_, service := setupPayment(t, ctx)
+
+		refund := &models.Refund{OrderId: 1, RefundId: "ref_1", Amount: 5000, Currency: "EUR", IsPartial: true, Status: models.RefundSucceeded}
+		require.NoError(t, service.RecordRefund(ctx, refund))
+		require.NoError(t, service.RecordRefund(ctx, refund))
+
+		// 54.00 less the 30.00 refunded plus 3.00 of commission back
+		assert.Equal(t, int64(2700), owed(t, ctx, service, 7))
+		assert.Equal(t, int64(1800), owed(t, ctx, service, 8))
+	})
+
+	t.Run("Marketplace sales are not owed to anyone", func(t *testing.T) {
+		service := internal.NewPaymentService(nil, setupTestRepository(t), 1000)
+
+		err := service.RecordPayment(ctx, &models.Transaction{OrderId: 2, PaymentId: "pay_2", TotalPrice: 2500, Currency: "EUR"},
+			[]models.SellerShare{{Amount: money.New(2500, "EUR")}})
+		require.NoError(t, err)
+
+		balances, err := service.GetSellerBalances(ctx, 0)
+		require.NoError(t, err)
+		assert.Empty(t, balances)
+	})
+}
+
+func TestPaymentService_CreatePayouts(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Sellers are paid out what they are owed once", func(t *testing.T) {
+		_, service := setupPayment(t, ctx)
+		require.NoError(t, service.RecordRefund(ctx, &models.Refund{OrderId: 1, RefundId: "ref_1", Amount: 5000, Currency: "EUR", IsPartial: true}))
+
+		payouts, err := service.CreatePayouts(ctx, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		require.Len(t, payouts, 2)
+		payout := payouts[0]
+		assert.Equal(t, uint64(7), payout.SellerId)
+		assert.Equal(t, int64(6000), payout.Sales)
+		assert.Equal(t, int64(300), payout.Commission)
+		assert.Equal(t, int64(3000), payout.Refunds)
+		assert.Equal(t, int64(2700), payout.Amount)
+
+		balances, err := service.GetSellerBalances(ctx, 7)
+		require.NoError(t, err)
+		require.Len(t, balances, 1)
+		assert.Equal(t, int64(0), balances[0].Owed)
+		assert.Equal(t, int64(2700), balances[0].PaidOut)
+
+		payouts, err = service.CreatePayouts(ctx, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		assert.Empty(t, payouts)
+	})
+
+	t.Run("Balances refunds left negative are carried over", func(t *testing.T) {
+		_, service := setupPayment(t, ctx)
+		_, err := service.CreatePayouts(ctx, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		require.NoError(t, service.RecordRefund(ctx, &models.Refund{OrderId: 1, RefundId: "ref_1", Amount: 10000, Currency: "EUR"}))
+
+		payouts, err := service.CreatePayouts(ctx, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		assert.Empty(t, payouts)
+		assert.Equal(t, int64(-5400), owed(t, ctx, service, 7))
+	})
+
+	t.Run("Payouts are listed newest first", func(t *testing.T) {
+		_, service := setupPayment(t, ctx)
+		_, err := service.CreatePayouts(ctx, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		err = service.RecordPayment(ctx, &models.Transaction{OrderId: 2, PaymentId: "pay_2", TotalPrice: 1000, Currency: "EUR"},
+			[]models.SellerShare{{SellerId: 7, Amount: money.New(1000, "EUR")}})
+		require.NoError(t, err)
+		_, err = service.CreatePayouts(ctx, time.Now().Add(time.Second))
+		require.NoError(t, err)
+
+		page, err := service.ListPayouts(ctx, 7, "", 1)
+		require.NoError(t, err)
+		require.Len(t, page.Payouts, 1)
+		assert.Equal(t, int64(900), page.Payouts[0].Amount)
+		assert.True(t, page.HasNextPage)
+
+		page, err = service.ListPayouts(ctx, 7, page.EndCursor, 1)
+		require.NoError(t, err)
+		require.Len(t, page.Payouts, 1)
+		assert.Equal(t, int64(5400), page.Payouts[0].Amount)
+		assert.False(t, page.HasNextPage)
+	})
+}
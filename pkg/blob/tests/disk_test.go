@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/pkg/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Blobs are stored and read back", func(t *testing.T) {
+		root := t.TempDir()
+		store, err := blob.NewDiskStore(root)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put(ctx, "invoices/INV-1.pdf", []byte("%PDF")))
+
+		data, err := store.Get(ctx, "invoices/INV-1.pdf")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("%PDF"), data)
+
+		// Only the blob itself is left behind
+		entries, err := os.ReadDir(filepath.Join(root, "invoices"))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("Blobs are never replaced", func(t *testing.T) {
+		store, err := blob.NewDiskStore(t.TempDir())
+		require.NoError(t, err)
+		require.NoError(t, store.Put(ctx, "a.html", []byte("first")))
+
+		err = store.Put(ctx, "a.html", []byte("second"))
+
+		assert.ErrorIs(t, err, blob.ErrExists)
+		data, err := store.Get(ctx, "a.html")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("first"), data)
+	})
+
+	t.Run("Missing blobs are not found", func(t *testing.T) {
+		store, err := blob.NewDiskStore(t.TempDir())
+		require.NoError(t, err)
+
+		_, err = store.Get(ctx, "missing.pdf")
+
+		assert.ErrorIs(t, err, blob.ErrNotFound)
+	})
+
+	t.Run("Keys cannot leave the root", func(t *testing.T) {
+		store, err := blob.NewDiskStore(t.TempDir())
+		require.NoError(t, err)
+
+		for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../b", "a//b", `a\b`} {
+			assert.ErrorIs(t, store.Put(ctx, key, []byte("x")), blob.ErrInvalidKey, key)
+		}
+	})
+}
@@ -3,3 +3,15 @@ package contextkeys
 type ctxKeyUserID struct{}
 
 var UserIDKey = ctxKeyUserID{}
+
+type ctxKeyIdempotencyKey struct{}
+
+var IdempotencyKey = ctxKeyIdempotencyKey{}
+
+type ctxKeyCartSession struct{}
+
+var CartSessionKey = ctxKeyCartSession{}
+
+type ctxKeyClientIP struct{}
+
+var ClientIPKey = ctxKeyClientIP{}
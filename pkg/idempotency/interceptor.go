@@ -0,0 +1,103 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// UnaryServerInterceptor makes the given methods idempotent for requests carrying an
+// idempotency key: a retry with the same key and payload gets the original response instead
+// of running again, and reusing a key for a different payload fails with codes.AlreadyExists.
+// Failed requests are not stored, so they can be retried with the same key.
+func UnaryServerInterceptor(store *Store, methods ...string) grpc.UnaryServerInterceptor {
+	guarded := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		guarded[method] = true
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		key := keyFromIncomingContext(ctx)
+		request, ok := req.(proto.Message)
+		if !guarded[info.FullMethod] || key == "" || !ok {
+			return handler(ctx, req)
+		}
+		if len(key) > MaxKeyLength {
+			return nil, status.Error(codes.InvalidArgument, "idempotency key is too long")
+		}
+
+		requestHash, err := hashRequest(request)
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := store.Begin(ctx, info.FullMethod, key, requestHash)
+		switch {
+		case errors.Is(err, ErrKeyReused):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		case errors.Is(err, ErrInProgress):
+			return nil, status.Error(codes.Aborted, err.Error())
+		case err != nil:
+			return nil, err
+		case record != nil:
+			return replay(record)
+		}
+
+		// The caller may give up before we finish; the outcome must be recorded regardless
+		storeCtx := context.WithoutCancel(ctx)
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			if releaseErr := store.Release(storeCtx, info.FullMethod, key); releaseErr != nil {
+				log.Println("Failed to release idempotency key:", releaseErr)
+			}
+			return nil, err
+		}
+
+		if response, ok := resp.(proto.Message); ok {
+			err = complete(storeCtx, store, info.FullMethod, key, response)
+			if err != nil {
+				log.Println("Failed to store idempotent response:", err)
+			}
+		}
+		return resp, nil
+	}
+}
+
+func hashRequest(request proto.Message) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func complete(ctx context.Context, store *Store, scope, key string, response proto.Message) error {
+	wrapped, err := anypb.New(response)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(wrapped)
+	if err != nil {
+		return err
+	}
+	return store.Complete(ctx, scope, key, data)
+}
+
+func replay(record *Record) (proto.Message, error) {
+	var wrapped anypb.Any
+	err := proto.Unmarshal(record.Response, &wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return wrapped.UnmarshalNew()
+}
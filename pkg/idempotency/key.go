@@ -0,0 +1,42 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/rasadov/EcommerceAPI/pkg/contextkeys"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// Header is the HTTP header clients send the key in.
+	Header = "Idempotency-Key"
+	// MetadataKey is the gRPC metadata key the key is forwarded in.
+	MetadataKey = "idempotency-key"
+	// MaxKeyLength is the longest key accepted.
+	MaxKeyLength = 255
+)
+
+// KeyFromContext returns the idempotency key put into ctx by the gateway, or "".
+func KeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(contextkeys.IdempotencyKey).(string)
+	return key
+}
+
+// UnaryClientInterceptor forwards the idempotency key in ctx to the called service.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if key := KeyFromContext(ctx); key != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, MetadataKey, key)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func keyFromIncomingContext(ctx context.Context) string {
+	values := metadata.ValueFromIncomingContext(ctx, MetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
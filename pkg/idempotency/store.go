@@ -0,0 +1,101 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrKeyReused  = errors.New("idempotency key was already used for a different request")
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// Record is the stored outcome of a request made with an idempotency key.
+type Record struct {
+	Scope       string `gorm:"primaryKey;size:128"`
+	Key         string `gorm:"primaryKey;column:idempotency_key;size:255"`
+	RequestHash string `gorm:"size:64"`
+	Response    []byte
+	Completed   bool
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+}
+
+func (Record) TableName() string {
+	return "idempotency_records"
+}
+
+// Store keeps idempotency records in the service's own database for ttl.
+type Store struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+func NewStore(db *gorm.DB, ttl time.Duration) (*Store, error) {
+	err := db.AutoMigrate(&Record{})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db, ttl: ttl}, nil
+}
+
+// Begin claims key within scope for a request hashing to requestHash. It returns nil if the
+// caller should go ahead and handle the request, or the completed record to replay.
+func (store *Store) Begin(ctx context.Context, scope, key, requestHash string) (*Record, error) {
+	now := time.Now().UTC()
+
+	// An expired record no longer protects its key
+	err := store.db.WithContext(ctx).
+		Where("scope = ? AND idempotency_key = ? AND expires_at <= ?", scope, key, now).
+		Delete(&Record{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	record := Record{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(store.ttl),
+	}
+	result := store.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var existing Record
+	err = store.db.WithContext(ctx).
+		First(&existing, "scope = ? AND idempotency_key = ?", scope, key).Error
+	if err != nil {
+		return nil, err
+	}
+	if existing.RequestHash != requestHash {
+		return nil, ErrKeyReused
+	}
+	if !existing.Completed {
+		return nil, ErrInProgress
+	}
+	return &existing, nil
+}
+
+// Complete stores the response of a request claimed with Begin.
+func (store *Store) Complete(ctx context.Context, scope, key string, response []byte) error {
+	return store.db.WithContext(ctx).Model(&Record{}).
+		Where("scope = ? AND idempotency_key = ?", scope, key).
+		Updates(map[string]any{"response": response, "completed": true}).Error
+}
+
+// Release gives up a claim made with Begin so that the request can be retried.
+func (store *Store) Release(ctx context.Context, scope, key string) error {
+	return store.db.WithContext(ctx).
+		Where("scope = ? AND idempotency_key = ? AND completed = ?", scope, key, false).
+		Delete(&Record{}).Error
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/idempotency"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const method = "/pb.OrderService/PostOrder"
+
+// Test helper to create a store backed by an in-memory SQLite database
+func setupStore(t *testing.T, ttl time.Duration) *idempotency.Store {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	store, err := idempotency.NewStore(db, ttl)
+	require.NoError(t, err)
+	return store
+}
+
+func withKey(key string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotency.MetadataKey, key))
+}
+
+// countingHandler answers every call with a new numbered response
+func countingHandler(calls *int) grpc.UnaryHandler {
+	return func(ctx context.Context, req any) (any, error) {
+		*calls++
+		return wrapperspb.Int64(int64(*calls)), nil
+	}
+}
+
+func call(interceptor grpc.UnaryServerInterceptor, ctx context.Context, req proto.Message, handler grpc.UnaryHandler) (any, error) {
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	t.Run("Retry replays the first response", func(t *testing.T) {
+		interceptor := idempotency.UnaryServerInterceptor(setupStore(t, time.Hour), method)
+		calls := 0
+
+		first, err := call(interceptor, withKey("a"), wrapperspb.String("order"), countingHandler(&calls))
+		require.NoError(t, err)
+		second, err := call(interceptor, withKey("a"), wrapperspb.String("order"), countingHandler(&calls))
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, calls)
+		assert.True(t, proto.Equal(first.(proto.Message), second.(proto.Message)))
+	})
+
+	t.Run("Different payload conflicts", func(t *testing.T) {
+		interceptor := idempotency.UnaryServerInterceptor(setupStore(t, time.Hour), method)
+		calls := 0
+
+		_, err := call(interceptor, withKey("a"), wrapperspb.String("order"), countingHandler(&calls))
+		require.NoError(t, err)
+		_, err = call(interceptor, withKey("a"), wrapperspb.String("other order"), countingHandler(&calls))
+
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Failures are not stored", func(t *testing.T) {
+		interceptor := idempotency.UnaryServerInterceptor(setupStore(t, time.Hour), method)
+		calls := 0
+
+		_, err := call(interceptor, withKey("a"), wrapperspb.String("order"), func(ctx context.Context, req any) (any, error) {
+			return nil, errors.New("product service unavailable")
+		})
+		require.Error(t, err)
+		_, err = call(interceptor, withKey("a"), wrapperspb.String("order"), countingHandler(&calls))
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Expired keys run again", func(t *testing.T) {
+		interceptor := idempotency.UnaryServerInterceptor(setupStore(t, time.Nanosecond), method)
+		calls := 0
+
+		_, err := call(interceptor, withKey("a"), wrapperspb.String("order"), countingHandler(&calls))
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+		_, err = call(interceptor, withKey("a"), wrapperspb.String("order"), countingHandler(&calls))
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Requests without a key or for other methods pass through", func(t *testing.T) {
+		interceptor := idempotency.UnaryServerInterceptor(setupStore(t, time.Hour), method)
+		calls := 0
+
+		_, err := call(interceptor, context.Background(), wrapperspb.String("order"), countingHandler(&calls))
+		require.NoError(t, err)
+		_, err = call(interceptor, context.Background(), wrapperspb.String("order"), countingHandler(&calls))
+		require.NoError(t, err)
+		_, err = interceptor(withKey("a"), wrapperspb.String("order"),
+			&grpc.UnaryServerInfo{FullMethod: "/pb.OrderService/GetOrder"}, countingHandler(&calls))
+		require.NoError(t, err)
+		_, err = interceptor(withKey("a"), wrapperspb.String("order"),
+			&grpc.UnaryServerInfo{FullMethod: "/pb.OrderService/GetOrder"}, countingHandler(&calls))
+		require.NoError(t, err)
+
+		assert.Equal(t, 4, calls)
+	})
+}
+
+func TestStore_Begin(t *testing.T) {
+	store := setupStore(t, time.Hour)
+	ctx := context.Background()
+
+	record, err := store.Begin(ctx, method, "a", "hash")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+
+	// A concurrent retry must not run the request a second time
+	_, err = store.Begin(ctx, method, "a", "hash")
+	assert.ErrorIs(t, err, idempotency.ErrInProgress)
+
+	// Keys are scoped to the method
+	record, err = store.Begin(ctx, "/pb.PaymentService/CreateCheckoutSession", "a", "other")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+}
@@ -18,7 +18,7 @@ func StartEventsConsumer(ctx context.Context, service ConsumerService, topic str
 		return err
 	}
 
-	log.Printf("Payment Kafka consumer starting; topic=%s partitions=%v", topic, partitions)
+	log.Printf("Kafka consumer starting; topic=%s partitions=%v", topic, partitions)
 
 	done := make(chan struct{})
 	for _, partition := range partitions {
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/EcommerceAPI/pkg/contextkeys"
+)
+
+const (
+	CartSessionCookie = "cart_session"
+	// cartSessionMaxAge keeps the cookie for 30 days
+	cartSessionMaxAge = 30 * 24 * 60 * 60
+)
+
+// CartSession puts the anonymous cart session from the cart_session cookie into the request
+// context, issuing a new session cookie to clients that do not have one yet.
+func CartSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, err := c.Cookie(CartSessionCookie)
+		if err != nil || session == "" {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				log.Println("Error generating cart session", err)
+				c.Next()
+				return
+			}
+			session = hex.EncodeToString(b)
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(CartSessionCookie, session, cartSessionMaxAge, "/", "", false, true)
+		}
+
+		ctx := context.WithValue(c.Request.Context(), contextkeys.CartSessionKey, session)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
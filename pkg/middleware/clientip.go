@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/EcommerceAPI/pkg/contextkeys"
+)
+
+// ClientIP puts the address of the client making the request into the request context, so
+// that orders can be screened by where they are placed from.
+func ClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), contextkeys.ClientIPKey, c.ClientIP())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasadov/EcommerceAPI/pkg/contextkeys"
+	"github.com/rasadov/EcommerceAPI/pkg/idempotency"
+)
+
+// IdempotencyKey puts the request's Idempotency-Key header, if any, into the request context
+// so that gRPC clients can forward it.
+func IdempotencyKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotency.Header)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > idempotency.MaxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key is too long",
+			})
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), contextkeys.IdempotencyKey, key)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
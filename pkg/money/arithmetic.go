@@ -0,0 +1,117 @@
+package money
+
+import (
+	"math/big"
+)
+
+func (m Money) sameCurrency(other Money) bool {
+	return Normalize(m.Currency) == Normalize(other.Currency)
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if !m.sameCurrency(other) {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: Normalize(m.Currency)}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if !m.sameCurrency(other) {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount - other.Amount, Currency: Normalize(m.Currency)}, nil
+}
+
+// Mul multiplies by a whole quantity, e.g. a unit price by the number of units.
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Cmp compares two amounts in the same currency and returns -1, 0 or +1.
+func (m Money) Cmp(other Money) (int, error) {
+	if !m.sameCurrency(other) {
+		return 0, ErrCurrencyMismatch
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// MulRat multiplies by an exact fraction (a tax rate, a commission, an exchange rate)
+// and rounds the result to whole minor units.
+func (m Money) MulRat(r *big.Rat, mode RoundingMode) (Money, error) {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), r)
+	amount, err := roundRat(product, mode)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: m.Currency}, nil
+}
+
+// MulRatio multiplies by num/den, e.g. MulRatio(15, 100, RoundHalfUp) for 15%.
+func (m Money) MulRatio(num, den int64, mode RoundingMode) (Money, error) {
+	if den == 0 {
+		return Money{}, ErrInvalidAmount
+	}
+	return m.MulRat(big.NewRat(num, den), mode)
+}
+
+// Allocate splits m in proportion to weights without losing or creating minor units:
+// the remainder left after rounding down is handed out one unit at a time from the first share.
+func (m Money) Allocate(weights ...int64) ([]Money, error) {
+	var total int64
+	for _, w := range weights {
+		if w < 0 {
+			return nil, ErrInvalidAmount
+		}
+		total += w
+	}
+	if total == 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	shares := make([]Money, len(weights))
+	remainder := m.Amount
+	for i, w := range weights {
+		share, err := m.MulRatio(w, total, RoundDown)
+		if err != nil {
+			return nil, err
+		}
+		shares[i] = share
+		remainder -= share.Amount
+	}
+
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(shares) {
+		if weights[i] == 0 {
+			continue
+		}
+		shares[i].Amount += step
+		remainder -= step
+	}
+	return shares, nil
+}
+
+// Sum adds up amounts that must all be in currency.
+func Sum(currency string, amounts ...Money) (Money, error) {
+	total := Zero(currency)
+	for _, amount := range amounts {
+		var err error
+		total, err = total.Add(amount)
+		if err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,66 @@
+package money
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// DefaultCurrency is used whenever a price is stored or sent without a currency.
+const DefaultCurrency = "USD"
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+)
+
+// exponents lists the ISO-4217 currencies we accept and the number of minor-unit digits for each.
+var exponents = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "AZN": 2, "BDT": 2, "BGN": 2, "BHD": 3, "BRL": 2,
+	"CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EGP": 2,
+	"EUR": 2, "GBP": 2, "GEL": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2,
+	"ISK": 0, "JOD": 3, "JPY": 0, "KES": 2, "KRW": 0, "KWD": 3, "KZT": 2, "MAD": 2,
+	"MXN": 2, "MYR": 2, "NGN": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PHP": 2, "PKR": 2,
+	"PLN": 2, "QAR": 2, "RON": 2, "RUB": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2,
+	"TND": 3, "TRY": 2, "TWD": 2, "UAH": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// Normalize upper-cases a currency code and falls back to DefaultCurrency when it is empty.
+func Normalize(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return currency
+}
+
+func IsValid(currency string) bool {
+	_, ok := exponents[Normalize(currency)]
+	return ok
+}
+
+// Validate returns the normalized currency code or ErrUnknownCurrency.
+func Validate(currency string) (string, error) {
+	currency = Normalize(currency)
+	if _, ok := exponents[currency]; !ok {
+		return "", ErrUnknownCurrency
+	}
+	return currency, nil
+}
+
+// Exponent returns the number of minor-unit digits for the currency, defaulting to 2.
+func Exponent(currency string) int {
+	if exp, ok := exponents[Normalize(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Currencies returns the supported currency codes in alphabetical order.
+func Currencies() []string {
+	codes := make([]string, 0, len(exponents))
+	for code := range exponents {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,115 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	moneypb "github.com/rasadov/EcommerceAPI/pkg/money/proto/pb"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+// Money is an amount expressed in the minor units of an ISO-4217 currency.
+// Stored with GORM it is embedded, e.g. `gorm:"embedded;embeddedPrefix:total_"`.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency" gorm:"type:varchar(3)"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: Normalize(currency)}
+}
+
+// Zero returns an empty amount in the given currency.
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// ParseMajor parses a decimal amount in major units (e.g. "12.99") exactly,
+// rounding half away from zero when it has more digits than the currency allows.
+func ParseMajor(amount string, currency string) (Money, error) {
+	currency = Normalize(currency)
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(amount))
+	if !ok {
+		return Money{}, ErrInvalidAmount
+	}
+	r.Mul(r, pow10Rat(Exponent(currency)))
+	minor, err := roundRat(r, RoundHalfUp)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// FromMajor converts an amount in major units (e.g. 12.99 USD) to Money. The float is read
+// through its shortest decimal representation, so 12.99 becomes exactly 1299 cents.
+func FromMajor(amount float64, currency string) Money {
+	m, err := ParseMajor(strconv.FormatFloat(amount, 'f', -1, 64), currency)
+	if err != nil {
+		return Zero(currency)
+	}
+	return m
+}
+
+// Major returns the amount in major units, for display purposes only.
+func (m Money) Major() float64 {
+	f, _ := new(big.Rat).SetFrac(big.NewInt(m.Amount), pow10Int(Exponent(m.Currency))).Float64()
+	return f
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// String formats the amount exactly, e.g. "12.99 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Formatted is the String form, exposed as a field on the GraphQL Money type.
+func (m Money) Formatted() string {
+	return m.String()
+}
+
+// Decimal returns the amount in major units as an exact decimal string, e.g. "12.99".
+func (m Money) Decimal() string {
+	exp := Exponent(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return fmt.Sprintf("%s%s.%s", sign, digits[:len(digits)-exp], digits[len(digits)-exp:])
+}
+
+func (m Money) ToProto() *moneypb.Money {
+	return &moneypb.Money{
+		Amount:   m.Amount,
+		Currency: m.Currency,
+	}
+}
+
+func FromProto(m *moneypb.Money) Money {
+	if m == nil {
+		return Money{Currency: DefaultCurrency}
+	}
+	return New(m.GetAmount(), m.GetCurrency())
+}
@@ -0,0 +1,217 @@
+package money
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRatesUnavailable = errors.New("exchange rates unavailable")
+)
+
+// Rates is an exchange rate table: one unit of Base buys Rates[code] units of code.
+type Rates struct {
+	Base      string             `json:"base"`
+	Rates     map[string]float64 `json:"rates"`
+	FetchedAt time.Time          `json:"-"`
+}
+
+// RateSource loads the current exchange rate table.
+type RateSource interface {
+	FetchRates(ctx context.Context) (*Rates, error)
+}
+
+// NewRateSource picks the HTTP source when url is set and falls back to the file source.
+// It returns nil when neither is configured, in which case only same-currency conversions work.
+func NewRateSource(url, path string) RateSource {
+	if url != "" {
+		return &HTTPRateSource{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+	}
+	if path != "" {
+		return &FileRateSource{Path: path}
+	}
+	return nil
+}
+
+// FileRateSource reads rates from a JSON file, e.g. {"base": "USD", "rates": {"EUR": 0.92}}.
+type FileRateSource struct {
+	Path string
+}
+
+func (s *FileRateSource) FetchRates(ctx context.Context) (*Rates, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRates(data)
+}
+
+// HTTPRateSource fetches the same JSON document as FileRateSource from a rates API.
+type HTTPRateSource struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *HTTPRateSource) FetchRates(ctx context.Context) (*Rates, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rate source returned status %d", res.StatusCode)
+	}
+
+	var rates Rates
+	if err := json.NewDecoder(res.Body).Decode(&rates); err != nil {
+		return nil, err
+	}
+	return normalizeRates(&rates)
+}
+
+func decodeRates(data []byte) (*Rates, error) {
+	var rates Rates
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, err
+	}
+	return normalizeRates(&rates)
+}
+
+func normalizeRates(rates *Rates) (*Rates, error) {
+	base, err := Validate(rates.Base)
+	if err != nil {
+		return nil, err
+	}
+	normalized := make(map[string]float64, len(rates.Rates)+1)
+	for code, rate := range rates.Rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %v", code, rate)
+		}
+		normalized[Normalize(code)] = rate
+	}
+	normalized[base] = 1
+	return &Rates{Base: base, Rates: normalized, FetchedAt: time.Now().UTC()}, nil
+}
+
+// Converter converts Money between currencies using a rate table refreshed from a RateSource.
+type Converter struct {
+	source RateSource
+	mu     sync.RWMutex
+	rates  *Rates
+}
+
+func NewConverter(source RateSource) *Converter {
+	return &Converter{source: source}
+}
+
+// Refresh reloads the rate table from the source.
+func (c *Converter) Refresh(ctx context.Context) error {
+	if c.source == nil {
+		return ErrRatesUnavailable
+	}
+	rates, err := c.source.FetchRates(ctx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.rates = rates
+	c.mu.Unlock()
+	return nil
+}
+
+// StartRefresh refreshes the rate table every interval until ctx is cancelled.
+func (c *Converter) StartRefresh(ctx context.Context, interval time.Duration) {
+	if c.source == nil {
+		return
+	}
+	if err := c.Refresh(ctx); err != nil {
+		log.Println("Failed to load exchange rates:", err)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.Refresh(ctx); err != nil {
+					log.Println("Failed to refresh exchange rates:", err)
+				}
+			}
+		}
+	}()
+}
+
+// Rate returns the exact exchange rate from one currency to another, built from the
+// decimal form of the published rates rather than their binary floats.
+func (c *Converter) Rate(from, to string) (*big.Rat, error) {
+	from, to = Normalize(from), Normalize(to)
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	c.mu.RLock()
+	rates := c.rates
+	c.mu.RUnlock()
+	if rates == nil {
+		return nil, ErrRatesUnavailable
+	}
+
+	fromRate, err := exactRate(rates.Rates, from)
+	if err != nil {
+		return nil, err
+	}
+	toRate, err := exactRate(rates.Rates, to)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Quo(toRate, fromRate), nil
+}
+
+func exactRate(rates map[string]float64, currency string) (*big.Rat, error) {
+	rate, ok := rates[currency]
+	if !ok {
+		return nil, ErrUnknownCurrency
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	if !ok || r.Sign() <= 0 {
+		return nil, ErrRatesUnavailable
+	}
+	return r, nil
+}
+
+// Convert returns m expressed in the target currency, rounding half to even.
+func (c *Converter) Convert(m Money, to string) (Money, error) {
+	to, err := Validate(to)
+	if err != nil {
+		return Money{}, err
+	}
+	if Normalize(m.Currency) == to {
+		return New(m.Amount, to), nil
+	}
+	rate, err := c.Rate(m.Currency, to)
+	if err != nil {
+		return Money{}, err
+	}
+	rate.Mul(rate, new(big.Rat).SetFrac(pow10Int(Exponent(to)), pow10Int(Exponent(m.Currency))))
+	converted, err := m.MulRat(rate, RoundHalfEven)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(converted.Amount, to), nil
+}
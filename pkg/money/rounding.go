@@ -0,0 +1,54 @@
+package money
+
+import (
+	"math"
+	"math/big"
+)
+
+// RoundingMode decides how fractions of a minor unit are resolved.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds halves away from zero. Used for prices entered by people.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds halves to the nearest even unit. Used for currency conversion
+	// and other computed amounts so that rounding errors do not drift in one direction.
+	RoundHalfEven
+	// RoundDown truncates towards zero.
+	RoundDown
+)
+
+func pow10Int(exp int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil)
+}
+
+func pow10Rat(exp int) *big.Rat {
+	return new(big.Rat).SetInt(pow10Int(exp))
+}
+
+// roundRat rounds r to a whole number using mode.
+func roundRat(r *big.Rat, mode RoundingMode) (int64, error) {
+	num := new(big.Int).Abs(r.Num())
+	den := r.Denom()
+
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if mode != RoundDown && remainder.Sign() != 0 {
+		twice := new(big.Int).Mul(remainder, big.NewInt(2))
+		switch twice.Cmp(den) {
+		case 1:
+			quotient.Add(quotient, big.NewInt(1))
+		case 0:
+			if mode == RoundHalfUp || quotient.Bit(0) == 1 {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		}
+	}
+
+	if !quotient.IsInt64() || quotient.Int64() == math.MinInt64 {
+		return 0, ErrInvalidAmount
+	}
+	if r.Sign() < 0 {
+		return -quotient.Int64(), nil
+	}
+	return quotient.Int64(), nil
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoney_ParseMajor(t *testing.T) {
+	t.Run("exact decimal", func(t *testing.T) {
+		m, err := money.ParseMajor("0.10", "USD")
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), m.Amount)
+	})
+
+	t.Run("extra digits round half up", func(t *testing.T) {
+		m, err := money.ParseMajor("1.005", "USD")
+		require.NoError(t, err)
+		assert.Equal(t, int64(101), m.Amount)
+
+		m, err = money.ParseMajor("-1.005", "USD")
+		require.NoError(t, err)
+		assert.Equal(t, int64(-101), m.Amount)
+	})
+
+	t.Run("three decimal currency", func(t *testing.T) {
+		m, err := money.ParseMajor("1.234", "KWD")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1234), m.Amount)
+	})
+
+	t.Run("invalid amount", func(t *testing.T) {
+		_, err := money.ParseMajor("abc", "USD")
+		assert.ErrorIs(t, err, money.ErrInvalidAmount)
+	})
+}
+
+func TestMoney_FromMajorAvoidsFloatDrift(t *testing.T) {
+	// 0.1 + 0.2 is 0.30000000000000004 as a float, but 30 cents as money.
+	m := money.FromMajor(0.1+0.2, "USD")
+	assert.Equal(t, int64(30), m.Amount)
+
+	// 1.15 * 100 is 114.99999999999999 as a float.
+	assert.Equal(t, int64(115), money.FromMajor(1.15, "USD").Amount)
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	assert.Equal(t, "12.99", money.New(1299, "USD").Decimal())
+	assert.Equal(t, "0.05", money.New(5, "USD").Decimal())
+	assert.Equal(t, "-0.05", money.New(-5, "USD").Decimal())
+	assert.Equal(t, "1500", money.New(1500, "JPY").Decimal())
+	assert.Equal(t, "0.001", money.New(1, "KWD").Decimal())
+	assert.Equal(t, "12.99 USD", money.New(1299, "USD").String())
+}
+
+func TestMoney_AddSub(t *testing.T) {
+	sum, err := money.New(1099, "USD").Add(money.New(1, "usd"))
+	require.NoError(t, err)
+	assert.Equal(t, money.New(1100, "USD"), sum)
+
+	diff, err := sum.Sub(money.New(1200, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(-100), diff.Amount)
+	assert.True(t, diff.IsNegative())
+
+	_, err = money.New(1, "USD").Add(money.New(1, "EUR"))
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
+
+func TestMoney_SumReconcilesToTheCent(t *testing.T) {
+	var floatTotal float64
+	lines := make([]money.Money, 0, 10)
+	for i := 0; i < 10; i++ {
+		floatTotal += 0.1
+		lines = append(lines, money.FromMajor(0.1, "USD"))
+	}
+
+	total, err := money.Sum("USD", lines...)
+	require.NoError(t, err)
+	assert.NotEqual(t, 1.0, floatTotal)
+	assert.Equal(t, money.New(100, "USD"), total)
+
+	_, err = money.Sum("USD", money.New(1, "USD"), money.New(1, "EUR"))
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
+
+func TestMoney_MulRatioRounding(t *testing.T) {
+	price := money.New(25, "USD")
+
+	half, err := price.MulRatio(1, 2, money.RoundHalfUp)
+	require.NoError(t, err)
+	assert.Equal(t, int64(13), half.Amount)
+
+	half, err = price.MulRatio(1, 2, money.RoundHalfEven)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), half.Amount)
+
+	half, err = price.MulRatio(1, 2, money.RoundDown)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), half.Amount)
+
+	_, err = price.MulRatio(1, 0, money.RoundDown)
+	assert.ErrorIs(t, err, money.ErrInvalidAmount)
+}
+
+func TestMoney_Allocate(t *testing.T) {
+	t.Run("remainder is distributed", func(t *testing.T) {
+		shares, err := money.New(100, "USD").Allocate(1, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []money.Money{money.New(34, "USD"), money.New(33, "USD"), money.New(33, "USD")}, shares)
+	})
+
+	t.Run("shares always add back up", func(t *testing.T) {
+		original := money.New(-1001, "USD")
+		shares, err := original.Allocate(3, 0, 7)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), shares[1].Amount)
+
+		total, err := money.Sum("USD", shares...)
+		require.NoError(t, err)
+		assert.Equal(t, original, total)
+	})
+
+	t.Run("weights must be positive", func(t *testing.T) {
+		_, err := money.New(100, "USD").Allocate(0, 0)
+		assert.ErrorIs(t, err, money.ErrInvalidAmount)
+	})
+}
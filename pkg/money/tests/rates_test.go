@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test helper to create a converter backed by a rates file
+func setupConverter(t *testing.T, rates string) *money.Converter {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(rates), 0o644))
+
+	converter := money.NewConverter(&money.FileRateSource{Path: path})
+	require.NoError(t, converter.Refresh(context.Background()))
+	return converter
+}
+
+func TestMoney_FromMajor(t *testing.T) {
+	t.Run("two decimal currency", func(t *testing.T) {
+		m := money.FromMajor(12.99, "usd")
+		assert.Equal(t, int64(1299), m.Amount)
+		assert.Equal(t, "USD", m.Currency)
+	})
+
+	t.Run("zero decimal currency", func(t *testing.T) {
+		m := money.FromMajor(1500, "JPY")
+		assert.Equal(t, int64(1500), m.Amount)
+		assert.Equal(t, 1500.0, m.Major())
+	})
+
+	t.Run("empty currency defaults to USD", func(t *testing.T) {
+		m := money.FromMajor(1, "")
+		assert.Equal(t, money.DefaultCurrency, m.Currency)
+	})
+}
+
+func TestMoney_Validate(t *testing.T) {
+	currency, err := money.Validate("eur")
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", currency)
+
+	_, err = money.Validate("XYZ")
+	assert.ErrorIs(t, err, money.ErrUnknownCurrency)
+}
+
+func TestConverter_Convert(t *testing.T) {
+	converter := setupConverter(t, `{"base": "USD", "rates": {"EUR": 0.5, "JPY": 150}}`)
+
+	t.Run("same currency is unchanged", func(t *testing.T) {
+		m, err := converter.Convert(money.New(1000, "USD"), "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, money.New(1000, "USD"), m)
+	})
+
+	t.Run("from base currency", func(t *testing.T) {
+		m, err := converter.Convert(money.New(1000, "USD"), "EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, money.New(500, "EUR"), m)
+	})
+
+	t.Run("between two non-base currencies", func(t *testing.T) {
+		m, err := converter.Convert(money.New(500, "EUR"), "JPY")
+		assert.NoError(t, err)
+		assert.Equal(t, money.New(1500, "JPY"), m)
+	})
+
+	t.Run("unknown currency", func(t *testing.T) {
+		_, err := converter.Convert(money.New(500, "EUR"), "GBP")
+		assert.ErrorIs(t, err, money.ErrUnknownCurrency)
+	})
+}
+
+func TestConverter_WithoutRates(t *testing.T) {
+	converter := money.NewConverter(nil)
+
+	m, err := converter.Convert(money.New(100, "USD"), "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), m.Amount)
+
+	_, err = converter.Convert(money.New(100, "USD"), "EUR")
+	assert.ErrorIs(t, err, money.ErrRatesUnavailable)
+}
+
+func TestFileRateSource_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"base": "USD", "rates": {"EUR": -1}}`), 0o644))
+
+	converter := money.NewConverter(&money.FileRateSource{Path: path})
+	assert.Error(t, converter.Refresh(context.Background()))
+}
+
+func TestConverter_ConvertRoundsHalfEven(t *testing.T) {
+	converter := setupConverter(t, `{"base": "USD", "rates": {"EUR": 0.5, "KWD": 0.3}}`)
+
+	// 0.25 USD at 0.5 is 0.125 EUR, which rounds to the even 12 cents.
+	m, err := converter.Convert(money.New(25, "USD"), "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, money.New(12, "EUR"), m)
+
+	// 0.35 USD at 0.5 is 0.175 EUR, which rounds to the even 18 cents.
+	m, err = converter.Convert(money.New(35, "USD"), "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, money.New(18, "EUR"), m)
+
+	// 0.3 is not exact as a float, but 10.00 USD is still exactly 3.000 KWD.
+	m, err = converter.Convert(money.New(1000, "USD"), "KWD")
+	require.NoError(t, err)
+	assert.Equal(t, money.New(3000, "KWD"), m)
+}
@@ -0,0 +1,192 @@
+// Package parquet writes flat tables as Apache Parquet files. Columns are required, plain
+// encoded and uncompressed, which every Parquet reader understands. Rows are buffered one
+// row group at a time, so files of any length are written with the same memory.
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+const magic = "PAR1"
+
+// RowGroupSize is how many rows are buffered before they are written out as a row group.
+const RowGroupSize = 10000
+
+// Type is the type of a column's values.
+type Type int
+
+const (
+	// Int64 columns take int64 values
+	Int64 Type = iota
+	// Boolean columns take bool values
+	Boolean
+	// String columns take string values, stored as UTF-8
+	String
+	// Timestamp columns take time.Time values, stored as milliseconds since the Unix epoch
+	Timestamp
+)
+
+// Column is a column of the table, in the order its values are given to Write.
+type Column struct {
+	Name string
+	Type Type
+}
+
+var ErrClosed = errors.New("parquet writer is closed")
+
+// Writer writes rows to a Parquet file. Close must be called to write the file footer.
+type Writer struct {
+	out     io.Writer
+	columns []Column
+	// offset is the number of bytes written to out so far
+	offset int64
+	// pages hold the plain encoded values of the row group being buffered, one per column
+	pages     [][]byte
+	rows      int
+	rowGroups []rowGroup
+	numRows   int64
+	closed    bool
+}
+
+type rowGroup struct {
+	numRows int64
+	chunks  []columnChunk
+}
+
+type columnChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// NewWriter starts a Parquet file with the columns on out.
+func NewWriter(out io.Writer, columns []Column) (*Writer, error) {
+	w := &Writer{out: out, columns: columns, pages: make([][]byte, len(columns))}
+	if err := w.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write adds a row with one value per column, of the column's type.
+func (w *Writer) Write(row ...any) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values for %d columns", len(row), len(w.columns))
+	}
+	for i, column := range w.columns {
+		page, err := appendValue(w.pages[i], column, row[i], w.rows)
+		if err != nil {
+			return err
+		}
+		w.pages[i] = page
+	}
+	w.rows++
+	if w.rows == RowGroupSize {
+		return w.flush()
+	}
+	return nil
+}
+
+func appendValue(page []byte, column Column, value any, row int) ([]byte, error) {
+	switch column.Type {
+	case Int64:
+		v, ok := value.(int64)
+		if !ok {
+			return nil, typeError(column, value)
+		}
+		return binary.LittleEndian.AppendUint64(page, uint64(v)), nil
+	case Boolean:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, typeError(column, value)
+		}
+		// Booleans are packed eight to a byte, least significant bit first
+		if row%8 == 0 {
+			page = append(page, 0)
+		}
+		if v {
+			page[len(page)-1] |= 1 << (row % 8)
+		}
+		return page, nil
+	case String:
+		v, ok := value.(string)
+		if !ok {
+			return nil, typeError(column, value)
+		}
+		if len(v) > math.MaxInt32 {
+			return nil, fmt.Errorf("parquet: value of column %s is too long", column.Name)
+		}
+		page = binary.LittleEndian.AppendUint32(page, uint32(len(v)))
+		return append(page, v...), nil
+	case Timestamp:
+		v, ok := value.(time.Time)
+		if !ok {
+			return nil, typeError(column, value)
+		}
+		return binary.LittleEndian.AppendUint64(page, uint64(v.UnixMilli())), nil
+	}
+	return nil, fmt.Errorf("parquet: column %s has an unknown type", column.Name)
+}
+
+func typeError(column Column, value any) error {
+	return fmt.Errorf("parquet: column %s does not take %T values", column.Name, value)
+}
+
+// flush writes the buffered rows out as a row group, with one data page per column.
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	group := rowGroup{numRows: int64(w.rows)}
+	for i, page := range w.pages {
+		header := encodePageHeader(len(page), w.rows)
+		chunk := columnChunk{offset: w.offset, size: int64(len(header) + len(page)), values: int64(w.rows)}
+		if err := w.write(header); err != nil {
+			return err
+		}
+		if err := w.write(page); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		w.pages[i] = page[:0]
+	}
+	w.rowGroups = append(w.rowGroups, group)
+	w.numRows += group.numRows
+	w.rows = 0
+	return nil
+}
+
+// Close writes out the rows still buffered and the file footer. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	w.closed = true
+
+	footer := encodeFileMetaData(w.columns, w.rowGroups, w.numRows)
+	if err := w.write(footer); err != nil {
+		return err
+	}
+	if err := w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return w.write([]byte(magic))
+}
+
+func (w *Writer) write(data []byte) error {
+	n, err := w.out.Write(data)
+	w.offset += int64(n)
+	return err
+}
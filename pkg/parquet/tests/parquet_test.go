@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/parquet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var columns = []parquet.Column{
+	{Name: "id", Type: parquet.Int64},
+	{Name: "name", Type: parquet.String},
+	{Name: "paid", Type: parquet.Boolean},
+	{Name: "placed_at", Type: parquet.Timestamp},
+}
+
+func TestWriter(t *testing.T) {
+	placed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("File is framed by its magic number and footer", func(t *testing.T) {
+		var out bytes.Buffer
+		w, err := parquet.NewWriter(&out, columns)
+		require.NoError(t, err)
+		require.NoError(t, w.Write(int64(1), "Kettle", true, placed))
+		require.NoError(t, w.Write(int64(2), "Lamp", false, placed.Add(time.Hour)))
+		require.NoError(t, w.Close())
+
+		file := out.Bytes()
+		require.True(t, bytes.HasPrefix(file, []byte("PAR1")))
+		require.True(t, bytes.HasSuffix(file, []byte("PAR1")))
+		footerLength := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+		require.Less(t, footerLength, len(file)-12)
+		footer := file[len(file)-8-footerLength : len(file)-8]
+		for _, column := range columns {
+			assert.Contains(t, string(footer), column.Name)
+		}
+	})
+
+	t.Run("Values are plain encoded", func(t *testing.T) {
+		var out bytes.Buffer
+		w, err := parquet.NewWriter(&out, columns)
+		require.NoError(t, err)
+		paid := []bool{true, false, false, true, false, false, true, false, true}
+		for i, p := range paid {
+			require.NoError(t, w.Write(int64(i+1), "Kettle", p, placed))
+		}
+		require.NoError(t, w.Close())
+
+		var ids []byte
+		for i := range paid {
+			ids = binary.LittleEndian.AppendUint64(ids, uint64(i+1))
+		}
+		assert.Contains(t, out.String(), string(ids))
+		assert.Contains(t, out.String(), "\x06\x00\x00\x00Kettle\x06\x00\x00\x00Kettle")
+		// Booleans are bit-packed, the first value in the lowest bit
+		assert.Contains(t, out.String(), "\x49\x01")
+		assert.Contains(t, out.String(), string(binary.LittleEndian.AppendUint64(nil, uint64(placed.UnixMilli()))))
+	})
+
+	t.Run("Files read back with their schema, rows and values", func(t *testing.T) {
+		var out bytes.Buffer
+		w, err := parquet.NewWriter(&out, columns)
+		require.NoError(t, err)
+		// Enough rows for a second row group, ending mid-byte of the booleans
+		rows := parquet.RowGroupSize + 13
+		var written [][]any
+		for i := range rows {
+			row := []any{int64(i) - 5, fmt.Sprintf("Item %d: Ünïcode", i), i%3 == 0, placed.Add(time.Duration(i) * time.Minute)}
+			require.NoError(t, w.Write(row...))
+			written = append(written, row)
+		}
+		require.NoError(t, w.Close())
+
+		read, err := readParquet(out.Bytes())
+
+		require.NoError(t, err)
+		assert.Equal(t, []readColumn{
+			{Name: "id", PhysicalType: typeInt64, ConvertedType: -1, Required: true},
+			{Name: "name", PhysicalType: typeByteArray, ConvertedType: convertedUTF8, Required: true},
+			{Name: "paid", PhysicalType: typeBoolean, ConvertedType: -1, Required: true},
+			{Name: "placed_at", PhysicalType: typeInt64, ConvertedType: convertedTimestampMillis, Required: true},
+		}, read.Columns)
+		assert.Equal(t, int64(rows), read.NumRows)
+		assert.Equal(t, 2, read.RowGroups)
+		assert.Equal(t, written, read.Rows)
+	})
+
+	t.Run("Files without rows read back empty", func(t *testing.T) {
+		var out bytes.Buffer
+		w, err := parquet.NewWriter(&out, columns)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		read, err := readParquet(out.Bytes())
+
+		require.NoError(t, err)
+		assert.Len(t, read.Columns, len(columns))
+		assert.Zero(t, read.NumRows)
+		assert.Zero(t, read.RowGroups)
+	})
+
+	t.Run("Rows must match the columns", func(t *testing.T) {
+		w, err := parquet.NewWriter(&bytes.Buffer{}, columns)
+		require.NoError(t, err)
+		assert.Error(t, w.Write(int64(1), "Kettle", true))
+		assert.Error(t, w.Write("1", "Kettle", true, placed))
+		require.NoError(t, w.Close())
+		assert.ErrorIs(t, w.Write(int64(1), "Kettle", true, placed), parquet.ErrClosed)
+	})
+}
@@ -0,0 +1,343 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// This file reads Parquet files back following the Parquet format specification, apart from
+// the writer, so that tests check what any reader would see rather than the bytes written.
+// It reads what the writer may produce: flat schemas of required, plain encoded and
+// uncompressed columns in v1 data pages.
+
+// Thrift compact protocol types
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+// thriftStructValue is a decoded Thrift struct, its fields by ID.
+type thriftStructValue map[int16]any
+
+func (s thriftStructValue) int(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStructValue) string(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+func (s thriftStructValue) structs(id int16) []thriftStructValue {
+	list, _ := s[id].([]any)
+	structs := make([]thriftStructValue, 0, len(list))
+	for _, v := range list {
+		structs = append(structs, v.(thriftStructValue))
+	}
+	return structs
+}
+
+// thriftDecoder reads values in the Thrift compact protocol.
+type thriftDecoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (d *thriftDecoder) fail(format string, args ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("thrift: "+format+" at byte %d", append(args, d.pos)...)
+	}
+}
+
+func (d *thriftDecoder) byte() byte {
+	if d.err != nil || d.pos >= len(d.data) {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b
+}
+
+func (d *thriftDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail("invalid varint")
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+// zigzag reads a zigzag encoded varint, as integers are written.
+func (d *thriftDecoder) zigzag() int64 {
+	v := d.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (d *thriftDecoder) bytes(n int) []byte {
+	if d.err != nil || n < 0 || d.pos+n > len(d.data) {
+		d.fail("unexpected end of data")
+		return nil
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *thriftDecoder) value(fieldType byte) any {
+	switch fieldType {
+	case thriftTrue:
+		return true
+	case thriftFalse:
+		return false
+	case thriftByte:
+		return int64(int8(d.byte()))
+	case thriftI16, thriftI32, thriftI64:
+		return d.zigzag()
+	case thriftDouble:
+		return math.Float64frombits(binary.LittleEndian.Uint64(d.bytes(8)))
+	case thriftBinary:
+		return d.bytes(int(d.uvarint()))
+	case thriftList, thriftSet:
+		header := d.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(d.uvarint())
+		}
+		list := make([]any, 0, size)
+		for range size {
+			elementType := header & 0x0f
+			if elementType == thriftTrue {
+				// Booleans in lists take a byte each
+				list = append(list, d.byte() == thriftTrue)
+				continue
+			}
+			list = append(list, d.value(elementType))
+		}
+		return list
+	case thriftMap:
+		size := int(d.uvarint())
+		if size == 0 {
+			return map[any]any{}
+		}
+		types := d.byte()
+		m := make(map[any]any, size)
+		for range size {
+			key := d.value(types >> 4)
+			m[fmt.Sprint(key)] = d.value(types & 0x0f)
+		}
+		return m
+	case thriftStruct:
+		return d.structValue()
+	}
+	d.fail("unknown type %d", fieldType)
+	return nil
+}
+
+func (d *thriftDecoder) structValue() thriftStructValue {
+	s := thriftStructValue{}
+	var last int16
+	for d.err == nil {
+		header := d.byte()
+		if header == 0 {
+			return s
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(d.zigzag())
+		}
+		last = id
+		s[id] = d.value(header & 0x0f)
+	}
+	return s
+}
+
+// Parquet metadata, as numbered in parquet.thrift
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	codecNone     = 0
+	pageData      = 0
+)
+
+// readColumn is a column as described by a file's schema.
+type readColumn struct {
+	Name          string
+	PhysicalType  int64
+	ConvertedType int64
+	Required      bool
+}
+
+// readFile is what a Parquet file holds.
+type readFile struct {
+	Columns   []readColumn
+	NumRows   int64
+	RowGroups int
+	Rows      [][]any
+}
+
+// readParquet reads a Parquet file, checking that its metadata is consistent.
+func readParquet(file []byte) (*readFile, error) {
+	if len(file) < 12 || !bytes.HasPrefix(file, []byte("PAR1")) || !bytes.HasSuffix(file, []byte("PAR1")) {
+		return nil, errors.New("parquet: missing magic number")
+	}
+	footerLength := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	if footerLength > len(file)-12 {
+		return nil, errors.New("parquet: footer longer than the file")
+	}
+	decoder := &thriftDecoder{data: file[len(file)-8-footerLength : len(file)-8]}
+	metadata := decoder.structValue()
+	if decoder.err != nil {
+		return nil, decoder.err
+	}
+	if decoder.pos != len(decoder.data) {
+		return nil, errors.New("parquet: footer has trailing bytes")
+	}
+
+	schema := metadata.structs(2)
+	if len(schema) == 0 || schema[0].int(5) != int64(len(schema)-1) {
+		return nil, errors.New("parquet: schema root does not count its columns")
+	}
+	read := &readFile{NumRows: metadata.int(3)}
+	for _, element := range schema[1:] {
+		converted := int64(-1)
+		if _, ok := element[6]; ok {
+			converted = element.int(6)
+		}
+		read.Columns = append(read.Columns, readColumn{
+			Name:          element.string(4),
+			PhysicalType:  element.int(1),
+			ConvertedType: converted,
+			Required:      element.int(3) == 0,
+		})
+	}
+
+	for _, group := range metadata.structs(4) {
+		read.RowGroups++
+		groupRows := int(group.int(3))
+		chunks := group.structs(1)
+		if len(chunks) != len(read.Columns) {
+			return nil, errors.New("parquet: row group does not have a chunk per column")
+		}
+		rows := make([][]any, groupRows)
+		for i := range rows {
+			rows[i] = make([]any, len(read.Columns))
+		}
+		for c, chunk := range chunks {
+			values, err := readChunk(file, read.Columns[c], chunk[3].(thriftStructValue), groupRows)
+			if err != nil {
+				return nil, err
+			}
+			for i, v := range values {
+				rows[i][c] = v
+			}
+		}
+		read.Rows = append(read.Rows, rows...)
+	}
+	if int64(len(read.Rows)) != read.NumRows {
+		return nil, fmt.Errorf("parquet: file has %d rows but its row groups %d", read.NumRows, len(read.Rows))
+	}
+	return read, nil
+}
+
+// readChunk reads the values of a column chunk from its single data page.
+func readChunk(file []byte, column readColumn, meta thriftStructValue, rows int) ([]any, error) {
+	if meta.int(1) != column.PhysicalType || meta.int(4) != codecNone || meta.int(5) != int64(rows) {
+		return nil, fmt.Errorf("parquet: chunk of %s does not match its column", column.Name)
+	}
+	path, _ := meta[3].([]any)
+	if len(path) != 1 || string(path[0].([]byte)) != column.Name {
+		return nil, fmt.Errorf("parquet: chunk of %s has the wrong path", column.Name)
+	}
+
+	offset := meta.int(9)
+	if offset <= 0 || offset+meta.int(7) > int64(len(file)) {
+		return nil, fmt.Errorf("parquet: chunk of %s is outside the file", column.Name)
+	}
+	decoder := &thriftDecoder{data: file[offset : offset+meta.int(7)]}
+	header := decoder.structValue()
+	if decoder.err != nil {
+		return nil, decoder.err
+	}
+	dataHeader, _ := header[5].(thriftStructValue)
+	if header.int(1) != pageData || dataHeader == nil || dataHeader.int(1) != int64(rows) || dataHeader.int(2) != encodingPlain {
+		return nil, fmt.Errorf("parquet: chunk of %s is not a plain data page of its rows", column.Name)
+	}
+	if header.int(2) != header.int(3) || int64(decoder.pos)+header.int(3) != meta.int(7) {
+		return nil, fmt.Errorf("parquet: page of %s does not fill its chunk", column.Name)
+	}
+	// Required columns of a flat schema have no repetition or definition levels
+	page := decoder.bytes(int(header.int(3)))
+	if decoder.err != nil {
+		return nil, decoder.err
+	}
+
+	values := make([]any, 0, rows)
+	pos := 0
+	for i := range rows {
+		switch column.PhysicalType {
+		case typeBoolean:
+			if i/8 >= len(page) {
+				return nil, fmt.Errorf("parquet: page of %s is short", column.Name)
+			}
+			values = append(values, page[i/8]&(1<<(i%8)) != 0)
+		case typeInt64:
+			if pos+8 > len(page) {
+				return nil, fmt.Errorf("parquet: page of %s is short", column.Name)
+			}
+			v := int64(binary.LittleEndian.Uint64(page[pos:]))
+			pos += 8
+			if column.ConvertedType == convertedTimestampMillis {
+				values = append(values, time.UnixMilli(v).UTC())
+			} else {
+				values = append(values, v)
+			}
+		case typeByteArray:
+			if pos+4 > len(page) {
+				return nil, fmt.Errorf("parquet: page of %s is short", column.Name)
+			}
+			n := int(binary.LittleEndian.Uint32(page[pos:]))
+			pos += 4
+			if pos+n > len(page) {
+				return nil, fmt.Errorf("parquet: page of %s is short", column.Name)
+			}
+			values = append(values, string(page[pos:pos+n]))
+			pos += n
+		default:
+			return nil, fmt.Errorf("parquet: column %s has unexpected type %d", column.Name, column.PhysicalType)
+		}
+	}
+	if column.PhysicalType == typeBoolean {
+		pos = (rows + 7) / 8
+	}
+	if pos != len(page) {
+		return nil, fmt.Errorf("parquet: page of %s has trailing bytes", column.Name)
+	}
+	return values, nil
+}
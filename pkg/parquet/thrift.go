@@ -0,0 +1,195 @@
+package parquet
+
+import "encoding/binary"
+
+// Parquet metadata is serialized with the Thrift compact protocol. The field IDs and enum
+// values below are those of parquet.thrift in the Parquet format specification.
+
+// Thrift compact protocol field types
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// Parquet physical types
+const (
+	physicalBoolean   = 0
+	physicalInt64     = 2
+	physicalByteArray = 6
+)
+
+// Parquet converted types
+const (
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+)
+
+const (
+	repetitionRequired = 0
+	encodingPlain      = 0
+	encodingRLE        = 3
+	codecUncompressed  = 0
+	pageTypeData       = 0
+	formatVersion      = 1
+	createdBy          = "github.com/rasadov/EcommerceAPI/pkg/parquet"
+)
+
+// compactEncoder writes Thrift structs in the compact protocol. Field IDs are written as
+// deltas from the previous field of the same struct, so each open struct keeps its last ID.
+type compactEncoder struct {
+	buf  []byte
+	last []int16
+}
+
+func (e *compactEncoder) field(id int16, fieldType byte) {
+	last := &e.last[len(e.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		e.buf = append(e.buf, byte(delta)<<4|fieldType)
+	} else {
+		e.buf = append(e.buf, fieldType)
+		e.buf = binary.AppendVarint(e.buf, int64(id))
+	}
+	*last = id
+}
+
+func (e *compactEncoder) i32(id int16, v int32) {
+	e.field(id, compactI32)
+	e.buf = binary.AppendVarint(e.buf, int64(v))
+}
+
+func (e *compactEncoder) i64(id int16, v int64) {
+	e.field(id, compactI64)
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *compactEncoder) string(id int16, v string) {
+	e.field(id, compactBinary)
+	e.rawString(v)
+}
+
+func (e *compactEncoder) rawString(v string) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+// list starts a list field of size elements of elementType.
+func (e *compactEncoder) list(id int16, elementType byte, size int) {
+	e.field(id, compactList)
+	if size < 15 {
+		e.buf = append(e.buf, byte(size)<<4|elementType)
+	} else {
+		e.buf = append(e.buf, 0xf0|elementType)
+		e.buf = binary.AppendUvarint(e.buf, uint64(size))
+	}
+}
+
+// begin starts a struct, either a field started with field(id, compactStruct) or a list
+// element.
+func (e *compactEncoder) begin() {
+	e.last = append(e.last, 0)
+}
+
+// end writes the stop byte of the struct begun last.
+func (e *compactEncoder) end() {
+	e.buf = append(e.buf, 0)
+	e.last = e.last[:len(e.last)-1]
+}
+
+func (e *compactEncoder) structField(id int16) {
+	e.field(id, compactStruct)
+	e.begin()
+}
+
+// encodePageHeader returns the PageHeader of a plain encoded data page of size bytes
+// holding values values without definition or repetition levels.
+func encodePageHeader(size, values int) []byte {
+	e := &compactEncoder{}
+	e.begin()
+	e.i32(1, pageTypeData)
+	e.i32(2, int32(size))
+	e.i32(3, int32(size))
+	e.structField(5)
+	e.i32(1, int32(values))
+	e.i32(2, encodingPlain)
+	e.i32(3, encodingRLE)
+	e.i32(4, encodingRLE)
+	e.end()
+	e.end()
+	return e.buf
+}
+
+func physicalType(t Type) int32 {
+	switch t {
+	case Boolean:
+		return physicalBoolean
+	case String:
+		return physicalByteArray
+	default:
+		return physicalInt64
+	}
+}
+
+// encodeFileMetaData returns the FileMetaData footer describing the columns and the row
+// groups written.
+func encodeFileMetaData(columns []Column, rowGroups []rowGroup, numRows int64) []byte {
+	e := &compactEncoder{}
+	e.begin()
+	e.i32(1, formatVersion)
+
+	// The schema is a root element followed by one element per column
+	e.list(2, compactStruct, len(columns)+1)
+	e.begin()
+	e.string(4, "schema")
+	e.i32(5, int32(len(columns)))
+	e.end()
+	for _, column := range columns {
+		e.begin()
+		e.i32(1, physicalType(column.Type))
+		e.i32(3, repetitionRequired)
+		e.string(4, column.Name)
+		switch column.Type {
+		case String:
+			e.i32(6, convertedUTF8)
+		case Timestamp:
+			e.i32(6, convertedTimestampMillis)
+		}
+		e.end()
+	}
+
+	e.i64(3, numRows)
+
+	e.list(4, compactStruct, len(rowGroups))
+	for _, group := range rowGroups {
+		e.begin()
+		var size int64
+		e.list(1, compactStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			size += chunk.size
+			e.begin()
+			e.i64(2, chunk.offset)
+			e.structField(3)
+			e.i32(1, physicalType(columns[i].Type))
+			e.list(2, compactI32, 1)
+			e.buf = binary.AppendVarint(e.buf, encodingPlain)
+			e.list(3, compactBinary, 1)
+			e.rawString(columns[i].Name)
+			e.i32(4, codecUncompressed)
+			e.i64(5, chunk.values)
+			e.i64(6, chunk.size)
+			e.i64(7, chunk.size)
+			e.i64(9, chunk.offset)
+			e.end()
+			e.end()
+		}
+		e.i64(2, size)
+		e.i64(3, group.numRows)
+		e.end()
+	}
+
+	e.string(6, createdBy)
+	e.end()
+	return e.buf
+}
@@ -0,0 +1,124 @@
+// Package pdf writes plain text documents, such as invoices, as PDF. Text is set in
+// Courier so that columns padded with spaces stay aligned, and characters outside Latin-1
+// are replaced.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Pages are A4, in points
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 50
+	// DefaultSize is the font size of lines without one
+	DefaultSize = 10
+	lineSpacing = 1.4
+)
+
+// Line is a line of text. Empty lines leave a gap.
+type Line struct {
+	Text string
+	Bold bool
+	// Size is the font size in points, DefaultSize when zero
+	Size float64
+}
+
+// Render lays the lines out top to bottom, starting new pages as needed, and returns the
+// PDF document.
+func Render(lines []Line) []byte {
+	var pages []string
+	var content strings.Builder
+	y := float64(pageHeight - margin)
+	for _, line := range lines {
+		size := line.Size
+		if size <= 0 {
+			size = DefaultSize
+		}
+		y -= size * lineSpacing
+		if y < margin {
+			pages = append(pages, content.String())
+			content.Reset()
+			y = pageHeight - margin - size*lineSpacing
+		}
+		if line.Text == "" {
+			continue
+		}
+		font := "F1"
+		if line.Bold {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "BT /%s %s Tf %d %s Td (%s) Tj ET\n", font, number(size), margin, number(y), escape(line.Text))
+	}
+	pages = append(pages, content.String())
+
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.4\n")
+
+	// Objects 1 to 4 are the catalog, the page tree and the two fonts; each page then
+	// takes a page object followed by its content stream.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	w.object("<< /Type /Catalog /Pages 2 0 R >>")
+	w.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	w.object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	w.object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range pages {
+		w.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		w.object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(page), page))
+	}
+	return w.finish()
+}
+
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *writer) object(body string) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", len(w.offsets), body)
+}
+
+func (w *writer) finish() []byte {
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, xref)
+	return w.buf.Bytes()
+}
+
+// escape encodes text as the body of a PDF string in WinAnsiEncoding.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '€':
+			b.WriteString(`\200`)
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x80:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func number(f float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", f), "0"), ".")
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/pkg/pdf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	t.Run("Document is well formed", func(t *testing.T) {
+		doc := pdf.Render([]pdf.Line{{Text: "Invoice", Bold: true, Size: 16}, {}, {Text: "Total: 19,99 €"}})
+
+		assert.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4\n")))
+		assert.True(t, bytes.HasSuffix(doc, []byte("%%EOF\n")))
+
+		// startxref points at the cross-reference table, whose entries point at the objects
+		match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(doc)
+		require.NotNil(t, match)
+		xref, err := strconv.Atoi(string(match[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(doc[xref:], []byte("xref\n0 7\n")))
+		entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(doc[xref:], -1)
+		require.Len(t, entries, 6)
+		for i, entry := range entries {
+			offset, err := strconv.Atoi(string(entry[1]))
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(doc[offset:], []byte(strconv.Itoa(i+1)+" 0 obj")))
+		}
+	})
+
+	t.Run("Text is escaped", func(t *testing.T) {
+		doc := string(pdf.Render([]pdf.Line{{Text: `Lamp (blue) \ 19,99 € – Grüße`}}))
+
+		assert.Contains(t, doc, `(Lamp \(blue\) \\ 19,99 \200 ? Gr\374\337e) Tj`)
+		assert.Contains(t, doc, "/F1 10 Tf")
+	})
+
+	t.Run("Long documents break into pages", func(t *testing.T) {
+		lines := make([]pdf.Line, 120)
+		for i := range lines {
+			lines[i] = pdf.Line{Text: "line " + strconv.Itoa(i)}
+		}
+		doc := string(pdf.Render(lines))
+
+		assert.Contains(t, doc, "/Count 3")
+		assert.Equal(t, 3, strings.Count(doc, "/Type /Page "))
+		assert.Equal(t, 120, strings.Count(doc, ") Tj"))
+	})
+}
@@ -0,0 +1,166 @@
+package tax
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+// Rate is one row of a rate table.
+type Rate struct {
+	Country string `json:"country"`
+	// Region narrows the rate to a state or province; empty applies to the whole country
+	Region string `json:"region,omitempty"`
+	// Category narrows the rate to one tax category; empty applies to every category
+	// without a rate of its own
+	Category Category `json:"category,omitempty"`
+	Name     string   `json:"name"`
+	// Rate is a decimal fraction between 0 and 1, e.g. "0.0725"
+	Rate string `json:"rate"`
+
+	rate *big.Rat
+}
+
+// RateTable holds the tax rates of every country taxes are collected in, e.g.
+// {"pricesIncludeTax": true, "rates": [{"country": "DE", "name": "VAT", "rate": "0.19"}]}.
+type RateTable struct {
+	// PricesIncludeTax is set when catalogue prices already include the tax
+	PricesIncludeTax bool   `json:"pricesIncludeTax"`
+	Rates            []Rate `json:"rates"`
+}
+
+// LoadRateTable reads a rate table from a JSON file.
+func LoadRateTable(path string) (*RateTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeRateTable(data)
+}
+
+// DecodeRateTable parses and validates a JSON rate table.
+func DecodeRateTable(data []byte) (*RateTable, error) {
+	var table RateTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, err
+	}
+
+	for i := range table.Rates {
+		rate := &table.Rates[i]
+		rate.Country = strings.ToUpper(strings.TrimSpace(rate.Country))
+		rate.Region = strings.ToUpper(strings.TrimSpace(rate.Region))
+		if len(rate.Country) != 2 {
+			return nil, fmt.Errorf("invalid country %q in tax rate table", rate.Country)
+		}
+		if rate.Category != "" {
+			category, err := ParseCategory(string(rate.Category))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", err, rate.Category)
+			}
+			rate.Category = category
+		}
+		rate.Rate = strings.TrimSpace(rate.Rate)
+		r, ok := new(big.Rat).SetString(rate.Rate)
+		if !ok || r.Sign() < 0 || r.Cmp(big.NewRat(1, 1)) > 0 {
+			return nil, fmt.Errorf("%w for %s: %q", ErrInvalidRate, rate.Country, rate.Rate)
+		}
+		rate.rate = r
+	}
+	return &table, nil
+}
+
+// Lookup returns the rate for category at location. A rate for the region beats one for
+// the whole country, and a rate for the category beats one for every category.
+func (t *RateTable) Lookup(location Location, category Category) (*Rate, bool) {
+	country := strings.ToUpper(strings.TrimSpace(location.Country))
+	region := strings.ToUpper(strings.TrimSpace(location.Region))
+
+	var best *Rate
+	bestScore := -1
+	for i := range t.Rates {
+		rate := &t.Rates[i]
+		if rate.Country != country {
+			continue
+		}
+		if rate.Region != "" && rate.Region != region {
+			continue
+		}
+		if rate.Category != "" && rate.Category != category {
+			continue
+		}
+
+		score := 0
+		if rate.Region != "" {
+			score += 2
+		}
+		if rate.Category != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rate, score
+		}
+	}
+	return best, best != nil
+}
+
+// TableCalculator taxes orders with the rates of a RateTable.
+type TableCalculator struct {
+	table *RateTable
+}
+
+func NewTableCalculator(table *RateTable) *TableCalculator {
+	return &TableCalculator{table: table}
+}
+
+// Calculate taxes every line at the rate for its category, rounding each line half up.
+// Orders without a country and exempt products are not taxed.
+func (c *TableCalculator) Calculate(ctx context.Context, location Location, currency string, lines []Line) (*Assessment, error) {
+	assessment := &Assessment{
+		Inclusive: c.table.PricesIncludeTax,
+		Total:     money.Zero(currency),
+	}
+	if strings.TrimSpace(location.Country) == "" {
+		return assessment, nil
+	}
+
+	for _, line := range lines {
+		if line.Category == CategoryExempt {
+			continue
+		}
+		rate, ok := c.table.Lookup(location, line.Category)
+		if !ok {
+			continue
+		}
+
+		// Inclusive prices hold rate/(1+rate) of tax, exclusive prices take rate on top
+		multiplier := rate.rate
+		if assessment.Inclusive {
+			multiplier = new(big.Rat).Quo(rate.rate, new(big.Rat).Add(big.NewRat(1, 1), rate.rate))
+		}
+		amount, err := line.Amount.MulRat(multiplier, money.RoundHalfUp)
+		if err != nil {
+			return nil, err
+		}
+		if amount.IsZero() {
+			continue
+		}
+
+		assessment.Total, err = assessment.Total.Add(amount)
+		if err != nil {
+			return nil, err
+		}
+		assessment.Lines = append(assessment.Lines, TaxLine{
+			ProductID: line.ProductID,
+			Category:  line.Category,
+			Name:      rate.Name,
+			Rate:      rate.Rate,
+			Amount:    amount,
+		})
+	}
+	return assessment, nil
+}
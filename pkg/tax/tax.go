@@ -0,0 +1,98 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+var (
+	ErrInvalidCategory = errors.New("invalid tax category")
+	ErrInvalidRate     = errors.New("invalid tax rate")
+)
+
+// Category is the tax treatment of a product. The digital categories share their names
+// with the ones the payment provider understands.
+type Category string
+
+const (
+	CategoryStandard Category = "standard"
+	CategoryReduced  Category = "reduced"
+	// CategoryExempt products are never taxed, whatever the rate table says
+	CategoryExempt          Category = "exempt"
+	CategoryDigitalProducts Category = "digital_products"
+	CategorySaaS            Category = "saas"
+	CategoryEBook           Category = "e_book"
+	CategoryEdtech          Category = "edtech"
+)
+
+var categories = []Category{
+	CategoryStandard,
+	CategoryReduced,
+	CategoryExempt,
+	CategoryDigitalProducts,
+	CategorySaaS,
+	CategoryEBook,
+	CategoryEdtech,
+}
+
+// ParseCategory returns the category named by s. An empty s is the standard category.
+func ParseCategory(s string) (Category, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return CategoryStandard, nil
+	}
+	for _, category := range categories {
+		if string(category) == s {
+			return category, nil
+		}
+	}
+	return "", ErrInvalidCategory
+}
+
+// Categories lists every supported tax category.
+func Categories() []Category {
+	return append([]Category(nil), categories...)
+}
+
+// Location is where an order is delivered, which decides the rates that apply.
+type Location struct {
+	// Country is an ISO 3166-1 alpha-2 code
+	Country string
+	// Region is the state or province code within the country, if any
+	Region string
+}
+
+// Line is an amount to be taxed: an order line after its share of the order discounts.
+type Line struct {
+	ProductID string
+	Category  Category
+	Amount    money.Money
+}
+
+// TaxLine is the tax charged on one line.
+type TaxLine struct {
+	ProductID string
+	Category  Category
+	// Name is the name of the tax, e.g. "VAT"
+	Name string
+	// Rate is the decimal rate applied, e.g. "0.19"
+	Rate   string
+	Amount money.Money
+}
+
+// Assessment is the tax due on an order.
+type Assessment struct {
+	Lines []TaxLine
+	// Inclusive is set when the taxed amounts already include the tax, in which case it is
+	// not added to the order total
+	Inclusive bool
+	Total     money.Money
+}
+
+// Calculator works out the tax due on lines delivered to location, all priced in currency.
+type Calculator interface {
+	Calculate(ctx context.Context, location Location, currency string, lines []Line) (*Assessment, error)
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/pkg/tax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rates = `{
+	"rates": [
+		{"country": "us", "region": "ca", "name": "CA sales tax", "rate": "0.0725"},
+		{"country": "DE", "name": "VAT", "rate": "0.19"},
+		{"country": "DE", "category": "reduced", "name": "VAT", "rate": "0.07"}
+	]
+}`
+
+// Test helper to decode a rate table, optionally with tax-inclusive prices
+func setupTable(t *testing.T, inclusive bool) *tax.RateTable {
+	table, err := tax.DecodeRateTable([]byte(rates))
+	require.NoError(t, err)
+	table.PricesIncludeTax = inclusive
+	return table
+}
+
+func TestRateTable_Lookup(t *testing.T) {
+	table := setupTable(t, false)
+
+	t.Run("Category rate beats the country rate", func(t *testing.T) {
+		rate, ok := table.Lookup(tax.Location{Country: "de"}, tax.CategoryReduced)
+		require.True(t, ok)
+		assert.Equal(t, "0.07", rate.Rate)
+
+		rate, ok = table.Lookup(tax.Location{Country: "DE"}, tax.CategoryStandard)
+		require.True(t, ok)
+		assert.Equal(t, "0.19", rate.Rate)
+	})
+
+	t.Run("Region rates only apply in their region", func(t *testing.T) {
+		rate, ok := table.Lookup(tax.Location{Country: "US", Region: "CA"}, tax.CategoryStandard)
+		require.True(t, ok)
+		assert.Equal(t, "CA sales tax", rate.Name)
+
+		_, ok = table.Lookup(tax.Location{Country: "US", Region: "OR"}, tax.CategoryStandard)
+		assert.False(t, ok)
+	})
+}
+
+func TestDecodeRateTable_Invalid(t *testing.T) {
+	_, err := tax.DecodeRateTable([]byte(`{"rates": [{"country": "DE", "rate": "1.5"}]}`))
+	assert.ErrorIs(t, err, tax.ErrInvalidRate)
+
+	_, err = tax.DecodeRateTable([]byte(`{"rates": [{"country": "DE", "category": "luxury", "rate": "0.2"}]}`))
+	assert.ErrorIs(t, err, tax.ErrInvalidCategory)
+}
+
+func TestTableCalculator_Calculate(t *testing.T) {
+	ctx := context.Background()
+	lines := []tax.Line{
+		{ProductID: "book", Category: tax.CategoryReduced, Amount: money.New(1999, "EUR")},
+		{ProductID: "lamp", Category: tax.CategoryStandard, Amount: money.New(5000, "EUR")},
+		{ProductID: "voucher", Category: tax.CategoryExempt, Amount: money.New(2500, "EUR")},
+	}
+
+	t.Run("Exclusive prices take the rate on top", func(t *testing.T) {
+		calculator := tax.NewTableCalculator(setupTable(t, false))
+
+		assessment, err := calculator.Calculate(ctx, tax.Location{Country: "DE"}, "EUR", lines)
+
+		require.NoError(t, err)
+		assert.False(t, assessment.Inclusive)
+		require.Len(t, assessment.Lines, 2)
+		assert.Equal(t, money.New(140, "EUR"), assessment.Lines[0].Amount)
+		assert.Equal(t, money.New(950, "EUR"), assessment.Lines[1].Amount)
+		assert.Equal(t, money.New(1090, "EUR"), assessment.Total)
+	})
+
+	t.Run("Inclusive prices already hold the tax", func(t *testing.T) {
+		calculator := tax.NewTableCalculator(setupTable(t, true))
+
+		assessment, err := calculator.Calculate(ctx, tax.Location{Country: "DE"}, "EUR", lines)
+
+		require.NoError(t, err)
+		assert.True(t, assessment.Inclusive)
+		require.Len(t, assessment.Lines, 2)
+		// 19.99 * 0.07 / 1.07 and 50.00 * 0.19 / 1.19
+		assert.Equal(t, money.New(131, "EUR"), assessment.Lines[0].Amount)
+		assert.Equal(t, money.New(798, "EUR"), assessment.Lines[1].Amount)
+	})
+
+	t.Run("Orders without a country are not taxed", func(t *testing.T) {
+		calculator := tax.NewTableCalculator(setupTable(t, false))
+
+		assessment, err := calculator.Calculate(ctx, tax.Location{}, "EUR", lines)
+
+		require.NoError(t, err)
+		assert.Empty(t, assessment.Lines)
+		assert.True(t, assessment.Total.IsZero())
+	})
+}
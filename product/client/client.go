@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 	"github.com/rasadov/EcommerceAPI/product/models"
 	"github.com/rasadov/EcommerceAPI/product/proto/pb"
 	"google.golang.org/grpc"
@@ -32,6 +33,21 @@ func (client *Client) Close() {
 	}
 }
 
+func stockToProto(stock *int) *wrapperspb.Int64Value {
+	if stock == nil {
+		return nil
+	}
+	return wrapperspb.Int64(int64(*stock))
+}
+
+func stockFromProto(stock *wrapperspb.Int64Value) *int {
+	if stock == nil {
+		return nil
+	}
+	value := int(stock.GetValue())
+	return &value
+}
+
 func (client *Client) GetProduct(ctx context.Context, id string) (*models.Product, error) {
 	res, err := client.service.GetProduct(ctx, &wrapperspb.StringValue{
 		Value: id,
@@ -43,8 +59,12 @@ func (client *Client) GetProduct(ctx context.Context, id string) (*models.Produc
 		ID:          res.Product.Id,
 		Name:        res.Product.Name,
 		Description: res.Product.Description,
-		Price:       res.Product.Price,
+		Price:       money.FromProto(res.Product.GetPrice()),
 		AccountID:   int(res.Product.GetAccountId()),
+		Stock:       stockFromProto(res.Product.GetStock()),
+		Category:    res.Product.GetCategory(),
+		TaxCategory: res.Product.GetTaxCategory(),
+		WeightGrams: int(res.Product.GetWeightGrams()),
 	}, nil
 }
 
@@ -64,19 +84,53 @@ func (client *Client) GetProducts(ctx context.Context, skip, take uint64, ids []
 			ID:          p.Id,
 			Name:        p.Name,
 			Description: p.Description,
-			Price:       p.Price,
+			Price:       money.FromProto(p.GetPrice()),
+			AccountID:   int(p.AccountId),
+			Stock:       stockFromProto(p.GetStock()),
+			Category:    p.GetCategory(),
+			TaxCategory: p.GetTaxCategory(),
+			WeightGrams: int(p.GetWeightGrams()),
+		})
+	}
+	return products, nil
+}
+
+func (client *Client) ListProductsBySeller(ctx context.Context, accountId int64, skip, take uint64) ([]models.Product, error) {
+	res, err := client.service.ListProductsBySeller(ctx, &pb.ListProductsBySellerRequest{
+		AccountId: accountId,
+		Skip:      skip,
+		Take:      take,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var products []models.Product
+	for _, p := range res.Products {
+		products = append(products, models.Product{
+			ID:          p.Id,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       money.FromProto(p.GetPrice()),
 			AccountID:   int(p.AccountId),
+			Stock:       stockFromProto(p.GetStock()),
+			Category:    p.GetCategory(),
+			TaxCategory: p.GetTaxCategory(),
+			WeightGrams: int(p.GetWeightGrams()),
 		})
 	}
 	return products, nil
 }
 
-func (client *Client) PostProduct(ctx context.Context, name, description string, price float64, accountId int64) (*models.Product, error) {
+func (client *Client) PostProduct(ctx context.Context, name, description, category, taxCategory string, price money.Money, stock *int, weightGrams int, accountId int64) (*models.Product, error) {
 	res, err := client.service.PostProduct(ctx, &pb.CreateProductRequest{
 		Name:        name,
 		Description: description,
-		Price:       price,
+		Price:       price.ToProto(),
 		AccountId:   accountId,
+		Stock:       stockToProto(stock),
+		Category:    category,
+		TaxCategory: taxCategory,
+		WeightGrams: int32(weightGrams),
 	})
 	if err != nil {
 		log.Println("Error creating product", err)
@@ -86,18 +140,26 @@ func (client *Client) PostProduct(ctx context.Context, name, description string,
 		ID:          res.Product.Id,
 		Name:        res.Product.Name,
 		Description: res.Product.Description,
-		Price:       res.Product.Price,
+		Price:       money.FromProto(res.Product.GetPrice()),
 		AccountID:   int(res.Product.GetAccountId()),
+		Stock:       stockFromProto(res.Product.GetStock()),
+		Category:    res.Product.GetCategory(),
+		TaxCategory: res.Product.GetTaxCategory(),
+		WeightGrams: int(res.Product.GetWeightGrams()),
 	}, nil
 }
 
-func (client *Client) UpdateProduct(ctx context.Context, id, name, description string, price float64, accountId int64) (*models.Product, error) {
+func (client *Client) UpdateProduct(ctx context.Context, id, name, description, category, taxCategory string, price money.Money, stock *int, weightGrams int, accountId int64) (*models.Product, error) {
 	res, err := client.service.UpdateProduct(ctx, &pb.UpdateProductRequest{
 		Id:          id,
 		Name:        name,
 		Description: description,
-		Price:       price,
+		Price:       price.ToProto(),
 		AccountId:   accountId,
+		Stock:       stockToProto(stock),
+		Category:    category,
+		TaxCategory: taxCategory,
+		WeightGrams: int32(weightGrams),
 	})
 	if err != nil {
 		return nil, err
@@ -106,8 +168,12 @@ func (client *Client) UpdateProduct(ctx context.Context, id, name, description s
 		ID:          res.Product.Id,
 		Name:        res.Product.Name,
 		Description: res.Product.Description,
-		Price:       res.Product.Price,
+		Price:       money.FromProto(res.Product.GetPrice()),
 		AccountID:   int(res.Product.GetAccountId()),
+		Stock:       stockFromProto(res.Product.GetStock()),
+		Category:    res.Product.GetCategory(),
+		TaxCategory: res.Product.GetTaxCategory(),
+		WeightGrams: int(res.Product.GetWeightGrams()),
 	}, nil
 }
 
@@ -115,3 +181,25 @@ func (client *Client) DeleteProduct(ctx context.Context, productId string, accou
 	_, err := client.service.DeleteProduct(ctx, &pb.DeleteProductRequest{ProductId: productId, AccountId: accountId})
 	return err
 }
+
+func stockItemsToProto(items []models.StockItem) []*pb.StockItem {
+	protoItems := make([]*pb.StockItem, 0, len(items))
+	for _, item := range items {
+		protoItems = append(protoItems, &pb.StockItem{
+			ProductId: item.ProductID,
+			Quantity:  uint32(item.Quantity),
+		})
+	}
+	return protoItems
+}
+
+// ReserveStock takes all items out of stock, failing with codes.FailedPrecondition if any is unavailable.
+func (client *Client) ReserveStock(ctx context.Context, items []models.StockItem) error {
+	_, err := client.service.ReserveStock(ctx, &pb.StockRequest{Items: stockItemsToProto(items)})
+	return err
+}
+
+func (client *Client) ReleaseStock(ctx context.Context, items []models.StockItem) error {
+	_, err := client.service.ReleaseStock(ctx, &pb.StockRequest{Items: stockItemsToProto(items)})
+	return err
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/rasadov/EcommerceAPI/product/config"
+	"github.com/rasadov/EcommerceAPI/product/internal"
+)
+
+// backfill sets the seller of products indexed before their accountId was stored, from a
+// productId,accountId CSV file.
+func main() {
+	file := flag.String("file", "", "CSV file of productId,accountId rows")
+	flag.Parse()
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	sellers, err := internal.ReadSellers(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	repository, err := internal.NewElasticRepository(config.DatabaseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer repository.Close()
+
+	updated, err := repository.BackfillSellers(context.Background(), sellers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Set the seller of %d of %d products", updated, len(sellers))
+}
@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadSellers reads productId,accountId rows, as exported from the recommender's products table,
+// into a map of product ID to seller. A header row is skipped.
+func ReadSellers(r io.Reader) (map[string]int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	sellers := make(map[string]int)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return sellers, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		productId := strings.TrimSpace(record[0])
+		accountId, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid account id %q", line, record[1])
+		}
+		if productId == "" || accountId <= 0 {
+			return nil, fmt.Errorf("line %d: product id and account id are required", line)
+		}
+		sellers[productId] = accountId
+	}
+}
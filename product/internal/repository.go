@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 
 	"gopkg.in/olivere/elastic.v5"
@@ -12,9 +14,18 @@ import (
 )
 
 var (
-	ErrNotFound = errors.New("entity not found")
+	ErrNotFound          = errors.New("entity not found")
+	ErrInsufficientStock = errors.New("insufficient stock")
 )
 
+// adjustStockScript changes tracked stock by params.delta unless that would make it
+// negative. Untracked stock and refused changes both leave the document untouched.
+const adjustStockScript = `if (ctx._source.stock == null || ctx._source.stock + params.delta < 0) {
+	ctx.op = 'none'
+} else {
+	ctx._source.stock += params.delta
+}`
+
 type Repository interface {
 	Close()
 	PutProduct(ctx context.Context, p *models.Product) error
@@ -22,8 +33,11 @@ type Repository interface {
 	ListProducts(ctx context.Context, skip, take uint64) ([]*models.Product, error)
 	ListProductsWithIDs(ctx context.Context, ids []string) ([]*models.Product, error)
 	SearchProducts(ctx context.Context, query string, skip, take uint64) ([]*models.Product, error)
+	ListProductsBySeller(ctx context.Context, accountId int, skip, take uint64) ([]*models.Product, error)
 	UpdateProduct(ctx context.Context, updatedProduct *models.Product) error
 	DeleteProduct(ctx context.Context, productId string) error
+	BackfillSellers(ctx context.Context, sellers map[string]int) (int, error)
+	AdjustStock(ctx context.Context, productId string, delta int) (*models.Product, error)
 }
 
 type elasticRepository struct {
@@ -38,9 +52,124 @@ func NewElasticRepository(url string) (Repository, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	err = migrateCatalog(context.Background(), client)
+	if err != nil {
+		return nil, err
+	}
+
 	return &elasticRepository{client}, nil
 }
 
+// catalogIndex holds the products since prices became money objects. The catalog name
+// every query uses is an alias for it.
+const catalogIndex = "catalog_v2"
+
+const catalogMapping = `{
+	"mappings": {
+		"product": {
+			"properties": {
+				"name": {"type": "text"},
+				"description": {"type": "text"},
+				"price": {
+					"properties": {
+						"amount": {"type": "long"},
+						"currency": {"type": "keyword"}
+					}
+				},
+				"accountId": {"type": "long"}
+			}
+		}
+	}
+}`
+
+// migrateCatalog creates catalogIndex and points the catalog alias at it. Products in a
+// catalog index created by older versions, where price was mapped as a number, are copied
+// over with their price converted to money and the old index is dropped. It is safe to run
+// again after a partial migration.
+func migrateCatalog(ctx context.Context, client *elastic.Client) error {
+	exists, err := client.IndexExists(catalogIndex).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		_, err = client.CreateIndex(catalogIndex).BodyString(catalogMapping).Do(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	aliases, err := client.Aliases().Index(catalogIndex).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if aliases.Indices[catalogIndex].HasAlias("catalog") {
+		return nil
+	}
+
+	legacy, err := client.IndexExists("catalog").Do(ctx)
+	if err != nil {
+		return err
+	}
+	if legacy {
+		if err = copyLegacyCatalog(ctx, client); err != nil {
+			return err
+		}
+		if _, err = client.DeleteIndex("catalog").Do(ctx); err != nil {
+			return err
+		}
+	}
+
+	_, err = client.Alias().Add(catalogIndex, "catalog").Do(ctx)
+	return err
+}
+
+// copyLegacyCatalog copies every product of the old catalog index into catalogIndex,
+// keeping their IDs.
+func copyLegacyCatalog(ctx context.Context, client *elastic.Client) error {
+	scroll := client.Scroll("catalog").Type("product").Size(500)
+	defer scroll.Clear(ctx)
+
+	copied := 0
+	for {
+		res, err := scroll.Do(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		bulk := client.Bulk()
+		for _, hit := range res.Hits.Hits {
+			product := models.ProductDocument{}
+			if err = json.Unmarshal(*hit.Source, &product); err != nil {
+				return fmt.Errorf("product %s: %w", hit.Id, err)
+			}
+			bulk.Add(elastic.NewBulkIndexRequest().
+				Index(catalogIndex).
+				Type("product").
+				Id(hit.Id).
+				Doc(product))
+		}
+		bulkRes, err := bulk.Do(ctx)
+		if err != nil {
+			return err
+		}
+		if failed := bulkRes.Failed(); len(failed) > 0 {
+			return fmt.Errorf("copying product %s: %s", failed[0].Id, failed[0].Error.Reason)
+		}
+		copied += len(res.Hits.Hits)
+	}
+
+	_, err := client.Refresh(catalogIndex).Do(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("Copied %d products into %s", copied, catalogIndex)
+	return nil
+}
+
 func (r *elasticRepository) Close() {
 	r.client.Stop()
 }
@@ -53,6 +182,11 @@ func (r *elasticRepository) PutProduct(ctx context.Context, p *models.Product) e
 			Name:        p.Name,
 			Description: p.Description,
 			Price:       p.Price,
+			AccountID:   p.AccountID,
+			Stock:       p.Stock,
+			Category:    p.Category,
+			TaxCategory: p.TaxCategory,
+			WeightGrams: p.WeightGrams,
 		}).
 		Do(ctx)
 	if err != nil {
@@ -81,6 +215,11 @@ func (r *elasticRepository) GetProductById(ctx context.Context, id string) (*mod
 		Name:        product.Name,
 		Description: product.Description,
 		Price:       product.Price,
+		AccountID:   product.AccountID,
+		Stock:       product.Stock,
+		Category:    product.Category,
+		TaxCategory: product.TaxCategory,
+		WeightGrams: product.WeightGrams,
 	}, nil
 }
 
@@ -105,6 +244,11 @@ func (r *elasticRepository) ListProducts(ctx context.Context, skip, take uint64)
 				Name:        product.Name,
 				Description: product.Description,
 				Price:       product.Price,
+				AccountID:   product.AccountID,
+				Stock:       product.Stock,
+				Category:    product.Category,
+				TaxCategory: product.TaxCategory,
+				WeightGrams: product.WeightGrams,
 			})
 		}
 	}
@@ -136,6 +280,11 @@ func (r *elasticRepository) ListProductsWithIDs(ctx context.Context, ids []strin
 				Name:        product.Name,
 				Description: product.Description,
 				Price:       product.Price,
+				AccountID:   product.AccountID,
+				Stock:       product.Stock,
+				Category:    product.Category,
+				TaxCategory: product.TaxCategory,
+				WeightGrams: product.WeightGrams,
 			})
 		}
 	}
@@ -163,6 +312,43 @@ func (r *elasticRepository) SearchProducts(ctx context.Context, query string, sk
 				Name:        product.Name,
 				Description: product.Description,
 				Price:       product.Price,
+				AccountID:   product.AccountID,
+				Stock:       product.Stock,
+				Category:    product.Category,
+				TaxCategory: product.TaxCategory,
+				WeightGrams: product.WeightGrams,
+			})
+		}
+	}
+	return products, err
+}
+
+func (r *elasticRepository) ListProductsBySeller(ctx context.Context, accountId int, skip, take uint64) ([]*models.Product, error) {
+	res, err := r.client.Search().
+		Index("catalog").
+		Type("product").
+		Query(elastic.NewTermQuery("accountId", accountId)).
+		From(int(skip)).
+		Size(int(take)).
+		Do(ctx)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	var products []*models.Product
+	for _, hit := range res.Hits.Hits {
+		product := models.ProductDocument{}
+		if err = json.Unmarshal(*hit.Source, &product); err == nil {
+			products = append(products, &models.Product{
+				ID:          hit.Id,
+				Name:        product.Name,
+				Description: product.Description,
+				Price:       product.Price,
+				AccountID:   product.AccountID,
+				Stock:       product.Stock,
+				Category:    product.Category,
+				TaxCategory: product.TaxCategory,
+				WeightGrams: product.WeightGrams,
 			})
 		}
 	}
@@ -178,6 +364,11 @@ func (r *elasticRepository) UpdateProduct(ctx context.Context, updatedProduct *m
 			Name:        updatedProduct.Name,
 			Description: updatedProduct.Description,
 			Price:       updatedProduct.Price,
+			AccountID:   updatedProduct.AccountID,
+			Stock:       updatedProduct.Stock,
+			Category:    updatedProduct.Category,
+			TaxCategory: updatedProduct.TaxCategory,
+			WeightGrams: updatedProduct.WeightGrams,
 		}).
 		Do(ctx)
 	return err
@@ -191,3 +382,81 @@ func (r *elasticRepository) DeleteProduct(ctx context.Context, productId string)
 		Do(ctx)
 	return err
 }
+
+// BackfillSellers sets the seller of products indexed before accountId was stored. Products that
+// already have a seller are left as they are. It returns how many products were updated.
+func (r *elasticRepository) BackfillSellers(ctx context.Context, sellers map[string]int) (int, error) {
+	if len(sellers) == 0 {
+		return 0, nil
+	}
+	bulk := r.client.Bulk()
+	for productId, accountId := range sellers {
+		bulk.Add(elastic.NewBulkUpdateRequest().
+			Index("catalog").
+			Type("product").
+			Id(productId).
+			Script(elastic.NewScript(
+				"if (ctx._source.accountId == null || ctx._source.accountId == 0) "+
+					"{ ctx._source.accountId = params.accountId } else { ctx.op = 'none' }").
+				Lang("painless").
+				Param("accountId", accountId)))
+	}
+	res, err := bulk.Do(ctx)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+	updated := 0
+	for _, item := range res.Updated() {
+		if item.Error != nil {
+			log.Println("Error backfilling seller of product", item.Id, item.Error.Reason)
+			continue
+		}
+		if item.Result == "updated" {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// AdjustStock atomically adds delta to the product's stock and returns the product as
+// stored afterwards. Products that do not track stock are returned unchanged.
+func (r *elasticRepository) AdjustStock(ctx context.Context, productId string, delta int) (*models.Product, error) {
+	res, err := r.client.Update().
+		Index("catalog").
+		Type("product").
+		Id(productId).
+		Script(elastic.NewScript(adjustStockScript).Lang("painless").Param("delta", delta)).
+		RetryOnConflict(3).
+		FetchSource(true).
+		Do(ctx)
+	if elastic.IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	if res.GetResult == nil || res.GetResult.Source == nil {
+		return nil, ErrNotFound
+	}
+
+	product := models.ProductDocument{}
+	if err = json.Unmarshal(*res.GetResult.Source, &product); err != nil {
+		return nil, err
+	}
+	if res.Result == "noop" && product.Stock != nil && delta != 0 {
+		return nil, ErrInsufficientStock
+	}
+	return &models.Product{
+		ID:          productId,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		AccountID:   product.AccountID,
+		Stock:       product.Stock,
+		Category:    product.Category,
+		TaxCategory: product.TaxCategory,
+		WeightGrams: product.WeightGrams,
+	}, nil
+}
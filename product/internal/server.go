@@ -2,15 +2,19 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
+	"github.com/rasadov/EcommerceAPI/pkg/money"
 	"github.com/rasadov/EcommerceAPI/product/models"
 	"github.com/rasadov/EcommerceAPI/product/proto/pb"
 )
@@ -34,6 +38,21 @@ func ListenGRPC(s Service, port int) error {
 	return serv.Serve(lis)
 }
 
+func stockToProto(stock *int) *wrapperspb.Int64Value {
+	if stock == nil {
+		return nil
+	}
+	return wrapperspb.Int64(int64(*stock))
+}
+
+func stockFromProto(stock *wrapperspb.Int64Value) *int {
+	if stock == nil {
+		return nil
+	}
+	value := int(stock.GetValue())
+	return &value
+}
+
 func (s *grpcServer) GetProduct(ctx context.Context, r *wrapperspb.StringValue) (*pb.ProductResponse, error) {
 	p, err := s.service.GetProduct(ctx, r.Value)
 	if err != nil {
@@ -43,7 +62,12 @@ func (s *grpcServer) GetProduct(ctx context.Context, r *wrapperspb.StringValue)
 		Id:          p.ID,
 		Name:        p.Name,
 		Description: p.Description,
-		Price:       p.Price,
+		Price:       p.Price.ToProto(),
+		AccountId:   int64(p.AccountID),
+		Stock:       stockToProto(p.Stock),
+		Category:    p.Category,
+		TaxCategory: p.TaxCategory,
+		WeightGrams: int32(p.WeightGrams),
 	}}, nil
 }
 
@@ -66,15 +90,43 @@ func (s *grpcServer) GetProducts(ctx context.Context, r *pb.GetProductsRequest)
 			Id:          p.ID,
 			Name:        p.Name,
 			Description: p.Description,
-			Price:       p.Price,
+			Price:       p.Price.ToProto(),
+			AccountId:   int64(p.AccountID),
+			Stock:       stockToProto(p.Stock),
+			Category:    p.Category,
+			TaxCategory: p.TaxCategory,
+			WeightGrams: int32(p.WeightGrams),
 		})
 
 	}
 	return &pb.ProductsResponse{Products: products}, nil
 }
 
+func (s *grpcServer) ListProductsBySeller(ctx context.Context, r *pb.ListProductsBySellerRequest) (*pb.ProductsResponse, error) {
+	res, err := s.service.GetProductsBySeller(ctx, int(r.GetAccountId()), r.GetSkip(), r.GetTake())
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	var products []*pb.Product
+	for _, p := range res {
+		products = append(products, &pb.Product{
+			Id:          p.ID,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       p.Price.ToProto(),
+			AccountId:   int64(p.AccountID),
+			Stock:       stockToProto(p.Stock),
+			Category:    p.Category,
+			TaxCategory: p.TaxCategory,
+			WeightGrams: int32(p.WeightGrams),
+		})
+	}
+	return &pb.ProductsResponse{Products: products}, nil
+}
+
 func (s *grpcServer) PostProduct(ctx context.Context, r *pb.CreateProductRequest) (*pb.ProductResponse, error) {
-	p, err := s.service.PostProduct(ctx, r.GetName(), r.GetDescription(), r.Price, int(r.GetAccountId()))
+	p, err := s.service.PostProduct(ctx, r.GetName(), r.GetDescription(), r.GetCategory(), r.GetTaxCategory(), money.FromProto(r.GetPrice()), stockFromProto(r.GetStock()), int(r.GetWeightGrams()), int(r.GetAccountId()))
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -83,12 +135,17 @@ func (s *grpcServer) PostProduct(ctx context.Context, r *pb.CreateProductRequest
 		Id:          p.ID,
 		Name:        p.Name,
 		Description: p.Description,
-		Price:       p.Price,
+		Price:       p.Price.ToProto(),
+		AccountId:   int64(p.AccountID),
+		Stock:       stockToProto(p.Stock),
+		Category:    p.Category,
+		TaxCategory: p.TaxCategory,
+		WeightGrams: int32(p.WeightGrams),
 	}}, nil
 }
 
 func (s *grpcServer) UpdateProduct(ctx context.Context, r *pb.UpdateProductRequest) (*pb.ProductResponse, error) {
-	p, err := s.service.UpdateProduct(ctx, r.GetId(), r.GetName(), r.GetDescription(), r.Price, int(r.GetAccountId()))
+	p, err := s.service.UpdateProduct(ctx, r.GetId(), r.GetName(), r.GetDescription(), r.GetCategory(), r.GetTaxCategory(), money.FromProto(r.GetPrice()), stockFromProto(r.GetStock()), int(r.GetWeightGrams()), int(r.GetAccountId()))
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -97,7 +154,12 @@ func (s *grpcServer) UpdateProduct(ctx context.Context, r *pb.UpdateProductReque
 		Id:          p.ID,
 		Name:        p.Name,
 		Description: p.Description,
-		Price:       p.Price,
+		Price:       p.Price.ToProto(),
+		AccountId:   int64(p.AccountID),
+		Stock:       stockToProto(p.Stock),
+		Category:    p.Category,
+		TaxCategory: p.TaxCategory,
+		WeightGrams: int32(p.WeightGrams),
 	}}, nil
 }
 
@@ -109,3 +171,42 @@ func (s *grpcServer) DeleteProduct(ctx context.Context, r *pb.DeleteProductReque
 	}
 	return &emptypb.Empty{}, nil
 }
+
+func stockItemsFromProto(items []*pb.StockItem) []models.StockItem {
+	stockItems := make([]models.StockItem, 0, len(items))
+	for _, item := range items {
+		stockItems = append(stockItems, models.StockItem{
+			ProductID: item.GetProductId(),
+			Quantity:  int(item.GetQuantity()),
+		})
+	}
+	return stockItems
+}
+
+func (s *grpcServer) ReserveStock(ctx context.Context, r *pb.StockRequest) (*emptypb.Empty, error) {
+	err := s.service.ReserveStock(ctx, stockItemsFromProto(r.GetItems()))
+	switch {
+	case errors.Is(err, ErrInsufficientStock):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrInvalidQuantity):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		log.Println(err)
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *grpcServer) ReleaseStock(ctx context.Context, r *pb.StockRequest) (*emptypb.Empty, error) {
+	err := s.service.ReleaseStock(ctx, stockItemsFromProto(r.GetItems()))
+	if errors.Is(err, ErrInvalidQuantity) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
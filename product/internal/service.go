@@ -8,17 +8,28 @@ import (
 	"github.com/IBM/sarama"
 
 	"github.com/rasadov/EcommerceAPI/pkg/kafka"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/pkg/tax"
 	"github.com/rasadov/EcommerceAPI/product/models"
 )
 
+var (
+	ErrInvalidStock    = errors.New("stock cannot be negative")
+	ErrInvalidQuantity = errors.New("quantity must be positive")
+	ErrInvalidWeight   = errors.New("weight cannot be negative")
+)
+
 type Service interface {
-	PostProduct(ctx context.Context, name, description string, price float64, accountId int) (*models.Product, error)
+	PostProduct(ctx context.Context, name, description, category, taxCategory string, price money.Money, stock *int, weightGrams int, accountId int) (*models.Product, error)
 	GetProduct(ctx context.Context, id string) (*models.Product, error)
 	GetProducts(ctx context.Context, skip, take uint64) ([]*models.Product, error)
 	GetProductsWithIDs(ctx context.Context, ids []string) ([]*models.Product, error)
 	SearchProducts(ctx context.Context, query string, skip, take uint64) ([]*models.Product, error)
-	UpdateProduct(ctx context.Context, id, name, description string, price float64, accountId int) (*models.Product, error)
+	GetProductsBySeller(ctx context.Context, accountId int, skip, take uint64) ([]*models.Product, error)
+	UpdateProduct(ctx context.Context, id, name, description, category, taxCategory string, price money.Money, stock *int, weightGrams int, accountId int) (*models.Product, error)
 	DeleteProduct(ctx context.Context, productId string, accountId int) error
+	ReserveStock(ctx context.Context, items []models.StockItem) error
+	ReleaseStock(ctx context.Context, items []models.StockItem) error
 	GetProducer() sarama.AsyncProducer
 }
 
@@ -35,28 +46,54 @@ func (service productService) GetProducer() sarama.AsyncProducer {
 	return service.producer
 }
 
-func (service productService) PostProduct(ctx context.Context, name, description string, price float64, accountId int) (*models.Product, error) {
+func (service productService) PostProduct(ctx context.Context, name, description, category, taxCategory string, price money.Money, stock *int, weightGrams int, accountId int) (*models.Product, error) {
+	currency, err := money.Validate(price.Currency)
+	if err != nil {
+		return nil, err
+	}
+	price.Currency = currency
+	if stock != nil && *stock < 0 {
+		return nil, ErrInvalidStock
+	}
+	parsedTaxCategory, err := tax.ParseCategory(taxCategory)
+	if err != nil {
+		return nil, err
+	}
+	if weightGrams < 0 {
+		return nil, ErrInvalidWeight
+	}
+
 	product := models.Product{
 		Name:        name,
 		Description: description,
 		Price:       price,
 		AccountID:   accountId,
+		Stock:       stock,
+		Category:    category,
+		TaxCategory: string(parsedTaxCategory),
+		WeightGrams: weightGrams,
 	}
 
-	err := service.repo.PutProduct(ctx, &product)
+	err = service.repo.PutProduct(ctx, &product)
 	if err != nil {
 		return nil, err
 	}
 
 	go func() {
+		price := product.Price.Major()
 		err = kafka.SendMessageToRecommender(service, models.Event{
 			Type: "product_created",
 			Data: models.EventData{
 				ID:          &product.ID,
 				Name:        &product.Name,
 				Description: &product.Description,
-				Price:       &product.Price,
+				Price:       &price,
+				Amount:      &product.Price.Amount,
+				Currency:    &product.Price.Currency,
 				AccountID:   &product.AccountID,
+				Stock:       product.Stock,
+				Category:    &product.Category,
+				TaxCategory: &product.TaxCategory,
 			},
 		}, "product_events")
 		if err != nil {
@@ -101,7 +138,15 @@ func (service productService) SearchProducts(ctx context.Context, query string,
 	return service.repo.SearchProducts(ctx, query, skip, take)
 }
 
-func (service productService) UpdateProduct(ctx context.Context, id, name, description string, price float64, accountId int) (*models.Product, error) {
+func (service productService) GetProductsBySeller(ctx context.Context, accountId int, skip, take uint64) ([]*models.Product, error) {
+	if take > 100 || (skip == 0 && take == 0) {
+		take = 100
+	}
+	return service.repo.ListProductsBySeller(ctx, accountId, skip, take)
+}
+
+// UpdateProduct replaces the product's details. A nil stock keeps the current stock level.
+func (service productService) UpdateProduct(ctx context.Context, id, name, description, category, taxCategory string, price money.Money, stock *int, weightGrams int, accountId int) (*models.Product, error) {
 	product, err := service.repo.GetProductById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -109,6 +154,23 @@ func (service productService) UpdateProduct(ctx context.Context, id, name, descr
 	if product.AccountID != accountId {
 		return nil, errors.New("unauthorized")
 	}
+	currency, err := money.Validate(price.Currency)
+	if err != nil {
+		return nil, err
+	}
+	price.Currency = currency
+	if stock == nil {
+		stock = product.Stock
+	} else if *stock < 0 {
+		return nil, ErrInvalidStock
+	}
+	parsedTaxCategory, err := tax.ParseCategory(taxCategory)
+	if err != nil {
+		return nil, err
+	}
+	if weightGrams < 0 {
+		return nil, ErrInvalidWeight
+	}
 
 	updatedProduct := &models.Product{
 		ID:          id,
@@ -116,6 +178,10 @@ func (service productService) UpdateProduct(ctx context.Context, id, name, descr
 		Description: description,
 		Price:       price,
 		AccountID:   accountId,
+		Stock:       stock,
+		Category:    category,
+		TaxCategory: string(parsedTaxCategory),
+		WeightGrams: weightGrams,
 	}
 	err = service.repo.UpdateProduct(ctx, updatedProduct)
 	if err != nil {
@@ -123,14 +189,20 @@ func (service productService) UpdateProduct(ctx context.Context, id, name, descr
 	}
 
 	go func() {
+		price := updatedProduct.Price.Major()
 		err = kafka.SendMessageToRecommender(service, models.Event{
 			Type: "product_updated",
 			Data: models.EventData{
 				ID:          &updatedProduct.ID,
 				Name:        &updatedProduct.Name,
 				Description: &updatedProduct.Description,
-				Price:       &updatedProduct.Price,
+				Price:       &price,
+				Amount:      &updatedProduct.Price.Amount,
+				Currency:    &updatedProduct.Price.Currency,
 				AccountID:   &updatedProduct.AccountID,
+				Stock:       updatedProduct.Stock,
+				Category:    &updatedProduct.Category,
+				TaxCategory: &updatedProduct.TaxCategory,
 			},
 		}, "product_events")
 		if err != nil {
@@ -163,3 +235,76 @@ func (service productService) DeleteProduct(ctx context.Context, productId strin
 
 	return service.repo.DeleteProduct(ctx, productId)
 }
+
+// ReserveStock takes every item out of stock or, if any of them is unavailable, none of them.
+// Products that do not track stock are always available.
+func (service productService) ReserveStock(ctx context.Context, items []models.StockItem) error {
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return ErrInvalidQuantity
+		}
+	}
+
+	for i, item := range items {
+		product, err := service.repo.AdjustStock(ctx, item.ProductID, -item.Quantity)
+		if err != nil {
+			if releaseErr := service.ReleaseStock(ctx, items[:i]); releaseErr != nil {
+				log.Println("Failed to release partially reserved stock:", releaseErr)
+			}
+			return err
+		}
+		service.sendStockChanged(product)
+	}
+	return nil
+}
+
+// ReleaseStock puts items back into stock. Products deleted since they were reserved are skipped.
+func (service productService) ReleaseStock(ctx context.Context, items []models.StockItem) error {
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return ErrInvalidQuantity
+		}
+	}
+
+	var firstErr error
+	for _, item := range items {
+		product, err := service.repo.AdjustStock(ctx, item.ProductID, item.Quantity)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to release %d of product %s: %v", item.Quantity, item.ProductID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		service.sendStockChanged(product)
+	}
+	return firstErr
+}
+
+func (service productService) sendStockChanged(product *models.Product) {
+	if product.Stock == nil {
+		return
+	}
+
+	go func() {
+		price := product.Price.Major()
+		err := kafka.SendMessageToRecommender(service, models.Event{
+			Type: "product_stock_changed",
+			Data: models.EventData{
+				ID:        &product.ID,
+				Name:      &product.Name,
+				Price:     &price,
+				Amount:    &product.Price.Amount,
+				Currency:  &product.Price.Currency,
+				AccountID: &product.AccountID,
+				Stock:     product.Stock,
+			},
+		}, "product_events")
+		if err != nil {
+			log.Println("Failed to send event to recommendation service:", err)
+		}
+	}()
+}
@@ -5,7 +5,12 @@ type EventData struct {
 	Name        *string  `json:"name"`
 	Description *string  `json:"description"`
 	Price       *float64 `json:"price"`
+	Amount      *int64   `json:"amount"`
+	Currency    *string  `json:"currency"`
 	AccountID   *int     `json:"accountID"`
+	Stock       *int     `json:"stock"`
+	Category    *string  `json:"category,omitempty"`
+	TaxCategory *string  `json:"tax_category,omitempty"`
 }
 
 type Event struct {
@@ -1,15 +1,68 @@
 package models
 
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
 type Product struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	AccountID   int     `json:"accountID"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`
+	AccountID   int         `json:"accountID"`
+	// Stock is nil when the seller does not track inventory for the product
+	Stock       *int   `json:"stock"`
+	Category    string `json:"category"`
+	TaxCategory string `json:"taxCategory"`
+	// WeightGrams is the shipping weight of one unit; 0 when unknown
+	WeightGrams int `json:"weightGrams"`
+}
+
+// InStock reports whether the product can currently be bought.
+func (p Product) InStock() bool {
+	return p.Stock == nil || *p.Stock > 0
 }
 
 type ProductDocument struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`
+	AccountID   int         `json:"accountId"`
+	Stock       *int        `json:"stock,omitempty"`
+	Category    string      `json:"category,omitempty"`
+	TaxCategory string      `json:"taxCategory,omitempty"`
+	WeightGrams int         `json:"weightGrams,omitempty"`
+}
+
+// UnmarshalJSON also accepts documents indexed before prices carried a currency, whose price
+// is a plain number in the default currency.
+func (d *ProductDocument) UnmarshalJSON(data []byte) error {
+	type document ProductDocument
+	aux := struct {
+		*document
+		Price json.RawMessage `json:"price"`
+	}{document: (*document)(d)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if len(aux.Price) == 0 || bytes.Equal(aux.Price, []byte("null")) {
+		d.Price = money.Money{}
+		return nil
+	}
+	var legacyPrice float64
+	if err := json.Unmarshal(aux.Price, &legacyPrice); err == nil {
+		d.Price = money.FromMajor(legacyPrice, money.DefaultCurrency)
+		return nil
+	}
+	return json.Unmarshal(aux.Price, &d.Price)
+}
+
+// StockItem is a quantity of a product taken out of or put back into stock.
+type StockItem struct {
+	ProductID string
+	Quantity  int
 }
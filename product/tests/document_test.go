@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/product/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductDocument_UnmarshalJSON(t *testing.T) {
+	t.Run("Money price", func(t *testing.T) {
+		var document models.ProductDocument
+		err := json.Unmarshal([]byte(`{"name":"Lamp","description":"Desk lamp","price":{"amount":1299,"currency":"EUR"},"accountId":7}`), &document)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.ProductDocument{
+			Name:        "Lamp",
+			Description: "Desk lamp",
+			Price:       money.New(1299, "EUR"),
+			AccountID:   7,
+		}, document)
+	})
+
+	t.Run("Legacy number price is in the default currency", func(t *testing.T) {
+		var document models.ProductDocument
+		err := json.Unmarshal([]byte(`{"name":"Lamp","description":"Desk lamp","price":12.99}`), &document)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Lamp", document.Name)
+		assert.Equal(t, money.New(1299, money.DefaultCurrency), document.Price)
+		assert.Zero(t, document.AccountID)
+	})
+
+	t.Run("Round trip", func(t *testing.T) {
+		document := models.ProductDocument{Name: "Lamp", Price: money.New(500, "JPY"), AccountID: 3}
+		data, err := json.Marshal(document)
+		require.NoError(t, err)
+
+		var decoded models.ProductDocument
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, document, decoded)
+	})
+
+	t.Run("Invalid price", func(t *testing.T) {
+		var document models.ProductDocument
+		err := json.Unmarshal([]byte(`{"name":"Lamp","price":"cheap"}`), &document)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/product/internal"
+	"github.com/rasadov/EcommerceAPI/product/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockRepository implements the Repository interface for testing
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Close() {
+
+}
+
+func (m *MockRepository) PutProduct(ctx context.Context, p *models.Product) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetProductById(ctx context.Context, id string) (*models.Product, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockRepository) ListProducts(ctx context.Context, skip, take uint64) ([]*models.Product, error) {
+	args := m.Called(ctx, skip, take)
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
+func (m *MockRepository) ListProductsWithIDs(ctx context.Context, ids []string) ([]*models.Product, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
+func (m *MockRepository) SearchProducts(ctx context.Context, query string, skip, take uint64) ([]*models.Product, error) {
+	args := m.Called(ctx, query, skip, take)
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
+func (m *MockRepository) ListProductsBySeller(ctx context.Context, accountId int, skip, take uint64) ([]*models.Product, error) {
+	args := m.Called(ctx, accountId, skip, take)
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
+func (m *MockRepository) UpdateProduct(ctx context.Context, updatedProduct *models.Product) error {
+	args := m.Called(ctx, updatedProduct)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteProduct(ctx context.Context, productId string) error {
+	args := m.Called(ctx, productId)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AdjustStock(ctx context.Context, productId string, delta int) (*models.Product, error) {
+	args := m.Called(ctx, productId, delta)
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockRepository) BackfillSellers(ctx context.Context, sellers map[string]int) (int, error) {
+	args := m.Called(ctx, sellers)
+	return args.Int(0), args.Error(1)
+}
+
+func TestProductService_GetProductsBySeller(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	service := internal.NewProductService(mockRepo, nil)
+	products := []*models.Product{{ID: "1", Name: "Lamp", Price: money.New(1000, "USD"), AccountID: 7}}
+
+	t.Run("Take is capped at 100", func(t *testing.T) {
+		mockRepo.On("ListProductsBySeller", ctx, 7, uint64(0), uint64(100)).Return(products, nil).Once()
+
+		result, err := service.GetProductsBySeller(ctx, 7, 0, 500)
+
+		assert.NoError(t, err)
+		assert.Equal(t, products, result)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("No pagination takes 100", func(t *testing.T) {
+		mockRepo.On("ListProductsBySeller", ctx, 7, uint64(0), uint64(100)).Return(products, nil).Once()
+
+		result, err := service.GetProductsBySeller(ctx, 7, 0, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, products, result)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Pagination within the cap is kept", func(t *testing.T) {
+		mockRepo.On("ListProductsBySeller", ctx, 7, uint64(20), uint64(10)).Return([]*models.Product{}, nil).Once()
+
+		result, err := service.GetProductsBySeller(ctx, 7, 20, 10)
+
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestReadSellers(t *testing.T) {
+	t.Run("Reads rows and skips the header", func(t *testing.T) {
+		sellers, err := internal.ReadSellers(strings.NewReader("id,account_id\nabc, 7\ndef,12\n"))
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"abc": 7, "def": 12}, sellers)
+	})
+
+	t.Run("Reads rows without a header", func(t *testing.T) {
+		sellers, err := internal.ReadSellers(strings.NewReader("abc,7\n"))
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"abc": 7}, sellers)
+	})
+
+	t.Run("Rejects an invalid account id", func(t *testing.T) {
+		_, err := internal.ReadSellers(strings.NewReader("abc,7\ndef,seven\n"))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects a missing account id", func(t *testing.T) {
+		_, err := internal.ReadSellers(strings.NewReader("abc,0\n"))
+
+		assert.Error(t, err)
+	})
+}
@@ -3,6 +3,12 @@ import pandas as pd
 from app.db.session import ReplicaSession
 from app.db.models import Interaction, Product
 
+# How strongly each interaction type signals interest, on the model's 1-3 rating scale.
+INTERACTION_RATINGS = {
+    "purchase": 3.0,
+    "wishlist_added": 2.0,
+}
+
 def fetch_interactions() -> pd.DataFrame:
     with ReplicaSession() as session:
         interactions = session.query(Interaction).all()
@@ -10,7 +16,7 @@ def fetch_interactions() -> pd.DataFrame:
             {
                 "user_id": i.user_id,
                 "product_id": i.product_id,
-                "rating": 3.0 if i.interaction_type == "purchase" else 1.0
+                "rating": INTERACTION_RATINGS.get(i.interaction_type, 1.0)
             }
             for i in interactions
         ]
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/shipping/models"
+	"github.com/rasadov/EcommerceAPI/shipping/proto/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type Client struct {
+	conn    *grpc.ClientConn
+	service pb.ShippingServiceClient
+}
+
+func NewClient(url string) (*Client, error) {
+	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	c := pb.NewShippingServiceClient(conn)
+	return &Client{conn, c}, nil
+}
+
+func (client *Client) Close() {
+	err := client.conn.Close()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// QuoteRates prices shipping the products, keyed by ID with their quantities, to country
+// with every method that can deliver them.
+func (client *Client) QuoteRates(ctx context.Context, country, currency string, quantities map[string]int) ([]*models.Quote, error) {
+	r, err := client.service.QuoteShipping(ctx, &pb.QuoteShippingRequest{
+		Country:  country,
+		Currency: currency,
+		Items:    encodeItems(quantities),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := make([]*models.Quote, 0, len(r.Quotes))
+	for _, quote := range r.Quotes {
+		quotes = append(quotes, decodeQuote(quote))
+	}
+	return quotes, nil
+}
+
+// QuoteMethod prices shipping the products to country with one method.
+func (client *Client) QuoteMethod(ctx context.Context, method, country, currency string, quantities map[string]int) (*models.Quote, error) {
+	r, err := client.service.QuoteShippingMethod(ctx, &pb.QuoteShippingMethodRequest{
+		Method:   method,
+		Country:  country,
+		Currency: currency,
+		Items:    encodeItems(quantities),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeQuote(r.Quote), nil
+}
+
+// CreateShipment records a parcel sent for an order. Without lines every unit not shipped
+// yet goes in the parcel.
+func (client *Client) CreateShipment(ctx context.Context, orderId uint64, carrier, trackingNumber string, lines []models.ShipmentLine) (*models.Shipment, error) {
+	request := &pb.CreateShipmentRequest{
+		OrderId:        orderId,
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+	}
+	for _, line := range lines {
+		request.Lines = append(request.Lines, &pb.ShipmentLine{ProductId: line.ProductID, Quantity: uint32(line.Quantity)})
+	}
+
+	r, err := client.service.CreateShipment(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return decodeShipment(r.Shipment)
+}
+
+func (client *Client) MarkDelivered(ctx context.Context, shipmentId uint64) (*models.Shipment, error) {
+	r, err := client.service.MarkShipmentDelivered(ctx, &pb.MarkShipmentDeliveredRequest{ShipmentId: shipmentId})
+	if err != nil {
+		return nil, err
+	}
+	return decodeShipment(r.Shipment)
+}
+
+func (client *Client) GetShipments(ctx context.Context, orderId uint64) ([]*models.Shipment, error) {
+	r, err := client.service.GetShipments(ctx, &pb.GetShipmentsRequest{OrderId: orderId})
+	if err != nil {
+		return nil, err
+	}
+
+	shipments := make([]*models.Shipment, 0, len(r.Shipments))
+	for _, s := range r.Shipments {
+		shipment, err := decodeShipment(s)
+		if err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, shipment)
+	}
+	return shipments, nil
+}
+
+func encodeItems(quantities map[string]int) []*pb.ShippingItem {
+	items := make([]*pb.ShippingItem, 0, len(quantities))
+	for productId, quantity := range quantities {
+		items = append(items, &pb.ShippingItem{ProductId: productId, Quantity: uint32(quantity)})
+	}
+	return items
+}
+
+func decodeQuote(quote *pb.ShippingQuote) *models.Quote {
+	return &models.Quote{
+		Method:       quote.Method,
+		Name:         quote.Name,
+		Carrier:      quote.Carrier,
+		Price:        money.FromProto(quote.GetPrice()),
+		DeliveryDays: int(quote.DeliveryDays),
+	}
+}
+
+func decodeShipment(encoded *pb.Shipment) (*models.Shipment, error) {
+	shipment := &models.Shipment{
+		ID:             uint(encoded.Id),
+		OrderID:        encoded.OrderId,
+		Carrier:        encoded.Carrier,
+		TrackingNumber: encoded.TrackingNumber,
+		Status:         models.ShipmentStatus(encoded.Status),
+	}
+	for _, line := range encoded.Lines {
+		shipment.Lines = append(shipment.Lines, models.ShipmentLine{ProductID: line.ProductId, Quantity: int(line.Quantity)})
+	}
+	err := shipment.ShippedAt.UnmarshalBinary(encoded.ShippedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded.DeliveredAt) > 0 {
+		var deliveredAt time.Time
+		if err := deliveredAt.UnmarshalBinary(encoded.DeliveredAt); err != nil {
+			return nil, err
+		}
+		shipment.DeliveredAt = &deliveredAt
+	}
+	return shipment, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/shipping/config"
+	"github.com/rasadov/EcommerceAPI/shipping/internal"
+	"github.com/rasadov/EcommerceAPI/shipping/models"
+	"github.com/tinrab/retry"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	var repository internal.Repository
+
+	producer, err := sarama.NewAsyncProducer([]string{config.BootstrapServers}, nil)
+	if err != nil {
+		log.Println(err)
+	}
+	defer func(producer sarama.AsyncProducer) {
+		err := producer.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(producer)
+
+	// Without a methods file there is nothing to quote, but shipments can still be recorded
+	var methods []*models.Method
+	if config.MethodsFile != "" {
+		methods, err = models.LoadMethods(config.MethodsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
+		db, err := gorm.Open(postgres.Open(config.DatabaseUrl), &gorm.Config{})
+		if err != nil {
+			log.Println(err)
+		}
+		repository, err = internal.NewPostgresRepository(db)
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	})
+	defer repository.Close()
+	log.Println("Listening on port 8080...")
+	converter := money.NewConverter(money.NewRateSource(config.CurrencyRatesURL, config.CurrencyRatesFile))
+	converter.StartRefresh(context.Background(), time.Hour)
+
+	service := internal.NewShippingService(repository, producer, converter, methods)
+	log.Fatal(internal.ListenGRPC(service, config.OrderUrl, config.ProductUrl, 8080))
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"os"
+)
+
+var (
+	DatabaseUrl       string
+	OrderUrl          string
+	ProductUrl        string
+	BootstrapServers  string
+	CurrencyRatesURL  string
+	CurrencyRatesFile string
+	// MethodsFile is the JSON file listing the shipping methods and their rate tables
+	MethodsFile string
+)
+
+func init() {
+	DatabaseUrl = os.Getenv("DATABASE_URL")
+	OrderUrl = os.Getenv("ORDER_SERVICE_URL")
+	ProductUrl = os.Getenv("PRODUCT_SERVICE_URL")
+	BootstrapServers = os.Getenv("KAFKA_BOOTSTRAP_SERVERS")
+	CurrencyRatesURL = os.Getenv("CURRENCY_RATES_URL")
+	CurrencyRatesFile = os.Getenv("CURRENCY_RATES_FILE")
+	MethodsFile = os.Getenv("SHIPPING_METHODS_FILE")
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/shipping/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrShipmentNotFound = errors.New("shipment not found")
+)
+
+type Repository interface {
+	Close()
+	CreateShipment(ctx context.Context, shipment *models.Shipment) error
+	GetShipment(ctx context.Context, id uint) (*models.Shipment, error)
+	ListShipmentsForOrder(ctx context.Context, orderId uint64) ([]*models.Shipment, error)
+	MarkDelivered(ctx context.Context, id uint, deliveredAt time.Time) error
+}
+
+type postgresRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresRepository(db *gorm.DB) (Repository, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	err = sqlDB.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&models.Shipment{}, &models.ShipmentLine{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresRepository{db}, nil
+}
+
+func (repository *postgresRepository) Close() {
+	sqlDB, err := repository.db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+		if err != nil {
+			log.Println("Error closing postgres repository")
+			log.Println(err)
+		}
+	}
+}
+
+// CreateShipment stores the shipment together with its lines.
+func (repository *postgresRepository) CreateShipment(ctx context.Context, shipment *models.Shipment) error {
+	return repository.db.WithContext(ctx).Create(shipment).Error
+}
+
+func (repository *postgresRepository) GetShipment(ctx context.Context, id uint) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := repository.withLines(ctx).First(&shipment, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrShipmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// ListShipmentsForOrder returns the order's shipments, oldest first.
+func (repository *postgresRepository) ListShipmentsForOrder(ctx context.Context, orderId uint64) ([]*models.Shipment, error) {
+	var shipments []*models.Shipment
+	err := repository.withLines(ctx).
+		Where("order_id = ?", orderId).
+		Order("shipped_at, id").
+		Find(&shipments).Error
+	if err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+func (repository *postgresRepository) MarkDelivered(ctx context.Context, id uint, deliveredAt time.Time) error {
+	return repository.db.WithContext(ctx).
+		Model(&models.Shipment{ID: id}).
+		Updates(map[string]any{"status": models.ShipmentDelivered, "delivered_at": deliveredAt}).Error
+}
+
+// withLines preloads the lines of the shipments queried.
+func (repository *postgresRepository) withLines(ctx context.Context) *gorm.DB {
+	return repository.db.WithContext(ctx).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		})
+}
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	order "github.com/rasadov/EcommerceAPI/order/client"
+	ordermodels "github.com/rasadov/EcommerceAPI/order/models"
+	product "github.com/rasadov/EcommerceAPI/product/client"
+	"github.com/rasadov/EcommerceAPI/shipping/models"
+	"github.com/rasadov/EcommerceAPI/shipping/proto/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+type grpcServer struct {
+	pb.UnimplementedShippingServiceServer
+	service       Service
+	orderClient   *order.Client
+	productClient *product.Client
+}
+
+func ListenGRPC(service Service, orderURL, productURL string, port int) error {
+	orderClient, err := order.NewClient(orderURL)
+	if err != nil {
+		return err
+	}
+
+	productClient, err := product.NewClient(productURL)
+	if err != nil {
+		orderClient.Close()
+		return err
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		orderClient.Close()
+		productClient.Close()
+		return err
+	}
+
+	serv := grpc.NewServer()
+	pb.RegisterShippingServiceServer(serv, &grpcServer{
+		pb.UnimplementedShippingServiceServer{},
+		service,
+		orderClient,
+		productClient,
+	})
+	reflection.Register(serv)
+
+	return serv.Serve(lis)
+}
+
+func (server *grpcServer) QuoteShipping(ctx context.Context, request *pb.QuoteShippingRequest) (*pb.QuoteShippingResponse, error) {
+	items, err := server.shippingItems(ctx, request.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes, err := server.service.QuoteRates(ctx, request.Country, request.Currency, items)
+	if err != nil {
+		log.Println("Error quoting shipping", err)
+		return nil, err
+	}
+
+	response := &pb.QuoteShippingResponse{}
+	for _, quote := range quotes {
+		response.Quotes = append(response.Quotes, encodeQuote(quote))
+	}
+	return response, nil
+}
+
+func (server *grpcServer) QuoteShippingMethod(ctx context.Context, request *pb.QuoteShippingMethodRequest) (*pb.QuoteShippingMethodResponse, error) {
+	items, err := server.shippingItems(ctx, request.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := server.service.QuoteMethod(ctx, request.Method, request.Country, request.Currency, items)
+	if err != nil {
+		log.Println("Error quoting shipping method", err)
+		return nil, err
+	}
+	return &pb.QuoteShippingMethodResponse{Quote: encodeQuote(quote)}, nil
+}
+
+// shippingItems looks up the weight and price of the requested products. Products that no
+// longer exist are left out.
+func (server *grpcServer) shippingItems(ctx context.Context, requested []*pb.ShippingItem) ([]models.Item, error) {
+	quantities := make(map[string]int, len(requested))
+	var productIds []string
+	for _, item := range requested {
+		if _, ok := quantities[item.ProductId]; !ok {
+			productIds = append(productIds, item.ProductId)
+		}
+		quantities[item.ProductId] += int(item.Quantity)
+	}
+	if len(productIds) == 0 {
+		return nil, nil
+	}
+
+	products, err := server.productClient.GetProducts(ctx, 0, 0, productIds, "")
+	if err != nil {
+		log.Println("Error getting shipped products", err)
+		return nil, err
+	}
+
+	items := make([]models.Item, 0, len(products))
+	for _, p := range products {
+		items = append(items, models.Item{
+			ProductID:   p.ID,
+			Quantity:    quantities[p.ID],
+			WeightGrams: p.WeightGrams,
+			UnitPrice:   p.Price,
+		})
+	}
+	return items, nil
+}
+
+func (server *grpcServer) CreateShipment(ctx context.Context, request *pb.CreateShipmentRequest) (*pb.ShipmentResponse, error) {
+	orderState, err := server.orderState(ctx, request.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []models.ShipmentLine
+	for _, line := range request.Lines {
+		lines = append(lines, models.ShipmentLine{ProductID: line.ProductId, Quantity: int(line.Quantity)})
+	}
+
+	shipment, err := server.service.CreateShipment(ctx, orderState, request.Carrier, request.TrackingNumber, lines)
+	if err != nil {
+		log.Println("Error creating shipment", err)
+		return nil, err
+	}
+	return &pb.ShipmentResponse{Shipment: encodeShipment(shipment)}, nil
+}
+
+func (server *grpcServer) MarkShipmentDelivered(ctx context.Context, request *pb.MarkShipmentDeliveredRequest) (*pb.ShipmentResponse, error) {
+	shipment, err := server.service.GetShipment(ctx, uint(request.ShipmentId))
+	if err != nil {
+		return nil, err
+	}
+	orderState, err := server.orderState(ctx, shipment.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	shipment, err = server.service.MarkDelivered(ctx, shipment.ID, orderState)
+	if err != nil {
+		log.Println("Error marking shipment delivered", err)
+		return nil, err
+	}
+	return &pb.ShipmentResponse{Shipment: encodeShipment(shipment)}, nil
+}
+
+func (server *grpcServer) GetShipments(ctx context.Context, request *pb.GetShipmentsRequest) (*pb.GetShipmentsResponse, error) {
+	shipments, err := server.service.GetShipmentsForOrder(ctx, request.OrderId)
+	if err != nil {
+		log.Println("Error getting shipments", err)
+		return nil, err
+	}
+
+	response := &pb.GetShipmentsResponse{}
+	for _, shipment := range shipments {
+		response.Shipments = append(response.Shipments, encodeShipment(shipment))
+	}
+	return response, nil
+}
+
+// orderState fetches what shipping needs to know about an order from the order service.
+func (server *grpcServer) orderState(ctx context.Context, orderId uint64) (models.OrderState, error) {
+	o, err := server.orderClient.GetOrder(ctx, orderId, 0)
+	if err != nil {
+		log.Println("Error getting shipped order", err)
+		return models.OrderState{}, err
+	}
+	return orderState(o), nil
+}
+
+func orderState(o *ordermodels.Order) models.OrderState {
+	state := models.OrderState{
+		ID:         uint64(o.ID),
+		AccountID:  o.AccountID,
+		Status:     o.Status,
+		Method:     o.ShippingMethod,
+		Quantities: make(map[string]int, len(o.Products)),
+	}
+	for _, p := range o.Products {
+		state.Quantities[p.ID] += int(p.Quantity)
+	}
+	return state
+}
+
+func encodeQuote(quote *models.Quote) *pb.ShippingQuote {
+	return &pb.ShippingQuote{
+		Method:       quote.Method,
+		Name:         quote.Name,
+		Carrier:      quote.Carrier,
+		Price:        quote.Price.ToProto(),
+		DeliveryDays: uint32(quote.DeliveryDays),
+	}
+}
+
+func encodeShipment(shipment *models.Shipment) *pb.Shipment {
+	encoded := &pb.Shipment{
+		Id:             uint64(shipment.ID),
+		OrderId:        shipment.OrderID,
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		Status:         string(shipment.Status),
+	}
+	for _, line := range shipment.Lines {
+		encoded.Lines = append(encoded.Lines, &pb.ShipmentLine{ProductId: line.ProductID, Quantity: uint32(line.Quantity)})
+	}
+	encoded.ShippedAt, _ = shipment.ShippedAt.MarshalBinary()
+	if shipment.DeliveredAt != nil {
+		encoded.DeliveredAt, _ = shipment.DeliveredAt.MarshalBinary()
+	}
+	return encoded
+}
@@ -0,0 +1,312 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	ordermodels "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/kafka"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/shipping/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrMissingCountry     = status.Error(codes.InvalidArgument, "a delivery country is required")
+	ErrMethodNotFound     = status.Error(codes.NotFound, "shipping method not found")
+	ErrMethodUnavailable  = status.Error(codes.FailedPrecondition, "the shipping method does not deliver this order")
+	ErrMissingTracking    = status.Error(codes.InvalidArgument, "a shipment needs a carrier and a tracking number")
+	ErrInvalidLine        = status.Error(codes.InvalidArgument, "shipment lines must name ordered products with a positive quantity")
+	ErrOverShipped        = status.Error(codes.FailedPrecondition, "shipment lines exceed the units left to ship")
+	ErrNothingToShip      = status.Error(codes.FailedPrecondition, "every ordered unit has already been shipped")
+	ErrOrderNotShippable  = status.Error(codes.FailedPrecondition, "only paid orders can be shipped")
+	ErrOtherOrderShipment = status.Error(codes.InvalidArgument, "the shipment belongs to another order")
+)
+
+type Service interface {
+	QuoteRates(ctx context.Context, country, currency string, items []models.Item) ([]*models.Quote, error)
+	QuoteMethod(ctx context.Context, code, country, currency string, items []models.Item) (*models.Quote, error)
+	CreateShipment(ctx context.Context, order models.OrderState, carrier, trackingNumber string, lines []models.ShipmentLine) (*models.Shipment, error)
+	GetShipment(ctx context.Context, id uint) (*models.Shipment, error)
+	GetShipmentsForOrder(ctx context.Context, orderId uint64) ([]*models.Shipment, error)
+	MarkDelivered(ctx context.Context, id uint, order models.OrderState) (*models.Shipment, error)
+	GetProducer() sarama.AsyncProducer
+}
+
+type shippingService struct {
+	repository Repository
+	producer   sarama.AsyncProducer
+	converter  *money.Converter
+	methods    []*models.Method
+}
+
+// NewShippingService returns a shipping service offering methods. The converter prices
+// quotes in the requested currency.
+func NewShippingService(repository Repository, producer sarama.AsyncProducer, converter *money.Converter, methods []*models.Method) Service {
+	return &shippingService{repository, producer, converter, methods}
+}
+
+func (service shippingService) GetProducer() sarama.AsyncProducer {
+	return service.producer
+}
+
+// QuoteRates prices shipping items to country with every method that delivers there and
+// has a rate covering them.
+func (service shippingService) QuoteRates(ctx context.Context, country, currency string, items []models.Item) ([]*models.Quote, error) {
+	if strings.TrimSpace(country) == "" {
+		return nil, ErrMissingCountry
+	}
+
+	quotes := []*models.Quote{}
+	for _, method := range service.methods {
+		if !method.DeliversTo(country) {
+			continue
+		}
+		quote, err := service.quote(method, currency, items)
+		if errors.Is(err, models.ErrNoRate) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, quote)
+	}
+	return quotes, nil
+}
+
+// QuoteMethod prices shipping items to country with the method named by code.
+func (service shippingService) QuoteMethod(ctx context.Context, code, country, currency string, items []models.Item) (*models.Quote, error) {
+	if strings.TrimSpace(country) == "" {
+		return nil, ErrMissingCountry
+	}
+	method := service.method(code)
+	if method == nil {
+		return nil, ErrMethodNotFound
+	}
+	if !method.DeliversTo(country) {
+		return nil, ErrMethodUnavailable
+	}
+
+	quote, err := service.quote(method, currency, items)
+	if errors.Is(err, models.ErrNoRate) {
+		return nil, ErrMethodUnavailable
+	}
+	return quote, err
+}
+
+// quote prices items with method in currency.
+func (service shippingService) quote(method *models.Method, currency string, items []models.Item) (*models.Quote, error) {
+	var weight int64
+	subtotal := money.Zero(method.Currency)
+	for _, item := range items {
+		weight += int64(item.WeightGrams) * int64(item.Quantity)
+		if method.Basis != models.BasisPrice {
+			continue
+		}
+		price, err := service.converter.Convert(item.UnitPrice, method.Currency)
+		if err != nil {
+			return nil, err
+		}
+		subtotal, err = subtotal.Add(price.Mul(int64(item.Quantity)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rate, err := method.Rate(weight, subtotal)
+	if err != nil {
+		return nil, err
+	}
+	price, err := service.converter.Convert(rate, money.Normalize(currency))
+	if err != nil {
+		return nil, err
+	}
+	return &models.Quote{
+		Method:       method.Code,
+		Name:         method.Name,
+		Carrier:      method.Carrier,
+		Price:        price,
+		DeliveryDays: method.DeliveryDays,
+	}, nil
+}
+
+func (service shippingService) method(code string) *models.Method {
+	code = strings.ToLower(strings.TrimSpace(code))
+	for _, method := range service.methods {
+		if method.Code == code {
+			return method
+		}
+	}
+	return nil
+}
+
+// CreateShipment records a parcel sent for order and announces it with an order_shipped
+// event. Without lines every unit left to ship goes in the parcel. The carrier defaults to
+// the one of the shipping method chosen at checkout.
+func (service shippingService) CreateShipment(ctx context.Context, order models.OrderState, carrier, trackingNumber string, lines []models.ShipmentLine) (*models.Shipment, error) {
+	if order.Status != ordermodels.StatusPaid && order.Status != ordermodels.StatusFulfilling {
+		return nil, ErrOrderNotShippable
+	}
+	carrier = strings.TrimSpace(carrier)
+	if carrier == "" {
+		if method := service.method(order.Method); method != nil {
+			carrier = method.Carrier
+		}
+	}
+	trackingNumber = strings.TrimSpace(trackingNumber)
+	if carrier == "" || trackingNumber == "" {
+		return nil, ErrMissingTracking
+	}
+
+	shipments, err := service.repository.ListShipmentsForOrder(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	remaining := remainingQuantities(order, shipments)
+
+	if len(lines) == 0 {
+		for _, productId := range slices.Sorted(maps.Keys(remaining)) {
+			if remaining[productId] > 0 {
+				lines = append(lines, models.ShipmentLine{ProductID: productId, Quantity: remaining[productId]})
+			}
+		}
+		if len(lines) == 0 {
+			return nil, ErrNothingToShip
+		}
+	}
+
+	shipped := map[string]int{}
+	var shipmentLines []models.ShipmentLine
+	for _, line := range lines {
+		if _, ok := order.Quantities[line.ProductID]; !ok || line.Quantity <= 0 {
+			return nil, ErrInvalidLine
+		}
+		if shipped[line.ProductID] == 0 {
+			shipmentLines = append(shipmentLines, models.ShipmentLine{ProductID: line.ProductID})
+		}
+		shipped[line.ProductID] += line.Quantity
+		if shipped[line.ProductID] > remaining[line.ProductID] {
+			return nil, ErrOverShipped
+		}
+	}
+	for i := range shipmentLines {
+		shipmentLines[i].Quantity = shipped[shipmentLines[i].ProductID]
+	}
+
+	shipment := &models.Shipment{
+		OrderID:        order.ID,
+		AccountID:      order.AccountID,
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+		Status:         models.ShipmentShipped,
+		Lines:          shipmentLines,
+		ShippedAt:      time.Now().UTC(),
+	}
+	err = service.repository.CreateShipment(ctx, shipment)
+	if err != nil {
+		return nil, err
+	}
+
+	complete := true
+	for productId, quantity := range remaining {
+		if shipped[productId] < quantity {
+			complete = false
+		}
+	}
+	service.announce("order_shipped", shipment, complete, shipment.ShippedAt)
+	return shipment, nil
+}
+
+func (service shippingService) GetShipment(ctx context.Context, id uint) (*models.Shipment, error) {
+	shipment, err := service.repository.GetShipment(ctx, id)
+	if errors.Is(err, ErrShipmentNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return shipment, err
+}
+
+func (service shippingService) GetShipmentsForOrder(ctx context.Context, orderId uint64) ([]*models.Shipment, error) {
+	return service.repository.ListShipmentsForOrder(ctx, orderId)
+}
+
+// MarkDelivered records that a shipment of order reached the customer and announces it
+// with an order_delivered event, complete once every unit has been shipped and delivered.
+// Marking a delivered shipment again is a no-op.
+func (service shippingService) MarkDelivered(ctx context.Context, id uint, order models.OrderState) (*models.Shipment, error) {
+	shipment, err := service.GetShipment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if shipment.OrderID != order.ID {
+		return nil, ErrOtherOrderShipment
+	}
+	if shipment.Status == models.ShipmentDelivered {
+		return shipment, nil
+	}
+
+	deliveredAt := time.Now().UTC()
+	err = service.repository.MarkDelivered(ctx, shipment.ID, deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	shipment.Status = models.ShipmentDelivered
+	shipment.DeliveredAt = &deliveredAt
+
+	shipments, err := service.repository.ListShipmentsForOrder(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	complete := true
+	for _, quantity := range remainingQuantities(order, shipments) {
+		if quantity > 0 {
+			complete = false
+		}
+	}
+	for _, s := range shipments {
+		if s.Status != models.ShipmentDelivered {
+			complete = false
+		}
+	}
+	service.announce("order_delivered", shipment, complete, deliveredAt)
+	return shipment, nil
+}
+
+// remainingQuantities returns the units of each ordered product not shipped yet.
+func remainingQuantities(order models.OrderState, shipments []*models.Shipment) map[string]int {
+	remaining := make(map[string]int, len(order.Quantities))
+	for productId, quantity := range order.Quantities {
+		remaining[productId] = quantity
+	}
+	for _, shipment := range shipments {
+		for _, line := range shipment.Lines {
+			remaining[line.ProductID] -= line.Quantity
+		}
+	}
+	return remaining
+}
+
+// announce publishes a shipment event on shipping_events for the order service.
+func (service shippingService) announce(eventType string, shipment *models.Shipment, complete bool, at time.Time) {
+	err := kafka.SendMessageToRecommender(service, models.ShipmentEvent{
+		Type: eventType,
+		Data: models.ShipmentEventData{
+			OrderId:        shipment.OrderID,
+			AccountId:      shipment.AccountID,
+			ShipmentId:     shipment.ID,
+			Carrier:        shipment.Carrier,
+			TrackingNumber: shipment.TrackingNumber,
+			Complete:       complete,
+			At:             at,
+		},
+	}, "shipping_events")
+	if err != nil {
+		log.Println("Failed to send shipment event:", err)
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type ShipmentEventData struct {
+	OrderId        uint64 `json:"order_id"`
+	AccountId      uint64 `json:"user_id"`
+	ShipmentId     uint   `json:"shipment_id"`
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"tracking_number"`
+	// Complete is set once every ordered unit has been shipped, or delivered
+	Complete bool      `json:"complete"`
+	At       time.Time `json:"at"`
+}
+
+// ShipmentEvent is an order_shipped or order_delivered event.
+type ShipmentEvent struct {
+	Type string            `json:"type"`
+	Data ShipmentEventData `json:"data"`
+}
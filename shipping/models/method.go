@@ -0,0 +1,149 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+var (
+	ErrInvalidMethod = errors.New("invalid shipping method")
+	ErrNoRate        = errors.New("no shipping rate covers the order")
+)
+
+// RateBasis is what a method's rate tiers are measured in.
+type RateBasis string
+
+const (
+	// BasisWeight tiers are upper bounds on the total weight in grams
+	BasisWeight RateBasis = "weight"
+	// BasisPrice tiers are upper bounds on the order subtotal in the method currency
+	BasisPrice RateBasis = "price"
+)
+
+// RateTier is the price of shipping orders up to a weight or subtotal.
+type RateTier struct {
+	// UpTo is the largest weight or subtotal the tier covers; empty for no limit
+	UpTo  string `json:"upTo,omitempty"`
+	Price string `json:"price"`
+
+	upTo  int64
+	price money.Money
+}
+
+// Method is a way of shipping orders, e.g.
+// {"code": "standard", "name": "Standard", "carrier": "DHL", "basis": "weight", "currency": "EUR",
+// "rates": [{"upTo": "2000", "price": "4.99"}, {"price": "9.99"}]}.
+type Method struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Carrier string `json:"carrier"`
+	// Countries are the ISO 3166-1 alpha-2 codes the method delivers to; empty for everywhere
+	Countries    []string  `json:"countries,omitempty"`
+	Basis        RateBasis `json:"basis"`
+	Currency     string    `json:"currency"`
+	DeliveryDays int       `json:"deliveryDays,omitempty"`
+	// Rates are tried in order and the first tier covering the order applies
+	Rates []RateTier `json:"rates"`
+}
+
+// LoadMethods reads the shipping methods from a JSON file holding {"methods": [...]}.
+func LoadMethods(path string) ([]*Method, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeMethods(data)
+}
+
+// DecodeMethods parses and validates a JSON list of shipping methods.
+func DecodeMethods(data []byte) ([]*Method, error) {
+	var file struct {
+		Methods []*Method `json:"methods"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	codes := map[string]bool{}
+	for _, method := range file.Methods {
+		if err := method.normalize(); err != nil {
+			return nil, err
+		}
+		if codes[method.Code] {
+			return nil, fmt.Errorf("%w: duplicate code %s", ErrInvalidMethod, method.Code)
+		}
+		codes[method.Code] = true
+	}
+	return file.Methods, nil
+}
+
+func (m *Method) normalize() error {
+	m.Code = strings.ToLower(strings.TrimSpace(m.Code))
+	if m.Code == "" || m.Carrier == "" || len(m.Rates) == 0 {
+		return fmt.Errorf("%w: %q needs a code, a carrier and rates", ErrInvalidMethod, m.Code)
+	}
+	if m.Basis != BasisWeight && m.Basis != BasisPrice {
+		return fmt.Errorf("%w: %q has unknown basis %q", ErrInvalidMethod, m.Code, m.Basis)
+	}
+	currency, err := money.Validate(m.Currency)
+	if err != nil {
+		return fmt.Errorf("%w: %q: %v", ErrInvalidMethod, m.Code, err)
+	}
+	m.Currency = currency
+	for i, country := range m.Countries {
+		m.Countries[i] = strings.ToUpper(strings.TrimSpace(country))
+	}
+
+	for i := range m.Rates {
+		tier := &m.Rates[i]
+		tier.price, err = money.ParseMajor(tier.Price, m.Currency)
+		if err != nil || tier.price.IsNegative() {
+			return fmt.Errorf("%w: %q has invalid price %q", ErrInvalidMethod, m.Code, tier.Price)
+		}
+		if tier.UpTo == "" {
+			tier.upTo = -1
+			continue
+		}
+		if m.Basis == BasisWeight {
+			tier.upTo, err = strconv.ParseInt(tier.UpTo, 10, 64)
+		} else {
+			var limit money.Money
+			limit, err = money.ParseMajor(tier.UpTo, m.Currency)
+			tier.upTo = limit.Amount
+		}
+		if err != nil || tier.upTo < 0 {
+			return fmt.Errorf("%w: %q has invalid tier limit %q", ErrInvalidMethod, m.Code, tier.UpTo)
+		}
+	}
+	return nil
+}
+
+// DeliversTo reports whether the method ships to country.
+func (m *Method) DeliversTo(country string) bool {
+	return len(m.Countries) == 0 || slices.Contains(m.Countries, strings.ToUpper(strings.TrimSpace(country)))
+}
+
+// Rate returns the price of shipping weightGrams worth of products costing subtotal, which
+// must be in the method currency.
+func (m *Method) Rate(weightGrams int64, subtotal money.Money) (money.Money, error) {
+	measure := weightGrams
+	if m.Basis == BasisPrice {
+		if subtotal.Currency != m.Currency {
+			return money.Money{}, money.ErrCurrencyMismatch
+		}
+		measure = subtotal.Amount
+	}
+	for _, tier := range m.Rates {
+		if tier.upTo < 0 || measure <= tier.upTo {
+			return tier.price, nil
+		}
+	}
+	return money.Money{}, ErrNoRate
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	ordermodels "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+)
+
+type ShipmentStatus string
+
+const (
+	ShipmentShipped   ShipmentStatus = "shipped"
+	ShipmentDelivered ShipmentStatus = "delivered"
+)
+
+// Shipment is a parcel sent for an order. An order may be shipped in several parcels,
+// each carrying some of the ordered units.
+type Shipment struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	OrderID        uint64 `gorm:"index"`
+	AccountID      uint64
+	Carrier        string
+	TrackingNumber string
+	Status         ShipmentStatus `gorm:"type:varchar(20)"`
+	Lines          []ShipmentLine `gorm:"foreignKey:ShipmentID"`
+	ShippedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// ShipmentLine is a quantity of one ordered product in a shipment.
+type ShipmentLine struct {
+	ID         uint `gorm:"primaryKey;autoIncrement"`
+	ShipmentID uint `gorm:"index"`
+	ProductID  string
+	Quantity   int
+}
+
+// Item is a product to be shipped, with what the rate tables need to know about it.
+type Item struct {
+	ProductID   string
+	Quantity    int
+	WeightGrams int
+	UnitPrice   money.Money
+}
+
+// Quote is the price of shipping items with a method.
+type Quote struct {
+	Method       string
+	Name         string
+	Carrier      string
+	Price        money.Money
+	DeliveryDays int
+}
+
+// OrderState is what shipping needs to know about an order.
+type OrderState struct {
+	ID        uint64
+	AccountID uint64
+	Status    ordermodels.OrderStatus
+	// Method is the shipping method chosen at checkout, if any
+	Method string
+	// Quantities are the ordered units per product ID
+	Quantities map[string]int
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/shipping/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test helper to decode the methods the service tests ship with
+func setupMethods(t *testing.T) []*models.Method {
+	methods, err := models.DecodeMethods([]byte(`{"methods": [
+		{"code": "Standard", "name": "Standard", "carrier": "DHL", "countries": ["de", "FR"], "basis": "weight", "currency": "EUR",
+		 "deliveryDays": 5, "rates": [{"upTo": "2000", "price": "4.99"}, {"upTo": "10000", "price": "9.99"}]},
+		{"code": "express", "name": "Express", "carrier": "UPS", "basis": "price", "currency": "EUR",
+		 "deliveryDays": 1, "rates": [{"upTo": "50", "price": "14.99"}, {"price": "0"}]}
+	]}`))
+	require.NoError(t, err)
+	return methods
+}
+
+func TestMethod_Rate(t *testing.T) {
+	methods := setupMethods(t)
+	standard, express := methods[0], methods[1]
+
+	assert.Equal(t, "standard", standard.Code)
+	assert.True(t, standard.DeliversTo("fr"))
+	assert.False(t, standard.DeliversTo("US"))
+	assert.True(t, express.DeliversTo("US"))
+
+	rate, err := standard.Rate(2000, money.Money{})
+	require.NoError(t, err)
+	assert.Equal(t, money.New(499, "EUR"), rate)
+
+	rate, err = standard.Rate(2001, money.Money{})
+	require.NoError(t, err)
+	assert.Equal(t, money.New(999, "EUR"), rate)
+
+	_, err = standard.Rate(10001, money.Money{})
+	assert.ErrorIs(t, err, models.ErrNoRate)
+
+	rate, err = express.Rate(0, money.New(5000, "EUR"))
+	require.NoError(t, err)
+	assert.Equal(t, money.New(1499, "EUR"), rate)
+
+	rate, err = express.Rate(0, money.New(5001, "EUR"))
+	require.NoError(t, err)
+	assert.True(t, rate.IsZero())
+
+	_, err = express.Rate(0, money.New(5000, "USD"))
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
+
+func TestDecodeMethods(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"Unknown basis", `{"methods": [{"code": "a", "carrier": "DHL", "basis": "volume", "currency": "EUR", "rates": [{"price": "1"}]}]}`},
+		{"No rates", `{"methods": [{"code": "a", "carrier": "DHL", "basis": "weight", "currency": "EUR"}]}`},
+		{"Negative price", `{"methods": [{"code": "a", "carrier": "DHL", "basis": "weight", "currency": "EUR", "rates": [{"price": "-1"}]}]}`},
+		{"Duplicate code", `{"methods": [
+			{"code": "a", "carrier": "DHL", "basis": "weight", "currency": "EUR", "rates": [{"price": "1"}]},
+			{"code": "A", "carrier": "UPS", "basis": "weight", "currency": "EUR", "rates": [{"price": "2"}]}
+		]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := models.DecodeMethods([]byte(tt.data))
+			assert.ErrorIs(t, err, models.ErrInvalidMethod)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	ordermodels "github.com/rasadov/EcommerceAPI/order/models"
+	"github.com/rasadov/EcommerceAPI/pkg/money"
+	"github.com/rasadov/EcommerceAPI/shipping/internal"
+	"github.com/rasadov/EcommerceAPI/shipping/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Test helper to create a repository backed by an in-memory SQLite database
+func setupTestRepository(t *testing.T) internal.Repository {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	r, err := internal.NewPostgresRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+	return r
+}
+
+// Test helper to create a mock producer expecting shipment events of the given types,
+// each written as "type" or "type complete"
+func setupProducer(t *testing.T, events ...string) *mocks.AsyncProducer {
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	producer := mocks.NewAsyncProducer(t, config)
+	for _, expected := range events {
+		producer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(message *sarama.ProducerMessage) error {
+			data, err := message.Value.Encode()
+			if err != nil {
+				return err
+			}
+			var event models.ShipmentEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			got := event.Type
+			if event.Data.Complete {
+				got += " complete"
+			}
+			if got != expected {
+				return fmt.Errorf("got event %q, want %q", got, expected)
+			}
+			return nil
+		})
+	}
+	t.Cleanup(func() {
+		for range events {
+			<-producer.Successes()
+		}
+		require.NoError(t, producer.Close())
+	})
+	return producer
+}
+
+func paidOrder() models.OrderState {
+	return models.OrderState{
+		ID:         1,
+		AccountID:  2,
+		Status:     ordermodels.StatusPaid,
+		Method:     "standard",
+		Quantities: map[string]int{"book": 2, "lamp": 1},
+	}
+}
+
+func TestShippingService_QuoteRates(t *testing.T) {
+	ctx := context.Background()
+	service := internal.NewShippingService(setupTestRepository(t), setupProducer(t), nil, setupMethods(t))
+	items := []models.Item{
+		{ProductID: "book", Quantity: 2, WeightGrams: 500, UnitPrice: money.New(1999, "EUR")},
+		{ProductID: "lamp", Quantity: 1, WeightGrams: 1500, UnitPrice: money.New(2500, "EUR")},
+	}
+
+	t.Run("Every method delivering to the country", func(t *testing.T) {
+		quotes, err := service.QuoteRates(ctx, "DE", "EUR", items)
+
+		require.NoError(t, err)
+		require.Len(t, quotes, 2)
+		// 2500g is past the first weight tier
+		assert.Equal(t, "standard", quotes[0].Method)
+		assert.Equal(t, money.New(999, "EUR"), quotes[0].Price)
+		// A 64.98 subtotal ships for free
+		assert.Equal(t, "express", quotes[1].Method)
+		assert.True(t, quotes[1].Price.IsZero())
+	})
+
+	t.Run("Methods not delivering to the country are left out", func(t *testing.T) {
+		quotes, err := service.QuoteRates(ctx, "us", "EUR", items)
+
+		require.NoError(t, err)
+		require.Len(t, quotes, 1)
+		assert.Equal(t, "express", quotes[0].Method)
+	})
+
+	t.Run("Unavailable method", func(t *testing.T) {
+		_, err := service.QuoteMethod(ctx, "standard", "US", "EUR", items)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = service.QuoteMethod(ctx, "pigeon", "DE", "EUR", items)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = service.QuoteMethod(ctx, "standard", "", "EUR", items)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestShippingService_CreateShipment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Order shipped in two parcels", func(t *testing.T) {
+		service := internal.NewShippingService(setupTestRepository(t), setupProducer(t, "order_shipped", "order_shipped complete"), nil, setupMethods(t))
+		order := paidOrder()
+
+		first, err := service.CreateShipment(ctx, order, "", "TRACK1", []models.ShipmentLine{{ProductID: "book", Quantity: 1}})
+		require.NoError(t, err)
+		// The carrier defaults to the one of the chosen method
+		assert.Equal(t, "DHL", first.Carrier)
+
+		order.Status = ordermodels.StatusFulfilling
+		second, err := service.CreateShipment(ctx, order, "UPS", "TRACK2", nil)
+		require.NoError(t, err)
+		require.Len(t, second.Lines, 2)
+		assert.Equal(t, models.ShipmentLine{ID: second.Lines[0].ID, ShipmentID: second.ID, ProductID: "book", Quantity: 1}, second.Lines[0])
+		assert.Equal(t, "lamp", second.Lines[1].ProductID)
+
+		shipments, err := service.GetShipmentsForOrder(ctx, order.ID)
+		require.NoError(t, err)
+		assert.Len(t, shipments, 2)
+	})
+
+	t.Run("Shipping more than was ordered", func(t *testing.T) {
+		service := internal.NewShippingService(setupTestRepository(t), setupProducer(t), nil, setupMethods(t))
+
+		_, err := service.CreateShipment(ctx, paidOrder(), "DHL", "TRACK1", []models.ShipmentLine{{ProductID: "book", Quantity: 3}})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = service.CreateShipment(ctx, paidOrder(), "DHL", "TRACK1", []models.ShipmentLine{{ProductID: "chair", Quantity: 1}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Unpaid order", func(t *testing.T) {
+		service := internal.NewShippingService(setupTestRepository(t), setupProducer(t), nil, setupMethods(t))
+		order := paidOrder()
+		order.Status = ordermodels.StatusPendingPayment
+
+		_, err := service.CreateShipment(ctx, order, "DHL", "TRACK1", nil)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Tracking number is required", func(t *testing.T) {
+		service := internal.NewShippingService(setupTestRepository(t), setupProducer(t), nil, setupMethods(t))
+
+		_, err := service.CreateShipment(ctx, paidOrder(), "DHL", " ", nil)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestShippingService_MarkDelivered(t *testing.T) {
+	ctx := context.Background()
+	service := internal.NewShippingService(setupTestRepository(t), setupProducer(t,
+		"order_shipped", "order_delivered", "order_shipped complete", "order_delivered complete",
+	), nil, setupMethods(t))
+	order := paidOrder()
+
+	first, err := service.CreateShipment(ctx, order, "", "TRACK1", []models.ShipmentLine{{ProductID: "lamp", Quantity: 1}})
+	require.NoError(t, err)
+	delivered, err := service.MarkDelivered(ctx, first.ID, order)
+	require.NoError(t, err)
+	assert.Equal(t, models.ShipmentDelivered, delivered.Status)
+	assert.NotNil(t, delivered.DeliveredAt)
+
+	// Marking the shipment again does not announce it twice
+	_, err = service.MarkDelivered(ctx, first.ID, order)
+	require.NoError(t, err)
+
+	order.Status = ordermodels.StatusFulfilling
+	second, err := service.CreateShipment(ctx, order, "", "TRACK2", nil)
+	require.NoError(t, err)
+	_, err = service.MarkDelivered(ctx, second.ID, order)
+	require.NoError(t, err)
+
+	other := paidOrder()
+	other.ID = 2
+	_, err = service.MarkDelivered(ctx, second.ID, other)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = service.MarkDelivered(ctx, 99, order)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
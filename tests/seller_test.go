@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+)
+
+// 9) Query the current account's seller dashboard
+func Test09SellerDashboard(t *testing.T) {
+	query := `
+        query Seller($pagination: PaginationInput) {
+          seller {
+            id
+            name
+            products(pagination: $pagination) {
+              id
+              accountId
+            }
+            sales {
+              orderCount
+              unitsSold
+              totalRevenue
+            }
+            orderLines(pagination: $pagination) {
+              orderId
+              productId
+              price
+              quantity
+              total
+            }
+          }
+        }
+    `
+	variables := map[string]interface{}{
+		"pagination": map[string]interface{}{
+			"skip": 0,
+			"take": 5,
+		},
+	}
+
+	resp := doRequest(t, serverURL, query, variables)
+	assert.Nil(t, resp.Errors, "unexpected GraphQL errors during Seller")
+
+	data, ok := resp.Data.(map[string]interface{})
+	assert.True(t, ok, "response Data should be a map")
+
+	seller, ok := data["seller"].(map[string]interface{})
+	assert.True(t, ok, "seller field should be a map")
+
+	products, ok := seller["products"].([]interface{})
+	assert.True(t, ok)
+	for _, p := range products {
+		assert.Equal(t, seller["id"], p.(map[string]interface{})["accountId"])
+	}
+
+	sales, ok := seller["sales"].(map[string]interface{})
+	assert.True(t, ok, "sales field should be a map")
+	assert.GreaterOrEqual(t, sales["totalRevenue"], 0.0)
+
+	orderLines, ok := seller["orderLines"].([]interface{})
+	assert.True(t, ok)
+	for _, l := range orderLines {
+		line := l.(map[string]interface{})
+		assert.InDelta(t, line["price"].(float64)*line["quantity"].(float64), line["total"], 0.001)
+	}
+	log.Println("Seller:", seller)
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/wishlist/models"
+	"github.com/rasadov/EcommerceAPI/wishlist/proto/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type Client struct {
+	conn    *grpc.ClientConn
+	service pb.WishlistServiceClient
+}
+
+func NewClient(url string) (*Client, error) {
+	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	c := pb.NewWishlistServiceClient(conn)
+	return &Client{conn, c}, nil
+}
+
+func (client *Client) Close() {
+	err := client.conn.Close()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func decodeWishlist(wishlistProto *pb.Wishlist) (*models.Wishlist, error) {
+	wishlist := &models.Wishlist{
+		ID:        uint(wishlistProto.Id),
+		AccountID: wishlistProto.AccountId,
+		Name:      wishlistProto.Name,
+	}
+	if wishlistProto.ShareToken != "" {
+		wishlist.ShareToken = &wishlistProto.ShareToken
+	}
+	wishlist.CreatedAt = time.Time{}
+	if err := wishlist.CreatedAt.UnmarshalBinary(wishlistProto.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	for _, itemProto := range wishlistProto.Items {
+		item := models.WishlistItem{
+			WishlistID: wishlist.ID,
+			ProductID:  itemProto.ProductId,
+		}
+		if err := item.AddedAt.UnmarshalBinary(itemProto.AddedAt); err != nil {
+			return nil, err
+		}
+		wishlist.Items = append(wishlist.Items, item)
+	}
+	return wishlist, nil
+}
+
+func (client *Client) CreateWishlist(ctx context.Context, accountId uint64, name string) (*models.Wishlist, error) {
+	r, err := client.service.CreateWishlist(ctx, &pb.CreateWishlistRequest{
+		AccountId: accountId,
+		Name:      name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeWishlist(r.Wishlist)
+}
+
+func (client *Client) GetWishlists(ctx context.Context, accountId uint64) ([]*models.Wishlist, error) {
+	r, err := client.service.GetWishlists(ctx, &wrapperspb.UInt64Value{Value: accountId})
+	if err != nil {
+		return nil, err
+	}
+
+	var wishlists []*models.Wishlist
+	for _, wishlistProto := range r.Wishlists {
+		wishlist, err := decodeWishlist(wishlistProto)
+		if err != nil {
+			return nil, err
+		}
+		wishlists = append(wishlists, wishlist)
+	}
+	return wishlists, nil
+}
+
+func (client *Client) GetWishlist(ctx context.Context, accountId, wishlistId uint64) (*models.Wishlist, error) {
+	r, err := client.service.GetWishlist(ctx, &pb.WishlistRequest{
+		AccountId:  accountId,
+		WishlistId: wishlistId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeWishlist(r.Wishlist)
+}
+
+func (client *Client) RenameWishlist(ctx context.Context, accountId, wishlistId uint64, name string) (*models.Wishlist, error) {
+	r, err := client.service.RenameWishlist(ctx, &pb.RenameWishlistRequest{
+		AccountId:  accountId,
+		WishlistId: wishlistId,
+		Name:       name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeWishlist(r.Wishlist)
+}
+
+func (client *Client) DeleteWishlist(ctx context.Context, accountId, wishlistId uint64) error {
+	_, err := client.service.DeleteWishlist(ctx, &pb.WishlistRequest{
+		AccountId:  accountId,
+		WishlistId: wishlistId,
+	})
+	return err
+}
+
+func (client *Client) AddItem(ctx context.Context, accountId, wishlistId uint64, productId string) (*models.Wishlist, error) {
+	r, err := client.service.AddWishlistItem(ctx, &pb.WishlistItemRequest{
+		AccountId:  accountId,
+		WishlistId: wishlistId,
+		ProductId:  productId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeWishlist(r.Wishlist)
+}
+
+func (client *Client) RemoveItem(ctx context.Context, accountId, wishlistId uint64, productId string) (*models.Wishlist, error) {
+	r, err := client.service.RemoveWishlistItem(ctx, &pb.WishlistItemRequest{
+		AccountId:  accountId,
+		WishlistId: wishlistId,
+		ProductId:  productId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeWishlist(r.Wishlist)
+}
+
+func (client *Client) MoveItem(ctx context.Context, accountId, fromWishlistId, toWishlistId uint64, productId string) (*models.Wishlist, error) {
+	r, err := client.service.MoveWishlistItem(ctx, &pb.MoveWishlistItemRequest{
+		AccountId:      accountId,
+		FromWishlistId: fromWishlistId,
+		ToWishlistId:   toWishlistId,
+		ProductId:      productId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeWishlist(r.Wishlist)
+}
+
+func (client *Client) ShareWishlist(ctx context.Context, accountId, wishlistId uint64, shared bool) (*models.Wishlist, error) {
+	r, err := client.service.ShareWishlist(ctx, &pb.ShareWishlistRequest{
+		AccountId:  accountId,
+		WishlistId: wishlistId,
+		Shared:     shared,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeWishlist(r.Wishlist)
+}
+
+func (client *Client) GetSharedWishlist(ctx context.Context, shareToken string) (*models.Wishlist, error) {
+	r, err := client.service.GetSharedWishlist(ctx, &pb.GetSharedWishlistRequest{ShareToken: shareToken})
+	if err != nil {
+		return nil, err
+	}
+	return decodeWishlist(r.Wishlist)
+}
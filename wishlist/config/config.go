@@ -0,0 +1,15 @@
+package config
+
+import "os"
+
+var (
+	DatabaseUrl      string
+	ProductUrl       string
+	BootstrapServers string
+)
+
+func init() {
+	DatabaseUrl = os.Getenv("DATABASE_URL")
+	ProductUrl = os.Getenv("PRODUCT_SERVICE_URL")
+	BootstrapServers = os.Getenv("KAFKA_BOOTSTRAP_SERVERS")
+}
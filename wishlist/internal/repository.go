@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/rasadov/EcommerceAPI/wishlist/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWishlistNotFound     = errors.New("wishlist not found")
+	ErrProductNotInWishlist = errors.New("product not in wishlist")
+)
+
+type Repository interface {
+	Close()
+	CreateWishlist(ctx context.Context, wishlist *models.Wishlist) error
+	GetWishlist(ctx context.Context, id uint64) (*models.Wishlist, error)
+	GetWishlistByName(ctx context.Context, accountId uint64, name string) (*models.Wishlist, error)
+	GetWishlistByShareToken(ctx context.Context, shareToken string) (*models.Wishlist, error)
+	GetWishlistsForAccount(ctx context.Context, accountId uint64) ([]*models.Wishlist, error)
+	UpdateWishlist(ctx context.Context, wishlist *models.Wishlist) error
+	DeleteWishlist(ctx context.Context, id uint64) error
+	AddItem(ctx context.Context, item *models.WishlistItem) error
+	RemoveItem(ctx context.Context, wishlistId uint64, productId string) error
+	MoveItem(ctx context.Context, fromWishlistId, toWishlistId uint64, productId string) error
+}
+
+type postgresRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresRepository(db *gorm.DB) (Repository, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	err = sqlDB.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&models.Wishlist{}, &models.WishlistItem{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresRepository{db}, nil
+}
+
+func (repository *postgresRepository) Close() {
+	sqlDB, err := repository.db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+		if err != nil {
+			log.Println("Error closing postgres repository")
+			log.Println(err)
+		}
+	}
+}
+
+func (repository *postgresRepository) CreateWishlist(ctx context.Context, wishlist *models.Wishlist) error {
+	return repository.db.WithContext(ctx).Create(wishlist).Error
+}
+
+func (repository *postgresRepository) findWishlist(ctx context.Context, query string, args ...any) (*models.Wishlist, error) {
+	var wishlist models.Wishlist
+	err := repository.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("added_at DESC, id DESC")
+		}).
+		Where(query, args...).
+		First(&wishlist).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWishlistNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wishlist, nil
+}
+
+func (repository *postgresRepository) GetWishlist(ctx context.Context, id uint64) (*models.Wishlist, error) {
+	return repository.findWishlist(ctx, "id = ?", id)
+}
+
+func (repository *postgresRepository) GetWishlistByName(ctx context.Context, accountId uint64, name string) (*models.Wishlist, error) {
+	return repository.findWishlist(ctx, "account_id = ? AND name = ?", accountId, name)
+}
+
+func (repository *postgresRepository) GetWishlistByShareToken(ctx context.Context, shareToken string) (*models.Wishlist, error) {
+	return repository.findWishlist(ctx, "share_token = ?", shareToken)
+}
+
+func (repository *postgresRepository) GetWishlistsForAccount(ctx context.Context, accountId uint64) ([]*models.Wishlist, error) {
+	var wishlists []*models.Wishlist
+	err := repository.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("added_at DESC, id DESC")
+		}).
+		Where("account_id = ?", accountId).
+		Order("created_at, id").
+		Find(&wishlists).Error
+	if err != nil {
+		return nil, err
+	}
+	return wishlists, nil
+}
+
+func (repository *postgresRepository) UpdateWishlist(ctx context.Context, wishlist *models.Wishlist) error {
+	return repository.db.WithContext(ctx).
+		Model(&models.Wishlist{}).
+		Where("id = ?", wishlist.ID).
+		Updates(map[string]any{
+			"name":        wishlist.Name,
+			"share_token": wishlist.ShareToken,
+		}).Error
+}
+
+func (repository *postgresRepository) DeleteWishlist(ctx context.Context, id uint64) error {
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("wishlist_id = ?", id).Delete(&models.WishlistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Wishlist{}, id).Error
+	})
+}
+
+// AddItem saves a product to a wishlist. Adding a product that is already on the list is a no-op.
+func (repository *postgresRepository) AddItem(ctx context.Context, item *models.WishlistItem) error {
+	return repository.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(item).Error
+}
+
+func (repository *postgresRepository) RemoveItem(ctx context.Context, wishlistId uint64, productId string) error {
+	result := repository.db.WithContext(ctx).
+		Where("wishlist_id = ? AND product_id = ?", wishlistId, productId).
+		Delete(&models.WishlistItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrProductNotInWishlist
+	}
+	return nil
+}
+
+// MoveItem moves a product between two lists, merging with the target if it is already there.
+func (repository *postgresRepository) MoveItem(ctx context.Context, fromWishlistId, toWishlistId uint64, productId string) error {
+	return repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var item models.WishlistItem
+		err := tx.Where("wishlist_id = ? AND product_id = ?", fromWishlistId, productId).First(&item).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProductNotInWishlist
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = tx.Delete(&item).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.WishlistItem{
+			WishlistID: uint(toWishlistId),
+			ProductID:  productId,
+			AddedAt:    item.AddedAt,
+		}).Error
+	})
+}
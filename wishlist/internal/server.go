@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	product "github.com/rasadov/EcommerceAPI/product/client"
+	"github.com/rasadov/EcommerceAPI/wishlist/models"
+	"github.com/rasadov/EcommerceAPI/wishlist/proto/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type grpcServer struct {
+	pb.UnimplementedWishlistServiceServer
+	service       Service
+	productClient *product.Client
+}
+
+func ListenGRPC(service Service, productURL string, port int) error {
+	productClient, err := product.NewClient(productURL)
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		productClient.Close()
+		return err
+	}
+
+	serv := grpc.NewServer()
+	pb.RegisterWishlistServiceServer(serv, &grpcServer{
+		pb.UnimplementedWishlistServiceServer{},
+		service,
+		productClient,
+	})
+	reflection.Register(serv)
+
+	return serv.Serve(lis)
+}
+
+func encodeWishlist(wishlist *models.Wishlist) *pb.Wishlist {
+	encoded := &pb.Wishlist{
+		Id:        uint64(wishlist.ID),
+		AccountId: wishlist.AccountID,
+		Name:      wishlist.Name,
+		Items:     []*pb.WishlistItem{},
+	}
+	if wishlist.ShareToken != nil {
+		encoded.ShareToken = *wishlist.ShareToken
+	}
+	encoded.CreatedAt, _ = wishlist.CreatedAt.MarshalBinary()
+	for _, item := range wishlist.Items {
+		encodedItem := &pb.WishlistItem{ProductId: item.ProductID}
+		encodedItem.AddedAt, _ = item.AddedAt.MarshalBinary()
+		encoded.Items = append(encoded.Items, encodedItem)
+	}
+	return encoded
+}
+
+func (server *grpcServer) CreateWishlist(ctx context.Context, request *pb.CreateWishlistRequest) (*pb.WishlistResponse, error) {
+	wishlist, err := server.service.CreateWishlist(ctx, request.AccountId, request.Name)
+	if err != nil {
+		log.Println("Error creating wishlist", err)
+		return nil, err
+	}
+	return &pb.WishlistResponse{Wishlist: encodeWishlist(wishlist)}, nil
+}
+
+func (server *grpcServer) GetWishlists(ctx context.Context, request *wrapperspb.UInt64Value) (*pb.GetWishlistsResponse, error) {
+	wishlists, err := server.service.GetWishlists(ctx, request.Value)
+	if err != nil {
+		log.Println("Error getting wishlists", err)
+		return nil, err
+	}
+
+	response := &pb.GetWishlistsResponse{}
+	for _, wishlist := range wishlists {
+		response.Wishlists = append(response.Wishlists, encodeWishlist(wishlist))
+	}
+	return response, nil
+}
+
+func (server *grpcServer) GetWishlist(ctx context.Context, request *pb.WishlistRequest) (*pb.WishlistResponse, error) {
+	wishlist, err := server.service.GetWishlist(ctx, request.AccountId, request.WishlistId)
+	if err != nil {
+		log.Println("Error getting wishlist", err)
+		return nil, err
+	}
+	return &pb.WishlistResponse{Wishlist: encodeWishlist(wishlist)}, nil
+}
+
+func (server *grpcServer) RenameWishlist(ctx context.Context, request *pb.RenameWishlistRequest) (*pb.WishlistResponse, error) {
+	wishlist, err := server.service.RenameWishlist(ctx, request.AccountId, request.WishlistId, request.Name)
+	if err != nil {
+		log.Println("Error renaming wishlist", err)
+		return nil, err
+	}
+	return &pb.WishlistResponse{Wishlist: encodeWishlist(wishlist)}, nil
+}
+
+func (server *grpcServer) DeleteWishlist(ctx context.Context, request *pb.WishlistRequest) (*emptypb.Empty, error) {
+	err := server.service.DeleteWishlist(ctx, request.AccountId, request.WishlistId)
+	if err != nil {
+		log.Println("Error deleting wishlist", err)
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (server *grpcServer) AddWishlistItem(ctx context.Context, request *pb.WishlistItemRequest) (*pb.WishlistResponse, error) {
+	// Only existing products can be saved
+	_, err := server.productClient.GetProduct(ctx, request.ProductId)
+	if err != nil {
+		log.Println("Error getting wishlist product", err)
+		return nil, err
+	}
+
+	wishlist, err := server.service.AddItem(ctx, request.AccountId, request.WishlistId, request.ProductId)
+	if err != nil {
+		log.Println("Error adding wishlist item", err)
+		return nil, err
+	}
+	return &pb.WishlistResponse{Wishlist: encodeWishlist(wishlist)}, nil
+}
+
+func (server *grpcServer) RemoveWishlistItem(ctx context.Context, request *pb.WishlistItemRequest) (*pb.WishlistResponse, error) {
+	wishlist, err := server.service.RemoveItem(ctx, request.AccountId, request.WishlistId, request.ProductId)
+	if err != nil {
+		log.Println("Error removing wishlist item", err)
+		return nil, err
+	}
+	return &pb.WishlistResponse{Wishlist: encodeWishlist(wishlist)}, nil
+}
+
+func (server *grpcServer) MoveWishlistItem(ctx context.Context, request *pb.MoveWishlistItemRequest) (*pb.WishlistResponse, error) {
+	wishlist, err := server.service.MoveItem(ctx, request.AccountId, request.FromWishlistId, request.ToWishlistId, request.ProductId)
+	if err != nil {
+		log.Println("Error moving wishlist item", err)
+		return nil, err
+	}
+	return &pb.WishlistResponse{Wishlist: encodeWishlist(wishlist)}, nil
+}
+
+func (server *grpcServer) ShareWishlist(ctx context.Context, request *pb.ShareWishlistRequest) (*pb.WishlistResponse, error) {
+	wishlist, err := server.service.ShareWishlist(ctx, request.AccountId, request.WishlistId, request.Shared)
+	if err != nil {
+		log.Println("Error sharing wishlist", err)
+		return nil, err
+	}
+	return &pb.WishlistResponse{Wishlist: encodeWishlist(wishlist)}, nil
+}
+
+func (server *grpcServer) GetSharedWishlist(ctx context.Context, request *pb.GetSharedWishlistRequest) (*pb.WishlistResponse, error) {
+	wishlist, err := server.service.GetSharedWishlist(ctx, request.ShareToken)
+	if err != nil {
+		log.Println("Error getting shared wishlist", err)
+		return nil, err
+	}
+	return &pb.WishlistResponse{Wishlist: encodeWishlist(wishlist)}, nil
+}
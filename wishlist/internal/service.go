@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/rasadov/EcommerceAPI/pkg/kafka"
+	"github.com/rasadov/EcommerceAPI/wishlist/models"
+)
+
+var (
+	ErrInvalidWishlistName = errors.New("invalid wishlist name")
+	ErrWishlistNameTaken   = errors.New("wishlist with this name already exists")
+)
+
+type Service interface {
+	CreateWishlist(ctx context.Context, accountId uint64, name string) (*models.Wishlist, error)
+	GetWishlists(ctx context.Context, accountId uint64) ([]*models.Wishlist, error)
+	GetWishlist(ctx context.Context, accountId, wishlistId uint64) (*models.Wishlist, error)
+	RenameWishlist(ctx context.Context, accountId, wishlistId uint64, name string) (*models.Wishlist, error)
+	DeleteWishlist(ctx context.Context, accountId, wishlistId uint64) error
+	AddItem(ctx context.Context, accountId, wishlistId uint64, productId string) (*models.Wishlist, error)
+	RemoveItem(ctx context.Context, accountId, wishlistId uint64, productId string) (*models.Wishlist, error)
+	MoveItem(ctx context.Context, accountId, fromWishlistId, toWishlistId uint64, productId string) (*models.Wishlist, error)
+	ShareWishlist(ctx context.Context, accountId, wishlistId uint64, shared bool) (*models.Wishlist, error)
+	GetSharedWishlist(ctx context.Context, shareToken string) (*models.Wishlist, error)
+	GetProducer() sarama.AsyncProducer
+}
+
+type wishlistService struct {
+	repository Repository
+	producer   sarama.AsyncProducer
+}
+
+func NewWishlistService(repository Repository, producer sarama.AsyncProducer) Service {
+	return &wishlistService{repository, producer}
+}
+
+func (service wishlistService) GetProducer() sarama.AsyncProducer {
+	return service.producer
+}
+
+func (service wishlistService) CreateWishlist(ctx context.Context, accountId uint64, name string) (*models.Wishlist, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidWishlistName
+	}
+	if _, err := service.repository.GetWishlistByName(ctx, accountId, name); err == nil {
+		return nil, ErrWishlistNameTaken
+	}
+
+	wishlist := &models.Wishlist{
+		AccountID: accountId,
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := service.repository.CreateWishlist(ctx, wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (service wishlistService) GetWishlists(ctx context.Context, accountId uint64) ([]*models.Wishlist, error) {
+	return service.repository.GetWishlistsForAccount(ctx, accountId)
+}
+
+// GetWishlist returns a wishlist owned by the account. A wishlistId of 0 refers to the
+// default list, which is created the first time it is needed.
+func (service wishlistService) GetWishlist(ctx context.Context, accountId, wishlistId uint64) (*models.Wishlist, error) {
+	if wishlistId == 0 {
+		wishlist, err := service.repository.GetWishlistByName(ctx, accountId, models.DefaultWishlistName)
+		if errors.Is(err, ErrWishlistNotFound) {
+			return service.CreateWishlist(ctx, accountId, models.DefaultWishlistName)
+		}
+		return wishlist, err
+	}
+
+	wishlist, err := service.repository.GetWishlist(ctx, wishlistId)
+	if err != nil {
+		return nil, err
+	}
+	// Other accounts' lists are reported as missing rather than forbidden
+	if wishlist.AccountID != accountId {
+		return nil, ErrWishlistNotFound
+	}
+	return wishlist, nil
+}
+
+func (service wishlistService) RenameWishlist(ctx context.Context, accountId, wishlistId uint64, name string) (*models.Wishlist, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidWishlistName
+	}
+
+	wishlist, err := service.GetWishlist(ctx, accountId, wishlistId)
+	if err != nil {
+		return nil, err
+	}
+	if existing, err := service.repository.GetWishlistByName(ctx, accountId, name); err == nil && existing.ID != wishlist.ID {
+		return nil, ErrWishlistNameTaken
+	}
+
+	wishlist.Name = name
+	if err = service.repository.UpdateWishlist(ctx, wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (service wishlistService) DeleteWishlist(ctx context.Context, accountId, wishlistId uint64) error {
+	wishlist, err := service.GetWishlist(ctx, accountId, wishlistId)
+	if err != nil {
+		return err
+	}
+	return service.repository.DeleteWishlist(ctx, uint64(wishlist.ID))
+}
+
+func (service wishlistService) AddItem(ctx context.Context, accountId, wishlistId uint64, productId string) (*models.Wishlist, error) {
+	wishlist, err := service.GetWishlist(ctx, accountId, wishlistId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.repository.AddItem(ctx, &models.WishlistItem{
+		WishlistID: wishlist.ID,
+		ProductID:  productId,
+		AddedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Send to recommendation service
+	go func() {
+		err := kafka.SendMessageToRecommender(service, models.Event{
+			Type: "wishlist_added",
+			EventData: models.EventData{
+				AccountId: int(accountId),
+				ProductId: productId,
+			},
+		}, "interaction_events")
+		if err != nil {
+			log.Println("Failed to send event to recommendation service:", err)
+		}
+	}()
+
+	return service.repository.GetWishlist(ctx, uint64(wishlist.ID))
+}
+
+func (service wishlistService) RemoveItem(ctx context.Context, accountId, wishlistId uint64, productId string) (*models.Wishlist, error) {
+	wishlist, err := service.GetWishlist(ctx, accountId, wishlistId)
+	if err != nil {
+		return nil, err
+	}
+	if err = service.repository.RemoveItem(ctx, uint64(wishlist.ID), productId); err != nil {
+		return nil, err
+	}
+	return service.repository.GetWishlist(ctx, uint64(wishlist.ID))
+}
+
+// MoveItem moves a product from one of the account's lists to another and returns the target list.
+func (service wishlistService) MoveItem(ctx context.Context, accountId, fromWishlistId, toWishlistId uint64, productId string) (*models.Wishlist, error) {
+	from, err := service.GetWishlist(ctx, accountId, fromWishlistId)
+	if err != nil {
+		return nil, err
+	}
+	to, err := service.GetWishlist(ctx, accountId, toWishlistId)
+	if err != nil {
+		return nil, err
+	}
+	if from.ID == to.ID {
+		return to, nil
+	}
+
+	if err = service.repository.MoveItem(ctx, uint64(from.ID), uint64(to.ID), productId); err != nil {
+		return nil, err
+	}
+	return service.repository.GetWishlist(ctx, uint64(to.ID))
+}
+
+// ShareWishlist creates a public share token for the list, or revokes it when shared is false.
+// Sharing an already shared list keeps its existing link.
+func (service wishlistService) ShareWishlist(ctx context.Context, accountId, wishlistId uint64, shared bool) (*models.Wishlist, error) {
+	wishlist, err := service.GetWishlist(ctx, accountId, wishlistId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case shared && wishlist.ShareToken == nil:
+		token, err := newShareToken()
+		if err != nil {
+			return nil, err
+		}
+		wishlist.ShareToken = &token
+	case !shared:
+		wishlist.ShareToken = nil
+	default:
+		return wishlist, nil
+	}
+
+	if err = service.repository.UpdateWishlist(ctx, wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (service wishlistService) GetSharedWishlist(ctx context.Context, shareToken string) (*models.Wishlist, error) {
+	if shareToken == "" {
+		return nil, ErrWishlistNotFound
+	}
+	return service.repository.GetWishlistByShareToken(ctx, shareToken)
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// DefaultWishlistName is the list items are saved to when no wishlist is given.
+const DefaultWishlistName = "Saved for later"
+
+type Wishlist struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	AccountID  uint64 `gorm:"index"`
+	Name       string
+	ShareToken *string `gorm:"uniqueIndex"`
+	CreatedAt  time.Time
+	Items      []WishlistItem `gorm:"foreignKey:WishlistID;constraint:OnDelete:CASCADE"`
+}
+
+type WishlistItem struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	WishlistID uint   `gorm:"uniqueIndex:idx_wishlist_product"`
+	ProductID  string `gorm:"uniqueIndex:idx_wishlist_product"`
+	AddedAt    time.Time
+}
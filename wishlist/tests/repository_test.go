@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasadov/EcommerceAPI/wishlist/internal"
+	"github.com/rasadov/EcommerceAPI/wishlist/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Test helper to create a repository backed by an in-memory SQLite database
+func setupTestRepository(t *testing.T) internal.Repository {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	r, err := internal.NewPostgresRepository(db)
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+	return r
+}
+
+func createWishlist(t *testing.T, repo internal.Repository, accountId uint64, name string) *models.Wishlist {
+	wishlist := &models.Wishlist{AccountID: accountId, Name: name, CreatedAt: time.Now().UTC()}
+	require.NoError(t, repo.CreateWishlist(context.Background(), wishlist))
+	return wishlist
+}
+
+func TestWishlistRepository_Items(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	wishlist := createWishlist(t, repo, 1, "Birthday")
+
+	t.Run("Adding the same product twice keeps one item", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			err := repo.AddItem(ctx, &models.WishlistItem{WishlistID: wishlist.ID, ProductID: "p1", AddedAt: time.Now().UTC()})
+			require.NoError(t, err)
+		}
+
+		found, err := repo.GetWishlist(ctx, uint64(wishlist.ID))
+		require.NoError(t, err)
+		assert.Len(t, found.Items, 1)
+	})
+
+	t.Run("Removing a missing product", func(t *testing.T) {
+		err := repo.RemoveItem(ctx, uint64(wishlist.ID), "missing")
+		assert.ErrorIs(t, err, internal.ErrProductNotInWishlist)
+	})
+
+	t.Run("Moving merges into the target list", func(t *testing.T) {
+		target := createWishlist(t, repo, 1, "Later")
+		require.NoError(t, repo.AddItem(ctx, &models.WishlistItem{WishlistID: target.ID, ProductID: "p1", AddedAt: time.Now().UTC()}))
+
+		require.NoError(t, repo.MoveItem(ctx, uint64(wishlist.ID), uint64(target.ID), "p1"))
+
+		from, err := repo.GetWishlist(ctx, uint64(wishlist.ID))
+		require.NoError(t, err)
+		assert.Empty(t, from.Items)
+
+		to, err := repo.GetWishlist(ctx, uint64(target.ID))
+		require.NoError(t, err)
+		assert.Len(t, to.Items, 1)
+	})
+}
+
+func TestWishlistRepository_ShareToken(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	wishlist := createWishlist(t, repo, 1, "Gifts")
+
+	token := "token"
+	wishlist.ShareToken = &token
+	require.NoError(t, repo.UpdateWishlist(ctx, wishlist))
+
+	found, err := repo.GetWishlistByShareToken(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, wishlist.ID, found.ID)
+
+	wishlist.ShareToken = nil
+	require.NoError(t, repo.UpdateWishlist(ctx, wishlist))
+
+	_, err = repo.GetWishlistByShareToken(ctx, token)
+	assert.ErrorIs(t, err, internal.ErrWishlistNotFound)
+}
+
+func TestWishlistRepository_DeleteWishlist(t *testing.T) {
+	repo := setupTestRepository(t)
+	ctx := context.Background()
+	wishlist := createWishlist(t, repo, 1, "Old")
+	require.NoError(t, repo.AddItem(ctx, &models.WishlistItem{WishlistID: wishlist.ID, ProductID: "p1", AddedAt: time.Now().UTC()}))
+
+	require.NoError(t, repo.DeleteWishlist(ctx, uint64(wishlist.ID)))
+
+	_, err := repo.GetWishlist(ctx, uint64(wishlist.ID))
+	assert.ErrorIs(t, err, internal.ErrWishlistNotFound)
+}
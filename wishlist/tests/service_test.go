@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama/mocks"
+	"github.com/rasadov/EcommerceAPI/wishlist/internal"
+	"github.com/rasadov/EcommerceAPI/wishlist/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test helper to create a mock producer that accepts count recommender events
+func setupProducer(t *testing.T, count int) *mocks.AsyncProducer {
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	producer := mocks.NewAsyncProducer(t, config)
+	for i := 0; i < count; i++ {
+		producer.ExpectInputAndSucceed()
+	}
+	t.Cleanup(func() {
+		for i := 0; i < count; i++ {
+			<-producer.Successes()
+		}
+		require.NoError(t, producer.Close())
+	})
+	return producer
+}
+
+func TestWishlistService_AddItem(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Default list is created on first use", func(t *testing.T) {
+		service := internal.NewWishlistService(setupTestRepository(t), setupProducer(t, 1))
+
+		wishlist, err := service.AddItem(ctx, 1, 0, "p1")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.DefaultWishlistName, wishlist.Name)
+		require.Len(t, wishlist.Items, 1)
+		assert.Equal(t, "p1", wishlist.Items[0].ProductID)
+	})
+
+	t.Run("Lists of other accounts are not found", func(t *testing.T) {
+		service := internal.NewWishlistService(setupTestRepository(t), setupProducer(t, 0))
+		wishlist, err := service.CreateWishlist(ctx, 1, "Mine")
+		require.NoError(t, err)
+
+		_, err = service.AddItem(ctx, 2, uint64(wishlist.ID), "p1")
+
+		assert.ErrorIs(t, err, internal.ErrWishlistNotFound)
+	})
+}
+
+func TestWishlistService_CreateWishlist(t *testing.T) {
+	ctx := context.Background()
+	service := internal.NewWishlistService(setupTestRepository(t), setupProducer(t, 0))
+
+	_, err := service.CreateWishlist(ctx, 1, "  ")
+	assert.ErrorIs(t, err, internal.ErrInvalidWishlistName)
+
+	_, err = service.CreateWishlist(ctx, 1, "Gifts")
+	require.NoError(t, err)
+	_, err = service.CreateWishlist(ctx, 1, "Gifts")
+	assert.ErrorIs(t, err, internal.ErrWishlistNameTaken)
+
+	// Names only need to be unique per account
+	_, err = service.CreateWishlist(ctx, 2, "Gifts")
+	assert.NoError(t, err)
+}
+
+func TestWishlistService_ShareWishlist(t *testing.T) {
+	ctx := context.Background()
+	service := internal.NewWishlistService(setupTestRepository(t), setupProducer(t, 0))
+	wishlist, err := service.CreateWishlist(ctx, 1, "Gifts")
+	require.NoError(t, err)
+
+	shared, err := service.ShareWishlist(ctx, 1, uint64(wishlist.ID), true)
+	require.NoError(t, err)
+	require.NotNil(t, shared.ShareToken)
+	token := *shared.ShareToken
+
+	again, err := service.ShareWishlist(ctx, 1, uint64(wishlist.ID), true)
+	require.NoError(t, err)
+	assert.Equal(t, token, *again.ShareToken)
+
+	found, err := service.GetSharedWishlist(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, wishlist.ID, found.ID)
+
+	_, err = service.ShareWishlist(ctx, 1, uint64(wishlist.ID), false)
+	require.NoError(t, err)
+	_, err = service.GetSharedWishlist(ctx, token)
+	assert.ErrorIs(t, err, internal.ErrWishlistNotFound)
+}